@@ -0,0 +1,1009 @@
+package bench
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/isucon/isucandar/score"
+	"github.com/isucon/isucon13/bench/internal/adaptive"
+	"github.com/isucon/isucon13/bench/internal/bencherror"
+	"github.com/isucon/isucon13/bench/internal/benchrand"
+	"github.com/isucon/isucon13/bench/internal/benchscore"
+	"github.com/isucon/isucon13/bench/internal/churn"
+	"github.com/isucon/isucon13/bench/internal/config"
+	"github.com/isucon/isucon13/bench/internal/coverage"
+	"github.com/isucon/isucon13/bench/internal/disqualify"
+	"github.com/isucon/isucon13/bench/internal/failfast"
+	"github.com/isucon/isucon13/bench/internal/journal"
+	"github.com/isucon/isucon13/bench/internal/pacer"
+	"github.com/isucon/isucon13/bench/internal/priority"
+	"github.com/isucon/isucon13/bench/internal/scheduler"
+	"github.com/isucon/isucon13/bench/internal/tracing"
+	"github.com/isucon/isucon13/bench/isupipe"
+	"github.com/isucon/isucon13/bench/scenario"
+	"go.uber.org/zap"
+	"golang.org/x/sync/semaphore"
+	"golang.org/x/time/rate"
+)
+
+var (
+	DnsWaterTortureAttackScenario      score.ScoreTag = "dns-watertorture-attack"
+	BasicStreamerColdReserve           score.ScoreTag = "streamer-cold-reserve"
+	BasicStreamerModerateScenario      score.ScoreTag = "streamer-moderate"
+	BasicViewerScenario                score.ScoreTag = "viewer"
+	BasicViewerReportScenario          score.ScoreTag = "viewer-report"
+	ViewerSpamScenario                 score.ScoreTag = "viewer-spam"
+	AggressiveStreamerModerateScenario score.ScoreTag = "aggressive-streamer-moderate"
+	ReservationCollisionScenario       score.ScoreTag = "reservation-collision"
+	RaidScenario                       score.ScoreTag = "raid"
+	RankingStabilityScenario           score.ScoreTag = "ranking-stability"
+	ModerationEffectivenessScenario    score.ScoreTag = "moderation-effectiveness"
+	ReactionBurstScenario              score.ScoreTag = "reaction-burst"
+	TipRecordingScenario               score.ScoreTag = "tip-recording"
+	UserRegistrationScenario           score.ScoreTag = "user-registration"
+	LongSessionStreamerScenario        score.ScoreTag = "long-session-streamer"
+	RankingConsistencyScenario         score.ScoreTag = "ranking-consistency"
+	NgWordIdempotencyScenario          score.ScoreTag = "ngword-idempotency"
+	// 最終チェック (最終チェックの段階でのみ数える)
+	FinalcheckScenario score.ScoreTag = "finalcheck"
+)
+
+// scenarioTimeouts は、シナリオ1回あたりのタイムアウトです
+// NOTE: 統計情報エンドポイントが固まるなどして、1つのシナリオが走行時間いっぱいワーカーを占有し続けないようにする
+var scenarioTimeouts = map[score.ScoreTag]time.Duration{
+	BasicStreamerColdReserve:           20 * time.Second,
+	BasicStreamerModerateScenario:      20 * time.Second,
+	BasicViewerScenario:                30 * time.Second,
+	BasicViewerReportScenario:          10 * time.Second,
+	ViewerSpamScenario:                 10 * time.Second,
+	AggressiveStreamerModerateScenario: 10 * time.Second,
+	ReservationCollisionScenario:       20 * time.Second,
+	RaidScenario:                       30 * time.Second,
+	RankingStabilityScenario:           20 * time.Second,
+	ModerationEffectivenessScenario:    20 * time.Second,
+	ReactionBurstScenario:              20 * time.Second,
+	TipRecordingScenario:               10 * time.Second,
+	UserRegistrationScenario:           10 * time.Second,
+	RankingConsistencyScenario:         20 * time.Second,
+	NgWordIdempotencyScenario:          10 * time.Second,
+	// NOTE: LongSessionStreamerScenario は走行中ずっとセッションを維持するため、タイムアウトを設けない
+}
+
+// scenarioClasses は、整合性を確かめる検証のシナリオです. 含まれないシナリオはスループットのシナリオとします
+// NOTE: LongSessionStreamerScenario はタイムアウトを設けず走行中ずっと実行するため、優先の対象としない
+var scenarioClasses = map[score.ScoreTag]priority.Class{
+	ReservationCollisionScenario:    priority.ClassVerification,
+	RankingStabilityScenario:        priority.ClassVerification,
+	ModerationEffectivenessScenario: priority.ClassVerification,
+	ReactionBurstScenario:           priority.ClassVerification,
+	TipRecordingScenario:            priority.ClassVerification,
+	UserRegistrationScenario:        priority.ClassVerification,
+	RankingConsistencyScenario:      priority.ClassVerification,
+	NgWordIdempotencyScenario:       priority.ClassVerification,
+}
+
+func scenarioClass(tag score.ScoreTag) priority.Class {
+	if class, ok := scenarioClasses[tag]; ok {
+		return class
+	}
+	return priority.ClassThroughput
+}
+
+// loadScenarios は、負荷走行で実行するシナリオの一覧です
+// NOTE: タイムアウトや優先度の対象外のシナリオも含むため、シナリオ名の検証にはこちらを用いる
+var loadScenarios = []score.ScoreTag{
+	DnsWaterTortureAttackScenario,
+	BasicStreamerColdReserve,
+	BasicStreamerModerateScenario,
+	BasicViewerScenario,
+	BasicViewerReportScenario,
+	ViewerSpamScenario,
+	AggressiveStreamerModerateScenario,
+	ReservationCollisionScenario,
+	RaidScenario,
+	RankingStabilityScenario,
+	ModerationEffectivenessScenario,
+	ReactionBurstScenario,
+	TipRecordingScenario,
+	UserRegistrationScenario,
+	LongSessionStreamerScenario,
+	RankingConsistencyScenario,
+	NgWordIdempotencyScenario,
+}
+
+func isLoadScenario(tag score.ScoreTag) bool {
+	return slices.Contains(loadScenarios, tag)
+}
+
+type LoginCounter struct {
+	sync.RWMutex
+	cnt uint64
+}
+
+func (c *LoginCounter) Inc() {
+	c.Lock()
+	defer c.Unlock()
+	c.cnt++
+}
+
+func (c *LoginCounter) Get() uint64 {
+	c.RLock()
+	defer c.RUnlock()
+	return c.cnt
+}
+
+func (c *LoginCounter) WaitUntil(threshold uint64) chan struct{} {
+	ch := make(chan struct{})
+	go func() {
+		defer close(ch)
+		for c.Get() >= threshold {
+		}
+	}()
+	return ch
+}
+
+// workerSem は、シナリオのワーカー数の制限です
+// NOTE: --adaptive-concurrency 指定時は、上限が動的に変わる adaptive.Limiter を用いる
+type workerSem interface {
+	TryAcquire(n int64) bool
+	Release(n int64)
+}
+
+type benchmarker struct {
+	contestantLogger *zap.Logger
+
+	streamerSem      workerSem
+	moderatorSem     workerSem
+	viewerSem        workerSem
+	viewerReportSem  workerSem
+	spammerSem       workerSem
+	collisionSem     *semaphore.Weighted
+	raidSem          *semaphore.Weighted
+	rankingSem       *semaphore.Weighted
+	moderationSem    *semaphore.Weighted
+	reactionBurstSem *semaphore.Weighted
+	tipRecordingSem  *semaphore.Weighted
+	registrationSem  *semaphore.Weighted
+	longSessionSem   *semaphore.Weighted
+	consistencySem   *semaphore.Weighted
+	ngWordSem        *semaphore.Weighted
+	attackSem        *semaphore.Weighted
+	attackParallelis int
+	// 基本シナリオのワーカー数の調整 (--adaptive-concurrency 指定時のみ)
+	concurrency *adaptive.Controller
+	// 検証のシナリオを優先するため、スループットのワーカーの起動を譲らせる
+	workerPriority *priority.Scheduler
+
+	// login
+	streamerLoginSem     *semaphore.Weighted
+	streamerLoginCounter *LoginCounter
+	viewerLoginSem       *semaphore.Weighted
+	viewerLoginCounter   *LoginCounter
+
+	longStreamerClientPool *isupipe.ClientPool
+	streamerClientPool     *isupipe.ClientPool
+	viewerClientPool       *isupipe.ClientPool
+
+	livestreamPool *isupipe.LivestreamPool
+
+	spamPool *isupipe.LivecommentPool
+
+	// 負荷走行の段階のシナリオの成功・失敗回数 (Runner が所有する)
+	scenarioCounter benchscore.PhaseScenarioCounter
+	// シナリオのタイムアウトで打ち切った回数 (ワーカーの占有)
+	timeoutCounter *score.Score
+
+	// 走行を中断すべき仕様違反を通知する
+	violateCh chan error
+	// 仕様違反を検出しても走行を続けるかを判定する (練習モードのみ. nilの場合は常に中断する)
+	waiveViolation func(err error) bool
+	// エラーの予算 (--fail-fast-errors, --fail-fast-5xx-rate 指定時のみ)
+	failFast failfast.Budget
+
+	profile config.LoadProfile
+	// シナリオごとの開始の平準化 (含まれないシナリオは制限しない)
+	pacers map[score.ScoreTag]*pacer.Pacer
+
+	startAt time.Time
+}
+
+func powWeightSize(m int) int64 {
+	return int64(math.Pow(2, float64(m)))
+}
+
+func newBenchmarker(ctx context.Context, contestantLogger *zap.Logger, profile config.LoadProfile, scenarioCounter *benchscore.ScenarioCounter) *benchmarker {
+	var weight int64 = profile.Parallelism
+	// いま負荷レベルは固定値なので選手に見せる意味がない
+	// contestantLogger.Info("負荷レベル", zap.Int64("level", weight))
+
+	longStreamerClientPool := isupipe.NewClientPool(ctx)
+	streamerClientPool := isupipe.NewClientPool(ctx)
+	viewerClientPool := isupipe.NewClientPool(ctx)
+
+	livestreamPool := isupipe.NewLivestreamPool(ctx)
+
+	spamPool := isupipe.NewLivecommentPool(ctx)
+
+	pacers := make(map[score.ScoreTag]*pacer.Pacer, len(profile.Pacing))
+	for name, p := range profile.Pacing {
+		pacers[score.ScoreTag(name)] = pacer.New(p.Rate, p.Burst, p.Jitter)
+	}
+
+	newWorkerSem := func(n int64) workerSem {
+		return semaphore.NewWeighted(n)
+	}
+	var limiters []*adaptive.Limiter
+	if config.AdaptiveConcurrency {
+		newWorkerSem = func(n int64) workerSem {
+			l := adaptive.NewLimiter(n)
+			limiters = append(limiters, l)
+			return l
+		}
+	}
+	var (
+		streamerSem     = newWorkerSem(weight)
+		moderatorSem    = newWorkerSem(weight)
+		viewerSem       = newWorkerSem(weight * profile.ViewerRatio)
+		viewerReportSem = newWorkerSem(weight)
+		spammerSem      = newWorkerSem(weight * profile.SpammerRatio)
+	)
+	var concurrency *adaptive.Controller
+	if config.AdaptiveConcurrency {
+		concurrency = adaptive.NewController(adaptive.Config{
+			MaxErrorRate:   config.AdaptiveMaxErrorRate,
+			MaxLatencyP99:  config.AdaptiveMaxLatencyP99,
+			MinRequests:    config.AdaptiveMinRequests,
+			MaxLevel:       config.AdaptiveMaxLevel,
+			MinLevel:       config.AdaptiveMinLevel,
+			Step:           config.AdaptiveStep,
+			Backoff:        config.AdaptiveBackoff,
+			SustainWindows: config.AdaptiveSustainWindows,
+		}, limiters...)
+	}
+
+	failFast := failfast.Budget{
+		MaxErrors:          config.FailFastMaxErrors,
+		MaxServerErrorRate: config.FailFastMaxServerErrorRate,
+		MinRequests:        config.FailFastMinRequests,
+	}
+
+	return &benchmarker{
+		contestantLogger:       contestantLogger,
+		streamerSem:            streamerSem,
+		moderatorSem:           moderatorSem,
+		viewerSem:              viewerSem,
+		viewerReportSem:        viewerReportSem,
+		spammerSem:             spammerSem,
+		concurrency:            concurrency,
+		workerPriority:         priority.NewScheduler(config.VerificationYieldRatio),
+		attackSem:              semaphore.NewWeighted(512), // 攻撃を段階的に大きくする最大値
+		attackParallelis:       profile.InitialAttackParallelism,
+		collisionSem:           semaphore.NewWeighted(1),
+		raidSem:                semaphore.NewWeighted(1),
+		rankingSem:             semaphore.NewWeighted(1),
+		moderationSem:          semaphore.NewWeighted(1),
+		reactionBurstSem:       semaphore.NewWeighted(1),
+		tipRecordingSem:        semaphore.NewWeighted(1),
+		registrationSem:        semaphore.NewWeighted(1),
+		longSessionSem:         semaphore.NewWeighted(1),
+		consistencySem:         semaphore.NewWeighted(1),
+		ngWordSem:              semaphore.NewWeighted(1),
+		streamerLoginSem:       semaphore.NewWeighted(weight),
+		streamerLoginCounter:   new(LoginCounter),
+		viewerLoginSem:         semaphore.NewWeighted(weight),
+		viewerLoginCounter:     new(LoginCounter),
+		longStreamerClientPool: longStreamerClientPool,
+		streamerClientPool:     streamerClientPool,
+		viewerClientPool:       viewerClientPool,
+		livestreamPool:         livestreamPool,
+		spamPool:               spamPool,
+		startAt:                time.Now(),
+		scenarioCounter:        scenarioCounter.Phase(benchscore.ScenarioPhaseLoad),
+		timeoutCounter:         score.NewScore(ctx),
+		violateCh:              make(chan error, 1),
+		failFast:               failFast,
+		profile:                profile,
+		pacers:                 pacers,
+	}
+}
+
+func (b *benchmarker) TimeoutCounter() score.ScoreTable {
+	return b.timeoutCounter.Breakdown()
+}
+
+// PriorityStats は、検証のシナリオを優先するため、スループットのワーカーの起動を譲った回数と時間を返します
+func (b *benchmarker) PriorityStats() priority.Stats {
+	return b.workerPriority.Stats()
+}
+
+// runScenario は、シナリオごとのタイムアウトを設定してシナリオを実行します
+// 走行時間の終了ではなくシナリオのタイムアウトで打ち切られた場合、ワーカーを占有していたとして記録します
+func (b *benchmarker) runScenario(ctx context.Context, tag score.ScoreTag, fn func(ctx context.Context) error) error {
+	// NOTE: クラッシュ時に実行中だったイテレーションを復元できるよう、開始と終了をジャーナルに記録する
+	ctx, it := journal.Begin(ctx, string(tag))
+	if it != nil {
+		it.Note(journal.NoteSeed, strconv.FormatInt(journal.IterationSeed(benchrand.Seed(), it.ID()), 10))
+	}
+	// NOTE: シナリオの1回の実行を1つのトレースとし、リクエストや名前解決を子のスパンとして記録する
+	ctx, span := tracing.Start(ctx, "scenario "+string(tag), tracing.KindInternal)
+	span.SetAttribute("scenario", string(tag))
+
+	// NOTE: 平準化のための待ち時間は、シナリオのタイムアウトに含めない
+	if err := b.pacers[tag].Wait(ctx); err != nil {
+		it.End(err)
+		span.End(err)
+		return err
+	}
+
+	timeout, ok := scenarioTimeouts[tag]
+	if !ok {
+		err := fn(ctx)
+		b.markSkipped(tag, err)
+		it.End(err)
+		span.End(err)
+		return err
+	}
+
+	if scenarioClass(tag) == priority.ClassVerification {
+		end := b.workerPriority.BeginVerification(time.Now(), timeout)
+		defer func() { end(time.Now()) }()
+	}
+
+	scenarioCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	err := fn(scenarioCtx)
+	b.markSkipped(tag, err)
+	if ctx.Err() == nil && errors.Is(scenarioCtx.Err(), context.DeadlineExceeded) {
+		b.timeoutCounter.Add(tag)
+		it.EndTimeout()
+		span.SetAttribute("scenario.timeout", true)
+		span.End(err)
+		return err
+	}
+	it.End(err)
+	span.End(err)
+	return err
+}
+
+// ValidatePacing は、開始レートの指定が既知のシナリオに対するものか検証します
+func ValidatePacing(profile config.LoadProfile) error {
+	for name := range profile.Pacing {
+		if !isLoadScenario(score.ScoreTag(name)) {
+			return fmt.Errorf("開始レートの指定に未知のシナリオが含まれています: %s", name)
+		}
+	}
+	return nil
+}
+
+// ValidateSLOs は、成功率の下限の指定が既知のシナリオに対するものか検証します
+func ValidateSLOs(slos map[score.ScoreTag]float64) error {
+	for tag := range slos {
+		if !isLoadScenario(tag) {
+			return fmt.Errorf("成功率の下限の指定に未知のシナリオが含まれています: %s", tag)
+		}
+	}
+	return nil
+}
+
+// markSkipped は、除外されたエンドポイントによってシナリオを継続できなかった場合、以降のシナリオの実行を止めます
+func (b *benchmarker) markSkipped(tag score.ScoreTag, err error) {
+	if endpoint, ok := coverage.SkippedEndpoint(err); ok {
+		coverage.MarkSkipped(scenarioCoverageTarget(tag), endpoint)
+	}
+}
+
+// skipped は、シナリオが除外されたエンドポイントを利用するため実行しないものであるかを返します
+func (b *benchmarker) skipped(tags ...score.ScoreTag) bool {
+	for _, tag := range tags {
+		if !coverage.IsSkipped(scenarioCoverageTarget(tag)) {
+			return false
+		}
+	}
+	return true
+}
+
+func scenarioCoverageTarget(tag score.ScoreTag) string {
+	return fmt.Sprintf("シナリオ %s", tag)
+}
+
+func (b *benchmarker) runClientProviders(ctx context.Context) {
+	loginFn := func(p *isupipe.ClientPool, sem *semaphore.Weighted, cnt *LoginCounter, parallelFetches int) func(u *scheduler.User) {
+		return func(u *scheduler.User) {
+			go func() {
+				if err := sem.Acquire(ctx, 1); err != nil {
+					return
+				}
+				defer sem.Release(1)
+
+				client, err := isupipe.NewClient(ctx, b.contestantLogger)
+				if err != nil {
+					return
+				}
+				client.SetParallelFetches(parallelFetches)
+
+				if _, err := client.Register(ctx, &isupipe.RegisterRequest{
+					Name:        u.Name,
+					DisplayName: u.DisplayName,
+					Description: u.Description,
+					Password:    u.RawPassword,
+					Theme: isupipe.Theme{
+						DarkMode: true,
+					},
+				}); err != nil {
+					return
+				}
+
+				if err := client.Login(ctx, &isupipe.LoginRequest{
+					Username: u.Name,
+					Password: u.RawPassword,
+				}); err != nil {
+					return
+				}
+
+				icon := scheduler.IconSched.GetRandomIcon()
+				if _, err := client.PostIcon(ctx, &isupipe.PostIconRequest{
+					Image: icon.Image,
+				}); err != nil {
+					return
+				}
+
+				p.Put(ctx, client)
+				cnt.Inc()
+			}()
+		}
+	}
+
+	scheduler.UserScheduler.RangeStreamer(loginFn(b.streamerClientPool, b.streamerLoginSem, b.streamerLoginCounter, 1))
+	// NOTE: 視聴者はブラウザと同様にアイコンを並行して取得する
+	scheduler.UserScheduler.RangeViewer(loginFn(b.viewerClientPool, b.viewerLoginSem, b.viewerLoginCounter, config.ViewerParallelFetches))
+
+	<-b.streamerLoginCounter.WaitUntil(config.NumMustTryLogins)
+	<-b.viewerLoginCounter.WaitUntil(config.NumMustTryLogins)
+}
+
+// dns水責め攻撃につかうhttp client
+func (b *benchmarker) loadAttackHTTPClient() *http.Client {
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			if ip, ok := ctx.Value(config.AttackHTTPClientContextKey).(string); ok {
+				addr = ip
+			}
+			dialer := &net.Dialer{Timeout: 5 * time.Second, LocalAddr: config.LocalAddr(network, addr)}
+			return dialer.DialContext(ctx, network, addr)
+		},
+		TLSHandshakeTimeout: 5 * time.Second,
+		// 複数labelがあるのでskip verify
+		TLSClientConfig:       &tls.Config{InsecureSkipVerify: false},
+		ExpectContinueTimeout: 5 * time.Second,
+		ResponseHeaderTimeout: 5 * time.Second,
+		ForceAttemptHTTP2:     true,
+	}
+	return &http.Client{
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+func (b *benchmarker) loadAttack(ctx context.Context, asize int64, httpClient *http.Client, loadLimiter *rate.Limiter) error {
+	defer b.attackSem.Release(asize)
+
+	defer b.scenarioCounter.Succeed(DnsWaterTortureAttackScenario)
+	if err := scenario.DnsWaterTortureAttackScenario(ctx, httpClient, loadLimiter); err != nil {
+		return err
+	}
+	return nil
+}
+
+var prevNumResolved = int64(0)
+
+func (b *benchmarker) loadAttackCoordinator(ctx context.Context) {
+	ticker := time.NewTicker(2 * time.Second)
+	defer ticker.Stop()
+loop:
+	for {
+		select {
+		case <-ticker.C:
+			set := benchscore.FromContext(ctx)
+			numResolves, numDNSFailed := set.GetByTag(benchscore.DNSResolve), set.GetByTag(benchscore.DNSFailed)
+			failRate := float64(numDNSFailed) / float64(numResolves+numDNSFailed+1)
+			avg := float64(numResolves-prevNumResolved) / 2.0
+			prevNumResolved = numResolves
+			if failRate < 0.01 && avg/float64(b.attackParallelis) > 50.0 {
+				new := int(float64(b.attackParallelis) * 1.5)
+				if new > b.profile.MaxAttackParallelism {
+					new = b.profile.MaxAttackParallelism
+				}
+				if new != b.attackParallelis {
+					b.contestantLogger.Info("DNS水責め負荷が上昇します", zap.Int("parallelis", new))
+					b.attackParallelis = new
+				}
+			}
+		case <-ctx.Done():
+			break loop
+		}
+	}
+}
+
+// runConcurrencyController は、webappの応答状況に応じて基本シナリオのワーカー数を増減させます
+func (b *benchmarker) runConcurrencyController(ctx context.Context) {
+	lgr := zap.S()
+
+	ticker := time.NewTicker(config.AdaptiveInterval)
+	defer ticker.Stop()
+	// NOTE: 負荷走行前のリクエストを含めない
+	adaptive.Snapshot()
+	for {
+		select {
+		case <-ticker.C:
+			w := adaptive.Snapshot()
+			if b.concurrency.Update(w) {
+				lgr.Infof("並列度を変更しました: level=%.1f, workers=%d (requests=%d, error_rate=%.3f, p99=%s)",
+					b.concurrency.Level(), b.concurrency.Workers(), w.Requests, w.ErrorRate(), w.LatencyP99)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// runFailFastWatcher は、エラーの予算を超えた時点で打ち切る条件を通知します
+func (b *benchmarker) runFailFastWatcher(ctx context.Context, exceededCh chan<- *failfast.Exceeded) {
+	ticker := time.NewTicker(config.FailFastInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			requests, serverErrors := failfast.Responses()
+			counts := failfast.Counts{
+				Requests:     requests,
+				ServerErrors: serverErrors,
+			}
+			// NOTE: ベンチマーカー内部のエラーは選手の責任ではないため含めない
+			for category, n := range bencherror.FromContext(ctx).CategoryCounts() {
+				if category != bencherror.CategoryInternal {
+					counts.Errors += n
+				}
+			}
+			if exceeded := b.failFast.Check(counts, time.Since(b.startAt)); exceeded != nil {
+				exceededCh <- exceeded
+				return
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (b *benchmarker) loadStreamer(ctx context.Context) error {
+	defer b.streamerSem.Release(1)
+
+	if err := b.runScenario(ctx, BasicStreamerColdReserve, func(ctx context.Context) error {
+		return scenario.BasicStreamerColdReserveScenario(ctx, b.contestantLogger, b.streamerClientPool, b.livestreamPool)
+	}); err != nil {
+		b.scenarioCounter.Fail(BasicStreamerColdReserve)
+		return err
+	}
+	b.scenarioCounter.Succeed(BasicStreamerColdReserve)
+
+	return nil
+}
+
+// moderateが成功するなら可能な限り高速にmoderationしなければならない
+func (b *benchmarker) loadModerator(ctx context.Context) error {
+	defer b.moderatorSem.Release(1)
+
+	if err := b.runScenario(ctx, BasicStreamerModerateScenario, func(ctx context.Context) error {
+		return scenario.BasicStreamerModerateScenario(ctx, b.contestantLogger, b.streamerClientPool)
+	}); err != nil {
+		b.scenarioCounter.Fail(BasicStreamerModerateScenario)
+		return err
+	}
+	b.scenarioCounter.Succeed(BasicStreamerModerateScenario)
+	return nil
+}
+
+func (b *benchmarker) loadViewer(ctx context.Context) error {
+	defer b.viewerSem.Release(1)
+
+	if err := b.runScenario(ctx, BasicViewerScenario, func(ctx context.Context) error {
+		return scenario.BasicViewerScenario(ctx, b.contestantLogger, b.viewerClientPool, b.livestreamPool)
+	}); err != nil {
+		b.scenarioCounter.Fail(BasicViewerScenario)
+		return err
+	}
+	b.scenarioCounter.Succeed(BasicViewerScenario)
+	return nil
+}
+
+func (b *benchmarker) loadViewerReport(ctx context.Context) error {
+	defer b.viewerReportSem.Release(1)
+
+	time.Sleep(1 * time.Second) // XXX: report回りすぎ抑止
+	if err := b.runScenario(ctx, BasicViewerReportScenario, func(ctx context.Context) error {
+		return scenario.BasicViewerReportScenario(ctx, b.contestantLogger, b.viewerClientPool, b.spamPool)
+	}); err != nil {
+		b.scenarioCounter.Fail(BasicViewerReportScenario)
+		return err
+	}
+	b.scenarioCounter.Succeed(BasicViewerReportScenario)
+	return nil
+}
+
+func (b *benchmarker) loadSpammer(ctx context.Context) error {
+	defer b.spammerSem.Release(1)
+
+	var spammerGrp sync.WaitGroup
+
+	spammerGrp.Add(1)
+	go func() {
+		defer spammerGrp.Done()
+		if err := b.runScenario(ctx, ViewerSpamScenario, func(ctx context.Context) error {
+			return scenario.ViewerSpamScenario(ctx, b.contestantLogger, b.viewerClientPool, b.livestreamPool, b.spamPool)
+		}); err != nil {
+			b.scenarioCounter.Fail(ViewerSpamScenario)
+			return
+		}
+		b.scenarioCounter.Succeed(ViewerSpamScenario)
+	}()
+
+	spammerGrp.Add(1)
+	go func() {
+		defer spammerGrp.Done()
+		if err := b.runScenario(ctx, AggressiveStreamerModerateScenario, func(ctx context.Context) error {
+			return scenario.AggressiveStreamerModerateScenario(ctx, b.contestantLogger, b.streamerClientPool)
+		}); err != nil {
+			b.scenarioCounter.Fail(AggressiveStreamerModerateScenario)
+			return
+		}
+		b.scenarioCounter.Succeed(AggressiveStreamerModerateScenario)
+	}()
+
+	spammerGrp.Wait()
+
+	return nil
+}
+
+// 予約枠の一斉予約でダブルブッキングが起きないか検証する
+func (b *benchmarker) loadReservationCollision(ctx context.Context) error {
+	defer b.collisionSem.Release(1)
+
+	time.Sleep(3 * time.Second) // NOTE: 衝突検証のたびに区間を1つ埋め尽くすので、頻度を抑える
+	if err := b.runScenario(ctx, ReservationCollisionScenario, func(ctx context.Context) error {
+		return scenario.ReservationCollisionScenario(ctx, b.contestantLogger, b.streamerClientPool, b.livestreamPool)
+	}); err != nil {
+		b.scenarioCounter.Fail(ReservationCollisionScenario)
+		if errors.Is(err, scenario.ErrDoubleBooking) {
+			select {
+			case b.violateCh <- disqualify.Wrap(disqualify.DoubleBooking, err):
+			default:
+			}
+		}
+		return err
+	}
+	b.scenarioCounter.Succeed(ReservationCollisionScenario)
+	return nil
+}
+
+// 人気配信に視聴者が殺到した状況で、書き込みをさばけるか検証する
+func (b *benchmarker) loadRaid(ctx context.Context) error {
+	defer b.raidSem.Release(1)
+
+	time.Sleep(config.RaidInterval)
+	if err := b.runScenario(ctx, RaidScenario, func(ctx context.Context) error {
+		return scenario.RaidScenario(ctx, b.contestantLogger, b.viewerClientPool, b.livestreamPool)
+	}); err != nil {
+		b.scenarioCounter.Fail(RaidScenario)
+		return err
+	}
+	b.scenarioCounter.Succeed(RaidScenario)
+	return nil
+}
+
+// 配信の順位変動が、ベンチマーカーの書き込みで説明できるか検証する
+func (b *benchmarker) loadRankingStability(ctx context.Context) error {
+	defer b.rankingSem.Release(1)
+
+	if err := b.runScenario(ctx, RankingStabilityScenario, func(ctx context.Context) error {
+		return scenario.RankingStabilityScenario(ctx, b.contestantLogger, b.viewerClientPool, b.livestreamPool)
+	}); err != nil {
+		b.scenarioCounter.Fail(RankingStabilityScenario)
+		return err
+	}
+	b.scenarioCounter.Succeed(RankingStabilityScenario)
+	return nil
+}
+
+// NGワードの登録が、猶予時間内にライブコメントへ反映されるか検証する
+func (b *benchmarker) loadModerationEffectiveness(ctx context.Context) error {
+	defer b.moderationSem.Release(1)
+
+	time.Sleep(config.ModerationCheckInterval)
+	if err := b.runScenario(ctx, ModerationEffectivenessScenario, func(ctx context.Context) error {
+		return scenario.ModerationEffectivenessScenario(ctx, b.contestantLogger, b.streamerClientPool, b.viewerClientPool)
+	}); err != nil {
+		b.scenarioCounter.Fail(ModerationEffectivenessScenario)
+		return err
+	}
+	b.scenarioCounter.Succeed(ModerationEffectivenessScenario)
+	return nil
+}
+
+// 連投したリアクションが、猶予時間内に一覧と統計情報に反映されるか検証する
+func (b *benchmarker) loadReactionBurst(ctx context.Context) error {
+	defer b.reactionBurstSem.Release(1)
+
+	time.Sleep(config.ReactionBurstInterval)
+	if err := b.runScenario(ctx, ReactionBurstScenario, func(ctx context.Context) error {
+		return scenario.ReactionBurstScenario(ctx, b.contestantLogger, b.viewerClientPool, b.livestreamPool)
+	}); err != nil {
+		b.scenarioCounter.Fail(ReactionBurstScenario)
+		return err
+	}
+	b.scenarioCounter.Succeed(ReactionBurstScenario)
+	return nil
+}
+
+// チップの境界値や不正な値を、webappが仕様どおりに受理・拒否するか検証する
+func (b *benchmarker) loadTipRecording(ctx context.Context) error {
+	defer b.tipRecordingSem.Release(1)
+
+	time.Sleep(config.TipRecordingInterval)
+	if err := b.runScenario(ctx, TipRecordingScenario, func(ctx context.Context) error {
+		return scenario.TipRecordingScenario(ctx, b.contestantLogger, b.streamerClientPool, b.viewerClientPool)
+	}); err != nil {
+		b.scenarioCounter.Fail(TipRecordingScenario)
+		// NOTE: チップの記録の誤りは売上の台帳を壊すため、ダブルブッキングと同様に走行を中断する
+		if errors.Is(err, scenario.ErrTipRecording) {
+			select {
+			case b.violateCh <- disqualify.Wrap(disqualify.TipRecording, err):
+			default:
+			}
+		}
+		return err
+	}
+	b.scenarioCounter.Succeed(TipRecordingScenario)
+	return nil
+}
+
+// 新規登録したユーザのサブドメインが、猶予時間内に名前解決できるか検証する
+func (b *benchmarker) loadUserRegistration(ctx context.Context) error {
+	defer b.registrationSem.Release(1)
+
+	time.Sleep(config.UserRegistrationInterval)
+	if err := b.runScenario(ctx, UserRegistrationScenario, func(ctx context.Context) error {
+		return scenario.UserRegistrationScenario(ctx, b.contestantLogger)
+	}); err != nil {
+		b.scenarioCounter.Fail(UserRegistrationScenario)
+		return err
+	}
+	b.scenarioCounter.Succeed(UserRegistrationScenario)
+	return nil
+}
+
+// 走行中ずっとログインしたままの配信者の統計情報が、一貫して推移するか検証する
+func (b *benchmarker) loadLongSessionStreamer(ctx context.Context) error {
+	defer b.longSessionSem.Release(1)
+
+	if err := b.runScenario(ctx, LongSessionStreamerScenario, func(ctx context.Context) error {
+		return scenario.LongSessionStreamerScenario(ctx, b.contestantLogger, b.streamerClientPool)
+	}); err != nil {
+		b.scenarioCounter.Fail(LongSessionStreamerScenario)
+		// NOTE: 検証に失敗した場合は別の配信者でやり直すが、失敗し続ける場合に問い合わせが集中しないよう間隔をあける
+		time.Sleep(config.LongSessionPollInterval)
+		return err
+	}
+	b.scenarioCounter.Succeed(LongSessionStreamerScenario)
+	return nil
+}
+
+// 書き込みが続く中で、複数の配信者の順位を同時に取得し、スコアの大小と整合するか検証する
+func (b *benchmarker) loadRankingConsistency(ctx context.Context) error {
+	defer b.consistencySem.Release(1)
+
+	time.Sleep(config.RankingConsistencyInterval)
+	if err := b.runScenario(ctx, RankingConsistencyScenario, func(ctx context.Context) error {
+		return scenario.RankingConsistencyScenario(ctx, b.contestantLogger, b.viewerClientPool, b.livestreamPool)
+	}); err != nil {
+		b.scenarioCounter.Fail(RankingConsistencyScenario)
+		return err
+	}
+	b.scenarioCounter.Succeed(RankingConsistencyScenario)
+	return nil
+}
+
+// 同じNGワードを同時・連続に登録し、登録が冪等に扱われるか検証する
+func (b *benchmarker) loadNgWordIdempotency(ctx context.Context) error {
+	defer b.ngWordSem.Release(1)
+
+	time.Sleep(config.NgWordIdempotencyInterval)
+	if err := b.runScenario(ctx, NgWordIdempotencyScenario, func(ctx context.Context) error {
+		return scenario.NgWordIdempotencyScenario(ctx, b.contestantLogger, b.streamerClientPool)
+	}); err != nil {
+		b.scenarioCounter.Fail(NgWordIdempotencyScenario)
+		return err
+	}
+	b.scenarioCounter.Succeed(NgWordIdempotencyScenario)
+	return nil
+}
+
+func (b *benchmarker) run(ctx context.Context) error {
+	lgr := zap.S()
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	childCtx, cancelChildCtx := context.WithCancel(ctx)
+	defer cancelChildCtx()
+
+	b.runClientProviders(ctx)
+
+	// NOTE: ダブルブッキングのみ走行を中断する。全体のチェックはとめておく bencherror.RunViolationChecker(ctx)
+	violateCh := b.violateCh
+
+	loadAttackHTTPClient := b.loadAttackHTTPClient()
+	loadAttackLimiter := rate.NewLimiter(rate.Limit(b.profile.AttackQPS), 1)
+	go func() { b.loadAttackCoordinator(ctx) }()
+	if b.concurrency != nil {
+		go func() { b.runConcurrencyController(ctx) }()
+	}
+	failFastCh := make(chan *failfast.Exceeded, 1)
+	if b.failFast.Enabled() {
+		go func() { b.runFailFastWatcher(ctx, failFastCh) }()
+	}
+	if !config.Sealed {
+		// NOTE: 封印モードでは途中経過を通知しない
+		go func() { b.runCheckpointReporter(ctx) }()
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			b.contestantLogger.Info("ベンチマーク走行を停止します")
+			return nil
+		case err := <-violateCh:
+			if b.waiveViolation != nil && b.waiveViolation(err) {
+				lgr.Warnf("仕様違反エラー (練習モードのため継続): %s", err.Error())
+				continue
+			}
+			b.contestantLogger.Warn("仕様違反が検出されたため、ベンチマーク走行を中断します")
+			lgr.Warnf("仕様違反エラー: %s", err.Error())
+			return err
+		case exceeded := <-failFastCh:
+			b.contestantLogger.Warn("エラーの予算を超えたため、ベンチマーク走行を中断します", zap.String("condition", exceeded.Error()))
+			lgr.Warnf("fail fast: %s", exceeded.Error())
+			return exceeded
+		default:
+			// NOTE: 検証のシナリオを先に起動し、webappが飽和している間はスループットのシナリオの起動を譲る
+			if ok := !b.skipped(ReservationCollisionScenario) && b.collisionSem.TryAcquire(1); ok {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					b.loadReservationCollision(childCtx)
+				}()
+			}
+			if ok := !b.skipped(RankingStabilityScenario) && b.rankingSem.TryAcquire(1); ok {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					b.loadRankingStability(childCtx)
+				}()
+			}
+			if ok := !b.skipped(ModerationEffectivenessScenario) && b.moderationSem.TryAcquire(1); ok {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					b.loadModerationEffectiveness(childCtx)
+				}()
+			}
+			if ok := !b.skipped(ReactionBurstScenario) && b.reactionBurstSem.TryAcquire(1); ok {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					b.loadReactionBurst(childCtx)
+				}()
+			}
+			if ok := !b.skipped(TipRecordingScenario) && b.tipRecordingSem.TryAcquire(1); ok {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					b.loadTipRecording(childCtx)
+				}()
+			}
+			if ok := !b.skipped(UserRegistrationScenario) && b.registrationSem.TryAcquire(1); ok {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					b.loadUserRegistration(childCtx)
+				}()
+			}
+			if ok := !b.skipped(LongSessionStreamerScenario) && b.longSessionSem.TryAcquire(1); ok {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					b.loadLongSessionStreamer(childCtx)
+				}()
+			}
+			if ok := !b.skipped(RankingConsistencyScenario) && b.consistencySem.TryAcquire(1); ok {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					b.loadRankingConsistency(childCtx)
+				}()
+			}
+			if ok := !b.skipped(NgWordIdempotencyScenario) && b.ngWordSem.TryAcquire(1); ok {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					b.loadNgWordIdempotency(childCtx)
+				}()
+			}
+			if b.workerPriority.AdmitThroughput(time.Now()) {
+				if ok := !b.skipped(BasicStreamerColdReserve) && b.streamerSem.TryAcquire(1); ok {
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						b.loadStreamer(childCtx)
+					}()
+				}
+				// NOTE: 到着を待つ間は視聴者の枠を占有しない
+				if ok := !b.skipped(BasicViewerScenario) && churn.ViewerChurn.TryArrive(func() bool { return b.viewerSem.TryAcquire(1) }); ok {
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						b.loadViewer(childCtx)
+					}()
+				}
+				if ok := !b.skipped(BasicViewerReportScenario) && b.viewerReportSem.TryAcquire(1); ok {
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						b.loadViewerReport(childCtx)
+					}()
+				}
+				if ok := !b.skipped(BasicStreamerModerateScenario) && b.moderatorSem.TryAcquire(1); ok {
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						b.loadModerator(childCtx)
+					}()
+				}
+				if ok := !b.skipped(ViewerSpamScenario, AggressiveStreamerModerateScenario) && b.spammerSem.TryAcquire(1); ok {
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						b.loadSpammer(childCtx)
+					}()
+				}
+				if ok := !b.skipped(RaidScenario) && b.raidSem.TryAcquire(1); ok {
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						b.loadRaid(childCtx)
+					}()
+				}
+			}
+			// NOTE: DNS水責めはwebappのHTTPの処理能力を奪わないため、起動を譲らない
+			asize := int64(512.0 / float64(b.attackParallelis))
+			if ok := b.attackSem.TryAcquire(asize); ok {
+				wg.Add(1)
+				asize := asize
+				go func() {
+					defer wg.Done()
+					b.loadAttack(childCtx, asize, loadAttackHTTPClient, loadAttackLimiter)
+				}()
+			}
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package bench
+
+import (
+	"context"
+	"time"
+
+	"github.com/isucon/isucon13/bench/internal/bencherror"
+	"github.com/isucon/isucon13/bench/internal/benchscore"
+	"github.com/isucon/isucon13/bench/internal/config"
+	"go.uber.org/zap"
+)
+
+// runCheckpointReporter は、走行時間に対する進捗ごとに、その時点のスコアとエラー件数を選手に通知します
+// 選手が自身のモニタリングの時系列とベンチマーカーの状況を突き合わせられるようにするためのものです
+func (b *benchmarker) runCheckpointReporter(ctx context.Context) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return
+	}
+	total := deadline.Sub(b.startAt)
+
+	for _, percentage := range config.CheckpointPercentages {
+		at := b.startAt.Add(total * time.Duration(percentage) / 100)
+		timer := time.NewTimer(time.Until(at))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		set := benchscore.FromContext(ctx)
+		scores := set.Snapshot()
+		if config.ScoreFreezeAfter > 0 {
+			// NOTE: 凍結後に得たスコアは、途中経過にも含めない
+			scores = set.SnapshotBefore(config.ScoreFreezeAfter)
+		}
+		errorCounts := bencherror.FromContext(ctx).CategoryCounts()
+		b.contestantLogger.Info("途中経過",
+			zap.Int("progress_percentage", percentage),
+			zap.Int64("score", scores.Score),
+			zap.Int64("validation_errors", errorCounts[bencherror.CategoryValidation]),
+			zap.Int64("timeout_errors", errorCounts[bencherror.CategoryTimeout]),
+			zap.Int64("penalty_errors", errorCounts[bencherror.CategoryPenalty]),
+		)
+	}
+}
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/isucon/isucon13/bench"
+	"github.com/isucon/isucon13/bench/internal/bencherror"
+	"github.com/isucon/isucon13/bench/internal/benchscore"
+	"github.com/isucon/isucon13/bench/internal/config"
+	"github.com/isucon/isucon13/bench/internal/logger"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+var (
+	errRunNotStarted   = errors.New("負荷走行中ではありません")
+	errExtensionLimit  = fmt.Errorf("走行時間の延長は合計 %s までです", config.MaxBenchmarkExtension)
+	errInvalidDuration = errors.New("延長時間は正の値で指定してください")
+)
+
+// runController は、走行中のベンチマークの段階と負荷走行の締切を管理します
+// NOTE: 管理APIから、負荷走行の早期終了と延長を行えるようにするためのものです
+type runController struct {
+	mu sync.Mutex
+
+	phase    bench.Phase
+	startAt  time.Time
+	deadline time.Time
+	extended time.Duration
+	stopped  bool
+
+	timer  *time.Timer
+	cancel context.CancelFunc
+	// 負荷走行のスコアとエラーの集計
+	scores *benchscore.ScoreSet
+	errs   *bencherror.ErrorSet
+}
+
+var runCtl = &runController{phase: bench.PhaseInitialize}
+
+func (c *runController) SetPhase(phase bench.Phase) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.phase = phase
+}
+
+// StartLoad は、負荷走行のcontextを返します。締切はtimeout後ですが、走行中に延長・早期終了できます
+func (c *runController) StartLoad(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	loadCtx, cancel := context.WithCancel(ctx)
+	c.phase = bench.PhaseLoad
+	c.startAt = time.Now()
+	c.deadline = c.startAt.Add(timeout)
+	c.cancel = cancel
+	c.timer = time.AfterFunc(timeout, cancel)
+	c.scores = benchscore.FromContext(ctx)
+	c.errs = bencherror.FromContext(ctx)
+
+	return &loadContext{Context: loadCtx, ctl: c}, cancel
+}
+
+// Deadline は、延長を反映した負荷走行の締切を返します
+func (c *runController) Deadline() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.deadline
+}
+
+// extend は、負荷走行の締切を延長し、延長後の締切を返します
+func (c *runController) extend(d time.Duration) (time.Time, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if d <= 0 {
+		return time.Time{}, errInvalidDuration
+	}
+	if c.phase != bench.PhaseLoad || c.stopped {
+		return time.Time{}, errRunNotStarted
+	}
+	if c.extended+d > config.MaxBenchmarkExtension {
+		return time.Time{}, errExtensionLimit
+	}
+	// NOTE: タイマーが既に発火していれば、負荷走行は終了している
+	if !c.timer.Stop() {
+		return time.Time{}, errRunNotStarted
+	}
+
+	c.extended += d
+	c.deadline = c.deadline.Add(d)
+	c.timer.Reset(time.Until(c.deadline))
+	return c.deadline, nil
+}
+
+// stop は、負荷走行を早期終了します。以降は通常通り最終チェックに進みます
+func (c *runController) stop() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.phase != bench.PhaseLoad || c.stopped {
+		return errRunNotStarted
+	}
+	c.stopped = true
+	c.timer.Stop()
+	c.cancel()
+	return nil
+}
+
+type runStatus struct {
+	Phase       bench.Phase                   `json:"phase"`
+	StartedAt   *time.Time                    `json:"started_at,omitempty"`
+	Deadline    *time.Time                    `json:"deadline,omitempty"`
+	Extended    string                        `json:"extended"`
+	Stopped     bool                          `json:"stopped"`
+	Profit      uint64                        `json:"profit"`
+	Scores      *benchscore.ScoreSnapshot     `json:"scores,omitempty"`
+	ErrorCounts map[bencherror.Category]int64 `json:"error_counts"`
+	LogLevel    string                        `json:"log_level"`
+}
+
+func (c *runController) status() *runStatus {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	status := &runStatus{
+		Phase:    c.phase,
+		Extended: c.extended.String(),
+		Stopped:  c.stopped,
+		LogLevel: logger.StaffLevel.String(),
+	}
+	if !c.startAt.IsZero() {
+		startAt, deadline := c.startAt, c.deadline
+		status.StartedAt = &startAt
+		status.Deadline = &deadline
+	}
+	// NOTE: カウンタやエラーは負荷走行の開始時に初期化されるため、それ以前は集計しない
+	if c.phase != bench.PhaseInitialize && c.phase != bench.PhasePretest {
+		if c.scores != nil {
+			status.Scores = c.scores.Snapshot()
+			status.Profit = status.Scores.Profit
+		}
+		if c.errs != nil {
+			status.ErrorCounts = c.errs.CategoryCounts()
+		}
+	}
+	return status
+}
+
+// loadContext は、締切の延長を反映するcontextです
+type loadContext struct {
+	context.Context
+	ctl *runController
+}
+
+func (c *loadContext) Deadline() (time.Time, bool) {
+	return c.ctl.Deadline(), true
+}
+
+// newAdminHandler は、スタッフ向けの管理APIのハンドラを返します
+//
+//	GET  /status                  走行の段階、締切、売上、エラー件数
+//	PUT  /log-level?level=debug   スタッフ向けログの出力レベルの変更
+//	POST /stop                    負荷走行の早期終了
+//	POST /extend?duration=30s     負荷走行の延長
+func newAdminHandler(ctl *runController) http.Handler {
+	lgr := zap.S()
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/status", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		writeAdminJSON(w, http.StatusOK, ctl.status())
+	})
+
+	mux.HandleFunc("/log-level", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		level, err := zapcore.ParseLevel(r.URL.Query().Get("level"))
+		if err != nil {
+			writeAdminError(w, http.StatusBadRequest, err)
+			return
+		}
+		logger.StaffLevel.SetLevel(level)
+		lgr.Warnf("管理APIからログレベルが %s に変更されました", level)
+		writeAdminJSON(w, http.StatusOK, ctl.status())
+	})
+
+	mux.HandleFunc("/stop", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if err := ctl.stop(); err != nil {
+			writeAdminError(w, http.StatusConflict, err)
+			return
+		}
+		lgr.Warn("管理APIから負荷走行の早期終了が指示されました")
+		writeAdminJSON(w, http.StatusOK, ctl.status())
+	})
+
+	mux.HandleFunc("/extend", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		d, err := time.ParseDuration(r.URL.Query().Get("duration"))
+		if err != nil {
+			writeAdminError(w, http.StatusBadRequest, err)
+			return
+		}
+		deadline, err := ctl.extend(d)
+		if err != nil {
+			status := http.StatusConflict
+			if errors.Is(err, errInvalidDuration) {
+				status = http.StatusBadRequest
+			}
+			writeAdminError(w, status, err)
+			return
+		}
+		lgr.Warnf("管理APIから負荷走行が %s 延長されました (締切: %s)", d, deadline.Format(time.RFC3339))
+		writeAdminJSON(w, http.StatusOK, ctl.status())
+	})
+
+	return mux
+}
+
+func writeAdminJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeAdminError(w http.ResponseWriter, status int, err error) {
+	writeAdminJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// startAdminServer は、--admin-addr が指定されていれば管理APIを起動し、停止する関数を返します
+func startAdminServer(ctl *runController) (func(), error) {
+	if len(config.AdminAddr) == 0 {
+		return func() {}, nil
+	}
+
+	ln, err := net.Listen("tcp", config.AdminAddr)
+	if err != nil {
+		return nil, fmt.Errorf("管理APIを起動できません: %w", err)
+	}
+
+	server := &http.Server{
+		Handler:           newAdminHandler(ctl),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	go func() {
+		if err := server.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			zap.S().Warnf("管理APIが停止しました: %s", err.Error())
+		}
+	}()
+	zap.S().Infof("管理APIを起動しました: %s", ln.Addr())
+
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		defer cancel()
+		server.Shutdown(ctx)
+	}, nil
+}
@@ -5,69 +5,72 @@ import (
 	"encoding/json"
 	"fmt"
 	"net"
+	"net/http"
 	"net/url"
 	"os"
+	"path/filepath"
 	"slices"
 	"strconv"
-	"strings"
 	"time"
 
-	"github.com/urfave/cli"
+	"github.com/urfave/cli/v2"
 	"go.uber.org/zap"
 
-	"github.com/isucon/isucandar/agent"
-	"github.com/isucon/isucandar/score"
-	"github.com/isucon/isucon13/bench/internal/bencherror"
+	"github.com/isucon/isucon13/bench"
 	"github.com/isucon/isucon13/bench/internal/benchscore"
 	"github.com/isucon/isucon13/bench/internal/config"
 	"github.com/isucon/isucon13/bench/internal/logger"
-	"github.com/isucon/isucon13/bench/internal/resolver"
-	"github.com/isucon/isucon13/bench/isupipe"
+	"github.com/isucon/isucon13/bench/internal/redact"
+	"github.com/isucon/isucon13/bench/internal/report"
+	"github.com/isucon/isucon13/bench/internal/scheduler"
+	"github.com/isucon/isucon13/bench/internal/tracing"
+	"github.com/isucon/isucon13/bench/internal/version"
 	"github.com/isucon/isucon13/bench/scenario"
 )
 
 var assetDir string
 
+var scoreConfigPath string
+
 var enableSSL bool
 var pretestOnly bool
 
-type BenchResult struct {
-	Pass          bool     `json:"pass"`
-	Score         int64    `json:"score"`
-	Messages      []string `json:"messages"`
-	Language      string   `json:"language"`
-	ResolvedCount int64    `json:"resolved_count"`
-}
+// sendResult は、走行結果を結果ファイルと extra に書き出し、--report-url が指定されていればポータルにもPOSTします
+// NOTE: 結果ファイルに書き出せていれば、ポータルへのPOSTに失敗しても警告に留め、エラーを返さない
+func sendResult(ctx context.Context, result []byte, extra ...report.Reporter) error {
+	lgr := zap.S()
 
-// UniqueMsgs は重複除去したメッセージ配列を返します
-func uniqueMsgs(msgs []string) (uniqMsgs []string) {
-	dedup := map[string]struct{}{}
-	for _, msg := range msgs {
-		if _, ok := dedup[msg]; ok {
-			continue
-		}
-		dedup[msg] = struct{}{}
-		uniqMsgs = append(uniqMsgs, msg)
+	reporters := []report.Reporter{&report.FileReporter{Path: config.ResultPath}}
+	reporters = append(reporters, extra...)
+	if err := report.Send(ctx, result, reporters...); err != nil {
+		return err
+	}
+
+	if len(config.ReportURL) == 0 {
+		return nil
+	}
+	portal := &report.HTTPReporter{
+		URL:           config.ReportURL,
+		SigningKey:    config.ResultSigningKey,
+		Retries:       config.ReportRetries,
+		RetryInterval: config.ReportRetryInterval,
+		Client:        &http.Client{Timeout: config.ReportTimeout},
+	}
+	if err := report.Send(ctx, result, portal); err != nil {
+		lgr.Warnf("ポータルへの走行結果の送信に失敗しました. 結果は %s に書き出しています: %+v", config.ResultPath, err)
 	}
-	return
+	return nil
 }
 
-func dumpFailedResult(msgs []string) {
+// dumpFailedResult は、失格とした走行の結果を書き出します
+func dumpFailedResult(result *bench.Result) {
 	lgr := zap.S()
 
-	messages := []string{}
-	messages = append(messages, msgs...)
-	for _, errs := range bencherror.GetFinalBenchErrors() {
-		messages = append(messages, errs...)
+	msgs := result.Messages
+	if err := signResult(result); err != nil {
+		lgr.Warnf("失格判定結果の署名に失敗. 運営に連絡してください: messages=%+v, err=%+v", msgs, err)
 	}
-	messages = uniqueMsgs(messages)
-
-	b, err := json.Marshal(&BenchResult{
-		Pass:     false,
-		Score:    0,
-		Messages: messages,
-		Language: config.Language,
-	})
+	b, err := json.Marshal(result)
 	if err != nil {
 		lgr.Warnf("失格判定結果書き出しに失敗. 運営に連絡してください: messages=%+v, err=%+v", msgs, err)
 		fmt.Printf(`{"pass": false, "score": 0, "messages": ["%s"]}`, string(b))
@@ -75,88 +78,421 @@ func dumpFailedResult(msgs []string) {
 		return
 	}
 
-	if err := os.WriteFile(config.ResultPath, b, os.ModePerm); err != nil {
+	if err := sendResult(context.Background(), b, report.NewStdoutReporter()); err != nil {
 		lgr.Warnf("失格判定結果書き出しに失敗. 運営に連絡してください: messages=%+v, err=%+v", msgs, err)
-		fmt.Printf(`{"pass": false, "score": 0, "messages": ["%s"]}`, string(b))
-		fmt.Println("")
+	}
+}
+
+// writePretestReport は、--pretest-reportが指定されている場合にpretestの結果を書き出します
+func writePretestReport(report *scenario.PretestReport) {
+	lgr := zap.S()
+
+	if len(config.PretestReportPath) == 0 || report == nil {
+		return
 	}
 
-	fmt.Println(string(b))
+	b, err := json.Marshal(report)
+	if err != nil {
+		lgr.Warnf("pretest結果のエンコードに失敗: %s", err.Error())
+		return
+	}
+	if err := os.WriteFile(config.PretestReportPath, redact.Bytes(b), os.ModePerm); err != nil {
+		lgr.Warnf("pretest結果の書き出しに失敗: %s", err.Error())
+	}
 }
 
-var run = cli.Command{
+var run = &cli.Command{
 	Name:  "run",
 	Usage: "ベンチマーク実行",
+	Description: "SIGINT/SIGTERMを受けると、実行中のシナリオとリクエストを中断します\n" +
+		"中断までの走行結果は --result-path などの出力先に書き出します",
 	Flags: []cli.Flag{
-		cli.StringFlag{
+		&cli.StringFlag{
 			Name:        "target",
 			Value:       fmt.Sprintf("http://pipe.u.isucon.dev:%d", config.TargetPort),
 			Destination: &config.TargetBaseURL,
-			EnvVar:      "BENCH_TARGET_URL",
+			EnvVars:     []string{"BENCH_TARGET_URL"},
 		},
-		cli.StringFlag{
+		&cli.StringFlag{
 			Name:        "nameserver",
 			Value:       "127.0.0.1",
 			Destination: &config.TargetNameserver,
-			EnvVar:      "BENCH_NAMESERVER",
+			EnvVars:     []string{"BENCH_NAMESERVER"},
 		},
-		cli.StringSliceFlag{
+		&cli.StringSliceFlag{
 			Name: "webapp",
 		},
-		cli.IntFlag{
+		&cli.Float64Flag{
+			Name:        "initialize-scale",
+			Destination: &config.InitializeScale,
+			EnvVars:     []string{"BENCH_INITIALIZE_SCALE"},
+		},
+		&cli.StringFlag{
+			Name:        "initialize-features",
+			Destination: &config.InitializeFeatures,
+			EnvVars:     []string{"BENCH_INITIALIZE_FEATURES"},
+		},
+		&cli.StringFlag{
+			Name:        "proxy",
+			Destination: &config.ProxyURL,
+			EnvVars:     []string{"BENCH_PROXY"},
+		},
+		&cli.StringFlag{
+			Name:        "targets",
+			Destination: &config.TargetsPath,
+			EnvVars:     []string{"BENCH_TARGETS"},
+		},
+		&cli.IntFlag{
 			Name:        "dns-port",
 			Value:       53,
 			Destination: &config.DNSPort,
-			EnvVar:      "BENCH_DNS_PORT",
+			EnvVars:     []string{"BENCH_DNS_PORT"},
 		},
-		cli.StringFlag{
+		&cli.StringFlag{
 			Name:        "assetdir",
 			Value:       "assets/testdata",
 			Destination: &assetDir,
-			EnvVar:      "BENCH_ASSETDIR",
+			EnvVars:     []string{"BENCH_ASSETDIR"},
 		},
-		cli.StringFlag{
+		&cli.StringFlag{
 			Name:        "staff-log-path",
 			Destination: &config.StaffLogPath,
-			EnvVar:      "BENCH_STAFF_LOG_PATH",
+			EnvVars:     []string{"BENCH_STAFF_LOG_PATH"},
 			Value:       "/tmp/staff.log",
 		},
-		cli.StringFlag{
+		&cli.StringFlag{
 			Name:        "contestant-log-path",
 			Destination: &config.ContestantLogPath,
-			EnvVar:      "BENCH_CONTESTANT_LOG_PATH",
+			EnvVars:     []string{"BENCH_CONTESTANT_LOG_PATH"},
 			Value:       "/tmp/contestant.log",
 		},
-		cli.StringFlag{
+		&cli.BoolFlag{
+			Name:        "json-logs",
+			Destination: &config.JSONLogs,
+			EnvVars:     []string{"BENCH_JSON_LOGS"},
+		},
+		&cli.StringFlag{
+			Name:        "contestant-log-level",
+			Value:       config.DefaultContestantLogLevel,
+			Destination: &config.ContestantLogLevel,
+			EnvVars:     []string{"BENCH_CONTESTANT_LOG_LEVEL"},
+		},
+		&cli.StringFlag{
+			Name:        "internal-hosts",
+			Destination: &config.InternalHosts,
+			EnvVars:     []string{"BENCH_INTERNAL_HOSTS"},
+		},
+		&cli.StringFlag{
 			Name:        "result-path",
 			Destination: &config.ResultPath,
-			EnvVar:      "BENCH_RESULT_PATH",
+			EnvVars:     []string{"BENCH_RESULT_PATH"},
 			Value:       "/tmp/result.json",
 		},
-		cli.BoolFlag{
+		&cli.StringFlag{
+			Name:        "score-config",
+			Destination: &scoreConfigPath,
+			EnvVars:     []string{"BENCH_SCORE_CONFIG"},
+		},
+		&cli.StringFlag{
+			Name:        "pretest-report",
+			Destination: &config.PretestReportPath,
+			EnvVars:     []string{"BENCH_PRETEST_REPORT_PATH"},
+		},
+		&cli.StringFlag{
+			Name:        "icon-comparator",
+			Value:       config.IconComparator,
+			Destination: &config.IconComparator,
+			EnvVars:     []string{"BENCH_ICON_COMPARATOR"},
+		},
+		&cli.StringFlag{
+			Name:        "timeout-tiers",
+			Destination: &config.TimeoutTiersSpec,
+			EnvVars:     []string{"BENCH_TIMEOUT_TIERS"},
+		},
+		&cli.StringFlag{
+			Name:        "viewer-arrival",
+			Value:       config.ViewerArrivalDistribution,
+			Destination: &config.ViewerArrivalDistribution,
+			EnvVars:     []string{"BENCH_VIEWER_ARRIVAL"},
+		},
+		&cli.StringFlag{
+			Name:        "viewer-session",
+			Value:       config.ViewerSessionDistribution,
+			Destination: &config.ViewerSessionDistribution,
+			EnvVars:     []string{"BENCH_VIEWER_SESSION"},
+		},
+		&cli.BoolFlag{
+			Name:        "viewer-departures",
+			Destination: &config.ViewerDepartures,
+			EnvVars:     []string{"BENCH_VIEWER_DEPARTURES"},
+		},
+		&cli.IntFlag{
+			Name:        "viewer-abandon-window",
+			Value:       config.ViewerAbandonWindow,
+			Destination: &config.ViewerAbandonWindow,
+			EnvVars:     []string{"BENCH_VIEWER_ABANDON_WINDOW"},
+		},
+		&cli.StringFlag{
+			Name:        "metrics-listen",
+			Destination: &config.MetricsListenAddr,
+			EnvVars:     []string{"BENCH_METRICS_LISTEN"},
+		},
+		&cli.StringFlag{
+			Name:        "profile",
+			Value:       config.LoadProfileName,
+			Destination: &config.LoadProfileName,
+			EnvVars:     []string{"BENCH_PROFILE"},
+		},
+		&cli.StringFlag{
+			Name:        "endpoint-filter",
+			Destination: &config.EndpointFilterPath,
+			EnvVars:     []string{"BENCH_ENDPOINT_FILTER"},
+		},
+		&cli.IntFlag{
+			Name:        "viewer-parallel-fetches",
+			Value:       config.DefaultViewerParallelFetches,
+			Destination: &config.ViewerParallelFetches,
+			EnvVars:     []string{"BENCH_VIEWER_PARALLEL_FETCHES"},
+		},
+		&cli.StringFlag{
+			Name:        "idle-probe",
+			Destination: &config.IdleProbePeriods,
+			EnvVars:     []string{"BENCH_IDLE_PROBE"},
+		},
+		&cli.BoolFlag{
+			Name:        "slow-loris",
+			Destination: &config.SlowLoris,
+			EnvVars:     []string{"BENCH_SLOW_LORIS"},
+		},
+		&cli.BoolFlag{
+			Name:        "chaos",
+			Destination: &config.Chaos,
+			EnvVars:     []string{"BENCH_CHAOS"},
+		},
+		&cli.BoolFlag{
+			Name:        "adaptive-concurrency",
+			Destination: &config.AdaptiveConcurrency,
+			EnvVars:     []string{"BENCH_ADAPTIVE_CONCURRENCY"},
+		},
+		&cli.Int64Flag{
+			Name:        "fail-fast-errors",
+			Destination: &config.FailFastMaxErrors,
+			EnvVars:     []string{"BENCH_FAIL_FAST_ERRORS"},
+		},
+		&cli.Float64Flag{
+			Name:        "fail-fast-5xx-rate",
+			Destination: &config.FailFastMaxServerErrorRate,
+			EnvVars:     []string{"BENCH_FAIL_FAST_5XX_RATE"},
+		},
+		&cli.Float64Flag{
+			Name:        "keep-alive-penalty",
+			Destination: &config.KeepAlivePenalty,
+			EnvVars:     []string{"BENCH_KEEP_ALIVE_PENALTY"},
+		},
+		&cli.BoolFlag{
+			Name:        "calibrate",
+			Destination: &config.Calibrate,
+			EnvVars:     []string{"BENCH_CALIBRATE"},
+		},
+		&cli.StringFlag{
+			Name:        "load-config",
+			Destination: &config.LoadConfigPath,
+			EnvVars:     []string{"BENCH_LOAD_CONFIG"},
+		},
+		&cli.Int64Flag{
+			Name:        "seed",
+			Destination: &config.Seed,
+			EnvVars:     []string{"BENCH_SEED"},
+		},
+		&cli.StringFlag{
+			Name:        "journal",
+			Destination: &config.JournalPath,
+			EnvVars:     []string{"BENCH_JOURNAL"},
+		},
+		&cli.StringFlag{
+			Name:        "admin-addr",
+			Destination: &config.AdminAddr,
+			EnvVars:     []string{"BENCH_ADMIN_ADDR"},
+		},
+		&cli.BoolFlag{
 			Name:        "enable-ssl",
 			Destination: &enableSSL,
-			EnvVar:      "BENCH_ENABLE_SSL",
+			EnvVars:     []string{"BENCH_ENABLE_SSL"},
+		},
+		&cli.IntFlag{
+			Name:        "max-tls-handshakes",
+			Value:       config.MaxConcurrentTLSHandshakes,
+			Destination: &config.MaxConcurrentTLSHandshakes,
+			EnvVars:     []string{"BENCH_MAX_TLS_HANDSHAKES"},
+		},
+		&cli.StringFlag{
+			Name:        "bind-address",
+			Destination: &config.BindAddress,
+			EnvVars:     []string{"BENCH_BIND_ADDRESS"},
+		},
+		&cli.DurationFlag{
+			Name:        "resolve-timeout",
+			Value:       config.DNSResolveTimeout,
+			Destination: &config.DNSResolveTimeout,
+			EnvVars:     []string{"BENCH_RESOLVE_TIMEOUT"},
+		},
+		&cli.BoolFlag{
+			Name:        "dns-cache",
+			Value:       true,
+			Destination: &config.DNSCacheEnabled,
+			EnvVars:     []string{"BENCH_DNS_CACHE"},
+		},
+		&cli.BoolFlag{
+			Name:        "bot-marker",
+			Value:       true,
+			Destination: &config.BotMarkerEnabled,
+			EnvVars:     []string{"BENCH_BOT_MARKER"},
+		},
+		&cli.StringFlag{
+			Name:        "bot-marker-header",
+			Value:       config.BotMarkerHeader,
+			Destination: &config.BotMarkerHeader,
+			EnvVars:     []string{"BENCH_BOT_MARKER_HEADER"},
+		},
+		&cli.StringFlag{
+			Name:        "bot-marker-value",
+			Value:       config.BotMarkerValue,
+			Destination: &config.BotMarkerValue,
+			EnvVars:     []string{"BENCH_BOT_MARKER_VALUE"},
+		},
+		&cli.DurationFlag{
+			Name:        "score-freeze-after",
+			Destination: &config.ScoreFreezeAfter,
+			EnvVars:     []string{"BENCH_SCORE_FREEZE_AFTER"},
+		},
+		&cli.BoolFlag{
+			Name:        "explain-score",
+			Destination: &config.ExplainScore,
+			EnvVars:     []string{"BENCH_EXPLAIN_SCORE"},
+		},
+		&cli.BoolFlag{
+			Name:        "practice",
+			Destination: &config.Practice,
+			EnvVars:     []string{"BENCH_PRACTICE"},
+		},
+		&cli.DurationFlag{
+			Name:        "stats-grace-period",
+			Value:       config.StatsGracePeriod,
+			Destination: &config.StatsGracePeriod,
+			EnvVars:     []string{"BENCH_STATS_GRACE_PERIOD"},
+		},
+		&cli.StringFlag{
+			Name:        "not-before",
+			Destination: &notBefore,
+			EnvVars:     []string{"BENCH_NOT_BEFORE"},
+		},
+		&cli.StringFlag{
+			Name:        "not-after",
+			Destination: &notAfter,
+			EnvVars:     []string{"BENCH_NOT_AFTER"},
+		},
+		&cli.BoolFlag{
+			Name:        "sealed",
+			Destination: &config.Sealed,
+			EnvVars:     []string{"BENCH_SEALED"},
+		},
+		&cli.StringFlag{
+			Name:        "result-signing-key",
+			Destination: &config.ResultSigningKey,
+			EnvVars:     []string{"BENCH_RESULT_SIGNING_KEY"},
 		},
-		cli.BoolFlag{
+		&cli.StringFlag{
+			Name:        "report-url",
+			Destination: &config.ReportURL,
+			EnvVars:     []string{"BENCH_REPORT_URL"},
+		},
+		&cli.StringFlag{
+			Name:        "otlp-endpoint",
+			Destination: &config.OTLPEndpoint,
+			EnvVars:     []string{"BENCH_OTLP_ENDPOINT"},
+		},
+		&cli.BoolFlag{
 			Name:        "pretest-only",
 			Destination: &pretestOnly,
-			EnvVar:      "BENCH_PRETEST_ONLY",
+			EnvVars:     []string{"BENCH_PRETEST_ONLY"},
 		},
 	},
 	Action: func(cliCtx *cli.Context) error {
-		ctx := context.Background()
-		benchscore.InitCounter(ctx)
-		bencherror.InitErrors(ctx)
+		ctx := cliCtx.Context
+		// NOTE: 平文のパスワードを秘匿情報として登録する前に、配信者・視聴者の定義を読み込む
+		populationLoaded, err := scheduler.UserScheduler.LoadPopulation(assetDir)
+		if err != nil {
+			return cli.Exit(err, 1)
+		}
+		corpusChecksum, corpusLoaded, err := scheduler.LivecommentScheduler.LoadSpamCorpus(assetDir)
+		if err != nil {
+			return cli.Exit(err, 1)
+		}
+		redact.Register(config.ResultSigningKey)
+		redact.Register(scheduler.UserScheduler.RawPasswords()...)
 		lgr, err := logger.InitStaffLogger()
 		if err != nil {
-			return cli.NewExitError(err, 1)
+			return cli.Exit(err, 1)
+		}
+		// NOTE: 選手向けログやトレースの設定を封印モードで無効化するため、それらの初期化より前に適用する
+		if err := applySealedMode(cliCtx); err != nil {
+			return cli.Exit(err, 1)
 		}
 
+		// NOTE: 管理APIなどのベンチマーカー内部のアドレスは、選手向けログに書き出さない
+		logger.RegisterInternalHosts(config.AdminAddr, config.MetricsListenAddr, config.BindAddress)
+		if proxyURL, err := url.Parse(config.ProxyURL); err == nil {
+			logger.RegisterInternalHosts(proxyURL.Host)
+		}
 		contestantLogger, err := logger.InitContestantLogger()
 		if err != nil {
-			return cli.NewExitError(err, 1)
+			return cli.Exit(err, 1)
+		}
+
+		lgr.Infof("ベンチマーカー: %s", version.Get())
+		if populationLoaded {
+			lgr.Infof("配信者・視聴者の定義を読み込みました: %s", filepath.Join(assetDir, scheduler.PopulationFileName))
+		}
+		if corpusLoaded {
+			lgr.Infof("スパムコメントの定義を読み込みました: %s (sha256: %s)", filepath.Join(assetDir, scheduler.SpamCorpusFileName), corpusChecksum)
+		}
+
+		if err := tracing.Init(config.OTLPEndpoint); err != nil {
+			return cli.Exit(err, 1)
+		}
+		defer func() {
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			tracing.Shutdown(shutdownCtx)
+		}()
+
+		if err := checkRunWindow(time.Now()); err != nil {
+			lgr.Warn(err.Error())
+			contestantLogger.Warn("走行可能期間外のため、ベンチマーク走行を開始しません")
+			return err
+		}
+
+		stopAdminServer, err := startAdminServer(runCtl)
+		if err != nil {
+			return cli.Exit(err, 1)
 		}
+		defer stopAdminServer()
+
+		stopMetricsServer, err := startMetricsServer(runCtl)
+		if err != nil {
+			return cli.Exit(err, 1)
+		}
+		defer stopMetricsServer()
+
+		if len(scoreConfigPath) > 0 {
+			if err := benchscore.LoadWeights(scoreConfigPath); err != nil {
+				return cli.Exit(err, 1)
+			}
+			lgr.Infof("配点ファイルを読み込みました: %s", scoreConfigPath)
+		}
+
+		// NOTE: --nameserver には [::1] のようにURLのホスト部の表記でIPv6アドレスを指定できる
+		config.TargetNameserver = config.TrimIPBrackets(config.TargetNameserver)
 
 		// Target Webserv
 		webapps := []string{}
@@ -175,15 +511,11 @@ var run = cli.Command{
 				return fmt.Errorf("不正なtaget URLです %w", err)
 			}
 			u.Scheme = "https"
-			if strings.Contains(u.Host, ":") {
-				if h, _, err := net.SplitHostPort(u.Host); err != nil {
-					return fmt.Errorf("不正なtaget URLです %w", err)
-				} else {
-					u.Host = h + ":443"
-				}
-			} else {
-				u.Host = u.Host + ":443"
+			// NOTE: IPv6アドレスのホストも扱えるよう、ポートを除いたホスト名から組み立てる
+			if len(u.Hostname()) == 0 {
+				return fmt.Errorf("不正なtaget URLです: %s", config.TargetBaseURL)
 			}
+			u.Host = net.JoinHostPort(u.Hostname(), "443")
 			config.TargetBaseURL = u.String()
 			contestantLogger.Info("SSL接続が有効になっています")
 		} else {
@@ -193,160 +525,38 @@ var run = cli.Command{
 		lgr.Infof("webapp: %s", config.TargetBaseURL)
 		lgr.Infof("nameserver: %s", net.JoinHostPort(config.TargetNameserver, strconv.Itoa(config.DNSPort)))
 
-		// FIXME: アセット読み込み
-		contestantLogger.Info("静的ファイルチェックを行います")
-		contestantLogger.Info("静的ファイルチェックが完了しました")
-
-		contestantLogger.Info("webappの初期化を行います")
-		initClient, err := isupipe.NewClient(contestantLogger,
-			agent.WithBaseURL(config.TargetBaseURL),
-			agent.WithTimeout(config.InitializeAgentTimeout),
-		)
-		if err != nil {
-			dumpFailedResult([]string{"webapp初期化クライアント生成が失敗しました"})
-			return cli.NewExitError(err, 1)
-		}
-
-		pretestDNSResolver := resolver.NewDNSResolver()
-		pretestDNSResolver.ResolveAttempts = 10
-		if err != nil {
-			dumpFailedResult([]string{"整合性チェックDNSリゾルバ生成に失敗しました"})
-			return cli.NewExitError(err, 1)
-		}
-
-		initializeResp, err := initClient.Initialize(ctx)
-		if err != nil {
-			dumpFailedResult([]string{"初期化が失敗しました", err.Error()})
-			return nil
-		}
-		config.Language = initializeResp.Language
-
-		contestantLogger.Info("ベンチマーク走行前のデータ整合性チェックを行います")
-
-		// NOTE: pretestにはこれら初期化が必要
-		benchscore.InitCounter(ctx)
-		bencherror.InitErrors(ctx)
-		if err := scenario.Pretest(ctx, contestantLogger, pretestDNSResolver); err != nil {
-			bencherror.Done()
-			dumpFailedResult([]string{"整合性チェックに失敗しました", err.Error()})
-			return nil
-		}
-		contestantLogger.Info("整合性チェックが成功しました")
-
-		if pretestOnly {
-			lgr.Info("--pretest-onlyが指定されているため、ベンチマーク走行をスキップします")
-			return nil
-		}
-
-		contestantLogger.Info("ベンチマーク走行を開始します")
-		benchStartAt := time.Now()
-
-		// NOTE: benchmarkにはこれら初期化が必要
-		benchscore.InitCounter(ctx)
-		bencherror.InitErrors(ctx)
-
-		benchCtx, cancelBench := context.WithTimeout(ctx, config.DefaultBenchmarkTimeout)
-		defer cancelBench()
-
-		benchmarker := newBenchmarker(benchCtx, contestantLogger)
-		if err := benchmarker.run(benchCtx); err != nil {
-			lgr.Warnf("ベンチマーク中断: %s", err.Error())
-			bencherror.Done()
-			dumpFailedResult([]string{"ベンチマーク走行が中断されました", err.Error()})
+		result, err := bench.Run(ctx, bench.Options{
+			ContestantLogger: contestantLogger,
+			Control:          runCtl,
+			PretestOnly:      pretestOnly,
+			OnPretestReport:  writePretestReport,
+		})
+		if result == nil {
+			if err != nil {
+				return cli.Exit(err, 1)
+			}
+			lgr.Info("--pretest-onlyが指定されているため、ベンチマーク走行をスキップしました")
 			return nil
 		}
-
-		benchElapsed := time.Since(benchStartAt)
-		lgr.Infof("ベンチマーク走行時間: %s", benchElapsed.String())
-
-		benchscore.DoneCounter()
-		bencherror.Done()
-		contestantLogger.Info("ベンチマーク走行終了")
-
-		contestantLogger.Info("最終チェックを実施します")
-		finalcheckDNSResolver := resolver.NewDNSResolver()
-		finalcheckDNSResolver.ResolveAttempts = 10
-		if err := scenario.FinalcheckScenario(ctx, contestantLogger, finalcheckDNSResolver); err != nil {
-			dumpFailedResult([]string{})
-			return cli.NewExitError(err, 1)
-		}
-		contestantLogger.Info("最終チェックが成功しました")
-		contestantLogger.Info("重複排除したログを以下に出力します")
-
-		// ベンチマーク処理のエラー収集
-		lgr.Info("ベンチエラーを収集します")
-		var benchErrors []string
-		for _, msgs := range bencherror.GetFinalBenchErrors() {
-			benchErrors = append(benchErrors, msgs...)
-		}
-		benchErrors = uniqueMsgs(benchErrors)
-
-		// ベンチマーカー内部エラー
-		lgr.Info("内部エラーを収集します")
-		var systemErrorFound bool
-		for _, msgs := range bencherror.GetFinalSystemErrors() {
-			for _, msg := range msgs {
-				if len(msg) == 0 {
-					continue
-				}
-				lgr.Warnf("内部エラー: %s\n", msg)
-				systemErrorFound = true
+		if !result.Pass {
+			dumpFailedResult(result)
+			if err != nil {
+				return cli.Exit(err, 1)
 			}
-		}
-		if systemErrorFound {
-			contestantLogger.Warn("システム内部エラーが発生しました。運営にジョブIDとともに連絡お願いいたします")
+			return nil
 		}
 
-		var msgs []string
-		lgr.Info("シナリオカウンタを出力します")
-		scenarioCounter := benchmarker.ScenarioCounter()
-		if count, ok := scenarioCounter[BasicViewerScenario]; ok {
-			contestantLogger.Info("配信を最後まで視聴できた視聴者数", zap.Int64("viewers", count))
+		if err := signResult(result); err != nil {
+			return cli.Exit(err, 1)
 		}
-
-		var scenarioLogs []string
-		for name, count := range scenarioCounter {
-			if strings.HasSuffix(string(name), "-fail") {
-				scenarioLogs = append(scenarioLogs, fmt.Sprintf("[失敗シナリオ %s] %d 回失敗", name, count))
-				continue
-			}
-
-			failKey := score.ScoreTag(fmt.Sprintf("%s-fail", name))
-			if failCount, ok := scenarioCounter[failKey]; ok {
-				scenarioLogs = append(scenarioLogs, fmt.Sprintf("[シナリオ %s] %d 回成功, %d 回失敗", name, count, failCount))
-			} else {
-				scenarioLogs = append(scenarioLogs, fmt.Sprintf("[シナリオ %s] %d 回成功", name, count))
-			}
-		}
-		slices.Sort(scenarioLogs)
-		for _, l := range scenarioLogs {
-			lgr.Info(l)
-		}
-
-		numResolves := benchscore.GetByTag(benchscore.DNSResolve)
-		numDNSFailed := benchscore.GetByTag(benchscore.DNSFailed)
-		msgs = append(msgs, fmt.Sprintf("名前解決成功数 %d", numResolves))
-		lgr.Infof("DNSAttacker並列数: %d", benchmarker.attackParallelis)
-		lgr.Infof("名前解決成功数: %d", numResolves)
-		lgr.Infof("名前解決失敗数: %d", numDNSFailed)
-
-		profit := benchscore.GetTotalProfit()
-		msgs = append(msgs, fmt.Sprintf("売上: %d", profit))
-		lgr.Infof("スコア: %d", profit)
-
-		b, err := json.Marshal(&BenchResult{
-			Pass:          true,
-			Score:         int64(profit),
-			Messages:      append(benchErrors, msgs...),
-			Language:      config.Language,
-			ResolvedCount: numResolves,
-		})
+		b, err := json.Marshal(result)
 		if err != nil {
-			return cli.NewExitError(err, 1)
+			return cli.Exit(err, 1)
 		}
 
-		if err := os.WriteFile(config.ResultPath, b, os.ModePerm); err != nil {
-			return cli.NewExitError(err, 1)
+		// NOTE: 走行が中断された場合も、結果は書き出す
+		if err := sendResult(context.WithoutCancel(ctx), b); err != nil {
+			return cli.Exit(err, 1)
 		}
 
 		return nil
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/isucon/isucon13/bench"
+	"github.com/isucon/isucon13/bench/internal/resultdiff"
+	"github.com/urfave/cli/v2"
+)
+
+// compareCmd は、2回の走行結果 (--result-path に書き出したJSON) を比べ、差分を出力するコマンドです
+var compareCmd = &cli.Command{
+	Name:      "compare",
+	Usage:     "2回の走行結果のスコア・シナリオの実行回数・エラーの差分",
+	ArgsUsage: "<old.json> <new.json>",
+	Action: func(cliCtx *cli.Context) error {
+		if cliCtx.NArg() != 2 {
+			return cli.Exit("比べる2つの走行結果のファイルを指定してください", 1)
+		}
+		old, err := loadResultSnapshot(cliCtx.Args().Get(0))
+		if err != nil {
+			return cli.Exit(err, 1)
+		}
+		new, err := loadResultSnapshot(cliCtx.Args().Get(1))
+		if err != nil {
+			return cli.Exit(err, 1)
+		}
+
+		if err := resultdiff.Compare(old, new).Write(os.Stdout); err != nil {
+			return cli.Exit(err, 1)
+		}
+		return nil
+	},
+}
+
+func loadResultSnapshot(path string) (resultdiff.Snapshot, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return resultdiff.Snapshot{}, err
+	}
+	var result bench.Result
+	if err := json.Unmarshal(b, &result); err != nil {
+		return resultdiff.Snapshot{}, fmt.Errorf("%s: 走行結果の形式が不正です: %w", path, err)
+	}
+	return resultdiff.Snapshot{
+		Pass:        result.Pass,
+		Score:       result.Score,
+		Scenarios:   result.Scenarios,
+		ErrorCounts: result.ErrorCounts,
+		Messages:    result.Messages,
+	}, nil
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/isucon/isucon13/bench/internal/scheduler"
+	"github.com/urfave/cli/v2"
+)
+
+// corpusCmd は、視聴者が投稿するスパムコメントの定義ファイル (assetdir/spam_corpus.json) を扱うコマンドです
+var corpusCmd = &cli.Command{
+	Name:  "corpus",
+	Usage: "スパムコメントとNGワードの定義ファイルの操作 (運営向け)",
+	Subcommands: []*cli.Command{
+		{
+			Name:   "export",
+			Usage:  "組み込みのスパムコメントとNGワードを定義ファイルの形式で標準出力に書き出す",
+			Action: exportCorpus,
+		},
+		{
+			Name:      "validate",
+			Usage:     "assetdir の定義ファイルをチェックサムとともに検証",
+			ArgsUsage: "<assetdir>",
+			Action:    validateCorpus,
+		},
+	},
+}
+
+func exportCorpus(cliCtx *cli.Context) error {
+	if err := scheduler.LivecommentScheduler.ExportSpamCorpus(os.Stdout); err != nil {
+		return cli.Exit(err, 1)
+	}
+	return nil
+}
+
+func validateCorpus(cliCtx *cli.Context) error {
+	dir := cliCtx.Args().First()
+	if len(dir) == 0 {
+		return cli.Exit("assetdir を指定してください", 1)
+	}
+
+	path := filepath.Join(dir, scheduler.SpamCorpusFileName)
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return cli.Exit(err, 1)
+	}
+	checksumFile, err := os.ReadFile(filepath.Join(dir, scheduler.SpamCorpusChecksumFileName))
+	if err != nil {
+		return cli.Exit(err, 1)
+	}
+	checksum, err := scheduler.VerifySpamCorpusChecksum(b, checksumFile)
+	if err != nil {
+		return cli.Exit(err, 1)
+	}
+
+	corpus, err := scheduler.ParseSpamCorpus(bytes.NewReader(b))
+	if err != nil {
+		return cli.Exit(err, 1)
+	}
+	fmt.Printf("%s: スパムコメント %d 件, ダミーのNGワード %d 件 (sha256: %s)\n", path, len(corpus.Comments), len(corpus.DummyNgWords), checksum)
+	return nil
+}
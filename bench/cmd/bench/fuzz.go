@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+
+	"github.com/isucon/isucon13/bench/internal/bencherror"
+	"github.com/isucon/isucon13/bench/internal/benchscore"
+	"github.com/isucon/isucon13/bench/internal/config"
+	"github.com/isucon/isucon13/bench/internal/logger"
+	"github.com/isucon/isucon13/bench/scenario"
+	"github.com/urfave/cli/v2"
+)
+
+var (
+	fuzzTarget     string
+	fuzzOutputPath string
+)
+
+// fuzzCmd は、webappのパラメータを範囲内外に変化させ、500系や内部エラーの漏洩がないかを確かめる運営向けのコマンドです
+// NOTE: 初期化(POST /api/initialize)済みであることを前提とし、書き込み系のエンドポイントも対象とします
+var fuzzCmd = &cli.Command{
+	Name:  "fuzz",
+	Usage: "パラメータを変化させたリクエストによるwebappの堅牢性確認 (運営向け)",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:        "target",
+			Destination: &fuzzTarget,
+			EnvVars:     []string{"BENCH_FUZZ_TARGET"},
+		},
+		&cli.IntFlag{
+			Name:        "dns-port",
+			Value:       config.DNSPort,
+			Destination: &config.DNSPort,
+			EnvVars:     []string{"BENCH_DNS_PORT"},
+		},
+		&cli.IntFlag{
+			Name:        "target-port",
+			Value:       config.TargetPort,
+			Destination: &config.TargetPort,
+			EnvVars:     []string{"BENCH_TARGET_PORT"},
+		},
+		&cli.StringFlag{
+			Name:        "output",
+			Destination: &fuzzOutputPath,
+			EnvVars:     []string{"BENCH_FUZZ_OUTPUT_PATH"},
+		},
+	},
+	Action: func(cliCtx *cli.Context) error {
+		ctx := cliCtx.Context
+		ctx = benchscore.WithScoreSet(ctx, benchscore.NewScoreSet(ctx))
+		ctx = bencherror.WithErrorSet(ctx, bencherror.NewErrorSet())
+		lgr, err := logger.InitStaffLogger()
+		if err != nil {
+			return cli.Exit(err, 1)
+		}
+		testLogger, err := logger.InitTestLogger()
+		if err != nil {
+			return cli.Exit(err, 1)
+		}
+
+		fuzzTarget = config.TrimIPBrackets(fuzzTarget)
+		if net.ParseIP(fuzzTarget) == nil {
+			return cli.Exit(fmt.Sprintf("不正なtargetです (IPアドレスを指定してください): %s", fuzzTarget), 1)
+		}
+		config.TargetWebapps = []string{fuzzTarget}
+		config.TargetBaseURL = fmt.Sprintf("%s://pipe.%s:%d", config.HTTPScheme, config.BaseDomain, config.TargetPort)
+
+		report, err := scenario.FuzzScenario(ctx, testLogger, newDiffResolver(fuzzTarget))
+		if err != nil {
+			return cli.Exit(err, 1)
+		}
+
+		endpoints := report.Endpoints()
+		for _, er := range endpoints {
+			if len(er.Violations) == 0 {
+				lgr.Infof("[OK] %s (%d 件)", er.Endpoint, er.Cases)
+			} else {
+				lgr.Warnf("[NG] %s (%d/%d 件)", er.Endpoint, len(er.Violations), er.Cases)
+			}
+			for _, v := range er.Violations {
+				lgr.Warnf("  %s %s: %s", v.Case, v.Path, v.Reason)
+			}
+			if len(er.AcceptedOutOfRange) > 0 {
+				lgr.Infof("  範囲外の値を受け付けたケース: %v", er.AcceptedOutOfRange)
+			}
+		}
+
+		if len(fuzzOutputPath) > 0 {
+			b, err := json.Marshal(endpoints)
+			if err != nil {
+				return cli.Exit(err, 1)
+			}
+			if err := os.WriteFile(fuzzOutputPath, b, os.ModePerm); err != nil {
+				return cli.Exit(err, 1)
+			}
+		}
+
+		if n := report.NumViolations(); n > 0 {
+			return cli.Exit(fmt.Sprintf("%d 件のケースで問題が見つかりました", n), 1)
+		}
+		lgr.Infof("%d 件のエンドポイントすべてで問題は見つかりませんでした", len(endpoints))
+		return nil
+	},
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/isucon/isucon13/bench/internal/journal"
+	"github.com/urfave/cli/v2"
+)
+
+var journalInFlightOnly bool
+
+// journalCmd は、--journal で記録したジャーナルを扱うコマンドです
+var journalCmd = &cli.Command{
+	Name:  "journal",
+	Usage: "シナリオのジャーナル操作",
+	Subcommands: []*cli.Command{
+		{
+			Name:      "dump",
+			Usage:     "ジャーナルの内容を表示",
+			ArgsUsage: "<journal file>",
+			Flags: []cli.Flag{
+				&cli.BoolFlag{
+					Name:        "in-flight",
+					Usage:       "終了していないイテレーションのみ表示",
+					Destination: &journalInFlightOnly,
+				},
+			},
+			Action: dumpJournal,
+		},
+	},
+}
+
+func dumpJournal(cliCtx *cli.Context) error {
+	path := cliCtx.Args().First()
+	if len(path) == 0 {
+		return cli.Exit("ジャーナルファイルを指定してください", 1)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return cli.Exit(err, 1)
+	}
+	defer f.Close()
+
+	records, err := journal.ReadAll(f)
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		// NOTE: クラッシュ時は末尾のレコードが途切れうるため、読めた分だけ表示する
+		fmt.Fprintln(os.Stderr, "ジャーナルの末尾が途切れています. 途切れたレコードは表示しません")
+	} else if err != nil {
+		return cli.Exit(err, 1)
+	}
+
+	summaries := journal.Summarize(records)
+	var inFlight int
+	for _, s := range summaries {
+		if s.InFlight() {
+			inFlight++
+		}
+	}
+
+	if journalInFlightOnly {
+		for _, s := range summaries {
+			if !s.InFlight() {
+				continue
+			}
+			notes := make([]string, len(s.Notes))
+			for i, note := range s.Notes {
+				notes[i] = fmt.Sprintf("%s=%s", note.Key, note.Value)
+			}
+			fmt.Printf("%s #%d %s %s\n", s.Begin.UTC().Format(time.RFC3339Nano), s.ID, s.Scenario, strings.Join(notes, " "))
+		}
+	} else {
+		for _, record := range records {
+			var detail string
+			switch record.Kind {
+			case journal.KindBegin:
+				detail = record.Scenario
+			case journal.KindNote:
+				detail = fmt.Sprintf("%s=%s", record.Key, record.Value)
+			case journal.KindEnd:
+				detail = record.Result
+			}
+			fmt.Printf("%s #%d %s %s\n", record.Time.UTC().Format(time.RFC3339Nano), record.Iteration, record.Kind, detail)
+		}
+	}
+	fmt.Fprintf(os.Stderr, "イテレーション: %d 件 (終了していないもの: %d 件)\n", len(summaries), inFlight)
+
+	return nil
+}
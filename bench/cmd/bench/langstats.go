@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/isucon/isucon13/bench/internal/langstats"
+	"github.com/urfave/cli/v2"
+)
+
+var langstatsOutputPath string
+
+// langstatsCmd は、複数回の走行結果を実装言語ごとに集計するコマンドです
+var langstatsCmd = &cli.Command{
+	Name:      "langstats",
+	Usage:     "走行結果を実装言語ごとに集計",
+	ArgsUsage: "<result file>...",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:        "output",
+			Usage:       "集計結果の出力先 (省略時は標準出力)",
+			Destination: &langstatsOutputPath,
+		},
+	},
+	Action: func(cliCtx *cli.Context) error {
+		paths := cliCtx.Args().Slice()
+		if len(paths) == 0 {
+			return cli.Exit("走行結果のファイルを指定してください", 1)
+		}
+
+		var runs []langstats.Run
+		for _, path := range paths {
+			f, err := os.Open(path)
+			if err != nil {
+				return cli.Exit(err, 1)
+			}
+			r, err := langstats.Decode(f)
+			f.Close()
+			if err != nil {
+				return cli.Exit(fmt.Errorf("%s: %w", path, err), 1)
+			}
+			runs = append(runs, r...)
+		}
+
+		if err := writeLangstats(langstatsOutputPath, langstats.Aggregate(runs)); err != nil {
+			return cli.Exit(err, 1)
+		}
+		return nil
+	},
+}
+
+func writeLangstats(path string, summary *langstats.Summary) error {
+	b, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return err
+	}
+	if len(path) == 0 {
+		_, err := fmt.Println(string(b))
+		return err
+	}
+	return os.WriteFile(path, b, 0644)
+}
+
+// appendSessionResult は、走行結果を1行のJSONとしてセッションの結果ファイルに追記します
+func appendSessionResult(path string, result []byte) error {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, result); err != nil {
+		return err
+	}
+	buf.WriteByte('\n')
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(buf.Bytes())
+	return err
+}
+
+// writeSessionSummary は、セッションの結果ファイルを実装言語ごとに集計し、<path>.summary.json に書き出します
+func writeSessionSummary(path string) error {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	runs, err := langstats.Decode(f)
+	if err != nil {
+		return err
+	}
+	return writeLangstats(path+".summary.json", langstats.Aggregate(runs))
+}
@@ -1,17 +1,20 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log"
-	"math/rand"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
-	"github.com/urfave/cli"
+	"github.com/isucon/isucon13/bench/internal/version"
+	"github.com/urfave/cli/v2"
 )
 
 func init() {
 	time.Local = time.UTC
-	rand.New(rand.NewSource(time.Now().UnixNano()))
 }
 
 func main() {
@@ -24,20 +27,38 @@ func cliMain() int {
 	app.Usage = "isupipe ベンチマーカー"
 	app.Description = "isupipeのベンチマークを実施"
 	app.HelpName = "isupipebench"
+	app.Version = version.Get().String()
 
-	app.Commands = []cli.Command{
+	app.Commands = []*cli.Command{
 		run,
 		supervise,
+		pretestDiff,
+		fuzzCmd,
+		seriesCmd,
+		compareCmd,
+		journalCmd,
+		replayCmd,
+		langstatsCmd,
+		usersCmd,
+		corpusCmd,
+		versionCmd,
 	}
 
 	app.Action = func(cliCtx *cli.Context) error {
 		return cli.ShowAppHelp(cliCtx)
 	}
 
-	if err := app.Run(os.Args); err != nil {
-		exitErr := err.(*cli.ExitError)
-		log.Println(exitErr.Error())
-		return exitErr.ExitCode()
+	// NOTE: 各コマンドの cliCtx.Context は、SIGINT/SIGTERMを受けるとキャンセルされる
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := app.RunContext(ctx, os.Args); err != nil {
+		log.Println(err.Error())
+		var exitErr cli.ExitCoder
+		if errors.As(err, &exitErr) {
+			return exitErr.ExitCode()
+		}
+		return 1
 	}
 
 	return 0
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/isucon/isucandar/score"
+	"github.com/isucon/isucon13/bench"
+	"github.com/isucon/isucon13/bench/internal/bencherror"
+	"github.com/isucon/isucon13/bench/internal/benchtrace"
+	"github.com/isucon/isucon13/bench/internal/config"
+	"github.com/isucon/isucon13/bench/internal/metrics"
+	"github.com/isucon/isucon13/bench/internal/topology"
+	"go.uber.org/zap"
+)
+
+// writeMetrics は、走行状況をPrometheusのテキスト形式で書き出します
+func writeMetrics(w io.Writer, ctl *runController) {
+	status := ctl.status()
+
+	fmt.Fprintln(w, "# HELP isupipe_bench_phase 現在の走行の段階 (該当する段階のみ1)")
+	fmt.Fprintln(w, "# TYPE isupipe_bench_phase gauge")
+	for _, phase := range bench.Phases {
+		var v int
+		if phase == status.Phase {
+			v = 1
+		}
+		fmt.Fprintf(w, "isupipe_bench_phase{phase=%q} %d\n", phase, v)
+	}
+
+	// NOTE: カウンタやエラーは負荷走行の開始時に初期化されるため、それ以前は出力しない
+	if status.Phase != bench.PhaseInitialize && status.Phase != bench.PhasePretest {
+		fmt.Fprintln(w, "# HELP isupipe_bench_profit 売上(チップ合計)")
+		fmt.Fprintln(w, "# TYPE isupipe_bench_profit gauge")
+		fmt.Fprintf(w, "isupipe_bench_profit %d\n", status.Scores.Profit)
+
+		fmt.Fprintln(w, "# HELP isupipe_bench_score 配点に基づく現時点のスコア")
+		fmt.Fprintln(w, "# TYPE isupipe_bench_score gauge")
+		fmt.Fprintf(w, "isupipe_bench_score %d\n", status.Scores.Score)
+
+		fmt.Fprintln(w, "# HELP isupipe_bench_score_counter_total スコアタグごとのカウンタ")
+		fmt.Fprintln(w, "# TYPE isupipe_bench_score_counter_total counter")
+		breakdown := status.Scores.Counters
+		tags := make([]string, 0, len(breakdown))
+		for tag := range breakdown {
+			tags = append(tags, string(tag))
+		}
+		sort.Strings(tags)
+		for _, tag := range tags {
+			fmt.Fprintf(w, "isupipe_bench_score_counter_total{tag=%q} %d\n", tag, breakdown[score.ScoreTag(tag)])
+		}
+
+		fmt.Fprintln(w, "# HELP isupipe_bench_errors_total カテゴリごとのエラー件数")
+		fmt.Fprintln(w, "# TYPE isupipe_bench_errors_total counter")
+		for _, category := range bencherror.Categories {
+			fmt.Fprintf(w, "isupipe_bench_errors_total{category=%q} %d\n", category, status.ErrorCounts[category])
+		}
+	}
+
+	fmt.Fprintln(w, "# HELP isupipe_bench_http_phase_duration_seconds HTTPリクエストの段階ごとの所要時間")
+	fmt.Fprintln(w, "# TYPE isupipe_bench_http_phase_duration_seconds summary")
+	for _, stat := range benchtrace.Stats() {
+		fmt.Fprintf(w, "isupipe_bench_http_phase_duration_seconds_sum{phase=%q} %g\n", stat.Phase, stat.Total.Seconds())
+		fmt.Fprintf(w, "isupipe_bench_http_phase_duration_seconds_count{phase=%q} %d\n", stat.Phase, stat.Count)
+	}
+
+	fmt.Fprintln(w, "# HELP isupipe_bench_http2_events_total HTTP/2で観測した接続の切断・再送・確立の回数")
+	fmt.Fprintln(w, "# TYPE isupipe_bench_http2_events_total counter")
+	h2Stats := benchtrace.H2Stats()
+	for _, event := range benchtrace.H2Events() {
+		fmt.Fprintf(w, "isupipe_bench_http2_events_total{event=%q} %d\n", event, h2Stats[event])
+	}
+
+	if reports := topology.Report(); len(reports) > 0 {
+		fmt.Fprintln(w, "# HELP isupipe_bench_target_requests_total 宛先ごとのリクエスト数")
+		fmt.Fprintln(w, "# TYPE isupipe_bench_target_requests_total counter")
+		for _, report := range reports {
+			fmt.Fprintf(w, "isupipe_bench_target_requests_total{target=%q} %d\n", report.Name, report.Requests)
+		}
+		fmt.Fprintln(w, "# HELP isupipe_bench_target_errors_total 宛先ごとの失敗したリクエスト数")
+		fmt.Fprintln(w, "# TYPE isupipe_bench_target_errors_total counter")
+		for _, report := range reports {
+			fmt.Fprintf(w, "isupipe_bench_target_errors_total{target=%q} %d\n", report.Name, report.Errors)
+		}
+	}
+
+	metrics.RequestDuration.Write(w)
+}
+
+// startMetricsServer は、--metrics-listen が指定されていればメトリクスを公開し、停止する関数を返します
+func startMetricsServer(ctl *runController) (func(), error) {
+	if len(config.MetricsListenAddr) == 0 {
+		return func() {}, nil
+	}
+
+	ln, err := net.Listen("tcp", config.MetricsListenAddr)
+	if err != nil {
+		return nil, fmt.Errorf("メトリクスを公開できません: %w", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		writeMetrics(w, ctl)
+	})
+	server := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	go func() {
+		if err := server.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			zap.S().Warnf("メトリクスの公開が停止しました: %s", err.Error())
+		}
+	}()
+	zap.S().Infof("メトリクスを公開しました: http://%s/metrics", ln.Addr())
+
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		defer cancel()
+		server.Shutdown(ctx)
+	}, nil
+}
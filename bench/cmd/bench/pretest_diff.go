@@ -0,0 +1,138 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"slices"
+	"strconv"
+
+	"github.com/isucon/isucon13/bench/internal/bencherror"
+	"github.com/isucon/isucon13/bench/internal/benchscore"
+	"github.com/isucon/isucon13/bench/internal/config"
+	"github.com/isucon/isucon13/bench/internal/logger"
+	"github.com/isucon/isucon13/bench/internal/resolver"
+	"github.com/isucon/isucon13/bench/scenario"
+	"github.com/urfave/cli/v2"
+)
+
+var (
+	diffTargetA    string
+	diffTargetB    string
+	diffOutputPath string
+)
+
+// pretestDiff は、2つのwebappに読み取り系のpretestを行い、レスポンスの差分を報告します
+// NOTE: 両者とも初期化(POST /api/initialize)済みであることを前提とします
+var pretestDiff = &cli.Command{
+	Name:  "pretest-diff",
+	Usage: "2つのwebappのレスポンス差分比較",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:        "target-a",
+			Destination: &diffTargetA,
+			EnvVars:     []string{"BENCH_DIFF_TARGET_A"},
+		},
+		&cli.StringFlag{
+			Name:        "target-b",
+			Destination: &diffTargetB,
+			EnvVars:     []string{"BENCH_DIFF_TARGET_B"},
+		},
+		&cli.IntFlag{
+			Name:        "dns-port",
+			Value:       config.DNSPort,
+			Destination: &config.DNSPort,
+			EnvVars:     []string{"BENCH_DNS_PORT"},
+		},
+		&cli.IntFlag{
+			Name:        "target-port",
+			Value:       config.TargetPort,
+			Destination: &config.TargetPort,
+			EnvVars:     []string{"BENCH_TARGET_PORT"},
+		},
+		&cli.StringFlag{
+			Name:        "output",
+			Destination: &diffOutputPath,
+			EnvVars:     []string{"BENCH_DIFF_OUTPUT_PATH"},
+		},
+	},
+	Action: func(cliCtx *cli.Context) error {
+		ctx := cliCtx.Context
+		ctx = benchscore.WithScoreSet(ctx, benchscore.NewScoreSet(ctx))
+		ctx = bencherror.WithErrorSet(ctx, bencherror.NewErrorSet())
+		lgr, err := logger.InitStaffLogger()
+		if err != nil {
+			return cli.Exit(err, 1)
+		}
+		testLogger, err := logger.InitTestLogger()
+		if err != nil {
+			return cli.Exit(err, 1)
+		}
+
+		if len(diffTargetA) == 0 || len(diffTargetB) == 0 {
+			return cli.Exit("--target-a と --target-b の両方を指定してください", 1)
+		}
+		diffTargetA, diffTargetB = config.TrimIPBrackets(diffTargetA), config.TrimIPBrackets(diffTargetB)
+		for _, target := range []string{diffTargetA, diffTargetB} {
+			if net.ParseIP(target) == nil {
+				return cli.Exit(fmt.Sprintf("不正なtargetです (IPアドレスを指定してください): %s", target), 1)
+			}
+		}
+
+		// NOTE: 各targetのDNSサーバが自身のIPアドレスを返す想定
+		webapps := []string{diffTargetA, diffTargetB}
+		slices.Sort(webapps)
+		config.TargetWebapps = slices.Compact(webapps)
+		config.TargetBaseURL = fmt.Sprintf("%s://pipe.%s:%d", config.HTTPScheme, config.BaseDomain, config.TargetPort)
+
+		results, err := scenario.PretestDiff(ctx, testLogger, newDiffResolver(diffTargetA), newDiffResolver(diffTargetB))
+		if err != nil {
+			return cli.Exit(err, 1)
+		}
+
+		var numDiffs int
+		for _, result := range results {
+			if result.Same() {
+				lgr.Infof("[一致] %s", result.Name)
+				continue
+			}
+			numDiffs++
+			lgr.Warnf("[差分] %s", result.Name)
+			if len(result.ErrorA) > 0 {
+				lgr.Warnf("  target-a エラー: %s", result.ErrorA)
+			}
+			if len(result.ErrorB) > 0 {
+				lgr.Warnf("  target-b エラー: %s", result.ErrorB)
+			}
+			for _, diff := range result.Differences {
+				lgr.Warnf("  %s", diff)
+			}
+		}
+
+		if len(diffOutputPath) > 0 {
+			b, err := json.Marshal(results)
+			if err != nil {
+				return cli.Exit(err, 1)
+			}
+			if err := os.WriteFile(diffOutputPath, b, os.ModePerm); err != nil {
+				return cli.Exit(err, 1)
+			}
+		}
+
+		if numDiffs > 0 {
+			return cli.Exit(fmt.Sprintf("%d/%d 件のエンドポイントで差分があります", numDiffs, len(results)), 1)
+		}
+		lgr.Infof("%d 件のエンドポイントすべてで一致しました", len(results))
+		return nil
+	},
+}
+
+// newDiffResolver は、targetのDNSサーバで名前解決するリゾルバを返します
+// NOTE: 名前解決のキャッシュはプロセス全体で共有されるため、両者が混ざらないよう無効化する
+func newDiffResolver(target string) *resolver.DNSResolver {
+	r := resolver.NewDNSResolver()
+	r.Nameserver = net.JoinHostPort(target, strconv.Itoa(config.DNSPort))
+	r.UseCache = false
+	return r
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+
+	"github.com/isucon/isucon13/bench"
+	"github.com/isucon/isucon13/bench/internal/bencherror"
+	"github.com/isucon/isucon13/bench/internal/benchscore"
+	"github.com/isucon/isucon13/bench/internal/config"
+	"github.com/isucon/isucon13/bench/internal/journal"
+	"github.com/isucon/isucon13/bench/internal/logger"
+	"github.com/isucon/isucon13/bench/internal/redact"
+	"github.com/isucon/isucon13/bench/internal/scheduler"
+	"github.com/urfave/cli/v2"
+)
+
+var (
+	replayUntilIteration uint64
+	replayOutputPath     string
+)
+
+// replayCmd は、--journal で記録したシナリオのイテレーションを、同じ順序で再実行します
+// NOTE: 特定の検証の失敗を再現できるよう、webappを初期化してから1つずつ再生する
+var replayCmd = &cli.Command{
+	Name:      "replay",
+	Usage:     "ジャーナルに記録したシナリオの再実行",
+	ArgsUsage: "<journal file>",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:        "target",
+			Value:       fmt.Sprintf("http://pipe.u.isucon.dev:%d", config.TargetPort),
+			Destination: &config.TargetBaseURL,
+			EnvVars:     []string{"BENCH_TARGET_URL"},
+		},
+		&cli.StringFlag{
+			Name:        "nameserver",
+			Value:       "127.0.0.1",
+			Destination: &config.TargetNameserver,
+			EnvVars:     []string{"BENCH_NAMESERVER"},
+		},
+		&cli.StringSliceFlag{
+			Name: "webapp",
+		},
+		&cli.IntFlag{
+			Name:        "dns-port",
+			Value:       53,
+			Destination: &config.DNSPort,
+			EnvVars:     []string{"BENCH_DNS_PORT"},
+		},
+		&cli.StringFlag{
+			Name:        "assetdir",
+			Value:       "assets/testdata",
+			Destination: &assetDir,
+			EnvVars:     []string{"BENCH_ASSETDIR"},
+		},
+		&cli.Uint64Flag{
+			Name:        "until",
+			Destination: &replayUntilIteration,
+			EnvVars:     []string{"BENCH_REPLAY_UNTIL"},
+		},
+		&cli.StringFlag{
+			Name:        "output",
+			Destination: &replayOutputPath,
+			EnvVars:     []string{"BENCH_REPLAY_OUTPUT_PATH"},
+		},
+	},
+	Action: func(cliCtx *cli.Context) error {
+		ctx := cliCtx.Context
+		path := cliCtx.Args().First()
+		if len(path) == 0 {
+			return cli.Exit("ジャーナルファイルを指定してください", 1)
+		}
+
+		if _, err := scheduler.UserScheduler.LoadPopulation(assetDir); err != nil {
+			return cli.Exit(err, 1)
+		}
+		if _, _, err := scheduler.LivecommentScheduler.LoadSpamCorpus(assetDir); err != nil {
+			return cli.Exit(err, 1)
+		}
+		redact.Register(scheduler.UserScheduler.RawPasswords()...)
+		ctx = benchscore.WithScoreSet(ctx, benchscore.NewScoreSet(ctx))
+		ctx = bencherror.WithErrorSet(ctx, bencherror.NewErrorSet())
+		lgr, err := logger.InitStaffLogger()
+		if err != nil {
+			return cli.Exit(err, 1)
+		}
+		contestantLogger, err := logger.InitContestantLogger()
+		if err != nil {
+			return cli.Exit(err, 1)
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			return cli.Exit(err, 1)
+		}
+		records, err := journal.ReadAll(f)
+		f.Close()
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			// NOTE: クラッシュ時は末尾のレコードが途切れうるため、読めた分だけ再生する
+			lgr.Warn("ジャーナルの末尾が途切れています. 途切れたレコードは再生しません")
+		} else if err != nil {
+			return cli.Exit(err, 1)
+		}
+
+		config.TargetNameserver = config.TrimIPBrackets(config.TargetNameserver)
+		webapps := []string{config.TargetNameserver}
+		webapps = append(webapps, cliCtx.StringSlice("webapp")...)
+		slices.Sort(webapps)
+		config.TargetWebapps = slices.Compact(webapps)
+
+		report, err := bench.Replay(ctx, bench.ReplayOptions{
+			ContestantLogger: contestantLogger,
+			Records:          records,
+			UntilIteration:   replayUntilIteration,
+		})
+		if err != nil && report == nil {
+			return cli.Exit(err, 1)
+		}
+
+		var replayed, reproduced int
+		for _, step := range report.Steps {
+			if step.Skipped {
+				fmt.Printf("#%d %s 再生しないシナリオです\n", step.Iteration, step.Scenario)
+				continue
+			}
+			replayed++
+			if step.Reproduced {
+				reproduced++
+			}
+			fmt.Printf("#%d +%s %s 記録=%s 再生=%s\n", step.Iteration, step.Offset, step.Scenario, step.Recorded, step.Replayed)
+		}
+		fmt.Fprintf(os.Stderr, "シード: %d, 再生したイテレーション: %d 件 (成否が一致したもの: %d 件)\n", report.Seed, replayed, reproduced)
+
+		if len(replayOutputPath) > 0 {
+			b, err := json.Marshal(report)
+			if err != nil {
+				return cli.Exit(err, 1)
+			}
+			if err := os.WriteFile(replayOutputPath, b, os.ModePerm); err != nil {
+				return cli.Exit(err, 1)
+			}
+		}
+		if err != nil {
+			return cli.Exit(err, 1)
+		}
+		return nil
+	},
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/isucon/isucon13/bench"
+	"github.com/isucon/isucon13/bench/internal/config"
+	"github.com/isucon/isucon13/bench/internal/report"
+	"github.com/urfave/cli/v2"
+	"go.uber.org/zap"
+)
+
+var errSigningKeyRequired = errors.New("封印モードでは結果の署名鍵(--result-signing-key)の指定が必須です")
+
+// sealedDisabledFlags は、封印モードで無効化するオプションと、既定値に戻す処理です
+// NOTE: 走行条件を変えるもの、選手向けに追加の出力を行うものを無効化する
+var sealedDisabledFlags = []struct {
+	name  string
+	reset func()
+}{
+	{"score-config", func() { scoreConfigPath = "" }},
+	{"pretest-report", func() { config.PretestReportPath = "" }},
+	{"explain-score", func() { config.ExplainScore = false }},
+	{"practice", func() { config.Practice = false }},
+	{"pretest-only", func() { pretestOnly = false }},
+	{"stats-grace-period", func() { config.StatsGracePeriod = config.DefaultStatsGracePeriod }},
+	{"resolve-timeout", func() { config.DNSResolveTimeout = config.DefaultDNSResolveTimeout }},
+	{"dns-cache", func() { config.DNSCacheEnabled = config.DefaultDNSCacheEnabled }},
+	{"bot-marker", func() { config.BotMarkerEnabled = config.DefaultBotMarkerEnabled }},
+	{"bot-marker-header", func() { config.BotMarkerHeader = config.DefaultBotMarkerHeader }},
+	{"bot-marker-value", func() { config.BotMarkerValue = config.DefaultBotMarkerValue }},
+	{"max-tls-handshakes", func() { config.MaxConcurrentTLSHandshakes = 0 }},
+	{"load-config", func() { config.LoadConfigPath = "" }},
+	{"profile", func() { config.LoadProfileName = config.DefaultLoadProfile }},
+	{"endpoint-filter", func() { config.EndpointFilterPath = "" }},
+	{"targets", func() { config.TargetsPath = "" }},
+	{"proxy", func() { config.ProxyURL = "" }},
+	{"initialize-scale", func() { config.InitializeScale = 0 }},
+	{"initialize-features", func() { config.InitializeFeatures = "" }},
+	{"viewer-parallel-fetches", func() { config.ViewerParallelFetches = config.DefaultViewerParallelFetches }},
+	{"idle-probe", func() { config.IdleProbePeriods = "" }},
+	{"slow-loris", func() { config.SlowLoris = false }},
+	{"chaos", func() { config.Chaos = false }},
+	{"adaptive-concurrency", func() { config.AdaptiveConcurrency = false }},
+	{"calibrate", func() { config.Calibrate = false }},
+	{"seed", func() { config.Seed = 0 }},
+	{"admin-addr", func() { config.AdminAddr = "" }},
+	{"timeout-tiers", func() { config.TimeoutTiersSpec = "" }},
+	{"viewer-arrival", func() { config.ViewerArrivalDistribution = config.DefaultViewerArrivalDistribution }},
+	{"viewer-session", func() { config.ViewerSessionDistribution = config.DefaultViewerSessionDistribution }},
+	{"viewer-departures", func() { config.ViewerDepartures = false }},
+	{"viewer-abandon-window", func() { config.ViewerAbandonWindow = config.DefaultViewerAbandonWindow }},
+	{"fail-fast-errors", func() { config.FailFastMaxErrors = 0 }},
+	{"fail-fast-5xx-rate", func() { config.FailFastMaxServerErrorRate = 0 }},
+	{"icon-comparator", func() { config.IconComparator = config.DefaultIconComparator }},
+	{"keep-alive-penalty", func() { config.KeepAlivePenalty = 0 }},
+	{"metrics-listen", func() { config.MetricsListenAddr = "" }},
+	{"journal", func() { config.JournalPath = "" }},
+	{"otlp-endpoint", func() { config.OTLPEndpoint = "" }},
+	{"contestant-log-level", func() { config.ContestantLogLevel = config.DefaultContestantLogLevel }},
+}
+
+// applySealedMode は、封印モードで無効なオプションが指定されていれば記録した上で既定値に戻します
+func applySealedMode(cliCtx *cli.Context) error {
+	lgr := zap.S()
+
+	if !config.Sealed {
+		return nil
+	}
+	lgr.Info("封印モードで実行します")
+
+	if len(config.ResultSigningKey) == 0 {
+		return errSigningKeyRequired
+	}
+
+	for _, flag := range sealedDisabledFlags {
+		if !cliCtx.IsSet(flag.name) {
+			continue
+		}
+		lgr.Warnf("封印モードでは --%s は無効です。指定を無視します", flag.name)
+		flag.reset()
+	}
+
+	return nil
+}
+
+// signResult は、署名鍵が指定されていれば、署名を除いた結果のJSONに対するHMAC-SHA256で署名します
+func signResult(result *bench.Result) error {
+	if len(config.ResultSigningKey) == 0 {
+		return nil
+	}
+
+	result.Signature = ""
+	b, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+
+	result.Signature = report.Sign(config.ResultSigningKey, b)
+
+	return nil
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"github.com/isucon/isucon13/bench"
+	"github.com/isucon/isucon13/bench/internal/series"
+	"github.com/urfave/cli/v2"
+)
+
+var (
+	seriesCount      int
+	seriesCooldown   time.Duration
+	seriesResultDir  string
+	seriesOutputPath string
+)
+
+// seriesCmd は、同一条件で連続して走行し、スコアの平均・標準偏差・最高値をまとめる運営向けのコマンドです
+// "--" 以降の引数はそのまま各走行(run)に渡します
+var seriesCmd = &cli.Command{
+	Name:      "series",
+	Usage:     "連続した走行とスコアの集計 (運営向け)",
+	ArgsUsage: "[-- <run options>...]",
+	Flags: []cli.Flag{
+		&cli.IntFlag{
+			Name:        "count",
+			Usage:       "走行回数",
+			Value:       3,
+			Destination: &seriesCount,
+			EnvVars:     []string{"BENCH_SERIES_COUNT"},
+		},
+		&cli.DurationFlag{
+			Name:        "cooldown",
+			Usage:       "走行の間の待機時間",
+			Value:       30 * time.Second,
+			Destination: &seriesCooldown,
+			EnvVars:     []string{"BENCH_SERIES_COOLDOWN"},
+		},
+		&cli.StringFlag{
+			Name:        "result-dir",
+			Usage:       "各走行の結果の出力先ディレクトリ",
+			Value:       "/tmp/series",
+			Destination: &seriesResultDir,
+			EnvVars:     []string{"BENCH_SERIES_RESULT_DIR"},
+		},
+		&cli.StringFlag{
+			Name:        "output",
+			Usage:       "集計結果の出力先 (省略時は標準出力)",
+			Destination: &seriesOutputPath,
+			EnvVars:     []string{"BENCH_SERIES_OUTPUT_PATH"},
+		},
+	},
+	Action: func(cliCtx *cli.Context) error {
+		ctx := cliCtx.Context
+
+		if seriesCount <= 0 {
+			return cli.Exit(fmt.Sprintf("走行回数は1以上を指定してください: %d", seriesCount), 1)
+		}
+		executablePath, err := os.Executable()
+		if err != nil {
+			return cli.Exit(err, 1)
+		}
+		if err := os.MkdirAll(seriesResultDir, 0755); err != nil {
+			return cli.Exit(err, 1)
+		}
+
+		startedAt := time.Now()
+		var runs []series.Run
+		for i := 1; i <= seriesCount; i++ {
+			if i > 1 && seriesCooldown > 0 {
+				log.Printf("次の走行まで %s 待機します\n", seriesCooldown)
+				select {
+				case <-ctx.Done():
+				case <-time.After(seriesCooldown):
+				}
+			}
+			if ctx.Err() != nil {
+				log.Println("中断されたため、以降の走行を行いません")
+				break
+			}
+
+			log.Printf("走行 %d/%d を開始します\n", i, seriesCount)
+			run := execSeriesRun(ctx, executablePath, i, cliCtx.Args().Slice())
+			if len(run.Error) > 0 {
+				log.Printf("走行 %d/%d が失敗しました: %s\n", i, seriesCount, run.Error)
+			} else {
+				log.Printf("走行 %d/%d が終了しました (pass=%t, score=%d)\n", i, seriesCount, run.Pass, run.Score)
+			}
+			runs = append(runs, run)
+		}
+
+		summary := series.Aggregate(startedAt, runs)
+		log.Printf("走行数=%d 合格=%d 平均=%.1f 標準偏差=%.1f 最高=%d\n",
+			summary.Runs, summary.Passed, summary.MeanScore, summary.StddevScore, summary.BestScore)
+		if err := writeSeriesSummary(seriesOutputPath, summary); err != nil {
+			return cli.Exit(err, 1)
+		}
+		return nil
+	},
+}
+
+// execSeriesRun は、ベンチマーカー自身を run として実行し、結果を読み込みます
+func execSeriesRun(ctx context.Context, executablePath string, index int, runArgs []string) series.Run {
+	run := series.Run{
+		Index:      index,
+		ResultPath: filepath.Join(seriesResultDir, fmt.Sprintf("result-%03d.json", index)),
+	}
+	// NOTE: 前回の走行結果を読み込まないよう、実行前に削除する
+	os.Remove(run.ResultPath)
+
+	// NOTE: 同じフラグは後に指定したものが優先されるため、結果の出力先は末尾で上書きする
+	args := append([]string{"run"}, runArgs...)
+	args = append(args, "--result-path", run.ResultPath)
+
+	startAt := time.Now()
+	cmd := exec.CommandContext(ctx, executablePath, args...)
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	cmd.Cancel = func() error {
+		return cmd.Process.Signal(os.Interrupt)
+	}
+	cmd.WaitDelay = 13 * time.Second
+	// NOTE: 失格や最終チェックの失敗でも非0で終了するため、終了コードではなく結果ファイルで判断する
+	runErr := cmd.Run()
+	run.DurationMillis = time.Since(startAt).Milliseconds()
+
+	b, err := os.ReadFile(run.ResultPath)
+	if err != nil {
+		if runErr != nil {
+			err = runErr
+		}
+		run.Error = fmt.Sprintf("走行結果を読み込めませんでした: %s", err.Error())
+		return run
+	}
+	var result bench.Result
+	if err := json.Unmarshal(b, &result); err != nil {
+		run.Error = fmt.Sprintf("走行結果の形式が不正です: %s", err.Error())
+		return run
+	}
+	run.Pass = result.Pass
+	run.Score = result.Score
+	run.Language = result.Language
+	run.Seed = result.Seed
+	return run
+}
+
+func writeSeriesSummary(path string, summary *series.Summary) error {
+	b, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return err
+	}
+	if len(path) == 0 {
+		_, err := fmt.Println(string(b))
+		return err
+	}
+	return os.WriteFile(path, b, 0644)
+}
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/eapache/go-resiliency/retrier"
+	"github.com/isucon/isucon13/bench/internal/redact"
 	"github.com/nlopes/slack"
 )
 
@@ -21,6 +22,15 @@ func notifyErr(title string, err error, attachments []slack.Attachment) error {
 			Text:  err.Error(),
 		})
 	}
+	// NOTE: 標準出力などをそのまま添付するため、送信前に秘匿情報を伏せる
+	title = redact.String(title)
+	for i := range attachments {
+		attachments[i].Title = redact.String(attachments[i].Title)
+		attachments[i].Text = redact.String(attachments[i].Text)
+		for j := range attachments[i].Fields {
+			attachments[i].Fields[j].Value = redact.String(attachments[i].Fields[j].Value)
+		}
+	}
 
 	var (
 		retryCnt      = 10
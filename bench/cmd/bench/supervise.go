@@ -15,8 +15,10 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/isucon/isucon13/bench"
 	"github.com/isucon/isucon13/bench/internal/config"
-	"github.com/urfave/cli"
+	"github.com/isucon/isucon13/bench/internal/redact"
+	"github.com/urfave/cli/v2"
 	"golang.org/x/crypto/ssh"
 )
 
@@ -27,6 +29,8 @@ var (
 	slackWebhookURL            string
 	messageLimit               int
 	production                 bool
+	// 走行結果を追記するファイル. 終了時に実装言語ごとの集計を書き出す
+	sessionResultsPath string
 )
 
 const (
@@ -169,12 +173,12 @@ func execBench(ctx context.Context, job *Job) (*Result, error) {
 	select {
 	case <-ctx.Done():
 		log.Println("execBench中断")
-		NotifyWorkerErr(job, ctx.Err(), stdout.String(), stderr.String(), "ベンチマーカーの実行がタイムアウトしました (StatusFailed)")
+		NotifyWorkerErr(job, ctx.Err(), redact.String(stdout.String()), redact.String(stderr.String()), "ベンチマーカーの実行がタイムアウトしました (StatusFailed)")
 		status = StatusTimeout
 	case err, ok := <-errCh:
 		if ok && err != nil {
 			log.Printf("execBenchでエラー発生: %s\n", err.Error())
-			NotifyWorkerErr(job, err, stdout.String(), stderr.String(), "ベンチマーカーの実行エラーが発生 (StatusFailed)")
+			NotifyWorkerErr(job, err, redact.String(stdout.String()), redact.String(stderr.String()), "ベンチマーカーの実行エラーが発生 (StatusFailed)")
 			status = StatusFailed
 		}
 	}
@@ -186,8 +190,8 @@ func execBench(ctx context.Context, job *Job) (*Result, error) {
 	if err != nil {
 		return &Result{
 			ID:         job.ID,
-			Stdout:     joinN(strings.Split(stdout.String(), "\n"), messageLimit),
-			Stderr:     joinN(strings.Split(stderr.String(), "\n"), messageLimit),
+			Stdout:     joinN(strings.Split(redact.String(stdout.String()), "\n"), messageLimit),
+			Stderr:     joinN(strings.Split(redact.String(stderr.String()), "\n"), messageLimit),
 			Reason:     err.Error(),
 			IsPassed:   false,
 			Score:      0,
@@ -199,13 +203,13 @@ func execBench(ctx context.Context, job *Job) (*Result, error) {
 
 	log.Println("read result path")
 	// result
-	var benchResult *BenchResult
+	var benchResult *bench.Result
 	b, err = os.ReadFile(config.ResultPath)
 	if err != nil {
 		return &Result{
 			ID:         job.ID,
-			Stdout:     joinN(strings.Split(stdout.String(), "\n"), messageLimit),
-			Stderr:     joinN(strings.Split(stderr.String(), "\n"), messageLimit),
+			Stdout:     joinN(strings.Split(redact.String(stdout.String()), "\n"), messageLimit),
+			Stderr:     joinN(strings.Split(redact.String(stderr.String()), "\n"), messageLimit),
 			Reason:     err.Error(),
 			IsPassed:   false,
 			Score:      0,
@@ -219,8 +223,8 @@ func execBench(ctx context.Context, job *Job) (*Result, error) {
 	if err := json.NewDecoder(bytes.NewBuffer(b)).Decode(&benchResult); err != nil {
 		return &Result{
 			ID:         job.ID,
-			Stdout:     joinN(strings.Split(stdout.String(), "\n"), messageLimit),
-			Stderr:     joinN(strings.Split(stderr.String(), "\n"), messageLimit),
+			Stdout:     joinN(strings.Split(redact.String(stdout.String()), "\n"), messageLimit),
+			Stderr:     joinN(strings.Split(redact.String(stderr.String()), "\n"), messageLimit),
 			Reason:     err.Error(),
 			IsPassed:   false,
 			Score:      0,
@@ -229,6 +233,16 @@ func execBench(ctx context.Context, job *Job) (*Result, error) {
 		}, nil
 	}
 
+	if len(sessionResultsPath) > 0 {
+		if err := appendSessionResult(sessionResultsPath, b); err != nil {
+			log.Printf("failed to append session result: %s\n", err.Error())
+		}
+		// NOTE: supervisorが強制終了されても集計が残るよう、走行のたびに書き直す
+		if err := writeSessionSummary(sessionResultsPath); err != nil {
+			log.Printf("failed to write session summary: %s\n", err.Error())
+		}
+	}
+
 	msgs = contestantLog
 	msgs = append(msgs, benchResult.Messages...)
 
@@ -236,8 +250,8 @@ func execBench(ctx context.Context, job *Job) (*Result, error) {
 		log.Println("success benchmark")
 		return &Result{
 			ID:            job.ID,
-			Stdout:        joinN(strings.Split(stdout.String(), "\n"), messageLimit),
-			Stderr:        joinN(strings.Split(stderr.String(), "\n"), messageLimit),
+			Stdout:        joinN(strings.Split(redact.String(stdout.String()), "\n"), messageLimit),
+			Stderr:        joinN(strings.Split(redact.String(stderr.String()), "\n"), messageLimit),
 			Reason:        joinN(msgs, messageLimit),
 			IsPassed:      benchResult.Pass,
 			Score:         benchResult.Score,
@@ -250,8 +264,8 @@ func execBench(ctx context.Context, job *Job) (*Result, error) {
 		log.Println("fail benchmark")
 		return &Result{
 			ID:         job.ID,
-			Stdout:     joinN(strings.Split(stdout.String(), "\n"), messageLimit),
-			Stderr:     joinN(strings.Split(stderr.String(), "\n"), messageLimit),
+			Stdout:     joinN(strings.Split(redact.String(stdout.String()), "\n"), messageLimit),
+			Stderr:     joinN(strings.Split(redact.String(stderr.String()), "\n"), messageLimit),
 			Reason:     "ベンチマーク失敗",
 			IsPassed:   false,
 			Score:      0,
@@ -261,49 +275,67 @@ func execBench(ctx context.Context, job *Job) (*Result, error) {
 	}
 }
 
-var supervise = cli.Command{
+var supervise = &cli.Command{
 	Name:  "supervise",
 	Usage: "supervisor実行",
 	Flags: []cli.Flag{
-		cli.StringFlag{
+		&cli.StringFlag{
 			Name:        "access-key",
 			Value:       "",
 			Destination: &accessKey,
-			EnvVar:      "SUPERVISOR_ACCESS_KEY",
+			EnvVars:     []string{"SUPERVISOR_ACCESS_KEY"},
 		},
-		cli.StringFlag{
+		&cli.StringFlag{
 			Name:        "secret-access-key",
 			Value:       "",
 			Destination: &secretAccessKey,
-			EnvVar:      "SUPERVISOR_SECRET_ACCESS_KEY",
+			EnvVars:     []string{"SUPERVISOR_SECRET_ACCESS_KEY"},
 		},
-		cli.StringFlag{
+		&cli.StringFlag{
 			Name:        "slack-webhook-url",
 			Value:       "",
 			Destination: &slackWebhookURL,
-			EnvVar:      "SUPERVISOR_SLACK_WEBHOOK_URL",
+			EnvVars:     []string{"SUPERVISOR_SLACK_WEBHOOK_URL"},
 		},
-		cli.IntFlag{
+		&cli.IntFlag{
 			Name:        "message-limit",
 			Value:       200,
 			Destination: &messageLimit,
-			EnvVar:      "SUPERVISOR_MESSAGE_LIMIT",
+			EnvVars:     []string{"SUPERVISOR_MESSAGE_LIMIT"},
 		},
-		cli.BoolFlag{
+		&cli.BoolFlag{
 			Name:        "production",
 			Destination: &production,
-			EnvVar:      "SUPERVISOR_PRODUCTION",
+			EnvVars:     []string{"SUPERVISOR_PRODUCTION"},
+		},
+		&cli.StringFlag{
+			Name:        "session-results",
+			Value:       "",
+			Destination: &sessionResultsPath,
+			EnvVars:     []string{"SUPERVISOR_SESSION_RESULTS"},
 		},
 	},
 	Action: func(cliCtx *cli.Context) error {
-		ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGQUIT, syscall.SIGTERM, syscall.SIGHUP)
+		ctx, cancel := signal.NotifyContext(cliCtx.Context, syscall.SIGQUIT, syscall.SIGHUP, syscall.SIGTERM, os.Interrupt)
 		defer cancel()
 		log.Println("Start ISUPipe Supervisor")
+		// NOTE: 途中でエラーにより終了した場合も、それまでの走行結果を集計する
+		defer func() {
+			if len(sessionResultsPath) == 0 {
+				return
+			}
+			log.Println("write session summary")
+			if err := writeSessionSummary(sessionResultsPath); err != nil {
+				log.Printf("failed to write session summary: %s\n", err.Error())
+			}
+		}()
+		redact.Register(accessKey, secretAccessKey, slackWebhookURL)
 
 		log.Println("Fetching AZ Name ...")
-		azName, err := fetchAZName(context.Background())
+		azName, err := fetchAZName(ctx)
 		if err != nil {
-			log.Fatalln(err)
+			log.Println(err)
+			return cli.Exit(err, 1)
 		}
 		AZName = azName
 		log.Printf("AZ Name = %s\n", AZName)
@@ -337,7 +369,7 @@ var supervise = cli.Command{
 			)
 			if err != nil {
 				log.Println("failed to initiate portal")
-				return cli.NewExitError(err, 1)
+				return cli.Exit(err, 1)
 			}
 		} else {
 			log.Println("Running on development")
@@ -351,7 +383,7 @@ var supervise = cli.Command{
 			)
 			if err != nil {
 				log.Println("failed to initiate portal")
-				return cli.NewExitError(err, 1)
+				return cli.Exit(err, 1)
 			}
 		}
 		jobCh := portal.StartReceiveJob(ctx)
@@ -359,7 +391,7 @@ var supervise = cli.Command{
 		for {
 			select {
 			case <-ctx.Done():
-				return cli.NewExitError(ctx.Err(), 1)
+				return cli.Exit(ctx.Err(), 1)
 			case job := <-jobCh:
 				log.Printf("receive job = %+v\n", job)
 
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/isucon/isucon13/bench/internal/scheduler"
+	"github.com/urfave/cli/v2"
+)
+
+// usersCmd は、走行中に登録する配信者・視聴者の定義ファイル (assetdir/users.json) を扱うコマンドです
+var usersCmd = &cli.Command{
+	Name:  "users",
+	Usage: "配信者・視聴者の定義ファイルの操作 (運営向け)",
+	Subcommands: []*cli.Command{
+		{
+			Name:   "export",
+			Usage:  "組み込みの配信者・視聴者を定義ファイルの形式で標準出力に書き出す",
+			Action: exportUsers,
+		},
+		{
+			Name:      "validate",
+			Usage:     "assetdir の定義ファイルを検証",
+			ArgsUsage: "<assetdir>",
+			Action:    validateUsers,
+		},
+	},
+}
+
+func exportUsers(cliCtx *cli.Context) error {
+	if err := scheduler.UserScheduler.ExportPopulation(os.Stdout); err != nil {
+		return cli.Exit(err, 1)
+	}
+	return nil
+}
+
+func validateUsers(cliCtx *cli.Context) error {
+	dir := cliCtx.Args().First()
+	if len(dir) == 0 {
+		return cli.Exit("assetdir を指定してください", 1)
+	}
+
+	path := filepath.Join(dir, scheduler.PopulationFileName)
+	f, err := os.Open(path)
+	if err != nil {
+		return cli.Exit(err, 1)
+	}
+	defer f.Close()
+
+	population, err := scheduler.ParsePopulation(f)
+	if err != nil {
+		return cli.Exit(err, 1)
+	}
+	fmt.Printf("%s: 配信者 %d 人, 視聴者 %d 人\n", path, len(population.Streamers), len(population.Viewers))
+	return nil
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/isucon/isucon13/bench/internal/version"
+	"github.com/urfave/cli/v2"
+)
+
+var versionJSON bool
+
+// versionCmd は、ベンチマーカーのビルド情報を表示するコマンドです
+var versionCmd = &cli.Command{
+	Name:  "version",
+	Usage: "ベンチマーカーのビルド情報を表示",
+	Flags: []cli.Flag{
+		&cli.BoolFlag{
+			Name:        "json",
+			Usage:       "JSON形式で表示",
+			Destination: &versionJSON,
+		},
+	},
+	Action: func(cliCtx *cli.Context) error {
+		info := version.Get()
+		if !versionJSON {
+			fmt.Println(info.String())
+			return nil
+		}
+
+		b, err := json.Marshal(info)
+		if err != nil {
+			return cli.Exit(err, 1)
+		}
+		fmt.Println(string(b))
+		return nil
+	},
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/urfave/cli/v2"
+)
+
+// 走行可能期間外のため、走行を拒否した場合の終了コード
+// NOTE: 通常のエラー(1)と区別できるようにする
+const exitCodeOutsideRunWindow = 3
+
+// 走行可能期間 (RFC3339, 空の場合は制限なし)
+// NOTE: 競技終了後の凍結期間に、誤ってスコアが記録される走行が行われないようにする
+var (
+	notBefore string
+	notAfter  string
+)
+
+func parseRunWindow() (time.Time, time.Time, error) {
+	var from, until time.Time
+	if len(notBefore) > 0 {
+		t, err := time.Parse(time.RFC3339, notBefore)
+		if err != nil {
+			return from, until, fmt.Errorf("--not-before の形式が不正です (RFC3339で指定してください): %w", err)
+		}
+		from = t
+	}
+	if len(notAfter) > 0 {
+		t, err := time.Parse(time.RFC3339, notAfter)
+		if err != nil {
+			return from, until, fmt.Errorf("--not-after の形式が不正です (RFC3339で指定してください): %w", err)
+		}
+		until = t
+	}
+	if !from.IsZero() && !until.IsZero() && !from.Before(until) {
+		return from, until, fmt.Errorf("--not-before は --not-after より前の時刻を指定してください")
+	}
+	return from, until, nil
+}
+
+// checkRunWindow は、現在時刻が走行可能期間内か検証します
+// 期間外の場合は、専用の終了コードを持つエラーを返します
+func checkRunWindow(now time.Time) error {
+	from, until, err := parseRunWindow()
+	if err != nil {
+		return cli.Exit(err, 1)
+	}
+
+	if (!from.IsZero() && now.Before(from)) || (!until.IsZero() && now.After(until)) {
+		return cli.Exit(fmt.Sprintf("走行可能期間外のため、ベンチマーク走行を開始しません (現在時刻: %s, 走行可能期間: %s 〜 %s)",
+			now.Format(time.RFC3339), formatWindowBound(from), formatWindowBound(until)), exitCodeOutsideRunWindow)
+	}
+	return nil
+}
+
+func formatWindowBound(t time.Time) string {
+	if t.IsZero() {
+		return "制限なし"
+	}
+	return t.Format(time.RFC3339)
+}
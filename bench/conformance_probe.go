@@ -0,0 +1,40 @@
+package bench
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"strconv"
+
+	"github.com/isucon/isucon13/bench/internal/config"
+	"github.com/isucon/isucon13/bench/internal/conformance"
+	"github.com/isucon/isucon13/bench/internal/resolver"
+	"go.uber.org/zap"
+)
+
+// probeConformance は、HTTP/1.0や不正な形式のリクエストへのwebappの振る舞いを確かめ、運営向けに記録します
+// NOTE: 採点には用いない. 独自に実装したHTTPサーバの互換性の問題を、問い合わせの際に調べられるようにする
+func probeConformance(ctx context.Context, dnsResolver *resolver.DNSResolver) {
+	lgr := zap.S()
+
+	host := fmt.Sprintf("pipe.%s", config.BaseDomain)
+	target := conformance.Target{
+		Addr:      net.JoinHostPort(host, strconv.Itoa(config.TargetPort)),
+		Host:      host,
+		Path:      "/api/tag",
+		LoginPath: "/api/login",
+	}
+	if config.HTTPScheme == "https" {
+		target.TLS = &tls.Config{InsecureSkipVerify: config.InsecureSkipVerify}
+	}
+
+	for _, probe := range conformance.Probes {
+		r := conformance.Run(ctx, dnsResolver.DialContext, target, probe, config.ConformanceProbeTimeout)
+		if r.OK() {
+			lgr.Infof("HTTPの互換性の確認: %s: %s (status=%d) %s", r.Probe, r.Outcome, r.StatusCode, r.Detail)
+		} else {
+			lgr.Warnf("HTTPの互換性の確認: %s: %s (status=%d) %s", r.Probe, r.Outcome, r.StatusCode, r.Detail)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package bench
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/isucon/isucon13/bench/internal/benchscore"
+	"go.uber.org/zap"
+)
+
+// ScoreExplanation は、スコアタグごとのスコアへの寄与と減点です (--explain-score 指定時のみ)
+type ScoreExplanation struct {
+	Contributions []benchscore.ScoreContribution `json:"contributions"`
+	Deductions    []ScoreDeduction               `json:"deductions,omitempty"`
+	// 寄与の合計から減点を差し引いた最終スコア
+	Score int64 `json:"score"`
+}
+
+// ScoreDeduction は、スコアからの減点です
+type ScoreDeduction struct {
+	Reason string `json:"reason"`
+	Points int64  `json:"points"`
+}
+
+// explainScore は、スコアの内訳を表にして選手向けに出力します
+func explainScore(contestantLogger *zap.Logger, explanation *ScoreExplanation) {
+	var buf bytes.Buffer
+	w := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "スコアタグ\t配点\t回数\t寄与\t")
+	for _, c := range explanation.Contributions {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t\n", c.Tag, c.Weight, c.Count, c.Points)
+	}
+	for _, d := range explanation.Deductions {
+		fmt.Fprintf(w, "%s\t\t\t-%d\t\n", d.Reason, d.Points)
+	}
+	fmt.Fprintf(w, "スコア\t\t\t%d\t\n", explanation.Score)
+	w.Flush()
+
+	contestantLogger.Info("スコアの内訳を出力します (売上は売上額、体感品質は視聴を終えた視聴者数を回数とします)")
+	for _, line := range strings.Split(strings.TrimRight(buf.String(), "\n"), "\n") {
+		contestantLogger.Info(line)
+	}
+}
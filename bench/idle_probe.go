@@ -0,0 +1,70 @@
+package bench
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/isucon/isucon13/bench/internal/config"
+	"github.com/isucon/isucon13/bench/internal/idleprobe"
+	"github.com/isucon/isucon13/bench/internal/resolver"
+	"go.uber.org/zap"
+)
+
+// idleProber は、負荷走行と並行してwebappのアイドルタイムアウトを確かめます
+type idleProber struct {
+	mu      sync.Mutex
+	results []idleprobe.Result
+}
+
+// startIdleProbe は、放置する時間ごとに接続を張って確認を始めます
+// NOTE: 負荷走行の終了を待たずに結果をまとめるため、終わっていない確認は結果に含めない
+func startIdleProbe(ctx context.Context, periods []time.Duration, dnsResolver *resolver.DNSResolver) *idleProber {
+	p := &idleProber{}
+	host := fmt.Sprintf("pipe.%s", config.BaseDomain)
+	target := idleprobe.Target{
+		Addr: net.JoinHostPort(host, strconv.Itoa(config.TargetPort)),
+		Host: host,
+		Path: "/api/tag",
+	}
+	if config.HTTPScheme == "https" {
+		target.TLS = &tls.Config{InsecureSkipVerify: config.InsecureSkipVerify}
+	}
+	for _, period := range periods {
+		go func(period time.Duration) {
+			result := idleprobe.Probe(ctx, dnsResolver.DialContext, target, period, config.IdleProbeRequestTimeout)
+			p.mu.Lock()
+			defer p.mu.Unlock()
+			p.results = append(p.results, result)
+		}(period)
+	}
+	return p
+}
+
+// report は、終わった確認の結果をまとめ、選手向けに記録します
+func (p *idleProber) report(contestantLogger *zap.Logger) *idleprobe.Report {
+	p.mu.Lock()
+	results := append([]idleprobe.Result(nil), p.results...)
+	p.mu.Unlock()
+
+	report := idleprobe.Summarize(results, config.ClientIdleConnTimeout)
+	for _, r := range report.Results {
+		zap.S().Infof("アイドルタイムアウト確認: %+v", r)
+	}
+	if report.ObservedIdleTimeout > 0 {
+		contestantLogger.Info("webappのアイドルタイムアウトを観測しました", zap.Duration("idle_timeout", report.ObservedIdleTimeout))
+	}
+	if report.ShorterThanClient {
+		contestantLogger.Warn("webappのアイドルタイムアウトがベンチマーカーの接続の再利用期間より短いため、再接続が増える可能性があります",
+			zap.Duration("idle_timeout", report.ObservedIdleTimeout),
+			zap.Duration("client_idle_timeout", config.ClientIdleConnTimeout))
+	}
+	for _, v := range report.Violations {
+		contestantLogger.Warn("アイドル状態の接続の扱いに問題があります", zap.String("detail", v))
+	}
+	return report
+}
@@ -0,0 +1,147 @@
+// adaptive は、webappの応答状況に応じてシナリオのワーカー数を増減させます
+//
+// 一定間隔ごとにリクエストのエラー率とレイテンシを集計し、閾値を下回っていれば並列度を少しずつ上げ、
+// 上回れば大きく下げます (AIMD). 固定のワーカー数では、速い実装には負荷が足りず、遅い実装はタイムアウトに埋もれてしまうためです
+package adaptive
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/isucon/isucon13/bench/internal/metrics"
+)
+
+var errReleaseWithoutAcquire = errors.New("adaptive: 獲得していないワーカー枠を解放しました")
+
+// Limiter は、上限を動的に変更できるワーカー数の制限です
+// semaphore.Weighted の TryAcquire, Release と同じ形で利用できます
+type Limiter struct {
+	mu    sync.Mutex
+	base  int64
+	limit int64
+	inUse int64
+}
+
+// NewLimiter は、並列度の倍率が1のときbase件までワーカーを許可するLimiterを生成します
+func NewLimiter(base int64) *Limiter {
+	return &Limiter{base: base, limit: base}
+}
+
+func (l *Limiter) TryAcquire(n int64) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.inUse+n > l.limit {
+		return false
+	}
+	l.inUse += n
+	return true
+}
+
+func (l *Limiter) Release(n int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.inUse < n {
+		panic(errReleaseWithoutAcquire)
+	}
+	l.inUse -= n
+}
+
+// Limit は、現在許可しているワーカー数を返します
+func (l *Limiter) Limit() int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.limit
+}
+
+// setLevel は、上限を base * level にします
+// NOTE: 上限を下げても実行中のワーカーは止めず、終了するまで新しいワーカーを許可しない
+func (l *Limiter) setLevel(level float64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.limit = max(1, int64(math.Round(float64(l.base)*level)))
+}
+
+// Window は、一定間隔に観測したリクエストの集計です
+type Window struct {
+	Requests   int64
+	Errors     int64
+	LatencyP99 time.Duration
+}
+
+func (w Window) ErrorRate() float64 {
+	if w.Requests == 0 {
+		return 0
+	}
+	return float64(w.Errors) / float64(w.Requests)
+}
+
+// NOTE: すべてのリクエストで記録するため、ロックを取らずに加算する
+var window atomic.Pointer[windowCounter]
+
+func init() {
+	window.Store(newWindowCounter())
+}
+
+type windowCounter struct {
+	requests atomic.Int64
+	errors   atomic.Int64
+	buckets  []atomic.Int64
+}
+
+func newWindowCounter() *windowCounter {
+	return &windowCounter{buckets: make([]atomic.Int64, len(metrics.DefaultBuckets)+1)}
+}
+
+// ObserveRequest は、リクエストの結果を現在の集計区間に記録します
+// NOTE: 集計区間の切り替えと同時に記録したリクエストは、前の集計区間に含まれないことがある
+func ObserveRequest(d time.Duration, err error) {
+	w := window.Load()
+	w.requests.Add(1)
+	if err != nil {
+		w.errors.Add(1)
+		return
+	}
+	w.buckets[sort.SearchFloat64s(metrics.DefaultBuckets, d.Seconds())].Add(1)
+}
+
+// Snapshot は、現在の集計区間の結果を返し、次の集計区間を開始します
+func Snapshot() Window {
+	w := window.Swap(newWindowCounter())
+
+	buckets := make([]int64, len(w.buckets))
+	for i := range w.buckets {
+		buckets[i] = w.buckets[i].Load()
+	}
+	return Window{
+		Requests:   w.requests.Load(),
+		Errors:     w.errors.Load(),
+		LatencyP99: percentile(buckets, 0.99),
+	}
+}
+
+// percentile は、成功したリクエストの所要時間の推定値を返します
+func percentile(buckets []int64, q float64) time.Duration {
+	var total int64
+	for _, c := range buckets {
+		total += c
+	}
+	if total == 0 {
+		return 0
+	}
+
+	threshold := int64(float64(total) * q)
+	var cumulative int64
+	for i, c := range buckets {
+		cumulative += c
+		if cumulative > threshold || cumulative == total {
+			// NOTE: 最大のバケットを超えたものは、最大のバケットの上限として扱う
+			i = min(i, len(metrics.DefaultBuckets)-1)
+			return time.Duration(metrics.DefaultBuckets[i] * float64(time.Second))
+		}
+	}
+	return 0
+}
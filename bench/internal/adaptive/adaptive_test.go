@@ -0,0 +1,123 @@
+package adaptive
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testConfig = Config{
+	MaxErrorRate:   0.01,
+	MaxLatencyP99:  time.Second,
+	MinRequests:    10,
+	MaxLevel:       2,
+	MinLevel:       0.25,
+	Step:           0.5,
+	Backoff:        0.5,
+	SustainWindows: 2,
+}
+
+var (
+	healthyWindow  = Window{Requests: 100, LatencyP99: 100 * time.Millisecond}
+	slowWindow     = Window{Requests: 100, LatencyP99: 5 * time.Second}
+	errorfulWindow = Window{Requests: 100, Errors: 10, LatencyP99: 100 * time.Millisecond}
+	tooSmallWindow = Window{Requests: 1, Errors: 1}
+)
+
+func TestLimiter(t *testing.T) {
+	l := NewLimiter(2)
+	assert.True(t, l.TryAcquire(1))
+	assert.True(t, l.TryAcquire(1))
+	assert.False(t, l.TryAcquire(1))
+
+	// 上限を下げても、実行中のワーカーが終了するまで新しいワーカーを許可しない
+	l.setLevel(0.5)
+	assert.Equal(t, int64(1), l.Limit())
+	l.Release(1)
+	assert.False(t, l.TryAcquire(1))
+	l.Release(1)
+	assert.True(t, l.TryAcquire(1))
+
+	// 上限は1を下回らない
+	l.setLevel(0.1)
+	assert.Equal(t, int64(1), l.Limit())
+
+	assert.Panics(t, func() {
+		l.Release(2)
+	})
+}
+
+func TestController(t *testing.T) {
+	streamer, viewer := NewLimiter(1), NewLimiter(3)
+	c := NewController(testConfig, streamer, viewer)
+	assert.Equal(t, int64(4), c.Workers())
+
+	// 持続できるまでは上げない
+	assert.False(t, c.Update(healthyWindow))
+	assert.True(t, c.Update(healthyWindow))
+	assert.Equal(t, 1.5, c.Level())
+	assert.Equal(t, int64(2), streamer.Limit())
+	assert.Equal(t, int64(5), viewer.Limit())
+	assert.Equal(t, int64(4), c.Report().PeakSustained)
+
+	// リクエストが少ない区間では判断しない
+	assert.False(t, c.Update(tooSmallWindow))
+
+	assert.False(t, c.Update(healthyWindow))
+	assert.True(t, c.Update(healthyWindow))
+	assert.Equal(t, 2.0, c.Level())
+	assert.Equal(t, int64(7), c.Report().PeakSustained)
+
+	// 上限に達した後は上げない
+	assert.False(t, c.Update(healthyWindow))
+	assert.False(t, c.Update(healthyWindow))
+	assert.Equal(t, int64(8), c.Report().PeakSustained)
+
+	// レイテンシが閾値を超えたら下げる
+	assert.True(t, c.Update(slowWindow))
+	assert.Equal(t, 1.0, c.Level())
+	// 負荷プロファイルのワーカー数でも捌けなければ、倍率は1を下回る
+	assert.True(t, c.Update(errorfulWindow))
+	assert.Equal(t, 0.5, c.Level())
+	assert.Equal(t, int64(1), streamer.Limit())
+	assert.Equal(t, int64(2), viewer.Limit())
+	// 倍率は下限を下回らない
+	assert.True(t, c.Update(errorfulWindow))
+	assert.False(t, c.Update(errorfulWindow))
+	assert.Equal(t, 0.25, c.Level())
+
+	report := c.Report()
+	assert.Equal(t, int64(8), report.PeakSustained)
+	assert.Equal(t, int64(2), report.Final)
+	assert.Equal(t, 2, report.Increases)
+	assert.Equal(t, 3, report.Decreases)
+}
+
+func TestSnapshot(t *testing.T) {
+	Snapshot()
+
+	for i := 0; i < 198; i++ {
+		ObserveRequest(10*time.Millisecond, nil)
+	}
+	ObserveRequest(3*time.Second, nil)
+	ObserveRequest(0, errors.New("timeout"))
+
+	w := Snapshot()
+	assert.Equal(t, int64(200), w.Requests)
+	assert.Equal(t, int64(1), w.Errors)
+	assert.Equal(t, 0.005, w.ErrorRate())
+	assert.Equal(t, 10*time.Millisecond, w.LatencyP99)
+
+	// 次の区間は空から始まる
+	assert.Equal(t, Window{}, Snapshot())
+}
+
+func TestCurrentReport(t *testing.T) {
+	defer Set(nil)
+
+	assert.Nil(t, CurrentReport())
+	Set(NewController(testConfig, NewLimiter(2)))
+	assert.Equal(t, &Report{Final: 2}, CurrentReport())
+}
@@ -0,0 +1,148 @@
+package adaptive
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Config は、並列度を調整する閾値と幅です
+type Config struct {
+	// これらを下回る間は並列度を上げる
+	MaxErrorRate  float64
+	MaxLatencyP99 time.Duration
+	// 集計区間のリクエストがこれより少ない場合は判断しない
+	MinRequests int64
+	// 並列度の倍率の上限・下限と、上げる幅・下げる割合
+	// NOTE: 下限は0より大きくする. 倍率が1を下回っても、Limiterは1件のワーカーを許可する
+	MaxLevel float64
+	MinLevel float64
+	Step     float64
+	Backoff  float64
+	// この回数連続で閾値を下回った並列度を、持続できた並列度とみなして次の並列度に上げる
+	SustainWindows int
+}
+
+// Report は、並列度の調整結果です
+type Report struct {
+	// 閾値を下回ったまま持続できた最大のワーカー数
+	PeakSustained int64 `json:"peak_sustained"`
+	// 走行終了時点のワーカー数
+	Final int64 `json:"final"`
+	// 並列度を上げた/下げた回数
+	Increases int `json:"increases"`
+	Decreases int `json:"decreases"`
+}
+
+// Controller は、集計区間ごとの結果をもとにLimiterの上限を調整します
+type Controller struct {
+	mu       sync.Mutex
+	cfg      Config
+	limiters []*Limiter
+	level    float64
+	// 現在の並列度で閾値を下回った連続回数
+	streak int
+	report Report
+}
+
+func NewController(cfg Config, limiters ...*Limiter) *Controller {
+	c := &Controller{
+		cfg:      cfg,
+		limiters: limiters,
+		level:    1,
+	}
+	c.apply()
+	return c
+}
+
+// workers は、現在の上限の合計を返します
+func (c *Controller) workers() int64 {
+	var total int64
+	for _, l := range c.limiters {
+		total += l.Limit()
+	}
+	return total
+}
+
+func (c *Controller) apply() {
+	for _, l := range c.limiters {
+		l.setLevel(c.level)
+	}
+	c.report.Final = c.workers()
+}
+
+// Update は、集計区間の結果をもとに並列度を調整し、変更した場合はtrueを返します
+func (c *Controller) Update(w Window) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if w.Requests < c.cfg.MinRequests {
+		return false
+	}
+
+	healthy := w.ErrorRate() <= c.cfg.MaxErrorRate && w.LatencyP99 <= c.cfg.MaxLatencyP99
+	if !healthy {
+		c.streak = 0
+		next := max(c.cfg.MinLevel, c.level*c.cfg.Backoff)
+		if next == c.level {
+			return false
+		}
+		c.level = next
+		c.report.Decreases++
+		c.apply()
+		return true
+	}
+
+	// NOTE: 閾値を下回ったまま持続できた場合のみ、並列度を上げる
+	c.streak++
+	if c.streak < c.cfg.SustainWindows {
+		return false
+	}
+	c.report.PeakSustained = max(c.report.PeakSustained, c.workers())
+	next := min(c.cfg.MaxLevel, c.level+c.cfg.Step)
+	if next == c.level {
+		return false
+	}
+	c.level = next
+	c.streak = 0
+	c.report.Increases++
+	c.apply()
+	return true
+}
+
+// Level は、現在の並列度の倍率を返します
+func (c *Controller) Level() float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.level
+}
+
+// Workers は、現在許可しているワーカー数の合計を返します
+func (c *Controller) Workers() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.workers()
+}
+
+func (c *Controller) Report() Report {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.report
+}
+
+var current atomic.Pointer[Controller]
+
+// Set は、結果に記録するControllerを設定します
+func Set(c *Controller) {
+	current.Store(c)
+}
+
+// CurrentReport は、設定されたControllerの調整結果を返します. 設定されていなければnilを返します
+func CurrentReport() *Report {
+	c := current.Load()
+	if c == nil {
+		return nil
+	}
+	report := c.Report()
+	return &report
+}
@@ -2,9 +2,9 @@ package attacker
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
-	"math/rand"
 	"net"
 	"net/http"
 	"strconv"
@@ -14,8 +14,10 @@ import (
 	"time"
 	"unsafe"
 
+	"github.com/isucon/isucon13/bench/internal/benchrand"
 	"github.com/isucon/isucon13/bench/internal/benchscore"
 	"github.com/isucon/isucon13/bench/internal/config"
+	"github.com/isucon/isucon13/bench/internal/resolver"
 	"github.com/miekg/dns"
 	"github.com/valyala/bytebufferpool"
 )
@@ -31,9 +33,6 @@ const (
 	maxLength     = 22
 )
 
-var src = rand.NewSource(time.Now().UnixNano())
-var mutex sync.Mutex
-
 type DnsWaterTortureAttacker struct {
 	connected               bool
 	dnsClient               *dns.Client
@@ -45,10 +44,7 @@ type DnsWaterTortureAttacker struct {
 }
 
 func int63() int64 {
-	mutex.Lock()
-	n := src.Int63()
-	mutex.Unlock()
-	return n
+	return benchrand.Int63()
 }
 
 func randString(bb io.ByteWriter, n int) {
@@ -66,7 +62,8 @@ func randString(bb io.ByteWriter, n int) {
 }
 
 func NewDnsWaterTortureAttacker() *DnsWaterTortureAttacker {
-	c := &dns.Client{Net: "udp", Timeout: 1 * time.Second}
+	nameserver := net.JoinHostPort(config.TargetNameserver, strconv.Itoa(config.DNSPort))
+	c := resolver.NewDNSClient("udp", nameserver, 1*time.Second)
 	return &DnsWaterTortureAttacker{
 		maxRequestPerConnection: 10,
 		numRequestPerConnection: 0,
@@ -79,10 +76,10 @@ func (a *DnsWaterTortureAttacker) Attack(ctx context.Context, httpClient *http.C
 	defer bytebufferpool.Put(buf)
 	numOfLabel := 1
 	if atomic.AddUint64(&a.request, 1)%30 == 0 {
-		numOfLabel += rand.Intn(3)
+		numOfLabel += benchrand.Intn(3)
 	}
 	for i := 0; i < numOfLabel; i++ {
-		length := 10 + rand.Intn(maxLength)
+		length := 10 + benchrand.Intn(maxLength)
 		randString(buf, length)
 		buf.WriteByte('0')
 		buf.WriteByte('.')
@@ -98,7 +95,7 @@ func (a *DnsWaterTortureAttacker) Attack(ctx context.Context, httpClient *http.C
 		url := fmt.Sprintf("%s://%s/%s",
 			config.HTTPScheme,
 			host,
-			endpoints[rand.Intn(len(endpoints))],
+			endpoints[benchrand.Intn(len(endpoints))],
 		)
 		valueCtx := context.WithValue(ctx, config.AttackHTTPClientContextKey,
 			fmt.Sprintf("%s:%d", ip.String(), config.TargetPort))
@@ -147,11 +144,17 @@ func (a *DnsWaterTortureAttacker) lookup(ctx context.Context, name string) net.I
 	msg.RecursionDesired = false
 
 	a.numRequestPerConnection++
-	in, _, err := a.dnsClient.ExchangeWithConn(msg, a.dnsConn)
+	in, err := resolver.ExchangeWithConn(a.dnsConn, msg, a.dnsClient.Timeout)
 	if err != nil {
 		a.dnsConn.Close()
 		a.connected = false
-		benchscore.IncDNSFailed()
+		benchscore.IncDNSFailed(ctx)
+		if errors.Is(err, resolver.ErrMalformedResponse) {
+			benchscore.IncDNSMalformed(ctx)
+		}
+		if errors.Is(err, resolver.ErrOversizedResponse) {
+			benchscore.IncDNSOversized(ctx)
+		}
 		return nil
 	}
 	if a.numRequestPerConnection >= a.maxRequestPerConnection {
@@ -160,7 +163,7 @@ func (a *DnsWaterTortureAttacker) lookup(ctx context.Context, name string) net.I
 		a.numRequestPerConnection = 0
 	}
 	// プロトコル上成功をカウントする
-	benchscore.IncResolves()
+	benchscore.IncResolves(ctx)
 
 	for _, ans := range in.Answer {
 		if record, ok := ans.(*dns.A); ok {
@@ -0,0 +1,109 @@
+// bandwidth は、ベンチマーカーがwebappと送受信したHTTPのバイト数を、エンドポイントの分類ごとに集計します
+//
+// 大会当日のネットワークの帯域を見積もれるよう、リハーサルの走行全体 (初期化から最終チェックまで) の転送量を記録します
+package bandwidth
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+// bytesPerMB は、転送量の表示に用いる MB のバイト数です
+// NOTE: 回線の帯域 (Mbps) と比べやすいよう、10進の MB とする
+const bytesPerMB = 1000 * 1000
+
+// Stat は、エンドポイントの分類ごとの送受信の集計です
+type Stat struct {
+	Class    string `json:"class"`
+	Requests int64  `json:"requests"`
+	// 送信したリクエストのバイト数 (リクエストライン・ヘッダ・ボディ)
+	SentBytes int64 `json:"sent_bytes"`
+	// 受信したレスポンスのバイト数 (ステータスライン・ヘッダ・通信路上のボディ)
+	ReceivedBytes int64 `json:"received_bytes"`
+}
+
+// Report は、走行全体の送受信の集計です. 結果に記録します
+type Report struct {
+	Requests      int64   `json:"requests"`
+	SentBytes     int64   `json:"sent_bytes"`
+	ReceivedBytes int64   `json:"received_bytes"`
+	TotalMB       float64 `json:"total_mb"`
+	Classes       []Stat  `json:"classes"`
+}
+
+// TotalBytes は、送受信したバイト数の合計を返します
+func (r *Report) TotalBytes() int64 {
+	return r.SentBytes + r.ReceivedBytes
+}
+
+// MB は、バイト数を MB に換算します
+func MB(bytes int64) float64 {
+	return float64(bytes) / bytesPerMB
+}
+
+// Meter は、1回の走行で送受信したバイト数を集計します
+// NOTE: 走行ごとに作り、contextに紐づけてTransportから記録する
+type Meter struct {
+	mu    sync.Mutex
+	stats map[string]*Stat
+}
+
+// NewMeter は、空の Meter を返します
+func NewMeter() *Meter {
+	return &Meter{stats: map[string]*Stat{}}
+}
+
+type meterContextKey struct{}
+
+// WithMeter は、Meter をcontextに紐づけます
+func WithMeter(ctx context.Context, m *Meter) context.Context {
+	return context.WithValue(ctx, meterContextKey{}, m)
+}
+
+// FromContext は、contextに紐づく Meter を返します。紐づいていなければnilを返します
+func FromContext(ctx context.Context) *Meter {
+	m, _ := ctx.Value(meterContextKey{}).(*Meter)
+	return m
+}
+
+// Observe は、リクエスト1件の送信と受信のバイト数を記録します. nilの場合は何もしません
+func (m *Meter) Observe(class string, sentBytes, receivedBytes int64) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stat, ok := m.stats[class]
+	if !ok {
+		stat = &Stat{Class: class}
+		m.stats[class] = stat
+	}
+	stat.Requests++
+	stat.SentBytes += sentBytes
+	stat.ReceivedBytes += receivedBytes
+}
+
+// Report は、記録した送受信の集計を返します. 記録がなければnilを返します
+func (m *Meter) Report() *Report {
+	if m == nil {
+		return nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.stats) == 0 {
+		return nil
+	}
+	report := &Report{Classes: make([]Stat, 0, len(m.stats))}
+	for _, stat := range m.stats {
+		report.Requests += stat.Requests
+		report.SentBytes += stat.SentBytes
+		report.ReceivedBytes += stat.ReceivedBytes
+		report.Classes = append(report.Classes, *stat)
+	}
+	report.TotalMB = MB(report.TotalBytes())
+	sort.Slice(report.Classes, func(i, j int) bool {
+		return report.Classes[i].Class < report.Classes[j].Class
+	})
+	return report
+}
@@ -0,0 +1,44 @@
+package bandwidth
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMeter_Report(t *testing.T) {
+	m := NewMeter()
+	assert.Nil(t, m.Report())
+
+	m.Observe("read", 200, 1_000_000)
+	m.Observe("read", 300, 500_000)
+	m.Observe("write", 1_000_000, 200)
+
+	report := m.Report()
+	if assert.NotNil(t, report) {
+		assert.Equal(t, int64(3), report.Requests)
+		assert.Equal(t, int64(1_000_500), report.SentBytes)
+		assert.Equal(t, int64(1_500_200), report.ReceivedBytes)
+		assert.Equal(t, int64(2_500_700), report.TotalBytes())
+		assert.InDelta(t, 2.5007, report.TotalMB, 1e-9)
+		assert.Equal(t, []Stat{
+			{Class: "read", Requests: 2, SentBytes: 500, ReceivedBytes: 1_500_000},
+			{Class: "write", Requests: 1, SentBytes: 1_000_000, ReceivedBytes: 200},
+		}, report.Classes)
+	}
+}
+
+func TestMeter_Nil(t *testing.T) {
+	// contextに紐づいていなければ記録しない
+	m := FromContext(context.Background())
+	assert.Nil(t, m)
+	m.Observe("read", 200, 1_000_000)
+	assert.Nil(t, m.Report())
+
+	m = NewMeter()
+	FromContext(WithMeter(context.Background(), m)).Observe("read", 200, 1_000_000)
+	if report := m.Report(); assert.NotNil(t, report) {
+		assert.Equal(t, int64(1), report.Requests)
+	}
+}
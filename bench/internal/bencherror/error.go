@@ -8,7 +8,7 @@ import (
 	"time"
 
 	"github.com/isucon/isucandar/failure"
-	"go.uber.org/zap"
+	"github.com/isucon/isucon13/bench/internal/config"
 )
 
 var (
@@ -27,86 +27,202 @@ var (
 	BenchmarkTimeoutError failure.StringCode = "benchmark-timeout"
 )
 
-var (
-	benchErrors  *failure.Errors
-	systemErrors *failure.Errors
-	doneOnce     sync.Once
+// Category は、選手向けにエラーを分類する種別です
+type Category string
+
+const (
+	// CategoryValidation は、レスポンスの検証に失敗したエラー
+	CategoryValidation Category = "validation"
+	// CategoryTimeout は、リクエストのタイムアウトによるエラー
+	CategoryTimeout Category = "timeout"
+	// CategoryInternal は、ベンチマーカー内部のエラー
+	CategoryInternal Category = "internal"
+	// CategoryPenalty は、検証以外でベンチ走行中に発生した一般的なエラー
+	CategoryPenalty Category = "penalty"
 )
 
-func InitErrors(ctx context.Context) {
-	benchErrors = failure.NewErrors(ctx)
-	systemErrors = failure.NewErrors(ctx)
+// Categories は、メッセージ出力時のカテゴリの並び順です
+var Categories = []Category{
+	CategoryValidation,
+	CategoryTimeout,
+	CategoryPenalty,
+	CategoryInternal,
+}
+
+// categoryError は、カテゴリと選手向けのヒントを保持するエラーです
+type categoryError struct {
+	category Category
+	hint     string
+	err      error
+}
+
+func (e *categoryError) Error() string {
+	return e.err.Error()
+}
+
+func (e *categoryError) Unwrap() error {
+	return e.err
+}
+
+// CategoryOf は、エラーのカテゴリを返します
+// カテゴリが付与されていないエラーはCategoryPenaltyとして扱います
+func CategoryOf(err error) Category {
+	var catErr *categoryError
+	if errors.As(err, &catErr) {
+		return catErr.category
+	}
+	return CategoryPenalty
+}
+
+// HintOf は、エラーに付与されたヒントを返します
+func HintOf(err error) string {
+	var catErr *categoryError
+	if errors.As(err, &catErr) {
+		return catErr.hint
+	}
+	return ""
+}
+
+// ErrorSet は、1回の走行(pretest、負荷走行など)で発生したエラーの記録です
+// 走行ごとに作り直すことで、前の走行のエラーや終了処理の影響を受けずに記録できます
+// NOTE: エラーの生成時は、WithErrorSet でcontextに紐づけた ErrorSet に記録する
+type ErrorSet struct {
+	benchErrors  *errorStore
+	systemErrors *errorStore
+	doneOnce     sync.Once
+}
+
+func NewErrorSet() *ErrorSet {
+	return &ErrorSet{
+		benchErrors:  newErrorStore(config.MaxUniqueErrorMessages),
+		systemErrors: newErrorStore(config.MaxUniqueErrorMessages),
+	}
+}
+
+// NOTE: 走行に紐づかないcontextで生成されたエラーも記録できるよう、既定の ErrorSet を持つ
+var defaultSet = NewErrorSet()
+
+// Default は、contextに ErrorSet が紐づいていない場合の記録先を返します
+func Default() *ErrorSet {
+	return defaultSet
 }
 
-func WrapError(code failure.StringCode, err error) error {
-	benchErrors.Add(failure.NewError(code, err))
-	return fmt.Errorf("%s: %w", code, err)
+type errorSetContextKey struct{}
+
+// WithErrorSet は、ErrorSet をcontextに紐づけます
+// NOTE: エラーの生成時は、contextに紐づく ErrorSet に記録する
+func WithErrorSet(ctx context.Context, set *ErrorSet) context.Context {
+	return context.WithValue(ctx, errorSetContextKey{}, set)
 }
 
-func WrapInternalError(code failure.StringCode, err error) error {
-	systemErrors.Add(failure.NewError(code, err))
-	return fmt.Errorf("%s: %w", code, err)
+// FromContext は、contextに紐づく ErrorSet を返します。紐づいていなければ既定の ErrorSet を返します
+func FromContext(ctx context.Context) *ErrorSet {
+	if set, ok := ctx.Value(errorSetContextKey{}).(*ErrorSet); ok {
+		return set
+	}
+	return defaultSet
 }
 
-func extractErrors(errs *failure.Errors) map[string][]string {
-	lgr := zap.S()
+func WrapError(ctx context.Context, code failure.StringCode, err error) error {
+	return WrapCategoryError(ctx, code, CategoryPenalty, "", err)
+}
 
-	// メッセージを整形した上でコード種別ごと詰め直して返す
+// WrapCategoryError は、カテゴリとヒントを付与してエラーを記録します
+// hintは空文字を許容します
+func WrapCategoryError(ctx context.Context, code failure.StringCode, category Category, hint string, err error) error {
+	if !errors.Is(err, ErrEndpointSkipped) {
+		FromContext(ctx).benchErrors.add(string(code), category, hint, err.Error())
+	}
+	return fmt.Errorf("%s: %w", code, &categoryError{
+		category: category,
+		hint:     hint,
+		err:      err,
+	})
+}
+
+func WrapInternalError(ctx context.Context, code failure.StringCode, err error) error {
+	FromContext(ctx).systemErrors.add(string(code), CategoryInternal, "", err.Error())
+	return fmt.Errorf("%s: %w", code, &categoryError{
+		category: CategoryInternal,
+		err:      err,
+	})
+}
+
+// formatEntry は、同種のエラーが複数発生していれば件数を付け加えます
+func formatEntry(entry messageEntry) string {
+	if entry.count > 1 {
+		return fmt.Sprintf("%s (同種のエラー %d 件)", entry.message, entry.count)
+	}
+	return entry.message
+}
+
+func extractErrors(errs *errorStore) map[string][]string {
+	// 重複排除したメッセージをコード種別ごと詰め直して返す
 	m := make(map[string][]string)
-	for _, e := range errs.All() {
-		code := failure.GetErrorCode(e)
+	for _, entry := range errs.entries() {
+		m[entry.code] = append(m[entry.code], formatEntry(entry))
+	}
 
-		failureErr, ok := e.(*failure.Error)
-		if !ok {
-			lgr.Warnf("ベンチマーカーが制御できないエラーが発生しました: %+v", e)
-			continue
-		}
+	return m
+}
 
-		err := failureErr.Unwrap()
-		if err == nil {
-			lgr.Warnf("ベンチマーカーが制御できないエラーが発生しました: %+v", e)
-		}
+// FinalBenchErrors は、選手向けのエラーメッセージをコード種別ごとに返します
+func (set *ErrorSet) FinalBenchErrors() map[string][]string {
+	return extractErrors(set.benchErrors)
+}
 
-		if _, ok := m[code]; !ok {
-			m[code] = []string{err.Error()}
-		} else {
-			m[code] = append(m[code], err.Error())
+// FinalSystemErrors は、ベンチマーカー内部のエラーメッセージをコード種別ごとに返します
+func (set *ErrorSet) FinalSystemErrors() map[string][]string {
+	return extractErrors(set.systemErrors)
+}
+
+// FinalErrorMessages は、選手向けのエラーメッセージを重複排除し、カテゴリごとにまとめて返します
+// ヒントが付与されている場合、メッセージの末尾に付け加えます
+// NOTE: 内部エラーは選手に見せないため、benchErrorsのみを対象とします
+func (set *ErrorSet) FinalErrorMessages() map[Category][]string {
+	m := make(map[Category][]string)
+	for _, entry := range set.benchErrors.entries() {
+		msg := formatEntry(entry)
+		if len(entry.hint) > 0 {
+			msg = fmt.Sprintf("%s (ヒント: %s)", msg, entry.hint)
 		}
+		m[entry.category] = append(m[entry.category], msg)
 	}
 
 	return m
 }
 
-func GetFinalBenchErrors() map[string][]string {
-	return extractErrors(benchErrors)
+// NumEvictedMessages は、重複排除したメッセージの上限を超えたため捨てたメッセージの種類数を返します
+func (set *ErrorSet) NumEvictedMessages() int64 {
+	return set.benchErrors.numEvicted() + set.systemErrors.numEvicted()
 }
 
-func GetFinalSystemErrors() map[string][]string {
-	return extractErrors(systemErrors)
+// CategoryCounts は、カテゴリごとのエラー件数を返します
+func (set *ErrorSet) CategoryCounts() map[Category]int64 {
+	counts := make(map[Category]int64)
+	for _, category := range Categories {
+		counts[category] = set.benchErrors.categoryCount(category) + set.systemErrors.categoryCount(category)
+	}
+
+	return counts
 }
 
-func Done() {
-	doneOnce.Do(func() {
-		benchErrors.Close()
-		systemErrors.Close()
+// Done は、以降のエラーを記録しないようにします。複数回呼び出しても安全です
+func (set *ErrorSet) Done() {
+	set.doneOnce.Do(func() {
+		set.benchErrors.close()
+		set.systemErrors.close()
 	})
 }
 
-func CheckViolation() error {
-	systemCounts := systemErrors.Count()
-	systemErrorCount, ok := systemCounts[string(SystemError)]
-	if !ok {
-		systemErrorCount = 0
-	}
+// CheckViolation は、走行を継続できないシステムエラーや仕様違反エラーが記録されていればエラーを返します
+func (set *ErrorSet) CheckViolation() error {
+	systemErrorCount := set.systemErrors.codeCount(string(SystemError))
 	if systemErrorCount > 0 {
 		return fmt.Errorf("%d件のシステムエラー: %w", systemErrorCount, ErrSystem)
 	}
 
-	benchCounts := benchErrors.Count()
-	violationCount, ok := benchCounts[string(BenchmarkViolationError)]
-	if !ok {
-		violationCount = 0
-	}
+	violationCount := set.benchErrors.codeCount(string(BenchmarkViolationError))
 	if violationCount > 0 {
 		return fmt.Errorf("%d件の仕様違反エラー: %w", violationCount, ErrViolation)
 	}
@@ -114,7 +230,7 @@ func CheckViolation() error {
 	return nil
 }
 
-func RunViolationChecker(ctx context.Context) chan error {
+func (set *ErrorSet) RunViolationChecker(ctx context.Context) chan error {
 	violate := make(chan error, 1)
 	go func() {
 		t := time.NewTicker(10 * time.Millisecond)
@@ -124,7 +240,7 @@ func RunViolationChecker(ctx context.Context) chan error {
 			select {
 			case <-ctx.Done():
 			case <-t.C:
-				if err := CheckViolation(); err != nil {
+				if err := set.CheckViolation(); err != nil {
 					violate <- err
 					return
 				}
@@ -133,3 +249,38 @@ func RunViolationChecker(ctx context.Context) chan error {
 	}()
 	return violate
 }
+
+// 以下のパッケージの関数は、既定の ErrorSet を対象とします
+
+func GetFinalBenchErrors() map[string][]string {
+	return defaultSet.FinalBenchErrors()
+}
+
+func GetFinalSystemErrors() map[string][]string {
+	return defaultSet.FinalSystemErrors()
+}
+
+func GetFinalErrorMessages() map[Category][]string {
+	return defaultSet.FinalErrorMessages()
+}
+
+func NumEvictedMessages() int64 {
+	return defaultSet.NumEvictedMessages()
+}
+
+// GetCategoryCounts は、カテゴリごとのエラー件数を返します
+func GetCategoryCounts() map[Category]int64 {
+	return defaultSet.CategoryCounts()
+}
+
+func Done() {
+	defaultSet.Done()
+}
+
+func CheckViolation() error {
+	return defaultSet.CheckViolation()
+}
+
+func RunViolationChecker(ctx context.Context) chan error {
+	return defaultSet.RunViolationChecker(ctx)
+}
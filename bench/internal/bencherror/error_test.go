@@ -0,0 +1,88 @@
+package bencherror
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCategoryAndHint(t *testing.T) {
+	ctx := WithErrorSet(context.Background(), NewErrorSet())
+
+	req, err := http.NewRequest(http.MethodGet, "http://pipe.u.isucon.dev/api/user/1/icon", nil)
+	assert.NoError(t, err)
+	cause := errors.New("cause")
+
+	testCases := []struct {
+		name     string
+		newErr   func() error
+		category Category
+		hint     string
+	}{
+		{"timeout", func() error { return NewTimeoutError(ctx, cause, "タイムアウト") }, CategoryTimeout, hintTimeout},
+		{"server error", func() error { return NewHttpStatusError(ctx, req, http.StatusOK, http.StatusInternalServerError) }, CategoryValidation, hintServerError},
+		// 5xx以外のステータスコードの不一致にはヒントを付与しない
+		{"status mismatch", func() error { return NewHttpStatusError(ctx, req, http.StatusOK, http.StatusNotFound) }, CategoryValidation, ""},
+		{"response format", func() error { return NewHttpResponseError(ctx, cause, req) }, CategoryValidation, hintResponseFormat},
+		{"empty response", func() error { return NewEmptyHttpResponseError(ctx, []string{"id"}, req) }, CategoryValidation, hintResponseFormat},
+		{"invalid response", func() error { return NewInvalidHttpResponseError(ctx, []string{"id"}, req) }, CategoryValidation, hintResponseFormat},
+		{"violation", func() error { return NewViolationError(ctx, cause, "仕様違反") }, CategoryValidation, ""},
+		{"assertion", func() error { return NewAssertionError(ctx, cause, "仕様違反") }, CategoryValidation, ""},
+		{"content length", func() error { return NewContentLengthError(ctx, cause, req, 10, 5) }, CategoryValidation, hintContentLength},
+		{"head mismatch", func() error { return NewHeadMismatchError(ctx, req, "不一致") }, CategoryValidation, hintContentLength},
+		{"caching", func() error { return NewCachingError(ctx, req, "ETagが不正") }, CategoryValidation, hintCaching},
+		{"tls certificate", func() error { return NewTLSCertificateError(ctx, cause, "証明書が不正") }, CategoryValidation, hintTLSCertificate},
+		{"dns provisioning", func() error { return NewDNSProvisioningError(ctx, cause, "名前解決できない") }, CategoryValidation, hintDNSProvisioning},
+		{"dns edns", func() error { return NewDNSEDNSError(ctx, cause, "応答が大きい") }, CategoryValidation, hintDNSEDNS},
+		{"dns zone", func() error { return NewDNSZoneError(ctx, cause, "NSレコードが不正") }, CategoryValidation, hintDNSZone},
+		{"fallback icon", func() error { return NewFallbackIconError(ctx, cause, "NoImage.jpgと一致しない") }, CategoryValidation, hintFallbackIcon},
+		{"application", func() error { return NewApplicationError(ctx, cause, "一般エラー") }, CategoryPenalty, ""},
+		{"http", func() error { return NewHttpError(ctx, cause, req, "一般エラー") }, CategoryPenalty, ""},
+		{"internal", func() error { return NewInternalError(ctx, cause) }, CategoryInternal, ""},
+		// カテゴリが付与されていないエラーは一般的なエラーとして扱う
+		{"plain", func() error { return cause }, CategoryPenalty, ""},
+	}
+	for _, tc := range testCases {
+		err := tc.newErr()
+		assert.Equal(t, tc.category, CategoryOf(err), tc.name)
+		assert.Equal(t, tc.hint, HintOf(err), tc.name)
+	}
+}
+
+func TestErrorSet_FinalErrorMessages(t *testing.T) {
+	set := NewErrorSet()
+	ctx := WithErrorSet(context.Background(), set)
+
+	req, err := http.NewRequest(http.MethodGet, "http://pipe.u.isucon.dev/api/tag", nil)
+	assert.NoError(t, err)
+
+	NewHttpStatusError(ctx, req, http.StatusOK, http.StatusInternalServerError)
+	NewHttpStatusError(ctx, req, http.StatusOK, http.StatusNotFound)
+	NewTimeoutError(ctx, errors.New("deadline"), "GET /api/tag")
+	NewApplicationError(ctx, errors.New("cause"), "一般エラー")
+	NewInternalError(ctx, errors.New("cause"))
+	set.Done()
+
+	messages := set.FinalErrorMessages()
+	// ヒントはメッセージの末尾に付け加える
+	if assert.Len(t, messages[CategoryValidation], 2) {
+		assert.Contains(t, messages[CategoryValidation][0], "(ヒント: "+hintServerError+")")
+		assert.NotContains(t, messages[CategoryValidation][1], "ヒント")
+	}
+	if assert.Len(t, messages[CategoryTimeout], 1) {
+		assert.Contains(t, messages[CategoryTimeout][0], "(ヒント: "+hintTimeout+")")
+	}
+	assert.Len(t, messages[CategoryPenalty], 1)
+	// 内部エラーは選手に見せない
+	assert.Empty(t, messages[CategoryInternal])
+
+	assert.Equal(t, map[Category]int64{
+		CategoryValidation: 2,
+		CategoryTimeout:    1,
+		CategoryPenalty:    1,
+		CategoryInternal:   1,
+	}, set.CategoryCounts())
+}
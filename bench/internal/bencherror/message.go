@@ -1,6 +1,7 @@
 package bencherror
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net/http"
@@ -10,65 +11,187 @@ import (
 // NOTE: Goのhttp.Clientがcontext.DeadlineExceededをラップして返してくれないので、暫定対応
 var ErrTimeout = errors.New("タイムアウトによりリクエスト失敗")
 
+// 選手向けのヒント
+const (
+	hintTimeout        = "アプリケーションの応答が遅延しています。スロークエリやロック待ちが発生していないか確認してください"
+	hintServerError    = "サーバ内部でエラーが発生しています。webappのエラーログを確認してください"
+	hintResponseFormat = "レスポンスボディがAPI仕様と一致しているか確認してください"
+)
+
 // ベンチマーカー本体由来のエラー
 
-func NewInternalError(err error) error {
+func NewInternalError(ctx context.Context, err error) error {
 	err = fmt.Errorf("[ベンチ本体のエラー] スタッフにのみ表示されます: %w", err)
-	return WrapInternalError(SystemError, err)
+	return WrapInternalError(ctx, SystemError, err)
 }
 
 // タイムアウト
 
-func NewTimeoutError(err error, msg string, args ...interface{}) error {
+func NewTimeoutError(ctx context.Context, err error, msg string, args ...interface{}) error {
 	message := fmt.Sprintf(msg, args...)
 	err = fmt.Errorf("%s: %w", err.Error(), ErrTimeout)
 	err = fmt.Errorf("[リクエストタイムアウト] %s: %w", message, err)
-	return WrapError(BenchmarkTimeoutError, err)
+	return WrapCategoryError(ctx, BenchmarkTimeoutError, CategoryTimeout, hintTimeout, err)
 }
 
 // 一般エラー
 
-func NewApplicationError(err error, msg string, args ...interface{}) error {
+func NewApplicationError(ctx context.Context, err error, msg string, args ...interface{}) error {
 	message := fmt.Sprintf(msg, args...)
 	err = fmt.Errorf("[一般エラー] %s: %w", message, err)
-	return WrapError(BenchmarkApplicationError, err)
+	return WrapError(ctx, BenchmarkApplicationError, err)
 }
 
-func NewHttpError(err error, req *http.Request, msg string, args ...interface{}) error {
+func NewHttpError(ctx context.Context, err error, req *http.Request, msg string, args ...interface{}) error {
 	endpoint := fmt.Sprintf("%s %s", req.Method, req.URL.EscapedPath())
 	message := fmt.Sprintf(msg, args...)
 	err = fmt.Errorf("[一般エラー] %sへのリクエストに対して、%s: %w", endpoint, message, err)
-	return WrapError(BenchmarkApplicationError, err)
+	return WrapError(ctx, BenchmarkApplicationError, err)
 }
 
-func NewHttpStatusError(req *http.Request, expected int, actual int) error {
+func NewHttpStatusError(ctx context.Context, req *http.Request, expected int, actual int) error {
 	endpoint := fmt.Sprintf("%s %s", req.Method, req.URL.EscapedPath())
 	err := fmt.Errorf("[一般エラー] %s へのリクエストに対して、期待されたHTTPステータスコードが確認できませんでした (expected:%d, actual:%d)", endpoint, expected, actual)
-	return WrapError(BenchmarkApplicationError, err)
+	var hint string
+	if actual >= http.StatusInternalServerError {
+		hint = hintServerError
+	}
+	return WrapCategoryError(ctx, BenchmarkApplicationError, CategoryValidation, hint, err)
 }
 
-func NewHttpResponseError(err error, req *http.Request) error {
+func NewHttpResponseError(ctx context.Context, err error, req *http.Request) error {
 	endpoint := fmt.Sprintf("%s %s", req.Method, req.URL.EscapedPath())
 	err = fmt.Errorf("[一般エラー] %s へのリクエストに対して、レスポンスボディの形式が不正です: %w", endpoint, err)
-	return WrapError(BenchmarkApplicationError, err)
+	return WrapCategoryError(ctx, BenchmarkApplicationError, CategoryValidation, hintResponseFormat, err)
 }
 
 // 仕様違反
 
-func NewViolationError(err error, msg string, args ...interface{}) error {
+func NewViolationError(ctx context.Context, err error, msg string, args ...interface{}) error {
 	message := fmt.Sprintf(msg, args...)
 	err = fmt.Errorf("[仕様違反] %s: %w", message, err)
-	return WrapError(BenchmarkViolationError, err)
+	return WrapCategoryError(ctx, BenchmarkViolationError, CategoryValidation, "", err)
 }
 
-func NewAssertionError(err error, msg string, args ...interface{}) error {
+func NewAssertionError(ctx context.Context, err error, msg string, args ...interface{}) error {
 	message := fmt.Sprintf(msg, args...)
 	err = fmt.Errorf("[仕様違反] %s: %w", message, err)
-	return WrapError(BenchmarkViolationError, err)
+	return WrapCategoryError(ctx, BenchmarkViolationError, CategoryValidation, "", err)
 }
 
-func NewEmptyHttpResponseError(errorFields []string, req *http.Request) error {
+func NewEmptyHttpResponseError(ctx context.Context, errorFields []string, req *http.Request) error {
 	endpoint := fmt.Sprintf("%s %s", req.Method, req.URL.EscapedPath())
 	err := fmt.Errorf("[仕様違反] %s へのリクエストに対して、レスポンスボディに必要なフィールドがありません: %s", endpoint, strings.Join(errorFields, ","))
-	return WrapError(BenchmarkViolationError, err)
+	return WrapCategoryError(ctx, BenchmarkViolationError, CategoryValidation, hintResponseFormat, err)
+}
+
+func NewInvalidHttpResponseError(ctx context.Context, messages []string, req *http.Request) error {
+	endpoint := fmt.Sprintf("%s %s", req.Method, req.URL.EscapedPath())
+	err := fmt.Errorf("[仕様違反] %s へのリクエストに対して、レスポンスボディの値が不正です: %s", endpoint, strings.Join(messages, ", "))
+	return WrapCategoryError(ctx, BenchmarkViolationError, CategoryValidation, hintResponseFormat, err)
+}
+
+// プロトコル
+
+const hintContentLength = "レスポンスを逐次書き出すハンドラが途中で打ち切っていないか、Content-Lengthを実際のボディと異なる値で設定していないか確認してください"
+
+// ErrContentLengthMismatch は、Content-Lengthとレスポンスボディの長さが一致しないことを表します
+var ErrContentLengthMismatch = errors.New("Content-Lengthとレスポンスボディの長さが一致しません")
+
+// NewContentLengthError は、Content-Lengthと実際に受信したボディの長さが一致しないことを記録します
+// NOTE: 宣言より長いボディは途中で打ち切られるため、actualは受信できた長さとなる
+func NewContentLengthError(ctx context.Context, err error, req *http.Request, declared, actual int64) error {
+	endpoint := fmt.Sprintf("%s %s", req.Method, req.URL.EscapedPath())
+	err = fmt.Errorf("[プロトコルエラー] %s へのリクエストに対して、%w (Content-Length: %d, 受信: %d bytes): %s", endpoint, ErrContentLengthMismatch, declared, actual, err.Error())
+	return WrapCategoryError(ctx, BenchmarkApplicationError, CategoryValidation, hintContentLength, err)
+}
+
+// NewHeadMismatchError は、HEADリクエストへのレスポンスがGETと一致しないことを記録します
+func NewHeadMismatchError(ctx context.Context, req *http.Request, msg string, args ...interface{}) error {
+	endpoint := fmt.Sprintf("%s %s", req.Method, req.URL.EscapedPath())
+	message := fmt.Sprintf(msg, args...)
+	err := fmt.Errorf("[プロトコルエラー] %s へのリクエストに対して、GETと一致しません: %s", endpoint, message)
+	return WrapCategoryError(ctx, BenchmarkApplicationError, CategoryValidation, hintContentLength, err)
+}
+
+const hintCaching = "条件付きリクエスト(If-None-Match, If-Modified-Since)の判定に用いるETagやLast-Modifiedが、現在のコンテンツと対応しているか確認してください"
+
+// NewCachingError は、キャッシュに関するヘッダや条件付きリクエストの扱いが誤っていることを記録します
+func NewCachingError(ctx context.Context, req *http.Request, msg string, args ...interface{}) error {
+	endpoint := fmt.Sprintf("%s %s", req.Method, req.URL.EscapedPath())
+	message := fmt.Sprintf(msg, args...)
+	err := fmt.Errorf("[プロトコルエラー] %s へのリクエストに対して、%s", endpoint, message)
+	return WrapCategoryError(ctx, BenchmarkApplicationError, CategoryValidation, hintCaching, err)
+}
+
+// TLS
+
+const hintTLSCertificate = "webappに配置したTLS証明書と鍵が、配布されたものと一致しているか確認してください"
+
+func NewTLSCertificateError(ctx context.Context, err error, msg string, args ...interface{}) error {
+	message := fmt.Sprintf(msg, args...)
+	err = fmt.Errorf("[TLS証明書] %s: %w", message, err)
+	return WrapCategoryError(ctx, BenchmarkViolationError, CategoryValidation, hintTLSCertificate, err)
+}
+
+// DNS
+
+const hintDNSProvisioning = "ユーザ登録時にDNSレコードを追加しているか、PowerDNSの否定応答のキャッシュ(negquery-cache-ttl)が長すぎないか確認してください"
+
+func NewDNSProvisioningError(ctx context.Context, err error, msg string, args ...interface{}) error {
+	message := fmt.Sprintf(msg, args...)
+	err = fmt.Errorf("[DNS] %s: %w", message, err)
+	return WrapCategoryError(ctx, BenchmarkApplicationError, CategoryValidation, hintDNSProvisioning, err)
+}
+
+const hintDNSEDNS = "PowerDNSのEDNS0に関する設定(udp-truncation-threshold など)を変更していないか、ネームサーバの前段に大きなUDPの応答を返すものがないか確認してください"
+
+// NewDNSEDNSError は、EDNS0のクエリへの応答や、UDPの応答のサイズが仕様に沿っていないことを記録します
+func NewDNSEDNSError(ctx context.Context, err error, msg string, args ...interface{}) error {
+	message := fmt.Sprintf(msg, args...)
+	err = fmt.Errorf("[DNS] %s: %w", message, err)
+	return WrapCategoryError(ctx, BenchmarkApplicationError, CategoryValidation, hintDNSEDNS, err)
+}
+
+const hintDNSZone = "ゾーンのSOA・NSレコードを変更していないか、ネームサーバ名のAレコードがwebappのアドレスを指しているか確認してください"
+
+// NewDNSZoneError は、ゾーンのSOA・NSレコードによる委譲が要件を満たしていないことを記録します
+func NewDNSZoneError(ctx context.Context, err error, msg string, args ...interface{}) error {
+	message := fmt.Sprintf(msg, args...)
+	err = fmt.Errorf("[DNS] %s: %w", message, err)
+	return WrapCategoryError(ctx, BenchmarkApplicationError, CategoryValidation, hintDNSZone, err)
+}
+
+// アイコン
+
+const hintFallbackIcon = "アイコン未設定のユーザには、webapp/img/NoImage.jpg をそのまま Content-Type: image/jpeg で返しているか確認してください"
+
+// NewFallbackIconError は、アイコン未設定のユーザのアイコンがNoImage.jpgと一致しないことを記録します
+func NewFallbackIconError(ctx context.Context, err error, msg string, args ...interface{}) error {
+	message := fmt.Sprintf(msg, args...)
+	err = fmt.Errorf("[仕様違反] %s: %w", message, err)
+	return WrapCategoryError(ctx, BenchmarkViolationError, CategoryValidation, hintFallbackIcon, err)
+}
+
+// 除外されたエンドポイント
+
+// ErrEndpointSkipped は、--endpoint-filter で除外されたエンドポイントへのリクエストを送らなかったことを表します
+// NOTE: 選手の誤りではないため、エラーとして記録しない
+var ErrEndpointSkipped = errors.New("除外されたエンドポイントのため、リクエストを送信しませんでした")
+
+type EndpointSkippedError struct {
+	// "GET /api/livestream/:id" のようなルート
+	Endpoint string
+}
+
+func (e *EndpointSkippedError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrEndpointSkipped.Error(), e.Endpoint)
+}
+
+func (e *EndpointSkippedError) Is(target error) bool {
+	return target == ErrEndpointSkipped
+}
+
+func NewEndpointSkippedError(endpoint string) error {
+	return &EndpointSkippedError{Endpoint: endpoint}
 }
@@ -0,0 +1,151 @@
+package bencherror
+
+import (
+	"regexp"
+	"sort"
+	"sync"
+
+	lru "github.com/hashicorp/golang-lru/v2"
+	"github.com/isucon/isucon13/bench/internal/redact"
+)
+
+// errorStore は、エラーを件数と重複排除したメッセージとして記録します
+// NOTE: 走行中に大量のエラーが発生してもメモリを使い切らないよう、エラーそのものは保持せず、
+//
+//	メッセージは正規化して重複排除した上で、上限を超えたら最も長く発生していないものから捨てる
+type errorStore struct {
+	mu     sync.Mutex
+	closed bool
+
+	codeCounts     map[string]int64
+	categoryCounts map[Category]int64
+
+	messages *lru.Cache[string, *messageEntry]
+	// 記録した順序 (メッセージの出力順に用いる)
+	seq int64
+	// 上限を超えて捨てたメッセージの種類数
+	evicted int64
+}
+
+// messageEntry は、正規化すると同じになるメッセージの集計です
+type messageEntry struct {
+	code     string
+	category Category
+	hint     string
+	// 最初に発生したメッセージ (正規化前)
+	message string
+	count   int64
+	seq     int64
+}
+
+func newErrorStore(size int) *errorStore {
+	s := &errorStore{
+		codeCounts:     make(map[string]int64),
+		categoryCounts: make(map[Category]int64),
+	}
+	messages, err := lru.NewWithEvict[string, *messageEntry](size, func(string, *messageEntry) {
+		s.evicted++
+	})
+	if err != nil {
+		panic(err)
+	}
+	s.messages = messages
+	return s
+}
+
+func (s *errorStore) add(code string, category Category, hint string, message string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return
+	}
+
+	// NOTE: メッセージは選手への結果やログに出力されるため、保持する前に秘匿情報を伏せる
+	message = redact.String(message)
+
+	s.codeCounts[code]++
+	s.categoryCounts[category]++
+
+	// NOTE: 同じメッセージでもエラーコードが異なれば別のエラーとして数える
+	key := code + "\x00" + string(category) + "\x00" + normalizeMessage(message)
+	if entry, ok := s.messages.Get(key); ok {
+		entry.count++
+		return
+	}
+	s.seq++
+	s.messages.Add(key, &messageEntry{
+		code:     code,
+		category: category,
+		hint:     hint,
+		message:  message,
+		count:    1,
+		seq:      s.seq,
+	})
+}
+
+func (s *errorStore) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+}
+
+func (s *errorStore) codeCount(code string) int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.codeCounts[code]
+}
+
+func (s *errorStore) categoryCount(category Category) int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.categoryCounts[category]
+}
+
+// entries は、保持しているメッセージの集計を最初に発生した順に返します
+func (s *errorStore) entries() []messageEntry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries := make([]messageEntry, 0, s.messages.Len())
+	for _, entry := range s.messages.Values() {
+		entries = append(entries, *entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].seq < entries[j].seq
+	})
+	return entries
+}
+
+func (s *errorStore) numEvicted() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.evicted
+}
+
+// メッセージに埋め込まれる、発生ごとに異なる値
+var volatilePatterns = []struct {
+	re          *regexp.Regexp
+	replacement string
+}{
+	// URLパス中のID (/api/livestream/123/livecomment, /api/user/1/2)
+	// NOTE: 続くIDも置き換えられるよう、後ろの区切りは消費しない
+	{regexp.MustCompile(`/\d+\b`), "/*"},
+	// id=123, livestream_id: 123, word_id=123
+	{regexp.MustCompile(`(?i)(id)(\s*[=:]\s*)\d+`), "$1$2*"},
+	// 配信 123, ライブコメント 123 など
+	{regexp.MustCompile(`(配信|ライブコメント|ユーザ|リアクション|NGワード) \d+`), "$1 *"},
+	// ハッシュ値やランダムな文字列
+	{regexp.MustCompile(`[0-9a-f]{16,}`), "*"},
+	// 名前解決の対象ドメイン (ランダムなサブドメイン)
+	{regexp.MustCompile(`[0-9a-z-]+\.u\.isucon\.dev`), "*.u.isucon.dev"},
+}
+
+// normalizeMessage は、メッセージからIDなど発生ごとに異なる値を取り除きます
+// NOTE: 重複排除のキーにのみ用い、選手には最初に発生したメッセージをそのまま見せる
+func normalizeMessage(message string) string {
+	for _, pattern := range volatilePatterns {
+		message = pattern.re.ReplaceAllString(message, pattern.replacement)
+	}
+	return message
+}
@@ -0,0 +1,111 @@
+package bencherror
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeMessage(t *testing.T) {
+	assert.Equal(t,
+		normalizeMessage("GET /api/livestream/123/livecomment へのリクエストに対して、期待されたHTTPステータスコードが確認できませんでした (expected:200, actual:500)"),
+		normalizeMessage("GET /api/livestream/4567/livecomment へのリクエストに対して、期待されたHTTPステータスコードが確認できませんでした (expected:200, actual:500)"),
+	)
+	assert.Equal(t,
+		normalizeMessage("配信 12 のライブコメント 34 がNGワードを含んでいます (word_id=5)"),
+		normalizeMessage("配信 98 のライブコメント 76 がNGワードを含んでいます (word_id=54)"),
+	)
+	// 続けて並んだIDも置き換える
+	assert.Equal(t,
+		normalizeMessage("GET /api/livestream/1/2/3 タイムアウト"),
+		normalizeMessage("GET /api/livestream/45/67/89 タイムアウト"),
+	)
+	assert.Equal(t, "GET /api/livestream/*/*/* タイムアウト", normalizeMessage("GET /api/livestream/1/2/3 タイムアウト"))
+	// ステータスコードなど、発生ごとに変わらない値は残す
+	assert.NotEqual(t,
+		normalizeMessage("GET /api/tag (expected:200, actual:500)"),
+		normalizeMessage("GET /api/tag (expected:200, actual:404)"),
+	)
+}
+
+func TestErrorStore(t *testing.T) {
+	s := newErrorStore(2)
+	s.add("code", CategoryValidation, "", "配信 1 の統計情報が不正です")
+	s.add("code", CategoryValidation, "", "配信 2 の統計情報が不正です")
+	s.add("code", CategoryTimeout, "", "GET /api/tag タイムアウト")
+	s.add("other", CategoryPenalty, "", "一般エラー")
+
+	assert.Equal(t, int64(3), s.codeCount("code"))
+	assert.Equal(t, int64(2), s.categoryCount(CategoryValidation))
+
+	// 上限を超えたら、最も長く発生していないものから捨てる
+	entries := s.entries()
+	assert.Len(t, entries, 2)
+	assert.Equal(t, "GET /api/tag タイムアウト", entries[0].message)
+	assert.Equal(t, "一般エラー", entries[1].message)
+	assert.Equal(t, int64(1), s.numEvicted())
+
+	s.close()
+	s.add("code", CategoryValidation, "", "閉じた後のエラー")
+	assert.Equal(t, int64(3), s.codeCount("code"))
+
+	// 同じメッセージでも、エラーコードが異なれば別に記録する
+	s = newErrorStore(10)
+	s.add("code", CategoryValidation, "", "配信 1 の統計情報が不正です")
+	s.add("other", CategoryValidation, "", "配信 2 の統計情報が不正です")
+	assert.Len(t, s.entries(), 2)
+}
+
+func TestErrorSet_Independent(t *testing.T) {
+	pretest := NewErrorSet()
+	load := NewErrorSet()
+	pretestCtx := WithErrorSet(context.Background(), pretest)
+	loadCtx := WithErrorSet(context.Background(), load)
+
+	NewApplicationError(pretestCtx, errors.New("pretest"), "pretestのエラー")
+	pretest.Done()
+
+	// 先に終了した ErrorSet があっても、別の ErrorSet は記録を続けられる
+	NewApplicationError(loadCtx, errors.New("load"), "負荷走行のエラー")
+	load.Done()
+	pretest.Done()
+
+	assert.Equal(t, int64(1), pretest.CategoryCounts()[CategoryPenalty])
+	assert.Equal(t, int64(1), load.CategoryCounts()[CategoryPenalty])
+	assert.Len(t, load.FinalErrorMessages()[CategoryPenalty], 1)
+}
+
+func TestErrorSet_Concurrent(t *testing.T) {
+	a := NewErrorSet()
+	b := NewErrorSet()
+	aCtx := WithErrorSet(context.Background(), a)
+	bCtx := WithErrorSet(context.Background(), b)
+
+	// 並行する走行のエラーは、それぞれのcontextに紐づく ErrorSet に記録する
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			NewViolationError(aCtx, errors.New("a"), "走行Aのエラー")
+		}()
+		go func() {
+			defer wg.Done()
+			NewTimeoutError(bCtx, errors.New("b"), "走行Bのエラー")
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int64(10), a.CategoryCounts()[CategoryValidation])
+	assert.Zero(t, a.CategoryCounts()[CategoryTimeout])
+	assert.Equal(t, int64(10), b.CategoryCounts()[CategoryTimeout])
+	assert.Zero(t, b.CategoryCounts()[CategoryValidation])
+	assert.ErrorIs(t, a.CheckViolation(), ErrViolation)
+	assert.NoError(t, b.CheckViolation())
+
+	// contextに紐づいていなければ、既定の ErrorSet に記録する
+	assert.Same(t, Default(), FromContext(context.Background()))
+}
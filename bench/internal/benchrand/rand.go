@@ -0,0 +1,82 @@
+// benchrand はベンチマーカー全体で共有する乱数源を提供します
+//
+// 同じシードで起動したベンチマーカーが同等の負荷をかけられるよう、
+// ユーザ選択やライブコメント・DNSサブドメインの生成などはこの乱数源を利用します
+package benchrand
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+const letters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+var (
+	mu   sync.Mutex
+	seed = time.Now().UnixNano()
+	rnd  = rand.New(rand.NewSource(seed))
+)
+
+// Init は乱数源をシードで初期化します
+// シードに0を指定した場合は現在時刻から決定し、実際に用いたシードを返します
+func Init(s int64) int64 {
+	if s == 0 {
+		s = time.Now().UnixNano()
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	seed = s
+	rnd = rand.New(rand.NewSource(s))
+	return s
+}
+
+// Seed は現在の乱数源のシードを返します
+func Seed() int64 {
+	mu.Lock()
+	defer mu.Unlock()
+	return seed
+}
+
+func Int63() int64 {
+	mu.Lock()
+	defer mu.Unlock()
+	return rnd.Int63()
+}
+
+func Intn(n int) int {
+	mu.Lock()
+	defer mu.Unlock()
+	return rnd.Intn(n)
+}
+
+func Float64() float64 {
+	mu.Lock()
+	defer mu.Unlock()
+	return rnd.Float64()
+}
+
+func ExpFloat64() float64 {
+	mu.Lock()
+	defer mu.Unlock()
+	return rnd.ExpFloat64()
+}
+
+func Shuffle(n int, swap func(i, j int)) {
+	mu.Lock()
+	defer mu.Unlock()
+	rnd.Shuffle(n, swap)
+}
+
+// String は英数字からなる長さnの文字列を生成します
+func String(n int) string {
+	b := make([]byte, n)
+
+	mu.Lock()
+	defer mu.Unlock()
+	for i := range b {
+		b[i] = letters[rnd.Intn(len(letters))]
+	}
+	return string(b)
+}
@@ -0,0 +1,33 @@
+package benchrand
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInit_SameSeed(t *testing.T) {
+	generate := func() ([]int, string) {
+		var ns []int
+		for i := 0; i < 10; i++ {
+			ns = append(ns, Intn(1000))
+		}
+		return ns, String(16)
+	}
+
+	assert.Equal(t, int64(42), Init(42))
+	ns1, s1 := generate()
+	Init(42)
+	ns2, s2 := generate()
+
+	assert.Equal(t, ns1, ns2)
+	assert.Equal(t, s1, s2)
+	assert.Len(t, s1, 16)
+	assert.Equal(t, int64(42), Seed())
+}
+
+func TestInit_ZeroSeed(t *testing.T) {
+	s := Init(0)
+	assert.NotZero(t, s)
+	assert.Equal(t, s, Seed())
+}
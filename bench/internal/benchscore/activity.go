@@ -0,0 +1,272 @@
+package benchscore
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+// ActivityKind は、統計情報に反映される操作の種類です
+type ActivityKind int
+
+const (
+	ActivityReaction ActivityKind = iota
+	ActivityLivecomment
+	// NOTE: モデレーションで削除されうるため、ライブコメントとは別に数える
+	ActivitySpamLivecomment
+	ActivityReport
+	// NOTE: 視聴者数は入室で増え、退室で減る
+	ActivityEnter
+	ActivityExit
+)
+
+// ActivityCount は、操作の件数の記録です
+type ActivityCount struct {
+	// 成功レスポンスを確認した件数
+	Confirmed int64 `json:"confirmed"`
+	// 送信した件数 (タイムアウトなどで成否が分からないものを含む)
+	Attempted int64 `json:"attempted"`
+}
+
+// ActivityEntry は、配信または配信者ごとの操作の記録です
+type ActivityEntry struct {
+	Reactions        ActivityCount `json:"reactions"`
+	Livecomments     ActivityCount `json:"livecomments"`
+	SpamLivecomments ActivityCount `json:"spam_livecomments"`
+	Reports          ActivityCount `json:"reports"`
+	Enters           ActivityCount `json:"enters"`
+	Exits            ActivityCount `json:"exits"`
+	// 入室と退室の記録から求めた、視聴者数が取りうる範囲
+	ViewersLower int64 `json:"viewers_lower"`
+	ViewersUpper int64 `json:"viewers_upper"`
+}
+
+func (e *ActivityEntry) count(kind ActivityKind) *ActivityCount {
+	switch kind {
+	case ActivityReaction:
+		return &e.Reactions
+	case ActivityLivecomment:
+		return &e.Livecomments
+	case ActivitySpamLivecomment:
+		return &e.SpamLivecomments
+	case ActivityEnter:
+		return &e.Enters
+	case ActivityExit:
+		return &e.Exits
+	default:
+		return &e.Reports
+	}
+}
+
+// Score は、成功を確認した操作の件数の合計を返します
+func (e ActivityEntry) Score() int64 {
+	return e.Reactions.Confirmed + e.Livecomments.Confirmed + e.SpamLivecomments.Confirmed + e.Reports.Confirmed
+}
+
+// ViewersRange は、入室と退室の記録から、統計情報の視聴者数が取りうる範囲を返します
+// NOTE: 成否が分からない入室・退室は、反映されていてもいなくても許容する
+func (e ActivityEntry) ViewersRange() (lower, upper int64) {
+	return e.ViewersLower, e.ViewersUpper
+}
+
+// presenceKey は、視聴者が入室している配信です
+type presenceKey struct {
+	livestreamID int64
+	viewer       string
+}
+
+// presence は、視聴者が配信に入室している件数が取りうる範囲です
+// NOTE: webappは入室のたびに視聴履歴を追加し、退室ではその視聴者の履歴をすべて削除する.
+// そのため退室は、それまでの入室の回数によらず件数を0にする
+type presence struct {
+	lower int64
+	upper int64
+}
+
+// LivestreamActivityEntry は、配信ごとの操作の記録です
+type LivestreamActivityEntry struct {
+	ActivityEntry
+	LivestreamID int64  `json:"livestream_id"`
+	Streamer     string `json:"streamer"`
+}
+
+// StreamerActivityEntry は、配信者ごとの操作の記録です
+type StreamerActivityEntry struct {
+	ActivityEntry
+	Streamer string `json:"streamer"`
+}
+
+// activityLog は、ベンチマーカーが行ったリアクション・ライブコメント・スパム報告・入退室を配信・配信者ごとに記録します
+// 最終チェックでwebappの統計情報を再計算して突き合わせるのに用います
+type activityLog struct {
+	mu          sync.Mutex
+	livestreams map[int64]*LivestreamActivityEntry
+	streamers   map[string]*StreamerActivityEntry
+	presences   map[presenceKey]*presence
+}
+
+func newActivityLog() *activityLog {
+	return &activityLog{
+		livestreams: make(map[int64]*LivestreamActivityEntry),
+		streamers:   make(map[string]*StreamerActivityEntry),
+		presences:   make(map[presenceKey]*presence),
+	}
+}
+
+func (l *activityLog) entries(livestreamID int64, streamer string) (*ActivityEntry, *ActivityEntry) {
+	livestream, ok := l.livestreams[livestreamID]
+	if !ok {
+		livestream = &LivestreamActivityEntry{LivestreamID: livestreamID, Streamer: streamer}
+		l.livestreams[livestreamID] = livestream
+	}
+	s, ok := l.streamers[streamer]
+	if !ok {
+		s = &StreamerActivityEntry{Streamer: streamer}
+		l.streamers[streamer] = s
+	}
+	return &livestream.ActivityEntry, &s.ActivityEntry
+}
+
+// RecordActivityAttempt は、操作の送信を記録します
+// NOTE: タイムアウトしたリクエストもwebappに反映されうるため、リクエスト送信前に記録する
+func (set *ScoreSet) RecordActivityAttempt(kind ActivityKind, livestreamID int64, streamer string) {
+	activities := set.activities
+	activities.mu.Lock()
+	defer activities.mu.Unlock()
+	livestream, s := activities.entries(livestreamID, streamer)
+	livestream.count(kind).Attempted++
+	s.count(kind).Attempted++
+}
+
+// ConfirmActivity は、成功を確認した操作を記録します
+func (set *ScoreSet) ConfirmActivity(kind ActivityKind, livestreamID int64, streamer string) {
+	activities := set.activities
+	activities.mu.Lock()
+	defer activities.mu.Unlock()
+	livestream, s := activities.entries(livestreamID, streamer)
+	livestream.count(kind).Confirmed++
+	s.count(kind).Confirmed++
+}
+
+// RecordPresenceAttempt は、視聴者の入室・退室の送信を記録します
+// NOTE: 成否が分からない退室は、視聴履歴をすべて削除したかもしれないため、その時点で下限を0にする
+func (set *ScoreSet) RecordPresenceAttempt(kind ActivityKind, livestreamID int64, streamer string, viewer string) {
+	activities := set.activities
+	activities.mu.Lock()
+	defer activities.mu.Unlock()
+	livestream, s := activities.entries(livestreamID, streamer)
+	livestream.count(kind).Attempted++
+	s.count(kind).Attempted++
+
+	p := activities.presence(livestreamID, viewer)
+	var lowerDelta, upperDelta int64
+	switch kind {
+	case ActivityEnter:
+		upperDelta = 1
+	case ActivityExit:
+		lowerDelta = -p.lower
+	}
+	p.lower += lowerDelta
+	p.upper += upperDelta
+	for _, e := range []*ActivityEntry{livestream, s} {
+		e.ViewersLower += lowerDelta
+		e.ViewersUpper += upperDelta
+	}
+}
+
+// ConfirmPresence は、成功を確認した視聴者の入室・退室を記録します
+func (set *ScoreSet) ConfirmPresence(kind ActivityKind, livestreamID int64, streamer string, viewer string) {
+	activities := set.activities
+	activities.mu.Lock()
+	defer activities.mu.Unlock()
+	livestream, s := activities.entries(livestreamID, streamer)
+	livestream.count(kind).Confirmed++
+	s.count(kind).Confirmed++
+
+	p := activities.presence(livestreamID, viewer)
+	var lowerDelta, upperDelta int64
+	switch kind {
+	case ActivityEnter:
+		lowerDelta = 1
+	case ActivityExit:
+		lowerDelta, upperDelta = -p.lower, -p.upper
+	}
+	p.lower += lowerDelta
+	p.upper += upperDelta
+	for _, e := range []*ActivityEntry{livestream, s} {
+		e.ViewersLower += lowerDelta
+		e.ViewersUpper += upperDelta
+	}
+}
+
+func (l *activityLog) presence(livestreamID int64, viewer string) *presence {
+	key := presenceKey{livestreamID: livestreamID, viewer: viewer}
+	p, ok := l.presences[key]
+	if !ok {
+		p = &presence{}
+		l.presences[key] = p
+	}
+	return p
+}
+
+// LivestreamActivities は、配信ごとの操作の記録を、成功を確認した操作の多い順に返します
+func (set *ScoreSet) LivestreamActivities() []LivestreamActivityEntry {
+	activities := set.activities
+	activities.mu.Lock()
+	defer activities.mu.Unlock()
+
+	entries := make([]LivestreamActivityEntry, 0, len(activities.livestreams))
+	for _, e := range activities.livestreams {
+		entries = append(entries, *e)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Score() != entries[j].Score() {
+			return entries[i].Score() > entries[j].Score()
+		}
+		return entries[i].LivestreamID < entries[j].LivestreamID
+	})
+	return entries
+}
+
+// StreamerActivities は、配信者ごとの操作の記録を、成功を確認した操作の多い順に返します
+func (set *ScoreSet) StreamerActivities() []StreamerActivityEntry {
+	activities := set.activities
+	activities.mu.Lock()
+	defer activities.mu.Unlock()
+
+	entries := make([]StreamerActivityEntry, 0, len(activities.streamers))
+	for _, e := range activities.streamers {
+		entries = append(entries, *e)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Score() != entries[j].Score() {
+			return entries[i].Score() > entries[j].Score()
+		}
+		return entries[i].Streamer < entries[j].Streamer
+	})
+	return entries
+}
+
+func RecordActivityAttempt(ctx context.Context, kind ActivityKind, livestreamID int64, streamer string) {
+	FromContext(ctx).RecordActivityAttempt(kind, livestreamID, streamer)
+}
+
+func ConfirmActivity(ctx context.Context, kind ActivityKind, livestreamID int64, streamer string) {
+	FromContext(ctx).ConfirmActivity(kind, livestreamID, streamer)
+}
+
+func RecordPresenceAttempt(ctx context.Context, kind ActivityKind, livestreamID int64, streamer string, viewer string) {
+	FromContext(ctx).RecordPresenceAttempt(kind, livestreamID, streamer, viewer)
+}
+
+func ConfirmPresence(ctx context.Context, kind ActivityKind, livestreamID int64, streamer string, viewer string) {
+	FromContext(ctx).ConfirmPresence(kind, livestreamID, streamer, viewer)
+}
+
+func LivestreamActivities(ctx context.Context) []LivestreamActivityEntry {
+	return FromContext(ctx).LivestreamActivities()
+}
+
+func StreamerActivities(ctx context.Context) []StreamerActivityEntry {
+	return FromContext(ctx).StreamerActivities()
+}
@@ -0,0 +1,85 @@
+package benchscore
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestActivityLog(t *testing.T) {
+	ctx := WithScoreSet(context.Background(), NewScoreSet(context.Background()))
+
+	for i := 0; i < 3; i++ {
+		RecordActivityAttempt(ctx, ActivityReaction, 1, "streamer-a")
+		ConfirmActivity(ctx, ActivityReaction, 1, "streamer-a")
+	}
+	// タイムアウトなどで成否が分からない
+	RecordActivityAttempt(ctx, ActivityReaction, 2, "streamer-a")
+	RecordActivityAttempt(ctx, ActivityLivecomment, 2, "streamer-a")
+	ConfirmActivity(ctx, ActivityLivecomment, 2, "streamer-a")
+	RecordActivityAttempt(ctx, ActivitySpamLivecomment, 3, "streamer-b")
+	ConfirmActivity(ctx, ActivitySpamLivecomment, 3, "streamer-b")
+	RecordActivityAttempt(ctx, ActivityReport, 3, "streamer-b")
+	ConfirmActivity(ctx, ActivityReport, 3, "streamer-b")
+
+	streamers := StreamerActivities(ctx)
+	assert.Len(t, streamers, 2)
+	assert.Equal(t, "streamer-a", streamers[0].Streamer)
+	assert.Equal(t, ActivityCount{Confirmed: 3, Attempted: 4}, streamers[0].Reactions)
+	assert.Equal(t, ActivityCount{Confirmed: 1, Attempted: 1}, streamers[0].Livecomments)
+	assert.Equal(t, int64(4), streamers[0].Score())
+	assert.Equal(t, "streamer-b", streamers[1].Streamer)
+	assert.Equal(t, ActivityCount{Confirmed: 1, Attempted: 1}, streamers[1].SpamLivecomments)
+	assert.Equal(t, ActivityCount{Confirmed: 1, Attempted: 1}, streamers[1].Reports)
+
+	livestreams := LivestreamActivities(ctx)
+	assert.Len(t, livestreams, 3)
+	assert.Equal(t, int64(1), livestreams[0].LivestreamID)
+	assert.Equal(t, int64(3), livestreams[1].LivestreamID)
+	assert.Equal(t, "streamer-b", livestreams[1].Streamer)
+	assert.Equal(t, int64(2), livestreams[2].LivestreamID)
+	assert.Equal(t, ActivityCount{Confirmed: 0, Attempted: 1}, livestreams[2].Reactions)
+}
+
+func TestActivityLog_ViewersRange(t *testing.T) {
+	set := NewScoreSet(context.Background())
+
+	// 退室は、それまでの入室の回数によらず視聴履歴をすべて削除する
+	for i := 0; i < 3; i++ {
+		set.RecordPresenceAttempt(ActivityEnter, 1, "streamer-a", "viewer-a")
+		set.ConfirmPresence(ActivityEnter, 1, "streamer-a", "viewer-a")
+	}
+	set.RecordPresenceAttempt(ActivityExit, 1, "streamer-a", "viewer-a")
+	set.ConfirmPresence(ActivityExit, 1, "streamer-a", "viewer-a")
+	// 別の視聴者は退室の影響を受けない
+	for i := 0; i < 2; i++ {
+		set.RecordPresenceAttempt(ActivityEnter, 1, "streamer-a", "viewer-b")
+		set.ConfirmPresence(ActivityEnter, 1, "streamer-a", "viewer-b")
+	}
+	// タイムアウトなどで成否が分からない
+	set.RecordPresenceAttempt(ActivityEnter, 1, "streamer-a", "viewer-c")
+	set.RecordPresenceAttempt(ActivityEnter, 2, "streamer-a", "viewer-a")
+	set.ConfirmPresence(ActivityEnter, 2, "streamer-a", "viewer-a")
+	set.RecordPresenceAttempt(ActivityExit, 2, "streamer-a", "viewer-a")
+
+	livestreams := set.LivestreamActivities()
+	assert.Len(t, livestreams, 2)
+	lower, upper := livestreams[0].ViewersRange()
+	assert.Equal(t, int64(1), livestreams[0].LivestreamID)
+	assert.Equal(t, int64(2), lower)
+	assert.Equal(t, int64(3), upper)
+	lower, upper = livestreams[1].ViewersRange()
+	assert.Equal(t, int64(0), lower)
+	assert.Equal(t, int64(1), upper)
+	// NOTE: 入退室は統計情報のスコアに含めない
+	assert.Equal(t, int64(0), livestreams[0].Score())
+
+	streamers := set.StreamerActivities()
+	assert.Len(t, streamers, 1)
+	lower, upper = streamers[0].ViewersRange()
+	assert.Equal(t, int64(2), lower)
+	assert.Equal(t, int64(4), upper)
+	assert.Equal(t, ActivityCount{Confirmed: 6, Attempted: 7}, streamers[0].Enters)
+	assert.Equal(t, ActivityCount{Confirmed: 1, Attempted: 2}, streamers[0].Exits)
+}
@@ -0,0 +1,47 @@
+package benchscore
+
+import (
+	"cmp"
+	"slices"
+
+	"github.com/isucon/isucandar/score"
+)
+
+// ScoreContribution は、スコアタグ1つ分のスコアへの寄与です
+type ScoreContribution struct {
+	Tag    score.ScoreTag `json:"tag"`
+	Weight int64          `json:"weight"`
+	Count  int64          `json:"count"`
+	Points int64          `json:"points"`
+}
+
+// Contributions は、スコアタグごとのスコアへの寄与を、寄与の大きい順に返します. 寄与の合計は Score と一致します
+// NOTE: 売上は売上額を、体感品質は視聴を終えたセッション数を Count とする. 体感品質の Points は、セッションごとの体感品質(0〜1)の合計に配点を掛けたもの
+func (set *ScoreSet) Contributions() []ScoreContribution {
+	breakdown := set.Breakdown()
+	sessions, _, _ := set.QualityStats()
+
+	var contributions []ScoreContribution
+	for tag, weight := range Weights() {
+		c := ScoreContribution{Tag: tag, Weight: weight}
+		switch tag {
+		case Profit:
+			c.Count = int64(set.TotalProfit())
+			c.Points = c.Count * weight
+		case SessionQuality:
+			c.Count = sessions
+			c.Points = set.quality.score()
+		default:
+			c.Count = breakdown[tag]
+			c.Points = c.Count * weight
+		}
+		contributions = append(contributions, c)
+	}
+	slices.SortFunc(contributions, func(a, b ScoreContribution) int {
+		if n := cmp.Compare(b.Points, a.Points); n != 0 {
+			return n
+		}
+		return cmp.Compare(a.Tag, b.Tag)
+	})
+	return contributions
+}
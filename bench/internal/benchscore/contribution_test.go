@@ -0,0 +1,56 @@
+package benchscore
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContributions(t *testing.T) {
+	defaults := Weights()
+	t.Cleanup(func() {
+		weightsMu.Lock()
+		defer weightsMu.Unlock()
+		weights = defaults
+	})
+	// NOTE: 既定ではProfit以外の配点は0なので、寄与の並びを確かめるため配点を与える
+	overridden := Weights()
+	overridden[DNSProvisioned] = 10
+	weightsMu.Lock()
+	weights = overridden
+	weightsMu.Unlock()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	set := NewScoreSet(ctx)
+
+	set.AddTip(1, "streamer-a", 100)
+	set.AddTip(2, "streamer-b", 50)
+	for i := 0; i < 3; i++ {
+		set.Add(DNSProvisioned)
+	}
+	set.Add(DNSResolve)
+	assert.Eventually(t, func() bool {
+		return set.GetByTag(DNSProvisioned) == 3 && set.GetByTag(DNSResolve) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	contributions := set.Contributions()
+	assert.Len(t, contributions, len(Weights()))
+
+	// 寄与の大きい順に並ぶ
+	assert.Equal(t, ScoreContribution{Tag: Profit, Weight: 1, Count: 150, Points: 150}, contributions[0])
+	assert.Equal(t, ScoreContribution{Tag: DNSProvisioned, Weight: 10, Count: 3, Points: 30}, contributions[1])
+
+	// 寄与の合計はスコアと一致する
+	var total int64
+	for _, c := range contributions {
+		total += c.Points
+		if c.Tag == DNSResolve {
+			assert.Equal(t, int64(1), c.Count)
+			assert.Equal(t, int64(0), c.Points)
+		}
+	}
+	assert.Equal(t, set.Score(), total)
+}
@@ -2,7 +2,6 @@ package benchscore
 
 import (
 	"context"
-	"sync"
 
 	"github.com/isucon/isucandar/score"
 )
@@ -10,48 +9,154 @@ import (
 const (
 	DNSResolve score.ScoreTag = "dns-resolve"
 	DNSFailed  score.ScoreTag = "dns-failed"
+	// 圧縮ポインタやレコード数が不正なDNSレスポンス (DNSFailedにも計上される)
+	DNSMalformed score.ScoreTag = "dns-malformed"
+	// クエリで広告したサイズを超えるUDPのDNSレスポンス (DNSFailedにも計上される)
+	DNSOversized score.ScoreTag = "dns-oversized"
+	// デュアルスタックの名前で、AレコードとAAAAレコードの一方のみが正しく返された名前解決
+	DNSDualStackMismatch score.ScoreTag = "dns-dualstack-mismatch"
+	// 期限内に応答がなかったクエリ (リトライした場合はクエリごとに計上される)
+	DNSTimeout score.ScoreTag = "dns-timeout"
+	// SERVFAIL, NXDOMAINが返された名前解決
+	DNSServFail score.ScoreTag = "dns-servfail"
+	DNSNXDomain score.ScoreTag = "dns-nxdomain"
 
+	// 遅延・スパムに耐えかねて、配信の途中で離脱した視聴者
+	// NOTE: 配点ファイルで指定できるよう残している. 体感品質は SessionQuality で評価する
 	TooSlow     score.ScoreTag = "too-slow-left"
 	TooManySpam score.ScoreTag = "too-many-spam"
-)
 
-var (
-	counter         *score.Score
-	doneCounterOnce sync.Once
+	// レイド(視聴者の殺到)中に成功した書き込み
+	RaidLivecomment score.ScoreTag = "raid-livecomment"
+	RaidReaction    score.ScoreTag = "raid-reaction"
+
+	// 猶予時間内に反映されたことを検証できたモデレーション
+	ModerationVerified score.ScoreTag = "moderation-verified"
+
+	// 連投したうち、猶予時間内に一覧への反映を検証できたリアクション
+	ReactionBurstAccepted score.ScoreTag = "reaction-burst-accepted"
+
+	// 新規登録したユーザのサブドメインが、猶予時間内に名前解決できたもの
+	DNSProvisioned score.ScoreTag = "dns-provisioned"
+	// 登録前の否定応答がキャッシュされ、猶予時間内に名前解決できなかったもの
+	DNSProvisioningStale score.ScoreTag = "dns-provisioning-stale"
+
+	// 応答をキャッシュする場合の、キャッシュのヒット・ミス
+	DNSCacheHit  score.ScoreTag = "dns-cache-hit"
+	DNSCacheMiss score.ScoreTag = "dns-cache-miss"
+	// Aレコードを返した応答のうち、TTLが妥当な範囲にあったもの・なかったもの
+	DNSSensibleTTL   score.ScoreTag = "dns-sensible-ttl"
+	DNSInsensibleTTL score.ScoreTag = "dns-insensible-ttl"
 )
 
-func InitCounter(ctx context.Context) {
-	counter = score.NewScore(ctx)
-	counter.Set(DNSResolve, 1)
-	counter.Set(DNSFailed, 1)
-	counter.Set(TooSlow, 1)
-	counter.Set(TooManySpam, 1)
+// add は、contextに紐づく ScoreSet のスコアタグのカウンタを1加算します
+func add(ctx context.Context, tag score.ScoreTag) {
+	FromContext(ctx).Add(tag)
 }
 
-func IncResolves() {
-	counter.Add(DNSResolve)
+func IncResolves(ctx context.Context) {
+	add(ctx, DNSResolve)
 }
 
-func NumResolves() int64 {
-	table := counter.Breakdown()
-	return table[DNSResolve]
+func IncDNSFailed(ctx context.Context) {
+	add(ctx, DNSFailed)
+}
+
+func IncDNSMalformed(ctx context.Context) {
+	add(ctx, DNSMalformed)
+}
+
+func IncDNSOversized(ctx context.Context) {
+	add(ctx, DNSOversized)
+}
+
+func IncDNSDualStackMismatch(ctx context.Context) {
+	add(ctx, DNSDualStackMismatch)
+}
+
+func IncDNSTimeout(ctx context.Context) {
+	add(ctx, DNSTimeout)
+}
+
+func IncDNSServFail(ctx context.Context) {
+	add(ctx, DNSServFail)
+}
+
+func IncDNSNXDomain(ctx context.Context) {
+	add(ctx, DNSNXDomain)
+}
+
+func IncTooSlow(ctx context.Context) {
+	add(ctx, TooSlow)
+}
+
+func IncTooManySpam(ctx context.Context) {
+	add(ctx, TooManySpam)
+}
+
+func IncRaidLivecomment(ctx context.Context) {
+	add(ctx, RaidLivecomment)
 }
 
-func IncDNSFailed() {
-	counter.Add(DNSFailed)
+func IncRaidReaction(ctx context.Context) {
+	add(ctx, RaidReaction)
+}
+
+func IncModerationVerified(ctx context.Context) {
+	add(ctx, ModerationVerified)
+}
+
+func IncReactionBurstAccepted(ctx context.Context) {
+	add(ctx, ReactionBurstAccepted)
+}
+
+func IncDNSProvisioned(ctx context.Context) {
+	add(ctx, DNSProvisioned)
+}
+
+func IncDNSProvisioningStale(ctx context.Context) {
+	add(ctx, DNSProvisioningStale)
+}
+
+func IncDNSCacheHit(ctx context.Context) {
+	add(ctx, DNSCacheHit)
+}
+
+func IncDNSCacheMiss(ctx context.Context) {
+	add(ctx, DNSCacheMiss)
+}
+
+func IncDNSSensibleTTL(ctx context.Context) {
+	add(ctx, DNSSensibleTTL)
+}
+
+func IncDNSInsensibleTTL(ctx context.Context) {
+	add(ctx, DNSInsensibleTTL)
+}
+
+// 以下のパッケージの関数は、既定の ScoreSet を対象とします
+
+func NumResolves() int64 {
+	return defaultSet.GetByTag(DNSResolve)
 }
 
 func NumDNSFailed() int64 {
-	table := counter.Breakdown()
-	return table[DNSFailed]
+	return defaultSet.GetByTag(DNSFailed)
+}
+
+func NumDNSMalformed() int64 {
+	return defaultSet.GetByTag(DNSMalformed)
+}
+
+// Breakdown は、スコアタグごとのカウンタの値を返します
+func Breakdown() score.ScoreTable {
+	return defaultSet.Breakdown()
 }
 
 func GetByTag(tag score.ScoreTag) int64 {
-	return counter.Breakdown()[tag]
+	return defaultSet.GetByTag(tag)
 }
 
 func DoneCounter() {
-	doneCounterOnce.Do(func() {
-		counter.Close()
-	})
+	defaultSet.Done()
 }
@@ -1,17 +1,282 @@
 package benchscore
 
 import (
-	"sync/atomic"
+	"context"
+	"sort"
+	"strings"
+	"sync"
 )
 
-var profit uint64
+// TipLedgerEntry は、配信または配信者ごとのチップの記録です
+type TipLedgerEntry struct {
+	// 成功レスポンスを確認したチップの合計と件数
+	Confirmed      uint64 `json:"confirmed"`
+	ConfirmedCount int64  `json:"confirmed_count"`
+	// 送信したチップの合計 (タイムアウトなどで成否が分からないものを含む)
+	Attempted uint64 `json:"attempted"`
+	// 成功を確認した/送信したチップの最大額
+	MaxConfirmed uint64 `json:"max_confirmed"`
+	MaxAttempted uint64 `json:"max_attempted"`
+	// 成功を確認したチップのうち、NGワードの登録によってライブコメントごと削除されたものの合計
+	Moderated uint64 `json:"moderated"`
+}
+
+// Unconfirmed は、送信したが成功を確認できなかったチップの合計を返します
+// NOTE: webappに反映されているかは分からないため、突き合わせの許容範囲として用いる
+func (e TipLedgerEntry) Unconfirmed() uint64 {
+	return e.Attempted - e.Confirmed
+}
+
+// Retained は、成功を確認したチップのうち、webappに残っているはずのものの合計を返します
+// NOTE: モデレーションで削除されたライブコメントのチップは、webappの統計情報や売上合計から消える
+func (e TipLedgerEntry) Retained() uint64 {
+	return e.Confirmed - min(e.Moderated, e.Confirmed)
+}
+
+// Merge は、2つの記録を合計した記録を返します
+func (e TipLedgerEntry) Merge(other TipLedgerEntry) TipLedgerEntry {
+	return TipLedgerEntry{
+		Confirmed:      e.Confirmed + other.Confirmed,
+		ConfirmedCount: e.ConfirmedCount + other.ConfirmedCount,
+		Attempted:      e.Attempted + other.Attempted,
+		MaxConfirmed:   max(e.MaxConfirmed, other.MaxConfirmed),
+		MaxAttempted:   max(e.MaxAttempted, other.MaxAttempted),
+		Moderated:      e.Moderated + other.Moderated,
+	}
+}
+
+// LivestreamTipLedgerEntry は、配信ごとのチップの記録です
+type LivestreamTipLedgerEntry struct {
+	TipLedgerEntry
+	LivestreamID int64  `json:"livestream_id"`
+	Streamer     string `json:"streamer"`
+}
+
+// StreamerTipLedgerEntry は、配信者ごとのチップの記録です
+type StreamerTipLedgerEntry struct {
+	TipLedgerEntry
+	Streamer string `json:"streamer"`
+}
+
+// tippedLivecomment は、成功を確認したチップ付きのライブコメントです
+type tippedLivecomment struct {
+	comment string
+	tip     uint64
+}
+
+// tipLedger は、ベンチマーカーが送ったチップを配信・配信者ごとに記録する台帳です
+// 最終チェックでwebappの統計情報と突き合わせるのに用います
+type tipLedger struct {
+	mu          sync.Mutex
+	livestreams map[int64]*LivestreamTipLedgerEntry
+	streamers   map[string]*StreamerTipLedgerEntry
+	// NGワードの登録で削除されるチップを求めるため、配信ごとにチップ付きのライブコメントを保持する
+	livecomments map[int64][]tippedLivecomment
+}
+
+func newTipLedger() *tipLedger {
+	return &tipLedger{
+		livestreams:  make(map[int64]*LivestreamTipLedgerEntry),
+		streamers:    make(map[string]*StreamerTipLedgerEntry),
+		livecomments: make(map[int64][]tippedLivecomment),
+	}
+}
+
+func (l *tipLedger) entries(livestreamID int64, streamer string) (*TipLedgerEntry, *TipLedgerEntry) {
+	livestream, ok := l.livestreams[livestreamID]
+	if !ok {
+		livestream = &LivestreamTipLedgerEntry{LivestreamID: livestreamID, Streamer: streamer}
+		l.livestreams[livestreamID] = livestream
+	}
+	s, ok := l.streamers[streamer]
+	if !ok {
+		s = &StreamerTipLedgerEntry{Streamer: streamer}
+		l.streamers[streamer] = s
+	}
+	return &livestream.TipLedgerEntry, &s.TipLedgerEntry
+}
+
+// RecordTipAttempt は、チップを含むライブコメントの送信を記録します
+// NOTE: タイムアウトしたリクエストもwebappに反映されうるため、リクエスト送信前に記録する
+func (set *ScoreSet) RecordTipAttempt(livestreamID int64, streamer string, tip uint64) {
+	if tip == 0 {
+		return
+	}
+
+	ledger := set.tips
+	ledger.mu.Lock()
+	defer ledger.mu.Unlock()
+	livestream, s := ledger.entries(livestreamID, streamer)
+	for _, e := range []*TipLedgerEntry{livestream, s} {
+		e.Attempted += tip
+		e.MaxAttempted = max(e.MaxAttempted, tip)
+	}
+}
+
+// AddTip は、成功を確認したチップを売上に加算し、台帳に記録します
+func (set *ScoreSet) AddTip(livestreamID int64, streamer string, tip uint64) {
+	set.profit.Add(tip)
+	set.timeline.addProfit(tip)
+	set.ConfirmTip(livestreamID, streamer, tip)
+}
+
+// ConfirmTip は、成功を確認したチップを、売上に加算せずに台帳にのみ記録します
+// NOTE: 入力検証のために送った境界値のチップは、webappの統計情報には反映されるが売上にはしない
+func (set *ScoreSet) ConfirmTip(livestreamID int64, streamer string, tip uint64) {
+	if tip == 0 {
+		return
+	}
+
+	ledger := set.tips
+	ledger.mu.Lock()
+	defer ledger.mu.Unlock()
+	livestream, s := ledger.entries(livestreamID, streamer)
+	for _, e := range []*TipLedgerEntry{livestream, s} {
+		e.Confirmed += tip
+		e.ConfirmedCount++
+		e.MaxConfirmed = max(e.MaxConfirmed, tip)
+	}
+}
+
+// RecordTippedLivecomment は、成功を確認したチップ付きのライブコメントの本文を記録します
+// NOTE: 後からNGワードが登録された際に、削除されるチップを求めるのに用いる
+func (set *ScoreSet) RecordTippedLivecomment(livestreamID int64, comment string, tip uint64) {
+	if tip == 0 {
+		return
+	}
 
-func AddTip(tip uint64) {
-	atomic.AddUint64(&profit, tip)
+	ledger := set.tips
+	ledger.mu.Lock()
+	defer ledger.mu.Unlock()
+	ledger.livecomments[livestreamID] = append(ledger.livecomments[livestreamID], tippedLivecomment{comment: comment, tip: tip})
+}
+
+// ModerateTips は、NGワードの登録によって削除されるチップ付きのライブコメントを台帳から除き、削除されたチップとして記録します
+// NOTE: webappは配信のライブコメントのうち、NGワードを含むものを削除する
+func (set *ScoreSet) ModerateTips(livestreamID int64, streamer string, ngWord string) {
+	if ngWord == "" {
+		return
+	}
+
+	ledger := set.tips
+	ledger.mu.Lock()
+	defer ledger.mu.Unlock()
+	comments, ok := ledger.livecomments[livestreamID]
+	if !ok {
+		return
+	}
+	var (
+		moderated uint64
+		retained  = comments[:0]
+	)
+	for _, c := range comments {
+		if strings.Contains(c.comment, ngWord) {
+			moderated += c.tip
+			continue
+		}
+		retained = append(retained, c)
+	}
+	ledger.livecomments[livestreamID] = retained
+	if moderated == 0 {
+		return
+	}
+	livestream, s := ledger.entries(livestreamID, streamer)
+	for _, e := range []*TipLedgerEntry{livestream, s} {
+		e.Moderated += moderated
+	}
+}
+
+// LivestreamTips は、配信ごとのチップの記録を、成功を確認したチップの多い順に返します
+func (set *ScoreSet) LivestreamTips() []LivestreamTipLedgerEntry {
+	ledger := set.tips
+	ledger.mu.Lock()
+	defer ledger.mu.Unlock()
+
+	entries := make([]LivestreamTipLedgerEntry, 0, len(ledger.livestreams))
+	for _, e := range ledger.livestreams {
+		entries = append(entries, *e)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Confirmed != entries[j].Confirmed {
+			return entries[i].Confirmed > entries[j].Confirmed
+		}
+		return entries[i].LivestreamID < entries[j].LivestreamID
+	})
+	return entries
+}
+
+// StreamerTips は、配信者ごとのチップの記録を、成功を確認したチップの多い順に返します
+func (set *ScoreSet) StreamerTips() []StreamerTipLedgerEntry {
+	ledger := set.tips
+	ledger.mu.Lock()
+	defer ledger.mu.Unlock()
+
+	entries := make([]StreamerTipLedgerEntry, 0, len(ledger.streamers))
+	for _, e := range ledger.streamers {
+		entries = append(entries, *e)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Confirmed != entries[j].Confirmed {
+			return entries[i].Confirmed > entries[j].Confirmed
+		}
+		return entries[i].Streamer < entries[j].Streamer
+	})
+	return entries
+}
+
+// TotalTips は、全配信のチップの記録を合計して返します
+func (set *ScoreSet) TotalTips() TipLedgerEntry {
+	ledger := set.tips
+	ledger.mu.Lock()
+	defer ledger.mu.Unlock()
+
+	var total TipLedgerEntry
+	for _, e := range ledger.streamers {
+		total.Confirmed += e.Confirmed
+		total.ConfirmedCount += e.ConfirmedCount
+		total.Attempted += e.Attempted
+		total.MaxConfirmed = max(total.MaxConfirmed, e.MaxConfirmed)
+		total.MaxAttempted = max(total.MaxAttempted, e.MaxAttempted)
+		total.Moderated += e.Moderated
+	}
+	return total
+}
+
+func RecordTipAttempt(ctx context.Context, livestreamID int64, streamer string, tip uint64) {
+	FromContext(ctx).RecordTipAttempt(livestreamID, streamer, tip)
+}
+
+func AddTip(ctx context.Context, livestreamID int64, streamer string, tip uint64) {
+	FromContext(ctx).AddTip(livestreamID, streamer, tip)
+}
+
+func ConfirmTip(ctx context.Context, livestreamID int64, streamer string, tip uint64) {
+	FromContext(ctx).ConfirmTip(livestreamID, streamer, tip)
+}
+
+func RecordTippedLivecomment(ctx context.Context, livestreamID int64, comment string, tip uint64) {
+	FromContext(ctx).RecordTippedLivecomment(livestreamID, comment, tip)
+}
+
+func ModerateTips(ctx context.Context, livestreamID int64, streamer string, ngWord string) {
+	FromContext(ctx).ModerateTips(livestreamID, streamer, ngWord)
+}
+
+func LivestreamTips(ctx context.Context) []LivestreamTipLedgerEntry {
+	return FromContext(ctx).LivestreamTips()
+}
+
+func StreamerTips(ctx context.Context) []StreamerTipLedgerEntry {
+	return FromContext(ctx).StreamerTips()
 }
 
 // GetFinalProfit は、最終売上を返します
 // FIXME: finalcheck後にprofitをスコアに加算しないと駄目
-func GetTotalProfit() uint64 {
-	return profit
+func GetTotalProfit(ctx context.Context) uint64 {
+	return FromContext(ctx).TotalProfit()
+}
+
+// TotalTips は、既定の ScoreSet のチップの台帳の合計を返します
+func TotalTips() TipLedgerEntry {
+	return defaultSet.TotalTips()
 }
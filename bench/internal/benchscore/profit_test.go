@@ -0,0 +1,119 @@
+package benchscore
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTipLedgerEntry_Merge(t *testing.T) {
+	pretest := TipLedgerEntry{Confirmed: 100, ConfirmedCount: 1, Attempted: 100, MaxConfirmed: 100, MaxAttempted: 100}
+	load := TipLedgerEntry{Confirmed: 50, ConfirmedCount: 2, Attempted: 550, MaxConfirmed: 30, MaxAttempted: 500}
+
+	total := pretest.Merge(load)
+	assert.Equal(t, uint64(150), total.Confirmed)
+	assert.Equal(t, int64(3), total.ConfirmedCount)
+	assert.Equal(t, uint64(650), total.Attempted)
+	assert.Equal(t, uint64(500), total.Unconfirmed())
+	assert.Equal(t, uint64(100), total.MaxConfirmed)
+	assert.Equal(t, uint64(500), total.MaxAttempted)
+}
+
+func TestTipLedger(t *testing.T) {
+	ctx := WithScoreSet(context.Background(), NewScoreSet(context.Background()))
+
+	// 成功
+	RecordTipAttempt(ctx, 1, "streamer-a", 100)
+	AddTip(ctx, 1, "streamer-a", 100)
+	RecordTipAttempt(ctx, 2, "streamer-a", 50)
+	AddTip(ctx, 2, "streamer-a", 50)
+	// タイムアウトなどで成否が分からない
+	RecordTipAttempt(ctx, 2, "streamer-a", 500)
+	RecordTipAttempt(ctx, 3, "streamer-b", 10)
+	AddTip(ctx, 3, "streamer-b", 10)
+	// チップなしは記録しない
+	RecordTipAttempt(ctx, 4, "streamer-c", 0)
+	AddTip(ctx, 4, "streamer-c", 0)
+
+	streamers := StreamerTips(ctx)
+	assert.Len(t, streamers, 2)
+	assert.Equal(t, "streamer-a", streamers[0].Streamer)
+	assert.Equal(t, uint64(150), streamers[0].Confirmed)
+	assert.Equal(t, uint64(650), streamers[0].Attempted)
+	assert.Equal(t, uint64(500), streamers[0].Unconfirmed())
+	assert.Equal(t, int64(2), streamers[0].ConfirmedCount)
+	assert.Equal(t, uint64(100), streamers[0].MaxConfirmed)
+	assert.Equal(t, uint64(500), streamers[0].MaxAttempted)
+
+	livestreams := LivestreamTips(ctx)
+	assert.Len(t, livestreams, 3)
+	assert.Equal(t, int64(1), livestreams[0].LivestreamID)
+	assert.Equal(t, int64(2), livestreams[1].LivestreamID)
+	assert.Equal(t, "streamer-a", livestreams[1].Streamer)
+	assert.Equal(t, uint64(550), livestreams[1].Attempted)
+
+	total := FromContext(ctx).TotalTips()
+	assert.Equal(t, uint64(160), total.Confirmed)
+	assert.Equal(t, uint64(660), total.Attempted)
+	assert.Equal(t, int64(3), total.ConfirmedCount)
+}
+
+func TestConfirmTip(t *testing.T) {
+	set := NewScoreSet(context.Background())
+
+	set.RecordTipAttempt(1, "streamer-a", 100000)
+	set.ConfirmTip(1, "streamer-a", 100000)
+
+	// 台帳には記録するが、売上には加算しない
+	assert.Equal(t, uint64(0), set.TotalProfit())
+	assert.Equal(t, uint64(100000), set.TotalTips().Confirmed)
+	assert.Equal(t, uint64(0), set.TotalTips().Unconfirmed())
+}
+
+func TestScoreSet_Independent(t *testing.T) {
+	pretest := NewScoreSet(context.Background())
+	load := NewScoreSet(context.Background())
+
+	pretest.AddTip(1, "streamer-a", 100)
+	pretest.Done()
+	// 先に終了した ScoreSet があっても、別の ScoreSet は集計を続けられる
+	load.AddTip(1, "streamer-a", 10)
+	load.Add(DNSProvisioned)
+	pretest.Done()
+
+	assert.Equal(t, uint64(100), pretest.TotalProfit())
+	assert.Equal(t, uint64(10), load.TotalProfit())
+	assert.Eventually(t, func() bool {
+		return load.GetByTag(DNSProvisioned) == 1
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, int64(0), pretest.GetByTag(DNSProvisioned))
+}
+
+func TestModerateTips(t *testing.T) {
+	set := NewScoreSet(context.Background())
+
+	set.AddTip(1, "streamer-a", 100)
+	set.RecordTippedLivecomment(1, "とても良い配信です", 100)
+	set.AddTip(1, "streamer-a", 30)
+	set.RecordTippedLivecomment(1, "スパムを含むコメント", 30)
+	set.AddTip(2, "streamer-a", 50)
+	set.RecordTippedLivecomment(2, "スパムを含むコメント", 50)
+
+	// NGワードは登録した配信のライブコメントにのみ作用する
+	set.ModerateTips(1, "streamer-a", "スパム")
+	// 削除済みのライブコメントは重ねて計上しない
+	set.ModerateTips(1, "streamer-a", "コメント")
+
+	livestreams := set.LivestreamTips()
+	assert.Equal(t, int64(1), livestreams[0].LivestreamID)
+	assert.Equal(t, uint64(30), livestreams[0].Moderated)
+	assert.Equal(t, uint64(100), livestreams[0].Retained())
+	assert.Equal(t, uint64(0), livestreams[1].Moderated)
+
+	total := set.TotalTips()
+	assert.Equal(t, uint64(180), total.Confirmed)
+	assert.Equal(t, uint64(30), total.Moderated)
+	assert.Equal(t, uint64(150), total.Retained())
+}
@@ -0,0 +1,201 @@
+package benchscore
+
+import (
+	"context"
+	"errors"
+	"math"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/isucon/isucandar/score"
+	"github.com/isucon/isucon13/bench/internal/bencherror"
+	"github.com/isucon/isucon13/bench/internal/config"
+)
+
+// SessionQuality は、視聴者が視聴を終えた時点の体感品質(0.0〜1.0)の合計の配点に用いるタグです
+const SessionQuality score.ScoreTag = "session-quality"
+
+// Session は、視聴者1人の視聴セッション中の体験を集計します
+// 遅延したリクエスト、エラー、目にしたスパムの割合から、視聴終了時の体感品質を算出します
+type Session struct {
+	mu sync.Mutex
+
+	requests     int64
+	slowRequests int64
+	errors       int64
+	livecomments int64
+	spams        int64
+
+	// 直近のリクエストの所要時間の、遅延の閾値に対する比 (config.ViewerAbandonWindow 件のリングバッファ)
+	recent     []float64
+	recentNext int
+}
+
+func NewSession() *Session {
+	return &Session{
+		recent: make([]float64, 0, max(config.ViewerAbandonWindow, 1)),
+	}
+}
+
+// ObserveRequest は、リクエスト1件の所要時間を記録します
+// slowThreshold は、エンドポイントの分類ごとの、視聴者が遅延していると感じる所要時間です
+func (s *Session) ObserveRequest(latency time.Duration, slowThreshold time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests++
+	if latency >= slowThreshold {
+		s.slowRequests++
+	}
+
+	// NOTE: エンドポイントの分類ごとに遅延の閾値が異なるため、閾値に対する比で比べる
+	slowness := float64(latency) / float64(slowThreshold)
+	if len(s.recent) < cap(s.recent) {
+		s.recent = append(s.recent, slowness)
+	} else {
+		s.recent[s.recentNext] = slowness
+	}
+	s.recentNext = (s.recentNext + 1) % cap(s.recent)
+}
+
+// ObserveError は、視聴者が遭遇したエラーを記録します
+// NOTE: タイムアウトは遅延したリクエストとして計上済みのため除く
+func (s *Session) ObserveError(err error) {
+	if err == nil || errors.Is(err, bencherror.ErrTimeout) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.errors++
+}
+
+// ObserveLivecomments は、視聴者が目にしたライブコメント数と、そのうちのスパム数を記録します
+func (s *Session) ObserveLivecomments(total, spams int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.livecomments += int64(total)
+	s.spams += int64(spams)
+}
+
+// RecentSlowness は、直近のリクエストの所要時間の、遅延の閾値に対する比の percentile パーセンタイルを返します
+// 1以上であれば、直近のリクエストの (100-percentile)% 以上が遅延していると感じたことになります
+// NOTE: 直近のリクエストが config.ViewerAbandonWindow 件に満たない場合は、判断せずに0を返す
+func (s *Session) RecentSlowness(percentile float64) float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.recent) == 0 || len(s.recent) < cap(s.recent) {
+		return 0
+	}
+	sorted := slices.Clone(s.recent)
+	slices.Sort(sorted)
+	// NOTE: 最近接順位法
+	rank := int(math.Ceil(percentile / 100 * float64(len(sorted))))
+	return sorted[min(max(rank, 1), len(sorted))-1]
+}
+
+// SpamPercentage は、目にしたライブコメントのうちスパムの割合[%]を返します
+func (s *Session) SpamPercentage() float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.livecomments == 0 {
+		return 0
+	}
+	return float64(s.spams) / float64(s.livecomments) * 100
+}
+
+// Quality は、遅延・エラー・スパムそれぞれの満足度の積を体感品質として返します
+func (s *Session) Quality() float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	latency, errorRate := 1.0, 1.0
+	if s.requests > 0 {
+		latency = 1 - float64(s.slowRequests)/float64(s.requests)
+		errorRate = math.Max(0, 1-float64(s.errors)/float64(s.requests))
+	} else if s.errors > 0 {
+		errorRate = 0
+	}
+
+	spam := 1.0
+	if s.livecomments > 0 {
+		// スパムの割合が離脱の閾値に達したら満足度は0
+		spamPercentage := float64(s.spams) / float64(s.livecomments) * 100
+		spam = math.Max(0, 1-spamPercentage/config.TooManySpamThresholdPercentage)
+	}
+
+	return latency * errorRate * spam
+}
+
+type sessionContextKey struct{}
+
+// WithSession は、セッションをcontextに紐づけます
+// NOTE: isupipeのクライアントは、contextに紐づくセッションにリクエストの所要時間を記録します
+func WithSession(ctx context.Context, s *Session) context.Context {
+	return context.WithValue(ctx, sessionContextKey{}, s)
+}
+
+// SessionFromContext は、contextに紐づくセッションを返します。紐づいていなければnilを返します
+func SessionFromContext(ctx context.Context) *Session {
+	s, _ := ctx.Value(sessionContextKey{}).(*Session)
+	return s
+}
+
+// qualityStats は、視聴を終えたセッションの体感品質の集計です
+type qualityStats struct {
+	mu                  sync.Mutex
+	numSessions         int64
+	totalQuality        float64
+	zeroQualitySessions int64
+}
+
+func newQualityStats() *qualityStats {
+	return &qualityStats{}
+}
+
+func (q *qualityStats) score() int64 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return int64(math.Round(q.totalQuality * float64(getWeight(SessionQuality))))
+}
+
+// RecordSession は、視聴を終えたセッションの体感品質を集計に加えます
+func (set *ScoreSet) RecordSession(s *Session) {
+	quality := s.Quality()
+	set.timeline.addQuality(quality)
+
+	q := set.quality
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.numSessions++
+	q.totalQuality += quality
+	if quality == 0 {
+		q.zeroQualitySessions++
+	}
+}
+
+// QualityStats は、視聴を終えたセッション数、体感品質の平均、体感品質が0だったセッション数を返します
+func (set *ScoreSet) QualityStats() (sessions int64, mean float64, zeroSessions int64) {
+	q := set.quality
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.numSessions == 0 {
+		return 0, 0, 0
+	}
+	return q.numSessions, q.totalQuality / float64(q.numSessions), q.zeroQualitySessions
+}
+
+func RecordSession(ctx context.Context, s *Session) {
+	FromContext(ctx).RecordSession(s)
+}
+
+// QualityStats は、既定の ScoreSet の視聴者の体感品質の集計を返します
+func QualityStats() (sessions int64, mean float64, zeroSessions int64) {
+	return defaultSet.QualityStats()
+}
@@ -0,0 +1,80 @@
+package benchscore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/isucon/isucon13/bench/internal/bencherror"
+	"github.com/isucon/isucon13/bench/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSession_RecentSlowness(t *testing.T) {
+	defaultWindow := config.ViewerAbandonWindow
+	config.ViewerAbandonWindow = 10
+	t.Cleanup(func() {
+		config.ViewerAbandonWindow = defaultWindow
+	})
+	threshold := config.SessionSlowRequestThreshold
+
+	s := NewSession()
+	// 直近のリクエストが窓の件数に満たない間は、遅延していても判断しない
+	s.ObserveRequest(10*threshold, threshold)
+	assert.Equal(t, 0.0, s.RecentSlowness(config.TooSlowPercentile))
+
+	// 1回だけ遅延しても、p90は閾値を下回る
+	for i := 0; i < 9; i++ {
+		s.ObserveRequest(threshold/10, threshold)
+	}
+	assert.InDelta(t, 0.1, s.RecentSlowness(config.TooSlowPercentile), 1e-9)
+
+	// 窓から外れた遅延は判断に用いない
+	s.ObserveRequest(2*threshold, threshold)
+	assert.InDelta(t, 0.1, s.RecentSlowness(config.TooSlowPercentile), 1e-9)
+
+	// 窓の中で2回遅延すると、p90は閾値に達する
+	s.ObserveRequest(2*threshold, threshold)
+	assert.InDelta(t, 2.0, s.RecentSlowness(config.TooSlowPercentile), 1e-9)
+
+	for i := 0; i < 10; i++ {
+		s.ObserveRequest(threshold/2, threshold)
+	}
+	assert.InDelta(t, 0.5, s.RecentSlowness(config.TooSlowPercentile), 1e-9)
+}
+
+func TestSessionQuality(t *testing.T) {
+	perfect := NewSession()
+	perfect.ObserveRequest(10*time.Millisecond, config.SessionSlowRequestThreshold)
+	perfect.ObserveLivecomments(10, 0)
+	assert.Equal(t, 1.0, perfect.Quality())
+
+	// 半分のリクエストが遅延し、タイムアウトはエラーとして二重に計上しない
+	slow := NewSession()
+	slow.ObserveRequest(10*time.Millisecond, config.SessionSlowRequestThreshold)
+	slow.ObserveRequest(config.SessionSlowRequestThreshold, config.SessionSlowRequestThreshold)
+	slow.ObserveError(fmt.Errorf("GET /api/tag: %w", bencherror.ErrTimeout))
+	slow.ObserveError(nil)
+	assert.InDelta(t, 0.5, slow.Quality(), 1e-9)
+
+	failed := NewSession()
+	failed.ObserveRequest(10*time.Millisecond, config.SessionSlowRequestThreshold)
+	failed.ObserveRequest(10*time.Millisecond, config.SessionSlowRequestThreshold)
+	failed.ObserveError(errors.New("unexpected status code"))
+	assert.InDelta(t, 0.5, failed.Quality(), 1e-9)
+
+	// スパムの割合が閾値に達したら体感品質は0
+	spammed := NewSession()
+	spammed.ObserveLivecomments(100, int(config.TooManySpamThresholdPercentage))
+	assert.Equal(t, 0.0, spammed.Quality())
+
+	set := NewScoreSet(context.Background())
+	set.RecordSession(perfect)
+	set.RecordSession(spammed)
+	sessions, mean, zeroSessions := set.QualityStats()
+	assert.Equal(t, int64(2), sessions)
+	assert.InDelta(t, 0.5, mean, 1e-9)
+	assert.Equal(t, int64(1), zeroSessions)
+}
@@ -0,0 +1,129 @@
+package benchscore
+
+import (
+	"cmp"
+	"slices"
+	"sync"
+
+	"github.com/isucon/isucandar/score"
+)
+
+// ScenarioPhase は、シナリオを実行した走行の段階です
+type ScenarioPhase string
+
+const (
+	ScenarioPhasePretest ScenarioPhase = "pretest"
+	ScenarioPhaseLoad    ScenarioPhase = "load"
+	ScenarioPhaseFinal   ScenarioPhase = "final"
+)
+
+// ScenarioPhases は、結果に出力する段階の順序です
+var ScenarioPhases = []ScenarioPhase{ScenarioPhasePretest, ScenarioPhaseLoad, ScenarioPhaseFinal}
+
+// ScenarioOutcome は、シナリオ1回分の結果です
+type ScenarioOutcome string
+
+const (
+	ScenarioSuccess ScenarioOutcome = "success"
+	ScenarioFail    ScenarioOutcome = "fail"
+)
+
+type scenarioKey struct {
+	phase    ScenarioPhase
+	scenario score.ScoreTag
+	outcome  ScenarioOutcome
+}
+
+// ScenarioCounter は、段階・シナリオ・結果ごとのシナリオの実行回数です
+// NOTE: score.Score と異なり、Add は同期的に反映されるため、走行の途中でも数え漏れなく読み出せる
+type ScenarioCounter struct {
+	mu     sync.Mutex
+	counts map[scenarioKey]int64
+}
+
+func NewScenarioCounter() *ScenarioCounter {
+	return &ScenarioCounter{
+		counts: make(map[scenarioKey]int64),
+	}
+}
+
+// Add は、シナリオの実行回数を1つ増やします
+func (c *ScenarioCounter) Add(phase ScenarioPhase, scenario score.ScoreTag, outcome ScenarioOutcome) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.counts[scenarioKey{phase: phase, scenario: scenario, outcome: outcome}]++
+}
+
+// Count は、シナリオの実行回数を返します
+func (c *ScenarioCounter) Count(phase ScenarioPhase, scenario score.ScoreTag, outcome ScenarioOutcome) int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.counts[scenarioKey{phase: phase, scenario: scenario, outcome: outcome}]
+}
+
+// Phase は、段階を固定して数える PhaseScenarioCounter を返します
+func (c *ScenarioCounter) Phase(phase ScenarioPhase) PhaseScenarioCounter {
+	return PhaseScenarioCounter{counter: c, phase: phase}
+}
+
+// ScenarioCount は、1つの段階における1つのシナリオの成功・失敗回数です
+type ScenarioCount struct {
+	Phase    ScenarioPhase  `json:"phase"`
+	Scenario score.ScoreTag `json:"scenario"`
+	Success  int64          `json:"success"`
+	Fail     int64          `json:"fail"`
+}
+
+// Rows は、シナリオの実行回数を段階の順、シナリオのタグ名の順に返します
+func (c *ScenarioCounter) Rows() []ScenarioCount {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	type rowKey struct {
+		phase    ScenarioPhase
+		scenario score.ScoreTag
+	}
+	rows := make(map[rowKey]*ScenarioCount)
+	for key, count := range c.counts {
+		rk := rowKey{phase: key.phase, scenario: key.scenario}
+		row, ok := rows[rk]
+		if !ok {
+			row = &ScenarioCount{Phase: key.phase, Scenario: key.scenario}
+			rows[rk] = row
+		}
+		switch key.outcome {
+		case ScenarioSuccess:
+			row.Success += count
+		case ScenarioFail:
+			row.Fail += count
+		}
+	}
+
+	result := make([]ScenarioCount, 0, len(rows))
+	for _, row := range rows {
+		result = append(result, *row)
+	}
+	slices.SortFunc(result, func(a, b ScenarioCount) int {
+		if n := cmp.Compare(slices.Index(ScenarioPhases, a.Phase), slices.Index(ScenarioPhases, b.Phase)); n != 0 {
+			return n
+		}
+		return cmp.Compare(a.Scenario, b.Scenario)
+	})
+	return result
+}
+
+// PhaseScenarioCounter は、1つの段階のシナリオの実行回数を数えます
+type PhaseScenarioCounter struct {
+	counter *ScenarioCounter
+	phase   ScenarioPhase
+}
+
+// Succeed は、シナリオの成功回数を1つ増やします
+func (c PhaseScenarioCounter) Succeed(scenario score.ScoreTag) {
+	c.counter.Add(c.phase, scenario, ScenarioSuccess)
+}
+
+// Fail は、シナリオの失敗回数を1つ増やします
+func (c PhaseScenarioCounter) Fail(scenario score.ScoreTag) {
+	c.counter.Add(c.phase, scenario, ScenarioFail)
+}
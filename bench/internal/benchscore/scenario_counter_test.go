@@ -0,0 +1,44 @@
+package benchscore
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScenarioCounter(t *testing.T) {
+	counter := NewScenarioCounter()
+	load := counter.Phase(ScenarioPhaseLoad)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if i%10 == 0 {
+				load.Fail("viewer")
+			} else {
+				load.Succeed("viewer")
+			}
+		}(i)
+	}
+	wg.Wait()
+	load.Fail("raid")
+	counter.Add(ScenarioPhaseFinal, "finalcheck", ScenarioSuccess)
+	counter.Add(ScenarioPhasePretest, "user_register", ScenarioSuccess)
+	counter.Add(ScenarioPhasePretest, "initial_data", ScenarioFail)
+
+	assert.Equal(t, int64(90), counter.Count(ScenarioPhaseLoad, "viewer", ScenarioSuccess))
+	assert.Equal(t, int64(10), counter.Count(ScenarioPhaseLoad, "viewer", ScenarioFail))
+	assert.Equal(t, int64(0), counter.Count(ScenarioPhasePretest, "viewer", ScenarioSuccess))
+
+	// 段階の順、シナリオのタグ名の順に並ぶ
+	assert.Equal(t, []ScenarioCount{
+		{Phase: ScenarioPhasePretest, Scenario: "initial_data", Fail: 1},
+		{Phase: ScenarioPhasePretest, Scenario: "user_register", Success: 1},
+		{Phase: ScenarioPhaseLoad, Scenario: "raid", Fail: 1},
+		{Phase: ScenarioPhaseLoad, Scenario: "viewer", Success: 90, Fail: 10},
+		{Phase: ScenarioPhaseFinal, Scenario: "finalcheck", Success: 1},
+	}, counter.Rows())
+}
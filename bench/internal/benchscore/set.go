@@ -0,0 +1,122 @@
+package benchscore
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/isucon/isucandar/score"
+)
+
+// ScoreSet は、1回の走行(pretest、負荷走行など)のカウンタ、売上、体感品質、チップと操作の記録です
+// 走行ごとに作り直すことで、前の走行の集計や終了処理の影響を受けずに集計できます
+// NOTE: パッケージの関数は WithScoreSet でcontextに紐づけた ScoreSet に対して集計する. 紐づいていなければ既定の ScoreSet に集計する
+type ScoreSet struct {
+	counter  *score.Score
+	doneOnce sync.Once
+	// GetByTag のため、カウンタとは別にスコアタグごとの件数を保持する (score.ScoreTag -> *atomic.Int64)
+	// NOTE: カウンタの Breakdown は呼び出しのたびに全タグを写すため、1つのタグを読むには重い
+	tags sync.Map
+
+	profit     atomic.Uint64
+	quality    *qualityStats
+	tips       *tipLedger
+	activities *activityLog
+	// スコアの凍結のため、加算した値を時間帯ごとにも集計する
+	timeline *timeline
+}
+
+// NewScoreSet は、配点を設定したカウンタを持つ ScoreSet を返します
+// NOTE: カウンタはctxが終了するか Done を呼び出すまで集計を続ける
+func NewScoreSet(ctx context.Context) *ScoreSet {
+	set := &ScoreSet{
+		counter:    score.NewScore(ctx),
+		quality:    newQualityStats(),
+		tips:       newTipLedger(),
+		activities: newActivityLog(),
+		timeline:   newTimeline(time.Now()),
+	}
+	for tag, weight := range Weights() {
+		if tag == Profit || tag == SessionQuality {
+			continue
+		}
+		set.counter.Set(tag, weight)
+	}
+	return set
+}
+
+// NOTE: 走行に紐づかないcontextで加算された値も失われないよう、既定の ScoreSet を持つ
+var defaultSet = NewScoreSet(context.Background())
+
+// Default は、contextに ScoreSet が紐づいていない場合の集計先を返します
+func Default() *ScoreSet {
+	return defaultSet
+}
+
+type scoreSetContextKey struct{}
+
+// WithScoreSet は、ScoreSet をcontextに紐づけます
+// NOTE: パッケージの関数は、contextに紐づく ScoreSet に対して集計する
+func WithScoreSet(ctx context.Context, set *ScoreSet) context.Context {
+	return context.WithValue(ctx, scoreSetContextKey{}, set)
+}
+
+// FromContext は、contextに紐づく ScoreSet を返します。紐づいていなければ既定の ScoreSet を返します
+func FromContext(ctx context.Context) *ScoreSet {
+	if set, ok := ctx.Value(scoreSetContextKey{}).(*ScoreSet); ok {
+		return set
+	}
+	return defaultSet
+}
+
+// Add は、スコアタグのカウンタを1加算します
+func (set *ScoreSet) Add(tag score.ScoreTag) {
+	set.counter.Add(tag)
+	set.tagCount(tag).Add(1)
+	set.timeline.add(tag)
+}
+
+func (set *ScoreSet) tagCount(tag score.ScoreTag) *atomic.Int64 {
+	if n, ok := set.tags.Load(tag); ok {
+		return n.(*atomic.Int64)
+	}
+	n, _ := set.tags.LoadOrStore(tag, new(atomic.Int64))
+	return n.(*atomic.Int64)
+}
+
+// Breakdown は、スコアタグごとのカウンタの値を返します
+func (set *ScoreSet) Breakdown() score.ScoreTable {
+	return set.counter.Breakdown()
+}
+
+// GetByTag は、スコアタグのカウンタの値を返します
+// NOTE: Breakdown と異なり、加算した直後の値を返す
+func (set *ScoreSet) GetByTag(tag score.ScoreTag) int64 {
+	if n, ok := set.tags.Load(tag); ok {
+		return n.(*atomic.Int64).Load()
+	}
+	return 0
+}
+
+// TotalProfit は、成功を確認したチップの合計(売上)を返します
+func (set *ScoreSet) TotalProfit() uint64 {
+	return set.profit.Load()
+}
+
+// Score は、配点に基づいて売上、カウンタ、視聴者の体感品質からスコアを算出します
+func (set *ScoreSet) Score() int64 {
+	return int64(set.TotalProfit())*getWeight(Profit) + set.counter.Sum() + set.quality.score()
+}
+
+// Done は、カウンタの集計を終了します。複数回呼び出しても安全です
+func (set *ScoreSet) Done() {
+	set.doneOnce.Do(func() {
+		set.counter.Close()
+	})
+}
+
+// GetScore は、既定の ScoreSet のスコアを返します
+func GetScore() int64 {
+	return defaultSet.Score()
+}
@@ -0,0 +1,65 @@
+package benchscore
+
+import (
+	"cmp"
+	"slices"
+
+	"github.com/isucon/isucandar/score"
+)
+
+// シナリオ(スコアタグ)ごとの成功率の下限
+// NOTE: 既定では設けない. 売上のみの採点では、機能を丸ごと落としても売上が正であれば通過してしまうため、
+// --score-config で指定したファイルの slo キーによって設定します
+var slos = map[score.ScoreTag]float64{}
+
+// SLOViolation は、成功率が下限を下回ったシナリオです
+type SLOViolation struct {
+	Scenario score.ScoreTag `json:"scenario"`
+	Success  int64          `json:"success"`
+	Fail     int64          `json:"fail"`
+	// 成功率 (一度も実行されなかった場合は0)
+	Ratio float64 `json:"ratio"`
+	// 設定した成功率の下限
+	MinRatio float64 `json:"min_ratio"`
+}
+
+// Attempts は、シナリオの実行回数を返します
+func (v SLOViolation) Attempts() int64 {
+	return v.Success + v.Fail
+}
+
+// SLOs は、現在の成功率の下限を返します
+func SLOs() map[score.ScoreTag]float64 {
+	weightsMu.RLock()
+	defer weightsMu.RUnlock()
+
+	m := make(map[score.ScoreTag]float64, len(slos))
+	for tag, ratio := range slos {
+		m[tag] = ratio
+	}
+	return m
+}
+
+// CheckSLOs は、シナリオの成功・失敗回数から、成功率が下限を下回ったシナリオをタグ名の順に返します
+// NOTE: 成功率は負荷走行の段階の実行回数で評価する. 一度も実行されなかったシナリオも、成功率0として下回ったものとする
+func CheckSLOs(counter *ScenarioCounter, slos map[score.ScoreTag]float64) []SLOViolation {
+	var violations []SLOViolation
+	for tag, minRatio := range slos {
+		v := SLOViolation{
+			Scenario: tag,
+			Success:  counter.Count(ScenarioPhaseLoad, tag, ScenarioSuccess),
+			Fail:     counter.Count(ScenarioPhaseLoad, tag, ScenarioFail),
+			MinRatio: minRatio,
+		}
+		if attempts := v.Attempts(); attempts > 0 {
+			v.Ratio = float64(v.Success) / float64(attempts)
+		}
+		if v.Ratio < minRatio {
+			violations = append(violations, v)
+		}
+	}
+	slices.SortFunc(violations, func(a, b SLOViolation) int {
+		return cmp.Compare(a.Scenario, b.Scenario)
+	})
+	return violations
+}
@@ -0,0 +1,68 @@
+package benchscore
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/isucon/isucandar/score"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadWeights_SLO(t *testing.T) {
+	defaultWeights, defaultSLOs := Weights(), SLOs()
+	t.Cleanup(func() {
+		weightsMu.Lock()
+		defer weightsMu.Unlock()
+		weights, slos = defaultWeights, defaultSLOs
+	})
+
+	dir := t.TempDir()
+
+	path := filepath.Join(dir, "score.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte("profit: 2\nslo:\n  viewer: 0.95\n"), 0644))
+	assert.NoError(t, LoadWeights(path))
+	assert.Equal(t, int64(2), Weights()[Profit])
+	assert.Equal(t, 0.95, SLOs()["viewer"])
+
+	invalidPath := filepath.Join(dir, "invalid.yaml")
+	assert.NoError(t, os.WriteFile(invalidPath, []byte("slo:\n  viewer: 1.5\n"), 0644))
+	assert.Error(t, LoadWeights(invalidPath))
+}
+
+func TestCheckSLOs(t *testing.T) {
+	counter := NewScenarioCounter()
+	load := counter.Phase(ScenarioPhaseLoad)
+	for i := 0; i < 95; i++ {
+		load.Succeed("viewer")
+	}
+	for i := 0; i < 5; i++ {
+		load.Fail("viewer")
+	}
+	for i := 0; i < 8; i++ {
+		load.Succeed("streamer-moderate")
+	}
+	for i := 0; i < 2; i++ {
+		load.Fail("streamer-moderate")
+	}
+	// 負荷走行以外の段階の実行回数は成功率に含めない
+	counter.Add(ScenarioPhasePretest, "reaction-burst", ScenarioSuccess)
+	slos := map[score.ScoreTag]float64{
+		"viewer":            0.95,
+		"streamer-moderate": 0.9,
+		"reaction-burst":    0.5,
+	}
+
+	violations := CheckSLOs(counter, slos)
+	assert.Len(t, violations, 2)
+
+	// 一度も実行されなかったシナリオ
+	assert.Equal(t, score.ScoreTag("reaction-burst"), violations[0].Scenario)
+	assert.Equal(t, int64(0), violations[0].Attempts())
+	assert.Equal(t, 0.0, violations[0].Ratio)
+
+	assert.Equal(t, score.ScoreTag("streamer-moderate"), violations[1].Scenario)
+	assert.Equal(t, int64(8), violations[1].Success)
+	assert.Equal(t, int64(2), violations[1].Fail)
+	assert.InDelta(t, 0.8, violations[1].Ratio, 1e-9)
+}
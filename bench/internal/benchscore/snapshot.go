@@ -0,0 +1,45 @@
+package benchscore
+
+import (
+	"math"
+	"time"
+
+	"github.com/isucon/isucandar/score"
+)
+
+// ScoreSnapshot は、ある時点のスコアタグごとのカウンタ、売上、スコアの写しです
+type ScoreSnapshot struct {
+	At       time.Time        `json:"at"`
+	Counters score.ScoreTable `json:"counters"`
+	Profit   uint64           `json:"profit"`
+	Score    int64            `json:"score"`
+}
+
+// Get は、スコアタグのカウンタの値を返します
+func (s *ScoreSnapshot) Get(tag score.ScoreTag) int64 {
+	return s.Counters[tag]
+}
+
+// Snapshot は、走行中でも安全に読み出せる、現時点のスコアの写しを返します
+// スコアはスナップショットに含まれるカウンタ、売上、体感品質から算出するため、スコアとその内訳は一致します
+// NOTE: 時間帯ごとの集計からまとめて読み出すため、各値は同じ時点のものとなる
+func (set *ScoreSet) Snapshot() *ScoreSnapshot {
+	counters, profit, quality := set.timeline.sum(-1)
+	return newScoreSnapshot(time.Now(), counters, profit, quality)
+}
+
+// newScoreSnapshot は、カウンタ、売上、体感品質から配点に基づいてスコアを算出した写しを返します
+func newScoreSnapshot(at time.Time, counters score.ScoreTable, profit uint64, quality float64) *ScoreSnapshot {
+	weights := Weights()
+	snapshot := &ScoreSnapshot{
+		At:       at,
+		Counters: counters,
+		Profit:   profit,
+		Score:    int64(profit) * weights[Profit],
+	}
+	for tag, n := range counters {
+		snapshot.Score += n * weights[tag]
+	}
+	snapshot.Score += int64(math.Round(quality * float64(weights[SessionQuality])))
+	return snapshot
+}
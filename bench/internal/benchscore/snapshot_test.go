@@ -0,0 +1,103 @@
+package benchscore
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScoreSet_Snapshot(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	set := NewScoreSet(ctx)
+	ctx = WithScoreSet(ctx, set)
+
+	snapshot := set.Snapshot()
+	assert.Equal(t, int64(0), snapshot.Get(DNSProvisioned))
+	assert.Equal(t, int64(0), snapshot.Score)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			IncDNSProvisioned(ctx)
+			AddTip(ctx, 1, "streamer-a", 100)
+			// 加算と並行して読み出しても競合しない
+			set.Snapshot()
+		}()
+	}
+	wg.Wait()
+
+	assert.Eventually(t, func() bool {
+		return set.Snapshot().Get(DNSProvisioned) == 10
+	}, time.Second, 10*time.Millisecond)
+
+	snapshot = set.Snapshot()
+	assert.Equal(t, uint64(1000), snapshot.Profit)
+	assert.Equal(t, int64(1000)*getWeight(Profit)+10*getWeight(DNSProvisioned), snapshot.Score)
+
+	// 写しは以降の加算の影響を受けない
+	IncDNSProvisioned(ctx)
+	assert.Equal(t, int64(10), snapshot.Get(DNSProvisioned))
+}
+
+func TestScoreSet_SnapshotConsistent(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	set := NewScoreSet(ctx)
+	ctx = WithScoreSet(ctx, set)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 1000; i++ {
+			IncDNSProvisioned(ctx)
+			AddTip(ctx, 1, "streamer-a", 100)
+		}
+	}()
+
+	// 加算と並行して読み出しても、スコアは写しのカウンタと売上から算出した値と一致する
+	weights := Weights()
+	for {
+		snapshot := set.Snapshot()
+		assert.Equal(t, int64(snapshot.Profit)*weights[Profit]+snapshot.Get(DNSProvisioned)*weights[DNSProvisioned], snapshot.Score)
+		// カウンタと売上は同じ時点の値で、カウンタの加算の後に売上を加算するため、売上は最大1回分だけ少ない
+		tips := int64(snapshot.Profit / 100)
+		assert.Contains(t, []int64{snapshot.Get(DNSProvisioned), snapshot.Get(DNSProvisioned) - 1}, tips)
+		select {
+		case <-done:
+			snapshot = set.Snapshot()
+			assert.Equal(t, int64(1000), snapshot.Get(DNSProvisioned))
+			assert.Equal(t, uint64(100000), snapshot.Profit)
+			return
+		default:
+		}
+	}
+}
+
+func TestWithScoreSet(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// ScoreSet が紐づいていなければ、既定の ScoreSet に集計する
+	provisioned, profit := GetByTag(DNSProvisioned), Default().TotalProfit()
+	IncDNSProvisioned(ctx)
+	AddTip(ctx, 1, "streamer-a", 100)
+	assert.Same(t, Default(), FromContext(ctx))
+	assert.Equal(t, provisioned+1, GetByTag(DNSProvisioned))
+	assert.Equal(t, profit+100, GetTotalProfit(ctx))
+
+	set := NewScoreSet(ctx)
+	ctx = WithScoreSet(ctx, set)
+	IncDNSProvisioned(ctx)
+	AddTip(ctx, 1, "streamer-a", 100)
+	assert.Same(t, set, FromContext(ctx))
+	assert.Equal(t, int64(1), set.GetByTag(DNSProvisioned))
+	assert.Equal(t, uint64(100), GetTotalProfit(ctx))
+	// 紐づけた ScoreSet の集計は、既定の ScoreSet に含めない
+	assert.Equal(t, provisioned+1, GetByTag(DNSProvisioned))
+}
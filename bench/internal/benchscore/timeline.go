@@ -0,0 +1,88 @@
+package benchscore
+
+import (
+	"sync"
+	"time"
+
+	"github.com/isucon/isucandar/score"
+)
+
+// 時間帯ごとの集計の幅
+const timelineBucketWidth = time.Second
+
+// timeBucket は、1つの時間帯に加算したカウンタ、売上、体感品質です
+type timeBucket struct {
+	counters score.ScoreTable
+	profit   uint64
+	quality  float64
+}
+
+// timeline は、ScoreSet の作成からの経過時間ごとに、加算した値を集計します
+// NOTE: スコアの凍結後も集計を続けた上で、凍結前の時間帯のみのスコアを算出するためのもの
+type timeline struct {
+	mu      sync.Mutex
+	startAt time.Time
+	buckets []*timeBucket
+}
+
+func newTimeline(startAt time.Time) *timeline {
+	return &timeline{startAt: startAt}
+}
+
+// bucket は、現在の時間帯の集計を返します. 呼び出し側でロックを取得します
+func (t *timeline) bucket() *timeBucket {
+	i := max(int(time.Since(t.startAt)/timelineBucketWidth), 0)
+	for len(t.buckets) <= i {
+		t.buckets = append(t.buckets, &timeBucket{counters: make(score.ScoreTable)})
+	}
+	return t.buckets[i]
+}
+
+func (t *timeline) add(tag score.ScoreTag) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.bucket().counters[tag]++
+}
+
+func (t *timeline) addProfit(tip uint64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.bucket().profit += tip
+}
+
+func (t *timeline) addQuality(quality float64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.bucket().quality += quality
+}
+
+// sum は、先頭から n 個の時間帯に加算した値を合計します. n が負の場合はすべての時間帯を合計します
+// NOTE: カウンタ、売上、体感品質を1度のロックで読み出すため、互いに同じ時点の値となる
+func (t *timeline) sum(n int) (counters score.ScoreTable, profit uint64, quality float64) {
+	counters = make(score.ScoreTable)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for i, b := range t.buckets {
+		if n >= 0 && i >= n {
+			break
+		}
+		for tag, count := range b.counters {
+			counters[tag] += count
+		}
+		profit += b.profit
+		quality += b.quality
+	}
+	return counters, profit, quality
+}
+
+// SnapshotBefore は、ScoreSet の作成から d が経過するまでに加算した値のみのスコアの写しを返します
+// NOTE: 時間帯の幅(1秒)単位で集計するため、d は切り捨てて扱う
+func (set *ScoreSet) SnapshotBefore(d time.Duration) *ScoreSnapshot {
+	at := set.timeline.startAt.Add(d)
+	if now := time.Now(); now.Before(at) {
+		at = now
+	}
+	counters, profit, quality := set.timeline.sum(int(d / timelineBucketWidth))
+	return newScoreSnapshot(at, counters, profit, quality)
+}
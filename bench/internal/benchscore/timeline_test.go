@@ -0,0 +1,35 @@
+package benchscore
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScoreSet_SnapshotBefore(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	set := NewScoreSet(ctx)
+
+	// 走行開始直後の加算
+	set.Add(DNSProvisioned)
+	set.AddTip(1, "streamer-a", 100)
+
+	// 走行開始から2秒以上経過した後の加算
+	set.timeline.startAt = set.timeline.startAt.Add(-2500 * time.Millisecond)
+	set.Add(DNSProvisioned)
+	set.AddTip(1, "streamer-a", 50)
+
+	before := set.SnapshotBefore(2 * time.Second)
+	assert.Equal(t, int64(1), before.Get(DNSProvisioned))
+	assert.Equal(t, uint64(100), before.Profit)
+	assert.Equal(t, int64(100)*getWeight(Profit)+getWeight(DNSProvisioned), before.Score)
+
+	// 凍結後も集計は続ける
+	all := set.SnapshotBefore(time.Hour)
+	assert.Equal(t, int64(2), all.Get(DNSProvisioned))
+	assert.Equal(t, uint64(150), all.Profit)
+	assert.Equal(t, set.TotalProfit(), all.Profit)
+}
@@ -0,0 +1,109 @@
+package benchscore
+
+import (
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/isucon/isucandar/score"
+	"gopkg.in/yaml.v3"
+)
+
+// Profit は、売上(チップ合計)の配点に用いるタグです
+const Profit score.ScoreTag = "profit"
+
+// スコアタグごとの配点
+// NOTE: 既定ではスコアは売上のみから算出するため、それ以外のカウンタの配点は0
+// --score-config で指定したファイルによって上書きされます
+var (
+	weightsMu sync.RWMutex
+	weights   = map[score.ScoreTag]int64{
+		Profit:                1,
+		DNSResolve:            0,
+		DNSFailed:             0,
+		DNSMalformed:          0,
+		DNSOversized:          0,
+		DNSDualStackMismatch:  0,
+		DNSTimeout:            0,
+		DNSServFail:           0,
+		DNSNXDomain:           0,
+		SessionQuality:        0,
+		TooSlow:               0,
+		TooManySpam:           0,
+		RaidLivecomment:       0,
+		RaidReaction:          0,
+		ModerationVerified:    0,
+		ReactionBurstAccepted: 0,
+		DNSProvisioned:        0,
+		DNSProvisioningStale:  0,
+		DNSCacheHit:           0,
+		DNSCacheMiss:          0,
+		// NOTE: 応答ごとに数えるため、TTLを短くして問い合わせを増やすほど得をしてしまう. 既定では配点しない
+		DNSSensibleTTL:   0,
+		DNSInsensibleTTL: 0,
+	}
+)
+
+// scoreConfig は、--score-config で指定するファイルの内容です
+type scoreConfig struct {
+	// シナリオ(スコアタグ名)ごとの成功率の下限
+	SLO map[string]float64 `yaml:"slo"`
+	// スコアタグごとの配点
+	Weights map[string]int64 `yaml:",inline"`
+}
+
+// LoadWeights は、スコアタグと配点の対応をYAMLまたはJSONファイルから読み込み、既定の配点を上書きします
+// slo キーにシナリオごとの成功率の下限を指定した場合は、あわせて読み込みます
+// 存在しないタグが含まれる場合はエラーを返します
+func LoadWeights(path string) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	// NOTE: JSONはYAMLとして解釈できる
+	var cfg scoreConfig
+	if err := yaml.Unmarshal(b, &cfg); err != nil {
+		return fmt.Errorf("配点ファイルの形式が不正です: %w", err)
+	}
+	for name, ratio := range cfg.SLO {
+		if ratio <= 0 || ratio > 1 {
+			return fmt.Errorf("配点ファイルの %s の成功率の下限が不正です (0より大きく1以下で指定してください): %v", name, ratio)
+		}
+	}
+
+	weightsMu.Lock()
+	defer weightsMu.Unlock()
+
+	for tag := range cfg.Weights {
+		if _, ok := weights[score.ScoreTag(tag)]; !ok {
+			return fmt.Errorf("配点ファイルに未知のスコアタグが含まれています: %s", tag)
+		}
+	}
+	for tag, weight := range cfg.Weights {
+		weights[score.ScoreTag(tag)] = weight
+	}
+	for name, ratio := range cfg.SLO {
+		slos[score.ScoreTag(name)] = ratio
+	}
+
+	return nil
+}
+
+// Weights は、現在の配点を返します
+func Weights() map[score.ScoreTag]int64 {
+	weightsMu.RLock()
+	defer weightsMu.RUnlock()
+
+	m := make(map[score.ScoreTag]int64, len(weights))
+	for tag, weight := range weights {
+		m[tag] = weight
+	}
+	return m
+}
+
+func getWeight(tag score.ScoreTag) int64 {
+	weightsMu.RLock()
+	defer weightsMu.RUnlock()
+	return weights[tag]
+}
@@ -0,0 +1,38 @@
+package benchscore
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadWeights(t *testing.T) {
+	defaults := Weights()
+	t.Cleanup(func() {
+		weightsMu.Lock()
+		defer weightsMu.Unlock()
+		weights = defaults
+	})
+
+	dir := t.TempDir()
+
+	yamlPath := filepath.Join(dir, "score.yaml")
+	assert.NoError(t, os.WriteFile(yamlPath, []byte("profit: 2\ndns-resolve: 1\n"), 0644))
+	assert.NoError(t, LoadWeights(yamlPath))
+	assert.Equal(t, int64(2), Weights()[Profit])
+	assert.Equal(t, int64(1), Weights()[DNSResolve])
+	assert.Equal(t, int64(0), Weights()[DNSFailed])
+
+	jsonPath := filepath.Join(dir, "score.json")
+	assert.NoError(t, os.WriteFile(jsonPath, []byte(`{"session-quality": 10}`), 0644))
+	assert.NoError(t, LoadWeights(jsonPath))
+	assert.Equal(t, int64(10), Weights()[SessionQuality])
+	assert.Equal(t, int64(2), Weights()[Profit])
+
+	unknownPath := filepath.Join(dir, "unknown.yaml")
+	assert.NoError(t, os.WriteFile(unknownPath, []byte("unknown-tag: 1\n"), 0644))
+	assert.Error(t, LoadWeights(unknownPath))
+	assert.Equal(t, int64(2), Weights()[Profit])
+}
@@ -0,0 +1,114 @@
+package benchtrace
+
+import (
+	"crypto/tls"
+	"net/http/httptrace"
+	"strings"
+	"sync"
+)
+
+// H2Event は、HTTP/2で観測した接続の入れ替わりに関する事象です
+// NOTE: GOAWAYを受けたリクエストの多くはnet/httpが透過的に再送するため、エラーだけでなく再送と接続の確立も数える
+type H2Event string
+
+const (
+	// GOAWAYを受け、リクエストが失敗した
+	H2GoAway H2Event = "goaway"
+	// RST_STREAMでストリームがリセットされた
+	H2StreamReset H2Event = "stream-reset"
+	// リクエスト中に接続が失われた
+	H2ConnectionLost H2Event = "connection-lost"
+	// GOAWAYや接続の切断により、別の接続で再送された
+	H2Retried H2Event = "retried"
+	// HTTP/2の接続を新たに確立した
+	H2NewConnection H2Event = "new-connection"
+)
+
+var h2Events = []H2Event{
+	H2GoAway,
+	H2StreamReset,
+	H2ConnectionLost,
+	H2Retried,
+	H2NewConnection,
+}
+
+// NOTE: net/httpに同梱されたHTTP/2実装のエラー型は非公開なので、メッセージで分類する
+var h2ErrorMarkers = []struct {
+	marker string
+	event  H2Event
+}{
+	{"GOAWAY", H2GoAway},
+	{"stream error", H2StreamReset},
+	{"RST_STREAM", H2StreamReset},
+	{"http2: client connection lost", H2ConnectionLost},
+	{"http2: client connection force closed", H2ConnectionLost},
+}
+
+var (
+	h2Mu     sync.Mutex
+	h2Counts = map[H2Event]int64{}
+)
+
+func initH2Stats() {
+	h2Mu.Lock()
+	defer h2Mu.Unlock()
+	h2Counts = map[H2Event]int64{}
+}
+
+func recordH2(event H2Event) {
+	h2Mu.Lock()
+	defer h2Mu.Unlock()
+	h2Counts[event]++
+}
+
+// H2Stats は、HTTP/2で観測した事象ごとの件数を返します
+func H2Stats() map[H2Event]int64 {
+	h2Mu.Lock()
+	defer h2Mu.Unlock()
+
+	result := make(map[H2Event]int64, len(h2Events))
+	for _, event := range h2Events {
+		result[event] = h2Counts[event]
+	}
+	return result
+}
+
+// H2Events は、H2Stats に含まれる事象を表示順に返します
+func H2Events() []H2Event {
+	return h2Events
+}
+
+// ObserveError は、リクエストのエラーがHTTP/2の接続の入れ替わりによるものであれば記録します
+func ObserveError(err error) {
+	if err == nil {
+		return
+	}
+
+	msg := err.Error()
+	for _, m := range h2ErrorMarkers {
+		if strings.Contains(msg, m.marker) {
+			recordH2(m.event)
+			return
+		}
+	}
+}
+
+func isH2(state tls.ConnectionState) bool {
+	return state.NegotiatedProtocol == "h2"
+}
+
+func (t *requestTrace) gotConn(info httptrace.GotConnInfo) {
+	recordConn(info)
+
+	conn, ok := info.Conn.(*tls.Conn)
+	if !ok || !isH2(conn.ConnectionState()) {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.h2Conns++
+	if t.h2Conns > 1 {
+		recordH2(H2Retried)
+	}
+}
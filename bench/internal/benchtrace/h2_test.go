@@ -0,0 +1,54 @@
+package benchtrace
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestH2Stats_NewConnection(t *testing.T) {
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, r.Proto)
+	}))
+	ts.EnableHTTP2 = true
+	ts.StartTLS()
+	defer ts.Close()
+
+	InitTrace(0)
+
+	client := ts.Client()
+	for i := 0; i < 3; i++ {
+		req, err := http.NewRequestWithContext(WithClientTrace(context.Background()), http.MethodGet, ts.URL, nil)
+		assert.NoError(t, err)
+		resp, err := client.Do(req)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, resp.ProtoMajor)
+		resp.Body.Close()
+	}
+
+	// 1つの接続を使い回す
+	stats := H2Stats()
+	assert.Equal(t, int64(1), stats[H2NewConnection])
+	assert.Equal(t, int64(0), stats[H2Retried])
+}
+
+func TestObserveError(t *testing.T) {
+	InitTrace(0)
+
+	ObserveError(errors.New("http2: server sent GOAWAY and closed the connection; LastStreamID=1, ErrCode=NO_ERROR, debug=\"\""))
+	ObserveError(errors.New("stream error: stream ID 3; INTERNAL_ERROR; received from peer"))
+	ObserveError(errors.New("http2: client connection lost"))
+	ObserveError(errors.New("dial tcp: connection refused"))
+	ObserveError(nil)
+
+	stats := H2Stats()
+	assert.Equal(t, int64(1), stats[H2GoAway])
+	assert.Equal(t, int64(1), stats[H2StreamReset])
+	assert.Equal(t, int64(1), stats[H2ConnectionLost])
+	assert.Equal(t, int64(0), stats[H2Retried])
+}
@@ -0,0 +1,96 @@
+package benchtrace
+
+import (
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// KeepAliveReport は、接続の再利用 (keep-alive) に関する計測結果です
+type KeepAliveReport struct {
+	// 接続を得たリクエスト数
+	Requests int64 `json:"requests"`
+	// 確立済みの接続を再利用したリクエスト数
+	ReusedConns int64 `json:"reused_conns"`
+	// 新たに接続を確立したリクエスト数
+	NewConns int64 `json:"new_conns"`
+	// webappが Connection: close を返し、接続を閉じたレスポンス数
+	ForcedCloses int64 `json:"forced_closes"`
+	// 接続の確立 (TCPとTLSのハンドシェイク) に要した時間の合計
+	HandshakeOverhead time.Duration `json:"handshake_overhead"`
+}
+
+// ReuseRatio は、接続を再利用したリクエストの割合を返します
+func (r *KeepAliveReport) ReuseRatio() float64 {
+	if r.Requests == 0 {
+		return 0
+	}
+	return float64(r.ReusedConns) / float64(r.Requests)
+}
+
+// ForcedCloseRatio は、webappが接続を閉じたレスポンスの割合を返します
+func (r *KeepAliveReport) ForcedCloseRatio() float64 {
+	if r.Requests == 0 {
+		return 0
+	}
+	return float64(r.ForcedCloses) / float64(r.Requests)
+}
+
+// HandshakeOverheadPerRequest は、接続の確立に要した時間をリクエストあたりに均して返します
+func (r *KeepAliveReport) HandshakeOverheadPerRequest() time.Duration {
+	if r.Requests == 0 {
+		return 0
+	}
+	return r.HandshakeOverhead / time.Duration(r.Requests)
+}
+
+var (
+	keepAliveMu sync.Mutex
+	keepAlive   = KeepAliveReport{}
+)
+
+func initKeepAliveStats() {
+	keepAliveMu.Lock()
+	defer keepAliveMu.Unlock()
+	keepAlive = KeepAliveReport{}
+}
+
+// CurrentKeepAliveReport は、接続の再利用に関する計測結果を返します. リクエストがなければnilを返します
+func CurrentKeepAliveReport() *KeepAliveReport {
+	keepAliveMu.Lock()
+	defer keepAliveMu.Unlock()
+	if keepAlive.Requests == 0 {
+		return nil
+	}
+	report := keepAlive
+	return &report
+}
+
+// ObserveResponse は、webappが Connection: close によって接続を閉じたかを記録します
+// NOTE: HTTP/1.0で keep-alive を指定しなかった場合も、net/httpは接続を閉じるものとして扱う
+func ObserveResponse(resp *http.Response) {
+	if resp == nil || !resp.Close {
+		return
+	}
+	keepAliveMu.Lock()
+	defer keepAliveMu.Unlock()
+	keepAlive.ForcedCloses++
+}
+
+func recordConn(info httptrace.GotConnInfo) {
+	keepAliveMu.Lock()
+	defer keepAliveMu.Unlock()
+	keepAlive.Requests++
+	if info.Reused {
+		keepAlive.ReusedConns++
+	} else {
+		keepAlive.NewConns++
+	}
+}
+
+func recordHandshake(d time.Duration) {
+	keepAliveMu.Lock()
+	defer keepAliveMu.Unlock()
+	keepAlive.HandshakeOverhead += d
+}
@@ -0,0 +1,47 @@
+package benchtrace
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeepAliveReport(t *testing.T) {
+	keepAliveServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer keepAliveServer.Close()
+	closeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Connection", "close")
+	}))
+	defer closeServer.Close()
+
+	InitTrace(0)
+	assert.Nil(t, CurrentKeepAliveReport())
+
+	client := &http.Client{Transport: &http.Transport{}}
+	for _, url := range []string{keepAliveServer.URL, keepAliveServer.URL, keepAliveServer.URL, closeServer.URL, closeServer.URL} {
+		req, err := http.NewRequestWithContext(WithClientTrace(context.Background()), http.MethodGet, url, nil)
+		assert.NoError(t, err)
+		resp, err := client.Do(req)
+		assert.NoError(t, err)
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		ObserveResponse(resp)
+	}
+
+	report := CurrentKeepAliveReport()
+	if assert.NotNil(t, report) {
+		assert.Equal(t, int64(5), report.Requests)
+		// keep-aliveのサーバには1回、接続を閉じるサーバにはリクエストのたびに接続する
+		assert.Equal(t, int64(3), report.NewConns)
+		assert.Equal(t, int64(2), report.ReusedConns)
+		assert.Equal(t, int64(2), report.ForcedCloses)
+		assert.InDelta(t, 0.4, report.ReuseRatio(), 0.001)
+		assert.InDelta(t, 0.4, report.ForcedCloseRatio(), 0.001)
+		assert.Greater(t, report.HandshakeOverhead, time.Duration(0))
+	}
+}
@@ -0,0 +1,34 @@
+package benchtrace
+
+import (
+	"context"
+
+	"golang.org/x/sync/semaphore"
+)
+
+// NOTE: SSL有効時、走行開始直後にTLSハンドシェイクが殺到するとベンチマーカーのCPUを使い切り、
+// 計測されるレイテンシが歪むため、同時に行うハンドシェイク数を制限する
+var tlsHandshakeSem *semaphore.Weighted
+
+func initTLSHandshakeLimiter(maxTLSHandshakes int) {
+	if maxTLSHandshakes <= 0 {
+		tlsHandshakeSem = nil
+		return
+	}
+	tlsHandshakeSem = semaphore.NewWeighted(int64(maxTLSHandshakes))
+}
+
+// acquireTLSHandshake は、ハンドシェイクの実行枠を確保し、解放用の関数を返します
+// NOTE: 制限なしの場合や、待機中にリクエストがキャンセルされた場合はnilを返し、待たずにハンドシェイクさせます
+func acquireTLSHandshake(ctx context.Context) func() {
+	sem := tlsHandshakeSem
+	if sem == nil {
+		return nil
+	}
+	if err := sem.Acquire(ctx, 1); err != nil {
+		return nil
+	}
+	return func() {
+		sem.Release(1)
+	}
+}
@@ -0,0 +1,202 @@
+package benchtrace
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// Phase は、httptraceで計測するHTTPリクエストの段階です
+type Phase string
+
+const (
+	PhaseDNS     Phase = "dns"
+	PhaseConnect Phase = "connect"
+	// TLSハンドシェイク同時実行数の制限による待ち時間
+	PhaseTLSHandshakeWait Phase = "tls-handshake-wait"
+	PhaseTLSHandshake     Phase = "tls-handshake"
+	// リクエスト書き込み完了からレスポンスの最初のバイトを受け取るまで
+	PhaseFirstByte Phase = "first-byte"
+)
+
+var phases = []Phase{
+	PhaseDNS,
+	PhaseConnect,
+	PhaseTLSHandshakeWait,
+	PhaseTLSHandshake,
+	PhaseFirstByte,
+}
+
+type PhaseStat struct {
+	Phase Phase
+	Count int64
+	Total time.Duration
+	Max   time.Duration
+}
+
+func (s PhaseStat) Mean() time.Duration {
+	if s.Count == 0 {
+		return 0
+	}
+	return s.Total / time.Duration(s.Count)
+}
+
+var (
+	statsMu sync.Mutex
+	stats   = map[Phase]*PhaseStat{}
+)
+
+// InitTrace は、計測結果を初期化し、TLSハンドシェイク同時実行数の上限を設定します
+// NOTE: maxTLSHandshakesが0以下の場合、制限しません
+func InitTrace(maxTLSHandshakes int) {
+	statsMu.Lock()
+	stats = map[Phase]*PhaseStat{}
+	statsMu.Unlock()
+	initH2Stats()
+	initKeepAliveStats()
+
+	initTLSHandshakeLimiter(maxTLSHandshakes)
+}
+
+// Stats は、段階ごとの計測結果を返します
+func Stats() []PhaseStat {
+	statsMu.Lock()
+	defer statsMu.Unlock()
+
+	result := make([]PhaseStat, len(phases))
+	for i, phase := range phases {
+		if stat, ok := stats[phase]; ok {
+			result[i] = *stat
+		} else {
+			result[i] = PhaseStat{Phase: phase}
+		}
+	}
+	return result
+}
+
+func record(phase Phase, d time.Duration) {
+	statsMu.Lock()
+	defer statsMu.Unlock()
+
+	stat, ok := stats[phase]
+	if !ok {
+		stat = &PhaseStat{Phase: phase}
+		stats[phase] = stat
+	}
+	stat.Count++
+	stat.Total += d
+	if d > stat.Max {
+		stat.Max = d
+	}
+}
+
+// requestTrace は、1リクエスト分の計測状態です
+// NOTE: コールバックはダイアル用のgoroutineから呼ばれることがあるので、排他制御します
+type requestTrace struct {
+	ctx context.Context
+
+	mu                sync.Mutex
+	dnsStartAt        time.Time
+	connectStartAt    map[string]time.Time
+	tlsStartAt        time.Time
+	wroteRequestAt    time.Time
+	releaseHandshakes []func()
+	// HTTP/2の接続を得た回数 (2回目以降は再送)
+	h2Conns int
+}
+
+// WithClientTrace は、段階ごとの所要時間を計測し、TLSハンドシェイクの同時実行数を制限するhttptraceをctxに設定します
+func WithClientTrace(ctx context.Context) context.Context {
+	t := &requestTrace{
+		ctx:            ctx,
+		connectStartAt: make(map[string]time.Time),
+	}
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		DNSStart:             t.dnsStart,
+		DNSDone:              t.dnsDone,
+		ConnectStart:         t.connectStart,
+		ConnectDone:          t.connectDone,
+		TLSHandshakeStart:    t.tlsHandshakeStart,
+		TLSHandshakeDone:     t.tlsHandshakeDone,
+		GotConn:              t.gotConn,
+		WroteRequest:         t.wroteRequest,
+		GotFirstResponseByte: t.gotFirstResponseByte,
+	})
+}
+
+func (t *requestTrace) dnsStart(httptrace.DNSStartInfo) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.dnsStartAt = time.Now()
+}
+
+func (t *requestTrace) dnsDone(httptrace.DNSDoneInfo) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.dnsStartAt.IsZero() {
+		record(PhaseDNS, time.Since(t.dnsStartAt))
+	}
+}
+
+func (t *requestTrace) connectStart(network, addr string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.connectStartAt[network+addr] = time.Now()
+}
+
+func (t *requestTrace) connectDone(network, addr string, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if startAt, ok := t.connectStartAt[network+addr]; ok {
+		record(PhaseConnect, time.Since(startAt))
+		recordHandshake(time.Since(startAt))
+		delete(t.connectStartAt, network+addr)
+	}
+}
+
+func (t *requestTrace) tlsHandshakeStart() {
+	// NOTE: ハンドシェイクはこのコールバックが戻ってから開始されるため、ここで待つことで同時実行数を制限できる
+	waitStartAt := time.Now()
+	release := acquireTLSHandshake(t.ctx)
+	record(PhaseTLSHandshakeWait, time.Since(waitStartAt))
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if release != nil {
+		t.releaseHandshakes = append(t.releaseHandshakes, release)
+	}
+	t.tlsStartAt = time.Now()
+}
+
+func (t *requestTrace) tlsHandshakeDone(state tls.ConnectionState, err error) {
+	if err == nil && isH2(state) {
+		recordH2(H2NewConnection)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if n := len(t.releaseHandshakes); n > 0 {
+		t.releaseHandshakes[n-1]()
+		t.releaseHandshakes = t.releaseHandshakes[:n-1]
+	}
+	if !t.tlsStartAt.IsZero() {
+		record(PhaseTLSHandshake, time.Since(t.tlsStartAt))
+		recordHandshake(time.Since(t.tlsStartAt))
+	}
+}
+
+func (t *requestTrace) wroteRequest(httptrace.WroteRequestInfo) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.wroteRequestAt = time.Now()
+}
+
+func (t *requestTrace) gotFirstResponseByte() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.wroteRequestAt.IsZero() {
+		record(PhaseFirstByte, time.Since(t.wroteRequestAt))
+	}
+}
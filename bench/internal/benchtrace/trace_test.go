@@ -0,0 +1,52 @@
+package benchtrace
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTLSHandshakeLimiter(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "ok")
+	}))
+	defer ts.Close()
+
+	InitTrace(1)
+	defer InitTrace(0)
+
+	// 実行枠を埋めておき、ハンドシェイクが待たされることを確認する
+	ctx := context.Background()
+	release := acquireTLSHandshake(ctx)
+	assert.NotNil(t, release)
+
+	const holdDuration = 100 * time.Millisecond
+	go func() {
+		time.Sleep(holdDuration)
+		release()
+	}()
+
+	req, err := http.NewRequestWithContext(WithClientTrace(ctx), http.MethodGet, ts.URL, nil)
+	assert.NoError(t, err)
+	resp, err := ts.Client().Do(req)
+	assert.NoError(t, err)
+	resp.Body.Close()
+
+	stats := map[Phase]PhaseStat{}
+	for _, stat := range Stats() {
+		stats[stat.Phase] = stat
+	}
+	assert.Equal(t, int64(1), stats[PhaseTLSHandshake].Count)
+	assert.Equal(t, int64(1), stats[PhaseFirstByte].Count)
+	assert.GreaterOrEqual(t, stats[PhaseTLSHandshakeWait].Max, holdDuration/2)
+
+	// ハンドシェイク完了後に実行枠が解放されていること
+	release = acquireTLSHandshake(ctx)
+	assert.NotNil(t, release)
+	release()
+}
@@ -0,0 +1,156 @@
+// calibrate は、ベンチマーカーを動かすホストの性能を計測し、スコアの補正係数を求めます
+//
+// 選手の手元など性能の異なる環境で得たスコアを比較できるよう、
+// 本番のベンチマーカーのホストに対する相対性能を補正係数として結果に記録します
+package calibrate
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/isucon/isucon13/bench/internal/config"
+)
+
+// httpConcurrency は、HTTPスループット計測の並列数です
+const httpConcurrency = 8
+
+type Result struct {
+	// ハッシュ計算とJSONエンコードを合わせた処理の毎秒処理数
+	CPUOpsPerSec float64 `json:"cpu_ops_per_sec"`
+	// ループバックでのHTTPリクエストの毎秒処理数
+	HTTPRequestsPerSec float64 `json:"http_requests_per_sec"`
+	// スコアに乗じることで、本番のベンチマーカーのホスト相当に補正する係数
+	NormalizationFactor float64 `json:"normalization_factor"`
+}
+
+func (r *Result) String() string {
+	return fmt.Sprintf("CPU: %.0f ops/s, HTTP: %.0f req/s, 補正係数: %.3f", r.CPUOpsPerSec, r.HTTPRequestsPerSec, r.NormalizationFactor)
+}
+
+// Run は、ホストの性能を計測します
+// NOTE: 負荷走行と干渉しないよう、走行前に実行してください
+func Run(ctx context.Context, d time.Duration) (*Result, error) {
+	cpu, err := measureCPU(ctx, d)
+	if err != nil {
+		return nil, err
+	}
+	httpRate, err := measureHTTP(ctx, d)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Result{
+		CPUOpsPerSec:        cpu,
+		HTTPRequestsPerSec:  httpRate,
+		NormalizationFactor: normalizationFactor(cpu, httpRate),
+	}, nil
+}
+
+// normalizationFactor は、基準値に対する性能比の幾何平均の逆数を補正係数とします
+func normalizationFactor(cpu, httpRate float64) float64 {
+	if cpu <= 0 || httpRate <= 0 {
+		return config.MaxNormalizationFactor
+	}
+
+	factor := math.Sqrt((config.ReferenceCPUOpsPerSec / cpu) * (config.ReferenceHTTPRequestsPerSec / httpRate))
+	return math.Max(config.MinNormalizationFactor, math.Min(config.MaxNormalizationFactor, factor))
+}
+
+type payload struct {
+	ID      int64    `json:"id"`
+	Name    string   `json:"name"`
+	Tags    []string `json:"tags"`
+	Comment string   `json:"comment"`
+}
+
+func measureCPU(ctx context.Context, d time.Duration) (float64, error) {
+	ctx, cancel := context.WithTimeout(ctx, d)
+	defer cancel()
+
+	var (
+		ops     atomic.Int64
+		wg      sync.WaitGroup
+		startAt = time.Now()
+	)
+	for i := 0; i < runtime.GOMAXPROCS(0); i++ {
+		wg.Add(1)
+		go func(id int64) {
+			defer wg.Done()
+
+			p := &payload{ID: id, Name: "isupipe", Tags: []string{"椅子", "ライブ配信"}, Comment: "こんにちは"}
+			for ctx.Err() == nil {
+				b, err := json.Marshal(p)
+				if err != nil {
+					return
+				}
+				sum := sha256.Sum256(b)
+				p.ID = int64(sum[0])
+				ops.Add(1)
+			}
+		}(int64(i))
+	}
+	wg.Wait()
+
+	return float64(ops.Load()) / time.Since(startAt).Seconds(), nil
+}
+
+func measureHTTP(ctx context.Context, d time.Duration) (float64, error) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"id":1,"name":"isupipe"}`)
+	}))
+	defer server.Close()
+
+	transport := &http.Transport{MaxIdleConnsPerHost: httpConcurrency}
+	defer transport.CloseIdleConnections()
+	client := &http.Client{Transport: transport}
+
+	ctx, cancel := context.WithTimeout(ctx, d)
+	defer cancel()
+
+	var (
+		requests atomic.Int64
+		failures atomic.Int64
+		wg       sync.WaitGroup
+		startAt  = time.Now()
+	)
+	for i := 0; i < httpConcurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+				if err != nil {
+					failures.Add(1)
+					return
+				}
+				resp, err := client.Do(req)
+				if err != nil {
+					if ctx.Err() == nil {
+						failures.Add(1)
+					}
+					continue
+				}
+				io.Copy(io.Discard, resp.Body)
+				resp.Body.Close()
+				requests.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if requests.Load() == 0 {
+		return 0, fmt.Errorf("ループバックでのHTTPリクエストが1件も成功しませんでした (失敗: %d件)", failures.Load())
+	}
+	return float64(requests.Load()) / time.Since(startAt).Seconds(), nil
+}
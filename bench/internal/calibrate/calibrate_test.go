@@ -0,0 +1,30 @@
+package calibrate
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/isucon/isucon13/bench/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRun(t *testing.T) {
+	result, err := Run(context.Background(), 100*time.Millisecond)
+	assert.NoError(t, err)
+	assert.Greater(t, result.CPUOpsPerSec, 0.0)
+	assert.Greater(t, result.HTTPRequestsPerSec, 0.0)
+	assert.GreaterOrEqual(t, result.NormalizationFactor, config.MinNormalizationFactor)
+	assert.LessOrEqual(t, result.NormalizationFactor, config.MaxNormalizationFactor)
+}
+
+func TestNormalizationFactor(t *testing.T) {
+	// 基準と同等の性能なら補正しない
+	assert.InDelta(t, 1.0, normalizationFactor(config.ReferenceCPUOpsPerSec, config.ReferenceHTTPRequestsPerSec), 1e-9)
+	// 半分の性能なら2倍に補正する
+	assert.InDelta(t, 2.0, normalizationFactor(config.ReferenceCPUOpsPerSec/2, config.ReferenceHTTPRequestsPerSec/2), 1e-9)
+	// 極端な性能差は範囲内に丸める
+	assert.Equal(t, config.MaxNormalizationFactor, normalizationFactor(1, 1))
+	assert.Equal(t, config.MinNormalizationFactor, normalizationFactor(config.ReferenceCPUOpsPerSec*100, config.ReferenceHTTPRequestsPerSec*100))
+	assert.Equal(t, config.MaxNormalizationFactor, normalizationFactor(0, 0))
+}
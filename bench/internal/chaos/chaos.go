@@ -0,0 +1,127 @@
+// chaos は、ベンチマーカー側でリクエストに人為的な遅延と接続の切断を注入します
+//
+// 劣化したネットワークの下でも採点とエラーの分類が妥当に振る舞うかを、競技前に運営が確かめるための開発者向けの機能です.
+// 注入は Start から Stop までの間 (負荷走行中) のみ行います
+package chaos
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrDropped は、注入によって切断したリクエストのエラーです
+var ErrDropped = errors.New("chaosモードにより接続を切断しました")
+
+// Config は、注入する遅延と切断の割合です
+type Config struct {
+	// 遅延を注入するリクエストの割合
+	DelayRate float64
+	// 注入する遅延の最大値. 0からこの値までの一様分布に従います
+	MaxDelay time.Duration
+	// 送信前に接続を切断するリクエストの割合
+	DropRate float64
+}
+
+// Report は、負荷走行中に注入した遅延と切断の集計です. 結果に記録します
+type Report struct {
+	Requests int64 `json:"requests"`
+	Delayed  int64 `json:"delayed"`
+	Dropped  int64 `json:"dropped"`
+	// 注入した遅延の合計[ms]
+	TotalDelayMillis int64 `json:"total_delay_ms"`
+}
+
+var (
+	mu      sync.Mutex
+	rnd     *rand.Rand
+	cfg     Config
+	started bool
+	active  atomic.Bool
+
+	requests   atomic.Int64
+	delayed    atomic.Int64
+	dropped    atomic.Int64
+	totalDelay atomic.Int64
+)
+
+// Start は、集計を破棄して注入を開始します
+// NOTE: シナリオの乱数源の系列を変えないよう、注入の判定には専用の乱数源を用いる
+func Start(c Config, seed int64) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	cfg = c
+	rnd = rand.New(rand.NewSource(seed))
+	started = true
+	requests.Store(0)
+	delayed.Store(0)
+	dropped.Store(0)
+	totalDelay.Store(0)
+	active.Store(true)
+}
+
+// Stop は、注入を終了します. 集計は CurrentReport で参照できます
+func Stop() {
+	active.Store(false)
+}
+
+// Active は、注入中であるかを返します
+func Active() bool {
+	return active.Load()
+}
+
+// Inject は、リクエストの送信前に呼び出し、抽選に当たれば遅延を注入します
+// 切断に当たった場合は ErrDropped を、遅延中にctxが終了した場合はctxのエラーを返します
+func Inject(ctx context.Context) error {
+	if !active.Load() {
+		return nil
+	}
+	requests.Add(1)
+
+	mu.Lock()
+	drop := rnd.Float64() < cfg.DropRate
+	var delay time.Duration
+	if !drop && cfg.MaxDelay > 0 && rnd.Float64() < cfg.DelayRate {
+		delay = time.Duration(rnd.Int63n(int64(cfg.MaxDelay)))
+	}
+	mu.Unlock()
+
+	if drop {
+		dropped.Add(1)
+		return ErrDropped
+	}
+	if delay <= 0 {
+		return nil
+	}
+
+	delayed.Add(1)
+	totalDelay.Add(int64(delay))
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// CurrentReport は、注入の集計を返します. 注入を開始していなければnilを返します
+func CurrentReport() *Report {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if !started {
+		return nil
+	}
+	return &Report{
+		Requests:         requests.Load(),
+		Delayed:          delayed.Load(),
+		Dropped:          dropped.Load(),
+		TotalDelayMillis: time.Duration(totalDelay.Load()).Milliseconds(),
+	}
+}
@@ -0,0 +1,41 @@
+package chaos
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInject(t *testing.T) {
+	ctx := context.Background()
+	assert.Nil(t, CurrentReport())
+	// NOTE: 開始前は注入しない
+	assert.NoError(t, Inject(ctx))
+
+	Start(Config{DropRate: 1}, 1)
+	assert.ErrorIs(t, Inject(ctx), ErrDropped)
+	assert.ErrorIs(t, Inject(ctx), ErrDropped)
+
+	Start(Config{DelayRate: 1, MaxDelay: 10 * time.Millisecond}, 1)
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, Inject(ctx))
+	}
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	Start(Config{DelayRate: 1, MaxDelay: time.Hour}, 1)
+	assert.ErrorIs(t, Inject(canceled), context.Canceled)
+
+	Stop()
+	assert.False(t, Active())
+	assert.NoError(t, Inject(ctx))
+
+	report := CurrentReport()
+	if assert.NotNil(t, report) {
+		// NOTE: 集計は最後に開始してからのもの
+		assert.Equal(t, int64(1), report.Requests)
+		assert.Equal(t, int64(1), report.Delayed)
+		assert.Equal(t, int64(0), report.Dropped)
+	}
+}
@@ -0,0 +1,134 @@
+package churn
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/isucon/isucon13/bench/internal/config"
+)
+
+// DepartureReason は、視聴者が配信を最後まで視聴せずに離脱した理由です
+type DepartureReason string
+
+const (
+	DepartureTooSlow     DepartureReason = "too-slow"
+	DepartureTooManySpam DepartureReason = "too-many-spam"
+)
+
+// ViewerChurn は、視聴者の到着・視聴時間・離脱を決める
+// NOTE: Init を呼ぶまでは、従来通り間隔を設けずに到着し、最後まで視聴し、離脱しない
+var ViewerChurn = &viewerChurn{
+	arrival:    ConstantDistribution{Value: 0},
+	session:    ConstantDistribution{Value: 1},
+	departures: make(map[DepartureReason]int64),
+}
+
+type viewerChurn struct {
+	arrival Distribution
+	session Distribution
+	// 遅延やスパムによって離脱するか
+	depart bool
+
+	arrivalMu     sync.Mutex
+	nextArrivalAt time.Time
+
+	departuresMu sync.Mutex
+	departures   map[DepartureReason]int64
+}
+
+// Init は、configで指定された分布で視聴者の振る舞いを初期化します
+func Init() error {
+	if config.ViewerAbandonWindow < 1 {
+		return fmt.Errorf("遅延離脱を判断するリクエスト数(--viewer-abandon-window)は1以上を指定してください: %d", config.ViewerAbandonWindow)
+	}
+	arrival, err := ParseDistribution(config.ViewerArrivalDistribution)
+	if err != nil {
+		return err
+	}
+	session, err := ParseDistribution(config.ViewerSessionDistribution)
+	if err != nil {
+		return err
+	}
+
+	ViewerChurn = &viewerChurn{
+		arrival:    arrival,
+		session:    session,
+		depart:     config.ViewerDepartures,
+		departures: make(map[DepartureReason]int64),
+	}
+	return nil
+}
+
+func (c *viewerChurn) ArrivalDistribution() Distribution {
+	return c.arrival
+}
+
+func (c *viewerChurn) SessionDistribution() Distribution {
+	return c.session
+}
+
+// TryArrive は、到着間隔の分布に従って次の視聴者が到着する時刻になっていれば、acquire で視聴者の枠を確保し、確保できたかを返します
+// NOTE: 到着を待つ間は枠を占有しないよう、到着してから枠を確保する. 枠が空くまでの間、到着は持ち越す
+// NOTE: 到着時刻は全視聴者で通しで決めるため、並列に呼び出しても到着間隔は分布に従う
+func (c *viewerChurn) TryArrive(acquire func() bool) bool {
+	c.arrivalMu.Lock()
+	defer c.arrivalMu.Unlock()
+
+	now := time.Now()
+	if now.Before(c.nextArrivalAt) {
+		return false
+	}
+	if !acquire() {
+		return false
+	}
+	if interval := time.Duration(c.arrival.Sample() * float64(time.Second)); interval > 0 {
+		c.nextArrivalAt = now.Add(interval)
+	}
+	return true
+}
+
+// SessionHours は、視聴時間の分布に従って、配信のうち何時間分視聴するかを返します
+// NOTE: 分布の値は配信時間に対する視聴時間の割合で、少なくとも1時間は視聴する
+func (c *viewerChurn) SessionHours(livestreamHours int) int {
+	hours := int(math.Ceil(c.session.Sample() * float64(livestreamHours)))
+	return min(max(hours, 1), livestreamHours)
+}
+
+// ShouldDepart は、視聴者が体験した遅延・スパムの割合が閾値に達していれば、離脱理由を返します
+// slowness は、直近のリクエストの所要時間の、遅延の閾値に対する比のパーセンタイルです (benchscore.Session.RecentSlowness)
+// 離脱が有効でない場合は、常に離脱しません
+func (c *viewerChurn) ShouldDepart(slowness, spamPercentage float64) (DepartureReason, bool) {
+	if !c.depart {
+		return "", false
+	}
+
+	var reason DepartureReason
+	switch {
+	case slowness >= 1:
+		reason = DepartureTooSlow
+	case spamPercentage >= config.TooManySpamThresholdPercentage:
+		reason = DepartureTooManySpam
+	default:
+		return "", false
+	}
+
+	c.departuresMu.Lock()
+	defer c.departuresMu.Unlock()
+	c.departures[reason]++
+
+	return reason, true
+}
+
+// Departures は、離脱理由ごとの離脱した視聴者数を返します
+func (c *viewerChurn) Departures() map[DepartureReason]int64 {
+	c.departuresMu.Lock()
+	defer c.departuresMu.Unlock()
+
+	m := make(map[DepartureReason]int64, len(c.departures))
+	for reason, n := range c.departures {
+		m[reason] = n
+	}
+	return m
+}
@@ -0,0 +1,71 @@
+package churn
+
+import (
+	"testing"
+	"time"
+
+	"github.com/isucon/isucon13/bench/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDistribution(t *testing.T) {
+	d, err := ParseDistribution("constant:0.5")
+	assert.NoError(t, err)
+	assert.Equal(t, 0.5, d.Sample())
+
+	d, err = ParseDistribution("uniform:1-2")
+	assert.NoError(t, err)
+	for i := 0; i < 100; i++ {
+		v := d.Sample()
+		assert.GreaterOrEqual(t, v, 1.0)
+		assert.Less(t, v, 2.0)
+	}
+
+	d, err = ParseDistribution("exponential:0.1")
+	assert.NoError(t, err)
+	assert.GreaterOrEqual(t, d.Sample(), 0.0)
+
+	for _, spec := range []string{"constant", "constant:-1", "uniform:2-1", "uniform:1", "normal:1"} {
+		_, err := ParseDistribution(spec)
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestViewerChurn(t *testing.T) {
+	c := &viewerChurn{
+		arrival:    ConstantDistribution{Value: 0.05},
+		session:    ConstantDistribution{Value: 0.5},
+		departures: make(map[DepartureReason]int64),
+	}
+
+	assert.Equal(t, 2, c.SessionHours(4))
+	assert.Equal(t, 1, c.SessionHours(1))
+
+	// 続けて到着しても、到着間隔は分布に従う
+	acquire := func() bool { return true }
+	assert.True(t, c.TryArrive(acquire))
+	assert.False(t, c.TryArrive(acquire))
+	time.Sleep(60 * time.Millisecond)
+
+	// 枠を確保できなければ、到着は持ち越す
+	assert.False(t, c.TryArrive(func() bool { return false }))
+	assert.True(t, c.TryArrive(acquire))
+	assert.False(t, c.TryArrive(acquire))
+
+	// 離脱が有効でなければ離脱しない
+	_, ok := c.ShouldDepart(1, config.TooManySpamThresholdPercentage)
+	assert.False(t, ok)
+
+	c.depart = true
+	_, ok = c.ShouldDepart(0, 0)
+	assert.False(t, ok)
+	_, ok = c.ShouldDepart(0.99, 0)
+	assert.False(t, ok)
+	reason, ok := c.ShouldDepart(1, 0)
+	assert.True(t, ok)
+	assert.Equal(t, DepartureTooSlow, reason)
+	reason, ok = c.ShouldDepart(0, config.TooManySpamThresholdPercentage)
+	assert.True(t, ok)
+	assert.Equal(t, DepartureTooManySpam, reason)
+	assert.Equal(t, map[DepartureReason]int64{DepartureTooSlow: 1, DepartureTooManySpam: 1}, c.Departures())
+}
@@ -0,0 +1,115 @@
+package churn
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/isucon/isucon13/bench/internal/benchrand"
+)
+
+// Distribution は、視聴者の振る舞いを決める値を標本として返す確率分布です
+type Distribution interface {
+	Sample() float64
+	String() string
+}
+
+const (
+	DistributionConstant    = "constant"
+	DistributionExponential = "exponential"
+	DistributionUniform     = "uniform"
+)
+
+// ParseDistribution は、"constant:1", "exponential:0.05", "uniform:0.5-1" の形式で指定された分布を返します
+func ParseDistribution(spec string) (Distribution, error) {
+	name, params, ok := strings.Cut(spec, ":")
+	if !ok {
+		return nil, fmt.Errorf("分布の指定が不正です: %q (例: constant:1, exponential:0.05, uniform:0.5-1)", spec)
+	}
+
+	switch name {
+	case DistributionConstant:
+		v, err := parseNonNegative(params)
+		if err != nil {
+			return nil, fmt.Errorf("分布の指定が不正です: %q: %w", spec, err)
+		}
+		return ConstantDistribution{Value: v}, nil
+	case DistributionExponential:
+		mean, err := parseNonNegative(params)
+		if err != nil {
+			return nil, fmt.Errorf("分布の指定が不正です: %q: %w", spec, err)
+		}
+		return ExponentialDistribution{Mean: mean}, nil
+	case DistributionUniform:
+		minParam, maxParam, ok := strings.Cut(params, "-")
+		if !ok {
+			return nil, fmt.Errorf("分布の指定が不正です: %q (uniformは 最小値-最大値 で指定します)", spec)
+		}
+		min, err := parseNonNegative(minParam)
+		if err != nil {
+			return nil, fmt.Errorf("分布の指定が不正です: %q: %w", spec, err)
+		}
+		max, err := parseNonNegative(maxParam)
+		if err != nil {
+			return nil, fmt.Errorf("分布の指定が不正です: %q: %w", spec, err)
+		}
+		if min > max {
+			return nil, fmt.Errorf("分布の指定が不正です: %q (最小値が最大値を超えています)", spec)
+		}
+		return UniformDistribution{Min: min, Max: max}, nil
+	default:
+		return nil, fmt.Errorf("未知の分布です: %q (指定可能な分布: %s, %s, %s)", name, DistributionConstant, DistributionExponential, DistributionUniform)
+	}
+}
+
+func parseNonNegative(s string) (float64, error) {
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, err
+	}
+	if v < 0 {
+		return 0, fmt.Errorf("負の値は指定できません: %v", v)
+	}
+	return v, nil
+}
+
+// ConstantDistribution は、常に同じ値を返します
+type ConstantDistribution struct {
+	Value float64
+}
+
+func (d ConstantDistribution) Sample() float64 {
+	return d.Value
+}
+
+func (d ConstantDistribution) String() string {
+	return fmt.Sprintf("%s:%v", DistributionConstant, d.Value)
+}
+
+// ExponentialDistribution は、平均Meanの指数分布に従う値を返します
+// NOTE: 到着間隔に用いると、到着がポアソン過程に従う
+type ExponentialDistribution struct {
+	Mean float64
+}
+
+func (d ExponentialDistribution) Sample() float64 {
+	return benchrand.ExpFloat64() * d.Mean
+}
+
+func (d ExponentialDistribution) String() string {
+	return fmt.Sprintf("%s:%v", DistributionExponential, d.Mean)
+}
+
+// UniformDistribution は、[Min, Max)の一様分布に従う値を返します
+type UniformDistribution struct {
+	Min float64
+	Max float64
+}
+
+func (d UniformDistribution) Sample() float64 {
+	return d.Min + benchrand.Float64()*(d.Max-d.Min)
+}
+
+func (d UniformDistribution) String() string {
+	return fmt.Sprintf("%s:%v-%v", DistributionUniform, d.Min, d.Max)
+}
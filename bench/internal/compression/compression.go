@@ -0,0 +1,111 @@
+// compression は、webappのレスポンスの圧縮(Content-Encoding)による転送量の削減を集計します
+//
+// ベンチマーカーは gzip, deflate, br を受け付けることを Accept-Encoding で通知し、
+// エンドポイントごとに、通信路上のバイト数と展開後のバイト数を記録します
+package compression
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Identity は、圧縮されていないレスポンスの Content-Encoding です
+const Identity = "identity"
+
+// Stat は、エンドポイントと圧縮方式ごとのレスポンスの集計です
+type Stat struct {
+	Endpoint  string `json:"endpoint"`
+	Encoding  string `json:"encoding"`
+	Responses int64  `json:"responses"`
+	// 通信路上のレスポンスボディのバイト数
+	WireBytes int64 `json:"wire_bytes"`
+	// 展開後のレスポンスボディのバイト数
+	DecodedBytes int64 `json:"decoded_bytes"`
+}
+
+// Report は、走行全体のレスポンスの圧縮の集計です. 結果に記録します
+type Report struct {
+	Responses           int64  `json:"responses"`
+	CompressedResponses int64  `json:"compressed_responses"`
+	WireBytes           int64  `json:"wire_bytes"`
+	DecodedBytes        int64  `json:"decoded_bytes"`
+	Endpoints           []Stat `json:"endpoints"`
+}
+
+// SavedRatio は、圧縮によって削減できた転送量の割合を返します
+func (r *Report) SavedRatio() float64 {
+	if r.DecodedBytes == 0 {
+		return 0
+	}
+	return 1 - float64(r.WireBytes)/float64(r.DecodedBytes)
+}
+
+type statKey struct {
+	endpoint string
+	encoding string
+}
+
+var (
+	mu    sync.Mutex
+	stats = map[statKey]*Stat{}
+)
+
+// Encoding は、Content-Encoding ヘッダの値を集計に用いる圧縮方式の名前にします
+func Encoding(contentEncoding string) string {
+	encoding := strings.ToLower(strings.TrimSpace(contentEncoding))
+	if len(encoding) == 0 {
+		return Identity
+	}
+	return encoding
+}
+
+// Observe は、レスポンスボディを読み終えた際に、通信路上と展開後のバイト数を記録します
+func Observe(endpoint, encoding string, wireBytes, decodedBytes int64) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	key := statKey{endpoint: endpoint, encoding: encoding}
+	stat, ok := stats[key]
+	if !ok {
+		stat = &Stat{Endpoint: endpoint, Encoding: encoding}
+		stats[key] = stat
+	}
+	stat.Responses++
+	stat.WireBytes += wireBytes
+	stat.DecodedBytes += decodedBytes
+}
+
+// Reset は、記録したレスポンスを破棄します. 負荷走行の開始時に呼び出します
+func Reset() {
+	mu.Lock()
+	defer mu.Unlock()
+	stats = map[statKey]*Stat{}
+}
+
+// CurrentReport は、記録したレスポンスの集計を返します. 記録がなければnilを返します
+func CurrentReport() *Report {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if len(stats) == 0 {
+		return nil
+	}
+	report := &Report{Endpoints: make([]Stat, 0, len(stats))}
+	for _, stat := range stats {
+		report.Responses += stat.Responses
+		if stat.Encoding != Identity {
+			report.CompressedResponses += stat.Responses
+		}
+		report.WireBytes += stat.WireBytes
+		report.DecodedBytes += stat.DecodedBytes
+		report.Endpoints = append(report.Endpoints, *stat)
+	}
+	sort.Slice(report.Endpoints, func(i, j int) bool {
+		if report.Endpoints[i].Endpoint != report.Endpoints[j].Endpoint {
+			return report.Endpoints[i].Endpoint < report.Endpoints[j].Endpoint
+		}
+		return report.Endpoints[i].Encoding < report.Endpoints[j].Encoding
+	})
+	return report
+}
@@ -0,0 +1,30 @@
+package compression
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCurrentReport(t *testing.T) {
+	Reset()
+	defer Reset()
+	assert.Nil(t, CurrentReport())
+
+	Observe("GET /api/tag", Encoding("gzip"), 300, 1000)
+	Observe("GET /api/tag", Encoding(" GZIP "), 200, 1000)
+	Observe("GET /api/livestream/:id/reaction", Encoding(""), 500, 500)
+
+	report := CurrentReport()
+	if assert.NotNil(t, report) {
+		assert.Equal(t, int64(3), report.Responses)
+		assert.Equal(t, int64(2), report.CompressedResponses)
+		assert.Equal(t, int64(1000), report.WireBytes)
+		assert.Equal(t, int64(2500), report.DecodedBytes)
+		assert.InDelta(t, 0.6, report.SavedRatio(), 1e-9)
+		assert.Equal(t, []Stat{
+			{Endpoint: "GET /api/livestream/:id/reaction", Encoding: Identity, Responses: 1, WireBytes: 500, DecodedBytes: 500},
+			{Endpoint: "GET /api/tag", Encoding: "gzip", Responses: 2, WireBytes: 500, DecodedBytes: 2000},
+		}, report.Endpoints)
+	}
+}
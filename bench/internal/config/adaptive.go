@@ -0,0 +1,35 @@
+package config
+
+import "time"
+
+// webappの応答状況に応じて、基本シナリオのワーカー数を増減させるか
+// NOTE: --adaptive-concurrency オプションによって変更されます
+var AdaptiveConcurrency = false
+
+// 並列度を見直す間隔
+const AdaptiveInterval = 2 * time.Second
+
+// エラー率とレイテンシ(p99)がこれらを下回る間は並列度を上げる
+const (
+	AdaptiveMaxErrorRate  = 0.01
+	AdaptiveMaxLatencyP99 = 1 * time.Second
+)
+
+// 見直す間隔のリクエストがこれより少ない場合は判断しない
+const AdaptiveMinRequests = 20
+
+// 負荷プロファイルのワーカー数に対する倍率の上限と下限
+// NOTE: 負荷プロファイルのワーカー数でも捌けない実装に対しては、それより下げる
+const (
+	AdaptiveMaxLevel = 8.0
+	AdaptiveMinLevel = 0.125
+)
+
+// 並列度を上げる幅(倍率)と、下げる割合
+const (
+	AdaptiveStep    = 0.5
+	AdaptiveBackoff = 0.5
+)
+
+// この回数連続で閾値を下回れば、並列度を持続できたとみなして次の並列度に上げる
+const AdaptiveSustainWindows = 3
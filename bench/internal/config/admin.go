@@ -0,0 +1,10 @@
+package config
+
+import "time"
+
+// スタッフ向けの管理APIを待ち受けるアドレス (空の場合は起動しない)
+// NOTE: --admin-addr オプションによって変更されます。外部に公開しないよう、ループバックアドレスを指定してください
+var AdminAddr = ""
+
+// 管理APIから走行時間を延長できる合計時間の上限
+const MaxBenchmarkExtension = 60 * time.Second
@@ -5,8 +5,91 @@ import "time"
 // ベンチマーク走行時間タイムアウト
 const DefaultBenchmarkTimeout = 60 * time.Second
 
+// 途中経過を選手に通知する、走行時間に対する進捗割合[%]
+var CheckpointPercentages = []int{25, 50, 75}
+
+// 負荷走行の開始からこの時間が経過した後に得たスコアを、選手向けの出力に含めない (0の場合は凍結しない)
+// NOTE: --score-freeze-after オプションによって変更されます. 凍結後もスコアは集計を続け、結果のスコアには含めます
+var ScoreFreezeAfter time.Duration = 0
+
+// 走行後に、スコアタグごとの配点・回数・スコアへの寄与と、減点を選手向けに出力する
+// NOTE: --explain-score オプションによって変更されます
+var ExplainScore = false
+
+// 練習モード. 失格となる検証の失敗を警告として詳細を出力し、走行を続ける. 結果は公式のものとして扱わない
+// NOTE: --practice オプションによって変更されます
+var Practice = false
+
+// 走行中の統計情報の検証で、不一致だった場合に再取得するまでの猶予期間
+// NOTE: --stats-grace-period オプションによって変更されます
+const DefaultStatsGracePeriod = 1 * time.Second
+
+var StatsGracePeriod = DefaultStatsGracePeriod
+
+// 走行中の統計情報の検証で、不一致だった場合に再取得する回数
+const StatsVerifyRetries = 1
+
+// ランキング安定性の検証で、同じ配信の順位を取得する回数と間隔
+const (
+	NumRankingSamples     = 3
+	RankingSampleInterval = 2 * time.Second
+)
+
+// ランキング整合性の検証で、同時に統計情報を取得する配信者の数、取得する回数と間隔
+const (
+	NumRankingConsistencyUsers    = 5
+	NumRankingConsistencyRounds   = 3
+	RankingConsistencyRoundPeriod = 2 * time.Second
+	// 検証を開始する間隔
+	RankingConsistencyInterval = 5 * time.Second
+)
+
+// 走行中ずっとログインしたままの配信者が、自身の統計情報を確認する間隔
+const LongSessionPollInterval = 3 * time.Second
+
 // スパム離脱割合
-const TooManySpamThresholdPercentage = 30.0
+// NOTE: 視聴者が目にしたスパムの割合がこれに達すると、視聴者は離脱し、体感品質のうちスパムに関する満足度が0になる
+var TooManySpamThresholdPercentage = 30.0
+
+// 遅延離脱パーセンタイル
+// NOTE: 視聴者の直近のリクエストの所要時間のこのパーセンタイルが、エンドポイントの分類ごとの遅延の閾値に達すると、視聴者は離脱する
+const TooSlowPercentile = 90.0
+
+// 遅延離脱を判断する、視聴者の直近のリクエスト数
+// NOTE: --viewer-abandon-window オプションによって変更されます. 1回の遅延(GCの停止など)で離脱しないよう、この件数に満たない間は離脱しない
+const DefaultViewerAbandonWindow = 20
+
+var ViewerAbandonWindow = DefaultViewerAbandonWindow
+
+// 視聴者の到着間隔[秒]の分布 (constant:値, exponential:平均, uniform:最小値-最大値)
+// NOTE: --viewer-arrival オプションによって変更されます。既定では間隔を設けず、並列数の上限まで視聴者が到着する
+const DefaultViewerArrivalDistribution = "constant:0"
+
+var ViewerArrivalDistribution = DefaultViewerArrivalDistribution
+
+// 視聴者が配信を視聴する時間の、配信時間に対する割合の分布
+// NOTE: --viewer-session オプションによって変更されます。既定では最後まで視聴する
+const DefaultViewerSessionDistribution = "constant:1"
+
+var ViewerSessionDistribution = DefaultViewerSessionDistribution
+
+// 視聴者が遅延やスパムに耐えかねて、配信の途中で離脱するか
+// NOTE: --viewer-departures オプションによって変更されます。既定では従来どおり離脱しない
+var ViewerDepartures = false
+
+// 視聴者が遅延していると感じるリクエストの所要時間
+const SessionSlowRequestThreshold = 1 * time.Second
+
+// レイド(視聴者の殺到)シナリオで、同じ配信に一斉に押し寄せる視聴者数
+const NumRaidViewers = 200
+
+// レイドシナリオで、押し寄せる視聴者が揃うまで待つ時間
+// NOTE: 揃わなければ、集めた視聴者を戻してレイドを見送る
+const RaidGatherTimeout = 3 * time.Second
+
+// レイドシナリオの実行間隔
+// NOTE: 視聴者を一度に大量に占有するので、頻度を抑える
+const RaidInterval = 10 * time.Second
 
 // 基本となる並列性
 // セマフォの重みに使われます
@@ -26,6 +109,13 @@ const DefaultAgentTimeout = 20 * time.Second
 // POST /api/initialize 時のタイムアウト
 const InitializeAgentTimeout = 42 * time.Second
 
+// POST /api/initialize の制限時間 (レギュレーション)
+// NOTE: これを超えた場合は失格とする。ネットワーク遅延を考慮し、InitializeAgentTimeoutは長めにとっている
+const InitializeTimeLimit = 30 * time.Second
+
+// POST /api/initialize がエラーを返した際に、選手に提示するレスポンスボディの最大長[byte]
+const InitializeErrorBodyLimit = 1024
+
 // SearchLivestreamsのLIMITのデフォルト
 const NumSearchLivestreams = 50
 
@@ -35,10 +125,106 @@ var (
 	InsecureSkipVerify = true
 )
 
+// ベンチマーカーが同時に行うTLSハンドシェイク数の上限 (0以下の場合は制限なし)
+// NOTE: --max-tls-handshakes オプションによって変更されます
+var MaxConcurrentTLSHandshakes = 0
+
+// アイコン画像の検証に用いる比較方式 (exact, perceptual, size)
+// NOTE: --icon-comparator オプションによって変更されます。再エンコードを許容する大会ではperceptualを指定します
+const DefaultIconComparator = "exact"
+
+var IconComparator = DefaultIconComparator
+
 const BaseDomain = "u.isucon.dev"
 
+// ゾーン BaseDomain のNSレコードとして登録すべきネームサーバ名
+var ZoneNameservers = []string{"ns1." + BaseDomain}
+
+// SSL接続が有効な場合に、webappが提示すべき証明書の対象ドメイン
+const TLSCertificateDomain = "*." + BaseDomain
+
+// SSL接続が有効な場合に、HTTPSへのリダイレクトまたは接続拒否を確認する平文HTTPのポート
+const PlainHTTPPort = 80
+
 // 暇になってる接続のタイムアウト
 // NOTE: これを設定しないと、keepaliveで繋ぎっぱなしの接続が増え、Nginxでworker_connectionが不十分だというエラーログが出るようになる
 const ClientIdleConnTimeout = 5 * time.Second
 
+// 視聴者のクライアントが、ページ内のアイコンをまとめて取得する際の並列数 (ブラウザの同時接続数の制限を模す)
+// NOTE: --viewer-parallel-fetches オプションによって変更されます. 既定では従来どおり1件ずつ取得する
+var ViewerParallelFetches = DefaultViewerParallelFetches
+
+const DefaultViewerParallelFetches = 1
+
 const AttackHTTPClientContextKey = "dns-attack-http-realip"
+
+// モデレーション有効性シナリオで、NGワード登録後にそれを含むライブコメントが一覧から消えるまでの猶予
+const ModerationMaxDelay = 3 * time.Second
+
+// モデレーション有効性シナリオで、ライブコメント一覧を再取得する間隔
+const ModerationPollInterval = 200 * time.Millisecond
+
+// モデレーション有効性シナリオの実行間隔
+const ModerationCheckInterval = 5 * time.Second
+
+// ベンチマーカーが送るチップの最大値
+const MaxTip = 100000
+
+// チップの記録シナリオの実行間隔
+const TipRecordingInterval = 10 * time.Second
+
+// リアクション連投シナリオで、同じ配信にリアクションを連投する視聴者数
+const NumReactionBurstViewers = 10
+
+// リアクション連投シナリオで、視聴者1人が連投するリアクション数
+const ReactionBurstSize = 10
+
+// リアクション連投シナリオで、投稿したリアクションが一覧に反映されるまでの猶予
+const ReactionBurstFreshness = 3 * time.Second
+
+// リアクション連投シナリオで、リアクション一覧を再取得する間隔
+const ReactionBurstPollInterval = 200 * time.Millisecond
+
+// リアクション連投シナリオで、リアクション一覧を取得する際に、連投した件数に上乗せする件数
+// NOTE: 他のシナリオによるリアクションが同時に投稿されても、連投したリアクションが一覧から押し出されにくくする
+const ReactionBurstListMargin = 50
+
+// リアクション連投シナリオの実行間隔
+const ReactionBurstInterval = 5 * time.Second
+
+// NGワード重複登録シナリオで、同じNGワードを同時に登録するリクエスト数
+const NumNgWordConcurrentRegistrations = 5
+
+// NGワード重複登録シナリオで、同時登録の後に同じNGワードを続けて登録する回数
+const NumNgWordSequentialRegistrations = 3
+
+// NGワード重複登録シナリオの実行間隔
+const NgWordIdempotencyInterval = 5 * time.Second
+
+// 重複排除して保持するエラーメッセージの種類数の上限
+// NOTE: IDを含むメッセージなどが大量に発生してもメモリを使い切らないようにする
+const MaxUniqueErrorMessages = 1000
+
+// ユーザ選択やライブコメント・DNSサブドメインの生成などに用いる乱数のシード (0の場合は現在時刻から決定する)
+// NOTE: --seed オプションによって変更されます。同じシードで走行すると、同等の負荷をかけられます
+var Seed int64 = 0
+
+// 検証のシナリオがタイムアウトのこの割合を超えて実行中であれば、webappが飽和しているとみなし、スループットのシナリオの起動を止める
+// NOTE: 飽和したwebappで、整合性を確かめるシナリオがタイムアウトして仕様違反を見逃さないようにする
+const VerificationYieldRatio = 0.5
+
+// ユーザ登録シナリオの実行間隔
+const UserRegistrationInterval = 1 * time.Second
+
+// ユーザ登録後、配信者用のサブドメインが名前解決できるようになるまでの猶予
+const DNSProvisioningDeadline = 3 * time.Second
+
+// ユーザ登録シナリオで、サブドメインの名前解決を再試行する間隔
+const DNSProvisioningPollInterval = 100 * time.Millisecond
+
+// ユーザ登録シナリオで、登録したユーザのアイコンがNoImage.jpgであるか検証する割合
+// NOTE: アイコン未設定の画像を外部のURLなどに差し替えていないか、走行中にも抜き打ちで確かめる
+const FallbackIconCheckRate = 0.2
+
+// アイコン未設定のユーザのアイコン(NoImage.jpg)のContent-Type
+const FallbackIconContentType = "image/jpeg"
@@ -0,0 +1,130 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// webappやネームサーバへの通信の送信元とするローカルのIPアドレスまたはネットワークインターフェース名 (空の場合はOSに任せる)
+// NOTE: --bind-address オプションによって変更されます
+var BindAddress = ""
+
+// ResolveBindAddress で決定した、アドレスファミリーごとの送信元のIPアドレス
+var bindIPv4, bindIPv6 net.IP
+
+// ResolveBindAddress は、送信元のアドレスを検証して決定し、決定したアドレスを返します
+// インターフェース名の場合は、そのIPv4アドレスとIPv6アドレスを宛先に応じて使い分けます
+func ResolveBindAddress() ([]net.IP, error) {
+	bindIPv4, bindIPv6 = nil, nil
+	if len(BindAddress) == 0 {
+		return nil, nil
+	}
+
+	ip := net.ParseIP(BindAddress)
+	if ip == nil {
+		iface, err := net.InterfaceByName(BindAddress)
+		if err != nil {
+			return nil, fmt.Errorf("送信元のアドレス(--bind-address)が不正です (IPアドレスかネットワークインターフェース名を指定してください): %s", BindAddress)
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			return nil, fmt.Errorf("ネットワークインターフェース %s のアドレスを取得できません: %w", iface.Name, err)
+		}
+		v4, v6 := interfaceIPs(addrs)
+		if v4 == nil && v6 == nil {
+			return nil, fmt.Errorf("ネットワークインターフェース %s に送信元とできるアドレスがありません", iface.Name)
+		}
+		bindIPv4, bindIPv6 = v4, v6
+	} else if !isLocalIP(ip) {
+		return nil, fmt.Errorf("送信元のアドレス(--bind-address)がこのホストのアドレスではありません: %s", BindAddress)
+	} else if ip.To4() != nil {
+		bindIPv4 = ip
+	} else {
+		bindIPv6 = ip
+	}
+
+	var ips []net.IP
+	for _, ip := range []net.IP{bindIPv4, bindIPv6} {
+		if ip != nil {
+			ips = append(ips, ip)
+		}
+	}
+	return ips, nil
+}
+
+// interfaceIPs は、インターフェースのアドレスから、送信元とするIPv4アドレスとIPv6アドレスを1つずつ選びます
+// NOTE: リンクローカルのIPv6アドレスはゾーンの指定が必要で、webappやネームサーバに届かないため選ばない
+func interfaceIPs(addrs []net.Addr) (v4, v6 net.IP) {
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok {
+			continue
+		}
+		switch ip := ipNet.IP; {
+		case ip.To4() != nil:
+			if v4 == nil {
+				v4 = ip
+			}
+		case ip.IsLinkLocalUnicast():
+		default:
+			if v6 == nil {
+				v6 = ip
+			}
+		}
+	}
+	return v4, v6
+}
+
+func isLocalIP(ip net.IP) bool {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return false
+	}
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// LocalAddr は、network(tcp, udp)で address に接続する際の送信元のアドレスを、net.Dialer.LocalAddr に設定できる形で返します
+// 送信元を指定していない場合や、宛先のアドレスファミリーの送信元がない場合はnilを返します
+// NOTE: net.Dialer.LocalAddr の型は network と一致している必要があるため、TCPとUDPで同じDialerを使い回さない
+func LocalAddr(network, address string) net.Addr {
+	ip := bindIPFor(network, address)
+	if ip == nil {
+		return nil
+	}
+	if strings.HasPrefix(network, "udp") {
+		return &net.UDPAddr{IP: ip}
+	}
+	return &net.TCPAddr{IP: ip}
+}
+
+// bindIPFor は、宛先のアドレスファミリーに合った送信元のIPアドレスを返します
+// 宛先がホスト名の場合は、IPv4を優先します
+func bindIPFor(network, address string) net.IP {
+	switch {
+	case strings.HasSuffix(network, "4"):
+		return bindIPv4
+	case strings.HasSuffix(network, "6"):
+		return bindIPv6
+	}
+
+	host := address
+	if h, _, err := net.SplitHostPort(address); err == nil {
+		host = h
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		if ip.To4() != nil {
+			return bindIPv4
+		}
+		return bindIPv6
+	}
+	if bindIPv4 != nil {
+		return bindIPv4
+	}
+	return bindIPv6
+}
@@ -0,0 +1,61 @@
+package config
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInterfaceIPs(t *testing.T) {
+	addrs := []net.Addr{
+		&net.IPNet{IP: net.ParseIP("fe80::1"), Mask: net.CIDRMask(64, 128)},
+		&net.IPNet{IP: net.ParseIP("2001:db8::10"), Mask: net.CIDRMask(64, 128)},
+		&net.IPNet{IP: net.ParseIP("192.168.0.10"), Mask: net.CIDRMask(24, 32)},
+		&net.IPNet{IP: net.ParseIP("192.168.0.11"), Mask: net.CIDRMask(24, 32)},
+	}
+	v4, v6 := interfaceIPs(addrs)
+	assert.Equal(t, "192.168.0.10", v4.String())
+	assert.Equal(t, "2001:db8::10", v6.String())
+
+	// リンクローカルのIPv6アドレスしかなければ、IPv6の送信元は選ばない
+	v4, v6 = interfaceIPs(addrs[:1])
+	assert.Nil(t, v4)
+	assert.Nil(t, v6)
+}
+
+func TestLocalAddr(t *testing.T) {
+	t.Cleanup(func() {
+		bindIPv4, bindIPv6 = nil, nil
+	})
+
+	bindIPv4, bindIPv6 = nil, nil
+	assert.Nil(t, LocalAddr("tcp", "192.168.0.1:443"))
+
+	bindIPv4, bindIPv6 = net.ParseIP("192.168.0.10"), net.ParseIP("2001:db8::10")
+
+	testCases := []struct {
+		network string
+		address string
+		want    net.Addr
+	}{
+		{"tcp", "192.168.0.1:443", &net.TCPAddr{IP: bindIPv4}},
+		{"tcp", "[2001:db8::1]:443", &net.TCPAddr{IP: bindIPv6}},
+		// プロトコルに合った型で返す
+		{"udp", "192.168.0.1:53", &net.UDPAddr{IP: bindIPv4}},
+		{"udp", "[2001:db8::1]:53", &net.UDPAddr{IP: bindIPv6}},
+		// ネットワークでアドレスファミリーを指定した場合はそれに従う
+		{"tcp6", "pipe.u.isucon.dev:443", &net.TCPAddr{IP: bindIPv6}},
+		{"udp4", "pipe.u.isucon.dev:53", &net.UDPAddr{IP: bindIPv4}},
+		// ホスト名はIPv4を優先する
+		{"tcp", "pipe.u.isucon.dev:443", &net.TCPAddr{IP: bindIPv4}},
+	}
+	for _, tc := range testCases {
+		assert.Equal(t, tc.want, LocalAddr(tc.network, tc.address), "%s %s", tc.network, tc.address)
+	}
+
+	// 宛先のアドレスファミリーの送信元がなければ、OSに任せる
+	bindIPv6 = nil
+	assert.Nil(t, LocalAddr("tcp", "[2001:db8::1]:443"))
+	assert.Equal(t, &net.TCPAddr{IP: bindIPv4}, LocalAddr("tcp", "pipe.u.isucon.dev:443"))
+}
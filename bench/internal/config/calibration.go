@@ -0,0 +1,26 @@
+package config
+
+import "time"
+
+// 走行前にベンチマーカーを動かすホストの性能を計測するか
+// NOTE: --calibrate オプションによって変更されます。選手の手元など、性能の異なる環境で得たスコアを比較するために用います
+var Calibrate = false
+
+// 性能計測で、各計測を行う時間
+const CalibrationDuration = 1 * time.Second
+
+// 性能計測の基準値 (本番のベンチマーカーを動かすホストの性能)
+// NOTE: 本番のベンチマーカーのホストで --calibrate を指定して走行し、「ホストの性能計測」のログに出力される CPU(ops/s), HTTP(req/s) の値を用いる
+// NOTE: CPUはGOMAXPROCSのすべてのコアでの合計、HTTPはループバックでの並列8の値のため、コア数の異なるホストに変更した場合は計測し直すこと
+// NOTE: 現在の値は本番のホストで計測する前の暫定の概算値である. 計測し直すまでは、補正係数は目安として扱うこと
+const (
+	ReferenceCPUOpsPerSec       = 2000000.0
+	ReferenceHTTPRequestsPerSec = 40000.0
+)
+
+// 補正係数の範囲
+// NOTE: 極端に性能の異なる環境ではスコアが線形に変化しないため、補正しすぎないようにする
+const (
+	MinNormalizationFactor = 0.25
+	MaxNormalizationFactor = 4.0
+)
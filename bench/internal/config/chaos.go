@@ -0,0 +1,16 @@
+package config
+
+import "time"
+
+// 負荷走行中のリクエストに、ベンチマーカー側で人為的な遅延と接続の切断を注入するか (運営向けの開発者モード)
+// NOTE: --chaos オプションによって変更されます
+var Chaos = false
+
+const (
+	// 遅延を注入するリクエストの割合
+	ChaosDelayRate = 0.1
+	// 注入する遅延の最大値
+	ChaosMaxDelay = 2 * time.Second
+	// 送信前に接続を切断するリクエストの割合
+	ChaosDropRate = 0.02
+)
@@ -0,0 +1,6 @@
+package config
+
+import "time"
+
+// HTTPの互換性の確認で、1つのリクエストに応答するまでのタイムアウト
+const ConformanceProbeTimeout = 5 * time.Second
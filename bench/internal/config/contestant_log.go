@@ -0,0 +1,11 @@
+package config
+
+// 選手向けログの出力レベル (debug, info, warn, error)
+// NOTE: --contestant-log-level オプションによって変更されます
+const DefaultContestantLogLevel = "info"
+
+var ContestantLogLevel = DefaultContestantLogLevel
+
+// 選手向けログで伏せる、ベンチマーカー内部のホスト名やアドレス (カンマ区切り)
+// NOTE: --internal-hosts オプションによって変更されます. ベンチマーカー自身のホスト名や管理APIのアドレスは指定しなくても伏せます
+var InternalHosts = ""
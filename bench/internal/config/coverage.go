@@ -0,0 +1,5 @@
+package config
+
+// エンドポイントの許可・除外リスト(JSON)のパス (空の場合は全てのエンドポイントを対象とする)
+// NOTE: --endpoint-filter オプションによって変更されます。移植途中のwebappで練習するためのもので、結果は部分的な走行として扱われます
+var EndpointFilterPath = ""
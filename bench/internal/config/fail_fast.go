@@ -0,0 +1,31 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// 負荷走行を打ち切るエラー件数 (0の場合は件数では打ち切らない)
+// NOTE: --fail-fast-errors オプションによって変更されます
+var FailFastMaxErrors int64 = 0
+
+// 負荷走行を打ち切る5xxレスポンスの割合 (0の場合は割合では打ち切らない)
+// NOTE: --fail-fast-5xx-rate オプションによって変更されます
+var FailFastMaxServerErrorRate = 0.0
+
+// レスポンスがこれより少ない間は、5xxの割合では打ち切らない
+const FailFastMinRequests = 200
+
+// エラーの予算を確かめる間隔
+const FailFastInterval = 1 * time.Second
+
+// ValidateFailFast は、エラーの予算が有効な範囲か検証します
+func ValidateFailFast() error {
+	if FailFastMaxErrors < 0 {
+		return fmt.Errorf("打ち切るエラー件数(--fail-fast-errors)は0以上を指定してください: %d", FailFastMaxErrors)
+	}
+	if FailFastMaxServerErrorRate < 0 || FailFastMaxServerErrorRate > 1 {
+		return fmt.Errorf("打ち切る5xxの割合(--fail-fast-5xx-rate)は0から1の範囲で指定してください: %v", FailFastMaxServerErrorRate)
+	}
+	return nil
+}
@@ -3,3 +3,19 @@ package config
 import "time"
 
 const FinalcheckTimeout = 10 * time.Second
+
+// 最終チェックで売上の台帳と統計情報を突き合わせる配信者・配信の数 (チップの多い順)
+const TipReconciliationSamples = 20
+
+// 売上の台帳と統計情報の食い違いを許容する割合
+// NOTE: 送信したが成功を確認できなかったチップは、反映されていてもいなくても許容した上で、さらにこの割合の誤差を許容する
+const TipReconciliationTolerance = 0.01
+
+// 最終チェックで操作の記録と統計情報を突き合わせる配信者・配信の数 (操作の多い順)
+const StatsReconciliationSamples = 20
+
+// 操作の記録と統計情報の食い違いを許容する割合
+const StatsReconciliationTolerance = 0.01
+
+// 最終チェックに失敗した際、結果のメッセージに含めるデータの食い違いの数 (全件は FinalcheckDriftPath に書き出す)
+const FinalcheckDriftMessages = 10
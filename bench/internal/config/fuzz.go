@@ -0,0 +1,12 @@
+package config
+
+import "time"
+
+// fuzzモードの1リクエストあたりのタイムアウト
+const FuzzTimeout = 10 * time.Second
+
+// fuzzモードで件数の上限(limit)に用いる有効範囲の最大値
+const FuzzMaxLimit = 100
+
+// fuzzモードでチップに用いる有効範囲の最大値
+const FuzzMaxTip = MaxTip
@@ -0,0 +1,30 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// 負荷走行中にkeep-aliveの接続を放置して、webappのアイドルタイムアウトを確かめる時間 (カンマ区切り. e.g. 3s,10s,60s)
+// NOTE: --idle-probe オプションによって変更されます
+var IdleProbePeriods = ""
+
+// アイドルタイムアウトの確認で送るリクエストのタイムアウト
+const IdleProbeRequestTimeout = 10 * time.Second
+
+// ParseIdleProbePeriods は、接続を放置する時間を検証して返します
+func ParseIdleProbePeriods() ([]time.Duration, error) {
+	var periods []time.Duration
+	for _, s := range strings.Split(IdleProbePeriods, ",") {
+		if s = strings.TrimSpace(s); len(s) == 0 {
+			continue
+		}
+		d, err := time.ParseDuration(s)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("接続を放置する時間(--idle-probe)が不正です: %q", s)
+		}
+		periods = append(periods, d)
+	}
+	return periods, nil
+}
@@ -0,0 +1,42 @@
+package config
+
+import (
+	"errors"
+	"strings"
+)
+
+// initializeで要求する初期データの規模 (ベンチマーカーが持つ初期データに対する倍率)
+// NOTE: --initialize-scale オプションによって変更されます
+// 0の場合は指定せず、webappの既定の初期データを用いる
+var InitializeScale float64 = 0
+
+// initializeで有効化を要求する機能フラグ (カンマ区切り)
+// NOTE: --initialize-features オプションによって変更されます
+var InitializeFeatures = ""
+
+var errInitializeScaleTooSmall = errors.New("初期データの規模(--initialize-scale)には1以上を指定してください (ベンチマーカーのシナリオは既定の初期データを前提とするため)")
+
+// ValidateInitializeParams は、initializeで要求するパラメータを検証します
+func ValidateInitializeParams() error {
+	if InitializeScale != 0 && InitializeScale < 1 {
+		return errInitializeScaleTooSmall
+	}
+	return nil
+}
+
+// InitializeFeatureList は、initializeで有効化を要求する機能フラグを返します
+func InitializeFeatureList() []string {
+	var features []string
+	for _, f := range strings.Split(InitializeFeatures, ",") {
+		if f = strings.TrimSpace(f); len(f) > 0 {
+			features = append(features, f)
+		}
+	}
+	return features
+}
+
+// HasInitializeParams は、initializeでパラメータを送るかを返します
+// NOTE: 指定がない場合はボディを送らず、パラメータに対応していないwebappでも従来通り初期化できるようにする
+func HasInitializeParams() bool {
+	return InitializeScale != 0 || len(InitializeFeatureList()) > 0
+}
@@ -0,0 +1,5 @@
+package config
+
+// シナリオのイテレーションを記録するジャーナルの出力先 (空の場合は記録しない)
+// NOTE: --journal オプションによって変更されます。bench journal dump で読み出せます
+var JournalPath = ""
@@ -0,0 +1,19 @@
+package config
+
+import "fmt"
+
+// すべてのレスポンスで Connection: close によって接続が閉じられた場合に、スコアから差し引く割合 (0の場合は差し引かない)
+// NOTE: 実際に差し引く割合は、接続が閉じられたレスポンスの割合に比例する
+// NOTE: --keep-alive-penalty オプションによって変更されます
+var KeepAlivePenalty = 0.0
+
+// 接続が閉じられたレスポンスの割合がこれを超えた場合、選手向けログで keep-alive の設定を見直すよう知らせる
+const KeepAliveWarnRatio = 0.1
+
+// ValidateKeepAlivePenalty は、keep-aliveのペナルティが有効な範囲か検証します
+func ValidateKeepAlivePenalty() error {
+	if KeepAlivePenalty < 0 || KeepAlivePenalty > 1 {
+		return fmt.Errorf("keep-aliveのペナルティ(--keep-alive-penalty)は0から1の範囲で指定してください: %v", KeepAlivePenalty)
+	}
+	return nil
+}
@@ -0,0 +1,5 @@
+package config
+
+// Prometheus形式のメトリクスを公開するアドレス (空の場合は公開しない)
+// NOTE: --metrics-listen オプションによって変更されます
+var MetricsListenAddr = ""
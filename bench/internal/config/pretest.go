@@ -4,6 +4,20 @@ import "time"
 
 const PretestTimeout = 20 * time.Second
 
+// キャッシュのpretestで条件付きリクエストを検証する静的ファイルの数 (/ を除く)
+const CachingPretestAssets = 3
+
+// キャッシュのpretestで、304 のレスポンスのヘッダを受信した後、ボディが続かないか待つ時間
+// NOTE: Connection: close を無視して接続を閉じないwebappでも、この時間で打ち切る
+const NotModifiedBodyWait = 1 * time.Second
+
+// ログイン時に発行するセッションCookieの有効期間の下限と上限 (Max-Age または Expires を指定した場合)
+// NOTE: セッション自体の有効期限(1時間)より先にCookieが失効しないこと. 下限は時計のずれを見込んで短めにする
+const (
+	SessionCookieMinLifetime = 55 * time.Minute
+	SessionCookieMaxLifetime = 24 * time.Hour
+)
+
 var DefaultDNSRecord = []string{
 	"www",
 	"www1",
@@ -0,0 +1,158 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Pacing は、シナリオごとの実行開始のレートです
+// NOTE: 待ち時間がシナリオのタイムアウトに含まれないよう、リクエストごとではなくシナリオの開始を平準化する
+type Pacing struct {
+	// 毎秒の開始数 (シナリオの全ワーカーの合計)
+	Rate float64 `yaml:"rate"`
+	// 連続で開始できる最大数
+	Burst int `yaml:"burst"`
+	// 開始タイミングに加えるゆらぎの最大値
+	Jitter time.Duration `yaml:"jitter"`
+}
+
+// LoadProfile は、負荷走行の強度をまとめたプリセットです
+type LoadProfile struct {
+	Name string
+	// 負荷走行の時間
+	Duration time.Duration
+	// 配信者・モデレーターなど基本となるシナリオのワーカー数
+	Parallelism int64
+	// 配信者に対する視聴者シナリオのワーカー数の倍率
+	ViewerRatio int64
+	// 配信者に対するスパム投稿シナリオのワーカー数の倍率
+	SpammerRatio int64
+	// DNS水責め攻撃の最大QPS
+	AttackQPS int
+	// DNS水責め攻撃の並列数の初期値と上限
+	InitialAttackParallelism int
+	MaxAttackParallelism     int
+	// シナリオ(スコアタグ名)ごとの開始レート (含まれないシナリオは制限しない)
+	Pacing map[string]Pacing
+}
+
+// 既定の負荷プロファイル (本番の競技環境向け)
+const DefaultLoadProfile = "standard"
+
+// 負荷プロファイル
+// NOTE: --profile オプションによって変更されます。standard 以外で走行したスコアは本番と比較できません
+var LoadProfileName = DefaultLoadProfile
+
+var loadProfiles = map[string]LoadProfile{
+	// 手元のノートPCなどで練習するための軽い負荷
+	"light": {
+		Duration:                 DefaultBenchmarkTimeout,
+		Parallelism:              BaseParallelism,
+		ViewerRatio:              3,
+		SpammerRatio:             1,
+		AttackQPS:                300,
+		InitialAttackParallelism: 1,
+		MaxAttackParallelism:     4,
+		// NOTE: 手元のマシンではベンチマーカー自身の待ち行列でレイテンシが歪みやすいので、発行を平準化する
+		Pacing: map[string]Pacing{
+			"viewer":                {Rate: 10, Burst: 2, Jitter: 20 * time.Millisecond},
+			"viewer-spam":           {Rate: 10, Burst: 2, Jitter: 20 * time.Millisecond},
+			"streamer-cold-reserve": {Rate: 5, Burst: 1, Jitter: 20 * time.Millisecond},
+		},
+	},
+	"standard": {
+		Duration:                 DefaultBenchmarkTimeout,
+		Parallelism:              BaseParallelism,
+		ViewerRatio:              10, // 配信者の10倍視聴者トラフィックがある
+		SpammerRatio:             2,  // 視聴者の２倍はスパム投稿者が潜んでいる
+		AttackQPS:                3000,
+		InitialAttackParallelism: 2,
+		MaxAttackParallelism:     15,
+	},
+	// 競技環境より強いマシンで限界を探るための重い負荷
+	"heavy": {
+		Duration:                 DefaultBenchmarkTimeout,
+		Parallelism:              BaseParallelism * 2,
+		ViewerRatio:              10,
+		SpammerRatio:             2,
+		AttackQPS:                6000,
+		InitialAttackParallelism: 4,
+		MaxAttackParallelism:     30,
+	},
+	// リークや劣化を見つけるため、標準より軽い負荷を長時間かけ続ける
+	"soak": {
+		Duration:                 10 * time.Minute,
+		Parallelism:              BaseParallelism,
+		ViewerRatio:              5,
+		SpammerRatio:             1,
+		AttackQPS:                1000,
+		InitialAttackParallelism: 2,
+		MaxAttackParallelism:     8,
+		Pacing: map[string]Pacing{
+			"viewer": {Rate: 20, Burst: 4, Jitter: 10 * time.Millisecond},
+		},
+	},
+}
+
+// 負荷プロファイルを上書きする設定ファイル(YAML/JSON)のパス
+// NOTE: --load-config オプションによって変更されます
+var LoadConfigPath = ""
+
+// loadConfig は、負荷プロファイルを上書きする設定です
+type loadConfig struct {
+	// シナリオ(スコアタグ名)ごとの開始レート. 指定したシナリオのみ上書きします
+	Pacing map[string]Pacing `yaml:"pacing"`
+}
+
+// ApplyLoadConfig は、設定ファイルの内容で負荷プロファイルを上書きします
+func ApplyLoadConfig(path string, profile *LoadProfile) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	// NOTE: JSONはYAMLとして解釈できる
+	var cfg loadConfig
+	if err := yaml.Unmarshal(b, &cfg); err != nil {
+		return fmt.Errorf("負荷設定ファイルの形式が不正です: %w", err)
+	}
+
+	pacing := make(map[string]Pacing, len(profile.Pacing)+len(cfg.Pacing))
+	for name, p := range profile.Pacing {
+		pacing[name] = p
+	}
+	for name, p := range cfg.Pacing {
+		if p.Rate <= 0 {
+			return fmt.Errorf("負荷設定ファイルの %s の開始レートが不正です: %v", name, p.Rate)
+		}
+		pacing[name] = p
+	}
+	profile.Pacing = pacing
+
+	return nil
+}
+
+// LoadProfileNames は、指定可能な負荷プロファイル名を返します
+func LoadProfileNames() []string {
+	names := make([]string, 0, len(loadProfiles))
+	for name := range loadProfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// CurrentLoadProfile は、指定された負荷プロファイルを返します
+func CurrentLoadProfile() (LoadProfile, error) {
+	profile, ok := loadProfiles[LoadProfileName]
+	if !ok {
+		return LoadProfile{}, fmt.Errorf("不明な負荷プロファイルです: %s (%s のいずれかを指定してください)", LoadProfileName, strings.Join(LoadProfileNames(), ", "))
+	}
+	profile.Name = LoadProfileName
+	return profile, nil
+}
@@ -0,0 +1,47 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+)
+
+// webappへのリクエストを中継するプロキシのURL (http, https, socks5)
+// NOTE: --proxy オプションによって変更されます
+// 名前解決はプロキシ側で行われるため、ベンチマーカーのリゾルバは利用されません
+var ProxyURL = ""
+
+// defaultProxyPorts は、URLでポートが省略された場合のプロキシの既定のポートです
+var defaultProxyPorts = map[string]string{
+	"http":   "80",
+	"https":  "443",
+	"socks5": "1080",
+}
+
+// ParseProxyURL は、プロキシのURLを検証して返します. 指定されていない場合はnilを返します
+func ParseProxyURL() (*url.URL, error) {
+	if len(ProxyURL) == 0 {
+		return nil, nil
+	}
+
+	u, err := url.Parse(ProxyURL)
+	if err != nil {
+		return nil, fmt.Errorf("プロキシのURLが不正です: %w", err)
+	}
+	if _, ok := defaultProxyPorts[u.Scheme]; !ok || len(u.Hostname()) == 0 {
+		return nil, fmt.Errorf("プロキシのURLが不正です (http://, https://, socks5:// のいずれかで指定してください): %s", ProxyURL)
+	}
+	return u, nil
+}
+
+// ProxyAddr は、プロキシへ接続する際のアドレス(host:port)を返します. 指定されていない場合は空文字を返します
+func ProxyAddr(u *url.URL) string {
+	if u == nil {
+		return ""
+	}
+	port := u.Port()
+	if len(port) == 0 {
+		port = defaultProxyPorts[u.Scheme]
+	}
+	return net.JoinHostPort(u.Hostname(), port)
+}
@@ -0,0 +1,16 @@
+package config
+
+import "time"
+
+// 走行結果をPOSTするポータルのURL (空の場合は送信しない)
+// NOTE: --report-url オプションによって変更されます
+var ReportURL = ""
+
+// 走行結果のPOSTに失敗した場合に再送する回数
+const ReportRetries = 3
+
+// 走行結果を再送するまでの待ち時間 (再送のたびに倍にする)
+const ReportRetryInterval = 1 * time.Second
+
+// 走行結果のPOST 1回あたりのタイムアウト
+const ReportTimeout = 10 * time.Second
@@ -13,3 +13,6 @@ const NumHours = (24 * 365) - 1
 
 // この時間[h]を超えた配信枠は長時間配信とみなす
 const LongHourThreshold = 10
+
+// 予約衝突シナリオで、同一の枠に一斉に予約を試みる配信者数
+const NumReservationCollisionStreamers = 8
@@ -0,0 +1,10 @@
+package config
+
+// NOTE: --sealed オプションによって変更されます
+// 決勝の封印モードでは、チーム間で走行条件を揃えるため、走行条件や出力を変えるオプションを無効化し、
+// 選手向けの出力を最小限の結果と簡潔なメッセージに絞ります
+var Sealed = false
+
+// 結果ファイルの署名に用いる鍵 (空の場合は署名しない)
+// NOTE: --result-signing-key オプションによって変更されます。封印モードでは必須です
+var ResultSigningKey = ""
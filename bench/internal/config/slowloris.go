@@ -0,0 +1,24 @@
+package config
+
+import "time"
+
+// 負荷走行と並行して、リクエストを少しずつ送る・ボディを送り終えないクライアントの接続を張るか
+// NOTE: --slow-loris オプションによって変更されます
+var SlowLoris = false
+
+const (
+	// 振る舞いごとに張る接続数
+	SlowLorisConnsPerMode = 8
+	// 数バイトずつ送る間隔
+	SlowLorisTrickleInterval = 1 * time.Second
+	// 接続を保持する上限. これを超えても打ち切られなければ、webappが打ち切らなかったものとする
+	SlowLorisHoldLimit = 30 * time.Second
+	// ボディを送り終えないリクエストで宣言するContent-Length
+	SlowLorisDeclaredBodyLength = 1024
+	// 正規のリクエストのレイテンシを計測する回数 (接続を張る前と、張った後それぞれ)
+	SlowLorisLatencySamples = 5
+	// 正規のリクエストのタイムアウト
+	SlowLorisRequestTimeout = 10 * time.Second
+	// 接続を張った後のレイテンシの中央値が、張る前の何倍を超えたら悪化したものとするか
+	SlowLorisDegradationRatio = 2.0
+)
@@ -7,3 +7,14 @@ var StaffLogPath string = "/tmp/result.json"
 var ContestantLogPath string = "/tmp/staff.log"
 var ResultPath string = "/tmp/contestant.log"
 var FinalcheckPath string = "/tmp/finalcheck.json"
+
+// NOTE: --json-logs オプションが指定された場合、スタッフ向け・選手向けログをJSONで書き出す
+//
+//	ポータルが正規表現に頼らずレベル、時刻、フィールドを取り出せるようにする
+var JSONLogs bool = false
+
+// NOTE: 最終チェックでデータの食い違いが見つかった場合、全件を当該パスにJSONで書き出す
+var FinalcheckDriftPath string = "/tmp/finalcheck_drift.json"
+
+// NOTE: --pretest-report オプションが指定された場合、pretestの結果を当該パスにJSONで書き出す
+var PretestReportPath string = ""
@@ -0,0 +1,134 @@
+package config
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// EndpointClass は、リクエストのタイムアウトと遅延の閾値を切り替えるエンドポイントの分類です
+type EndpointClass string
+
+const (
+	// ユーザ登録・ログイン (パスワードのハッシュ計算を伴う)
+	EndpointClassAuth EndpointClass = "auth"
+	// 参照系 (GET, HEAD)
+	EndpointClassRead EndpointClass = "read"
+	// 更新系 (POST, PUT, DELETE など)
+	EndpointClassWrite EndpointClass = "write"
+	// POST /api/initialize
+	EndpointClassInitialize EndpointClass = "initialize"
+)
+
+// TimeoutTier は、エンドポイントの分類ごとのリクエストのタイムアウトと、視聴者が遅延していると感じる所要時間です
+type TimeoutTier struct {
+	Timeout       time.Duration
+	SlowThreshold time.Duration
+}
+
+func (t TimeoutTier) String() string {
+	return fmt.Sprintf("タイムアウト %s, 遅延の閾値 %s", t.Timeout, t.SlowThreshold)
+}
+
+// エンドポイントの分類ごとのタイムアウトと遅延の閾値を上書きする指定 (class=timeout[/slow] のカンマ区切り. e.g. auth=30s/2s,read=10s)
+// NOTE: --timeout-tiers オプションによって変更されます
+var TimeoutTiersSpec = ""
+
+// 既定のタイムアウトと遅延の閾値
+// NOTE: 既定ではすべての分類で同じ値とし、従来の単一のタイムアウトと同じ振る舞いにする
+var defaultTimeoutTiers = map[EndpointClass]TimeoutTier{
+	EndpointClassAuth:       {Timeout: DefaultAgentTimeout, SlowThreshold: SessionSlowRequestThreshold},
+	EndpointClassRead:       {Timeout: DefaultAgentTimeout, SlowThreshold: SessionSlowRequestThreshold},
+	EndpointClassWrite:      {Timeout: DefaultAgentTimeout, SlowThreshold: SessionSlowRequestThreshold},
+	EndpointClassInitialize: {Timeout: InitializeAgentTimeout, SlowThreshold: SessionSlowRequestThreshold},
+}
+
+// ApplyTimeoutTiers で決定したタイムアウトと遅延の閾値
+var timeoutTiers = copyTimeoutTiers(defaultTimeoutTiers)
+
+func copyTimeoutTiers(tiers map[EndpointClass]TimeoutTier) map[EndpointClass]TimeoutTier {
+	m := make(map[EndpointClass]TimeoutTier, len(tiers))
+	for class, tier := range tiers {
+		m[class] = tier
+	}
+	return m
+}
+
+// ApplyTimeoutTiers は、TimeoutTiersSpec を検証し、既定のタイムアウトと遅延の閾値を上書きします
+// 遅延の閾値を省略した分類は、既定の閾値のままとします
+func ApplyTimeoutTiers() error {
+	tiers := copyTimeoutTiers(defaultTimeoutTiers)
+	for _, s := range strings.Split(TimeoutTiersSpec, ",") {
+		if s = strings.TrimSpace(s); len(s) == 0 {
+			continue
+		}
+		name, value, ok := strings.Cut(s, "=")
+		class := EndpointClass(strings.TrimSpace(name))
+		tier, known := tiers[class]
+		if !ok || !known {
+			return fmt.Errorf("タイムアウトの指定(--timeout-tiers)が不正です (%s のいずれかを指定してください): %q", strings.Join(EndpointClassNames(), ", "), s)
+		}
+
+		timeout, slow, hasSlow := strings.Cut(value, "/")
+		d, err := time.ParseDuration(strings.TrimSpace(timeout))
+		if err != nil || d <= 0 {
+			return fmt.Errorf("タイムアウトの指定(--timeout-tiers)が不正です: %q", s)
+		}
+		tier.Timeout = d
+		if hasSlow {
+			d, err := time.ParseDuration(strings.TrimSpace(slow))
+			if err != nil || d <= 0 || d > tier.Timeout {
+				return fmt.Errorf("遅延の閾値の指定(--timeout-tiers)が不正です (タイムアウト以下で指定してください): %q", s)
+			}
+			tier.SlowThreshold = d
+		}
+		tiers[class] = tier
+	}
+
+	timeoutTiers = tiers
+	return nil
+}
+
+// EndpointClassNames は、指定可能なエンドポイントの分類名を返します
+func EndpointClassNames() []string {
+	names := make([]string, 0, len(defaultTimeoutTiers))
+	for class := range defaultTimeoutTiers {
+		names = append(names, string(class))
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ClassifyEndpoint は、リクエストのメソッドとパスからエンドポイントの分類を返します
+func ClassifyEndpoint(method, path string) EndpointClass {
+	switch {
+	case method == http.MethodPost && path == "/api/initialize":
+		return EndpointClassInitialize
+	case method == http.MethodPost && (path == "/api/register" || path == "/api/login"):
+		return EndpointClassAuth
+	case method == http.MethodGet || method == http.MethodHead:
+		return EndpointClassRead
+	default:
+		return EndpointClassWrite
+	}
+}
+
+// CurrentTimeoutTier は、エンドポイントの分類のタイムアウトと遅延の閾値を返します
+func CurrentTimeoutTier(class EndpointClass) TimeoutTier {
+	return timeoutTiers[class]
+}
+
+// MaxTierTimeout は、初期化を除く分類のタイムアウトのうち最大のものを返します
+// NOTE: HTTPクライアント全体のタイムアウトには、個々のリクエストのタイムアウトより先に切れないようこの値を用いる
+func MaxTierTimeout() time.Duration {
+	var d time.Duration
+	for class, tier := range timeoutTiers {
+		if class == EndpointClassInitialize {
+			continue
+		}
+		d = max(d, tier.Timeout)
+	}
+	return d
+}
@@ -0,0 +1,5 @@
+package config
+
+// シナリオのトレースを送信するOTLP/HTTPのエンドポイント (空の場合はトレースを記録しない)
+// NOTE: --otlp-endpoint オプションによって変更されます
+var OTLPEndpoint = ""
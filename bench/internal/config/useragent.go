@@ -0,0 +1,32 @@
+package config
+
+// クライアントごとに選ぶ、実在するブラウザのUser-Agent
+var UserAgents = []string{
+	"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/119.0.0.0 Safari/537.36",
+	"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/119.0.0.0 Safari/537.36",
+	"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.1 Safari/605.1.15",
+	"Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:120.0) Gecko/20100101 Firefox/120.0",
+	"Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/119.0.0.0 Safari/537.36",
+	"Mozilla/5.0 (iPhone; CPU iPhone OS 17_1 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.1 Mobile/15E148 Safari/604.1",
+	"Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/119.0.0.0 Mobile Safari/537.36",
+	"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/119.0.0.0 Safari/537.36 Edg/119.0.0.0",
+}
+
+// ベンチマーカーからのリクエストであることを示すヘッダを付与するか
+// NOTE: --bot-marker オプションによって変更されます
+// 付与しない走行(ステルス走行)と結果を比べることで、ベンチマーカーからのリクエストだけを特別扱いする実装を検出できる
+var BotMarkerEnabled = DefaultBotMarkerEnabled
+
+const DefaultBotMarkerEnabled = true
+
+// ベンチマーカーからのリクエストであることを示すヘッダの名前と値
+// NOTE: --bot-marker-header, --bot-marker-value オプションによって変更されます
+var (
+	BotMarkerHeader = DefaultBotMarkerHeader
+	BotMarkerValue  = DefaultBotMarkerValue
+)
+
+const (
+	DefaultBotMarkerHeader = "X-Isupipe-Bench"
+	DefaultBotMarkerValue  = "1"
+)
@@ -3,6 +3,8 @@ package config
 import (
 	"fmt"
 	"net"
+	"strings"
+	"time"
 )
 
 var (
@@ -13,11 +15,71 @@ var (
 	TargetPort       int      = 8080
 )
 
+// ネームサーバへの1回のクエリの期限 (ResolveAttempts 回までリトライする場合も、クエリごとにこの期限で打ち切る)
+// NOTE: --resolve-timeout オプションによって変更されます
+var DNSResolveTimeout = DefaultDNSResolveTimeout
+
+const DefaultDNSResolveTimeout = 2 * time.Second
+
+// ネームサーバの応答を、返されたTTLの間キャッシュするか (実際のスタブリゾルバの振る舞いを模す)
+// NOTE: --dns-cache オプションによって変更されます
+var DNSCacheEnabled = DefaultDNSCacheEnabled
+
+const DefaultDNSCacheEnabled = true
+
+// キャッシュする期間の上限 (これより長いTTLが返されても、この期間で問い合わせ直す)
+const DNSCacheMaxTTL = 5 * time.Minute
+
+// 妥当なTTLとみなす範囲
+// NOTE: 短すぎるとキャッシュが効かずネームサーバに問い合わせが集中し、長すぎると新規ユーザのレコードの変更が反映されない
+const (
+	DNSSensibleTTLMin = 10 * time.Second
+	DNSSensibleTTLMax = 24 * time.Hour
+)
+
+// pretestでEDNS0のクエリに広告するUDPペイロードサイズ[byte]
+// NOTE: 512は従来のDNSの上限、1232はDNS Flag Day 2020の推奨値、4096はBINDやPowerDNSの既定値
+var EDNSPayloadSizes = []uint16{512, 1232, 4096}
+
+// 複数の宛先とその重み・期待するアドレスを定義した設定ファイル(YAML/JSON)のパス (空の場合は TargetBaseURL のみを用いる)
+// NOTE: --targets オプションによって変更されます
+var TargetsPath = ""
+
+// NOTE: IPv6アドレスは表記が一意でないため、文字列ではなくアドレスとして比較する
 func IsWebappIP(ip net.IP) bool {
 	for _, s := range TargetWebapps {
-		if ip.String() == s {
+		if ip.Equal(net.ParseIP(TrimIPBrackets(s))) {
 			return true
 		}
 	}
 	return false
 }
+
+// WebappIPFamilies は、webappのアドレスにIPv4・IPv6のアドレスが含まれるかを返します
+// NOTE: 名前解決でどちらのレコード(A/AAAA)を問い合わせるかの判断に用いる. アドレスが1つもない場合はIPv4のみとみなす
+func WebappIPFamilies() (hasIPv4 bool, hasIPv6 bool) {
+	for _, s := range TargetWebapps {
+		ip := net.ParseIP(TrimIPBrackets(s))
+		if ip == nil {
+			continue
+		}
+		if ip.To4() != nil {
+			hasIPv4 = true
+		} else {
+			hasIPv6 = true
+		}
+	}
+	if !hasIPv4 && !hasIPv6 {
+		hasIPv4 = true
+	}
+	return hasIPv4, hasIPv6
+}
+
+// TrimIPBrackets は、URLのホスト部の表記で指定されたIPv6アドレス([::1]など)の角括弧を取り除きます
+// NOTE: net.JoinHostPort で改めて角括弧を付けるため、アドレスは角括弧なしで保持する
+func TrimIPBrackets(s string) string {
+	if strings.HasPrefix(s, "[") && strings.HasSuffix(s, "]") {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
@@ -0,0 +1,233 @@
+// conformance は、生のTCP接続でHTTP/1.0のリクエストや、ヘッダの大文字小文字・チャンク形式のボディ・不正な形式のリクエストを送り、
+// webappが正しく応答するか、行儀よく拒否するかを確かめます
+//
+// 選手が独自に実装したHTTPサーバでも、一般的なクライアントが送りうるリクエストを扱えるよう、運営向けに記録します
+package conformance
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"slices"
+	"syscall"
+	"time"
+)
+
+// Probe は、確かめるリクエストの種類です
+type Probe string
+
+const (
+	// ProbeHTTP10 は、HTTP/1.0のGETを送り、チャンク形式でないレスポンスが返り、接続が閉じられることを確かめる
+	ProbeHTTP10 Probe = "http10"
+	// ProbeHeaderCasing は、ヘッダ名の大文字小文字を崩したGETを送り、通常どおり応答することを確かめる
+	ProbeHeaderCasing Probe = "header-casing"
+	// ProbeChunkedBody は、チャンク形式のボディでログインし、ボディを読んで応答するか、411などで拒否することを確かめる
+	ProbeChunkedBody Probe = "chunked-body"
+	// ProbeMalformedHeader は、コロンのないヘッダ行を含むリクエストを送り、400などで拒否するか接続を閉じることを確かめる
+	ProbeMalformedHeader Probe = "malformed-header"
+)
+
+// Probes は、確かめるリクエストの一覧です
+var Probes = []Probe{ProbeHTTP10, ProbeHeaderCasing, ProbeChunkedBody, ProbeMalformedHeader}
+
+// Outcome は、リクエストに対するwebappの振る舞いの分類です
+type Outcome string
+
+const (
+	// OutcomeAnswered は、正しく応答した
+	OutcomeAnswered Outcome = "answered"
+	// OutcomeRejected は、4xxのレスポンスを返すか接続を閉じて、行儀よく拒否した
+	OutcomeRejected Outcome = "rejected"
+	// OutcomeFailed は、5xxや不正な形式のレスポンスを返したか、応答しなかった
+	OutcomeFailed Outcome = "failed"
+)
+
+// Target は、確かめる宛先です
+type Target struct {
+	// 接続先 (host:port)
+	Addr string
+	// Hostヘッダ (TLSの場合はSNIにも用いる)
+	Host string
+	// GETで取得するパス (内容が変化しないもの)
+	Path string
+	// チャンク形式のボディでログインするパス
+	LoginPath string
+	// nilでなければTLSで接続する
+	TLS *tls.Config
+}
+
+// Result は、1つのリクエストの結果です
+type Result struct {
+	Probe   Probe   `json:"probe"`
+	Outcome Outcome `json:"outcome"`
+	// レスポンスのステータスコード (レスポンスを受け取った場合のみ)
+	StatusCode int    `json:"status_code,omitempty"`
+	Detail     string `json:"detail,omitempty"`
+}
+
+// OK は、正しく応答したか、行儀よく拒否したかを返します
+func (r Result) OK() bool {
+	return r.Outcome != OutcomeFailed
+}
+
+// チャンク形式のボディで送るログインのリクエスト (存在しないユーザのため401が正しい応答)
+const chunkedLoginBody = `{"username":"conformance-probe","password":"conformance-probe"}`
+
+// request は、確かめるリクエストの生のバイト列を返します
+func request(probe Probe, target Target) string {
+	switch probe {
+	case ProbeHTTP10:
+		return fmt.Sprintf("GET %s HTTP/1.0\r\nHost: %s\r\n\r\n", target.Path, target.Host)
+	case ProbeHeaderCasing:
+		return fmt.Sprintf("GET %s HTTP/1.1\r\nhOsT: %s\r\nACCEPT: application/json\r\nuser-AGENT: isucandar\r\nconnection: CLOSE\r\n\r\n", target.Path, target.Host)
+	case ProbeChunkedBody:
+		half := len(chunkedLoginBody) / 2
+		return fmt.Sprintf("POST %s HTTP/1.1\r\nHost: %s\r\nContent-Type: application/json\r\nTransfer-Encoding: chunked\r\nConnection: close\r\n\r\n%x\r\n%s\r\n%x\r\n%s\r\n0\r\n\r\n",
+			target.LoginPath, target.Host,
+			half, chunkedLoginBody[:half],
+			len(chunkedLoginBody)-half, chunkedLoginBody[half:])
+	default:
+		return fmt.Sprintf("GET %s HTTP/1.1\r\nHost: %s\r\nX-Malformed-Header\r\nConnection: close\r\n\r\n", target.Path, target.Host)
+	}
+}
+
+// Run は、probe のリクエストを新しい接続で送り、webappの振る舞いを分類します
+func Run(ctx context.Context, dial func(ctx context.Context, network, addr string) (net.Conn, error), target Target, probe Probe, timeout time.Duration) Result {
+	result := Result{Probe: probe, Outcome: OutcomeFailed}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	c, err := dialTarget(ctx, dial, target)
+	if err != nil {
+		result.Detail = err.Error()
+		return result
+	}
+	defer c.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		c.SetDeadline(deadline)
+	}
+
+	if _, err := io.WriteString(c, request(probe, target)); err != nil {
+		if probe == ProbeMalformedHeader && isClosedByPeer(err) {
+			result.Outcome = OutcomeRejected
+			result.Detail = "接続が閉じられました"
+			return result
+		}
+		result.Detail = err.Error()
+		return result
+	}
+
+	br := bufio.NewReader(c)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil {
+		switch {
+		case isClosedByPeer(err) && probe != ProbeHTTP10 && probe != ProbeHeaderCasing:
+			// NOTE: ボディの形式や不正なヘッダを理由に、レスポンスを返さず接続を閉じるのは行儀のよい拒否とする
+			result.Outcome = OutcomeRejected
+			result.Detail = "レスポンスを返さずに接続が閉じられました"
+		case errors.Is(err, os.ErrDeadlineExceeded):
+			result.Detail = fmt.Sprintf("%s 以内にレスポンスが返りませんでした", timeout)
+		default:
+			result.Detail = fmt.Sprintf("レスポンスを読み込めません: %s", err.Error())
+		}
+		return result
+	}
+	defer resp.Body.Close()
+	result.StatusCode = resp.StatusCode
+	if _, err := io.Copy(io.Discard, resp.Body); err != nil {
+		result.Detail = fmt.Sprintf("レスポンスのボディを読み込めません: %s", err.Error())
+		return result
+	}
+
+	result = classify(probe, resp, result)
+	if probe == ProbeHTTP10 && result.OK() {
+		// NOTE: HTTP/1.0では Connection: keep-alive がなければ、応答した後に接続を閉じなければならない
+		c.SetReadDeadline(time.Now().Add(closeWait))
+		if _, err := br.Peek(1); !isClosedByPeer(err) {
+			result.Outcome = OutcomeFailed
+			result.Detail = "HTTP/1.0のリクエストに応答した後、接続を閉じませんでした"
+		}
+	}
+	return result
+}
+
+// HTTP/1.0のリクエストに応答した後、接続が閉じられるのを待つ時間
+const closeWait = 1 * time.Second
+
+// classify は、受け取ったレスポンスからwebappの振る舞いを分類します
+func classify(probe Probe, resp *http.Response, result Result) Result {
+	if resp.StatusCode >= http.StatusInternalServerError {
+		result.Detail = fmt.Sprintf("ステータスコード %d を返しました", resp.StatusCode)
+		return result
+	}
+
+	switch probe {
+	case ProbeHTTP10:
+		if resp.StatusCode != http.StatusOK {
+			result.Detail = fmt.Sprintf("HTTP/1.0のGETにステータスコード %d を返しました", resp.StatusCode)
+			return result
+		}
+		// NOTE: HTTP/1.0のクライアントはチャンク形式を解釈できない
+		if slices.Contains(resp.TransferEncoding, "chunked") {
+			result.Detail = "HTTP/1.0のリクエストにチャンク形式で応答しました"
+			return result
+		}
+		result.Outcome = OutcomeAnswered
+	case ProbeHeaderCasing:
+		if resp.StatusCode != http.StatusOK {
+			result.Detail = fmt.Sprintf("ヘッダ名の大文字小文字を崩したGETにステータスコード %d を返しました", resp.StatusCode)
+			return result
+		}
+		result.Outcome = OutcomeAnswered
+	case ProbeChunkedBody:
+		switch resp.StatusCode {
+		case http.StatusUnauthorized:
+			result.Outcome = OutcomeAnswered
+		case http.StatusBadRequest, http.StatusLengthRequired, http.StatusRequestEntityTooLarge, http.StatusNotImplemented:
+			result.Outcome = OutcomeRejected
+		default:
+			result.Detail = fmt.Sprintf("存在しないユーザのログインにステータスコード %d を返しました", resp.StatusCode)
+		}
+	default:
+		if resp.StatusCode >= http.StatusBadRequest {
+			result.Outcome = OutcomeRejected
+		} else {
+			// NOTE: 不正なヘッダ行を無視して応答するのは寛容な実装として許容する
+			result.Outcome = OutcomeAnswered
+			result.Detail = "不正なヘッダ行を無視して応答しました"
+		}
+	}
+	return result
+}
+
+func dialTarget(ctx context.Context, dial func(ctx context.Context, network, addr string) (net.Conn, error), target Target) (net.Conn, error) {
+	c, err := dial(ctx, "tcp", target.Addr)
+	if err != nil {
+		return nil, err
+	}
+	if target.TLS != nil {
+		cfg := target.TLS.Clone()
+		if len(cfg.ServerName) == 0 {
+			cfg.ServerName = target.Host
+		}
+		// NOTE: HTTP/1.xの振る舞いを確かめるため、HTTP/2をネゴシエートしない
+		cfg.NextProtos = []string{"http/1.1"}
+		tlsConn := tls.Client(c, cfg)
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			c.Close()
+			return nil, err
+		}
+		c = tlsConn
+	}
+	return c, nil
+}
+
+func isClosedByPeer(err error) bool {
+	return errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.EPIPE)
+}
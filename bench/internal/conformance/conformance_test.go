@@ -0,0 +1,88 @@
+package conformance
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var dialer = &net.Dialer{}
+
+func newTarget(t *testing.T, handler http.Handler) Target {
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	return Target{
+		Addr:      srv.Listener.Addr().String(),
+		Host:      "pipe.u.isucon.dev",
+		Path:      "/api/tag",
+		LoginPath: "/api/login",
+	}
+}
+
+func TestRun_Conformant(t *testing.T) {
+	target := newTarget(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			var req map[string]string
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"tags":[]}`))
+	}))
+
+	want := map[Probe]Outcome{
+		ProbeHTTP10:          OutcomeAnswered,
+		ProbeHeaderCasing:    OutcomeAnswered,
+		ProbeChunkedBody:     OutcomeAnswered,
+		ProbeMalformedHeader: OutcomeRejected,
+	}
+	for _, probe := range Probes {
+		r := Run(context.Background(), dialer.DialContext, target, probe, time.Second)
+		assert.Equal(t, want[probe], r.Outcome, "%s: %s", probe, r.Detail)
+		assert.True(t, r.OK(), probe)
+	}
+}
+
+func TestRun_Failed(t *testing.T) {
+	target := newTarget(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost && len(r.TransferEncoding) > 0 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte(`{"tags":[]}`))
+	}))
+
+	r := Run(context.Background(), dialer.DialContext, target, ProbeChunkedBody, time.Second)
+	assert.Equal(t, OutcomeFailed, r.Outcome)
+	assert.Equal(t, http.StatusInternalServerError, r.StatusCode)
+	assert.False(t, r.OK())
+}
+
+func TestRun_NotResponding(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			t.Cleanup(func() { c.Close() })
+		}
+	}()
+
+	target := Target{Addr: l.Addr().String(), Host: "pipe.u.isucon.dev", Path: "/api/tag", LoginPath: "/api/login"}
+	r := Run(context.Background(), dialer.DialContext, target, ProbeHTTP10, 200*time.Millisecond)
+	assert.Equal(t, OutcomeFailed, r.Outcome)
+	assert.NotEmpty(t, r.Detail)
+}
@@ -0,0 +1,218 @@
+// coverage は、練習用の走行で未実装のエンドポイントを除外し、除外した範囲を記録します
+//
+// 移植途中のwebappでも、実装済みのエンドポイントだけで走行できるようにするためのものです
+// 除外したエンドポイントへはリクエストを送らず、それを利用するpretestの項目やシナリオを実施しなかったものとして扱います
+package coverage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/isucon/isucon13/bench/internal/bencherror"
+	"github.com/isucon/isucon13/bench/internal/metrics"
+)
+
+// FilterConfig は、エンドポイントの許可・除外リストです
+// "GET /api/livestream/:id" のようにメソッドとルートを空白区切りで指定します
+// メソッドに "*" を指定すると全メソッドに、ルートの末尾に "*" を指定すると前方一致でマッチします
+// NOTE: 走行に必須の POST /api/initialize と GET /api/payment は除外できません
+type FilterConfig struct {
+	// 空でなければ、ここに含まれないエンドポイントを除外します
+	Allow []string `json:"allow"`
+	// ここに含まれるエンドポイントを除外します (許可より優先されます)
+	Deny []string `json:"deny"`
+}
+
+type pattern struct {
+	method string
+	route  string
+	prefix bool
+}
+
+func parsePattern(s string) (pattern, error) {
+	method, route, ok := strings.Cut(strings.TrimSpace(s), " ")
+	if !ok || len(method) == 0 || !strings.HasPrefix(route, "/") {
+		return pattern{}, fmt.Errorf("エンドポイントの指定が不正です (\"GET /api/tag\" の形式で指定してください): %q", s)
+	}
+
+	p := pattern{method: strings.ToUpper(method), route: strings.TrimSpace(route)}
+	if strings.HasSuffix(p.route, "*") {
+		p.route = strings.TrimSuffix(p.route, "*")
+		p.prefix = true
+	}
+	return p, nil
+}
+
+func (p pattern) match(method, route string) bool {
+	if p.method != "*" && p.method != method {
+		return false
+	}
+	if p.prefix {
+		return strings.HasPrefix(route, p.route)
+	}
+	return p.route == route
+}
+
+type filter struct {
+	allow []pattern
+	deny  []pattern
+}
+
+func newFilter(cfg *FilterConfig) (*filter, error) {
+	f := &filter{}
+	for _, s := range cfg.Allow {
+		p, err := parsePattern(s)
+		if err != nil {
+			return nil, err
+		}
+		f.allow = append(f.allow, p)
+	}
+	for _, s := range cfg.Deny {
+		p, err := parsePattern(s)
+		if err != nil {
+			return nil, err
+		}
+		f.deny = append(f.deny, p)
+	}
+	return f, nil
+}
+
+func (f *filter) allowed(method, route string) bool {
+	for _, p := range f.deny {
+		if p.match(method, route) {
+			return false
+		}
+	}
+	if len(f.allow) == 0 {
+		return true
+	}
+	for _, p := range f.allow {
+		if p.match(method, route) {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	mu sync.RWMutex
+	// nilの場合、全てのエンドポイントを許可します
+	current *filter
+
+	skippedEndpoints = map[string]int64{}
+	skippedTargets   = map[string]string{}
+)
+
+// LoadFilter は、許可・除外リストをJSONファイルから読み込みます
+func LoadFilter(path string) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var cfg FilterConfig
+	if err := json.Unmarshal(b, &cfg); err != nil {
+		return fmt.Errorf("エンドポイントの許可・除外リストの形式が不正です: %w", err)
+	}
+	return SetFilter(&cfg)
+}
+
+// SetFilter は、許可・除外リストを設定し、これまでの除外の記録を破棄します
+func SetFilter(cfg *FilterConfig) error {
+	f, err := newFilter(cfg)
+	if err != nil {
+		return err
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	current = f
+	skippedEndpoints = map[string]int64{}
+	skippedTargets = map[string]string{}
+	return nil
+}
+
+// Partial は、許可・除外リストが設定された部分的な走行であるかを返します
+func Partial() bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	return current != nil
+}
+
+// Check は、エンドポイントが除外されていれば記録した上で bencherror.ErrEndpointSkipped を返します
+func Check(method, path string) error {
+	mu.RLock()
+	f := current
+	mu.RUnlock()
+	if f == nil {
+		return nil
+	}
+
+	route := metrics.Route(path)
+	if f.allowed(method, route) {
+		return nil
+	}
+
+	endpoint := fmt.Sprintf("%s %s", method, route)
+	mu.Lock()
+	skippedEndpoints[endpoint]++
+	mu.Unlock()
+	return bencherror.NewEndpointSkippedError(endpoint)
+}
+
+// SkippedEndpoint は、除外されたエンドポイントが原因のエラーであれば、そのエンドポイントを返します
+func SkippedEndpoint(err error) (string, bool) {
+	var skipErr *bencherror.EndpointSkippedError
+	if !errors.As(err, &skipErr) {
+		return "", false
+	}
+	return skipErr.Endpoint, true
+}
+
+// IsSkippedError は、除外されたエンドポイントが原因のエラーであるかを返します
+func IsSkippedError(err error) bool {
+	_, ok := SkippedEndpoint(err)
+	return ok
+}
+
+// MarkSkipped は、除外されたエンドポイントを利用するため実施しなかった対象(pretestの項目やシナリオ)を記録します
+func MarkSkipped(target, endpoint string) {
+	mu.Lock()
+	defer mu.Unlock()
+	if _, ok := skippedTargets[target]; !ok {
+		skippedTargets[target] = endpoint
+	}
+}
+
+// IsSkipped は、対象を実施しなかったものとして記録済みかを返します
+func IsSkipped(target string) bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	_, ok := skippedTargets[target]
+	return ok
+}
+
+// Skipped は、実施しなかった対象と、リクエストを送らなかったエンドポイントの一覧を返します
+func Skipped() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	var skipped []string
+	for target, endpoint := range skippedTargets {
+		skipped = append(skipped, fmt.Sprintf("%s (%s)", target, endpoint))
+	}
+	sort.Strings(skipped)
+
+	var endpoints []string
+	for endpoint, count := range skippedEndpoints {
+		endpoints = append(endpoints, fmt.Sprintf("%s: %d 件", endpoint, count))
+	}
+	sort.Strings(endpoints)
+
+	return append(skipped, endpoints...)
+}
@@ -0,0 +1,60 @@
+package coverage
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/isucon/isucon13/bench/internal/bencherror"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheck_Deny(t *testing.T) {
+	assert.NoError(t, SetFilter(&FilterConfig{
+		Deny: []string{"POST /api/livestream/:id/reaction", "* /api/payment*"},
+	}))
+	assert.True(t, Partial())
+
+	assert.NoError(t, Check("GET", "/api/livestream/1/reaction"))
+	assert.NoError(t, Check("GET", "/api/tag"))
+
+	err := Check("POST", "/api/livestream/10/reaction")
+	assert.ErrorIs(t, err, bencherror.ErrEndpointSkipped)
+	endpoint, ok := SkippedEndpoint(err)
+	assert.True(t, ok)
+	assert.Equal(t, "POST /api/livestream/:id/reaction", endpoint)
+
+	assert.Error(t, Check("GET", "/api/payment"))
+	assert.Equal(t, []string{
+		"GET /api/payment: 1 件",
+		"POST /api/livestream/:id/reaction: 1 件",
+	}, Skipped())
+}
+
+func TestCheck_Allow(t *testing.T) {
+	assert.NoError(t, SetFilter(&FilterConfig{
+		Allow: []string{"* /api/user/*", "GET /api/tag"},
+		Deny:  []string{"GET /api/user/:username/statistics"},
+	}))
+
+	assert.NoError(t, Check("GET", "/api/tag"))
+	assert.NoError(t, Check("POST", "/api/user/me"))
+	assert.NoError(t, Check("GET", "/api/user/test001/icon"))
+	assert.Error(t, Check("GET", "/api/user/test001/statistics"))
+	assert.Error(t, Check("GET", "/api/livestream/search"))
+}
+
+func TestMarkSkipped(t *testing.T) {
+	assert.NoError(t, SetFilter(&FilterConfig{}))
+
+	MarkSkipped("シナリオ basic-viewer", "POST /api/livestream/:id/livecomment")
+	MarkSkipped("シナリオ basic-viewer", "GET /api/livestream/:id")
+	assert.True(t, IsSkipped("シナリオ basic-viewer"))
+	assert.False(t, IsSkipped("シナリオ raid"))
+	assert.Equal(t, []string{"シナリオ basic-viewer (POST /api/livestream/:id/livecomment)"}, Skipped())
+}
+
+func TestSetFilter_Invalid(t *testing.T) {
+	assert.Error(t, SetFilter(&FilterConfig{Deny: []string{"/api/tag"}}))
+	_, ok := SkippedEndpoint(errors.New("other"))
+	assert.False(t, ok)
+}
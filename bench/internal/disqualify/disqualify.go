@@ -0,0 +1,192 @@
+// disqualify は、走行を失格とする判定規則と、走行中に該当した規則を扱います
+//
+// 失格の判定は初期化・整合性チェック・負荷走行・最終チェックの各段階で行われるため、
+// 規則ごとにIDとメッセージを定め、該当した規則のIDを結果に記録して判定の根拠を明示します
+package disqualify
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+)
+
+// RuleID は、失格の判定規則の識別子です. 結果に記録するため、一度定めたIDは変更しません
+type RuleID string
+
+// Rule は、失格の判定規則です
+type Rule struct {
+	ID      RuleID `json:"id"`
+	Message string `json:"message"`
+}
+
+var (
+	InitializeFailed  = Rule{ID: "initialize-failed", Message: "初期化処理(POST /api/initialize)に失敗しました"}
+	InitializeTimeout = Rule{ID: "initialize-timeout", Message: "初期化処理が制限時間を超過しました"}
+	PretestFailed     = Rule{ID: "pretest-failed", Message: "整合性チェックに失敗しました"}
+	DoubleBooking     = Rule{ID: "double-booking", Message: "予約枠のダブルブッキングが発生しました"}
+	TipRecording      = Rule{ID: "tip-recording", Message: "受理したチップの記録に誤りがあります"}
+	ErrorBudget       = Rule{ID: "error-budget", Message: "エラー件数が上限に達しました"}
+	ServerErrorRate   = Rule{ID: "server-error-rate", Message: "5xxレスポンスの割合が上限に達しました"}
+	LoadAborted       = Rule{ID: "load-aborted", Message: "負荷走行が中断されました"}
+	FinalcheckFailed  = Rule{ID: "finalcheck-failed", Message: "最終チェックに失敗しました"}
+	SLOViolation      = Rule{ID: "slo-violation", Message: "シナリオの成功率が下限を下回りました"}
+)
+
+// Rules は、すべての判定規則を、判定する段階の順に並べたものです
+var Rules = []Rule{
+	InitializeFailed,
+	InitializeTimeout,
+	PretestFailed,
+	DoubleBooking,
+	TipRecording,
+	ErrorBudget,
+	ServerErrorRate,
+	LoadAborted,
+	FinalcheckFailed,
+	SLOViolation,
+}
+
+// Waivable は、練習モード(--practice)で失格とせずに走行を続ける判定規則です
+// NOTE: 初期化の失敗は以降の段階を実施できず、エラーの予算は選手が打ち切りを指定したものであるため、含めない
+var Waivable = []Rule{
+	InitializeTimeout,
+	PretestFailed,
+	DoubleBooking,
+	TipRecording,
+	FinalcheckFailed,
+	SLOViolation,
+}
+
+// Error は、判定規則に該当したことを表すエラーです
+type Error struct {
+	Rule Rule
+	Err  error
+}
+
+func (e *Error) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("[%s] %s", e.Rule.ID, e.Rule.Message)
+	}
+	return fmt.Sprintf("[%s] %s: %s", e.Rule.ID, e.Rule.Message, e.Err.Error())
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Wrap は、エラーが判定規則に該当することを付与します
+// NOTE: 検出した箇所で規則を付与し、走行を中断する箇所で RuleOf によって取り出す
+func Wrap(rule Rule, err error) error {
+	return &Error{Rule: rule, Err: err}
+}
+
+// RuleOf は、エラーに付与された判定規則を返します
+func RuleOf(err error) (Rule, bool) {
+	var dqErr *Error
+	if errors.As(err, &dqErr) {
+		return dqErr.Rule, true
+	}
+	return Rule{}, false
+}
+
+// Triggered は、走行中に該当した判定規則と、その詳細です
+type Triggered struct {
+	Rule   Rule   `json:"rule"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// Engine は、1回の走行で該当した判定規則を記録します
+type Engine struct {
+	mu        sync.Mutex
+	triggered []Triggered
+	// 練習モードで失格とせずに走行を続けた判定規則
+	waived []Triggered
+}
+
+func NewEngine() *Engine {
+	return &Engine{}
+}
+
+// Trigger は、判定規則に該当したことを記録し、規則を付与したエラーを返します
+// err に別の規則が付与されている場合は、そちらを優先します
+func (e *Engine) Trigger(rule Rule, err error) error {
+	if wrapped, ok := RuleOf(err); ok {
+		rule = wrapped
+	} else {
+		err = Wrap(rule, err)
+	}
+
+	triggered := Triggered{Rule: rule}
+	var dqErr *Error
+	if errors.As(err, &dqErr) && dqErr.Err != nil {
+		triggered.Detail = dqErr.Err.Error()
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.triggered = append(e.triggered, triggered)
+	return err
+}
+
+// Waive は、判定規則に該当したものの、失格とせずに走行を続けることを記録します
+// err に別の規則が付与されている場合は、そちらを優先します. 規則が Waivable に含まれない場合は記録せず、falseを返します
+func (e *Engine) Waive(rule Rule, err error) (Triggered, bool) {
+	if wrapped, ok := RuleOf(err); ok {
+		rule = wrapped
+	}
+	if !slices.Contains(Waivable, rule) {
+		return Triggered{}, false
+	}
+	waived := Triggered{Rule: rule}
+	var dqErr *Error
+	if errors.As(err, &dqErr) && dqErr.Err != nil {
+		waived.Detail = dqErr.Err.Error()
+	} else if err != nil {
+		waived.Detail = err.Error()
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.waived = append(e.waived, waived)
+	return waived, true
+}
+
+// Waived は、失格とせずに走行を続けた判定規則を記録した順に返します
+func (e *Engine) Waived() []Triggered {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]Triggered(nil), e.waived...)
+}
+
+// Check は、条件を満たさない場合に判定規則に該当したことを記録します. 該当しなければnilを返します
+func (e *Engine) Check(rule Rule, ok bool, detail error) error {
+	if ok {
+		return nil
+	}
+	return e.Trigger(rule, detail)
+}
+
+// Triggered は、該当した判定規則を記録した順に返します
+func (e *Engine) Triggered() []Triggered {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]Triggered(nil), e.triggered...)
+}
+
+// RuleIDs は、該当した判定規則のIDを重複を除いて記録した順に返します
+func (e *Engine) RuleIDs() []RuleID {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var ids []RuleID
+	seen := make(map[RuleID]struct{}, len(e.triggered))
+	for _, t := range e.triggered {
+		if _, ok := seen[t.Rule.ID]; ok {
+			continue
+		}
+		seen[t.Rule.ID] = struct{}{}
+		ids = append(ids, t.Rule.ID)
+	}
+	return ids
+}
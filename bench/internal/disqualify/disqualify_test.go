@@ -0,0 +1,63 @@
+package disqualify
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEngine(t *testing.T) {
+	engine := NewEngine()
+
+	assert.NoError(t, engine.Check(InitializeTimeout, true, errors.New("所要時間: 1s")))
+	assert.Empty(t, engine.RuleIDs())
+
+	err := engine.Check(InitializeTimeout, false, errors.New("所要時間: 60s"))
+	assert.Error(t, err)
+	rule, ok := RuleOf(err)
+	assert.True(t, ok)
+	assert.Equal(t, InitializeTimeout, rule)
+	assert.Contains(t, err.Error(), "[initialize-timeout]")
+
+	// 検出した箇所で付与した規則を優先する
+	cause := errors.New("予約が重複しました")
+	err = engine.Trigger(LoadAborted, fmt.Errorf("走行を中断: %w", Wrap(DoubleBooking, cause)))
+	assert.ErrorIs(t, err, cause)
+	rule, _ = RuleOf(err)
+	assert.Equal(t, DoubleBooking, rule)
+	engine.Trigger(DoubleBooking, cause)
+
+	assert.Equal(t, []RuleID{InitializeTimeout.ID, DoubleBooking.ID}, engine.RuleIDs())
+	triggered := engine.Triggered()
+	assert.Len(t, triggered, 3)
+	assert.Equal(t, "所要時間: 60s", triggered[0].Detail)
+	assert.Equal(t, "予約が重複しました", triggered[1].Detail)
+}
+
+func TestEngine_Waive(t *testing.T) {
+	engine := NewEngine()
+
+	waived, ok := engine.Waive(LoadAborted, fmt.Errorf("走行を中断: %w", Wrap(DoubleBooking, errors.New("予約が重複しました"))))
+	assert.True(t, ok)
+	assert.Equal(t, DoubleBooking, waived.Rule)
+	assert.Equal(t, "予約が重複しました", waived.Detail)
+
+	// 初期化の失敗は走行を続けられない
+	_, ok = engine.Waive(InitializeFailed, errors.New("500 Internal Server Error"))
+	assert.False(t, ok)
+
+	assert.Len(t, engine.Waived(), 1)
+	assert.Empty(t, engine.RuleIDs())
+}
+
+func TestRules_UniqueIDs(t *testing.T) {
+	ids := make(map[RuleID]struct{}, len(Rules))
+	for _, rule := range Rules {
+		assert.NotEmpty(t, rule.Message)
+		_, dup := ids[rule.ID]
+		assert.False(t, dup, rule.ID)
+		ids[rule.ID] = struct{}{}
+	}
+}
@@ -0,0 +1,117 @@
+// failfast は、エラーの予算を超えた負荷走行を早期に打ち切ります
+//
+// 完全に壊れたwebappでも走行時間いっぱいまで負荷をかけると、競技中のベンチマーカーの実行枠を無駄にするためです
+package failfast
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// Condition は、負荷走行を打ち切った条件です
+type Condition string
+
+const (
+	// ConditionErrors は、エラー件数が上限に達したこと
+	ConditionErrors Condition = "errors"
+	// ConditionServerErrorRate は、5xxレスポンスの割合が上限に達したこと
+	ConditionServerErrorRate Condition = "server_error_rate"
+)
+
+// Budget は、負荷走行で許容するエラーの予算です. 0の項目では打ち切りません
+type Budget struct {
+	MaxErrors          int64
+	MaxServerErrorRate float64
+	// レスポンスがこれより少ない間は、5xxの割合では打ち切らない
+	MinRequests int64
+}
+
+// Enabled は、いずれかの予算が設定されているかを返します
+func (b Budget) Enabled() bool {
+	return b.MaxErrors > 0 || b.MaxServerErrorRate > 0
+}
+
+// Counts は、負荷走行を開始してからの観測結果です
+type Counts struct {
+	Errors       int64
+	Requests     int64
+	ServerErrors int64
+}
+
+func (c Counts) ServerErrorRate() float64 {
+	if c.Requests == 0 {
+		return 0
+	}
+	return float64(c.ServerErrors) / float64(c.Requests)
+}
+
+// Exceeded は、予算を超えたため負荷走行を打ち切ったことを表します. 結果に記録します
+type Exceeded struct {
+	Condition       Condition `json:"condition"`
+	Threshold       float64   `json:"threshold"`
+	Errors          int64     `json:"errors"`
+	Requests        int64     `json:"requests"`
+	ServerErrors    int64     `json:"server_errors"`
+	ServerErrorRate float64   `json:"server_error_rate"`
+	// 負荷走行を開始してから打ち切るまでの時間[ms]
+	ElapsedMillis int64 `json:"elapsed_ms"`
+}
+
+func (e *Exceeded) Error() string {
+	switch e.Condition {
+	case ConditionErrors:
+		return fmt.Sprintf("エラーが %d 件に達したため、負荷走行を打ち切りました (上限: %d 件)", e.Errors, int64(e.Threshold))
+	case ConditionServerErrorRate:
+		return fmt.Sprintf("5xxレスポンスの割合が %.1f%% (%d/%d 件) に達したため、負荷走行を打ち切りました (上限: %.1f%%)",
+			e.ServerErrorRate*100, e.ServerErrors, e.Requests, e.Threshold*100)
+	default:
+		return fmt.Sprintf("エラーの予算を超えたため、負荷走行を打ち切りました (%s)", e.Condition)
+	}
+}
+
+// Check は、観測結果が予算を超えていれば、打ち切る条件を返します. 超えていなければnilを返します
+func (b Budget) Check(c Counts, elapsed time.Duration) *Exceeded {
+	exceeded := &Exceeded{
+		Errors:          c.Errors,
+		Requests:        c.Requests,
+		ServerErrors:    c.ServerErrors,
+		ServerErrorRate: c.ServerErrorRate(),
+		ElapsedMillis:   elapsed.Milliseconds(),
+	}
+	switch {
+	case b.MaxErrors > 0 && c.Errors >= b.MaxErrors:
+		exceeded.Condition = ConditionErrors
+		exceeded.Threshold = float64(b.MaxErrors)
+	case b.MaxServerErrorRate > 0 && c.Requests >= b.MinRequests && exceeded.ServerErrorRate >= b.MaxServerErrorRate:
+		exceeded.Condition = ConditionServerErrorRate
+		exceeded.Threshold = b.MaxServerErrorRate
+	default:
+		return nil
+	}
+	return exceeded
+}
+
+var (
+	requests     atomic.Int64
+	serverErrors atomic.Int64
+)
+
+// ObserveResponse は、webappのレスポンスのステータスコードを記録します
+func ObserveResponse(statusCode int) {
+	requests.Add(1)
+	if statusCode >= 500 {
+		serverErrors.Add(1)
+	}
+}
+
+// Reset は、記録したレスポンスを破棄します. 負荷走行の開始時に呼び出します
+func Reset() {
+	requests.Store(0)
+	serverErrors.Store(0)
+}
+
+// Responses は、記録したレスポンスの件数と、そのうち5xxの件数を返します
+func Responses() (int64, int64) {
+	return requests.Load(), serverErrors.Load()
+}
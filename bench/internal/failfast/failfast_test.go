@@ -0,0 +1,52 @@
+package failfast
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBudget_Check(t *testing.T) {
+	assert.False(t, Budget{}.Enabled())
+	assert.Nil(t, Budget{}.Check(Counts{Errors: 1000, Requests: 1000, ServerErrors: 1000}, time.Second))
+
+	budget := Budget{MaxErrors: 100, MaxServerErrorRate: 0.1, MinRequests: 200}
+	assert.True(t, budget.Enabled())
+	assert.Nil(t, budget.Check(Counts{Errors: 99, Requests: 1000, ServerErrors: 99}, time.Second))
+
+	exceeded := budget.Check(Counts{Errors: 100, Requests: 1000}, 3*time.Second)
+	if assert.NotNil(t, exceeded) {
+		assert.Equal(t, ConditionErrors, exceeded.Condition)
+		assert.Equal(t, float64(100), exceeded.Threshold)
+		assert.Equal(t, int64(3000), exceeded.ElapsedMillis)
+		assert.Equal(t, "エラーが 100 件に達したため、負荷走行を打ち切りました (上限: 100 件)", exceeded.Error())
+	}
+
+	// レスポンスが少ない間は割合で打ち切らない
+	assert.Nil(t, budget.Check(Counts{Requests: 10, ServerErrors: 10}, time.Second))
+
+	exceeded = budget.Check(Counts{Requests: 400, ServerErrors: 40}, time.Second)
+	if assert.NotNil(t, exceeded) {
+		assert.Equal(t, ConditionServerErrorRate, exceeded.Condition)
+		assert.InDelta(t, 0.1, exceeded.ServerErrorRate, 1e-9)
+		assert.Equal(t, "5xxレスポンスの割合が 10.0% (40/400 件) に達したため、負荷走行を打ち切りました (上限: 10.0%)", exceeded.Error())
+	}
+}
+
+func TestObserveResponse(t *testing.T) {
+	Reset()
+	ObserveResponse(http.StatusOK)
+	ObserveResponse(http.StatusNotFound)
+	ObserveResponse(http.StatusBadGateway)
+
+	total, serverErrors := Responses()
+	assert.Equal(t, int64(3), total)
+	assert.Equal(t, int64(1), serverErrors)
+
+	Reset()
+	total, serverErrors = Responses()
+	assert.Zero(t, total)
+	assert.Zero(t, serverErrors)
+}
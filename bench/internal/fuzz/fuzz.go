@@ -0,0 +1,249 @@
+// fuzz は、エンドポイントのパラメータのモデル(種類と有効範囲)から、範囲内・境界・範囲外の値を生成します
+//
+// 参照実装を公開前に堅牢にするための運営向けの機能で、webappが500系を返したり、
+// 内部のエラー(SQLやスタックトレースなど)をレスポンスに含めたりしないことを確かめます
+package fuzz
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Location は、パラメータを埋め込む位置です
+type Location int
+
+const (
+	InPath Location = iota
+	InQuery
+	InBody
+)
+
+// Kind は、パラメータの種類です. 種類ごとに生成する値が異なります
+type Kind int
+
+const (
+	// 数値のID
+	KindID Kind = iota
+	// 件数の上限
+	KindLimit
+	// UNIX時刻
+	KindTimestamp
+	// 金額などの0以上の数値
+	KindAmount
+	// ユーザ名などの文字列のID
+	KindName
+)
+
+// Param は、エンドポイントのパラメータのモデルです
+type Param struct {
+	Name string
+	In   Location
+	Kind Kind
+	// 有効な値 (他のパラメータを変化させる際に用いる)
+	Valid string
+	// 数値の有効範囲 (KindName では用いない)
+	Min, Max int64
+}
+
+// Value は、パラメータに与える値です
+type Value struct {
+	// 値の由来 (valid, min-1 など)
+	Label string
+	Text  string
+	// 有効範囲内の値か
+	InRange bool
+}
+
+// Values は、パラメータのモデルから、範囲内・境界・範囲外の値を生成します
+func Values(p Param) []Value {
+	if p.Kind == KindName {
+		return []Value{
+			{Label: "valid", Text: p.Valid, InRange: true},
+			{Label: "unknown", Text: "isupipe-fuzz-unknown-user"},
+			{Label: "long", Text: strings.Repeat("a", 256)},
+			{Label: "quote", Text: "'\""},
+			{Label: "percent", Text: "%"},
+		}
+	}
+
+	values := []Value{
+		{Label: "valid", Text: p.Valid, InRange: true},
+		{Label: "min", Text: strconv.FormatInt(p.Min, 10), InRange: true},
+		{Label: "max", Text: strconv.FormatInt(p.Max, 10), InRange: true},
+		{Label: "min-1", Text: strconv.FormatInt(p.Min-1, 10)},
+		{Label: "max+1", Text: strconv.FormatInt(p.Max+1, 10)},
+		{Label: "negative", Text: "-1"},
+		{Label: "int32-overflow", Text: "2147483648"},
+		{Label: "int64-overflow", Text: "9223372036854775808"},
+		{Label: "float", Text: "1.5"},
+		{Label: "not-a-number", Text: "abc"},
+		{Label: "empty", Text: ""},
+	}
+	if p.Kind == KindTimestamp {
+		values = append(values, Value{Label: "year-10000", Text: "253402300800"})
+	}
+
+	// NOTE: 境界と重なる値は省く
+	seen := make(map[string]struct{}, len(values))
+	deduped := values[:0]
+	for _, v := range values {
+		if _, ok := seen[v.Text]; ok {
+			continue
+		}
+		seen[v.Text] = struct{}{}
+		deduped = append(deduped, v)
+	}
+	return deduped
+}
+
+// Endpoint は、エンドポイントのモデルです
+type Endpoint struct {
+	Method string
+	// パスパラメータを {name} で埋め込んだパス
+	Path   string
+	Params []Param
+	// パラメータ以外のボディのフィールド
+	Body map[string]any
+}
+
+// Name は、レポートに用いるエンドポイント名です
+func (e *Endpoint) Name() string {
+	return fmt.Sprintf("%s %s", e.Method, e.Path)
+}
+
+// Case は、1回のリクエストです
+type Case struct {
+	Endpoint *Endpoint
+	// 変化させたパラメータと値 (ボディ全体を壊す場合は Param が空)
+	Param string
+	Value Value
+	// リクエスト
+	Path string
+	Body []byte
+}
+
+// Label は、ケースを表す文字列です
+func (c *Case) Label() string {
+	if len(c.Param) == 0 {
+		return c.Value.Label
+	}
+	return fmt.Sprintf("%s=%s", c.Param, c.Value.Label)
+}
+
+// Cases は、パラメータを1つずつ変化させたケースを生成します. 他のパラメータには有効な値を用います
+// パラメータを持たないエンドポイントには、有効なリクエストのケースを1つ生成します
+// ボディを持つエンドポイントには、不正なJSONと空のボディのケースも加えます
+func Cases(e *Endpoint) []*Case {
+	var cases []*Case
+	if len(e.Params) == 0 {
+		cases = append(cases, e.newCase("", Value{Label: "valid", InRange: true}))
+	}
+	for _, p := range e.Params {
+		for _, v := range Values(p) {
+			cases = append(cases, e.newCase(p.Name, v))
+		}
+	}
+	if e.hasBody() {
+		for _, v := range []Value{
+			{Label: "malformed-json", Text: `{"`},
+			{Label: "empty-body", Text: ""},
+			{Label: "array-body", Text: "[]"},
+		} {
+			c := e.newCase("", Value{})
+			c.Value = v
+			c.Body = []byte(v.Text)
+			cases = append(cases, c)
+		}
+	}
+	return cases
+}
+
+func (e *Endpoint) hasBody() bool {
+	if len(e.Body) > 0 {
+		return true
+	}
+	for _, p := range e.Params {
+		if p.In == InBody {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *Endpoint) newCase(name string, value Value) *Case {
+	path := e.Path
+	query := url.Values{}
+	body := make(map[string]any, len(e.Body))
+	for k, v := range e.Body {
+		body[k] = v
+	}
+
+	for _, p := range e.Params {
+		text := p.Valid
+		if p.Name == name {
+			text = value.Text
+		}
+		switch p.In {
+		case InPath:
+			path = strings.ReplaceAll(path, fmt.Sprintf("{%s}", p.Name), url.PathEscape(text))
+		case InQuery:
+			query.Set(p.Name, text)
+		case InBody:
+			body[p.Name] = bodyValue(p.Kind, text)
+		}
+	}
+	if len(query) > 0 {
+		path = fmt.Sprintf("%s?%s", path, query.Encode())
+	}
+
+	c := &Case{
+		Endpoint: e,
+		Param:    name,
+		Value:    value,
+		Path:     path,
+	}
+	if e.hasBody() {
+		// NOTE: map[string]any のMarshalは失敗しない
+		c.Body, _ = json.Marshal(body)
+	}
+	return c
+}
+
+// bodyValue は、ボディに埋め込む値を返します. 数値として解釈できるものは数値のまま埋め込みます
+func bodyValue(kind Kind, text string) any {
+	if kind != KindName && len(text) > 0 && json.Valid([]byte(text)) {
+		return json.RawMessage(text)
+	}
+	return text
+}
+
+// 内部のエラーが漏れていることを示す文字列 (小文字で比較する)
+var leakPatterns = []string{
+	"sql:",
+	"sqlstate",
+	"error 1064",
+	"you have an error in your sql syntax",
+	"mysql",
+	"goroutine ",
+	"panic",
+	"runtime error",
+	"traceback (most recent call last)",
+	"stack trace",
+	"exception in thread",
+	"/home/isucon/",
+}
+
+// DetectLeak は、レスポンスボディに内部のエラーが含まれていれば、該当する文字列を返します
+func DetectLeak(body []byte) string {
+	lower := bytes.ToLower(body)
+	for _, pattern := range leakPatterns {
+		if bytes.Contains(lower, []byte(pattern)) {
+			return pattern
+		}
+	}
+	return ""
+}
@@ -0,0 +1,126 @@
+package fuzz
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testEndpoint = &Endpoint{
+	Method: http.MethodPost,
+	Path:   "/api/livestream/{livestream_id}/livecomment",
+	Params: []Param{
+		{Name: "livestream_id", In: InPath, Kind: KindID, Valid: "7", Min: 1, Max: 100},
+		{Name: "tip", In: InBody, Kind: KindAmount, Valid: "0", Min: 0, Max: 20000},
+	},
+	Body: map[string]any{"comment": "こんにちは"},
+}
+
+func TestValues(t *testing.T) {
+	values := Values(Param{Kind: KindLimit, Valid: "10", Min: 1, Max: 50})
+	labels := make(map[string]Value)
+	for _, v := range values {
+		labels[v.Label] = v
+	}
+	assert.Equal(t, "0", labels["min-1"].Text)
+	assert.Equal(t, "51", labels["max+1"].Text)
+	assert.True(t, labels["max"].InRange)
+	assert.False(t, labels["not-a-number"].InRange)
+	assert.NotContains(t, labels, "year-10000")
+
+	// 境界と重なる値は省く
+	values = Values(Param{Kind: KindAmount, Valid: "0", Min: 0, Max: 100})
+	var zeros, negatives int
+	for _, v := range values {
+		switch v.Text {
+		case "0":
+			zeros++
+		case "-1":
+			negatives++
+		}
+	}
+	assert.Equal(t, 1, zeros)
+	assert.Equal(t, 1, negatives)
+}
+
+func TestCases(t *testing.T) {
+	cases := Cases(testEndpoint)
+
+	var pathCase, bodyCase, malformed *Case
+	for _, c := range cases {
+		switch c.Label() {
+		case "livestream_id=max+1":
+			pathCase = c
+		case "tip=not-a-number":
+			bodyCase = c
+		case "malformed-json":
+			malformed = c
+		}
+	}
+
+	if assert.NotNil(t, pathCase) {
+		assert.Equal(t, "/api/livestream/101/livecomment", pathCase.Path)
+		assert.JSONEq(t, `{"comment":"こんにちは","tip":0}`, string(pathCase.Body))
+	}
+	if assert.NotNil(t, bodyCase) {
+		assert.Equal(t, "/api/livestream/7/livecomment", bodyCase.Path)
+		assert.JSONEq(t, `{"comment":"こんにちは","tip":"abc"}`, string(bodyCase.Body))
+	}
+	if assert.NotNil(t, malformed) {
+		assert.Equal(t, `{"`, string(malformed.Body))
+	}
+
+	query := Cases(&Endpoint{
+		Method: http.MethodGet,
+		Path:   "/api/livestream/search",
+		Params: []Param{{Name: "limit", In: InQuery, Kind: KindLimit, Valid: "10", Min: 1, Max: 50}},
+	})
+	assert.Equal(t, "/api/livestream/search?limit=10", query[0].Path)
+	assert.Nil(t, query[0].Body)
+
+	// パラメータを持たないエンドポイントも、有効なリクエストを送る
+	noParams := Cases(&Endpoint{Method: http.MethodGet, Path: "/api/tag"})
+	if assert.Len(t, noParams, 1) {
+		assert.Equal(t, "valid", noParams[0].Label())
+		assert.Equal(t, "/api/tag", noParams[0].Path)
+		assert.Nil(t, noParams[0].Body)
+	}
+	withBody := Cases(&Endpoint{Method: http.MethodPost, Path: "/api/icon", Body: map[string]any{"image": "aWNvbg=="}})
+	if assert.Len(t, withBody, 4) {
+		assert.Equal(t, "valid", withBody[0].Label())
+		assert.JSONEq(t, `{"image":"aWNvbg=="}`, string(withBody[0].Body))
+	}
+}
+
+func TestDetectLeak(t *testing.T) {
+	assert.Empty(t, DetectLeak([]byte(`{"message":"livestream not found"}`)))
+	assert.Equal(t, "sql:", DetectLeak([]byte(`{"message":"failed to get livestream: sql: no rows in result set"}`)))
+	assert.Equal(t, "goroutine ", DetectLeak([]byte("goroutine 1 [running]:")))
+}
+
+func TestReport(t *testing.T) {
+	report := NewReport()
+	cases := Cases(testEndpoint)
+	byLabel := make(map[string]*Case)
+	for _, c := range cases {
+		byLabel[c.Label()] = c
+	}
+
+	report.Record(byLabel["livestream_id=valid"], http.StatusCreated, []byte(`{}`), nil)
+	report.Record(byLabel["livestream_id=max+1"], http.StatusNotFound, []byte(`{"message":"sql: no rows"}`), nil)
+	report.Record(byLabel["tip=min-1"], http.StatusCreated, []byte(`{}`), nil)
+	report.Record(byLabel["malformed-json"], http.StatusInternalServerError, nil, nil)
+	report.Record(byLabel["tip=float"], 0, nil, errors.New("connection reset"))
+
+	endpoints := report.Endpoints()
+	assert.Len(t, endpoints, 1)
+	er := endpoints[0]
+	assert.Equal(t, "POST /api/livestream/{livestream_id}/livecomment", er.Endpoint)
+	assert.Equal(t, 5, er.Cases)
+	assert.Equal(t, map[int]int{0: 1, 201: 2, 404: 1, 500: 1}, er.Statuses)
+	assert.Equal(t, []string{"tip=min-1"}, er.AcceptedOutOfRange)
+	assert.Len(t, er.Violations, 3)
+	assert.Equal(t, 3, report.NumViolations())
+}
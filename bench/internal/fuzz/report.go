@@ -0,0 +1,102 @@
+package fuzz
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Violation は、webappが満たすべき性質に反したケースです
+type Violation struct {
+	Case   string `json:"case"`
+	Path   string `json:"path"`
+	Status int    `json:"status,omitempty"`
+	Reason string `json:"reason"`
+}
+
+// EndpointReport は、エンドポイントごとの結果です
+type EndpointReport struct {
+	Endpoint string `json:"endpoint"`
+	Cases    int    `json:"cases"`
+	// ステータスコードごとのケース数 (リクエスト自体が失敗した場合は0)
+	Statuses map[int]int `json:"statuses"`
+	// 範囲外の値を2xxで受け付けたケース (仕様上許容されうるため、違反とはしない)
+	AcceptedOutOfRange []string    `json:"accepted_out_of_range,omitempty"`
+	Violations         []Violation `json:"violations,omitempty"`
+}
+
+// Report は、エンドポイントごとの結果を集計します
+type Report struct {
+	mu        sync.Mutex
+	endpoints map[string]*EndpointReport
+}
+
+func NewReport() *Report {
+	return &Report{
+		endpoints: make(map[string]*EndpointReport),
+	}
+}
+
+// Record は、ケースの結果を記録します. status はリクエスト自体が失敗した場合は0です
+func (r *Report) Record(c *Case, status int, body []byte, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	name := c.Endpoint.Name()
+	er, ok := r.endpoints[name]
+	if !ok {
+		er = &EndpointReport{
+			Endpoint: name,
+			Statuses: make(map[int]int),
+		}
+		r.endpoints[name] = er
+	}
+	er.Cases++
+	er.Statuses[status]++
+
+	violation := Violation{Case: c.Label(), Path: c.Path, Status: status}
+	switch {
+	case err != nil:
+		violation.Reason = fmt.Sprintf("リクエストが失敗しました: %s", err.Error())
+	case status >= 500:
+		violation.Reason = fmt.Sprintf("ステータスコード %d が返されました", status)
+	default:
+		if leak := DetectLeak(body); len(leak) > 0 {
+			violation.Reason = fmt.Sprintf("レスポンスに内部のエラーが含まれています (%q)", leak)
+		}
+	}
+	if len(violation.Reason) > 0 {
+		er.Violations = append(er.Violations, violation)
+		return
+	}
+	if len(c.Param) > 0 && !c.Value.InRange && status >= 200 && status < 300 {
+		er.AcceptedOutOfRange = append(er.AcceptedOutOfRange, c.Label())
+	}
+}
+
+// Endpoints は、エンドポイント名の順に結果を返します
+func (r *Report) Endpoints() []*EndpointReport {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	reports := make([]*EndpointReport, 0, len(r.endpoints))
+	for _, er := range r.endpoints {
+		reports = append(reports, er)
+	}
+	sort.Slice(reports, func(i, j int) bool {
+		return reports[i].Endpoint < reports[j].Endpoint
+	})
+	return reports
+}
+
+// NumViolations は、全エンドポイントの違反の数を返します
+func (r *Report) NumViolations() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var n int
+	for _, er := range r.endpoints {
+		n += len(er.Violations)
+	}
+	return n
+}
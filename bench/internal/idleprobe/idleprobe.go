@@ -0,0 +1,221 @@
+// idleprobe は、keep-aliveの接続を一定時間放置し、webappのアイドルタイムアウトの挙動を確かめます
+//
+// 放置中にwebappが接続を閉じた場合はその時刻と閉じ方(FIN/RST)を、閉じなかった場合は同じ接続で
+// 再度リクエストを送り、レスポンスの途中で接続が閉じられないかを記録します
+package idleprobe
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"syscall"
+	"time"
+)
+
+// Target は、確かめる宛先です
+type Target struct {
+	// 接続先 (host:port)
+	Addr string
+	// Hostヘッダ (TLSの場合はSNIにも用いる)
+	Host string
+	// 送信するリクエストのパス (冪等なGETに限る)
+	Path string
+	// nilでなければTLSで接続する
+	TLS *tls.Config
+}
+
+// Result は、1回の確認の結果です
+type Result struct {
+	// 放置した時間
+	Period time.Duration `json:"period"`
+	// 放置中にwebappが接続を閉じたか
+	Closed bool `json:"closed"`
+	// 閉じられるまでの時間 (Closedの場合のみ)
+	ClosedAfter time.Duration `json:"closed_after,omitempty"`
+	// RSTで閉じられたか (Closedの場合のみ)
+	Reset bool `json:"reset,omitempty"`
+	// 閉じられなかった接続で、再度のリクエストが成功したか
+	Reused bool `json:"reused,omitempty"`
+	// 再度のリクエストを送るのと同時に閉じられた (クライアントが再送すべき競合であり、異常ではない)
+	Race bool `json:"race,omitempty"`
+	// 異常な挙動 (リクエスト中の切断など)
+	Violation string `json:"violation,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+var errUnexpectedData = errors.New("放置中の接続にwebappからデータが送られました")
+
+type conn struct {
+	net.Conn
+	br *bufio.Reader
+}
+
+func dialTarget(ctx context.Context, dial func(ctx context.Context, network, addr string) (net.Conn, error), target Target) (*conn, error) {
+	c, err := dial(ctx, "tcp", target.Addr)
+	if err != nil {
+		return nil, err
+	}
+	if target.TLS != nil {
+		cfg := target.TLS.Clone()
+		if len(cfg.ServerName) == 0 {
+			cfg.ServerName = target.Host
+		}
+		// NOTE: HTTP/2ではアイドルタイムアウトの挙動が異なるため、HTTP/1.1に限る
+		cfg.NextProtos = []string{"http/1.1"}
+		tlsConn := tls.Client(c, cfg)
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			c.Close()
+			return nil, err
+		}
+		c = tlsConn
+	}
+	return &conn{Conn: c, br: bufio.NewReader(c)}, nil
+}
+
+// roundTrip は、keep-aliveを要求するリクエストを送り、レスポンスボディを読み切ります
+// レスポンスを1バイトも受け取れなかったかを合わせて返します
+func (c *conn) roundTrip(ctx context.Context, target Target) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target.Path, nil)
+	if err != nil {
+		return false, err
+	}
+	req.Host = target.Host
+	req.Header.Set("Connection", "keep-alive")
+
+	if deadline, ok := ctx.Deadline(); ok {
+		c.SetDeadline(deadline)
+		defer c.SetDeadline(time.Time{})
+	}
+	if err := req.Write(c); err != nil {
+		return true, err
+	}
+	if _, err := c.br.Peek(1); err != nil {
+		return true, err
+	}
+	resp, err := http.ReadResponse(c.br, req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	if _, err := io.Copy(io.Discard, resp.Body); err != nil {
+		return false, err
+	}
+	if resp.Close {
+		return false, fmt.Errorf("keep-aliveを要求したリクエストに対してConnection: closeが返されました")
+	}
+	return false, nil
+}
+
+func isReset(err error) bool {
+	return errors.Is(err, syscall.ECONNRESET)
+}
+
+// Probe は、接続を period の間放置し、webappのアイドルタイムアウトの挙動を確かめます
+func Probe(ctx context.Context, dial func(ctx context.Context, network, addr string) (net.Conn, error), target Target, period time.Duration, timeout time.Duration) Result {
+	result := Result{Period: period}
+
+	reqCtx, cancel := context.WithTimeout(ctx, timeout)
+	c, err := dialTarget(reqCtx, dial, target)
+	if err != nil {
+		cancel()
+		result.Error = err.Error()
+		return result
+	}
+	defer c.Close()
+	_, err = c.roundTrip(reqCtx, target)
+	cancel()
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	// 放置中に閉じられるか、期限まで待つ
+	idleStartAt := time.Now()
+	deadline := idleStartAt.Add(period)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		result.Error = "走行の残り時間が放置する時間より短いため確認できませんでした"
+		return result
+	}
+	c.SetReadDeadline(deadline)
+	_, err = c.br.Peek(1)
+	c.SetReadDeadline(time.Time{})
+	switch {
+	case err == nil:
+		result.Violation = errUnexpectedData.Error()
+		return result
+	case errors.Is(err, os.ErrDeadlineExceeded):
+		// 閉じられなかった
+	case errors.Is(err, io.EOF) || isReset(err):
+		result.Closed = true
+		result.ClosedAfter = time.Since(idleStartAt)
+		result.Reset = isReset(err)
+		return result
+	default:
+		result.Error = err.Error()
+		return result
+	}
+	if err := ctx.Err(); err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	// 同じ接続で再度リクエストを送る
+	reqCtx, cancel = context.WithTimeout(ctx, timeout)
+	defer cancel()
+	noResponse, err := c.roundTrip(reqCtx, target)
+	switch {
+	case err == nil:
+		result.Reused = true
+	case noResponse && (errors.Is(err, io.EOF) || isReset(err) || errors.Is(err, syscall.EPIPE)):
+		result.Race = true
+	default:
+		result.Violation = fmt.Sprintf("放置後のリクエストの途中で接続が閉じられました: %s", err.Error())
+	}
+	return result
+}
+
+// Report は、確認の結果をまとめたものです
+type Report struct {
+	Results []Result `json:"results"`
+	// 観測したアイドルタイムアウト (放置中に閉じられた最短の時間)
+	ObservedIdleTimeout time.Duration `json:"observed_idle_timeout,omitempty"`
+	// 閉じられなかった最長の放置時間
+	LongestSurvivedPeriod time.Duration `json:"longest_survived_period,omitempty"`
+	Violations            []string      `json:"violations,omitempty"`
+	// アイドルタイムアウトがクライアントの想定より短い
+	ShorterThanClient bool `json:"shorter_than_client,omitempty"`
+}
+
+// Summarize は、確認の結果をまとめます
+// clientIdleTimeout はベンチマーカーのHTTPクライアントが接続を使い回す期間で、これより短く閉じられると再送が増えます
+func Summarize(results []Result, clientIdleTimeout time.Duration) *Report {
+	sorted := append([]Result(nil), results...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Period < sorted[j].Period
+	})
+
+	report := &Report{Results: sorted}
+	for _, r := range sorted {
+		if r.Closed && (report.ObservedIdleTimeout == 0 || r.ClosedAfter < report.ObservedIdleTimeout) {
+			report.ObservedIdleTimeout = r.ClosedAfter
+		}
+		if r.Reused {
+			report.LongestSurvivedPeriod = max(report.LongestSurvivedPeriod, r.Period)
+		}
+		if r.Closed && r.Reset {
+			report.Violations = append(report.Violations, fmt.Sprintf("%s 放置した接続がRSTで閉じられました", r.Period))
+		}
+		if len(r.Violation) > 0 {
+			report.Violations = append(report.Violations, fmt.Sprintf("%s 放置: %s", r.Period, r.Violation))
+		}
+	}
+	report.ShorterThanClient = report.ObservedIdleTimeout > 0 && report.ObservedIdleTimeout < clientIdleTimeout
+	return report
+}
@@ -0,0 +1,107 @@
+package idleprobe
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var dialer = &net.Dialer{}
+
+func newServer(t *testing.T, idleTimeout time.Duration, handler http.HandlerFunc) Target {
+	srv := httptest.NewUnstartedServer(handler)
+	srv.Config.IdleTimeout = idleTimeout
+	srv.Start()
+	t.Cleanup(srv.Close)
+	return Target{
+		Addr: srv.Listener.Addr().String(),
+		Host: "pipe.u.isucon.dev",
+		Path: "/api/tag",
+	}
+}
+
+func okHandler(w http.ResponseWriter, r *http.Request) {
+	w.Write([]byte(`{"tags":[]}`))
+}
+
+func TestProbe_Closed(t *testing.T) {
+	target := newServer(t, 100*time.Millisecond, okHandler)
+
+	r := Probe(context.Background(), dialer.DialContext, target, time.Second, time.Second)
+	assert.Empty(t, r.Error)
+	assert.True(t, r.Closed)
+	assert.False(t, r.Reset)
+	assert.GreaterOrEqual(t, r.ClosedAfter, 100*time.Millisecond)
+	assert.Less(t, r.ClosedAfter, time.Second)
+	assert.Empty(t, r.Violation)
+}
+
+func TestProbe_Reused(t *testing.T) {
+	target := newServer(t, time.Second, okHandler)
+
+	r := Probe(context.Background(), dialer.DialContext, target, 50*time.Millisecond, time.Second)
+	assert.Empty(t, r.Error)
+	assert.False(t, r.Closed)
+	assert.True(t, r.Reused)
+	assert.Empty(t, r.Violation)
+}
+
+func TestProbe_MidRequestClose(t *testing.T) {
+	var count int32
+	target := newServer(t, time.Second, func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&count, 1) == 1 {
+			okHandler(w, r)
+			return
+		}
+		// 2回目のリクエストでは、レスポンスの途中で接続を閉じる
+		conn, bufrw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			return
+		}
+		bufrw.WriteString("HTTP/1.1 200 OK\r\nContent-Length: 100\r\n\r\n{\"tags\":")
+		bufrw.Flush()
+		conn.Close()
+	})
+
+	r := Probe(context.Background(), dialer.DialContext, target, 50*time.Millisecond, time.Second)
+	assert.Empty(t, r.Error)
+	assert.False(t, r.Reused)
+	assert.False(t, r.Race)
+	assert.NotEmpty(t, r.Violation)
+}
+
+func TestProbe_ShortDeadline(t *testing.T) {
+	target := newServer(t, time.Second, okHandler)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	r := Probe(ctx, dialer.DialContext, target, time.Second, time.Second)
+	assert.NotEmpty(t, r.Error)
+	assert.False(t, r.Closed)
+}
+
+func TestSummarize(t *testing.T) {
+	report := Summarize([]Result{
+		{Period: 10 * time.Second, Closed: true, ClosedAfter: 3 * time.Second},
+		{Period: 2 * time.Second, Reused: true},
+		{Period: 5 * time.Second, Closed: true, ClosedAfter: 3 * time.Second, Reset: true},
+		{Period: 1 * time.Second, Violation: "途中で閉じられました"},
+	}, 5*time.Second)
+
+	assert.Equal(t, time.Second, report.Results[0].Period)
+	assert.Equal(t, 3*time.Second, report.ObservedIdleTimeout)
+	assert.Equal(t, 2*time.Second, report.LongestSurvivedPeriod)
+	assert.Len(t, report.Violations, 2)
+	assert.True(t, report.ShorterThanClient)
+
+	report = Summarize([]Result{{Period: time.Second, Reused: true}}, 5*time.Second)
+	assert.Zero(t, report.ObservedIdleTimeout)
+	assert.False(t, report.ShorterThanClient)
+	assert.Empty(t, report.Violations)
+}
@@ -0,0 +1,291 @@
+// journal は、シナリオの各イテレーションの開始・終了と対象エンティティを軽量なバイナリ形式で記録します
+//
+// ベンチマーカーがクラッシュした場合でも、実行中だったイテレーションを後から復元できるよう、
+// レコードはバッファリングせずに1件ずつファイルへ書き込みます
+//
+// フォーマット:
+//
+//	ヘッダ: "ISUJ" + バージョン(1byte)
+//	レコード: 種別(1byte) + イテレーションID(uvarint) + 時刻[UnixNano](varint) + 種別ごとの文字列(長さuvarint + バイト列)
+//	  - 開始: シナリオ名
+//	  - 対象: キー, 値
+//	  - 終了: 結果
+//
+// 走行全体の情報(乱数のシードなど)は、イテレーションID 0 の対象レコードとして記録します
+package journal
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+
+	"github.com/isucon/isucon13/bench/internal/redact"
+	"go.uber.org/zap"
+)
+
+const (
+	magic   = "ISUJ"
+	version = 1
+
+	// 終了レコードに記録する結果の最大長
+	maxResultLength = 256
+)
+
+// 終了レコードに記録する結果
+const (
+	ResultOK      = "ok"
+	ResultTimeout = "timeout"
+)
+
+// Kind はレコードの種別です
+type Kind uint8
+
+const (
+	KindBegin Kind = iota + 1
+	KindNote
+	KindEnd
+)
+
+func (k Kind) String() string {
+	switch k {
+	case KindBegin:
+		return "begin"
+	case KindNote:
+		return "note"
+	case KindEnd:
+		return "end"
+	default:
+		return "unknown"
+	}
+}
+
+// 対象エンティティのキー
+const (
+	NoteUser        = "user"
+	NoteLivestream  = "livestream"
+	NoteLivecomment = "livecomment"
+	// リクエストしたエンドポイント (メソッドとパス)
+	NoteEndpoint = "endpoint"
+	// イテレーションを再生する際の乱数のシード
+	NoteSeed = "seed"
+)
+
+// 走行全体の情報のキー
+const (
+	// 走行の乱数のシード
+	RunSeed = "seed"
+)
+
+// runIteration は、走行全体の情報を記録するイテレーションIDです
+// NOTE: イテレーションのIDは1から採番する
+const runIteration = 0
+
+// IterationSeed は、走行のシードとイテレーションIDから、イテレーションを再生する際の乱数のシードを導出します
+// NOTE: 共有の乱数源を消費しないよう、シードから計算で求める
+func IterationSeed(runSeed int64, id uint64) int64 {
+	return runSeed ^ int64(id*0x9e3779b97f4a7c15)
+}
+
+var ErrInvalidHeader = errors.New("ジャーナルのヘッダが不正です")
+
+type Journal struct {
+	mu     sync.Mutex
+	w      io.Writer
+	closer io.Closer
+
+	seq    atomic.Uint64
+	failed atomic.Bool
+}
+
+// NewWriter は、wにヘッダを書き込み、以降のレコードを書き込むジャーナルを生成します
+func NewWriter(w io.Writer) (*Journal, error) {
+	if _, err := w.Write(append([]byte(magic), version)); err != nil {
+		return nil, err
+	}
+	return &Journal{w: w}, nil
+}
+
+// Create は、pathにジャーナルファイルを作成します
+func Create(path string) (*Journal, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	j, err := NewWriter(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	j.closer = f
+	return j, nil
+}
+
+func (j *Journal) Close() error {
+	if j.closer == nil {
+		return nil
+	}
+	return j.closer.Close()
+}
+
+// RecordRun は、走行全体の情報を記録します
+func (j *Journal) RecordRun(key, value string) {
+	j.write(KindNote, runIteration, key, redact.String(value))
+}
+
+// Begin は、シナリオのイテレーション開始を記録します
+func (j *Journal) Begin(scenario string) *Iteration {
+	it := &Iteration{
+		j:  j,
+		id: j.seq.Add(1),
+	}
+	j.write(KindBegin, it.id, scenario)
+	return it
+}
+
+func (j *Journal) write(kind Kind, id uint64, fields ...string) {
+	buf := make([]byte, 0, 64)
+	buf = append(buf, byte(kind))
+	buf = binary.AppendUvarint(buf, id)
+	buf = binary.AppendVarint(buf, time.Now().UnixNano())
+	for _, field := range fields {
+		buf = binary.AppendUvarint(buf, uint64(len(field)))
+		buf = append(buf, field...)
+	}
+
+	// NOTE: レコードが途中で分断されないよう、1回のWriteで書き込む
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if _, err := j.w.Write(buf); err != nil && j.failed.CompareAndSwap(false, true) {
+		// NOTE: 走行は継続するため、最初の失敗のみ記録する
+		zap.S().Warnf("ジャーナルの書き込みに失敗しました: %+v", err)
+	}
+}
+
+// Iteration は、記録中のシナリオのイテレーションです
+// NOTE: nilの場合は何も記録しません
+type Iteration struct {
+	j  *Journal
+	id uint64
+}
+
+func (it *Iteration) ID() uint64 {
+	if it == nil {
+		return 0
+	}
+	return it.id
+}
+
+// Note は、イテレーションの対象となるエンティティを記録します
+func (it *Iteration) Note(key, value string) {
+	if it == nil {
+		return
+	}
+	it.j.write(KindNote, it.id, key, redact.String(value))
+}
+
+// End は、イテレーションの終了を記録します
+func (it *Iteration) End(err error) {
+	if it == nil {
+		return
+	}
+
+	result := ResultOK
+	if err != nil {
+		// NOTE: 切り詰めで秘匿情報の一部だけが残らないよう、先に伏せる
+		result = truncate(redact.String(err.Error()), maxResultLength)
+	}
+	it.j.write(KindEnd, it.id, result)
+}
+
+// truncate は、文字列を n バイト以内に切り詰めます
+// NOTE: エラーメッセージは日本語を含むため、マルチバイト文字の途中では切らない
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// EndTimeout は、タイムアウトによるイテレーションの終了を記録します
+func (it *Iteration) EndTimeout() {
+	if it == nil {
+		return
+	}
+	it.j.write(KindEnd, it.id, ResultTimeout)
+}
+
+var (
+	stdMu sync.RWMutex
+	std   *Journal
+)
+
+// Open は、ベンチマーク走行のジャーナルをpathに作成します
+func Open(path string) error {
+	j, err := Create(path)
+	if err != nil {
+		return err
+	}
+
+	stdMu.Lock()
+	defer stdMu.Unlock()
+	std = j
+	return nil
+}
+
+// Close は、ベンチマーク走行のジャーナルを閉じます
+func Close() error {
+	stdMu.Lock()
+	defer stdMu.Unlock()
+	if std == nil {
+		return nil
+	}
+	err := std.Close()
+	std = nil
+	return err
+}
+
+// RecordRun は、ベンチマーク走行のジャーナルに走行全体の情報を記録します
+// NOTE: ジャーナルを開いていない場合、何も記録しません
+func RecordRun(key, value string) {
+	stdMu.RLock()
+	j := std
+	stdMu.RUnlock()
+	if j == nil {
+		return
+	}
+	j.RecordRun(key, value)
+}
+
+type iterationKey struct{}
+
+// Begin は、ベンチマーク走行のジャーナルにイテレーション開始を記録し、イテレーションを保持したコンテキストを返します
+// NOTE: ジャーナルを開いていない場合、nilのイテレーションを返します
+func Begin(ctx context.Context, scenario string) (context.Context, *Iteration) {
+	stdMu.RLock()
+	j := std
+	stdMu.RUnlock()
+	if j == nil {
+		return ctx, nil
+	}
+
+	it := j.Begin(scenario)
+	return context.WithValue(ctx, iterationKey{}, it), it
+}
+
+// Note は、コンテキストが保持するイテレーションの対象エンティティを記録します
+func Note(ctx context.Context, key, value string) {
+	it, ok := ctx.Value(iterationKey{}).(*Iteration)
+	if !ok {
+		return
+	}
+	it.Note(key, value)
+}
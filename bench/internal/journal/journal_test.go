@@ -0,0 +1,124 @@
+package journal
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJournal_RoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	j, err := NewWriter(&buf)
+	assert.NoError(t, err)
+
+	it1 := j.Begin("basic-viewer")
+	it1.Note(NoteUser, "test001")
+	it1.Note(NoteLivestream, "42")
+	it2 := j.Begin("basic-streamer")
+	it1.End(nil)
+	it2.End(errors.New("failed"))
+	it3 := j.Begin("raid")
+	it3.Note(NoteUser, "test002")
+
+	records, err := ReadAll(&buf)
+	assert.NoError(t, err)
+	assert.Len(t, records, 8)
+	assert.Equal(t, KindBegin, records[0].Kind)
+	assert.Equal(t, "basic-viewer", records[0].Scenario)
+	assert.Equal(t, KindNote, records[1].Kind)
+	assert.Equal(t, NoteUser, records[1].Key)
+	assert.Equal(t, "test001", records[1].Value)
+
+	summaries := Summarize(records)
+	assert.Len(t, summaries, 3)
+	assert.False(t, summaries[0].InFlight())
+	assert.Equal(t, ResultOK, summaries[0].Result)
+	assert.Len(t, summaries[0].Notes, 2)
+	assert.Equal(t, "failed", summaries[1].Result)
+	assert.True(t, summaries[2].InFlight())
+	assert.Equal(t, "raid", summaries[2].Scenario)
+}
+
+func TestReadAll_Truncated(t *testing.T) {
+	var buf bytes.Buffer
+	j, err := NewWriter(&buf)
+	assert.NoError(t, err)
+
+	j.Begin("basic-viewer").Note(NoteUser, "test001")
+	b := buf.Bytes()
+
+	// 書き込み途中でクラッシュした場合
+	records, err := ReadAll(bytes.NewReader(b[:len(b)-3]))
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	assert.Len(t, records, 1)
+}
+
+func TestNewReader_InvalidHeader(t *testing.T) {
+	_, err := NewReader(bytes.NewReader([]byte("ISUC")))
+	assert.ErrorIs(t, err, ErrInvalidHeader)
+}
+
+func TestBegin_Context(t *testing.T) {
+	ctx, it := Begin(context.Background(), "basic-viewer")
+	assert.Nil(t, it)
+	Note(ctx, NoteUser, "test001")
+	it.End(nil)
+
+	path := filepath.Join(t.TempDir(), "journal.bin")
+	assert.NoError(t, Open(path))
+
+	ctx, it = Begin(context.Background(), "basic-viewer")
+	Note(ctx, NoteUser, "test001")
+	it.End(nil)
+	assert.NoError(t, Close())
+
+	f, err := os.Open(path)
+	assert.NoError(t, err)
+	defer f.Close()
+	records, err := ReadAll(f)
+	assert.NoError(t, err)
+	assert.Len(t, records, 3)
+	assert.Equal(t, "test001", records[1].Value)
+}
+
+func TestRunNotes(t *testing.T) {
+	var buf bytes.Buffer
+	j, err := NewWriter(&buf)
+	assert.NoError(t, err)
+
+	j.RecordRun(RunSeed, "42")
+	it := j.Begin("basic-viewer")
+	it.Note(NoteSeed, strconv.FormatInt(IterationSeed(42, it.ID()), 10))
+	it.Note(NoteEndpoint, "GET /api/tag")
+	it.End(nil)
+
+	records, err := ReadAll(&buf)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{RunSeed: "42"}, RunNotes(records))
+
+	// 走行全体の情報はイテレーションに含めない
+	summaries := Summarize(records)
+	assert.Len(t, summaries, 1)
+	seed, ok := summaries[0].Note(NoteSeed)
+	assert.True(t, ok)
+	assert.Equal(t, strconv.FormatInt(IterationSeed(42, 1), 10), seed)
+	assert.NotEqual(t, IterationSeed(42, 1), IterationSeed(42, 2))
+}
+
+func TestTruncate(t *testing.T) {
+	assert.Equal(t, "abc", truncate("abc", 5))
+	assert.Equal(t, "ab", truncate("abc", 2))
+	// マルチバイト文字の途中では切らない
+	assert.Equal(t, "エ", truncate("エラー", 4))
+	assert.Equal(t, "エラ", truncate("エラー", 6))
+	assert.True(t, utf8.ValidString(truncate(strings.Repeat("失敗", 200), maxResultLength)))
+}
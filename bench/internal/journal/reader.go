@@ -0,0 +1,208 @@
+package journal
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+// 1フィールドあたりの最大長 (破損したジャーナルで巨大な領域を確保しないため)
+const maxFieldLength = 1 << 16
+
+// Record は、ジャーナルの1レコードです
+type Record struct {
+	Kind      Kind
+	Iteration uint64
+	Time      time.Time
+
+	// 開始レコードのシナリオ名
+	Scenario string
+	// 対象レコードのキーと値
+	Key   string
+	Value string
+	// 終了レコードの結果
+	Result string
+}
+
+type Reader struct {
+	r *bufio.Reader
+}
+
+// NewReader は、ヘッダを検証した上でジャーナルを読み込むReaderを生成します
+func NewReader(r io.Reader) (*Reader, error) {
+	br := bufio.NewReader(r)
+
+	header := make([]byte, len(magic)+1)
+	if _, err := io.ReadFull(br, header); err != nil {
+		return nil, ErrInvalidHeader
+	}
+	if string(header[:len(magic)]) != magic {
+		return nil, ErrInvalidHeader
+	}
+	if header[len(magic)] != version {
+		return nil, fmt.Errorf("未対応のジャーナルのバージョンです: %d", header[len(magic)])
+	}
+
+	return &Reader{r: br}, nil
+}
+
+// Next は次のレコードを返します
+// 末尾に達した場合は io.EOF を、書き込み途中で途切れたレコードの場合は io.ErrUnexpectedEOF を返します
+func (r *Reader) Next() (*Record, error) {
+	kind, err := r.r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+
+	record := &Record{Kind: Kind(kind)}
+	if record.Iteration, err = binary.ReadUvarint(r.r); err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	nanos, err := binary.ReadVarint(r.r)
+	if err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	record.Time = time.Unix(0, nanos)
+
+	switch record.Kind {
+	case KindBegin:
+		record.Scenario, err = r.readString()
+	case KindNote:
+		if record.Key, err = r.readString(); err == nil {
+			record.Value, err = r.readString()
+		}
+	case KindEnd:
+		record.Result, err = r.readString()
+	default:
+		return nil, fmt.Errorf("不明なレコード種別です: %d", kind)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return record, nil
+}
+
+func (r *Reader) readString() (string, error) {
+	n, err := binary.ReadUvarint(r.r)
+	if err != nil {
+		return "", unexpectedEOF(err)
+	}
+	if n > maxFieldLength {
+		return "", fmt.Errorf("フィールド長が不正です: %d", n)
+	}
+
+	b := make([]byte, n)
+	if _, err := io.ReadFull(r.r, b); err != nil {
+		return "", unexpectedEOF(err)
+	}
+	return string(b), nil
+}
+
+func unexpectedEOF(err error) error {
+	if errors.Is(err, io.EOF) {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// ReadAll はジャーナルの全レコードを読み込みます
+// NOTE: クラッシュ時は末尾のレコードが途切れうるため、io.ErrUnexpectedEOF の場合もそれまでのレコードを返します
+func ReadAll(r io.Reader) ([]*Record, error) {
+	reader, err := NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []*Record
+	for {
+		record, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			return records, nil
+		}
+		if err != nil {
+			return records, err
+		}
+		records = append(records, record)
+	}
+}
+
+// IterationSummary は、1イテレーション分のレコードをまとめたものです
+type IterationSummary struct {
+	ID       uint64
+	Scenario string
+	Begin    time.Time
+	// 終了していない場合はゼロ値
+	End    time.Time
+	Result string
+	Notes  []*Record
+}
+
+func (s *IterationSummary) InFlight() bool {
+	return s.End.IsZero()
+}
+
+// Note は、キーに対応する最初の対象レコードの値を返します
+func (s *IterationSummary) Note(key string) (string, bool) {
+	for _, note := range s.Notes {
+		if note.Key == key {
+			return note.Value, true
+		}
+	}
+	return "", false
+}
+
+// RunNotes は、走行全体の情報をキーごとに返します
+func RunNotes(records []*Record) map[string]string {
+	notes := make(map[string]string)
+	for _, record := range records {
+		if record.Iteration == runIteration && record.Kind == KindNote {
+			notes[record.Key] = record.Value
+		}
+	}
+	return notes
+}
+
+// Summarize は、レコードをイテレーションごとにまとめ、開始順に返します
+// NOTE: 走行全体の情報は含めない (RunNotes で取得する)
+func Summarize(records []*Record) []*IterationSummary {
+	summaries := make(map[uint64]*IterationSummary)
+	get := func(id uint64) *IterationSummary {
+		s, ok := summaries[id]
+		if !ok {
+			s = &IterationSummary{ID: id}
+			summaries[id] = s
+		}
+		return s
+	}
+
+	for _, record := range records {
+		if record.Iteration == runIteration {
+			continue
+		}
+		s := get(record.Iteration)
+		switch record.Kind {
+		case KindBegin:
+			s.Scenario = record.Scenario
+			s.Begin = record.Time
+		case KindNote:
+			s.Notes = append(s.Notes, record)
+		case KindEnd:
+			s.End = record.Time
+			s.Result = record.Result
+		}
+	}
+
+	result := make([]*IterationSummary, 0, len(summaries))
+	for _, s := range summaries {
+		result = append(result, s)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ID < result[j].ID
+	})
+	return result
+}
@@ -0,0 +1,100 @@
+package jsondiff
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// Difference は、2つのJSONの値の差分1件です
+// NOTE: 片方にしか存在しないフィールドは、存在しない側をnilとします
+type Difference struct {
+	Path string `json:"path"`
+	A    any    `json:"a"`
+	B    any    `json:"b"`
+}
+
+func (d Difference) String() string {
+	return fmt.Sprintf("%s: a=%s, b=%s", d.Path, formatValue(d.A), formatValue(d.B))
+}
+
+func formatValue(v any) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(b)
+}
+
+// Diff は、aとbをJSONとして比較し、フィールド単位の差分をパス順に返します
+// 配列は添字ごとに比較します
+func Diff(a, b any) ([]Difference, error) {
+	normalizedA, err := normalize(a)
+	if err != nil {
+		return nil, err
+	}
+	normalizedB, err := normalize(b)
+	if err != nil {
+		return nil, err
+	}
+
+	var diffs []Difference
+	compare("$", normalizedA, normalizedB, &diffs)
+	return diffs, nil
+}
+
+// normalize は、構造体などをJSONの汎用的な表現(map, slice, float64, string, bool, nil)に変換します
+func normalize(v any) (any, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var normalized any
+	if err := json.Unmarshal(b, &normalized); err != nil {
+		return nil, err
+	}
+	return normalized, nil
+}
+
+func compare(path string, a, b any, diffs *[]Difference) {
+	switch a := a.(type) {
+	case map[string]any:
+		b, ok := b.(map[string]any)
+		if !ok {
+			break
+		}
+		keys := make(map[string]struct{}, len(a)+len(b))
+		for key := range a {
+			keys[key] = struct{}{}
+		}
+		for key := range b {
+			keys[key] = struct{}{}
+		}
+		sortedKeys := make([]string, 0, len(keys))
+		for key := range keys {
+			sortedKeys = append(sortedKeys, key)
+		}
+		sort.Strings(sortedKeys)
+		for _, key := range sortedKeys {
+			compare(path+"."+key, a[key], b[key], diffs)
+		}
+		return
+	case []any:
+		b, ok := b.([]any)
+		if !ok {
+			break
+		}
+		if len(a) != len(b) {
+			*diffs = append(*diffs, Difference{Path: path + ".length", A: len(a), B: len(b)})
+		}
+		for i := 0; i < min(len(a), len(b)); i++ {
+			compare(fmt.Sprintf("%s[%d]", path, i), a[i], b[i], diffs)
+		}
+		return
+	}
+
+	if !reflect.DeepEqual(a, b) {
+		*diffs = append(*diffs, Difference{Path: path, A: a, B: b})
+	}
+}
@@ -0,0 +1,39 @@
+package jsondiff
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type user struct {
+	ID   int64    `json:"id"`
+	Name string   `json:"name"`
+	Tags []string `json:"tags"`
+}
+
+func TestDiff(t *testing.T) {
+	diffs, err := Diff(&user{ID: 1, Name: "a", Tags: []string{"x"}}, &user{ID: 1, Name: "a", Tags: []string{"x"}})
+	assert.NoError(t, err)
+	assert.Empty(t, diffs)
+
+	diffs, err = Diff(
+		&user{ID: 1, Name: "a", Tags: []string{"x", "y"}},
+		&user{ID: 2, Name: "a", Tags: []string{"z"}},
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, []Difference{
+		{Path: "$.id", A: float64(1), B: float64(2)},
+		{Path: "$.tags.length", A: 2, B: 1},
+		{Path: "$.tags[0]", A: "x", B: "z"},
+	}, diffs)
+	assert.Equal(t, `$.tags[0]: a="x", b="z"`, diffs[2].String())
+
+	// 片方にしかないフィールド、型の異なる値
+	diffs, err = Diff(map[string]any{"a": 1, "b": "1"}, map[string]any{"b": 1})
+	assert.NoError(t, err)
+	assert.Equal(t, []Difference{
+		{Path: "$.a", A: float64(1), B: nil},
+		{Path: "$.b", A: "1", B: float64(1)},
+	}, diffs)
+}
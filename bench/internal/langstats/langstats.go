@@ -0,0 +1,151 @@
+// langstats は、複数回の走行結果を実装言語ごとに集計します
+//
+// 競技後の分析で、言語ごとの走行数・スコア・よくあるエラーを運営が手作業でまとめていたものを置き換えます
+package langstats
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// UnknownLanguage は、初期化に失敗するなどして言語が分からない走行の集計名です
+const UnknownLanguage = "unknown"
+
+// 言語ごとに記録するよくあるメッセージの数
+const NumCommonMessages = 5
+
+// Run は、集計に用いる走行結果の項目です (ベンチマーカーの結果JSONのうち必要なもの)
+type Run struct {
+	Pass        bool             `json:"pass"`
+	Score       int64            `json:"score"`
+	Messages    []string         `json:"messages"`
+	Language    string           `json:"language"`
+	ErrorCounts map[string]int64 `json:"error_counts"`
+}
+
+// MessageCount は、メッセージとそれが含まれた走行の数です
+type MessageCount struct {
+	Message string `json:"message"`
+	Runs    int    `json:"runs"`
+}
+
+// LanguageSummary は、言語ごとの集計です
+type LanguageSummary struct {
+	Language string `json:"language"`
+	Runs     int    `json:"runs"`
+	Passed   int    `json:"passed"`
+	// スコアは合格した走行のみで集計する
+	MeanScore   float64 `json:"mean_score"`
+	MedianScore int64   `json:"median_score"`
+	MaxScore    int64   `json:"max_score"`
+	// カテゴリ別のエラー件数の合計
+	ErrorCounts map[string]int64 `json:"error_counts"`
+	// 多くの走行に含まれたメッセージ
+	CommonMessages []MessageCount `json:"common_messages"`
+}
+
+// Summary は、走行結果全体の集計です
+type Summary struct {
+	Runs      int               `json:"runs"`
+	Languages []LanguageSummary `json:"languages"`
+}
+
+// Decode は、結果JSONを読み込みます. 1つのJSONでも、複数のJSONを連結したもの(JSONL)でもよい
+func Decode(r io.Reader) ([]Run, error) {
+	var runs []Run
+	dec := json.NewDecoder(r)
+	for {
+		var run Run
+		if err := dec.Decode(&run); err == io.EOF {
+			return runs, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("走行結果の形式が不正です: %w", err)
+		}
+		runs = append(runs, run)
+	}
+}
+
+// Aggregate は、走行結果を言語ごとに集計します. 言語は走行数の多い順に並べます
+func Aggregate(runs []Run) *Summary {
+	type group struct {
+		summary  LanguageSummary
+		scores   []int64
+		messages map[string]int
+	}
+	groups := make(map[string]*group)
+	for _, run := range runs {
+		lang := run.Language
+		if len(lang) == 0 {
+			lang = UnknownLanguage
+		}
+		g, ok := groups[lang]
+		if !ok {
+			g = &group{
+				summary: LanguageSummary{
+					Language:    lang,
+					ErrorCounts: make(map[string]int64),
+				},
+				messages: make(map[string]int),
+			}
+			groups[lang] = g
+		}
+
+		g.summary.Runs++
+		if run.Pass {
+			g.summary.Passed++
+			g.scores = append(g.scores, run.Score)
+		}
+		for category, n := range run.ErrorCounts {
+			g.summary.ErrorCounts[category] += n
+		}
+		// NOTE: 1回の走行で同じメッセージが複数回出ても1回と数える
+		seen := make(map[string]struct{}, len(run.Messages))
+		for _, msg := range run.Messages {
+			if _, ok := seen[msg]; ok {
+				continue
+			}
+			seen[msg] = struct{}{}
+			g.messages[msg]++
+		}
+	}
+
+	summary := &Summary{Runs: len(runs)}
+	for _, g := range groups {
+		s := g.summary
+		if len(g.scores) > 0 {
+			sort.Slice(g.scores, func(i, j int) bool { return g.scores[i] < g.scores[j] })
+			var total int64
+			for _, score := range g.scores {
+				total += score
+			}
+			s.MeanScore = float64(total) / float64(len(g.scores))
+			s.MedianScore = g.scores[len(g.scores)/2]
+			s.MaxScore = g.scores[len(g.scores)-1]
+		}
+		s.CommonMessages = commonMessages(g.messages)
+		summary.Languages = append(summary.Languages, s)
+	}
+	sort.Slice(summary.Languages, func(i, j int) bool {
+		if summary.Languages[i].Runs != summary.Languages[j].Runs {
+			return summary.Languages[i].Runs > summary.Languages[j].Runs
+		}
+		return summary.Languages[i].Language < summary.Languages[j].Language
+	})
+	return summary
+}
+
+func commonMessages(messages map[string]int) []MessageCount {
+	counts := make([]MessageCount, 0, len(messages))
+	for msg, n := range messages {
+		counts = append(counts, MessageCount{Message: msg, Runs: n})
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Runs != counts[j].Runs {
+			return counts[i].Runs > counts[j].Runs
+		}
+		return counts[i].Message < counts[j].Message
+	})
+	return counts[:min(len(counts), NumCommonMessages)]
+}
@@ -0,0 +1,52 @@
+package langstats
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecode(t *testing.T) {
+	runs, err := Decode(strings.NewReader(`{"pass":true,"score":100,"language":"go"}
+{"pass":false,"score":0,"language":"","messages":["初期化が失敗しました"]}
+`))
+	assert.NoError(t, err)
+	assert.Len(t, runs, 2)
+	assert.Equal(t, "go", runs[0].Language)
+	assert.Equal(t, []string{"初期化が失敗しました"}, runs[1].Messages)
+
+	_, err = Decode(strings.NewReader(`{"pass":`))
+	assert.Error(t, err)
+}
+
+func TestAggregate(t *testing.T) {
+	summary := Aggregate([]Run{
+		{Pass: true, Score: 100, Language: "go", ErrorCounts: map[string]int64{"timeout": 3}, Messages: []string{"a", "a", "b"}},
+		{Pass: true, Score: 300, Language: "go", ErrorCounts: map[string]int64{"timeout": 1, "validation": 2}, Messages: []string{"a"}},
+		{Pass: true, Score: 200, Language: "go"},
+		{Pass: false, Score: 0, Language: "go", Messages: []string{"c"}},
+		{Pass: true, Score: 50, Language: "rust"},
+		{Pass: false, Language: ""},
+	})
+
+	assert.Equal(t, 6, summary.Runs)
+	assert.Len(t, summary.Languages, 3)
+
+	golang := summary.Languages[0]
+	assert.Equal(t, "go", golang.Language)
+	assert.Equal(t, 4, golang.Runs)
+	assert.Equal(t, 3, golang.Passed)
+	assert.Equal(t, 200.0, golang.MeanScore)
+	assert.Equal(t, int64(200), golang.MedianScore)
+	assert.Equal(t, int64(300), golang.MaxScore)
+	assert.Equal(t, map[string]int64{"timeout": 4, "validation": 2}, golang.ErrorCounts)
+	assert.Equal(t, []MessageCount{{"a", 2}, {"b", 1}, {"c", 1}}, golang.CommonMessages)
+
+	// 走行数が同じ場合は言語名順
+	assert.Equal(t, "rust", summary.Languages[1].Language)
+	unknown := summary.Languages[2]
+	assert.Equal(t, UnknownLanguage, unknown.Language)
+	assert.Equal(t, 0, unknown.Passed)
+	assert.Zero(t, unknown.MeanScore)
+}
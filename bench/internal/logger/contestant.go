@@ -0,0 +1,121 @@
+package logger
+
+import (
+	"net"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/isucon/isucon13/bench/internal/redact"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// ContestantLevel は、選手向けログの出力レベルです
+// NOTE: --contestant-log-level オプションによって変更されます
+var ContestantLevel = zap.NewAtomicLevelAt(zapcore.InfoLevel)
+
+// InternalHostMask は、選手向けログでベンチマーカー内部のホスト名を置き換える文字列です
+const InternalHostMask = "[internal]"
+
+// ベンチマーカー内部のホスト名やアドレス
+// NOTE: ホスト名のラベルやIPアドレスのオクテットの途中では一致させない
+var internalHosts = redact.NewBoundaryMatcher(InternalHostMask, isHostByte)
+
+func isHostByte(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || c == '-' || c == '_'
+}
+
+// RegisterInternalHosts は、選手向けログで伏せるベンチマーカー内部のホスト名やアドレスを登録します
+// ポートを含む場合はホスト部分のみを登録します. ループバックや未指定のアドレスは、選手の環境と区別できないため登録しません
+func RegisterInternalHosts(hosts ...string) {
+	for _, host := range hosts {
+		host = strings.TrimSpace(host)
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if len(host) == 0 || host == "localhost" {
+			continue
+		}
+		if ip := net.ParseIP(host); ip != nil && (ip.IsLoopback() || ip.IsUnspecified()) {
+			continue
+		}
+		internalHosts.Register(host)
+	}
+}
+
+// ResetInternalHosts は、登録したホスト名を破棄します
+func ResetInternalHosts() {
+	internalHosts.Reset()
+}
+
+// スタックトレースの始まり
+// goroutineのダンプ、または関数名に続く "\t/path/to/file.go:123" の行
+var stackTraceStart = regexp.MustCompile(`(?m)^goroutine \d+ \[|^[^\n]+\n\t[^\n]+\.go:\d+`)
+
+// SanitizeContestant は、選手向けログに書き出す文字列から、スタックトレースとベンチマーカー内部のホスト名を取り除きます
+func SanitizeContestant(s string) string {
+	if len(s) == 0 {
+		return s
+	}
+	if loc := stackTraceStart.FindStringIndex(s); loc != nil {
+		s = strings.TrimRight(s[:loc[0]], " \t\r\n")
+	}
+	s = internalHosts.Replace(s)
+	return s
+}
+
+// contestantCore は、選手向けログに書き込む前に、スタッフ向けにのみ出力すべき内部の情報を取り除くzapcore.Coreです
+// NOTE: 呼び出し側で選手向けとスタッフ向けを書き分ける前提に頼らず、選手向けロガー自体で防ぐ
+type contestantCore struct {
+	zapcore.Core
+}
+
+func wrapContestantCore(c zapcore.Core) zapcore.Core {
+	return &contestantCore{Core: c}
+}
+
+func (c *contestantCore) With(fields []zapcore.Field) zapcore.Core {
+	return &contestantCore{Core: c.Core.With(sanitizeFields(fields))}
+}
+
+func (c *contestantCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *contestantCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	ent.Message = SanitizeContestant(ent.Message)
+	ent.Stack = ""
+	return c.Core.Write(ent, sanitizeFields(fields))
+}
+
+func sanitizeFields(fields []zapcore.Field) []zapcore.Field {
+	sanitized := make([]zapcore.Field, len(fields))
+	for i, f := range fields {
+		switch f.Type {
+		case zapcore.StringType:
+			f.String = SanitizeContestant(f.String)
+		case zapcore.ErrorType:
+			// NOTE: %+v で書き出されるスタックトレース(errorVerbose)を含めないよう、文字列にする
+			if err, ok := f.Interface.(error); ok && err != nil {
+				f = zap.String(f.Key, SanitizeContestant(err.Error()))
+			}
+		case zapcore.ByteStringType:
+			if b, ok := f.Interface.([]byte); ok {
+				f = zap.ByteString(f.Key, []byte(SanitizeContestant(string(b))))
+			}
+		}
+		sanitized[i] = f
+	}
+	return sanitized
+}
+
+// registerDefaultInternalHosts は、設定によらず伏せるベンチマーカー自身のホスト名を登録します
+func registerDefaultInternalHosts() {
+	if hostname, err := os.Hostname(); err == nil {
+		RegisterInternalHosts(hostname)
+	}
+}
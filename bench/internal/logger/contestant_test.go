@@ -0,0 +1,88 @@
+package logger
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/isucon/isucon13/bench/internal/config"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestSanitizeContestant(t *testing.T) {
+	defer ResetInternalHosts()
+	ResetInternalHosts()
+	RegisterInternalHosts("bench-01.internal:9090", "bench-01", "127.0.0.1:8080", "localhost", "")
+
+	assert.Equal(t, "GET /api/tag: 接続に失敗しました ([internal] から送信)", SanitizeContestant("GET /api/tag: 接続に失敗しました (bench-01.internal から送信)"))
+	assert.Equal(t, "[internal]:9090 127.0.0.1:8080", SanitizeContestant("bench-01:9090 127.0.0.1:8080"))
+
+	// ラベルやオクテットの途中では置き換えない
+	RegisterInternalHosts("10.0.0.1", "bench")
+	assert.Equal(t, "[internal] 10.0.0.12 110.0.0.1", SanitizeContestant("10.0.0.1 10.0.0.12 110.0.0.1"))
+	assert.Equal(t, "[internal].local bench2 mybench", SanitizeContestant("bench.local bench2 mybench"))
+
+	withStack := "panic: unexpected\n\ngoroutine 1 [running]:\nmain.main()\n\t/home/bench/main.go:12 +0x1d\n"
+	assert.Equal(t, "panic: unexpected", SanitizeContestant(withStack))
+
+	withFrames := "failed to decode\ngithub.com/isucon/isucon13/bench/isupipe.(*Client).GetTags\n\t/home/bench/isupipe/client_tag.go:42"
+	assert.Equal(t, "failed to decode", SanitizeContestant(withFrames))
+}
+
+func TestInitContestantLoggerLevel(t *testing.T) {
+	defer func(path, level string) {
+		config.ContestantLogPath, config.ContestantLogLevel = path, level
+		ResetInternalHosts()
+	}(config.ContestantLogPath, config.ContestantLogLevel)
+
+	config.ContestantLogPath = filepath.Join(t.TempDir(), "contestant.log")
+	config.ContestantLogLevel = "invalid"
+	_, err := InitContestantLogger()
+	assert.Error(t, err)
+
+	config.ContestantLogLevel = "warn"
+	RegisterInternalHosts("bench-01.internal")
+	contestantLogger, err := InitContestantLogger()
+	assert.NoError(t, err)
+
+	contestantLogger.Info("info")
+	contestantLogger.Warn("warn", zap.Error(errors.New("bench-01.internal に接続できません")))
+	contestantLogger.Sync()
+
+	b, err := os.ReadFile(config.ContestantLogPath)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(b), "\tinfo")
+	assert.Contains(t, string(b), "\twarn")
+	assert.Contains(t, string(b), "[internal] に接続できません")
+	assert.NotContains(t, string(b), "bench-01.internal")
+}
+
+func TestInitContestantLoggerJSON(t *testing.T) {
+	defer func(path string, jsonLogs bool) {
+		config.ContestantLogPath, config.JSONLogs = path, jsonLogs
+		ResetInternalHosts()
+	}(config.ContestantLogPath, config.JSONLogs)
+
+	config.ContestantLogPath = filepath.Join(t.TempDir(), "contestant.log")
+	config.JSONLogs = true
+	assert.Equal(t, EncodingJSON, CurrentEncoding())
+	RegisterInternalHosts("bench-01.internal")
+	contestantLogger, err := InitContestantLogger()
+	assert.NoError(t, err)
+
+	contestantLogger.Warn("途中経過", zap.Int64("score", 100), zap.Error(errors.New("bench-01.internal に接続できません")))
+	contestantLogger.Sync()
+
+	b, err := os.ReadFile(config.ContestantLogPath)
+	assert.NoError(t, err)
+	var entry map[string]any
+	assert.NoError(t, json.Unmarshal(b, &entry))
+	assert.Equal(t, "warn", entry["level"])
+	assert.Equal(t, "途中経過", entry["msg"])
+	assert.Equal(t, float64(100), entry["score"])
+	assert.Equal(t, "[internal] に接続できません", entry["error"])
+	assert.NotEmpty(t, entry["ts"])
+}
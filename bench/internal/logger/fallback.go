@@ -0,0 +1,63 @@
+package logger
+
+import (
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// Fallback は、書き込めなかったためにログの出力先から外したファイルです
+type Fallback struct {
+	Logger string `json:"logger"`
+	Path   string `json:"path"`
+	Error  string `json:"error"`
+}
+
+var (
+	fallbacksMu sync.Mutex
+	fallbacks   []Fallback
+)
+
+// Fallbacks は、出力先から外したファイルを返します
+func Fallbacks() []Fallback {
+	fallbacksMu.Lock()
+	defer fallbacksMu.Unlock()
+	return append([]Fallback(nil), fallbacks...)
+}
+
+// buildWithFallback は、ロガーを生成します
+// 書き込めない出力先(コンテナで/tmpに書き込めないなど)があれば、走行を中断せずにその出力先を外し、
+// 出力先がなくなった場合は fallbackPath (標準エラー出力など) に出力します
+func buildWithFallback(name string, c zap.Config, fallbackPath string, opts ...zap.Option) (*zap.Logger, error) {
+	var (
+		paths   []string
+		dropped []Fallback
+	)
+	for _, path := range c.OutputPaths {
+		_, closeFn, err := zap.Open(path)
+		if err != nil {
+			dropped = append(dropped, Fallback{Logger: name, Path: path, Error: err.Error()})
+			continue
+		}
+		closeFn()
+		paths = append(paths, path)
+	}
+	if len(paths) == 0 {
+		paths = []string{fallbackPath}
+	}
+	c.OutputPaths = paths
+
+	l, err := c.Build(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	fallbacksMu.Lock()
+	fallbacks = append(fallbacks, dropped...)
+	fallbacksMu.Unlock()
+	for _, f := range dropped {
+		l.Warn("ログファイルに書き込めないため、出力先から外しました", zap.String("path", f.Path), zap.String("error", f.Error))
+	}
+
+	return l, nil
+}
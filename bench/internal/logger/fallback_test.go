@@ -0,0 +1,41 @@
+package logger
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/isucon/isucon13/bench/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInitLoggerFallback(t *testing.T) {
+	defer func(staff, contestant string) {
+		config.StaffLogPath, config.ContestantLogPath = staff, contestant
+		fallbacks = nil
+	}(config.StaffLogPath, config.ContestantLogPath)
+
+	dir := t.TempDir()
+	config.StaffLogPath = filepath.Join(dir, "staff.log")
+	// 存在しないディレクトリには書き込めない
+	config.ContestantLogPath = filepath.Join(dir, "missing", "contestant.log")
+
+	lgr, err := InitStaffLogger()
+	assert.NoError(t, err)
+	contestantLogger, err := InitContestantLogger()
+	assert.NoError(t, err)
+
+	lgr.Info("staff")
+	contestantLogger.Info("contestant")
+	lgr.Sync()
+
+	b, err := os.ReadFile(config.StaffLogPath)
+	assert.NoError(t, err)
+	assert.Contains(t, string(b), "staff")
+
+	got := Fallbacks()
+	assert.Len(t, got, 1)
+	assert.Equal(t, "contestant", got[0].Logger)
+	assert.Equal(t, config.ContestantLogPath, got[0].Path)
+	assert.NotEmpty(t, got[0].Error)
+}
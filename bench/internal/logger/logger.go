@@ -1,26 +1,57 @@
 package logger
 
 import (
+	"fmt"
+	"strings"
+
 	"github.com/isucon/isucon13/bench/internal/config"
+	"github.com/isucon/isucon13/bench/internal/redact"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
 
 const loggerName = "isupipe-benchmarker"
 
+// StaffLevel は、スタッフ向けログの出力レベルです
+// NOTE: 走行中に管理APIから変更できます
+var StaffLevel = zap.NewAtomicLevelAt(zapcore.InfoLevel)
+
+// Encoding は、ログの出力形式です
+type Encoding string
+
+const (
+	EncodingConsole Encoding = "console"
+	EncodingJSON    Encoding = "json"
+)
+
+// CurrentEncoding は、ログの出力形式を返します
+func CurrentEncoding() Encoding {
+	if config.JSONLogs {
+		return EncodingJSON
+	}
+	return EncodingConsole
+}
+
+// newConfig は、現在の出力形式でロガーの設定を返します
+// NOTE: JSONでも時刻はISO8601で書き出し、ポータル側でコンソール形式と同じように解釈できるようにする
+func newConfig() zap.Config {
+	c := zap.NewProductionConfig()
+	c.Encoding = string(CurrentEncoding())
+	c.EncoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
+	c.Sampling = nil
+	return c
+}
+
 // InitZapLogger はzapロガーを初期化します
 func InitStaffLogger() (*zap.SugaredLogger, error) {
-	c := zap.NewProductionConfig()
-	c.Encoding = "console"
+	c := newConfig()
 	c.DisableCaller = false
 	c.DisableStacktrace = true
-	c.Level = zap.NewAtomicLevelAt(zapcore.InfoLevel)
-	c.EncoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
+	c.Level = StaffLevel
 	c.OutputPaths = []string{config.StaffLogPath, "stderr"}
 	c.ErrorOutputPaths = []string{"stderr"}
-	c.Sampling = nil
 
-	l, err := c.Build()
+	l, err := buildWithFallback("staff", c, "stderr", zap.WrapCore(redact.WrapCore))
 	if err != nil {
 		return nil, err
 	}
@@ -32,17 +63,14 @@ func InitStaffLogger() (*zap.SugaredLogger, error) {
 
 // InitZapLogger はzapロガーを初期化します
 func InitTestLogger() (*zap.Logger, error) {
-	c := zap.NewProductionConfig()
-	c.Encoding = "console"
+	c := newConfig()
 	c.DisableCaller = false
 	c.DisableStacktrace = true
 	c.Level = zap.NewAtomicLevelAt(zapcore.InfoLevel)
-	c.EncoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
 	c.OutputPaths = []string{"stderr"}
 	c.ErrorOutputPaths = []string{"stderr"}
-	c.Sampling = nil
 
-	l, err := c.Build()
+	l, err := c.Build(zap.WrapCore(redact.WrapCore))
 	if err != nil {
 		return nil, err
 	}
@@ -50,18 +78,28 @@ func InitTestLogger() (*zap.Logger, error) {
 	return l.Named("test-logger"), nil
 }
 
+// InitContestantLogger は選手向けのzapロガーを初期化します
+// スタックトレースやベンチマーカー内部のホスト名は、選手向けログには書き出しません
 func InitContestantLogger() (*zap.Logger, error) {
-	c := zap.NewProductionConfig()
-	c.Encoding = "console"
+	level, err := zapcore.ParseLevel(config.ContestantLogLevel)
+	if err != nil {
+		return nil, fmt.Errorf("選手向けログの出力レベル(--contestant-log-level)が不正です: %w", err)
+	}
+	ContestantLevel.SetLevel(level)
+
+	registerDefaultInternalHosts()
+	RegisterInternalHosts(strings.Split(config.InternalHosts, ",")...)
+
+	c := newConfig()
 	c.DisableCaller = true
 	c.DisableStacktrace = true
-	c.Level = zap.NewAtomicLevelAt(zapcore.InfoLevel)
-	c.EncoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
+	c.Level = ContestantLevel
 	c.OutputPaths = []string{config.ContestantLogPath, "stdout"}
 	c.ErrorOutputPaths = []string{"stdout"}
-	c.Sampling = nil
 
-	l, err := c.Build()
+	l, err := buildWithFallback("contestant", c, "stderr", zap.WrapCore(func(c zapcore.Core) zapcore.Core {
+		return wrapContestantCore(redact.WrapCore(c))
+	}))
 	if err != nil {
 		return nil, err
 	}
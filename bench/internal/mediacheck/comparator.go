@@ -0,0 +1,170 @@
+package mediacheck
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"math/bits"
+	"sort"
+)
+
+// Comparator は、バイナリのレスポンス(画像など)が期待するものと一致するか検証します
+// 大会の仕様に応じて、再エンコードを許容するか禁止するかを検証ごとに選択できるようにするためのものです
+type Comparator interface {
+	Name() string
+	Compare(expected, actual []byte) error
+}
+
+const (
+	ComparatorExact      = "exact"
+	ComparatorPerceptual = "perceptual"
+	ComparatorSize       = "size"
+)
+
+// 知覚ハッシュで同一画像とみなすハミング距離の上限 (64bit中)
+const DefaultPerceptualHashDistance = 10
+
+var comparators = map[string]Comparator{
+	ComparatorExact:      ExactHashComparator{},
+	ComparatorPerceptual: PerceptualHashComparator{MaxDistance: DefaultPerceptualHashDistance},
+	ComparatorSize:       SizeComparator{},
+}
+
+// Get は、名前に対応するComparatorを返します
+func Get(name string) (Comparator, error) {
+	comparator, ok := comparators[name]
+	if !ok {
+		return nil, fmt.Errorf("未知の比較方式です: %s (指定可能な方式: %v)", name, Names())
+	}
+	return comparator, nil
+}
+
+// Names は、指定可能な比較方式の名前を返します
+func Names() []string {
+	names := make([]string, 0, len(comparators))
+	for name := range comparators {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ExactHashComparator は、SHA-256ハッシュが完全に一致することを検証します
+// 再エンコードを禁止する場合に用います
+type ExactHashComparator struct{}
+
+func (ExactHashComparator) Name() string {
+	return ComparatorExact
+}
+
+func (ExactHashComparator) Compare(expected, actual []byte) error {
+	expectedHash := sha256.Sum256(expected)
+	actualHash := sha256.Sum256(actual)
+	if !bytes.Equal(expectedHash[:], actualHash[:]) {
+		return fmt.Errorf("ハッシュ値が一致しません (expected:%x, actual:%x)", expectedHash, actualHash)
+	}
+	return nil
+}
+
+// PerceptualHashComparator は、画像の見た目が同じであることを知覚ハッシュ(average hash)で検証します
+// 再エンコードや画質変更を許容する場合に用います
+type PerceptualHashComparator struct {
+	MaxDistance int
+}
+
+func (PerceptualHashComparator) Name() string {
+	return ComparatorPerceptual
+}
+
+func (c PerceptualHashComparator) Compare(expected, actual []byte) error {
+	expectedImg, err := decode(expected)
+	if err != nil {
+		return fmt.Errorf("期待する画像をデコードできません: %w", err)
+	}
+	actualImg, err := decode(actual)
+	if err != nil {
+		return fmt.Errorf("画像としてデコードできません: %w", err)
+	}
+
+	distance := bits.OnesCount64(averageHash(expectedImg) ^ averageHash(actualImg))
+	if distance > c.MaxDistance {
+		return fmt.Errorf("画像の見た目が一致しません (知覚ハッシュの距離:%d, 許容:%d)", distance, c.MaxDistance)
+	}
+	return nil
+}
+
+// SizeComparator は、画像の幅と高さのみが一致することを検証します
+// 画像の内容を問わず、サイズの変更のみを禁止する場合に用います
+type SizeComparator struct{}
+
+func (SizeComparator) Name() string {
+	return ComparatorSize
+}
+
+func (SizeComparator) Compare(expected, actual []byte) error {
+	expectedConfig, _, err := image.DecodeConfig(bytes.NewReader(expected))
+	if err != nil {
+		return fmt.Errorf("期待する画像をデコードできません: %w", err)
+	}
+	actualConfig, _, err := image.DecodeConfig(bytes.NewReader(actual))
+	if err != nil {
+		return fmt.Errorf("画像としてデコードできません: %w", err)
+	}
+
+	if expectedConfig.Width != actualConfig.Width || expectedConfig.Height != actualConfig.Height {
+		return fmt.Errorf("画像のサイズが一致しません (expected:%dx%d, actual:%dx%d)",
+			expectedConfig.Width, expectedConfig.Height, actualConfig.Width, actualConfig.Height)
+	}
+	return nil
+}
+
+func decode(b []byte) (image.Image, error) {
+	img, _, err := image.Decode(bytes.NewReader(b))
+	return img, err
+}
+
+// averageHash は、画像を8x8のグレースケールに縮小し、平均より明るい画素を1とした64bitのハッシュを返します
+func averageHash(img image.Image) uint64 {
+	const size = 8
+
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+
+	var (
+		pixels [size * size]float64
+		total  float64
+	)
+	for i := 0; i < size*size; i++ {
+		// 各マスに対応する領域の平均輝度を求める
+		x0 := bounds.Min.X + (i%size)*width/size
+		x1 := bounds.Min.X + (i%size+1)*width/size
+		y0 := bounds.Min.Y + (i/size)*height/size
+		y1 := bounds.Min.Y + (i/size+1)*height/size
+
+		var (
+			sum float64
+			n   int
+		)
+		for y := y0; y < max(y1, y0+1); y++ {
+			for x := x0; x < max(x1, x0+1); x++ {
+				r, g, b, _ := img.At(x, y).RGBA()
+				sum += 0.299*float64(r) + 0.587*float64(g) + 0.114*float64(b)
+				n++
+			}
+		}
+		pixels[i] = sum / float64(n)
+		total += pixels[i]
+	}
+
+	mean := total / float64(size*size)
+	var hash uint64
+	for i, pixel := range pixels {
+		if pixel > mean {
+			hash |= 1 << uint(i)
+		}
+	}
+	return hash
+}
@@ -0,0 +1,58 @@
+package mediacheck
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func encodeJPEG(t *testing.T, img image.Image, quality int) []byte {
+	var buf bytes.Buffer
+	assert.NoError(t, jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality}))
+	return buf.Bytes()
+}
+
+func gradientImage(width, height int, inverted bool) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			v := uint8((x + y) * 255 / (width + height))
+			if inverted {
+				v = 255 - v
+			}
+			img.Set(x, y, color.RGBA{R: v, G: v / 2, B: 255 - v, A: 255})
+		}
+	}
+	return img
+}
+
+func TestComparators(t *testing.T) {
+	original := encodeJPEG(t, gradientImage(64, 64, false), 90)
+	reencoded := encodeJPEG(t, gradientImage(64, 64, false), 40)
+	different := encodeJPEG(t, gradientImage(64, 64, true), 90)
+	resized := encodeJPEG(t, gradientImage(32, 32, false), 90)
+
+	exact, err := Get(ComparatorExact)
+	assert.NoError(t, err)
+	assert.NoError(t, exact.Compare(original, original))
+	assert.Error(t, exact.Compare(original, reencoded))
+
+	perceptual, err := Get(ComparatorPerceptual)
+	assert.NoError(t, err)
+	assert.NoError(t, perceptual.Compare(original, reencoded))
+	assert.NoError(t, perceptual.Compare(original, resized))
+	assert.Error(t, perceptual.Compare(original, different))
+	assert.Error(t, perceptual.Compare(original, []byte("not an image")))
+
+	size, err := Get(ComparatorSize)
+	assert.NoError(t, err)
+	assert.NoError(t, size.Compare(original, different))
+	assert.Error(t, size.Compare(original, resized))
+
+	_, err = Get("unknown")
+	assert.Error(t, err)
+}
@@ -0,0 +1,119 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// リクエストの所要時間のヒストグラムのバケット[秒]
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// HistogramVec は、ラベルの組ごとのヒストグラムです
+type HistogramVec struct {
+	name       string
+	help       string
+	labelNames []string
+	buckets    []float64
+
+	mu         sync.Mutex
+	histograms map[string]*histogram
+}
+
+type histogram struct {
+	labelValues []string
+	counts      []uint64
+	count       uint64
+	sum         float64
+}
+
+func NewHistogramVec(name, help string, labelNames []string, buckets []float64) *HistogramVec {
+	return &HistogramVec{
+		name:       name,
+		help:       help,
+		labelNames: labelNames,
+		buckets:    buckets,
+		histograms: make(map[string]*histogram),
+	}
+}
+
+// Observe は、ラベルの組に対応するヒストグラムに所要時間を記録します
+func (v *HistogramVec) Observe(d time.Duration, labelValues ...string) {
+	key := strings.Join(labelValues, "\x00")
+	seconds := d.Seconds()
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	h, ok := v.histograms[key]
+	if !ok {
+		h = &histogram{
+			labelValues: labelValues,
+			counts:      make([]uint64, len(v.buckets)),
+		}
+		v.histograms[key] = h
+	}
+	for i, upper := range v.buckets {
+		if seconds <= upper {
+			h.counts[i]++
+		}
+	}
+	h.count++
+	h.sum += seconds
+}
+
+// Reset は、記録したヒストグラムをすべて破棄します
+func (v *HistogramVec) Reset() {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.histograms = make(map[string]*histogram)
+}
+
+// Write は、Prometheusのテキスト形式でヒストグラムを書き出します
+func (v *HistogramVec) Write(w io.Writer) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	fmt.Fprintf(w, "# HELP %s %s\n", v.name, v.help)
+	fmt.Fprintf(w, "# TYPE %s histogram\n", v.name)
+
+	keys := make([]string, 0, len(v.histograms))
+	for key := range v.histograms {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		h := v.histograms[key]
+		labels := FormatLabels(v.labelNames, h.labelValues)
+		for i, upper := range v.buckets {
+			fmt.Fprintf(w, "%s_bucket%s %d\n", v.name, withLabel(labels, "le", strconv.FormatFloat(upper, 'g', -1, 64)), h.counts[i])
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", v.name, withLabel(labels, "le", "+Inf"), h.count)
+		fmt.Fprintf(w, "%s_sum%s %g\n", v.name, labels, h.sum)
+		fmt.Fprintf(w, "%s_count%s %d\n", v.name, labels, h.count)
+	}
+}
+
+// FormatLabels は、ラベルをPrometheusのテキスト形式 {name="value",...} に整形します
+func FormatLabels(names, values []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		pairs[i] = fmt.Sprintf("%s=%s", name, strconv.Quote(values[i]))
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func withLabel(labels, name, value string) string {
+	pair := fmt.Sprintf("%s=%s", name, strconv.Quote(value))
+	if len(labels) == 0 {
+		return "{" + pair + "}"
+	}
+	return labels[:len(labels)-1] + "," + pair + "}"
+}
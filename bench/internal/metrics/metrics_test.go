@@ -0,0 +1,34 @@
+package metrics
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRoute(t *testing.T) {
+	assert.Equal(t, "/api/livestream/:id/livecomment/:id/report", Route("/api/livestream/12/livecomment/345/report"))
+	assert.Equal(t, "/api/user/:username/icon", Route("/api/user/alice/icon"))
+	assert.Equal(t, "/api/user/me", Route("/api/user/me"))
+	assert.Equal(t, "/api/tag", Route("/api/tag"))
+}
+
+func TestHistogramVec(t *testing.T) {
+	v := NewHistogramVec("test_duration_seconds", "テスト", []string{"method"}, []float64{0.1, 1})
+	v.Observe(50*time.Millisecond, "GET")
+	v.Observe(500*time.Millisecond, "GET")
+	v.Observe(2*time.Second, "GET")
+
+	var buf bytes.Buffer
+	v.Write(&buf)
+	assert.Equal(t, `# HELP test_duration_seconds テスト
+# TYPE test_duration_seconds histogram
+test_duration_seconds_bucket{method="GET",le="0.1"} 1
+test_duration_seconds_bucket{method="GET",le="1"} 2
+test_duration_seconds_bucket{method="GET",le="+Inf"} 3
+test_duration_seconds_sum{method="GET"} 2.55
+test_duration_seconds_count{method="GET"} 3
+`, buf.String())
+}
@@ -0,0 +1,36 @@
+package metrics
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RequestDuration は、webappへのリクエストの所要時間です
+var RequestDuration = NewHistogramVec(
+	"isupipe_bench_request_duration_seconds",
+	"webappへのリクエストの所要時間",
+	[]string{"method", "route"},
+	DefaultBuckets,
+)
+
+// ObserveRequest は、リクエストの所要時間を、IDやユーザ名を除いたルートごとに記録します
+func ObserveRequest(method, path string, d time.Duration) {
+	RequestDuration.Observe(d, method, Route(path))
+}
+
+// Route は、パスに含まれるIDやユーザ名をプレースホルダに置き換えます
+// NOTE: ラベルの種類数が走行ごとに増え続けないようにする
+func Route(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if _, err := strconv.ParseInt(segment, 10, 64); err == nil {
+			segments[i] = ":id"
+			continue
+		}
+		if i > 0 && segments[i-1] == "user" && segment != "me" {
+			segments[i] = ":username"
+		}
+	}
+	return strings.Join(segments, "/")
+}
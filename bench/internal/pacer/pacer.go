@@ -0,0 +1,54 @@
+// pacer は、シナリオごとの実行開始をトークンバケットで平準化します
+//
+// ワーカーが一斉にリクエストを発行すると、ベンチマーカー側で待ち行列が生じて計測されるレイテンシが歪むため、
+// シナリオごとに開始のレートを制限し、ゆらぎを加えて開始タイミングを分散させます
+// NOTE: 待ち時間をシナリオのタイムアウトに含めないよう、タイムアウトを設定する前に待つ
+package pacer
+
+import (
+	"context"
+	"time"
+
+	"github.com/isucon/isucon13/bench/internal/benchrand"
+	"golang.org/x/time/rate"
+)
+
+type Pacer struct {
+	limiter *rate.Limiter
+	jitter  time.Duration
+}
+
+// New は、毎秒rate件、最大burst件まで連続で開始できるPacerを生成します
+// jitterが正の場合、トークンを得た後に [0, jitter) のランダムな時間だけ待ちます
+func New(r float64, burst int, jitter time.Duration) *Pacer {
+	if burst < 1 {
+		burst = 1
+	}
+	return &Pacer{
+		limiter: rate.NewLimiter(rate.Limit(r), burst),
+		jitter:  jitter,
+	}
+}
+
+// Wait は、シナリオを開始してよくなるまで待ちます
+// NOTE: nilの場合は待ちません
+func (p *Pacer) Wait(ctx context.Context) error {
+	if p == nil {
+		return nil
+	}
+	if err := p.limiter.Wait(ctx); err != nil {
+		return err
+	}
+	if p.jitter <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(time.Duration(benchrand.Int63() % int64(p.jitter)))
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
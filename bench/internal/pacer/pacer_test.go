@@ -0,0 +1,44 @@
+package pacer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPacer_Rate(t *testing.T) {
+	p := New(100, 1, 0)
+
+	startAt := time.Now()
+	for i := 0; i < 11; i++ {
+		assert.NoError(t, p.Wait(context.Background()))
+	}
+	// 最初の1件以降は10ms間隔で発行される
+	assert.GreaterOrEqual(t, time.Since(startAt), 90*time.Millisecond)
+}
+
+func TestPacer_Jitter(t *testing.T) {
+	p := New(1000, 10, 20*time.Millisecond)
+
+	startAt := time.Now()
+	for i := 0; i < 5; i++ {
+		assert.NoError(t, p.Wait(context.Background()))
+	}
+	assert.Less(t, time.Since(startAt), 5*20*time.Millisecond+50*time.Millisecond)
+}
+
+func TestPacer_Nil(t *testing.T) {
+	var p *Pacer
+	assert.NoError(t, p.Wait(context.Background()))
+}
+
+func TestPacer_Canceled(t *testing.T) {
+	p := New(1, 1, 0)
+	ctx, cancel := context.WithCancel(context.Background())
+	assert.NoError(t, p.Wait(ctx))
+
+	cancel()
+	assert.Error(t, p.Wait(ctx))
+}
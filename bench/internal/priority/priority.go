@@ -0,0 +1,109 @@
+// priority は、負荷走行のシナリオを、スコアを稼ぐスループットのワーカーと、整合性を確かめる検証のワーカーに分け、
+// webappが飽和していても検証のシナリオが実行されるよう、スループットのワーカーの起動を譲らせます
+//
+// 飽和したwebappでは検証のシナリオのリクエストもスループットのリクエストと同じく待たされ、
+// タイムアウトで打ち切られて、本来検出すべき仕様違反を見逃してしまうためです
+package priority
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Class は、シナリオのワーカーの種類です
+type Class string
+
+const (
+	// ClassThroughput は、スコアを稼ぐシナリオ
+	ClassThroughput Class = "throughput"
+	// ClassVerification は、整合性を確かめるシナリオ
+	ClassVerification Class = "verification"
+)
+
+// Scheduler は、実行中の検証のシナリオを追跡し、スループットのワーカーの起動を認めるか判定します
+type Scheduler struct {
+	// 検証のシナリオがタイムアウトのこの割合を超えて実行中であれば、webappが飽和しているとみなす
+	yieldRatio float64
+
+	mu sync.Mutex
+	// 実行中の検証のシナリオが、飽和しているとみなされる時刻
+	verifying map[uint64]time.Time
+	seq       uint64
+	// 飽和しているとみなし始めた時刻 (飽和していなければゼロ値)
+	yieldingSince time.Time
+
+	// スループットのワーカーの起動を譲った回数と時間の合計
+	yields        atomic.Int64
+	yieldDuration atomic.Int64
+}
+
+func NewScheduler(yieldRatio float64) *Scheduler {
+	return &Scheduler{
+		yieldRatio: yieldRatio,
+		verifying:  make(map[uint64]time.Time),
+	}
+}
+
+// BeginVerification は、タイムアウトが timeout の検証のシナリオの開始を記録し、終了を記録する関数を返します
+func (s *Scheduler) BeginVerification(now time.Time, timeout time.Duration) (end func(now time.Time)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.seq++
+	id := s.seq
+	s.verifying[id] = now.Add(time.Duration(float64(timeout) * s.yieldRatio))
+
+	var once sync.Once
+	return func(now time.Time) {
+		once.Do(func() {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			delete(s.verifying, id)
+			s.updateLocked(now)
+		})
+	}
+}
+
+// AdmitThroughput は、スループットのワーカーを新たに起動してよいかを返します
+// NOTE: 検証のシナリオがタイムアウトに近づいている間は起動させず、実行中のワーカーがクライアントやwebappの処理能力を手放すのを待つ
+func (s *Scheduler) AdmitThroughput(now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return !s.updateLocked(now)
+}
+
+// updateLocked は、飽和しているかを判定し、譲っている時間を記録します
+func (s *Scheduler) updateLocked(now time.Time) bool {
+	saturated := false
+	for _, yieldAt := range s.verifying {
+		if !now.Before(yieldAt) {
+			saturated = true
+			break
+		}
+	}
+
+	switch {
+	case saturated && s.yieldingSince.IsZero():
+		s.yieldingSince = now
+		s.yields.Add(1)
+	case !saturated && !s.yieldingSince.IsZero():
+		s.yieldDuration.Add(int64(now.Sub(s.yieldingSince)))
+		s.yieldingSince = time.Time{}
+	}
+	return saturated
+}
+
+// Stats は、スループットのワーカーの起動を譲った回数と時間の合計です
+type Stats struct {
+	Yields        int64
+	YieldDuration time.Duration
+}
+
+// Stats は、これまでに譲った回数と時間の合計を返します
+// NOTE: 譲っている最中の時間は含めない
+func (s *Scheduler) Stats() Stats {
+	return Stats{
+		Yields:        s.yields.Load(),
+		YieldDuration: time.Duration(s.yieldDuration.Load()),
+	}
+}
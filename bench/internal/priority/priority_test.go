@@ -0,0 +1,46 @@
+package priority
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScheduler_AdmitThroughput(t *testing.T) {
+	s := NewScheduler(0.5)
+	start := time.Unix(0, 0)
+
+	assert.True(t, s.AdmitThroughput(start))
+
+	end := s.BeginVerification(start, 10*time.Second)
+	assert.True(t, s.AdmitThroughput(start.Add(4*time.Second)))
+	// タイムアウトの半分を超えて実行中なら、スループットのワーカーを起動させない
+	assert.False(t, s.AdmitThroughput(start.Add(5*time.Second)))
+	assert.False(t, s.AdmitThroughput(start.Add(6*time.Second)))
+
+	end(start.Add(7 * time.Second))
+	assert.True(t, s.AdmitThroughput(start.Add(7*time.Second)))
+	// 2回目以降の呼び出しは無視する
+	end(start.Add(8 * time.Second))
+
+	assert.Equal(t, Stats{Yields: 1, YieldDuration: 2 * time.Second}, s.Stats())
+}
+
+func TestScheduler_MultipleVerifications(t *testing.T) {
+	s := NewScheduler(0.5)
+	start := time.Unix(0, 0)
+
+	end1 := s.BeginVerification(start, 10*time.Second)
+	end2 := s.BeginVerification(start, 20*time.Second)
+	assert.False(t, s.AdmitThroughput(start.Add(5*time.Second)))
+
+	// まだタイムアウトに近づいていない検証のシナリオだけが残れば、起動を再開する
+	end1(start.Add(6 * time.Second))
+	assert.True(t, s.AdmitThroughput(start.Add(6*time.Second)))
+	assert.False(t, s.AdmitThroughput(start.Add(10*time.Second)))
+	end2(start.Add(12 * time.Second))
+	assert.True(t, s.AdmitThroughput(start.Add(12*time.Second)))
+
+	assert.Equal(t, Stats{Yields: 2, YieldDuration: 3 * time.Second}, s.Stats())
+}
@@ -13,17 +13,27 @@ type PubSub struct {
 	itemCh    chan interface{}
 	processCh chan chan interface{}
 	closeOnce sync.Once
+	// Runに渡したcontext. 分配の停止後に、アイテムを戻す処理を終える
+	runCtx context.Context
 }
 
 func NewPubSub(itemCapacity int) *PubSub {
 	return &PubSub{
 		itemCh:    make(chan interface{}, itemCapacity),
 		processCh: make(chan chan interface{}),
+		runCtx:    context.Background(),
 	}
 }
 
 // NOTE: 誰もSubscriberがいない状態で、itemだけたくさん書き出し続けているとブロックする場合があります(通常想定されない)
 func (p *PubSub) Publish(ctx context.Context, v interface{}) error {
+	// NOTE: ctxが終了済みでも、空きがあればアイテムを失わないよう優先して書き込む
+	select {
+	case p.itemCh <- v:
+		return nil
+	default:
+	}
+
 	select {
 	case <-ctx.Done():
 		return ctx.Err()
@@ -34,7 +44,8 @@ func (p *PubSub) Publish(ctx context.Context, v interface{}) error {
 
 // NOTE: アイテム供給ができていないと、Subscribeがブロックする場合があります (通常想定されない)
 func (p *PubSub) Subscribe(ctx context.Context) (interface{}, error) {
-	ch := make(chan interface{})
+	// NOTE: Subscriberが待機をやめた後でもPubSub.Run(context.Context)が停止しないよう、バッファを持たせる
+	ch := make(chan interface{}, 1)
 	select {
 	case <-ctx.Done():
 		return nil, ctx.Err()
@@ -43,6 +54,8 @@ func (p *PubSub) Subscribe(ctx context.Context) (interface{}, error) {
 		// processCh書き込み後に読み込みを行わずに寄り道すると、公平に分配するPubSub.Run(context.Context)が停止する
 		select {
 		case <-ctx.Done():
+			// 分配予定のアイテムが失われないよう、受け取り次第戻す
+			go p.requeue(p.runCtx, ch)
 			return nil, ctx.Err()
 		case v := <-ch:
 			return v, nil
@@ -50,16 +63,37 @@ func (p *PubSub) Subscribe(ctx context.Context) (interface{}, error) {
 	}
 }
 
+// requeue は、待機をやめたSubscriber宛のアイテムを受け取り次第戻します
+// NOTE: ctxが終了した後は分配されないため、アイテムを待たずに終了する
+func (p *PubSub) requeue(ctx context.Context, ch chan interface{}) {
+	select {
+	case <-ctx.Done():
+	case v, ok := <-ch:
+		if !ok {
+			return
+		}
+		select {
+		case <-ctx.Done():
+		case p.itemCh <- v:
+		}
+	}
+}
+
 // Run は、公平にアイテムをSubscriberへ分配します。PublisherやSubScriber動作前に実行しておく必要があります
 func (p *PubSub) Run(ctx context.Context) {
+	p.runCtx = ctx
 	go func() {
 		for {
 			select {
 			case <-ctx.Done():
+				return
 			case subscriberCh := <-p.processCh:
 				select {
 				case <-ctx.Done():
-				case subscriberCh <- (<-p.itemCh):
+					return
+				case v := <-p.itemCh:
+					// NOTE: subscriberChはバッファを持つため、Subscriberが待機をやめていてもブロックしない
+					subscriberCh <- v
 					close(subscriberCh)
 				}
 			}
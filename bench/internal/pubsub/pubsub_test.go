@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -24,3 +25,58 @@ func TestPubSub(t *testing.T) {
 	assert.NoError(t, err)
 	fmt.Println(v)
 }
+
+func TestPubSub_CancelSubscribe(t *testing.T) {
+	pool := NewPubSub(10)
+	pool.Run(context.TODO())
+
+	// アイテムがない状態でSubscriberが待機をやめる
+	ctx, cancel := context.WithTimeout(context.TODO(), 10*time.Millisecond)
+	defer cancel()
+	_, err := pool.Subscribe(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// 待機をやめたSubscriber宛のアイテムが失われず、分配も止まらない
+	item := &Item{}
+	pool.Publish(context.TODO(), item)
+
+	ctx, cancel = context.WithTimeout(context.TODO(), 1*time.Second)
+	defer cancel()
+	v, err := pool.Subscribe(ctx)
+	assert.NoError(t, err)
+	assert.Same(t, item, v)
+}
+
+func TestPubSub_PublishAfterCancel(t *testing.T) {
+	pool := NewPubSub(10)
+	pool.Run(context.TODO())
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	cancel()
+	assert.NoError(t, pool.Publish(ctx, &Item{}))
+}
+
+func TestPubSub_RequeueStopsWithRun(t *testing.T) {
+	pool := NewPubSub(10)
+	ctx, cancel := context.WithCancel(context.TODO())
+	pool.Run(ctx)
+
+	// アイテムがない状態でSubscriberが待機をやめた後、分配を停止する
+	subscribeCtx, cancelSubscribe := context.WithTimeout(context.TODO(), 10*time.Millisecond)
+	defer cancelSubscribe()
+	_, err := pool.Subscribe(subscribeCtx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	cancel()
+
+	// 分配の停止後は、アイテムを待たずに戻す処理を終える
+	done := make(chan struct{})
+	go func() {
+		pool.requeue(ctx, make(chan interface{}, 1))
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(1 * time.Second):
+		t.Fatal("requeue did not stop after the run context was cancelled")
+	}
+}
@@ -0,0 +1,98 @@
+package redact_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/isucon/isucon13/bench/internal/bencherror"
+	"github.com/isucon/isucon13/bench/internal/config"
+	"github.com/isucon/isucon13/bench/internal/journal"
+	"github.com/isucon/isucon13/bench/internal/logger"
+	"github.com/isucon/isucon13/bench/internal/redact"
+	"github.com/isucon/isucon13/bench/internal/scheduler"
+	"github.com/stretchr/testify/assert"
+)
+
+// 走行で書き出される成果物(ログ、エラーメッセージ、ジャーナル)に、初期データの認証情報がそのまま現れないことを確認します
+func TestArtifacts_NoSeededCredentials(t *testing.T) {
+	redact.Reset()
+	defer redact.Reset()
+
+	dir := t.TempDir()
+	staffLogPath, contestantLogPath := config.StaffLogPath, config.ContestantLogPath
+	config.StaffLogPath = filepath.Join(dir, "staff.log")
+	config.ContestantLogPath = filepath.Join(dir, "contestant.log")
+	defer func() {
+		config.StaffLogPath, config.ContestantLogPath = staffLogPath, contestantLogPath
+	}()
+
+	const (
+		signingKey = "result-signing-key-0123456789"
+		sessionID  = "MTcwMDAwMDAwMHxEdi1CQkFFQ180SUFBUkFCRUFBQV9"
+	)
+	passwords := scheduler.UserScheduler.RawPasswords()
+	redact.Register(signingKey)
+	redact.Register(passwords...)
+	redact.Register(sessionID)
+
+	var seeded []string
+	for _, password := range passwords {
+		if len(password) >= redact.MinSecretLength {
+			seeded = append(seeded, password)
+		}
+	}
+	assert.NotEmpty(t, seeded)
+	seeded = append(seeded, signingKey, sessionID)
+
+	lgr, err := logger.InitStaffLogger()
+	assert.NoError(t, err)
+	contestantLogger, err := logger.InitContestantLogger()
+	assert.NoError(t, err)
+
+	errs := bencherror.NewErrorSet()
+	ctx := bencherror.WithErrorSet(context.Background(), errs)
+	j, err := journal.Create(filepath.Join(dir, "journal.bin"))
+	assert.NoError(t, err)
+
+	req, err := http.NewRequest(http.MethodPost, "http://pipe.u.isucon.dev/api/login", nil)
+	assert.NoError(t, err)
+	for _, secret := range seeded[:10] {
+		// シナリオが失敗した際に、認証情報を含むメッセージが各所に書き出される状況を再現する
+		cause := fmt.Errorf(`login failed: body={"password":"%s"} Cookie: isupipe=%s key=%s`, secret, sessionID, signingKey)
+		benchErr := bencherror.NewHttpError(ctx, cause, req, "ログインに失敗しました (password=%s)", secret)
+
+		lgr.Warnf("login failed: password=%s, err=%+v", secret, benchErr)
+		contestantLogger.Warn(benchErr.Error())
+
+		it := j.Begin("login")
+		it.Note(journal.NoteUser, secret)
+		it.End(benchErr)
+	}
+	assert.NoError(t, j.Close())
+	lgr.Sync()
+	contestantLogger.Sync()
+
+	var artifacts []string
+	for _, name := range []string{"staff.log", "contestant.log", "journal.bin"} {
+		b, err := os.ReadFile(filepath.Join(dir, name))
+		assert.NoError(t, err)
+		assert.NotEmpty(t, b, name)
+		artifacts = append(artifacts, string(b))
+	}
+	for _, msgs := range errs.FinalErrorMessages() {
+		artifacts = append(artifacts, msgs...)
+	}
+	for _, msgs := range errs.FinalBenchErrors() {
+		artifacts = append(artifacts, msgs...)
+	}
+
+	for _, artifact := range artifacts {
+		for _, secret := range seeded {
+			assert.NotContains(t, artifact, secret)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package redact
+
+import (
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Matcher は、登録した値を文字列中から探し、伏せ字に置き換えます
+// NOTE: 秘匿情報のほか、選手向けログで伏せるベンチマーカー内部のホスト名にも用います
+type Matcher struct {
+	mask      string
+	minLength int
+	// isWordByte は、値の直前・直後にあると一致とみなさない文字です (nilの場合は前後を問わない)
+	isWordByte func(c byte) bool
+
+	mu     sync.RWMutex
+	values map[string]struct{}
+	// NOTE: 登録のたびにすべての値から作り直すと初期ユーザの登録で重くなるため、
+	//       前回作り直した後に登録された値は小さな置き換えで別に照合し、一定数たまったらまとめて作り直す
+	base           func(s string) string
+	pending        []string
+	pendingReplace func(s string) string
+	stale          bool
+}
+
+// pendingRebuildThreshold は、別に照合する値をまとめて作り直すまでの件数です
+const pendingRebuildThreshold = 64
+
+// NewMatcher は、minLength に満たない値を登録しない Matcher を返します
+func NewMatcher(mask string, minLength int) *Matcher {
+	return &Matcher{
+		mask:      mask,
+		minLength: minLength,
+		values:    map[string]struct{}{},
+	}
+}
+
+// NewBoundaryMatcher は、値の前後が isWordByte の文字でない場合にのみ置き換える Matcher を返します
+// NOTE: ホスト名 bench が bench2 や mybench の一部として置き換えられないよう、ラベルやオクテットの境界で照合する
+func NewBoundaryMatcher(mask string, isWordByte func(c byte) bool) *Matcher {
+	m := NewMatcher(mask, 1)
+	m.isWordByte = isWordByte
+	return m
+}
+
+// Register は、置き換える値を登録します
+func (m *Matcher) Register(values ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, v := range values {
+		if len(v) < m.minLength {
+			continue
+		}
+		if _, ok := m.values[v]; ok {
+			continue
+		}
+		m.values[v] = struct{}{}
+		m.pending = append(m.pending, v)
+		m.pendingReplace = nil
+		if len(m.pending) >= pendingRebuildThreshold {
+			m.stale = true
+		}
+	}
+}
+
+// Reset は、登録した値を破棄します
+func (m *Matcher) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.values = map[string]struct{}{}
+	m.base = nil
+	m.pending = nil
+	m.pendingReplace = nil
+	m.stale = false
+}
+
+// Replace は、文字列に含まれる登録済みの値を伏せ字に置き換えます
+// NOTE: 後から登録した値を先に照合する. 別の値を含む値が部分的に置き換えられても、値そのものは伏せられる
+func (m *Matcher) Replace(s string) string {
+	if len(s) == 0 {
+		return s
+	}
+	base, pending := m.current()
+	if pending != nil {
+		s = pending(s)
+	}
+	if base != nil {
+		s = base(s)
+	}
+	return s
+}
+
+func (m *Matcher) current() (base, pending func(s string) string) {
+	m.mu.RLock()
+	base, pending = m.base, m.pendingReplace
+	ok := !m.stale && (pending != nil || len(m.pending) == 0)
+	m.mu.RUnlock()
+	if ok {
+		return base, pending
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.stale {
+		values := make([]string, 0, len(m.values))
+		for v := range m.values {
+			values = append(values, v)
+		}
+		m.base = m.build(values)
+		m.pending = nil
+		m.pendingReplace = nil
+		m.stale = false
+	}
+	if m.pendingReplace == nil && len(m.pending) > 0 {
+		m.pendingReplace = m.build(slices.Clone(m.pending))
+	}
+	return m.base, m.pendingReplace
+}
+
+// build は、values のいずれかを置き換える関数を返します. values は並べ替えます
+func (m *Matcher) build(values []string) func(s string) string {
+	if len(values) == 0 {
+		return nil
+	}
+	// NOTE: 別の値を含む値が部分的に置き換えられないよう、長いものから照合する
+	sort.Slice(values, func(i, j int) bool {
+		if len(values[i]) != len(values[j]) {
+			return len(values[i]) > len(values[j])
+		}
+		return values[i] < values[j]
+	})
+	if m.isWordByte == nil {
+		return newReplacer(values, m.mask).Replace
+	}
+	return newBoundaryReplacer(values, m.mask, m.isWordByte)
+}
+
+func newReplacer(values []string, mask string) *strings.Replacer {
+	oldnew := make([]string, 0, len(values)*2)
+	for _, v := range values {
+		oldnew = append(oldnew, v, mask)
+	}
+	return strings.NewReplacer(oldnew...)
+}
+
+// newBoundaryReplacer は、長い順に並べた values のいずれかに一致し、前後が境界である箇所を置き換える関数を返します
+func newBoundaryReplacer(values []string, mask string, isWordByte func(c byte) bool) func(s string) string {
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = regexp.QuoteMeta(v)
+	}
+	// NOTE: Goの正規表現の選択は左にあるものを優先するため、長いものから一致する
+	re := regexp.MustCompile(strings.Join(quoted, "|"))
+
+	return func(s string) string {
+		locs := re.FindAllStringIndex(s, -1)
+		if len(locs) == 0 {
+			return s
+		}
+		var (
+			b    strings.Builder
+			last int
+		)
+		for _, loc := range locs {
+			if loc[0] > 0 && isWordByte(s[loc[0]-1]) {
+				continue
+			}
+			if loc[1] < len(s) && isWordByte(s[loc[1]]) {
+				continue
+			}
+			b.WriteString(s[last:loc[0]])
+			b.WriteString(mask)
+			last = loc[1]
+		}
+		if last == 0 {
+			return s
+		}
+		b.WriteString(s[last:])
+		return b.String()
+	}
+}
@@ -0,0 +1,65 @@
+// redact は、ログや成果物に書き出す文字列からパスワードやセッションなどの秘匿情報を伏せます
+//
+// 書き出し先(ロガー、エラーメッセージ、ジャーナル、pretest結果、通知)はそれぞれ String を通してから書き込みます
+// 値が既知の秘匿情報 (走行中に利用したパスワード、署名鍵、アクセスキーなど) は Register で登録し、
+// 値を事前に知り得ないもの (Cookieヘッダ、トークンなど) は書式から検出して伏せます
+package redact
+
+import "regexp"
+
+// Mask は、秘匿情報を置き換える文字列です
+const Mask = "[REDACTED]"
+
+// 登録する秘匿情報の最短の長さ
+// NOTE: 検証用ユーザの "test" のように短い値まで伏せると、ユーザ名やメッセージの一部まで壊してしまうため登録しない
+//
+//	短い値も下記の書式による検出の対象にはなる
+const MinSecretLength = 6
+
+// 書式から検出する秘匿情報
+var patterns = []struct {
+	re          *regexp.Regexp
+	replacement string
+}{
+	// {"password":"..."} のようなJSONのフィールド
+	{regexp.MustCompile(`(?i)("(?:password|raw_password|token|secret|session_id|access_key|secret_access_key)"\s*:\s*")(?:[^"\\]|\\.)*(")`), "${1}" + Mask + "${2}"},
+	// Cookie, Set-Cookie, Authorization ヘッダ
+	{regexp.MustCompile(`(?im)^((?:set-)?cookie|authorization|proxy-authorization)(\s*:\s*)[^\r\n]+`), "${1}${2}" + Mask},
+	// password=..., token=..., session_id=... のようなクエリやCookieの値
+	// NOTE: セッションCookieの名前は参照実装ごとに異なる (SESSIONID, session, rack.session, isupipe_perl など)
+	{regexp.MustCompile(`(?i)\b((?:password|passwd|token|secret|(?:[a-z]+[._])?session[a-z_]*|isupipe_[a-z]+|access_?key)\s*=\s*)[^\s&;",]+`), "${1}" + Mask},
+	// Bearerトークン
+	{regexp.MustCompile(`(?i)\b(bearer\s+)[A-Za-z0-9._~+/-]+=*`), "${1}" + Mask},
+	// SlackのWebhook URL
+	{regexp.MustCompile(`https://hooks\.slack\.com/services/[^\s"'<>]+`), Mask},
+}
+
+// 値が既知の秘匿情報
+var secrets = NewMatcher(Mask, MinSecretLength)
+
+// Register は、値が既知の秘匿情報を登録します
+func Register(values ...string) {
+	secrets.Register(values...)
+}
+
+// Reset は、登録した秘匿情報を破棄します
+func Reset() {
+	secrets.Reset()
+}
+
+// String は、文字列に含まれる秘匿情報を Mask に置き換えます
+func String(s string) string {
+	if len(s) == 0 {
+		return s
+	}
+	s = secrets.Replace(s)
+	for _, p := range patterns {
+		s = p.re.ReplaceAllString(s, p.replacement)
+	}
+	return s
+}
+
+// Bytes は、String のバイト列版です
+func Bytes(b []byte) []byte {
+	return []byte(String(string(b)))
+}
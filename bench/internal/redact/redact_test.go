@@ -0,0 +1,88 @@
+package redact
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestString_Registered(t *testing.T) {
+	Reset()
+	defer Reset()
+
+	Register("VK0bYU5qrx", "5RVJlhuS5i", "test", "")
+	assert.Equal(t, "login failed: user=ayamazaki0 password=[REDACTED]", String("login failed: user=ayamazaki0 password=VK0bYU5qrx"))
+	assert.Equal(t, "[REDACTED] と [REDACTED]", String("VK0bYU5qrx と 5RVJlhuS5i"))
+	// 短い値は登録されない
+	assert.Equal(t, "test001", String("test001"))
+
+	// 後から登録した値も伏せる
+	Register("signing-key-0123")
+	assert.Equal(t, "key: [REDACTED]", String("key: signing-key-0123"))
+}
+
+func TestString_RegisteredIncrementally(t *testing.T) {
+	Reset()
+	defer Reset()
+
+	// NOTE: まとめて作り直す件数をまたいで登録しても、すべての値を伏せる
+	var values []string
+	for i := 0; i < pendingRebuildThreshold*2+1; i++ {
+		v := fmt.Sprintf("password-%03d", i)
+		Register(v)
+		values = append(values, v)
+		assert.Equal(t, "pw: "+Mask, String("pw: "+v))
+	}
+	for _, v := range values {
+		assert.Equal(t, "pw: "+Mask, String("pw: "+v))
+	}
+}
+
+func TestString_Patterns(t *testing.T) {
+	Reset()
+	defer Reset()
+
+	testCases := []struct {
+		in   string
+		want string
+	}{
+		{`{"name":"test001","password":"test"}`, `{"name":"test001","password":"[REDACTED]"}`},
+		{`{"password": "a\"b"}`, `{"password": "[REDACTED]"}`},
+		{"Cookie: isupipe_go=MTcwMDAw\r\nHost: pipe.u.isucon.dev", "Cookie: [REDACTED]\r\nHost: pipe.u.isucon.dev"},
+		{"set-cookie: SESSIONID=abc; Path=/", "set-cookie: [REDACTED]"},
+		{"Authorization: Bearer eyJhbGciOi.xxx", "Authorization: [REDACTED]"},
+		{"token=abc123&user=test001", "token=[REDACTED]&user=test001"},
+		{"SESSIONID=MTcwMDAw; rack.session=BAh7; isupipe_perl=a1b2c3", "SESSIONID=[REDACTED]; rack.session=[REDACTED]; isupipe_perl=[REDACTED]"},
+		{"process=viewer", "process=viewer"},
+		{"got bearer abc.def-ghi", "got bearer [REDACTED]"},
+		{"post to https://hooks.slack.com/services/T000/B000/XXXX failed", "post to [REDACTED] failed"},
+		{"GET /api/livestream/1 へのリクエストに失敗しました", "GET /api/livestream/1 へのリクエストに失敗しました"},
+	}
+	for _, tc := range testCases {
+		assert.Equal(t, tc.want, String(tc.in))
+	}
+}
+
+func TestWrapCore(t *testing.T) {
+	Reset()
+	defer Reset()
+	Register("VK0bYU5qrx")
+
+	var buf bytes.Buffer
+	c := zapcore.NewCore(zapcore.NewConsoleEncoder(zap.NewProductionEncoderConfig()), zapcore.AddSync(&buf), zapcore.DebugLevel)
+	l := zap.New(c, zap.WrapCore(WrapCore))
+
+	l.With(zap.String("password", "VK0bYU5qrx")).Info("login VK0bYU5qrx",
+		zap.Error(assert.AnError),
+		zap.String("raw", "VK0bYU5qrx"),
+		zap.ByteString("body", []byte(`{"password":"VK0bYU5qrx"}`)),
+	)
+	l.Sugar().Infof("password=%s", "VK0bYU5qrx")
+
+	assert.NotContains(t, buf.String(), "VK0bYU5qrx")
+	assert.Contains(t, buf.String(), Mask)
+}
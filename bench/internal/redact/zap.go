@@ -0,0 +1,53 @@
+package redact
+
+import (
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// core は、書き込む前にメッセージとフィールドの秘匿情報を伏せるzapcore.Coreです
+type core struct {
+	zapcore.Core
+}
+
+// WrapCore は、秘匿情報を伏せてから書き込むようにzapcore.Coreを包みます
+// zap.WrapCore(redact.WrapCore) としてロガーの生成時に指定します
+func WrapCore(c zapcore.Core) zapcore.Core {
+	return &core{Core: c}
+}
+
+func (c *core) With(fields []zapcore.Field) zapcore.Core {
+	return &core{Core: c.Core.With(fieldsOf(fields))}
+}
+
+func (c *core) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *core) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	ent.Message = String(ent.Message)
+	return c.Core.Write(ent, fieldsOf(fields))
+}
+
+func fieldsOf(fields []zapcore.Field) []zapcore.Field {
+	redacted := make([]zapcore.Field, len(fields))
+	for i, f := range fields {
+		switch f.Type {
+		case zapcore.StringType:
+			f.String = String(f.String)
+		case zapcore.ErrorType:
+			if err, ok := f.Interface.(error); ok && err != nil {
+				f = zap.String(f.Key, String(err.Error()))
+			}
+		case zapcore.ByteStringType:
+			if b, ok := f.Interface.([]byte); ok {
+				f = zap.ByteString(f.Key, Bytes(b))
+			}
+		}
+		redacted[i] = f
+	}
+	return redacted
+}
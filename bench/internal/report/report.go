@@ -0,0 +1,144 @@
+// report は、走行結果(result.json)の書き出し先を扱います
+//
+// 結果ファイルへの書き出しに加え、標準出力やポータルへのPOSTなど、書き出し先を Reporter として差し替えられるようにします
+package report
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+)
+
+// SignatureHeader は、POSTするリクエストボディのHMAC-SHA256を載せるヘッダです
+const SignatureHeader = "X-Bench-Signature"
+
+// Reporter は、走行結果の書き出し先です
+type Reporter interface {
+	// Name は、ログに表示する書き出し先の名前です
+	Name() string
+	// Report は、JSONにエンコードした走行結果を書き出します
+	Report(ctx context.Context, result []byte) error
+}
+
+// Send は、走行結果をすべての書き出し先に書き出します
+// 一部の書き出し先で失敗しても残りには書き出し、失敗したものをまとめて返します
+func Send(ctx context.Context, result []byte, reporters ...Reporter) error {
+	var errs []error
+	for _, r := range reporters {
+		if err := r.Report(ctx, result); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", r.Name(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// FileReporter は、走行結果をファイルに書き出します
+type FileReporter struct {
+	Path string
+}
+
+func (r *FileReporter) Name() string {
+	return r.Path
+}
+
+func (r *FileReporter) Report(ctx context.Context, result []byte) error {
+	return os.WriteFile(r.Path, result, os.ModePerm)
+}
+
+// WriterReporter は、走行結果を1行で標準出力などに書き出します
+type WriterReporter struct {
+	W io.Writer
+}
+
+// NewStdoutReporter は、標準出力に書き出す Reporter を返します
+func NewStdoutReporter() *WriterReporter {
+	return &WriterReporter{W: os.Stdout}
+}
+
+func (r *WriterReporter) Name() string {
+	return "stdout"
+}
+
+func (r *WriterReporter) Report(ctx context.Context, result []byte) error {
+	_, err := fmt.Fprintln(r.W, string(result))
+	return err
+}
+
+// HTTPReporter は、走行結果をポータルにPOSTします
+// 通信エラー、429、5xxの場合は待ち時間を倍にしながら再送します
+type HTTPReporter struct {
+	URL string
+	// リクエストボディの署名に用いる鍵 (空の場合は署名しない)
+	SigningKey    string
+	Retries       int
+	RetryInterval time.Duration
+	Client        *http.Client
+}
+
+func (r *HTTPReporter) Name() string {
+	return r.URL
+}
+
+func (r *HTTPReporter) Report(ctx context.Context, result []byte) error {
+	interval := r.RetryInterval
+	for attempt := 0; ; attempt++ {
+		retryable, err := r.post(ctx, result)
+		if err == nil {
+			return nil
+		}
+		if !retryable || attempt >= r.Retries {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(interval):
+		}
+		interval *= 2
+	}
+}
+
+// post は、走行結果を1度POSTし、失敗した場合は再送すべきかを返します
+func (r *HTTPReporter) post(ctx context.Context, result []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.URL, bytes.NewReader(result))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if len(r.SigningKey) > 0 {
+		req.Header.Set(SignatureHeader, "sha256="+Sign(r.SigningKey, result))
+	}
+
+	client := r.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return ctx.Err() == nil, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retryable := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retryable, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+}
+
+// Sign は、鍵による本文のHMAC-SHA256を16進数で返します
+func Sign(key string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
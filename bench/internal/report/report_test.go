@@ -0,0 +1,69 @@
+package report
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTTPReporter(t *testing.T) {
+	result := []byte(`{"pass":true,"score":100}`)
+
+	var attempts atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if attempts.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		assert.Equal(t, result, body)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.Equal(t, "sha256="+Sign("secret", result), r.Header.Get(SignatureHeader))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	r := &HTTPReporter{URL: srv.URL, SigningKey: "secret", Retries: 3}
+	assert.NoError(t, r.Report(context.Background(), result))
+	assert.Equal(t, int32(3), attempts.Load())
+}
+
+func TestHTTPReporter_NoRetryOnClientError(t *testing.T) {
+	var attempts atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		assert.Empty(t, r.Header.Get(SignatureHeader))
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	r := &HTTPReporter{URL: srv.URL, Retries: 3}
+	assert.Error(t, r.Report(context.Background(), []byte(`{}`)))
+	assert.Equal(t, int32(1), attempts.Load())
+}
+
+func TestSend(t *testing.T) {
+	var buf bytes.Buffer
+	path := filepath.Join(t.TempDir(), "result.json")
+	missing := filepath.Join(t.TempDir(), "missing", "result.json")
+
+	err := Send(context.Background(), []byte(`{"pass":false}`),
+		&FileReporter{Path: missing},
+		&FileReporter{Path: path},
+		&WriterReporter{W: &buf},
+	)
+	// 書き込めない出力先があっても、残りには書き出す
+	assert.ErrorContains(t, err, missing)
+	b, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"pass":false}`, string(b))
+	assert.Equal(t, "{\"pass\":false}\n", buf.String())
+}
@@ -2,8 +2,10 @@ package resolver
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net"
+	"os"
 	"strconv"
 	"sync"
 	"sync/atomic"
@@ -12,9 +14,14 @@ import (
 	lru "github.com/hashicorp/golang-lru/v2"
 	"github.com/isucon/isucon13/bench/internal/benchscore"
 	"github.com/isucon/isucon13/bench/internal/config"
+	"github.com/isucon/isucon13/bench/internal/topology"
+	"github.com/isucon/isucon13/bench/internal/tracing"
 	"github.com/miekg/dns"
 )
 
+// ErrNXDomain は、名前が存在しない(NXDOMAIN)という応答を表します
+var ErrNXDomain = errors.New("名前が存在しません (NXDOMAIN)")
+
 var cache *lru.Cache[string, cacheEntry]
 
 func init() {
@@ -44,8 +51,10 @@ var msgPool = sync.Pool{
 }
 
 type DNSResolver struct {
-	Nameserver      string
-	Timeout         time.Duration
+	Nameserver string
+	Timeout    time.Duration
+	// 1回のクエリの期限 (走行のcontextの期限が先に来る場合はそちらに従う)
+	ResolveTimeout  time.Duration
 	ResolveAttempts uint
 	UseCache        bool
 }
@@ -54,87 +63,275 @@ func NewDNSResolver() *DNSResolver {
 	return &DNSResolver{
 		Nameserver:      net.JoinHostPort(config.TargetNameserver, strconv.Itoa(config.DNSPort)),
 		Timeout:         2 * time.Second,
+		ResolveTimeout:  config.DNSResolveTimeout,
 		ResolveAttempts: 1,
-		UseCache:        true,
+		UseCache:        config.DNSCacheEnabled,
 	}
 }
 
 func (r *DNSResolver) Lookup(ctx context.Context, network, addr string) (net.IP, error) {
+	ctx, span := tracing.Start(ctx, "DNS "+addr, tracing.KindClient)
+	span.SetAttribute("dns.question.name", addr)
+	ip, err := r.lookup(ctx, network, addr)
+	if ip != nil {
+		span.SetAttribute("dns.answer", ip.String())
+	}
+	span.End(err)
+	return ip, err
+}
+
+func (r *DNSResolver) lookup(ctx context.Context, network, addr string) (net.IP, error) {
 	if r.UseCache {
-		if entry, ok := cache.Get(addr); ok {
-			if entry.Expires.After(time.Now()) {
-				return entry.IP, nil
-			}
+		if entry, ok := cache.Get(addr); ok && entry.Expires.After(time.Now()) {
+			benchscore.IncDNSCacheHit(ctx)
+			return entry.IP, nil
 		}
+		benchscore.IncDNSCacheMiss(ctx)
+	}
+
+	var (
+		ip  net.IP
+		ttl time.Duration
+		err error
+	)
+	switch hasIPv4, hasIPv6 := config.WebappIPFamilies(); {
+	case hasIPv4 && hasIPv6:
+		ip, ttl, err = r.resolveDualStack(ctx, addr)
+	case hasIPv6:
+		ip, ttl, err = r.resolve(ctx, addr, dns.TypeAAAA)
+	default:
+		ip, ttl, err = r.resolve(ctx, addr, dns.TypeA)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if r.UseCache && ttl > 0 {
+		cache.Add(addr, cacheEntry{
+			IP:      ip,
+			Expires: time.Now().Add(min(ttl, config.DNSCacheMaxTTL)),
+		})
+	}
+	return ip, nil
+}
+
+// resolveDualStack は、AレコードとAAAAレコードを並行して問い合わせます
+// 両方のレコードが返された(デュアルスタックを広告している)場合は、どちらもwebappのアドレスであることを検証し、IPv4のアドレスを返します
+// NOTE: 一方のみ存在する名前は、存在する方のアドレスを返す
+func (r *DNSResolver) resolveDualStack(ctx context.Context, addr string) (net.IP, time.Duration, error) {
+	type answer struct {
+		ip  net.IP
+		ttl time.Duration
+		err error
+	}
+	aaaaCh := make(chan answer, 1)
+	go func() {
+		ip, ttl, err := r.resolve(ctx, addr, dns.TypeAAAA)
+		aaaaCh <- answer{ip, ttl, err}
+	}()
+	a4 := answer{}
+	a4.ip, a4.ttl, a4.err = r.resolve(ctx, addr, dns.TypeA)
+	a6 := <-aaaaCh
+
+	switch {
+	case ctx.Err() != nil && (a4.err != nil || a6.err != nil):
+		// NOTE: 走行終了などによる打ち切りは、レコードの不一致として数えない
+		return nil, 0, fmt.Errorf("「%s」の名前解決が打ち切られました: %w", addr, ctx.Err())
+	case a4.err == nil && a6.err == nil:
+		return a4.ip, min(a4.ttl, a6.ttl), nil
+	case a4.err == nil && errors.Is(a6.err, errNoRecord):
+		return a4.ip, a4.ttl, nil
+	case a6.err == nil && errors.Is(a4.err, errNoRecord):
+		return a6.ip, a6.ttl, nil
+	case a4.err == nil:
+		// NOTE: Aレコードのみ正しく返されても、AAAAレコードで誤った宛先を返すクライアントがあれば到達できない
+		benchscore.IncDNSDualStackMismatch(ctx)
+		return nil, 0, fmt.Errorf("「%s」のAレコードとAAAAレコードが一致しません: %w", addr, a6.err)
+	case a6.err == nil:
+		benchscore.IncDNSDualStackMismatch(ctx)
+		return nil, 0, fmt.Errorf("「%s」のAレコードとAAAAレコードが一致しません: %w", addr, a4.err)
+	default:
+		return nil, 0, a4.err
 	}
+}
+
+// NewDNSClient は、ネームサーバ nameserver に network(udp, tcp) で問い合わせる dns.Client を返します
+// NOTE: 送信元のアドレスはプロトコルと宛先のアドレスファミリーに合わせて決めるため、TCPで問い合わせ直す場合は作り直す
+func NewDNSClient(network, nameserver string, timeout time.Duration) *dns.Client {
+	client := &dns.Client{Net: network, Timeout: timeout}
+	if localAddr := config.LocalAddr(network, nameserver); localAddr != nil {
+		client.Dialer = &net.Dialer{Timeout: timeout, LocalAddr: localAddr}
+	}
+	return client
+}
 
+// errNoRecord は、名前は存在するものの、問い合わせた種類のレコードが存在しない(NODATA)という応答を表します
+var errNoRecord = errors.New("問い合わせた種類のレコードが含まれていません")
+
+// resolve は、名前を qtype(A/AAAA) のレコードで名前解決し、webappのアドレスとそのTTLを返します
+func (r *DNSResolver) resolve(ctx context.Context, addr string, qtype uint16) (net.IP, time.Duration, error) {
 	msg := msgPool.Get().(*dns.Msg)
 	defer msgPool.Put(msg)
 	msg.Id = uint16(atomic.AddUint64(&atomicId, 1))
 	msg.Question[0].Name = dns.Fqdn(addr)
+	msg.Question[0].Qtype = qtype
 	msg.RecursionDesired = false
 
-	client := new(dns.Client)
+	client := NewDNSClient("udp", r.Nameserver, r.Timeout)
 
 	var in *dns.Msg
 	var err error
 
 	for i := uint(0); i < r.ResolveAttempts; i++ {
-		in, _, err = client.ExchangeContext(ctx, msg, r.Nameserver)
+		if ctx.Err() != nil {
+			break
+		}
+		in, err = r.exchange(ctx, client, msg)
 		if err != nil {
+			if isTimeout(err) && ctx.Err() == nil {
+				benchscore.IncDNSTimeout(ctx)
+			}
+			if errors.Is(err, ErrMalformedResponse) || errors.Is(err, ErrOversizedResponse) {
+				// 不正な形式・サイズのレスポンスはリトライせず、プロトコルエラーとして扱う
+				break
+			}
 			continue
 		}
 		break
 	}
+	if ctxErr := ctx.Err(); ctxErr != nil && (in == nil || err != nil) {
+		// NOTE: 走行終了などによる打ち切りは名前解決の失敗として数えない
+		return nil, 0, fmt.Errorf("「%s」の名前解決が打ち切られました: %w", addr, ctxErr)
+	}
 	if err != nil {
-		benchscore.IncDNSFailed()
-		return nil, err
+		benchscore.IncDNSFailed(ctx)
+		if errors.Is(err, ErrMalformedResponse) {
+			benchscore.IncDNSMalformed(ctx)
+			return nil, 0, fmt.Errorf("「%s」の名前解決に失敗しました: %w", addr, err)
+		}
+		if errors.Is(err, ErrOversizedResponse) {
+			benchscore.IncDNSOversized(ctx)
+			return nil, 0, fmt.Errorf("「%s」の名前解決に失敗しました: %w", addr, err)
+		}
+		return nil, 0, err
 	}
 
 	// プロトコル上成功をカウントする
-	benchscore.IncResolves()
+	benchscore.IncResolves(ctx)
 
+	if in.Rcode == dns.RcodeNameError {
+		benchscore.IncDNSNXDomain(ctx)
+		return nil, 0, fmt.Errorf("「%s」の名前解決に失敗しました (rcode=%d): %w", addr, in.Rcode, ErrNXDomain)
+	}
+	if in.Rcode == dns.RcodeServerFailure {
+		benchscore.IncDNSServFail(ctx)
+	}
 	if in.Rcode != dns.RcodeSuccess {
-		return nil, fmt.Errorf("「%s」の名前解決に失敗しました (rcode=%d)", addr, in.Rcode)
+		return nil, 0, fmt.Errorf("「%s」の名前解決に失敗しました (rcode=%d)", addr, in.Rcode)
 	}
 
 	// webappsに含まれるかどうか
 	for _, ans := range in.Answer {
-		if record, ok := ans.(*dns.A); ok {
-			if !config.IsWebappIP(record.A) {
+		if ip := answerIP(ans, qtype); ip != nil {
+			if !config.IsWebappIP(ip) {
 				// webappsにないものが返ってきた
-				return nil, fmt.Errorf("「%s」の名前解決に失敗しました。「%s」はサーバーリストに含まれていません", addr, record.A.String())
+				return nil, 0, fmt.Errorf("「%s」の名前解決に失敗しました。「%s」はサーバーリストに含まれていません", addr, ip.String())
 			}
 		}
 	}
 
 	for _, ans := range in.Answer {
-		if record, ok := ans.(*dns.A); ok {
-			if r.UseCache && ans.Header().Ttl > 0 {
-				cache.Add(addr, cacheEntry{
-					IP:      record.A,
-					Expires: time.Now().Add(time.Duration(ans.Header().Ttl) * time.Second),
-				})
+		if ip := answerIP(ans, qtype); ip != nil {
+			ttl := time.Duration(ans.Header().Ttl) * time.Second
+			if IsSensibleTTL(ttl) {
+				benchscore.IncDNSSensibleTTL(ctx)
+			} else {
+				benchscore.IncDNSInsensibleTTL(ctx)
 			}
-			return record.A, nil
+			return ip, ttl, nil
 		}
 	}
 
-	return nil, fmt.Errorf("「%s」の名前解決に失敗しました。レスポンスにAレコードが含まれていません", addr)
+	return nil, 0, fmt.Errorf("「%s」の名前解決に失敗しました。レスポンスに%sレコードが含まれていません: %w", addr, dns.TypeToString[qtype], errNoRecord)
 }
 
-func (r *DNSResolver) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
-	host, port, err := net.SplitHostPort(address)
+// answerIP は、レコードが qtype(A/AAAA) のものであればそのアドレスを返します
+func answerIP(rr dns.RR, qtype uint16) net.IP {
+	switch record := rr.(type) {
+	case *dns.A:
+		if qtype == dns.TypeA {
+			return record.A
+		}
+	case *dns.AAAA:
+		if qtype == dns.TypeAAAA {
+			return record.AAAA
+		}
+	}
+	return nil
+}
+
+// IsSensibleTTL は、レコードのTTLが妥当な範囲にあるかを返します
+func IsSensibleTTL(ttl time.Duration) bool {
+	return config.DNSSensibleTTLMin <= ttl && ttl <= config.DNSSensibleTTLMax
+}
+
+// exchange は、1回のクエリを送信します. クエリは ResolveTimeout と ctx の期限のうち早い方で打ち切られます
+func (r *DNSResolver) exchange(ctx context.Context, client *dns.Client, msg *dns.Msg) (*dns.Msg, error) {
+	timeout := r.ResolveTimeout
+	if timeout <= 0 {
+		timeout = r.Timeout
+	}
+	queryCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	co, err := client.DialContext(queryCtx, r.Nameserver)
 	if err != nil {
 		return nil, err
 	}
+	defer co.Close()
+	// NOTE: 期限を設定しただけでは、期限より前にctxがキャンセルされても読み込みが中断されないため、接続を閉じて中断する
+	stop := context.AfterFunc(queryCtx, func() {
+		co.Close()
+	})
+	defer stop()
 
-	ip, err := r.Lookup(ctx, network, host)
+	if deadline, ok := queryCtx.Deadline(); ok {
+		timeout = time.Until(deadline)
+	}
+	in, err := ExchangeWithConn(co, msg, timeout)
+	if err != nil && queryCtx.Err() != nil {
+		return nil, queryCtx.Err()
+	}
+	return in, err
+}
+
+func isTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, os.ErrDeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+func (r *DNSResolver) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(address)
 	if err != nil {
 		return nil, err
 	}
 
+	// NOTE: --target にIPアドレスを指定した場合は、名前解決せずに接続する
+	ip := net.ParseIP(host)
+	if ip == nil {
+		ip, err = r.Lookup(ctx, network, host)
+		if err != nil {
+			return nil, err
+		}
+		topology.ObserveResolved(host, ip)
+	}
+
 	d := new(net.Dialer)
 	d.Timeout = r.Timeout
-	return d.DialContext(ctx, network, net.JoinHostPort(ip.String(), port))
+	address = net.JoinHostPort(ip.String(), port)
+	d.LocalAddr = config.LocalAddr(network, address)
+	return d.DialContext(ctx, network, address)
 }
@@ -0,0 +1,206 @@
+package resolver
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/isucon/isucon13/bench/internal/benchscore"
+	"github.com/isucon/isucon13/bench/internal/config"
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestNameserver は、handler が nil を返したクエリには応答しないネームサーバを起動します
+func newTestNameserver(t *testing.T, handler func(req *dns.Msg) *dns.Msg) string {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	go func() {
+		buf := make([]byte, dns.MaxMsgSize)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			req := new(dns.Msg)
+			if err := req.Unpack(buf[:n]); err != nil {
+				continue
+			}
+			resp := handler(req)
+			if resp == nil {
+				continue
+			}
+			b, err := resp.Pack()
+			if err != nil {
+				continue
+			}
+			conn.WriteTo(b, addr)
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+func newTestResolver(nameserver string) *DNSResolver {
+	return &DNSResolver{
+		Nameserver:      nameserver,
+		Timeout:         time.Second,
+		ResolveTimeout:  50 * time.Millisecond,
+		ResolveAttempts: 3,
+	}
+}
+
+// newTestScoreSet は、テストの終了時に集計を終える ScoreSet と、それを紐づけたcontextを返します
+func newTestScoreSet(t *testing.T) (context.Context, *benchscore.ScoreSet) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	set := benchscore.NewScoreSet(ctx)
+	return benchscore.WithScoreSet(ctx, set), set
+}
+
+func TestLookup_Timeout(t *testing.T) {
+	ctx, set := newTestScoreSet(t)
+	nameserver := newTestNameserver(t, func(req *dns.Msg) *dns.Msg { return nil })
+
+	r := newTestResolver(nameserver)
+	startAt := time.Now()
+	_, err := r.Lookup(ctx, "udp", "pipe.u.isucon.dev")
+	assert.Error(t, err)
+	// クエリごとに ResolveTimeout で打ち切られる
+	assert.Less(t, time.Since(startAt), time.Second)
+	assert.Equal(t, int64(3), set.GetByTag(benchscore.DNSTimeout))
+	assert.Equal(t, int64(1), set.GetByTag(benchscore.DNSFailed))
+}
+
+func TestLookup_ContextCanceled(t *testing.T) {
+	scoreCtx, set := newTestScoreSet(t)
+	nameserver := newTestNameserver(t, func(req *dns.Msg) *dns.Msg { return nil })
+
+	r := newTestResolver(nameserver)
+	r.ResolveTimeout = 10 * time.Second
+	ctx, cancel := context.WithCancel(scoreCtx)
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	startAt := time.Now()
+	_, err := r.Lookup(ctx, "udp", "pipe.u.isucon.dev")
+	assert.ErrorIs(t, err, context.Canceled)
+	// 期限より前でも、ctxのキャンセルで打ち切られ、残りのリトライも行わない
+	assert.Less(t, time.Since(startAt), time.Second)
+	assert.Zero(t, set.GetByTag(benchscore.DNSTimeout))
+	assert.Zero(t, set.GetByTag(benchscore.DNSFailed))
+}
+
+func TestLookup_Rcode(t *testing.T) {
+	ctx, set := newTestScoreSet(t)
+	nameserver := newTestNameserver(t, func(req *dns.Msg) *dns.Msg {
+		resp := new(dns.Msg)
+		if req.Question[0].Name == "unknown.u.isucon.dev." {
+			return resp.SetRcode(req, dns.RcodeNameError)
+		}
+		return resp.SetRcode(req, dns.RcodeServerFailure)
+	})
+
+	r := newTestResolver(nameserver)
+	_, err := r.Lookup(ctx, "udp", "unknown.u.isucon.dev")
+	assert.ErrorIs(t, err, ErrNXDomain)
+	_, err = r.Lookup(ctx, "udp", "pipe.u.isucon.dev")
+	assert.Error(t, err)
+
+	assert.Equal(t, int64(1), set.GetByTag(benchscore.DNSNXDomain))
+	assert.Equal(t, int64(1), set.GetByTag(benchscore.DNSServFail))
+	assert.Equal(t, int64(2), set.GetByTag(benchscore.DNSResolve))
+}
+
+func TestLookup_Cache(t *testing.T) {
+	ctx, set := newTestScoreSet(t)
+	var queries atomic.Int64
+	nameserver := newTestNameserver(t, func(req *dns.Msg) *dns.Msg {
+		queries.Add(1)
+		resp := new(dns.Msg)
+		resp.SetReply(req)
+		ttl := uint32(60)
+		if req.Question[0].Name == "nottl.u.isucon.dev." {
+			ttl = 0
+		}
+		resp.Answer = append(resp.Answer, &dns.A{
+			Hdr: dns.RR_Header{Name: req.Question[0].Name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: ttl},
+			A:   net.ParseIP("127.0.0.1"),
+		})
+		return resp
+	})
+	webapps := config.TargetWebapps
+	config.TargetWebapps = []string{"127.0.0.1"}
+	t.Cleanup(func() { config.TargetWebapps = webapps })
+
+	r := newTestResolver(nameserver)
+	r.UseCache = true
+	// TTLの間はキャッシュから返す
+	for i := 0; i < 3; i++ {
+		ip, err := r.Lookup(ctx, "udp", "cached.u.isucon.dev")
+		assert.NoError(t, err)
+		assert.Equal(t, "127.0.0.1", ip.String())
+	}
+	assert.Equal(t, int64(1), queries.Load())
+	// TTLが0ならキャッシュしない
+	for i := 0; i < 2; i++ {
+		_, err := r.Lookup(ctx, "udp", "nottl.u.isucon.dev")
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, int64(3), queries.Load())
+
+	assert.Equal(t, int64(2), set.GetByTag(benchscore.DNSCacheHit))
+	assert.Equal(t, int64(3), set.GetByTag(benchscore.DNSCacheMiss))
+	assert.Equal(t, int64(1), set.GetByTag(benchscore.DNSSensibleTTL))
+	assert.Equal(t, int64(2), set.GetByTag(benchscore.DNSInsensibleTTL))
+}
+
+func TestIsSensibleTTL(t *testing.T) {
+	assert.False(t, IsSensibleTTL(0))
+	assert.True(t, IsSensibleTTL(config.DNSSensibleTTLMin))
+	assert.True(t, IsSensibleTTL(time.Hour))
+	assert.False(t, IsSensibleTTL(config.DNSSensibleTTLMax+time.Second))
+}
+
+func TestLookup_DualStack(t *testing.T) {
+	ctx, set := newTestScoreSet(t)
+	nameserver := newTestNameserver(t, func(req *dns.Msg) *dns.Msg {
+		resp := new(dns.Msg)
+		resp.SetReply(req)
+		q := req.Question[0]
+		hdr := dns.RR_Header{Name: q.Name, Rrtype: q.Qtype, Class: dns.ClassINET, Ttl: 60}
+		switch {
+		case q.Qtype == dns.TypeA && q.Name != "v6only.u.isucon.dev.":
+			resp.Answer = append(resp.Answer, &dns.A{Hdr: hdr, A: net.ParseIP("127.0.0.1")})
+		case q.Qtype == dns.TypeAAAA && q.Name == "mismatch.u.isucon.dev.":
+			resp.Answer = append(resp.Answer, &dns.AAAA{Hdr: hdr, AAAA: net.ParseIP("2001:db8::1")})
+		case q.Qtype == dns.TypeAAAA && q.Name != "v4only.u.isucon.dev.":
+			resp.Answer = append(resp.Answer, &dns.AAAA{Hdr: hdr, AAAA: net.ParseIP("::1")})
+		}
+		return resp
+	})
+	webapps := config.TargetWebapps
+	config.TargetWebapps = []string{"127.0.0.1", "[::1]"}
+	t.Cleanup(func() { config.TargetWebapps = webapps })
+
+	r := newTestResolver(nameserver)
+	// 両方のレコードが返された場合はIPv4のアドレスを用いる
+	ip, err := r.Lookup(ctx, "udp", "pipe.u.isucon.dev")
+	assert.NoError(t, err)
+	assert.Equal(t, "127.0.0.1", ip.String())
+
+	// 一方のみ存在する名前
+	ip, err = r.Lookup(ctx, "udp", "v4only.u.isucon.dev")
+	assert.NoError(t, err)
+	assert.Equal(t, "127.0.0.1", ip.String())
+	ip, err = r.Lookup(ctx, "udp", "v6only.u.isucon.dev")
+	assert.NoError(t, err)
+	assert.Equal(t, "::1", ip.String())
+
+	// AAAAレコードがサーバーリストにないアドレスを返す
+	_, err = r.Lookup(ctx, "udp", "mismatch.u.isucon.dev")
+	assert.ErrorContains(t, err, "サーバーリストに含まれていません")
+	assert.Equal(t, int64(1), set.GetByTag(benchscore.DNSDualStackMismatch))
+}
@@ -0,0 +1,40 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/miekg/dns"
+)
+
+// ErrEDNSUnsupported は、EDNS0のクエリに対して、EDNS0に対応した応答が返されなかったことを表します
+var ErrEDNSUnsupported = errors.New("EDNS0のクエリに対して、EDNS0に対応したDNSレスポンスが返されませんでした")
+
+// CheckEDNS は、UDPペイロードサイズ size を広告したEDNS0のクエリを送り、
+// ネームサーバがEDNS0に対応しているか、広告したサイズを超えるレスポンスを返さないかを確かめます
+// NOTE: 名前解決の成否や回数には数えない
+func (r *DNSResolver) CheckEDNS(ctx context.Context, name string, qtype uint16, size uint16) error {
+	msg := new(dns.Msg)
+	msg.SetQuestion(dns.Fqdn(name), qtype)
+	msg.Id = uint16(atomic.AddUint64(&atomicId, 1))
+	msg.RecursionDesired = false
+	msg.SetEdns0(size, false)
+
+	client := NewDNSClient("udp", r.Nameserver, r.Timeout)
+
+	in, err := r.exchange(ctx, client, msg)
+	if err != nil {
+		return fmt.Errorf("「%s」(%s) へのEDNS0のクエリ(UDPペイロードサイズ %d bytes)に失敗しました: %w", name, dns.TypeToString[qtype], size, err)
+	}
+
+	opt := in.IsEdns0()
+	if opt == nil {
+		return fmt.Errorf("「%s」(%s) へのクエリ(UDPペイロードサイズ %d bytes)の応答にOPTレコードが含まれていません (rcode=%s): %w", name, dns.TypeToString[qtype], size, dns.RcodeToString[in.Rcode], ErrEDNSUnsupported)
+	}
+	if in.Rcode == dns.RcodeFormatError || in.Rcode == dns.RcodeBadVers || opt.Version() != 0 {
+		return fmt.Errorf("「%s」(%s) へのクエリ(UDPペイロードサイズ %d bytes)の応答が不正です (rcode=%s, version=%d): %w", name, dns.TypeToString[qtype], size, dns.RcodeToString[in.Rcode], opt.Version(), ErrEDNSUnsupported)
+	}
+	return nil
+}
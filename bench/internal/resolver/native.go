@@ -23,11 +23,12 @@ func NewNativeDNSResolver() *NativeDNSResolver {
 
 func (r *NativeDNSResolver) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
 	dialer := net.Dialer{
-		Timeout: r.Timeout,
+		Timeout:   r.Timeout,
+		LocalAddr: config.LocalAddr(network, address),
 		Resolver: &net.Resolver{
 			PreferGo: true,
 			Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
-				dialer := net.Dialer{Timeout: r.Timeout}
+				dialer := net.Dialer{Timeout: r.Timeout, LocalAddr: config.LocalAddr("udp", r.Nameserver)}
 				return dialer.DialContext(ctx, "udp", r.Nameserver)
 			},
 		},
@@ -0,0 +1,243 @@
+package resolver
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// ErrMalformedResponse は、ネームサーバのレスポンスがDNSのワイヤーフォーマットとして不正であることを表します
+var ErrMalformedResponse = errors.New("ネームサーバから不正な形式のDNSレスポンスが返されました")
+
+// ErrOversizedResponse は、UDPのレスポンスが、クエリで広告したサイズ(EDNS0がなければ512バイト)を超えていることを表します
+var ErrOversizedResponse = errors.New("ネームサーバから、クエリで広告したサイズを超えるUDPのDNSレスポンスが返されました")
+
+const (
+	headerLength = 12
+	// 名前の最大長 (RFC1035 3.1)
+	maxNameLength = 255
+	// ラベルの最大長 (RFC1035 2.3.4)
+	maxLabelLength = 63
+)
+
+// ExchangeWithConn は、確立済みのコネクションでクエリを送信し、ワイヤーフォーマットを検証した上でレスポンスを返します
+// NOTE: miekg/dnsのUnpackは不正な圧縮ポインタやセクション数の不一致を黙って許容する場合があるため、生のバイト列を検証してからUnpackする
+// UDPでは、レスポンスの長さがクエリで広告したサイズ以内であることも検証します
+func ExchangeWithConn(co *dns.Conn, m *dns.Msg, timeout time.Duration) (*dns.Msg, error) {
+	_, udp := co.Conn.(net.PacketConn)
+	if udp {
+		// NOTE: 広告したサイズを超えるレスポンスを切り詰めずに検出できるよう、読み込みのバッファは最大長とする
+		co.UDPSize = dns.MaxMsgSize
+	}
+
+	co.SetWriteDeadline(time.Now().Add(timeout))
+	if err := co.WriteMsg(m); err != nil {
+		return nil, err
+	}
+
+	co.SetReadDeadline(time.Now().Add(timeout))
+	for {
+		b, err := co.ReadMsgHeader(nil)
+		if err != nil {
+			return nil, err
+		}
+		if len(b) < headerLength {
+			return nil, fmt.Errorf("ヘッダ長が不足しています (length=%d): %w", len(b), ErrMalformedResponse)
+		}
+		// NOTE: UDPでは以前のクエリに対するレスポンスが遅れて届くことがあるので、IDが一致するまで読み捨てる
+		if binary.BigEndian.Uint16(b) != m.Id {
+			continue
+		}
+
+		if limit := advertisedUDPSize(m); udp && len(b) > limit {
+			return nil, fmt.Errorf("レスポンス長 %d bytes, 広告したサイズ %d bytes: %w", len(b), limit, ErrOversizedResponse)
+		}
+		if err := validateMessage(b); err != nil {
+			return nil, err
+		}
+
+		in := new(dns.Msg)
+		if err := in.Unpack(b); err != nil {
+			return nil, fmt.Errorf("%s: %w", err.Error(), ErrMalformedResponse)
+		}
+		return in, nil
+	}
+}
+
+// advertisedUDPSize は、クエリが受け付けるUDPのレスポンスの最大長を返します
+// EDNS0のOPTレコードがなければ512バイト、あれば広告したサイズ(512バイト未満の場合は512バイト)です (RFC6891 6.2.3, 6.2.5)
+func advertisedUDPSize(m *dns.Msg) int {
+	if opt := m.IsEdns0(); opt != nil {
+		return max(int(opt.UDPSize()), dns.MinMsgSize)
+	}
+	return dns.MinMsgSize
+}
+
+// validateMessage は、DNSメッセージのワイヤーフォーマットを検証します
+// セクションのレコード数とメッセージ長の整合性、圧縮ポインタの正当性(前方参照のみ、ループなし、範囲内)を確認します
+func validateMessage(b []byte) error {
+	if len(b) < headerLength {
+		return fmt.Errorf("ヘッダ長が不足しています (length=%d): %w", len(b), ErrMalformedResponse)
+	}
+
+	var (
+		qdcount = int(binary.BigEndian.Uint16(b[4:]))
+		ancount = int(binary.BigEndian.Uint16(b[6:]))
+		nscount = int(binary.BigEndian.Uint16(b[8:]))
+		arcount = int(binary.BigEndian.Uint16(b[10:]))
+		off     = headerLength
+		err     error
+	)
+
+	for i := 0; i < qdcount; i++ {
+		if off, err = validateName(b, off, len(b)); err != nil {
+			return fmt.Errorf("question[%d]: %w", i, err)
+		}
+		// QTYPE, QCLASS
+		if off+4 > len(b) {
+			return fmt.Errorf("question[%d]がメッセージ長を超えています (qdcount=%d): %w", i, qdcount, ErrMalformedResponse)
+		}
+		off += 4
+	}
+
+	sections := []struct {
+		name  string
+		count int
+	}{
+		{"answer", ancount},
+		{"authority", nscount},
+		{"additional", arcount},
+	}
+	for _, section := range sections {
+		for i := 0; i < section.count; i++ {
+			if off, err = validateRR(b, off); err != nil {
+				return fmt.Errorf("%s[%d] (count=%d): %w", section.name, i, section.count, err)
+			}
+		}
+	}
+
+	if off != len(b) {
+		return fmt.Errorf("ヘッダのレコード数とメッセージ長が一致しません (余剰=%dbytes): %w", len(b)-off, ErrMalformedResponse)
+	}
+
+	return nil
+}
+
+// validateRR は、リソースレコード1件を検証し、次のレコードのオフセットを返します
+func validateRR(b []byte, off int) (int, error) {
+	off, err := validateName(b, off, len(b))
+	if err != nil {
+		return off, err
+	}
+
+	// TYPE, CLASS, TTL, RDLENGTH
+	if off+10 > len(b) {
+		return off, fmt.Errorf("レコードヘッダがメッセージ長を超えています: %w", ErrMalformedResponse)
+	}
+	rrtype := binary.BigEndian.Uint16(b[off:])
+	rdlength := int(binary.BigEndian.Uint16(b[off+8:]))
+	off += 10
+
+	end := off + rdlength
+	if end > len(b) {
+		return off, fmt.Errorf("RDATAがメッセージ長を超えています (rdlength=%d): %w", rdlength, ErrMalformedResponse)
+	}
+
+	// RDATAにドメイン名を含むレコードは、RDATA内の名前も検証する
+	rdoff := off
+	switch rrtype {
+	case dns.TypeNS, dns.TypeCNAME, dns.TypePTR:
+		if rdoff, err = validateName(b, rdoff, end); err != nil {
+			return off, err
+		}
+	case dns.TypeMX:
+		if rdoff+2 > end {
+			return off, fmt.Errorf("MXレコードのRDATAが不足しています: %w", ErrMalformedResponse)
+		}
+		if rdoff, err = validateName(b, rdoff+2, end); err != nil {
+			return off, err
+		}
+	case dns.TypeSOA:
+		if rdoff, err = validateName(b, rdoff, end); err != nil {
+			return off, err
+		}
+		if rdoff, err = validateName(b, rdoff, end); err != nil {
+			return off, err
+		}
+		// SERIAL, REFRESH, RETRY, EXPIRE, MINIMUM
+		rdoff += 20
+	case dns.TypeA:
+		rdoff += 4
+	default:
+		rdoff = end
+	}
+	if rdoff != end {
+		return off, fmt.Errorf("RDATAの長さがRDLENGTHと一致しません (type=%s, rdlength=%d): %w", dns.TypeToString[rrtype], rdlength, ErrMalformedResponse)
+	}
+
+	return end, nil
+}
+
+// validateName は、offから始まるドメイン名を検証し、名前の直後のオフセットを返します
+// limitは圧縮されていない部分が収まるべき範囲 (RDATA内であればRDATAの終端)
+// 圧縮ポインタは、直前に読んだ位置より前方(メッセージ先頭側)のみを指すことを要求します
+// これによりポインタループは起こり得ず、またヘッダ内を指すポインタも不正として扱います
+func validateName(b []byte, off int, limit int) (int, error) {
+	var (
+		next      = -1
+		nameLen   = 0
+		lowerMost = off
+	)
+	for {
+		if off >= limit {
+			return off, fmt.Errorf("ドメイン名が途中で終わっています (offset=%d): %w", off, ErrMalformedResponse)
+		}
+
+		c := int(b[off])
+		switch c & 0xC0 {
+		case 0x00:
+			if c == 0 {
+				if next < 0 {
+					next = off + 1
+				}
+				return next, nil
+			}
+			if c > maxLabelLength {
+				return off, fmt.Errorf("ラベル長が不正です (length=%d): %w", c, ErrMalformedResponse)
+			}
+			if off+1+c > limit {
+				return off, fmt.Errorf("ラベルがメッセージ長を超えています (offset=%d): %w", off, ErrMalformedResponse)
+			}
+			nameLen += c + 1
+			if nameLen+1 > maxNameLength {
+				return off, fmt.Errorf("ドメイン名が長すぎます: %w", ErrMalformedResponse)
+			}
+			off += c + 1
+		case 0xC0:
+			if off+2 > limit {
+				return off, fmt.Errorf("圧縮ポインタが途中で終わっています (offset=%d): %w", off, ErrMalformedResponse)
+			}
+			ptr := int(binary.BigEndian.Uint16(b[off:]) & 0x3FFF)
+			if ptr < headerLength {
+				return off, fmt.Errorf("圧縮ポインタがヘッダを指しています (offset=%d, pointer=%d): %w", off, ptr, ErrMalformedResponse)
+			}
+			if ptr >= lowerMost {
+				return off, fmt.Errorf("圧縮ポインタが前方を指していません (offset=%d, pointer=%d): %w", off, ptr, ErrMalformedResponse)
+			}
+			if next < 0 {
+				next = off + 2
+			}
+			// ポインタ先は圧縮されたメッセージ全体の範囲で読む
+			limit = len(b)
+			off = ptr
+			lowerMost = ptr
+		default:
+			// 0x40, 0x80 は拡張ラベル(廃止済み)
+			return off, fmt.Errorf("未定義のラベル種別です (offset=%d, type=0x%02x): %w", off, c&0xC0, ErrMalformedResponse)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package resolver
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+)
+
+func packTestResponse(t *testing.T) []byte {
+	m := new(dns.Msg)
+	m.SetQuestion("pipe.u.isucon.dev.", dns.TypeA)
+	m.Response = true
+	m.Compress = true
+	for _, ip := range []string{"192.168.0.11", "192.168.0.12"} {
+		m.Answer = append(m.Answer, &dns.A{
+			Hdr: dns.RR_Header{Name: "pipe.u.isucon.dev.", Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 0},
+			A:   net.ParseIP(ip).To4(),
+		})
+	}
+	m.Ns = append(m.Ns, &dns.NS{
+		Hdr: dns.RR_Header{Name: "u.isucon.dev.", Rrtype: dns.TypeNS, Class: dns.ClassINET, Ttl: 3600},
+		Ns:  "ns1.u.isucon.dev.",
+	})
+
+	b, err := m.Pack()
+	assert.NoError(t, err)
+	return b
+}
+
+func TestValidateMessage_Valid(t *testing.T) {
+	b := packTestResponse(t)
+	assert.NoError(t, validateMessage(b))
+}
+
+func TestValidateMessage_CountMismatch(t *testing.T) {
+	b := packTestResponse(t)
+
+	// ANCOUNTを実際より多く申告する
+	tooMany := append([]byte{}, b...)
+	binary.BigEndian.PutUint16(tooMany[6:], 5)
+	assert.True(t, errors.Is(validateMessage(tooMany), ErrMalformedResponse))
+
+	// 末尾に余計なバイト列がある
+	trailing := append(append([]byte{}, b...), 0x00, 0x00)
+	assert.True(t, errors.Is(validateMessage(trailing), ErrMalformedResponse))
+}
+
+func TestValidateMessage_PointerLoop(t *testing.T) {
+	b := packTestResponse(t)
+
+	// 最初のanswerの名前(圧縮ポインタ)を自分自身を指すように書き換える
+	off := headerLength
+	off, err := validateName(b, off, len(b))
+	assert.NoError(t, err)
+	off += 4
+	assert.Equal(t, byte(0xC0), b[off]&0xC0)
+
+	loop := append([]byte{}, b...)
+	binary.BigEndian.PutUint16(loop[off:], 0xC000|uint16(off))
+	assert.True(t, errors.Is(validateMessage(loop), ErrMalformedResponse))
+
+	// 後方(メッセージ末尾側)を指すポインタ
+	forward := append([]byte{}, b...)
+	binary.BigEndian.PutUint16(forward[off:], 0xC000|uint16(len(b)-1))
+	assert.True(t, errors.Is(validateMessage(forward), ErrMalformedResponse))
+
+	// ヘッダを指すポインタ
+	header := append([]byte{}, b...)
+	binary.BigEndian.PutUint16(header[off:], 0xC000|uint16(4))
+	assert.True(t, errors.Is(validateMessage(header), ErrMalformedResponse))
+}
+
+func TestValidateMessage_Truncated(t *testing.T) {
+	b := packTestResponse(t)
+	for _, n := range []int{0, headerLength - 1, headerLength + 3, len(b) - 1} {
+		assert.True(t, errors.Is(validateMessage(b[:n]), ErrMalformedResponse), "length=%d", n)
+	}
+}
+
+func startOversizedServer(t *testing.T) string {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	srv := &dns.Server{
+		PacketConn: pc,
+		Handler: dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+			m := new(dns.Msg)
+			m.SetReply(r)
+			// NOTE: 広告したサイズにかかわらず、切り詰めずに大きなレスポンスを返す
+			for i := 0; i < 64; i++ {
+				m.Answer = append(m.Answer, &dns.A{
+					Hdr: dns.RR_Header{Name: r.Question[0].Name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 60},
+					A:   net.IPv4(192, 168, 0, byte(i)).To4(),
+				})
+			}
+			if opt := r.IsEdns0(); opt != nil {
+				m.SetEdns0(opt.UDPSize(), false)
+			}
+			w.WriteMsg(m)
+		}),
+	}
+	go srv.ActivateAndServe()
+	t.Cleanup(func() { srv.Shutdown() })
+	return pc.LocalAddr().String()
+}
+
+func TestExchangeWithConn_Oversized(t *testing.T) {
+	addr := startOversizedServer(t)
+
+	exchange := func(edns uint16) error {
+		co, err := dns.Dial("udp", addr)
+		assert.NoError(t, err)
+		defer co.Close()
+
+		m := new(dns.Msg)
+		m.SetQuestion("pipe.u.isucon.dev.", dns.TypeA)
+		if edns > 0 {
+			m.SetEdns0(edns, false)
+		}
+		_, err = ExchangeWithConn(co, m, time.Second)
+		return err
+	}
+
+	// EDNS0がなければ512バイトを超えてはならない
+	assert.ErrorIs(t, exchange(0), ErrOversizedResponse)
+	assert.ErrorIs(t, exchange(512), ErrOversizedResponse)
+	assert.NoError(t, exchange(4096))
+}
@@ -0,0 +1,153 @@
+package resolver
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+	"sync/atomic"
+
+	"github.com/isucon/isucon13/bench/internal/config"
+	"github.com/miekg/dns"
+)
+
+// ZoneRecords は、ゾーンの頂点のSOAレコードとNSレコード、ゾーン内のネームサーバ名のアドレスです
+type ZoneRecords struct {
+	Zone string
+	SOA  *dns.SOA
+	// NSレコードのネームサーバ名 (FQDN, 名前の順)
+	Nameservers []string
+	// ゾーン内のネームサーバ名のアドレス
+	NameserverAddrs map[string][]net.IP
+}
+
+// QueryZone は、ゾーン zone のSOAレコードとNSレコード、ゾーン内のネームサーバ名のアドレスを問い合わせます
+// NOTE: 名前解決の成否や回数には数えない
+func (r *DNSResolver) QueryZone(ctx context.Context, zone string) (*ZoneRecords, error) {
+	zone = dns.Fqdn(zone)
+	records := &ZoneRecords{
+		Zone:            zone,
+		NameserverAddrs: make(map[string][]net.IP),
+	}
+
+	in, err := r.queryAuthoritative(ctx, zone, dns.TypeSOA)
+	if err != nil {
+		return nil, err
+	}
+	for _, rr := range in.Answer {
+		if soa, ok := rr.(*dns.SOA); ok && strings.EqualFold(soa.Hdr.Name, zone) {
+			records.SOA = soa
+			break
+		}
+	}
+	if records.SOA == nil {
+		return nil, fmt.Errorf("「%s」のSOAレコードが応答に含まれていません", zone)
+	}
+
+	in, err = r.queryAuthoritative(ctx, zone, dns.TypeNS)
+	if err != nil {
+		return nil, err
+	}
+	for _, rr := range in.Answer {
+		if ns, ok := rr.(*dns.NS); ok && strings.EqualFold(ns.Hdr.Name, zone) {
+			records.Nameservers = append(records.Nameservers, strings.ToLower(ns.Ns))
+		}
+	}
+	slices.Sort(records.Nameservers)
+
+	for _, ns := range records.Nameservers {
+		if !dns.IsSubDomain(zone, ns) {
+			continue
+		}
+		for _, qtype := range []uint16{dns.TypeA, dns.TypeAAAA} {
+			in, err := r.queryAuthoritative(ctx, ns, qtype)
+			if err != nil {
+				return nil, err
+			}
+			for _, rr := range in.Answer {
+				if ip := answerIP(rr, qtype); ip != nil {
+					records.NameserverAddrs[ns] = append(records.NameserverAddrs[ns], ip)
+				}
+			}
+		}
+	}
+	return records, nil
+}
+
+// queryAuthoritative は、ネームサーバに問い合わせ、権威を持つ応答であることを確かめます
+// NOTE: NXDOMAINはネームサーバ名が存在しないことを表すため、エラーとせず呼び出し元で扱う
+func (r *DNSResolver) queryAuthoritative(ctx context.Context, name string, qtype uint16) (*dns.Msg, error) {
+	msg := new(dns.Msg)
+	msg.SetQuestion(dns.Fqdn(name), qtype)
+	msg.Id = uint16(atomic.AddUint64(&atomicId, 1))
+	msg.RecursionDesired = false
+
+	client := NewDNSClient("udp", r.Nameserver, r.Timeout)
+
+	in, err := r.exchange(ctx, client, msg)
+	if err != nil {
+		return nil, fmt.Errorf("「%s」(%s) の問い合わせに失敗しました: %w", name, dns.TypeToString[qtype], err)
+	}
+	if in.Rcode != dns.RcodeSuccess && in.Rcode != dns.RcodeNameError {
+		return nil, fmt.Errorf("「%s」(%s) の問い合わせに失敗しました (rcode=%s)", name, dns.TypeToString[qtype], dns.RcodeToString[in.Rcode])
+	}
+	if !in.Authoritative {
+		return nil, fmt.Errorf("「%s」(%s) の応答が権威を持つ応答(AA)ではありません", name, dns.TypeToString[qtype])
+	}
+	return in, nil
+}
+
+// Misconfigurations は、ゾーンの委譲が要件を満たさない点を返します
+// ネームサーバ名が nameservers と一致し、SOAレコードのMNAMEがいずれかのネームサーバ名で、
+// ゾーン内のネームサーバ名がwebappのアドレスを指していることを要件とします
+func (z *ZoneRecords) Misconfigurations(nameservers []string) []string {
+	var problems []string
+
+	want := make([]string, len(nameservers))
+	for i, ns := range nameservers {
+		want[i] = strings.ToLower(dns.Fqdn(ns))
+	}
+	slices.Sort(want)
+	if !slices.Equal(z.Nameservers, want) {
+		problems = append(problems, fmt.Sprintf("「%s」のNSレコードが %s ではありません (実際: %s)", z.Zone, strings.Join(want, ", "), joinOrNone(z.Nameservers)))
+	}
+
+	if mname := strings.ToLower(z.SOA.Ns); !slices.Contains(z.Nameservers, mname) {
+		problems = append(problems, fmt.Sprintf("「%s」のSOAレコードのMNAME「%s」がNSレコードに含まれていません", z.Zone, z.SOA.Ns))
+	}
+
+	for _, ns := range z.Nameservers {
+		if !dns.IsSubDomain(z.Zone, ns) {
+			continue
+		}
+		addrs := z.NameserverAddrs[ns]
+		if len(addrs) == 0 {
+			problems = append(problems, fmt.Sprintf("ネームサーバ名「%s」のアドレスが登録されていません", ns))
+			continue
+		}
+		for _, ip := range addrs {
+			if !config.IsWebappIP(ip) {
+				problems = append(problems, fmt.Sprintf("ネームサーバ名「%s」のアドレス「%s」はサーバーリストに含まれていません", ns, ip))
+			}
+		}
+	}
+	return problems
+}
+
+func joinOrNone(names []string) string {
+	if len(names) == 0 {
+		return "なし"
+	}
+	return strings.Join(names, ", ")
+}
+
+// SerialIncreased は、SOAレコードのシリアルが old から new に増えたかを、シリアル番号の算術(RFC 1982)で判定します
+func SerialIncreased(old, new uint32) bool {
+	return int32(new-old) > 0
+}
+
+// SerialDecreased は、SOAレコードのシリアルが old から new に減ったかを、シリアル番号の算術(RFC 1982)で判定します
+func SerialDecreased(old, new uint32) bool {
+	return int32(new-old) < 0
+}
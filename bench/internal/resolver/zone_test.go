@@ -0,0 +1,89 @@
+package resolver
+
+import (
+	"context"
+	"math"
+	"net"
+	"testing"
+
+	"github.com/isucon/isucon13/bench/internal/config"
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestZoneHandler は、ゾーン u.isucon.dev. の権威を持つ応答を返すハンドラです
+func newTestZoneHandler(mname string, nameservers []string, addrs map[string]string) func(req *dns.Msg) *dns.Msg {
+	return func(req *dns.Msg) *dns.Msg {
+		resp := new(dns.Msg)
+		resp.SetReply(req)
+		resp.Authoritative = true
+		q := req.Question[0]
+		hdr := dns.RR_Header{Name: q.Name, Rrtype: q.Qtype, Class: dns.ClassINET, Ttl: 0}
+		switch q.Qtype {
+		case dns.TypeSOA:
+			resp.Answer = append(resp.Answer, &dns.SOA{Hdr: hdr, Ns: mname, Mbox: "hostmaster.u.isucon.dev.", Serial: 1})
+		case dns.TypeNS:
+			for _, ns := range nameservers {
+				resp.Answer = append(resp.Answer, &dns.NS{Hdr: hdr, Ns: ns})
+			}
+		case dns.TypeA:
+			if addr, ok := addrs[q.Name]; ok {
+				resp.Answer = append(resp.Answer, &dns.A{Hdr: hdr, A: net.ParseIP(addr)})
+			}
+		}
+		return resp
+	}
+}
+
+func TestQueryZone(t *testing.T) {
+	webapps := config.TargetWebapps
+	config.TargetWebapps = []string{"127.0.0.1"}
+	t.Cleanup(func() { config.TargetWebapps = webapps })
+	nameserver := newTestNameserver(t, newTestZoneHandler("ns1.u.isucon.dev.", []string{"ns1.u.isucon.dev."}, map[string]string{
+		"ns1.u.isucon.dev.": "127.0.0.1",
+	}))
+
+	r := newTestResolver(nameserver)
+	zone, err := r.QueryZone(context.Background(), "u.isucon.dev")
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(1), zone.SOA.Serial)
+	assert.Equal(t, []string{"ns1.u.isucon.dev."}, zone.Nameservers)
+	assert.Empty(t, zone.Misconfigurations([]string{"ns1.u.isucon.dev"}))
+}
+
+func TestQueryZone_Misconfigured(t *testing.T) {
+	webapps := config.TargetWebapps
+	config.TargetWebapps = []string{"127.0.0.1"}
+	t.Cleanup(func() { config.TargetWebapps = webapps })
+	nameserver := newTestNameserver(t, newTestZoneHandler("ns.example.com.", []string{"ns1.u.isucon.dev.", "ns2.u.isucon.dev."}, map[string]string{
+		"ns1.u.isucon.dev.": "192.0.2.1",
+	}))
+
+	r := newTestResolver(nameserver)
+	zone, err := r.QueryZone(context.Background(), "u.isucon.dev")
+	assert.NoError(t, err)
+	// NSレコードの不一致, MNAME, ns1のアドレス, ns2のアドレスなし
+	assert.Len(t, zone.Misconfigurations([]string{"ns1.u.isucon.dev"}), 4)
+}
+
+func TestQueryZone_NotAuthoritative(t *testing.T) {
+	nameserver := newTestNameserver(t, func(req *dns.Msg) *dns.Msg {
+		resp := new(dns.Msg)
+		resp.SetReply(req)
+		return resp
+	})
+
+	r := newTestResolver(nameserver)
+	_, err := r.QueryZone(context.Background(), "u.isucon.dev")
+	assert.Error(t, err)
+}
+
+func TestSerialIncreased(t *testing.T) {
+	assert.True(t, SerialIncreased(1, 2))
+	assert.False(t, SerialIncreased(2, 2))
+	assert.False(t, SerialIncreased(2, 1))
+	// シリアルが一周した場合も増えたとみなす
+	assert.True(t, SerialIncreased(math.MaxUint32, 0))
+	assert.True(t, SerialDecreased(2, 1))
+	assert.False(t, SerialDecreased(math.MaxUint32, 0))
+}
@@ -0,0 +1,217 @@
+// resultdiff は、2回の走行結果を比べ、スコア・シナリオの実行回数・エラーの差分をまとめます
+//
+// 選手がチューニングの前後の走行をログを見比べて確かめていたものを置き換えます
+package resultdiff
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+
+	"github.com/isucon/isucon13/bench/internal/bencherror"
+	"github.com/isucon/isucon13/bench/internal/benchscore"
+)
+
+// Snapshot は、比べる走行結果の項目です
+type Snapshot struct {
+	Pass        bool
+	Score       int64
+	Scenarios   []benchscore.ScenarioCount
+	ErrorCounts map[bencherror.Category]int64
+	Messages    []string
+}
+
+// ScenarioDelta は、1つの段階における1つのシナリオの成功・失敗回数の差分です
+type ScenarioDelta struct {
+	Phase      benchscore.ScenarioPhase `json:"phase"`
+	Scenario   string                   `json:"scenario"`
+	OldSuccess int64                    `json:"old_success"`
+	NewSuccess int64                    `json:"new_success"`
+	OldFail    int64                    `json:"old_fail"`
+	NewFail    int64                    `json:"new_fail"`
+}
+
+// ErrorCountDelta は、カテゴリ別のエラー件数の差分です
+type ErrorCountDelta struct {
+	Category bencherror.Category `json:"category"`
+	Old      int64               `json:"old"`
+	New      int64               `json:"new"`
+}
+
+// Diff は、2回の走行結果の差分です
+type Diff struct {
+	OldPass  bool  `json:"old_pass"`
+	NewPass  bool  `json:"new_pass"`
+	OldScore int64 `json:"old_score"`
+	NewScore int64 `json:"new_score"`
+	// 段階の順、シナリオのタグ名の順 (どちらかの走行で実行したもの)
+	Scenarios []ScenarioDelta `json:"scenarios"`
+	// カテゴリの順 (件数が変わったもの)
+	ErrorCounts []ErrorCountDelta `json:"error_counts"`
+	// 新しい走行にのみ現れたメッセージと、古い走行にのみ現れたメッセージ
+	AppearedMessages    []string `json:"appeared_messages"`
+	DisappearedMessages []string `json:"disappeared_messages"`
+}
+
+// ScoreDelta は、スコアの差分を返します
+func (d *Diff) ScoreDelta() int64 {
+	return d.NewScore - d.OldScore
+}
+
+// Compare は、古い走行結果 old と新しい走行結果 new を比べます
+func Compare(old, new Snapshot) *Diff {
+	return &Diff{
+		OldPass:             old.Pass,
+		NewPass:             new.Pass,
+		OldScore:            old.Score,
+		NewScore:            new.Score,
+		Scenarios:           compareScenarios(old.Scenarios, new.Scenarios),
+		ErrorCounts:         compareErrorCounts(old.ErrorCounts, new.ErrorCounts),
+		AppearedMessages:    subtractMessages(new.Messages, old.Messages),
+		DisappearedMessages: subtractMessages(old.Messages, new.Messages),
+	}
+}
+
+func compareScenarios(old, new []benchscore.ScenarioCount) []ScenarioDelta {
+	type key struct {
+		phase    benchscore.ScenarioPhase
+		scenario string
+	}
+	deltas := make(map[key]*ScenarioDelta)
+	delta := func(c benchscore.ScenarioCount) *ScenarioDelta {
+		k := key{phase: c.Phase, scenario: string(c.Scenario)}
+		d, ok := deltas[k]
+		if !ok {
+			d = &ScenarioDelta{Phase: c.Phase, Scenario: string(c.Scenario)}
+			deltas[k] = d
+		}
+		return d
+	}
+	for _, c := range old {
+		d := delta(c)
+		d.OldSuccess, d.OldFail = c.Success, c.Fail
+	}
+	for _, c := range new {
+		d := delta(c)
+		d.NewSuccess, d.NewFail = c.Success, c.Fail
+	}
+
+	result := make([]ScenarioDelta, 0, len(deltas))
+	for _, d := range deltas {
+		result = append(result, *d)
+	}
+	slices.SortFunc(result, func(a, b ScenarioDelta) int {
+		if n := cmp.Compare(slices.Index(benchscore.ScenarioPhases, a.Phase), slices.Index(benchscore.ScenarioPhases, b.Phase)); n != 0 {
+			return n
+		}
+		return cmp.Compare(a.Scenario, b.Scenario)
+	})
+	return result
+}
+
+func compareErrorCounts(old, new map[bencherror.Category]int64) []ErrorCountDelta {
+	categories := append([]bencherror.Category(nil), bencherror.Categories...)
+	// NOTE: 古いベンチマーカーの結果など、既知でないカテゴリも名前の順に含める
+	var unknown []bencherror.Category
+	for _, counts := range []map[bencherror.Category]int64{old, new} {
+		for category := range counts {
+			if !slices.Contains(categories, category) && !slices.Contains(unknown, category) {
+				unknown = append(unknown, category)
+			}
+		}
+	}
+	slices.Sort(unknown)
+	categories = append(categories, unknown...)
+
+	var deltas []ErrorCountDelta
+	for _, category := range categories {
+		if old[category] == new[category] {
+			continue
+		}
+		deltas = append(deltas, ErrorCountDelta{Category: category, Old: old[category], New: new[category]})
+	}
+	return deltas
+}
+
+var digits = regexp.MustCompile(`[0-9]+`)
+
+// messageKey は、数値を除いたメッセージを返します
+// NOTE: 件数やIDなどの数値だけが異なるメッセージは、同じメッセージとして扱う
+func messageKey(msg string) string {
+	return digits.ReplaceAllString(msg, "#")
+}
+
+// subtractMessages は、a のメッセージのうち、b に現れないものを a の順に返します
+func subtractMessages(a, b []string) []string {
+	keys := make(map[string]struct{}, len(b))
+	for _, msg := range b {
+		keys[messageKey(msg)] = struct{}{}
+	}
+	var result []string
+	for _, msg := range a {
+		key := messageKey(msg)
+		if _, ok := keys[key]; ok {
+			continue
+		}
+		keys[key] = struct{}{}
+		result = append(result, msg)
+	}
+	return result
+}
+
+// Write は、差分を選手が読める形式で書き出します
+func (d *Diff) Write(w io.Writer) error {
+	p := &printer{w: w}
+	p.printf("合否: %s -> %s\n", passLabel(d.OldPass), passLabel(d.NewPass))
+	p.printf("スコア: %d -> %d (%+d)\n", d.OldScore, d.NewScore, d.ScoreDelta())
+
+	if len(d.Scenarios) > 0 {
+		p.printf("\nシナリオの成功・失敗回数:\n")
+		for _, s := range d.Scenarios {
+			p.printf("  [%s] %s: 成功 %d -> %d (%+d), 失敗 %d -> %d (%+d)\n", s.Phase, s.Scenario,
+				s.OldSuccess, s.NewSuccess, s.NewSuccess-s.OldSuccess,
+				s.OldFail, s.NewFail, s.NewFail-s.OldFail)
+		}
+	}
+	if len(d.ErrorCounts) > 0 {
+		p.printf("\nカテゴリ別のエラー件数:\n")
+		for _, e := range d.ErrorCounts {
+			p.printf("  %s: %d -> %d (%+d)\n", e.Category, e.Old, e.New, e.New-e.Old)
+		}
+	}
+	if len(d.AppearedMessages) > 0 {
+		p.printf("\n新たに現れたメッセージ:\n")
+		for _, msg := range d.AppearedMessages {
+			p.printf("  + %s\n", msg)
+		}
+	}
+	if len(d.DisappearedMessages) > 0 {
+		p.printf("\n現れなくなったメッセージ:\n")
+		for _, msg := range d.DisappearedMessages {
+			p.printf("  - %s\n", msg)
+		}
+	}
+	return p.err
+}
+
+func passLabel(pass bool) string {
+	if pass {
+		return "合格"
+	}
+	return "不合格"
+}
+
+// printer は、最初に失敗した書き込みのエラーを保持します
+type printer struct {
+	w   io.Writer
+	err error
+}
+
+func (p *printer) printf(format string, args ...any) {
+	if p.err != nil {
+		return
+	}
+	_, p.err = fmt.Fprintf(p.w, format, args...)
+}
@@ -0,0 +1,70 @@
+package resultdiff
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/isucon/isucon13/bench/internal/bencherror"
+	"github.com/isucon/isucon13/bench/internal/benchscore"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompare(t *testing.T) {
+	old := Snapshot{
+		Pass:  true,
+		Score: 1000,
+		Scenarios: []benchscore.ScenarioCount{
+			{Phase: benchscore.ScenarioPhaseLoad, Scenario: "viewer", Success: 10, Fail: 2},
+			{Phase: benchscore.ScenarioPhaseLoad, Scenario: "viewer-spam", Success: 5},
+		},
+		ErrorCounts: map[bencherror.Category]int64{
+			bencherror.CategoryTimeout: 3,
+		},
+		Messages: []string{
+			"[タイムアウト] GET /api/livestream/12 がタイムアウトしました",
+			"[一般エラー] POST /api/register のステータスコードが不正です",
+			"売上: 1000",
+		},
+	}
+	new := Snapshot{
+		Pass:  true,
+		Score: 1500,
+		Scenarios: []benchscore.ScenarioCount{
+			{Phase: benchscore.ScenarioPhasePretest, Scenario: "initial_payment", Success: 1},
+			{Phase: benchscore.ScenarioPhaseLoad, Scenario: "raid", Fail: 1},
+			{Phase: benchscore.ScenarioPhaseLoad, Scenario: "viewer", Success: 20},
+		},
+		ErrorCounts: map[bencherror.Category]int64{
+			bencherror.CategoryTimeout: 3,
+			"unknown":                  1,
+		},
+		Messages: []string{
+			"[タイムアウト] GET /api/livestream/34 がタイムアウトしました",
+			"[一般エラー] GET /api/tag のレスポンスが不正です",
+			"売上: 1500",
+		},
+	}
+
+	diff := Compare(old, new)
+	assert.Equal(t, int64(500), diff.ScoreDelta())
+
+	assert.Equal(t, []ScenarioDelta{
+		{Phase: benchscore.ScenarioPhasePretest, Scenario: "initial_payment", NewSuccess: 1},
+		{Phase: benchscore.ScenarioPhaseLoad, Scenario: "raid", NewFail: 1},
+		{Phase: benchscore.ScenarioPhaseLoad, Scenario: "viewer", OldSuccess: 10, NewSuccess: 20, OldFail: 2},
+		{Phase: benchscore.ScenarioPhaseLoad, Scenario: "viewer-spam", OldSuccess: 5},
+	}, diff.Scenarios)
+
+	// 件数が変わったカテゴリのみ
+	assert.Equal(t, []ErrorCountDelta{{Category: "unknown", New: 1}}, diff.ErrorCounts)
+
+	// 数値だけが異なるメッセージは同じものとして扱う
+	assert.Equal(t, []string{"[一般エラー] GET /api/tag のレスポンスが不正です"}, diff.AppearedMessages)
+	assert.Equal(t, []string{"[一般エラー] POST /api/register のステータスコードが不正です"}, diff.DisappearedMessages)
+
+	var buf bytes.Buffer
+	assert.NoError(t, diff.Write(&buf))
+	assert.Contains(t, buf.String(), "スコア: 1000 -> 1500 (+500)")
+	assert.Contains(t, buf.String(), "[load] viewer: 成功 10 -> 20 (+10), 失敗 2 -> 0 (-2)")
+	assert.Contains(t, buf.String(), "+ [一般エラー] GET /api/tag のレスポンスが不正です")
+}
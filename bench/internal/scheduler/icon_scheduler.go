@@ -3,10 +3,11 @@ package scheduler
 import (
 	"crypto/sha256"
 	"embed"
-	"math/rand"
 	"path/filepath"
 
 	"go.uber.org/zap"
+
+	"github.com/isucon/isucon13/bench/internal/benchrand"
 )
 
 //go:embed images/*
@@ -60,6 +61,18 @@ func mustNewIconScheduler() *IconScheduler {
 }
 
 func (s *IconScheduler) GetRandomIcon() *Image {
-	idx := rand.Intn(len(s.images))
+	idx := benchrand.Intn(len(s.images))
 	return s.images[idx]
 }
+
+// GetRandomIconExcept は、icon と異なる画像を無作為に選んで返します. 異なる画像がなければfalseを返します
+func (s *IconScheduler) GetRandomIconExcept(icon *Image) (*Image, bool) {
+	start := benchrand.Intn(len(s.images))
+	for i := range s.images {
+		img := s.images[(start+i)%len(s.images)]
+		if img.Hash != icon.Hash {
+			return img, true
+		}
+	}
+	return nil, false
+}
@@ -17,3 +17,13 @@ type InitialReaction struct {
 	LivestreamID int64
 	EmojiName    string
 }
+
+// InitialUserCount は、初期データのユーザ数を返します
+func InitialUserCount() int {
+	return len(initialUserPool)
+}
+
+// InitialLivestreamCount は、初期データのライブ配信数を返します
+func InitialLivestreamCount() int {
+	return len(initialReservationPool)
+}
@@ -1,12 +1,14 @@
 package scheduler
 
 import (
+	"context"
 	"fmt"
 	"math"
-	"math/rand"
+	"sort"
 	"sync"
 
 	"github.com/isucon/isucon13/bench/internal/bencherror"
+	"github.com/isucon/isucon13/bench/internal/benchrand"
 	"github.com/isucon/isucon13/bench/internal/config"
 )
 
@@ -60,22 +62,57 @@ type Tip struct {
 type livecommentScheduler struct {
 	ngLivecomments map[string]string
 
+	// スパムコメントと、抽選の重みの累積和
+	negativeComments  []*NegativeComment
+	cumulativeWeights []int
+	dummyNgWords      []*NgWord
+	// 定義ファイルから読み込んだ場合のSHA-256
+	corpusChecksum string
+
+	// 投稿したスパムコメント
+	exercisedMu sync.Mutex
+	exercised   map[string]struct{}
+
 	moderatedMu sync.RWMutex
 	moderated   map[string]struct{}
 }
 
+// NOTE: ダミーのNGワードはbenchrandで抽選するため、並べ替えない
+// パッケージの初期化はシードの指定より前に行われるため、ここで並べ替えると --seed で再現できなくなる
 func mustNewLivecommentScheduler() *livecommentScheduler {
-	ngLivecomments := make(map[string]string)
-	for _, comment := range negativeCommentPool {
+	s := &livecommentScheduler{
+		dummyNgWords: dummyNgWords,
+		moderated:    make(map[string]struct{}),
+		exercised:    make(map[string]struct{}),
+	}
+	// NOTE: 組み込みの定義では、すべてのスパムコメントを等しい重みで抽選する
+	weights := make([]int, len(negativeCommentPool))
+	for i := range weights {
+		weights[i] = 1
+	}
+	s.setNegativeComments(negativeCommentPool, weights)
+	return s
+}
+
+func (s *livecommentScheduler) setNegativeComments(comments []*NegativeComment, weights []int) {
+	ngLivecomments := make(map[string]string, len(comments))
+	cumulativeWeights := make([]int, len(comments))
+	total := 0
+	for i, comment := range comments {
 		ngLivecomments[comment.Comment] = comment.NgWord
+		total += weights[i]
+		cumulativeWeights[i] = total
 	}
-	rand.Shuffle(len(dummyNgWords), func(i, j int) {
-		dummyNgWords[i], dummyNgWords[j] = dummyNgWords[j], dummyNgWords[i]
-	})
-	return &livecommentScheduler{
-		ngLivecomments: ngLivecomments,
-		moderated:      make(map[string]struct{}),
+	s.ngLivecomments = ngLivecomments
+	s.negativeComments = comments
+	s.cumulativeWeights = cumulativeWeights
+}
+
+func (s *livecommentScheduler) commentWeight(idx int) int {
+	if idx == 0 {
+		return s.cumulativeWeights[0]
 	}
+	return s.cumulativeWeights[idx] - s.cumulativeWeights[idx-1]
 }
 
 // ライブコメント一覧に何件スパムが含まれるか調べるために使う
@@ -87,22 +124,22 @@ func (s *livecommentScheduler) IsNgLivecomment(comment string) bool {
 	}
 }
 
-func (s *livecommentScheduler) GetNgWord(comment string) (string, error) {
+func (s *livecommentScheduler) GetNgWord(ctx context.Context, comment string) (string, error) {
 	ngword, ok := s.ngLivecomments[comment]
 	if !ok {
-		return "", bencherror.NewInternalError(fmt.Errorf("想定されているスパムコメントではありません: %s", comment))
+		return "", bencherror.NewInternalError(ctx, fmt.Errorf("想定されているスパムコメントではありません: %s", comment))
 	}
 
 	return ngword, nil
 }
 
 func (s *livecommentScheduler) GetShortPositiveComment() *PositiveComment {
-	idx := rand.Intn(len(positiveCommentPool))
+	idx := benchrand.Intn(len(positiveCommentPool))
 	return positiveCommentPool[idx]
 }
 
 func (s *livecommentScheduler) GetLongPositiveComment() *PositiveComment {
-	idx := rand.Intn(len(positiveCommentPool))
+	idx := benchrand.Intn(len(positiveCommentPool))
 	return positiveCommentPool[idx]
 }
 
@@ -110,9 +147,18 @@ func (s *livecommentScheduler) GetNegativeComment() (*NegativeComment, bool) {
 	s.moderatedMu.RLock()
 	defer s.moderatedMu.RUnlock()
 
-	idx := rand.Intn(len(negativeCommentPool))
-	comment := negativeCommentPool[idx]
+	// NOTE: 重みの累積和を二分探索し、重みに比例した確率で選ぶ
+	r := benchrand.Intn(s.cumulativeWeights[len(s.cumulativeWeights)-1])
+	idx := sort.Search(len(s.cumulativeWeights), func(i int) bool {
+		return s.cumulativeWeights[i] > r
+	})
+	comment := s.negativeComments[idx]
 	_, isModerated := s.moderated[comment.Comment]
+
+	s.exercisedMu.Lock()
+	s.exercised[comment.Comment] = struct{}{}
+	s.exercisedMu.Unlock()
+
 	return comment, isModerated
 }
 
@@ -135,7 +181,7 @@ func (s *livecommentScheduler) ModerateNgWord(ngword string) {
 	s.moderatedMu.Lock()
 	defer s.moderatedMu.Unlock()
 
-	for _, comment := range negativeCommentPool {
+	for _, comment := range s.negativeComments {
 		if comment.NgWord == ngword {
 			s.moderated[comment.Comment] = struct{}{}
 		}
@@ -182,12 +228,12 @@ func (s *livecommentScheduler) generateTip(level int, totalHours, currentHour in
 	}
 }
 
-func (s *livecommentScheduler) GetTipsForStream(totalHours, currentHour int) (*Tip, error) {
+func (s *livecommentScheduler) GetTipsForStream(ctx context.Context, totalHours, currentHour int) (*Tip, error) {
 	if currentHour > totalHours {
-		return &Tip{Level: 0, Tip: 0}, bencherror.NewInternalError(fmt.Errorf("GetTipsForStreamの引数が不正です: current=%d, total=%d", currentHour, totalHours))
+		return &Tip{Level: 0, Tip: 0}, bencherror.NewInternalError(ctx, fmt.Errorf("GetTipsForStreamの引数が不正です: current=%d, total=%d", currentHour, totalHours))
 	}
 	if totalHours < 1 || currentHour < 1 {
-		return &Tip{Level: 0, Tip: 0}, bencherror.NewInternalError(fmt.Errorf("GetTipsForStreamの引数が不正です: current=%d, total=%d", currentHour, totalHours))
+		return &Tip{Level: 0, Tip: 0}, bencherror.NewInternalError(ctx, fmt.Errorf("GetTipsForStreamの引数が不正です: current=%d, total=%d", currentHour, totalHours))
 	}
 
 	// levelによって金額クラスが分かれる. より長い配信枠のほうが高いレベルになる. 予約が捌けているほど高いレベルになる.
@@ -214,6 +260,6 @@ func (s *livecommentScheduler) GetTipsForStream(totalHours, currentHour int) (*T
 }
 
 func (s *livecommentScheduler) GetDummyNgWord() *NgWord {
-	idx := rand.Intn(len(dummyNgWords))
-	return dummyNgWords[idx]
+	idx := benchrand.Intn(len(s.dummyNgWords))
+	return s.dummyNgWords[idx]
 }
@@ -0,0 +1,42 @@
+package scheduler
+
+import "sync"
+
+// RankingLedger は、配信のスコア(リアクション数+チップ合計)を増やしうる書き込みを記録する台帳です
+// ランキングの順位変動が、ベンチマーカーの書き込みで説明できるか検証するのに用います
+var RankingLedger = NewRankingLedger()
+
+type rankingLedger struct {
+	mu     sync.RWMutex
+	events map[int64]int64
+	total  int64
+}
+
+func NewRankingLedger() *rankingLedger {
+	return &rankingLedger{
+		events: make(map[int64]int64),
+	}
+}
+
+// RecordScoreEvent は、配信のスコアを増やしうる書き込みを記録します
+// NOTE: タイムアウトしたリクエストもwebappに反映されうるため、リクエスト送信前に記録する
+func (l *rankingLedger) RecordScoreEvent(livestreamID int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.events[livestreamID]++
+	l.total++
+}
+
+// NumScoreEvents は、これまでに記録された配信のスコアを増やしうる書き込み数を返します
+func (l *rankingLedger) NumScoreEvents(livestreamID int64) int64 {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.events[livestreamID]
+}
+
+// NumTotalScoreEvents は、これまでに記録された全配信のスコアを増やしうる書き込み数を返します
+func (l *rankingLedger) NumTotalScoreEvents() int64 {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.total
+}
@@ -283,6 +283,59 @@ func (r *ReservationScheduler) GetColdLongReservation() (*Reservation, error) {
 	return nil, ErrNoReservation
 }
 
+// GetCollisionReservation は、予約衝突の検証に用いる1時間の予約を払い出します
+// 区間内の枠をベンチ側で一切消費していないものを選び、区間とオーバーラップする予約をすべて払い出し済みにします
+// NOTE: 衝突検証を行った区間は枠が埋まるため、以後通常の予約として払い出されることはありません
+func (r *ReservationScheduler) GetCollisionReservation() (*Reservation, error) {
+	r.intTreeMu.Lock()
+	defer r.intTreeMu.Unlock()
+
+	lgr := zap.S()
+
+	// NOTE: Cold予約は期間の先頭から払い出されるので、末尾から探す
+	for i := len(r.reservationPool) - 1; i >= 0; i-- {
+		reservation := r.reservationPool[i]
+		if reservation.Hours() != 1 {
+			continue
+		}
+		if state, ok := r.intTreeStates[reservation.id]; !ok || state != CommitState_None {
+			continue
+		}
+
+		overlaps, err := ConvertFromIntInterface(r.intervalTree.Get(reservation))
+		if err != nil {
+			lgr.Warnf("GetCollisionReservation: failed to convert reservation: %s\n", err.Error())
+			return nil, err
+		}
+
+		untouched := true
+		for _, overlap := range overlaps {
+			if overlap == nil {
+				continue
+			}
+			if state, ok := r.intTreeStates[overlap.id]; ok && state != CommitState_None {
+				untouched = false
+				break
+			}
+		}
+		if !untouched {
+			continue
+		}
+
+		for _, overlap := range overlaps {
+			if overlap == nil {
+				continue
+			}
+			r.intTreeStates[overlap.id] = CommitState_Inflight
+		}
+		r.intTreeStates[reservation.id] = CommitState_Inflight
+		return reservation, nil
+	}
+
+	lgr.Warn("GetCollisionReservation: failed to get reservation (not found)")
+	return nil, ErrNoReservation
+}
+
 // 予約の突合処理に使う
 func (r *ReservationScheduler) RangeReserved(fn func(*Reservation)) {
 	r.reservationsMu.Lock()
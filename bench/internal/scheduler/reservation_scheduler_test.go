@@ -137,3 +137,34 @@ func TestReservationScheduler_Abort(t *testing.T) {
 }
 
 // membenchを実行して、リソース消費について簡単に見ておく
+
+func TestReservationScheduler_Collision(t *testing.T) {
+	var (
+		baseUnix int64 = 1711897200
+		baseAt         = time.Unix(baseUnix, 0)
+		hours          = 5
+	)
+
+	sched := mustNewReservationScheduler(baseUnix, 2, hours)
+	sched.loadReservations([]*Reservation{
+		{id: 1, StartAt: baseAt.Add(0 * time.Hour).Unix(), EndAt: baseAt.Add(1 * time.Hour).Unix()},
+		{id: 2, StartAt: baseAt.Add(0 * time.Hour).Unix(), EndAt: baseAt.Add(1 * time.Hour).Unix()},
+		{id: 3, StartAt: baseAt.Add(1 * time.Hour).Unix(), EndAt: baseAt.Add(2 * time.Hour).Unix()},
+		{id: 4, StartAt: baseAt.Add(1 * time.Hour).Unix(), EndAt: baseAt.Add(3 * time.Hour).Unix()},
+	})
+
+	// 末尾の1時間予約から払い出され、オーバーラップする予約も払い出し済みになる
+	reservation, err := sched.GetCollisionReservation()
+	assert.NoError(t, err)
+	assert.Equal(t, 3, reservation.id)
+	assert.Equal(t, CommitState_Inflight, sched.intTreeStates[4])
+
+	reservation, err = sched.GetCollisionReservation()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, reservation.id)
+	assert.Equal(t, CommitState_Inflight, sched.intTreeStates[1])
+
+	reservation, err = sched.GetCollisionReservation()
+	assert.ErrorIs(t, err, ErrNoReservation)
+	assert.Nil(t, reservation)
+}
@@ -0,0 +1,217 @@
+package scheduler
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SpamCorpusFileName は、assetdir に配置するスパムコメントとNGワードの定義ファイル名です
+const SpamCorpusFileName = "spam_corpus.json"
+
+// SpamCorpusChecksumFileName は、定義ファイルのSHA-256を記したファイル名です (sha256sum の出力形式)
+// NOTE: リハーサルと本番で定義を差し替える際に、意図した定義が配置されていることを確かめるため必須とする
+const SpamCorpusChecksumFileName = SpamCorpusFileName + ".sha256"
+
+// SpamCorpus は、視聴者が投稿するスパムコメントと、配信者が登録するダミーのNGワードの定義です
+type SpamCorpus struct {
+	Comments     []*SpamCorpusComment `json:"comments"`
+	DummyNgWords []string             `json:"dummy_ngwords"`
+}
+
+type SpamCorpusComment struct {
+	Comment string `json:"comment"`
+	NgWord  string `json:"ngword"`
+	// 抽選の重み. 0または省略した場合は1
+	Weight int `json:"weight,omitempty"`
+}
+
+// SpamCorpusCoverage は、負荷走行中に投稿したスパムコメントの種類数です. 結果に記録します
+type SpamCorpusCoverage struct {
+	// 定義ファイルのSHA-256 (組み込みの定義を用いた場合は空)
+	Checksum      string `json:"checksum,omitempty"`
+	Comments      int    `json:"comments"`
+	TotalComments int    `json:"total_comments"`
+	NgWords       int    `json:"ngwords"`
+	TotalNgWords  int    `json:"total_ngwords"`
+}
+
+// ParseSpamCorpus は、スパムコメントとNGワードの定義を読み込み、検証します
+func ParseSpamCorpus(r io.Reader) (*SpamCorpus, error) {
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+
+	var corpus SpamCorpus
+	if err := decoder.Decode(&corpus); err != nil {
+		return nil, fmt.Errorf("スパムコメントの定義を読み込めません: %w", err)
+	}
+	if err := corpus.validate(); err != nil {
+		return nil, err
+	}
+	return &corpus, nil
+}
+
+// VerifySpamCorpusChecksum は、定義ファイルの内容がチェックサムファイルに記したSHA-256と一致するか検証し、SHA-256を返します
+func VerifySpamCorpusChecksum(corpus, checksumFile []byte) (string, error) {
+	fields := strings.Fields(string(checksumFile))
+	if len(fields) == 0 {
+		return "", fmt.Errorf("%s が空です", SpamCorpusChecksumFileName)
+	}
+	sum := sha256.Sum256(corpus)
+	actual := hex.EncodeToString(sum[:])
+	if !strings.EqualFold(fields[0], actual) {
+		return "", fmt.Errorf("%s がチェックサムと一致しません (expected: %s, actual: %s)", SpamCorpusFileName, fields[0], actual)
+	}
+	return actual, nil
+}
+
+// NOTE: webappはNGワードを部分一致で判定するため、意図しないライブコメントがモデレーションの対象とならないよう検証する
+func (c *SpamCorpus) validate() error {
+	var errs []error
+	if len(c.Comments) == 0 {
+		errs = append(errs, fmt.Errorf("comments を1件以上定義してください"))
+	}
+	if len(c.DummyNgWords) == 0 {
+		errs = append(errs, fmt.Errorf("dummy_ngwords を1件以上定義してください"))
+	}
+
+	comments := make(map[string]struct{}, len(c.Comments))
+	for i, comment := range c.Comments {
+		if comment == nil {
+			errs = append(errs, fmt.Errorf("comments[%d]: 定義が空です", i))
+			continue
+		}
+		if comment.Comment == "" || comment.NgWord == "" {
+			errs = append(errs, fmt.Errorf("comments[%d]: comment と ngword を指定してください", i))
+			continue
+		}
+		if !strings.Contains(comment.Comment, comment.NgWord) {
+			errs = append(errs, fmt.Errorf("comments[%d]: comment が ngword を含んでいません: %q", i, comment.NgWord))
+		}
+		if comment.Weight < 0 {
+			errs = append(errs, fmt.Errorf("comments[%d]: weight は0以上で指定してください", i))
+		}
+		if _, ok := comments[comment.Comment]; ok {
+			errs = append(errs, fmt.Errorf("comments[%d]: comment が重複しています: %q", i, comment.Comment))
+		}
+		comments[comment.Comment] = struct{}{}
+
+		for _, positive := range positiveCommentPool {
+			if strings.Contains(positive.Comment, comment.NgWord) {
+				errs = append(errs, fmt.Errorf("comments[%d]: ngword がスパムでないライブコメントに含まれています: %q", i, comment.NgWord))
+				break
+			}
+		}
+	}
+	// NOTE: ダミーのNGワードは、投稿済みのライブコメントが削除されることも含めて負荷とするため、スパムでないライブコメントとの重複は許容する
+	for i, word := range c.DummyNgWords {
+		if word == "" {
+			errs = append(errs, fmt.Errorf("dummy_ngwords[%d]: 空のNGワードは指定できません", i))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// LoadSpamCorpus は、assetdir に定義ファイルがあれば、スパムコメントとNGワードをその定義で置き換え、定義ファイルのSHA-256を返します
+// 定義ファイルがない場合は、組み込みの定義を用い、falseを返します
+func (s *livecommentScheduler) LoadSpamCorpus(assetDir string) (string, bool, error) {
+	path := filepath.Join(assetDir, SpamCorpusFileName)
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", false, nil
+	} else if err != nil {
+		return "", false, err
+	}
+	checksumFile, err := os.ReadFile(filepath.Join(assetDir, SpamCorpusChecksumFileName))
+	if err != nil {
+		return "", false, fmt.Errorf("%s: チェックサムファイルを読み込めません (sha256sum %s > %s で作成してください): %w", path, SpamCorpusFileName, SpamCorpusChecksumFileName, err)
+	}
+	checksum, err := VerifySpamCorpusChecksum(b, checksumFile)
+	if err != nil {
+		return "", false, fmt.Errorf("%s: %w", path, err)
+	}
+
+	corpus, err := ParseSpamCorpus(bytes.NewReader(b))
+	if err != nil {
+		return "", false, fmt.Errorf("%s: %w", path, err)
+	}
+	s.useSpamCorpus(corpus, checksum)
+	return checksum, true, nil
+}
+
+// ExportSpamCorpus は、現在のスパムコメントとNGワードを定義ファイルの形式で書き出します
+// NOTE: 組み込みの定義を書き出し、リハーサルと本番で定義を差し替える際の雛形とする
+func (s *livecommentScheduler) ExportSpamCorpus(w io.Writer) error {
+	corpus := &SpamCorpus{
+		Comments:     make([]*SpamCorpusComment, len(s.negativeComments)),
+		DummyNgWords: make([]string, len(s.dummyNgWords)),
+	}
+	for i, comment := range s.negativeComments {
+		corpus.Comments[i] = &SpamCorpusComment{
+			Comment: comment.Comment,
+			NgWord:  comment.NgWord,
+			Weight:  s.commentWeight(i),
+		}
+	}
+	for i, word := range s.dummyNgWords {
+		corpus.DummyNgWords[i] = word.Word
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+	return encoder.Encode(corpus)
+}
+
+func (s *livecommentScheduler) useSpamCorpus(corpus *SpamCorpus, checksum string) {
+	comments := make([]*NegativeComment, len(corpus.Comments))
+	weights := make([]int, len(corpus.Comments))
+	for i, comment := range corpus.Comments {
+		comments[i] = &NegativeComment{Comment: comment.Comment, NgWord: comment.NgWord}
+		weights[i] = max(comment.Weight, 1)
+	}
+	dummyNgWords := make([]*NgWord, len(corpus.DummyNgWords))
+	for i, word := range corpus.DummyNgWords {
+		dummyNgWords[i] = &NgWord{Word: word}
+	}
+
+	s.setNegativeComments(comments, weights)
+	s.dummyNgWords = dummyNgWords
+	s.corpusChecksum = checksum
+}
+
+// SpamCorpusCoverage は、ResetSpamCorpusCoverage を呼び出してから投稿したスパムコメントとそのNGワードの種類数を返します
+func (s *livecommentScheduler) SpamCorpusCoverage() *SpamCorpusCoverage {
+	s.exercisedMu.Lock()
+	defer s.exercisedMu.Unlock()
+
+	ngwords := make(map[string]struct{})
+	for _, comment := range s.negativeComments {
+		ngwords[comment.NgWord] = struct{}{}
+	}
+	exercisedNgWords := make(map[string]struct{})
+	for comment := range s.exercised {
+		exercisedNgWords[s.ngLivecomments[comment]] = struct{}{}
+	}
+	return &SpamCorpusCoverage{
+		Checksum:      s.corpusChecksum,
+		Comments:      len(s.exercised),
+		TotalComments: len(s.negativeComments),
+		NgWords:       len(exercisedNgWords),
+		TotalNgWords:  len(ngwords),
+	}
+}
+
+// ResetSpamCorpusCoverage は、投稿したスパムコメントの記録を破棄します. 負荷走行の開始時に呼び出します
+func (s *livecommentScheduler) ResetSpamCorpusCoverage() {
+	s.exercisedMu.Lock()
+	defer s.exercisedMu.Unlock()
+	s.exercised = make(map[string]struct{})
+}
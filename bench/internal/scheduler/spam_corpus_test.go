@@ -0,0 +1,116 @@
+package scheduler
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeSpamCorpus(t *testing.T, dir string, b []byte) {
+	sum := sha256.Sum256(b)
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, SpamCorpusFileName), b, 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, SpamCorpusChecksumFileName), []byte(hex.EncodeToString(sum[:])+"  "+SpamCorpusFileName+"\n"), 0644))
+}
+
+func TestParseSpamCorpus_Invalid(t *testing.T) {
+	corpus := &SpamCorpus{
+		Comments: []*SpamCorpusComment{
+			{Comment: "このNAT、セキュア？", NgWord: "NAT"},
+			{Comment: "このNAT、セキュア？", NgWord: "NAT"},
+			{Comment: "WEP、最新版使ってる？", NgWord: "TFTP"},
+			{Comment: "ありがとう、ありがとう", NgWord: "ありがとう"},
+			{Comment: "このQoS、何のために入れた？", NgWord: "QoS", Weight: -1},
+		},
+		DummyNgWords: []string{"量子音響学", "", "応援"},
+	}
+	b, err := json.Marshal(corpus)
+	assert.NoError(t, err)
+
+	_, err = ParseSpamCorpus(bytes.NewReader(b))
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "comments[1]")
+		assert.Contains(t, err.Error(), "comments[2]")
+		assert.Contains(t, err.Error(), "comments[3]")
+		assert.Contains(t, err.Error(), "comments[4]")
+		assert.Contains(t, err.Error(), "dummy_ngwords[1]")
+		assert.NotContains(t, err.Error(), "comments[0]")
+		assert.NotContains(t, err.Error(), "dummy_ngwords[0]")
+		assert.NotContains(t, err.Error(), "dummy_ngwords[2]")
+	}
+
+	_, err = ParseSpamCorpus(bytes.NewReader([]byte(`{"comments": [], "dummy_ngwords": [], "positive": []}`)))
+	assert.Error(t, err)
+}
+
+func TestLoadSpamCorpus(t *testing.T) {
+	sched := mustNewLivecommentScheduler()
+
+	// 定義ファイルがなければ組み込みの定義を用いる
+	dir := t.TempDir()
+	_, loaded, err := sched.LoadSpamCorpus(dir)
+	assert.NoError(t, err)
+	assert.False(t, loaded)
+	assert.Len(t, sched.negativeComments, len(negativeCommentPool))
+
+	// 組み込みの定義を書き出したものは、そのまま読み込める
+	var buf bytes.Buffer
+	assert.NoError(t, sched.ExportSpamCorpus(&buf))
+	writeSpamCorpus(t, dir, buf.Bytes())
+	checksum, loaded, err := sched.LoadSpamCorpus(dir)
+	assert.NoError(t, err)
+	assert.True(t, loaded)
+	assert.Len(t, checksum, 64)
+	assert.Len(t, sched.negativeComments, len(negativeCommentPool))
+	assert.Len(t, sched.dummyNgWords, len(dummyNgWords))
+
+	// チェックサムが一致しなければ読み込まない
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, SpamCorpusChecksumFileName), []byte("0000  "+SpamCorpusFileName+"\n"), 0644))
+	_, _, err = sched.LoadSpamCorpus(dir)
+	assert.Error(t, err)
+	assert.NoError(t, os.Remove(filepath.Join(dir, SpamCorpusChecksumFileName)))
+	_, _, err = sched.LoadSpamCorpus(dir)
+	assert.Error(t, err)
+}
+
+func TestSpamCorpus_WeightedSampling(t *testing.T) {
+	sched := mustNewLivecommentScheduler()
+	dir := t.TempDir()
+	b, err := json.Marshal(&SpamCorpus{
+		Comments: []*SpamCorpusComment{
+			{Comment: "このNAT、セキュア？", NgWord: "NAT", Weight: 99},
+			{Comment: "WEP、最新版使ってる？", NgWord: "WEP"},
+			{Comment: "このQoS、何のために入れた？", NgWord: "QoS"},
+		},
+		DummyNgWords: []string{"量子音響学"},
+	})
+	assert.NoError(t, err)
+	writeSpamCorpus(t, dir, b)
+	_, loaded, err := sched.LoadSpamCorpus(dir)
+	assert.NoError(t, err)
+	assert.True(t, loaded)
+
+	sched.ResetSpamCorpusCoverage()
+	counts := make(map[string]int)
+	for i := 0; i < 1000; i++ {
+		comment, _ := sched.GetNegativeComment()
+		counts[comment.NgWord]++
+	}
+	assert.Greater(t, counts["NAT"], 900)
+	assert.Equal(t, "量子音響学", sched.GetDummyNgWord().Word)
+
+	coverage := sched.SpamCorpusCoverage()
+	assert.Equal(t, 3, coverage.TotalComments)
+	assert.Equal(t, 3, coverage.TotalNgWords)
+	assert.Equal(t, coverage.Comments, len(counts))
+	assert.Equal(t, coverage.NgWords, len(counts))
+	assert.NotEmpty(t, coverage.Checksum)
+
+	sched.ResetSpamCorpusCoverage()
+	assert.Equal(t, 0, sched.SpamCorpusCoverage().Comments)
+}
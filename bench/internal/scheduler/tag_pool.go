@@ -1,5 +1,7 @@
 package scheduler
 
+import "fmt"
+
 var tagPool = [...]string{
 	"ライブ配信",
 	"ゲーム実況",
@@ -117,3 +119,41 @@ func GetTagsMap() map[int64]string {
 	}
 	return tags
 }
+
+// CheckTagList は、タグ一覧がマスタデータと完全に一致するか確かめ、食い違いを返します
+// ids と names はレスポンスの順に並べたタグのIDと名前です
+// NOTE: API仕様はタグの並び順を定めないため、IDと名前の組を集合として比較する
+func CheckTagList(ids []int64, names []string) []string {
+	var problems []string
+	if len(ids) != len(tagPool) {
+		problems = append(problems, fmt.Sprintf("タグの数が正しくありません (expected:%d actual:%d)", len(tagPool), len(ids)))
+	}
+
+	seenIDs := make(map[int64]struct{}, len(ids))
+	seenNames := make(map[string]struct{}, len(names))
+	for i, id := range ids {
+		name := names[i]
+		if _, ok := seenIDs[id]; ok {
+			problems = append(problems, fmt.Sprintf("タグID %d が重複しています", id))
+		}
+		seenIDs[id] = struct{}{}
+		if _, ok := seenNames[name]; ok {
+			problems = append(problems, fmt.Sprintf("タグ名 %q が重複しています", name))
+		}
+		seenNames[name] = struct{}{}
+
+		if id < 1 || int(id) > len(tagPool) {
+			problems = append(problems, fmt.Sprintf("存在しないタグID %d (%q) が含まれています", id, name))
+			continue
+		}
+		if want := tagPool[id-1]; name != want {
+			problems = append(problems, fmt.Sprintf("タグID %d の名前が正しくありません (expected:%q actual:%q)", id, want, name))
+		}
+	}
+	for i, name := range tagPool {
+		if _, ok := seenIDs[int64(i+1)]; !ok {
+			problems = append(problems, fmt.Sprintf("タグID %d (%q) が含まれていません", i+1, name))
+		}
+	}
+	return problems
+}
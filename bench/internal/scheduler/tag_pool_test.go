@@ -0,0 +1,58 @@
+package scheduler
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func masterTagList() ([]int64, []string) {
+	ids := make([]int64, len(tagPool))
+	names := make([]string, len(tagPool))
+	for i, name := range tagPool {
+		ids[i] = int64(i + 1)
+		names[i] = name
+	}
+	return ids, names
+}
+
+func TestCheckTagList(t *testing.T) {
+	ids, names := masterTagList()
+	assert.Empty(t, CheckTagList(ids, names))
+
+	// 順序は問わない
+	ids, names = masterTagList()
+	for i, j := 0, len(ids)-1; i < j; i, j = i+1, j-1 {
+		ids[i], ids[j] = ids[j], ids[i]
+		names[i], names[j] = names[j], names[i]
+	}
+	assert.Empty(t, CheckTagList(ids, names))
+
+	// IDと名前の組の入れ替え
+	ids, names = masterTagList()
+	names[0], names[1] = names[1], names[0]
+	assert.ElementsMatch(t, []string{
+		`タグID 1 の名前が正しくありません (expected:"ライブ配信" actual:"ゲーム実況")`,
+		`タグID 2 の名前が正しくありません (expected:"ゲーム実況" actual:"ライブ配信")`,
+	}, CheckTagList(ids, names))
+
+	// 重複と欠落
+	ids, names = masterTagList()
+	ids[2], names[2] = ids[1], names[1]
+	assert.ElementsMatch(t, []string{
+		"タグID 2 が重複しています",
+		`タグ名 "ゲーム実況" が重複しています`,
+		`タグID 3 ("生放送") が含まれていません`,
+	}, CheckTagList(ids, names))
+
+	// 名前の誤りと過不足
+	ids, names = masterTagList()
+	names[0] = "ライブ"
+	ids = append(ids, 1000)
+	names = append(names, "存在しない")
+	assert.ElementsMatch(t, []string{
+		"タグの数が正しくありません (expected:103 actual:104)",
+		`タグID 1 の名前が正しくありません (expected:"ライブ配信" actual:"ライブ")`,
+		`存在しないタグID 1000 ("存在しない") が含まれています`,
+	}, CheckTagList(ids, names))
+}
@@ -0,0 +1,157 @@
+package scheduler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/isucon/isucon13/bench/internal/config"
+)
+
+// PopulationFileName は、assetdir に配置する配信者・視聴者の定義ファイル名です
+const PopulationFileName = "users.json"
+
+// ユーザ名はサブドメインとしても用いられるため、DNSのラベルとして使える文字に制限する
+var populationUserNameRegexp = regexp.MustCompile(`^[a-z0-9]{1,63}$`)
+
+// Population は、走行中にベンチマーカーが登録する配信者・視聴者の定義です
+// NOTE: 初期データのユーザはwebappのデータベースと一致している必要があるため、定義の対象外
+type Population struct {
+	Streamers []*PopulationUser `json:"streamers"`
+	Viewers   []*PopulationUser `json:"viewers"`
+}
+
+type PopulationUser struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name"`
+	Description string `json:"description"`
+	Password    string `json:"password"`
+	DarkMode    bool   `json:"dark_mode"`
+}
+
+// ParsePopulation は、配信者・視聴者の定義を読み込み、検証します
+func ParsePopulation(r io.Reader) (*Population, error) {
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+
+	var population Population
+	if err := decoder.Decode(&population); err != nil {
+		return nil, fmt.Errorf("配信者・視聴者の定義を読み込めません: %w", err)
+	}
+	if err := population.validate(); err != nil {
+		return nil, err
+	}
+	return &population, nil
+}
+
+func (p *Population) validate() error {
+	// NOTE: 負荷走行の開始時に、配信者・視聴者それぞれ config.NumMustTryLogins 人のログインを試みるまで待つ
+	var errs []error
+	if len(p.Streamers) < config.NumMustTryLogins {
+		errs = append(errs, fmt.Errorf("配信者は %d 人以上定義してください (%d 人)", config.NumMustTryLogins, len(p.Streamers)))
+	}
+	if len(p.Viewers) < config.NumMustTryLogins {
+		errs = append(errs, fmt.Errorf("視聴者は %d 人以上定義してください (%d 人)", config.NumMustTryLogins, len(p.Viewers)))
+	}
+
+	names := make(map[string]struct{}, len(initialUserPool)+len(p.Streamers)+len(p.Viewers))
+	for _, user := range initialUserPool {
+		names[user.Name] = struct{}{}
+	}
+	for _, group := range []struct {
+		label string
+		users []*PopulationUser
+	}{
+		{"streamers", p.Streamers},
+		{"viewers", p.Viewers},
+	} {
+		for i, user := range group.users {
+			if user == nil {
+				errs = append(errs, fmt.Errorf("%s[%d]: 定義が空です", group.label, i))
+				continue
+			}
+			if !populationUserNameRegexp.MatchString(user.Name) {
+				errs = append(errs, fmt.Errorf("%s[%d]: name は英小文字と数字の63文字以内で指定してください: %q", group.label, i, user.Name))
+			}
+			if _, ok := names[user.Name]; ok {
+				errs = append(errs, fmt.Errorf("%s[%d]: name が初期データまたは他のユーザと重複しています: %q", group.label, i, user.Name))
+			}
+			names[user.Name] = struct{}{}
+			if user.DisplayName == "" {
+				errs = append(errs, fmt.Errorf("%s[%d]: display_name を指定してください", group.label, i))
+			}
+			if user.Description == "" {
+				errs = append(errs, fmt.Errorf("%s[%d]: description を指定してください", group.label, i))
+			}
+			if user.Password == "" {
+				errs = append(errs, fmt.Errorf("%s[%d]: password を指定してください", group.label, i))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (p *Population) users() (streamers []*User, viewers []*User) {
+	convert := func(users []*PopulationUser) []*User {
+		converted := make([]*User, len(users))
+		for i, user := range users {
+			converted[i] = &User{
+				Name:        user.Name,
+				DisplayName: user.DisplayName,
+				Description: user.Description,
+				RawPassword: user.Password,
+				DarkMode:    user.DarkMode,
+			}
+		}
+		return converted
+	}
+	return convert(p.Streamers), convert(p.Viewers)
+}
+
+// LoadPopulation は、assetdir に定義ファイルがあれば、配信者・視聴者をその定義で置き換えます
+// 定義ファイルがない場合は、組み込みの配信者・視聴者を用い、falseを返します
+func (s *userScheduler) LoadPopulation(assetDir string) (bool, error) {
+	f, err := os.Open(filepath.Join(assetDir, PopulationFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	population, err := ParsePopulation(f)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", f.Name(), err)
+	}
+	s.streamerPool, s.viewerPool = population.users()
+	return true, nil
+}
+
+// ExportPopulation は、現在の配信者・視聴者を定義ファイルの形式で書き出します
+// NOTE: 組み込みの定義を書き出し、リハーサルごとに人数や認証情報を変える際の雛形とする
+func (s *userScheduler) ExportPopulation(w io.Writer) error {
+	convert := func(users []*User) []*PopulationUser {
+		converted := make([]*PopulationUser, len(users))
+		for i, user := range users {
+			converted[i] = &PopulationUser{
+				Name:        user.Name,
+				DisplayName: user.DisplayName,
+				Description: user.Description,
+				Password:    user.RawPassword,
+				DarkMode:    user.DarkMode,
+			}
+		}
+		return converted
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(&Population{
+		Streamers: convert(s.streamerPool),
+		Viewers:   convert(s.viewerPool),
+	})
+}
@@ -0,0 +1,95 @@
+package scheduler
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/isucon/isucon13/bench/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestPopulation(n int) *Population {
+	population := &Population{}
+	for i := 0; i < n; i++ {
+		population.Streamers = append(population.Streamers, &PopulationUser{
+			Name: fmt.Sprintf("rehearsalstreamer%d", i), DisplayName: "配信者", Description: "リハーサル用", Password: "s3cret",
+		})
+		population.Viewers = append(population.Viewers, &PopulationUser{
+			Name: fmt.Sprintf("rehearsalviewer%d", i), DisplayName: "視聴者", Description: "リハーサル用", Password: "s3cret",
+		})
+	}
+	return population
+}
+
+func TestParsePopulation(t *testing.T) {
+	b, err := json.Marshal(newTestPopulation(config.NumMustTryLogins))
+	assert.NoError(t, err)
+
+	population, err := ParsePopulation(bytes.NewReader(b))
+	assert.NoError(t, err)
+	assert.Len(t, population.Streamers, config.NumMustTryLogins)
+	assert.Len(t, population.Viewers, config.NumMustTryLogins)
+}
+
+func TestParsePopulation_Invalid(t *testing.T) {
+	population := newTestPopulation(config.NumMustTryLogins)
+	population.Streamers[0].Name = "Invalid_Name"
+	population.Streamers[1].Name = "test001"
+	population.Viewers[0].Name = population.Viewers[1].Name
+	population.Viewers[2].Password = ""
+	b, err := json.Marshal(population)
+	assert.NoError(t, err)
+
+	_, err = ParsePopulation(bytes.NewReader(b))
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "streamers[0]")
+		assert.Contains(t, err.Error(), "streamers[1]")
+		assert.Contains(t, err.Error(), "viewers[1]")
+		assert.Contains(t, err.Error(), "viewers[2]")
+	}
+
+	_, err = ParsePopulation(bytes.NewReader([]byte(`{"streamers": [], "viewers": [], "admins": []}`)))
+	assert.Error(t, err)
+
+	b, err = json.Marshal(newTestPopulation(config.NumMustTryLogins - 1))
+	assert.NoError(t, err)
+	_, err = ParsePopulation(bytes.NewReader(b))
+	assert.Error(t, err)
+}
+
+func TestLoadPopulation(t *testing.T) {
+	sched := mustNewUserScheduler()
+
+	// 定義ファイルがなければ組み込みの定義を用いる
+	dir := t.TempDir()
+	loaded, err := sched.LoadPopulation(dir)
+	assert.NoError(t, err)
+	assert.False(t, loaded)
+	assert.Len(t, sched.streamerPool, len(streamerPool))
+
+	// 組み込みの定義を書き出したものは、そのまま読み込める
+	var buf bytes.Buffer
+	assert.NoError(t, sched.ExportPopulation(&buf))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, PopulationFileName), buf.Bytes(), 0644))
+	loaded, err = sched.LoadPopulation(dir)
+	assert.NoError(t, err)
+	assert.True(t, loaded)
+	assert.Len(t, sched.streamerPool, len(streamerPool))
+	assert.Len(t, sched.viewerPool, len(viewerPool))
+
+	b, err := json.Marshal(newTestPopulation(config.NumMustTryLogins))
+	assert.NoError(t, err)
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, PopulationFileName), b, 0644))
+	loaded, err = sched.LoadPopulation(dir)
+	assert.NoError(t, err)
+	assert.True(t, loaded)
+	assert.Len(t, sched.streamerPool, config.NumMustTryLogins)
+	assert.Contains(t, sched.RawPasswords(), "s3cret")
+	sched.RangeViewer(func(viewer *User) {
+		assert.Equal(t, "視聴者", viewer.DisplayName)
+	})
+}
@@ -2,7 +2,8 @@ package scheduler
 
 import (
 	"fmt"
-	"math/rand"
+
+	"github.com/isucon/isucon13/bench/internal/benchrand"
 )
 
 var UserScheduler = mustNewUserScheduler()
@@ -18,11 +19,13 @@ type User struct {
 
 type userScheduler struct {
 	streamerPool []*User
+	viewerPool   []*User
 }
 
 func mustNewUserScheduler() *userScheduler {
 	sched := new(userScheduler)
 	sched.streamerPool = streamerPool
+	sched.viewerPool = viewerPool
 
 	return sched
 }
@@ -36,13 +39,13 @@ func (s *userScheduler) RangeStreamer(fn func(streamer *User)) {
 
 // テスト用
 func (s *userScheduler) GetRandomStreamer() *User {
-	idx := rand.Intn(len(s.streamerPool))
+	idx := benchrand.Intn(len(s.streamerPool))
 	return s.streamerPool[idx]
 }
 
 // 視聴者 (様々な動きをする視聴者を用意するが、どれも同じ視聴者として扱えるようにする)
 func (s *userScheduler) RangeViewer(fn func(viewer *User)) {
-	for _, viewer := range viewerPool {
+	for _, viewer := range s.viewerPool {
 		fn(viewer)
 	}
 }
@@ -55,3 +58,15 @@ func (s *userScheduler) GetInitialUserForPretest(id int64) (*User, error) {
 
 	return initialUserPool[idx], nil
 }
+
+// RawPasswords は、初期データと走行で利用する全ユーザの平文パスワードを返します
+// NOTE: ログなどに書き出さないよう、秘匿情報として登録するために用いる
+func (s *userScheduler) RawPasswords() []string {
+	passwords := make([]string, 0, len(initialUserPool)+len(s.streamerPool)+len(s.viewerPool))
+	for _, pools := range [][]*User{initialUserPool, s.streamerPool, s.viewerPool} {
+		for _, user := range pools {
+			passwords = append(passwords, user.RawPassword)
+		}
+	}
+	return passwords
+}
@@ -0,0 +1,86 @@
+// series は、同一条件で連続して走行した結果を集計します
+//
+// スコアの重みを調整する際に、運営が複数回の走行を外部のスクリプトで回して手作業で集計していたものを置き換えます
+package series
+
+import (
+	"math"
+	"time"
+)
+
+// Run は、1回の走行の結果です
+type Run struct {
+	// 1始まりの走行番号
+	Index    int    `json:"index"`
+	Pass     bool   `json:"pass"`
+	Score    int64  `json:"score"`
+	Language string `json:"language,omitempty"`
+	Seed     int64  `json:"seed"`
+	// 走行結果のファイル
+	ResultPath string `json:"result_path"`
+	// 走行全体の所要時間[ms]
+	DurationMillis int64 `json:"duration_ms"`
+	// 結果を読み込めなかった場合など、ベンチマーカー自体の失敗
+	Error string `json:"error,omitempty"`
+}
+
+// Summary は、連続した走行全体の集計です
+type Summary struct {
+	StartedAt time.Time `json:"started_at"`
+	Runs      int       `json:"runs"`
+	Passed    int       `json:"passed"`
+	// スコアは合格した走行のみで集計する
+	MeanScore float64 `json:"mean_score"`
+	// 標本標準偏差 (合格した走行が1回以下の場合は0)
+	StddevScore float64 `json:"stddev_score"`
+	BestScore   int64   `json:"best_score"`
+	WorstScore  int64   `json:"worst_score"`
+	// 平均に対する標準偏差の割合 (走行ごとのばらつきの目安)
+	CoefficientOfVariation float64 `json:"coefficient_of_variation"`
+	Results                []Run   `json:"results"`
+}
+
+// Aggregate は、走行結果を集計します
+func Aggregate(startedAt time.Time, runs []Run) *Summary {
+	summary := &Summary{
+		StartedAt: startedAt,
+		Runs:      len(runs),
+		Results:   runs,
+	}
+
+	var scores []float64
+	for _, run := range runs {
+		if !run.Pass {
+			continue
+		}
+		summary.Passed++
+		if len(scores) == 0 || run.Score > summary.BestScore {
+			summary.BestScore = run.Score
+		}
+		if len(scores) == 0 || run.Score < summary.WorstScore {
+			summary.WorstScore = run.Score
+		}
+		scores = append(scores, float64(run.Score))
+	}
+	if len(scores) == 0 {
+		return summary
+	}
+
+	var total float64
+	for _, score := range scores {
+		total += score
+	}
+	summary.MeanScore = total / float64(len(scores))
+	if len(scores) > 1 {
+		var squares float64
+		for _, score := range scores {
+			d := score - summary.MeanScore
+			squares += d * d
+		}
+		summary.StddevScore = math.Sqrt(squares / float64(len(scores)-1))
+	}
+	if summary.MeanScore > 0 {
+		summary.CoefficientOfVariation = summary.StddevScore / summary.MeanScore
+	}
+	return summary
+}
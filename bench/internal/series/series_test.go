@@ -0,0 +1,43 @@
+package series
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAggregate(t *testing.T) {
+	startedAt := time.Date(2023, 11, 25, 10, 0, 0, 0, time.UTC)
+	runs := []Run{
+		{Index: 1, Pass: true, Score: 1000},
+		{Index: 2, Pass: false, Score: 0, Error: "走行結果を読み込めませんでした"},
+		{Index: 3, Pass: true, Score: 1400},
+		{Index: 4, Pass: true, Score: 1200},
+	}
+
+	summary := Aggregate(startedAt, runs)
+	assert.Equal(t, startedAt, summary.StartedAt)
+	assert.Equal(t, 4, summary.Runs)
+	assert.Equal(t, 3, summary.Passed)
+	assert.InDelta(t, 1200, summary.MeanScore, 1e-9)
+	assert.InDelta(t, 200, summary.StddevScore, 1e-9)
+	assert.Equal(t, int64(1400), summary.BestScore)
+	assert.Equal(t, int64(1000), summary.WorstScore)
+	assert.InDelta(t, 200.0/1200.0, summary.CoefficientOfVariation, 1e-9)
+	assert.Len(t, summary.Results, 4)
+}
+
+func TestAggregate_NoPassed(t *testing.T) {
+	summary := Aggregate(time.Time{}, []Run{{Index: 1}})
+	assert.Equal(t, 1, summary.Runs)
+	assert.Equal(t, 0, summary.Passed)
+	assert.Zero(t, summary.MeanScore)
+	assert.Zero(t, summary.StddevScore)
+
+	summary = Aggregate(time.Time{}, []Run{{Index: 1, Pass: true, Score: 500}})
+	assert.InDelta(t, 500, summary.MeanScore, 1e-9)
+	assert.Zero(t, summary.StddevScore)
+	assert.Equal(t, int64(500), summary.BestScore)
+	assert.Equal(t, int64(500), summary.WorstScore)
+}
@@ -0,0 +1,287 @@
+// slowloris は、リクエストを少しずつ送る・ボディを送り終えない行儀の悪いクライアントを模し、
+// webappがそれらの接続を打ち切れるか、その間も正規のリクエストに応答できるかを確かめます
+//
+// 接続が打ち切られた場合はその時刻と閉じ方を、打ち切られなかった場合は保持できた時間を記録します
+package slowloris
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"syscall"
+	"time"
+)
+
+// Mode は、行儀の悪いクライアントの振る舞いです
+type Mode string
+
+const (
+	// ModeTrickleHeaders は、リクエストヘッダを少しずつ送り続け、ヘッダを送り終えない
+	ModeTrickleHeaders Mode = "trickle-headers"
+	// ModePartialBody は、Content-Lengthより短いボディを少しずつ送り、ボディを送り終えない
+	ModePartialBody Mode = "partial-body"
+)
+
+// Modes は、確かめる振る舞いの一覧です
+var Modes = []Mode{ModeTrickleHeaders, ModePartialBody}
+
+// Target は、確かめる宛先です
+type Target struct {
+	// 接続先 (host:port)
+	Addr string
+	// Hostヘッダ (TLSの場合はSNIにも用いる)
+	Host string
+	// ヘッダを少しずつ送るリクエストのパス
+	Path string
+	// ボディを送り終えないリクエストのパス (POSTを受け付けるもの)
+	BodyPath string
+	// nilでなければTLSで接続する
+	TLS *tls.Config
+}
+
+// Options は、1つの接続の振る舞いの設定です
+type Options struct {
+	// 数バイトずつ送る間隔
+	TrickleInterval time.Duration
+	// 接続を保持する上限. これを超えても打ち切られなければ、webappが打ち切らなかったものとする
+	HoldLimit time.Duration
+	// ボディを送り終えないリクエストで宣言するContent-Length
+	DeclaredBodyLength int
+}
+
+// Result は、1つの接続の結果です
+type Result struct {
+	Mode Mode `json:"mode"`
+	// webappが接続を打ち切ったか (408などのレスポンスを返した場合を含む)
+	Closed bool `json:"closed"`
+	// 打ち切られるまでの時間 (Closedの場合のみ)
+	ClosedAfter time.Duration `json:"closed_after,omitempty"`
+	// 打ち切る前にレスポンスを返したか (Closedの場合のみ)
+	Responded bool `json:"responded,omitempty"`
+	// 打ち切られるまでに送ったバイト数
+	BytesSent int64  `json:"bytes_sent"`
+	Error     string `json:"error,omitempty"`
+}
+
+func dialTarget(ctx context.Context, dial func(ctx context.Context, network, addr string) (net.Conn, error), target Target) (net.Conn, error) {
+	c, err := dial(ctx, "tcp", target.Addr)
+	if err != nil {
+		return nil, err
+	}
+	if target.TLS != nil {
+		cfg := target.TLS.Clone()
+		if len(cfg.ServerName) == 0 {
+			cfg.ServerName = target.Host
+		}
+		// NOTE: HTTP/2では1つの接続に複数のストリームを多重化できるため、HTTP/1.1に限る
+		cfg.NextProtos = []string{"http/1.1"}
+		tlsConn := tls.Client(c, cfg)
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			c.Close()
+			return nil, err
+		}
+		c = tlsConn
+	}
+	return c, nil
+}
+
+// request は、振る舞いごとに最初に送る部分と、その後に少しずつ送る部分を返します
+func request(mode Mode, target Target, opts Options) (head string, trickle func(i int) string) {
+	switch mode {
+	case ModePartialBody:
+		head = fmt.Sprintf("POST %s HTTP/1.1\r\nHost: %s\r\nContent-Type: application/json\r\nContent-Length: %d\r\n\r\n{", target.BodyPath, target.Host, opts.DeclaredBodyLength)
+		return head, func(i int) string {
+			// NOTE: 宣言した長さに達するとリクエストが完結してしまうため、手前で止める
+			if i+2 >= opts.DeclaredBodyLength {
+				return ""
+			}
+			return " "
+		}
+	default:
+		head = fmt.Sprintf("GET %s HTTP/1.1\r\nHost: %s\r\n", target.Path, target.Host)
+		return head, func(i int) string {
+			return fmt.Sprintf("X-Trickle-%d: %d\r\n", i, i)
+		}
+	}
+}
+
+// Attack は、mode の振る舞いで接続を保持し、webappが打ち切るまでの時間を確かめます
+func Attack(ctx context.Context, dial func(ctx context.Context, network, addr string) (net.Conn, error), target Target, mode Mode, opts Options) Result {
+	result := Result{Mode: mode}
+
+	dialCtx, cancel := context.WithTimeout(ctx, opts.HoldLimit)
+	c, err := dialTarget(dialCtx, dial, target)
+	cancel()
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	defer c.Close()
+
+	// webappからの切断やレスポンスを待つ
+	closed := make(chan error, 1)
+	go func() {
+		_, err := bufio.NewReader(c).Peek(1)
+		closed <- err
+	}()
+
+	startAt := time.Now()
+	deadline := startAt.Add(opts.HoldLimit)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	c.SetDeadline(deadline)
+
+	head, trickle := request(mode, target, opts)
+	n, err := io.WriteString(c, head)
+	result.BytesSent += int64(n)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	ticker := time.NewTicker(opts.TrickleInterval)
+	defer ticker.Stop()
+	for i := 0; ; i++ {
+		select {
+		case err := <-closed:
+			return observeClose(result, startAt, err)
+		case <-ticker.C:
+			chunk := trickle(i)
+			if len(chunk) == 0 {
+				continue
+			}
+			n, err := io.WriteString(c, chunk)
+			result.BytesSent += int64(n)
+			if err != nil {
+				if isClosedByPeer(err) {
+					result.Closed = true
+					result.ClosedAfter = time.Since(startAt)
+					return result
+				}
+				// NOTE: 書き込みの期限切れは、読み込み側で保持の上限に達したものとして扱う
+				if !errors.Is(err, os.ErrDeadlineExceeded) {
+					result.Error = err.Error()
+					return result
+				}
+			}
+		}
+	}
+}
+
+// observeClose は、読み込みが終わった理由から、webappが接続を打ち切ったかを判断します
+func observeClose(result Result, startAt time.Time, err error) Result {
+	switch {
+	case err == nil:
+		// 408 Request Timeout などのレスポンスを返した
+		result.Closed = true
+		result.Responded = true
+		result.ClosedAfter = time.Since(startAt)
+	case errors.Is(err, os.ErrDeadlineExceeded):
+		// 保持の上限まで打ち切られなかった
+	case isClosedByPeer(err):
+		result.Closed = true
+		result.ClosedAfter = time.Since(startAt)
+	default:
+		result.Error = err.Error()
+	}
+	return result
+}
+
+func isClosedByPeer(err error) bool {
+	return errors.Is(err, io.EOF) || errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.EPIPE)
+}
+
+// Report は、確かめた結果をまとめたものです
+type Report struct {
+	Results []Result `json:"results"`
+	// 打ち切られた接続数と、打ち切られなかった接続数
+	Closed   int `json:"closed"`
+	Survived int `json:"survived"`
+	// 打ち切られるまでの時間の最大値
+	LongestHeld time.Duration `json:"longest_held,omitempty"`
+	// 行儀の悪いクライアントがいない状態と、いる状態の正規のリクエストのレイテンシの中央値
+	BaselineLatency    time.Duration `json:"baseline_latency"`
+	UnderAttackLatency time.Duration `json:"under_attack_latency"`
+	// 正規のリクエストのレイテンシが悪化したか
+	Degraded bool `json:"degraded"`
+	// 行儀の悪いクライアントがいる状態で失敗した正規のリクエスト数
+	FailedRequests int `json:"failed_requests,omitempty"`
+}
+
+// Median は、レイテンシの中央値を返します
+func Median(latencies []time.Duration) time.Duration {
+	if len(latencies) == 0 {
+		return 0
+	}
+	sorted := append([]time.Duration(nil), latencies...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i] < sorted[j]
+	})
+	return sorted[len(sorted)/2]
+}
+
+// Summarize は、接続ごとの結果と正規のリクエストのレイテンシをまとめます
+// 行儀の悪いクライアントがいる状態のレイテンシの中央値が、いない状態の degradationRatio 倍を超えたら悪化したものとします
+func Summarize(results []Result, baseline, underAttack []time.Duration, failedRequests int, degradationRatio float64) *Report {
+	report := &Report{
+		Results:            results,
+		BaselineLatency:    Median(baseline),
+		UnderAttackLatency: Median(underAttack),
+		FailedRequests:     failedRequests,
+	}
+	for _, r := range results {
+		if len(r.Error) > 0 {
+			continue
+		}
+		if r.Closed {
+			report.Closed++
+			report.LongestHeld = max(report.LongestHeld, r.ClosedAfter)
+		} else {
+			report.Survived++
+		}
+	}
+	report.Degraded = failedRequests > 0 ||
+		(report.BaselineLatency > 0 && float64(report.UnderAttackLatency) > float64(report.BaselineLatency)*degradationRatio)
+	return report
+}
+
+// Measure は、新しい接続で正規のリクエストを1回送り、レスポンスを読み終えるまでの時間を返します
+// NOTE: 行儀の悪いクライアントは新しい接続の受け付けを妨げるため、接続の確立から計測する
+func Measure(ctx context.Context, dial func(ctx context.Context, network, addr string) (net.Conn, error), target Target, timeout time.Duration) (time.Duration, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	startAt := time.Now()
+	c, err := dialTarget(ctx, dial, target)
+	if err != nil {
+		return 0, err
+	}
+	defer c.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		c.SetDeadline(deadline)
+	}
+
+	if _, err := fmt.Fprintf(c, "GET %s HTTP/1.1\r\nHost: %s\r\nConnection: close\r\n\r\n", target.Path, target.Host); err != nil {
+		return 0, err
+	}
+	resp, err := http.ReadResponse(bufio.NewReader(c), nil)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if _, err := io.Copy(io.Discard, resp.Body); err != nil {
+		return 0, err
+	}
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return 0, fmt.Errorf("GET %s のステータスコードが %d でした", target.Path, resp.StatusCode)
+	}
+	return time.Since(startAt), nil
+}
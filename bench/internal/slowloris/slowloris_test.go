@@ -0,0 +1,96 @@
+package slowloris
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var dialer = &net.Dialer{}
+
+var opts = Options{
+	TrickleInterval:    20 * time.Millisecond,
+	HoldLimit:          time.Second,
+	DeclaredBodyLength: 1024,
+}
+
+func newServer(t *testing.T, readTimeout time.Duration) Target {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := io.ReadAll(r.Body); err != nil {
+			return
+		}
+		w.Write([]byte(`{"tags":[]}`))
+	}))
+	srv.Config.ReadTimeout = readTimeout
+	srv.Start()
+	t.Cleanup(srv.Close)
+	return Target{
+		Addr:     srv.Listener.Addr().String(),
+		Host:     "pipe.u.isucon.dev",
+		Path:     "/api/tag",
+		BodyPath: "/api/register",
+	}
+}
+
+func TestAttack_Closed(t *testing.T) {
+	target := newServer(t, 200*time.Millisecond)
+
+	for _, mode := range Modes {
+		r := Attack(context.Background(), dialer.DialContext, target, mode, opts)
+		assert.Empty(t, r.Error, mode)
+		assert.True(t, r.Closed, mode)
+		assert.GreaterOrEqual(t, r.ClosedAfter, 200*time.Millisecond, mode)
+		assert.Less(t, r.ClosedAfter, opts.HoldLimit, mode)
+		assert.Positive(t, r.BytesSent, mode)
+	}
+}
+
+func TestAttack_Survived(t *testing.T) {
+	target := newServer(t, 0)
+
+	holdOpts := opts
+	holdOpts.HoldLimit = 300 * time.Millisecond
+	for _, mode := range Modes {
+		r := Attack(context.Background(), dialer.DialContext, target, mode, holdOpts)
+		assert.Empty(t, r.Error, mode)
+		assert.False(t, r.Closed, mode)
+	}
+}
+
+func TestMeasure(t *testing.T) {
+	target := newServer(t, time.Second)
+
+	latency, err := Measure(context.Background(), dialer.DialContext, target, time.Second)
+	assert.NoError(t, err)
+	assert.Positive(t, latency)
+}
+
+func TestSummarize(t *testing.T) {
+	results := []Result{
+		{Mode: ModeTrickleHeaders, Closed: true, ClosedAfter: 5 * time.Second},
+		{Mode: ModePartialBody, Closed: true, ClosedAfter: 10 * time.Second},
+		{Mode: ModePartialBody},
+		{Mode: ModePartialBody, Error: "connection refused"},
+	}
+	baseline := []time.Duration{10 * time.Millisecond, 12 * time.Millisecond, 11 * time.Millisecond}
+
+	report := Summarize(results, baseline, []time.Duration{20 * time.Millisecond}, 0, 2.0)
+	assert.Equal(t, 2, report.Closed)
+	assert.Equal(t, 1, report.Survived)
+	assert.Equal(t, 10*time.Second, report.LongestHeld)
+	assert.Equal(t, 11*time.Millisecond, report.BaselineLatency)
+	assert.False(t, report.Degraded)
+
+	report = Summarize(results, baseline, []time.Duration{30 * time.Millisecond}, 0, 2.0)
+	assert.True(t, report.Degraded)
+
+	// 正規のリクエストが失敗した場合も悪化したものとする
+	report = Summarize(results, baseline, []time.Duration{11 * time.Millisecond}, 1, 2.0)
+	assert.True(t, report.Degraded)
+}
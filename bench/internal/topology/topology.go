@@ -0,0 +1,309 @@
+// topology は、複数のホスト名・サーバに役割を振り分けた構成を想定して、リクエスト先を重み付きで選び、宛先ごとの結果を集計します
+//
+// 最終日のように、ホスト名ごとに異なる役割のサーバへ振り分ける構成をスタッフがリハーサルするためのものです
+// 宛先を設定しない場合、全てのクライアントは config.TargetBaseURL を利用します
+package topology
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/isucon/isucon13/bench/internal/benchrand"
+	"github.com/isucon/isucon13/bench/internal/config"
+	"github.com/isucon/isucon13/bench/internal/metrics"
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+)
+
+// Target は、リクエストの宛先の定義です
+type Target struct {
+	Name string `yaml:"name"`
+	// クライアントの起点となるURL (重みが0の場合は省略できます)
+	BaseURL string `yaml:"base_url"`
+	// この宛先として集計するホスト名. "*.u.isucon.dev" のように先頭の "*." でサブドメインにマッチします
+	// 省略した場合、BaseURLのホスト名のみを対象とします
+	Hosts []string `yaml:"hosts"`
+	// クライアントの起点として選ぶ重み (0の場合は起点に選ばず、集計のみ行います)
+	Weight int `yaml:"weight"`
+	// 名前解決の結果として期待するアドレス (空の場合は検証しません)
+	ExpectAddrs []string `yaml:"expect_addrs"`
+}
+
+// Config は、宛先の一覧です
+type Config struct {
+	Targets []Target `yaml:"targets"`
+}
+
+// TargetReport は、宛先ごとの集計です
+type TargetReport struct {
+	Name     string `json:"name"`
+	Requests int64  `json:"requests"`
+	Errors   int64  `json:"errors"`
+	// 成功したリクエストの割合
+	SuccessRate float64 `json:"success_rate"`
+	// リクエストの所要時間の推定値[ms] (ヒストグラムのバケットの上限)
+	LatencyP50Millis int64 `json:"latency_p50_ms"`
+	LatencyP99Millis int64 `json:"latency_p99_ms"`
+	// 期待しないアドレスに名前解決された回数
+	DNSMismatches int64 `json:"dns_mismatches"`
+}
+
+type target struct {
+	Target
+	exact    map[string]struct{}
+	suffixes []string
+	expect   map[string]struct{}
+
+	requests      int64
+	errors        int64
+	buckets       []int64
+	dnsMismatches int64
+	warnedHosts   map[string]struct{}
+}
+
+func newTarget(t Target) (*target, error) {
+	if len(t.Name) == 0 {
+		return nil, fmt.Errorf("宛先の名前が指定されていません")
+	}
+	if t.Weight < 0 {
+		return nil, fmt.Errorf("宛先 %s の重みが不正です: %d", t.Name, t.Weight)
+	}
+
+	hosts := t.Hosts
+	if len(t.BaseURL) > 0 {
+		u, err := url.Parse(t.BaseURL)
+		if err != nil || len(u.Hostname()) == 0 {
+			return nil, fmt.Errorf("宛先 %s のURLが不正です: %s", t.Name, t.BaseURL)
+		}
+		if len(hosts) == 0 {
+			hosts = []string{u.Hostname()}
+		}
+	} else if t.Weight > 0 {
+		return nil, fmt.Errorf("宛先 %s はクライアントの起点に選ばれるため、URLの指定が必須です", t.Name)
+	}
+	if len(hosts) == 0 {
+		return nil, fmt.Errorf("宛先 %s のホスト名が指定されていません", t.Name)
+	}
+
+	tt := &target{
+		Target:      t,
+		exact:       make(map[string]struct{}),
+		expect:      make(map[string]struct{}),
+		buckets:     make([]int64, len(metrics.DefaultBuckets)+1),
+		warnedHosts: make(map[string]struct{}),
+	}
+	for _, host := range hosts {
+		host = strings.ToLower(strings.TrimSuffix(host, "."))
+		if suffix, ok := strings.CutPrefix(host, "*"); ok {
+			tt.suffixes = append(tt.suffixes, suffix)
+		} else {
+			tt.exact[host] = struct{}{}
+		}
+	}
+	for _, addr := range t.ExpectAddrs {
+		ip := net.ParseIP(addr)
+		if ip == nil {
+			return nil, fmt.Errorf("宛先 %s の期待するアドレスが不正です: %s", t.Name, addr)
+		}
+		tt.expect[ip.String()] = struct{}{}
+	}
+	return tt, nil
+}
+
+var (
+	mu      sync.Mutex
+	targets []*target
+	// 重みの合計 (0の場合は宛先が設定されていない)
+	totalWeight int
+)
+
+// Load は、宛先の一覧をYAML(JSON)ファイルから読み込みます
+func Load(path string) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	// NOTE: JSONはYAMLとして解釈できる
+	var cfg Config
+	if err := yaml.Unmarshal(b, &cfg); err != nil {
+		return fmt.Errorf("宛先の設定ファイルの形式が不正です: %w", err)
+	}
+	return Set(&cfg)
+}
+
+// Set は、宛先の一覧を設定し、これまでの集計を破棄します
+// nilを指定すると、宛先の設定を解除します
+func Set(cfg *Config) error {
+	var (
+		ts     []*target
+		weight int
+	)
+	if cfg != nil {
+		names := make(map[string]struct{}, len(cfg.Targets))
+		for _, t := range cfg.Targets {
+			if _, ok := names[t.Name]; ok {
+				return fmt.Errorf("宛先 %s が重複しています", t.Name)
+			}
+			names[t.Name] = struct{}{}
+
+			tt, err := newTarget(t)
+			if err != nil {
+				return err
+			}
+			ts = append(ts, tt)
+			weight += t.Weight
+		}
+		if len(ts) > 0 && weight == 0 {
+			return fmt.Errorf("クライアントの起点となる宛先(重みが1以上)がありません")
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	targets = ts
+	totalWeight = weight
+	return nil
+}
+
+// Enabled は、宛先が設定されているかを返します
+func Enabled() bool {
+	mu.Lock()
+	defer mu.Unlock()
+	return len(targets) > 0
+}
+
+// PickBaseURL は、クライアントの起点となるURLを重みに従って選びます
+// 宛先が設定されていない場合、config.TargetBaseURL を返します
+func PickBaseURL() string {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if totalWeight == 0 {
+		return config.TargetBaseURL
+	}
+	n := benchrand.Intn(totalWeight)
+	for _, t := range targets {
+		if n < t.Weight {
+			return t.BaseURL
+		}
+		n -= t.Weight
+	}
+	return config.TargetBaseURL
+}
+
+// match は、ホスト名に対応する宛先を返します. 完全一致するものを優先します
+// NOTE: muを獲得した上で呼び出すこと
+func match(host string) *target {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for _, t := range targets {
+		if _, ok := t.exact[host]; ok {
+			return t
+		}
+	}
+	var (
+		matched *target
+		longest int
+	)
+	for _, t := range targets {
+		for _, suffix := range t.suffixes {
+			if strings.HasSuffix(host, suffix) && len(suffix) > longest {
+				matched, longest = t, len(suffix)
+			}
+		}
+	}
+	return matched
+}
+
+// ObserveRequest は、リクエストの結果を宛先ごとに記録します
+func ObserveRequest(host string, d time.Duration, err error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	t := match(host)
+	if t == nil {
+		return
+	}
+	t.requests++
+	if err != nil {
+		t.errors++
+		return
+	}
+	seconds := d.Seconds()
+	idx := sort.SearchFloat64s(metrics.DefaultBuckets, seconds)
+	t.buckets[idx]++
+}
+
+// ObserveResolved は、名前解決の結果が宛先の期待するアドレスであるか検証し、異なれば記録します
+func ObserveResolved(host string, ip net.IP) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	t := match(host)
+	if t == nil || len(t.expect) == 0 {
+		return
+	}
+	if _, ok := t.expect[ip.String()]; ok {
+		return
+	}
+	t.dnsMismatches++
+	if _, ok := t.warnedHosts[host]; !ok {
+		// NOTE: ホスト名ごとに初回のみ記録する
+		t.warnedHosts[host] = struct{}{}
+		zap.S().Warnf("宛先 %s の %s が期待しないアドレスに名前解決されました: %s (期待するアドレス: %s)", t.Name, host, ip, strings.Join(t.ExpectAddrs, ", "))
+	}
+}
+
+// percentile は、成功したリクエストの所要時間の推定値を返します
+func (t *target) percentile(q float64) time.Duration {
+	var total int64
+	for _, c := range t.buckets {
+		total += c
+	}
+	if total == 0 {
+		return 0
+	}
+
+	threshold := int64(float64(total) * q)
+	var cumulative int64
+	for i, c := range t.buckets {
+		cumulative += c
+		if cumulative > threshold || cumulative == total {
+			if i >= len(metrics.DefaultBuckets) {
+				// NOTE: 最大のバケットを超えたものは、最大のバケットの上限として扱う
+				i = len(metrics.DefaultBuckets) - 1
+			}
+			return time.Duration(metrics.DefaultBuckets[i] * float64(time.Second))
+		}
+	}
+	return 0
+}
+
+// Report は、宛先ごとの集計を設定順に返します
+func Report() []TargetReport {
+	mu.Lock()
+	defer mu.Unlock()
+
+	reports := make([]TargetReport, 0, len(targets))
+	for _, t := range targets {
+		r := TargetReport{
+			Name:             t.Name,
+			Requests:         t.requests,
+			Errors:           t.errors,
+			LatencyP50Millis: t.percentile(0.5).Milliseconds(),
+			LatencyP99Millis: t.percentile(0.99).Milliseconds(),
+			DNSMismatches:    t.dnsMismatches,
+		}
+		if t.requests > 0 {
+			r.SuccessRate = float64(t.requests-t.errors) / float64(t.requests)
+		}
+		reports = append(reports, r)
+	}
+	return reports
+}
@@ -0,0 +1,100 @@
+package topology
+
+import (
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/isucon/isucon13/bench/internal/benchrand"
+	"github.com/isucon/isucon13/bench/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPickBaseURL(t *testing.T) {
+	defer Set(nil)
+	benchrand.Init(1)
+
+	// 宛先がなければ既定のURL
+	assert.NoError(t, Set(nil))
+	assert.False(t, Enabled())
+	assert.Equal(t, config.TargetBaseURL, PickBaseURL())
+
+	assert.NoError(t, Set(&Config{Targets: []Target{
+		{Name: "dc1", BaseURL: "http://pipe.u.isucon.dev:8080", Weight: 3},
+		{Name: "dc2", BaseURL: "http://pipe2.u.isucon.dev:8080", Weight: 1},
+		{Name: "streamer", Hosts: []string{"*.u.isucon.dev"}},
+	}}))
+	assert.True(t, Enabled())
+
+	picked := map[string]int{}
+	for i := 0; i < 4000; i++ {
+		picked[PickBaseURL()]++
+	}
+	assert.Len(t, picked, 2)
+	assert.InDelta(t, 3000, picked["http://pipe.u.isucon.dev:8080"], 200)
+	assert.InDelta(t, 1000, picked["http://pipe2.u.isucon.dev:8080"], 200)
+}
+
+func TestSet_Invalid(t *testing.T) {
+	defer Set(nil)
+
+	assert.Error(t, Set(&Config{Targets: []Target{{Name: "dc1", Weight: 1}}}))
+	assert.Error(t, Set(&Config{Targets: []Target{{Name: "dc1", Hosts: []string{"pipe.u.isucon.dev"}}}}))
+	assert.Error(t, Set(&Config{Targets: []Target{
+		{Name: "dc1", BaseURL: "http://pipe.u.isucon.dev", Weight: 1},
+		{Name: "dc1", BaseURL: "http://pipe2.u.isucon.dev", Weight: 1},
+	}}))
+	assert.Error(t, Set(&Config{Targets: []Target{
+		{Name: "dc1", BaseURL: "http://pipe.u.isucon.dev", Weight: 1, ExpectAddrs: []string{"not-an-ip"}},
+	}}))
+}
+
+func TestReport(t *testing.T) {
+	defer Set(nil)
+
+	path := filepath.Join(t.TempDir(), "targets.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte(`
+targets:
+  - name: api
+    base_url: http://pipe.u.isucon.dev:8080
+    weight: 1
+    expect_addrs: [192.168.0.11]
+  - name: streamer
+    hosts: ["*.u.isucon.dev"]
+    expect_addrs: [192.168.0.12]
+`), 0o644))
+	assert.NoError(t, Load(path))
+
+	// 完全一致するホスト名を優先する
+	for i := 0; i < 99; i++ {
+		ObserveRequest("pipe.u.isucon.dev", 20*time.Millisecond, nil)
+	}
+	ObserveRequest("pipe.u.isucon.dev", 3*time.Second, nil)
+	ObserveRequest("PIPE.u.isucon.dev.", 0, errors.New("failed"))
+	ObserveRequest("streamer0.u.isucon.dev", 200*time.Millisecond, nil)
+	// どの宛先にもマッチしない
+	ObserveRequest("example.com", time.Second, nil)
+
+	ObserveResolved("pipe.u.isucon.dev", net.ParseIP("192.168.0.11"))
+	ObserveResolved("streamer0.u.isucon.dev", net.ParseIP("192.168.0.11"))
+	ObserveResolved("streamer0.u.isucon.dev", net.ParseIP("192.168.0.11"))
+
+	reports := Report()
+	assert.Len(t, reports, 2)
+
+	assert.Equal(t, "api", reports[0].Name)
+	assert.Equal(t, int64(101), reports[0].Requests)
+	assert.Equal(t, int64(1), reports[0].Errors)
+	assert.InDelta(t, 100.0/101.0, reports[0].SuccessRate, 1e-9)
+	assert.Equal(t, int64(25), reports[0].LatencyP50Millis)
+	assert.Equal(t, int64(5000), reports[0].LatencyP99Millis)
+	assert.Equal(t, int64(0), reports[0].DNSMismatches)
+
+	assert.Equal(t, "streamer", reports[1].Name)
+	assert.Equal(t, int64(1), reports[1].Requests)
+	assert.Equal(t, int64(250), reports[1].LatencyP50Millis)
+	assert.Equal(t, int64(2), reports[1].DNSMismatches)
+}
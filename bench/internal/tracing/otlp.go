@@ -0,0 +1,271 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// 一度に送信するスパン数の上限
+	maxBatchSize = 512
+	// 送信を待つスパン数の上限. 超えた分は捨てます
+	// NOTE: コレクタが詰まってもベンチマーカーのメモリを使い切らないようにする
+	maxQueueSize = 8192
+	// 送信間隔
+	exportInterval = 1 * time.Second
+	// 送信1回あたりのタイムアウト
+	exportTimeout = 5 * time.Second
+)
+
+// Exporter は、スパンをまとめてOTLP/HTTP (JSON) でコレクタに送信します
+type Exporter struct {
+	url    string
+	client *http.Client
+
+	queue   chan *Span
+	dropped int64
+	mu      sync.Mutex
+	done    chan struct{}
+	stopped chan struct{}
+}
+
+// Init は、endpoint (例: http://localhost:4318) にスパンを送信するよう設定します. 空の場合は何もしません
+func Init(endpoint string) error {
+	if len(endpoint) == 0 {
+		return nil
+	}
+	url := strings.TrimRight(endpoint, "/")
+	if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+		return fmt.Errorf("OTLPのエンドポイント(--otlp-endpoint)はhttp://またはhttps://で始まるURLを指定してください: %s", endpoint)
+	}
+	if !strings.HasSuffix(url, "/v1/traces") {
+		url += "/v1/traces"
+	}
+
+	e := &Exporter{
+		url:     url,
+		client:  &http.Client{Timeout: exportTimeout},
+		queue:   make(chan *Span, maxQueueSize),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	go e.run()
+	exporter.Store(e)
+	return nil
+}
+
+// Shutdown は、送信を待つスパンをすべて送信してから、トレースの記録を終了します
+func Shutdown(ctx context.Context) {
+	e := exporter.Swap(nil)
+	if e == nil {
+		return
+	}
+	close(e.done)
+	select {
+	case <-e.stopped:
+	case <-ctx.Done():
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.dropped > 0 {
+		zap.S().Warnf("送信が追いつかなかったため、%d 件のスパンを破棄しました", e.dropped)
+	}
+}
+
+func (e *Exporter) enqueue(s *Span) {
+	select {
+	case e.queue <- s:
+	default:
+		e.mu.Lock()
+		e.dropped++
+		e.mu.Unlock()
+	}
+}
+
+func (e *Exporter) run() {
+	defer close(e.stopped)
+
+	ticker := time.NewTicker(exportInterval)
+	defer ticker.Stop()
+
+	batch := make([]*Span, 0, maxBatchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := e.export(batch); err != nil {
+			zap.S().Warnf("スパンの送信に失敗しました (%d 件): %s", len(batch), err.Error())
+		}
+		batch = batch[:0]
+	}
+	for {
+		select {
+		case s := <-e.queue:
+			batch = append(batch, s)
+			if len(batch) >= maxBatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case <-e.done:
+			for {
+				select {
+				case s := <-e.queue:
+					batch = append(batch, s)
+					if len(batch) >= maxBatchSize {
+						flush()
+					}
+				default:
+					flush()
+					return
+				}
+			}
+		}
+	}
+}
+
+func (e *Exporter) export(spans []*Span) error {
+	b, err := json.Marshal(newExportRequest(spans))
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), exportTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// 以下は、OTLP/HTTPのJSONエンコーディング (opentelemetry-proto の ExportTraceServiceRequest) です
+// NOTE: SDKを依存に加えず、送信に必要なフィールドのみを定義する
+
+type exportRequest struct {
+	ResourceSpans []resourceSpans `json:"resourceSpans"`
+}
+
+type resourceSpans struct {
+	Resource   resource     `json:"resource"`
+	ScopeSpans []scopeSpans `json:"scopeSpans"`
+}
+
+type resource struct {
+	Attributes []keyValue `json:"attributes"`
+}
+
+type scopeSpans struct {
+	Scope scope      `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type scope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string     `json:"traceId"`
+	SpanID            string     `json:"spanId"`
+	ParentSpanID      string     `json:"parentSpanId,omitempty"`
+	Name              string     `json:"name"`
+	Kind              Kind       `json:"kind"`
+	StartTimeUnixNano string     `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string     `json:"endTimeUnixNano"`
+	Attributes        []keyValue `json:"attributes,omitempty"`
+	Status            *status    `json:"status,omitempty"`
+}
+
+type status struct {
+	Message string `json:"message,omitempty"`
+	// 2: STATUS_CODE_ERROR
+	Code int `json:"code"`
+}
+
+type keyValue struct {
+	Key   string   `json:"key"`
+	Value anyValue `json:"value"`
+}
+
+type anyValue struct {
+	StringValue *string `json:"stringValue,omitempty"`
+	// NOTE: OTLPのJSONエンコーディングでは、int64は文字列で表す
+	IntValue  *string `json:"intValue,omitempty"`
+	BoolValue *bool   `json:"boolValue,omitempty"`
+}
+
+func newAnyValue(v any) anyValue {
+	switch v := v.(type) {
+	case string:
+		return anyValue{StringValue: &v}
+	case int:
+		s := strconv.Itoa(v)
+		return anyValue{IntValue: &s}
+	case int64:
+		s := strconv.FormatInt(v, 10)
+		return anyValue{IntValue: &s}
+	case bool:
+		return anyValue{BoolValue: &v}
+	default:
+		s := fmt.Sprint(v)
+		return anyValue{StringValue: &s}
+	}
+}
+
+func newExportRequest(spans []*Span) *exportRequest {
+	converted := make([]otlpSpan, len(spans))
+	for i, s := range spans {
+		span := otlpSpan{
+			TraceID:           hex.EncodeToString(s.TraceID[:]),
+			SpanID:            hex.EncodeToString(s.SpanID[:]),
+			Name:              s.Name,
+			Kind:              s.Kind,
+			StartTimeUnixNano: strconv.FormatInt(s.StartAt.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(s.EndAt.UnixNano(), 10),
+		}
+		if s.ParentSpanID != ([8]byte{}) {
+			span.ParentSpanID = hex.EncodeToString(s.ParentSpanID[:])
+		}
+		for key, value := range s.Attributes {
+			span.Attributes = append(span.Attributes, keyValue{Key: key, Value: newAnyValue(value)})
+		}
+		if len(s.Error) > 0 {
+			span.Status = &status{Message: s.Error, Code: 2}
+		}
+		converted[i] = span
+	}
+
+	return &exportRequest{
+		ResourceSpans: []resourceSpans{{
+			Resource: resource{Attributes: []keyValue{
+				{Key: "service.name", Value: newAnyValue(ServiceName)},
+			}},
+			ScopeSpans: []scopeSpans{{
+				Scope: scope{Name: ServiceName},
+				Spans: converted,
+			}},
+		}},
+	}
+}
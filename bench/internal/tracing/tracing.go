@@ -0,0 +1,101 @@
+// tracing は、シナリオの1回の実行を1つのトレースとし、HTTPリクエストや名前解決を子のスパンとして記録します
+//
+// --otlp-endpoint が指定された場合のみ有効となり、OTLP/HTTP (JSON) でトレースのコレクタに送信します
+// 「ベンチマーカーが遅いのであってwebappは遅くない」という申し立てを、ベンチマーカー側の所要時間と突き合わせて調べるのに用います
+// NOTE: 無効な場合、Start はスパンを作らず、nilのスパンに対する操作は何もしません
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync/atomic"
+	"time"
+)
+
+// ServiceName は、トレースの送信元として記録するサービス名です
+const ServiceName = "isupipe-benchmarker"
+
+// Kind は、スパンの種別です (OTLPのSpanKindに対応します)
+type Kind int
+
+const (
+	KindInternal Kind = 1
+	KindClient   Kind = 3
+)
+
+// Span は、計測中または計測を終えた1つの処理です
+type Span struct {
+	TraceID      [16]byte
+	SpanID       [8]byte
+	ParentSpanID [8]byte
+	Name         string
+	Kind         Kind
+	StartAt      time.Time
+	EndAt        time.Time
+	Attributes   map[string]any
+	// エラーで終了した場合のメッセージ
+	Error string
+}
+
+type spanKey struct{}
+
+var exporter atomic.Pointer[Exporter]
+
+// Enabled は、トレースを記録するかを返します
+func Enabled() bool {
+	return exporter.Load() != nil
+}
+
+// Start は、contextのスパンを親とするスパンを開始します. 親がなければ新しいトレースを開始します
+// トレースを記録しない場合は、contextをそのまま返します
+func Start(ctx context.Context, name string, kind Kind) (context.Context, *Span) {
+	if !Enabled() {
+		return ctx, nil
+	}
+
+	span := &Span{
+		Name:       name,
+		Kind:       kind,
+		StartAt:    time.Now(),
+		Attributes: map[string]any{},
+	}
+	rand.Read(span.SpanID[:])
+	if parent, ok := ctx.Value(spanKey{}).(*Span); ok {
+		span.TraceID = parent.TraceID
+		span.ParentSpanID = parent.SpanID
+	} else {
+		rand.Read(span.TraceID[:])
+	}
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+// SetAttribute は、スパンに属性を記録します. 値は文字列、整数、真偽値のいずれかです
+func (s *Span) SetAttribute(key string, value any) {
+	if s == nil {
+		return
+	}
+	s.Attributes[key] = value
+}
+
+// End は、スパンを終了して送信します. errがnilでなければエラーとして記録します
+func (s *Span) End(err error) {
+	if s == nil {
+		return
+	}
+	s.EndAt = time.Now()
+	if err != nil {
+		s.Error = err.Error()
+	}
+	if e := exporter.Load(); e != nil {
+		e.enqueue(s)
+	}
+}
+
+// TraceIDString は、トレースIDを16進数で返します
+func (s *Span) TraceIDString() string {
+	if s == nil {
+		return ""
+	}
+	return hex.EncodeToString(s.TraceID[:])
+}
@@ -0,0 +1,84 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStart_Disabled(t *testing.T) {
+	ctx := context.Background()
+	got, span := Start(ctx, "scenario", KindInternal)
+	assert.Nil(t, span)
+	assert.Equal(t, ctx, got)
+
+	// NOTE: 無効な場合でも呼び出し側で分岐しなくてよいこと
+	span.SetAttribute("key", "value")
+	span.End(errors.New("error"))
+}
+
+func TestExport(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		received []otlpSpan
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/traces", r.URL.Path)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+
+		var req exportRequest
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		if assert.Len(t, req.ResourceSpans, 1) {
+			assert.Equal(t, "service.name", req.ResourceSpans[0].Resource.Attributes[0].Key)
+			mu.Lock()
+			for _, ss := range req.ResourceSpans[0].ScopeSpans {
+				received = append(received, ss.Spans...)
+			}
+			mu.Unlock()
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	assert.NoError(t, Init(srv.URL))
+	assert.True(t, Enabled())
+
+	ctx, scenario := Start(context.Background(), "scenario test", KindInternal)
+	_, request := Start(ctx, "HTTP GET /api/tag", KindClient)
+	request.SetAttribute("http.response.status_code", 200)
+	request.End(nil)
+	scenario.End(errors.New("failed"))
+
+	Shutdown(context.Background())
+	assert.False(t, Enabled())
+
+	mu.Lock()
+	defer mu.Unlock()
+	if !assert.Len(t, received, 2) {
+		return
+	}
+	child, parent := received[0], received[1]
+	assert.Equal(t, parent.TraceID, child.TraceID)
+	assert.Equal(t, parent.SpanID, child.ParentSpanID)
+	assert.Empty(t, parent.ParentSpanID)
+	assert.Nil(t, child.Status)
+	if assert.NotNil(t, parent.Status) {
+		assert.Equal(t, 2, parent.Status.Code)
+	}
+	if assert.Len(t, child.Attributes, 1) {
+		assert.Equal(t, "200", *child.Attributes[0].Value.IntValue)
+	}
+}
+
+func TestInit_InvalidEndpoint(t *testing.T) {
+	assert.Error(t, Init("localhost:4318"))
+	assert.False(t, Enabled())
+	assert.NoError(t, Init(""))
+	assert.False(t, Enabled())
+}
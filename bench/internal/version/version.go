@@ -0,0 +1,76 @@
+// version は、ベンチマーカーのビルド情報を提供します
+//
+// 結果に異議があった際に、どのビルドで走行したかを特定できるよう、ビルド時に以下のように埋め込みます
+//
+//	go build -ldflags "-X github.com/isucon/isucon13/bench/internal/version.Commit=$(git rev-parse HEAD) -X github.com/isucon/isucon13/bench/internal/version.BuildDate=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+//
+// 埋め込まれていない場合、go build が記録するVCSの情報を用います
+package version
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+)
+
+// NOTE: ビルド時に -ldflags "-X" で上書きされます
+var (
+	Version   = "dev"
+	Commit    = ""
+	BuildDate = ""
+)
+
+const unknown = "unknown"
+
+// Info は、ベンチマーカーのビルド情報です
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildDate string `json:"build_date"`
+	// コミットされていない変更を含むビルドか (VCSの情報から判定できた場合のみ)
+	Modified  bool   `json:"modified,omitempty"`
+	GoVersion string `json:"go_version"`
+}
+
+// Get は、ベンチマーカーのビルド情報を返します
+func Get() Info {
+	info := Info{
+		Version:   Version,
+		Commit:    Commit,
+		BuildDate: BuildDate,
+		GoVersion: runtime.Version(),
+	}
+
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range bi.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				if len(info.Commit) == 0 {
+					info.Commit = s.Value
+				}
+			case "vcs.time":
+				if len(info.BuildDate) == 0 {
+					info.BuildDate = s.Value
+				}
+			case "vcs.modified":
+				info.Modified = s.Value == "true"
+			}
+		}
+	}
+
+	if len(info.Commit) == 0 {
+		info.Commit = unknown
+	}
+	if len(info.BuildDate) == 0 {
+		info.BuildDate = unknown
+	}
+	return info
+}
+
+func (i Info) String() string {
+	commit := i.Commit
+	if i.Modified {
+		commit += "-dirty"
+	}
+	return fmt.Sprintf("%s (commit: %s, built at: %s, %s)", i.Version, commit, i.BuildDate, i.GoVersion)
+}
@@ -0,0 +1,40 @@
+package version
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGet(t *testing.T) {
+	commit, buildDate := Commit, BuildDate
+	defer func() {
+		Commit, BuildDate = commit, buildDate
+	}()
+
+	Commit = "0123456789abcdef"
+	BuildDate = "2023-11-25T10:00:00Z"
+
+	info := Get()
+	assert.Equal(t, "dev", info.Version)
+	assert.Equal(t, "0123456789abcdef", info.Commit)
+	assert.Equal(t, "2023-11-25T10:00:00Z", info.BuildDate)
+	assert.Equal(t, runtime.Version(), info.GoVersion)
+	assert.Contains(t, info.String(), "commit: 0123456789abcdef")
+}
+
+func TestGet_Unknown(t *testing.T) {
+	commit, buildDate := Commit, BuildDate
+	defer func() {
+		Commit, BuildDate = commit, buildDate
+	}()
+
+	Commit = ""
+	BuildDate = ""
+
+	// NOTE: テストバイナリにはVCSの情報が記録されない
+	info := Get()
+	assert.Equal(t, unknown, info.Commit)
+	assert.Equal(t, unknown, info.BuildDate)
+}
@@ -0,0 +1,68 @@
+package isupipe
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/isucon/isucon13/bench/internal/bencherror"
+)
+
+// NOTE: HTTP/2の実装が宣言より長いボディを打ち切った際のエラーは非公開なので、メッセージで判定する
+const tooLongBodyMarker = "more than declared Content-Length"
+
+// checkedBody は、Content-Lengthと実際に受信したボディの長さを突き合わせるレスポンスボディです
+// ボディが途中で打ち切られた場合、JSONのデコードエラーではなくプロトコルエラーとして報告します
+type checkedBody struct {
+	io.ReadCloser
+	ctx      context.Context
+	req      *http.Request
+	declared int64
+	read     int64
+	err      error
+}
+
+// withContentLengthCheck は、Content-Lengthが宣言されたレスポンスのボディを検証するよう包みます
+func withContentLengthCheck(ctx context.Context, req *http.Request, resp *http.Response) {
+	if resp.ContentLength < 0 || req.Method == http.MethodHead || resp.Body == nil || resp.Body == http.NoBody {
+		return
+	}
+	resp.Body = &checkedBody{
+		ReadCloser: resp.Body,
+		ctx:        ctx,
+		req:        req,
+		declared:   resp.ContentLength,
+	}
+}
+
+func (b *checkedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.read += int64(n)
+	if err == nil || err == io.EOF {
+		return n, err
+	}
+
+	if errors.Is(err, io.ErrUnexpectedEOF) || strings.Contains(err.Error(), tooLongBodyMarker) {
+		// NOTE: 読み直しても同じエラーが返るため、記録は1度だけにする
+		if b.err == nil {
+			b.err = bencherror.NewContentLengthError(b.ctx, err, b.req, b.declared, b.read)
+		}
+		return n, b.err
+	}
+	return n, err
+}
+
+// cancelOnCloseBody は、閉じたときにリクエストのタイムアウトのcontextを解放するレスポンスボディです
+// NOTE: ボディを読み終えるまでタイムアウトを有効にするため、レスポンスを返した時点では解放しない
+type cancelOnCloseBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnCloseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
@@ -0,0 +1,62 @@
+package isupipe
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/isucon/isucon13/bench/internal/bencherror"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContentLengthCheck(t *testing.T) {
+	ctx := context.Background()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// 宣言より短いボディを返して接続を切る
+		conn, buf, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		body := `{"tags":[{"id":1,`
+		fmt.Fprintf(buf, "HTTP/1.1 200 OK\r\nContent-Type: application/json\r\nContent-Length: %d\r\n\r\n%s", len(body)+10, body)
+		buf.Flush()
+	}))
+	defer ts.Close()
+
+	req, err := http.NewRequest(http.MethodGet, ts.URL+"/api/tag", nil)
+	assert.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	withContentLengthCheck(ctx, req, resp)
+	_, err = DecodeAndValidate[*TagsResponse](ctx, req, resp.Body)
+	assert.ErrorIs(t, err, bencherror.ErrContentLengthMismatch)
+	assert.Contains(t, err.Error(), "Content-Length: 27, 受信: 17 bytes")
+}
+
+func TestContentLengthCheck_OK(t *testing.T) {
+	ctx := context.Background()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"tags":[{"id":1,"name":"ライブ配信"}]}`)
+	}))
+	defer ts.Close()
+
+	req, err := http.NewRequest(http.MethodGet, ts.URL+"/api/tag", nil)
+	assert.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	assert.GreaterOrEqual(t, resp.ContentLength, int64(0))
+
+	withContentLengthCheck(ctx, req, resp)
+	b, err := io.ReadAll(bufio.NewReader(resp.Body))
+	assert.NoError(t, err)
+	assert.Equal(t, resp.ContentLength, int64(len(b)))
+}
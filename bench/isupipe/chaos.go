@@ -0,0 +1,44 @@
+package isupipe
+
+import (
+	"errors"
+	"net"
+	"net/http"
+
+	"github.com/isucon/isucandar/agent"
+	"github.com/isucon/isucon13/bench/internal/chaos"
+	"github.com/isucon/isucon13/bench/internal/config"
+)
+
+// withChaosTransport は、負荷走行中のリクエストに遅延と接続の切断を注入します
+// NOTE: --chaos を指定しない場合は何もしない
+func withChaosTransport() agent.AgentOption {
+	return func(a *agent.Agent) error {
+		if !config.Chaos {
+			return nil
+		}
+		if _, ok := a.HttpClient.Transport.(*chaosTransport); !ok {
+			a.HttpClient.Transport = &chaosTransport{RoundTripper: a.HttpClient.Transport}
+		}
+		return nil
+	}
+}
+
+type chaosTransport struct {
+	http.RoundTripper
+}
+
+func (t *chaosTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := chaos.Inject(req.Context()); err != nil {
+		// NOTE: RoundTripperはエラーの場合もリクエストボディを閉じなければならない
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		if errors.Is(err, chaos.ErrDropped) {
+			// NOTE: 実際に接続が切断された場合と同じ分類になるよう、ネットワークエラーとして返す
+			return nil, &net.OpError{Op: "read", Net: "tcp", Err: err}
+		}
+		return nil, err
+	}
+	return t.RoundTripper.RoundTrip(req)
+}
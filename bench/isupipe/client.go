@@ -8,11 +8,21 @@ import (
 	"net"
 	"net/http"
 	"net/url"
+	"time"
 
 	"github.com/isucon/isucandar/agent"
+	"github.com/isucon/isucon13/bench/internal/adaptive"
 	"github.com/isucon/isucon13/bench/internal/bencherror"
+	"github.com/isucon/isucon13/bench/internal/benchscore"
+	"github.com/isucon/isucon13/bench/internal/benchtrace"
 	"github.com/isucon/isucon13/bench/internal/config"
+	"github.com/isucon/isucon13/bench/internal/coverage"
+	"github.com/isucon/isucon13/bench/internal/failfast"
+	"github.com/isucon/isucon13/bench/internal/journal"
+	"github.com/isucon/isucon13/bench/internal/metrics"
 	"github.com/isucon/isucon13/bench/internal/resolver"
+	"github.com/isucon/isucon13/bench/internal/topology"
+	"github.com/isucon/isucon13/bench/internal/tracing"
 	"go.uber.org/zap"
 )
 
@@ -26,6 +36,9 @@ type Client struct {
 	agentOptions []agent.AgentOption
 
 	username string
+	// ログインのレスポンスで発行されたCookieと、その発行時刻
+	loginCookies  []*http.Cookie
+	loginIssuedAt time.Time
 
 	// ユーザカスタムテーマ適用ページアクセス用agent
 	// ライブ配信画面など
@@ -36,79 +49,112 @@ type Client struct {
 	// キャッシュ可能
 	assetAgent   *agent.Agent
 	assetOptions []agent.AgentOption
+	// アイコンなどをまとめて取得する際の並列数 (0の場合は1件ずつ取得する)
+	// NOTE: HTTP/2では1本の接続に多重化されるため、接続数ではなく同時に送るリクエスト数で制限する
+	parallelFetches int
+	// エンドポイントの分類ごとのタイムアウトを適用するか
+	// NOTE: pretestやfinalcheckのように、明示的にタイムアウトを指定したクライアントには適用しない
+	tierTimeouts bool
 
 	contestantLogger *zap.Logger
 }
 
-func NewClient(contestantLogger *zap.Logger, customOpts ...agent.AgentOption) (*Client, error) {
-	return NewCustomResolverClient(contestantLogger, resolver.NewDNSResolver(), customOpts...)
+// timeoutUnset は、customOpts でタイムアウトが指定されたかを判別するための仮のタイムアウトです
+const timeoutUnset time.Duration = -1
+
+func NewClient(ctx context.Context, contestantLogger *zap.Logger, customOpts ...agent.AgentOption) (*Client, error) {
+	return NewCustomResolverClient(ctx, contestantLogger, resolver.NewDNSResolver(), customOpts...)
 }
 
 // NewClient は、HTTPクライアント群を初期化します
 // NOTE: キャッシュ無効化オプションなどを指定すると、意図しない挙動をする可能性があります
 // タイムアウトやURLなどの振る舞いでないパラメータを指定するのにcustomOptsを用いてください
-func NewCustomResolverClient(contestantLogger *zap.Logger, dnsResolver *resolver.DNSResolver, customOpts ...agent.AgentOption) (*Client, error) {
+func NewCustomResolverClient(ctx context.Context, contestantLogger *zap.Logger, dnsResolver *resolver.DNSResolver, customOpts ...agent.AgentOption) (*Client, error) {
+	// NOTE: 宛先が複数ある場合、クライアントごとに重みに従って起点を選ぶ
+	baseURL := topology.PickBaseURL()
+	proxy := proxyFunc()
+	dial := dialContext(dnsResolver)
+	// NOTE: 同じクライアントのリクエストは、同じブラウザから送られたように同じUser-Agentを用いる
+	userAgent := pickUserAgent()
 	opts := []agent.AgentOption{
-		agent.WithBaseURL(config.TargetBaseURL),
+		agent.WithBaseURL(baseURL),
+		agent.WithUserAgent(userAgent),
 		agent.WithCloneTransport(&http.Transport{
 			TLSClientConfig: &tls.Config{
 				InsecureSkipVerify: config.InsecureSkipVerify,
 			},
-			DialContext:       dnsResolver.DialContext,
+			Proxy:             proxy,
+			DialContext:       dial,
 			IdleConnTimeout:   config.ClientIdleConnTimeout,
 			ForceAttemptHTTP2: true,
 		}),
-		agent.WithTimeout(config.DefaultAgentTimeout),
+		agent.WithTimeout(timeoutUnset),
 		agent.WithNoCache(),
 	}
 	for _, customOpt := range customOpts {
 		opts = append(opts, customOpt)
 	}
+	tierTimeouts := false
+	opts = append(opts, func(a *agent.Agent) error {
+		if a.HttpClient.Timeout == timeoutUnset {
+			tierTimeouts = true
+			a.HttpClient.Timeout = config.MaxTierTimeout()
+		}
+		return nil
+	})
+	opts = append(opts, withWireCountingTransport(), withBotMarkerTransport(), withChaosTransport())
 
 	baseAgent, err := agent.NewAgent(opts...)
 	if err != nil {
-		return nil, bencherror.NewInternalError(err)
+		return nil, bencherror.NewInternalError(ctx, err)
 	}
 
 	themeOpts := []agent.AgentOption{
 		withClient(baseAgent.HttpClient),
+		agent.WithUserAgent(userAgent),
 		agent.WithCloneTransport(&http.Transport{
 			TLSClientConfig: &tls.Config{
 				InsecureSkipVerify: config.InsecureSkipVerify,
 			},
 			// Custom DNS Resolver
-			DialContext:       dnsResolver.DialContext,
+			Proxy:             proxy,
+			DialContext:       dial,
 			IdleConnTimeout:   config.ClientIdleConnTimeout,
 			ForceAttemptHTTP2: true,
 		}),
-		agent.WithTimeout(config.DefaultAgentTimeout),
+		agent.WithTimeout(config.MaxTierTimeout()),
 		agent.WithNoCache(),
 	}
 	for _, customOpt := range customOpts {
 		themeOpts = append(themeOpts, customOpt)
 	}
+	themeOpts = append(themeOpts, withWireCountingTransport(), withBotMarkerTransport(), withChaosTransport())
 
 	assetOpts := []agent.AgentOption{
-		agent.WithBaseURL(config.TargetBaseURL),
+		agent.WithBaseURL(baseURL),
 		withClient(baseAgent.HttpClient),
+		agent.WithUserAgent(userAgent),
 		agent.WithCloneTransport(&http.Transport{
 			TLSClientConfig: &tls.Config{
 				InsecureSkipVerify: config.InsecureSkipVerify,
 			},
-			DialContext:     dnsResolver.DialContext,
+			Proxy:           proxy,
+			DialContext:     dial,
 			IdleConnTimeout: config.ClientIdleConnTimeout,
 		}),
-		agent.WithTimeout(config.DefaultAgentTimeout),
+		agent.WithTimeout(config.MaxTierTimeout()),
 		agent.WithNoCache(),
 	}
 	for _, customOpt := range customOpts {
 		assetOpts = append(assetOpts, customOpt)
 	}
+	assetOpts = append(assetOpts, withWireCountingTransport(), withBotMarkerTransport(), withChaosTransport())
 
 	client := &Client{
 		agent:            baseAgent,
 		themeOptions:     themeOpts,
 		assetOptions:     assetOpts,
+		tierTimeouts:     tierTimeouts,
 		contestantLogger: contestantLogger,
 	}
 	if contestantLogger != nil {
@@ -120,9 +166,19 @@ func NewCustomResolverClient(contestantLogger *zap.Logger, dnsResolver *resolver
 	return client, nil
 }
 
-func (c *Client) Username() (string, error) {
+// SetParallelFetches は、アイコンなどをまとめて取得する際の並列数を設定します
+func (c *Client) SetParallelFetches(n int) {
+	c.parallelFetches = n
+}
+
+// ParallelFetches は、アイコンなどをまとめて取得する際の並列数を返します. 1未満は1として扱います
+func (c *Client) ParallelFetches() int {
+	return max(c.parallelFetches, 1)
+}
+
+func (c *Client) Username(ctx context.Context) (string, error) {
 	if len(c.username) == 0 {
-		return "", bencherror.NewInternalError(fmt.Errorf("未ログインクライアントです"))
+		return "", bencherror.NewInternalError(ctx, fmt.Errorf("未ログインクライアントです"))
 	}
 
 	return c.username, nil
@@ -142,27 +198,79 @@ func (c *Client) setStreamerURL(streamerName string) error {
 
 // sendRequestはagent.Doをラップしたリクエスト送信関数
 // bencherror.WrapErrorはここで実行しているので、呼び出し側ではwrapしない
-func sendRequest(ctx context.Context, agent *agent.Agent, req *http.Request) (*http.Response, error) {
+func (c *Client) sendRequest(ctx context.Context, agent *agent.Agent, req *http.Request) (*http.Response, error) {
+	// NOTE: 除外されたエンドポイントにはリクエストを送らない
+	if err := coverage.Check(req.Method, req.URL.Path); err != nil {
+		return nil, err
+	}
+
 	endpoint := fmt.Sprintf("%s %s", req.Method, req.URL.EscapedPath())
-	resp, err := agent.Do(ctx, req)
+	journal.Note(ctx, journal.NoteEndpoint, endpoint)
+	wire := &wireCounter{}
+	ctx, span := tracing.Start(ctx, fmt.Sprintf("HTTP %s %s", req.Method, metrics.Route(req.URL.Path)), tracing.KindClient)
+	span.SetAttribute("http.request.method", req.Method)
+	span.SetAttribute("http.route", metrics.Route(req.URL.Path))
+	span.SetAttribute("server.address", req.URL.Hostname())
+	// NOTE: エンドポイントの分類ごとのタイムアウトは、レスポンスボディを閉じるまで有効とする
+	tier := config.CurrentTimeoutTier(config.ClassifyEndpoint(req.Method, req.URL.Path))
+	var (
+		reqCtx context.Context
+		cancel context.CancelFunc
+	)
+	if c.tierTimeouts {
+		reqCtx, cancel = context.WithTimeout(ctx, tier.Timeout)
+	} else {
+		reqCtx, cancel = context.WithCancel(ctx)
+	}
+	startAt := time.Now()
+	resp, err := agent.Do(withWireCounter(benchtrace.WithClientTrace(reqCtx), wire), req)
+	if resp != nil {
+		span.SetAttribute("http.response.status_code", resp.StatusCode)
+	}
+	span.End(err)
+	// 走行の締切ではなく、リクエストのタイムアウト (エンドポイントの分類ごと、またはクライアントに指定したもの) を超えたか
+	// NOTE: http.Client のタイムアウトも context.DeadlineExceeded として返るため、走行のcontextで区別する
+	timedOut := err != nil && ctx.Err() == nil && errors.Is(err, context.DeadlineExceeded)
+	if !errors.Is(err, context.DeadlineExceeded) || timedOut {
+		elapsed := time.Since(startAt)
+		metrics.ObserveRequest(req.Method, req.URL.Path, elapsed)
+		topology.ObserveRequest(req.URL.Hostname(), elapsed, err)
+		adaptive.ObserveRequest(elapsed, err)
+		if session := benchscore.SessionFromContext(ctx); session != nil {
+			session.ObserveRequest(elapsed, tier.SlowThreshold)
+		}
+	}
 	if err != nil {
+		cancel()
+		benchtrace.ObserveError(err)
+
 		var (
 			netErr net.Error
 		)
-		if errors.Is(err, context.DeadlineExceeded) {
+		if timedOut {
+			return resp, bencherror.NewTimeoutError(ctx, err, "%s", endpoint)
+		} else if errors.Is(err, context.DeadlineExceeded) {
 			// 締切がすぎるのはベンチの都合なので、減点しない
 			// リクエストをキャンセルする
 			return resp, ErrCancelRequest
 		} else if errors.As(err, &netErr) {
 			if netErr.Timeout() {
-				return resp, bencherror.NewTimeoutError(err, "%s", endpoint)
+				return resp, bencherror.NewTimeoutError(ctx, err, "%s", endpoint)
 			} else {
 				return resp, fmt.Errorf("%s: %w", netErr.Error(), ErrCancelRequest)
 			}
+		} else if isUnknownContentEncoding(err) {
+			return resp, bencherror.NewApplicationError(ctx, err, "%s のレスポンスが Accept-Encoding で受け付けていない方式で圧縮されています", endpoint)
 		} else {
-			return resp, bencherror.NewApplicationError(err, "%s に対するリクエストが失敗しました", endpoint)
+			return resp, bencherror.NewApplicationError(ctx, err, "%s に対するリクエストが失敗しました", endpoint)
 		}
 	}
 
+	resp.Body = &cancelOnCloseBody{ReadCloser: resp.Body, cancel: cancel}
+	failfast.ObserveResponse(resp.StatusCode)
+	benchtrace.ObserveResponse(resp)
+	withContentLengthCheck(ctx, req, resp)
+	withEncodingObservation(ctx, req, resp, wire)
+
 	return resp, nil
 }
@@ -0,0 +1,58 @@
+package isupipe
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+
+	"github.com/isucon/isucon13/bench/internal/bencherror"
+)
+
+// SessionCookies は、ログインによって保持しているCookieを返します
+func (c *Client) SessionCookies() []*http.Cookie {
+	if c.agent.HttpClient.Jar == nil {
+		return nil
+	}
+	return c.agent.HttpClient.Jar.Cookies(c.agent.BaseURL)
+}
+
+// VerifyAuthRequired は、与えたCookieのみでリクエストを送り、認証エラーが返されることを検証します
+// NOTE: ログインしていないクライアントで用いること (ログイン済みのセッションが送られてしまうため)
+func (c *Client) VerifyAuthRequired(ctx context.Context, method, urlPath string, cookies []*http.Cookie, opts ...ClientOption) error {
+	var (
+		defaultStatusCode = ExpectedStatusCode(ActionUnauthenticated)
+		o                 = newClientOptions(defaultStatusCode, opts...)
+	)
+
+	// NOTE: ボディを先に検証する実装でも認証エラーを確認できるよう、POSTには空のオブジェクトを送る
+	var body io.Reader
+	if method == http.MethodPost {
+		body = bytes.NewBufferString("{}")
+	}
+	req, err := c.agent.NewRequest(method, urlPath, body)
+	if err != nil {
+		return bencherror.NewInternalError(ctx, err)
+	}
+	if body != nil {
+		req.Header.Add("Content-Type", "application/json;charset=utf-8")
+	}
+	for _, cookie := range cookies {
+		req.AddCookie(cookie)
+	}
+
+	resp, err := c.sendRequest(ctx, c.agent, req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}()
+
+	if !o.isExpectedStatusCode(resp.StatusCode) {
+		return bencherror.NewHttpStatusError(ctx, req, o.wantStatusCode, resp.StatusCode)
+	}
+
+	return nil
+}
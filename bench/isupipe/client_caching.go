@@ -0,0 +1,78 @@
+package isupipe
+
+import (
+	"context"
+	"io"
+	"net/http"
+
+	"github.com/isucon/isucon13/bench/internal/bencherror"
+)
+
+// CacheConditions は、条件付きリクエストに付与する検証子です
+type CacheConditions struct {
+	IfNoneMatch     string
+	IfModifiedSince string
+}
+
+func (c CacheConditions) empty() bool {
+	return c.IfNoneMatch == "" && c.IfModifiedSince == ""
+}
+
+// CacheableResponse は、キャッシュの検証に用いるレスポンスです
+type CacheableResponse struct {
+	Request      *http.Request
+	StatusCode   int
+	Body         []byte
+	ETag         string
+	LastModified string
+	CacheControl string
+}
+
+// NotModified は、304 Not Modified が返されたかを返します
+func (r *CacheableResponse) NotModified() bool {
+	return r.StatusCode == http.StatusNotModified
+}
+
+// GetCacheable は、urlPathを条件付きリクエストで取得し、キャッシュに関するヘッダとともに返します
+// NOTE: アイコンや静的ファイルと同様に、assetAgentを用いる
+func (c *Client) GetCacheable(ctx context.Context, urlPath string, conditions CacheConditions) (*CacheableResponse, error) {
+	req, err := c.assetAgent.NewRequest(http.MethodGet, urlPath, nil)
+	if err != nil {
+		return nil, bencherror.NewInternalError(ctx, err)
+	}
+	if conditions.IfNoneMatch != "" {
+		req.Header.Set("If-None-Match", conditions.IfNoneMatch)
+	}
+	if conditions.IfModifiedSince != "" {
+		req.Header.Set("If-Modified-Since", conditions.IfModifiedSince)
+	}
+
+	resp, err := c.sendRequest(ctx, c.assetAgent, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, bencherror.NewHttpResponseError(ctx, err, req)
+	}
+
+	result := &CacheableResponse{
+		Request:      req,
+		StatusCode:   resp.StatusCode,
+		Body:         body,
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		CacheControl: resp.Header.Get("Cache-Control"),
+	}
+	if result.NotModified() {
+		if conditions.empty() {
+			return nil, bencherror.NewCachingError(ctx, req, "条件付きリクエストでないにも関わらず 304 が返されました")
+		}
+		if len(body) > 0 {
+			return nil, bencherror.NewCachingError(ctx, req, "304 のレスポンスにボディ(%d bytes)が含まれています", len(body))
+		}
+	}
+	return result, nil
+}
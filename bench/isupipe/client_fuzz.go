@@ -0,0 +1,37 @@
+package isupipe
+
+import (
+	"bytes"
+	"context"
+	"io"
+
+	"github.com/isucon/isucon13/bench/internal/bencherror"
+)
+
+// SendRaw は、レスポンスを検証せずにリクエストを送り、ステータスコードとボディを返します
+// NOTE: 運営向けのfuzzモードで、型や範囲を問わず任意のパラメータを送るために用います
+func (c *Client) SendRaw(ctx context.Context, method, urlPath string, body []byte) (int, []byte, error) {
+	var reqBody io.Reader
+	if body != nil {
+		reqBody = bytes.NewReader(body)
+	}
+	req, err := c.agent.NewRequest(method, urlPath, reqBody)
+	if err != nil {
+		return 0, nil, bencherror.NewInternalError(ctx, err)
+	}
+	if body != nil {
+		req.Header.Add("Content-Type", "application/json;charset=utf-8")
+	}
+
+	resp, err := c.sendRequest(ctx, c.agent, req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return resp.StatusCode, nil, bencherror.NewHttpResponseError(ctx, err, req)
+	}
+	return resp.StatusCode, respBody, nil
+}
@@ -1,50 +1,118 @@
 package isupipe
 
 import (
+	"bytes"
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"time"
+
+	"github.com/isucon/isucon13/bench/internal/config"
 
 	"go.uber.org/zap"
 )
 
+// InitializeRequest は、初期データの規模や機能フラグを指定する際のinitializeのリクエストボディです
+type InitializeRequest struct {
+	Scale    float64  `json:"scale,omitempty"`
+	Features []string `json:"features,omitempty"`
+}
+
+// InitializeDataset は、webappが実際に投入した初期データの情報です
+// NOTE: パラメータを指定した場合のみ必須とし、pretestで要求通りか検証する
+type InitializeDataset struct {
+	Scale       float64  `json:"scale"`
+	Features    []string `json:"features"`
+	Users       int64    `json:"users"`
+	Livestreams int64    `json:"livestreams"`
+}
+
 type InitializeResponse struct {
-	Language string `json:"language" validate:"required"`
+	Language string             `json:"language" validate:"required"`
+	Dataset  *InitializeDataset `json:"dataset,omitempty"`
+
+	// NOTE: レスポンスボディではなく、レスポンスヘッダや接続情報から収集する
+	Metadata ServerMetadata `json:"-"`
+	// リクエスト送信からレスポンスボディ読み込み完了までの所要時間
+	Duration time.Duration `json:"-"`
+}
+
+// ServerMetadata は、webappのミドルウェアや通信方式に関する情報です
+// 競技後の統計に用いるため、結果に記録します
+type ServerMetadata struct {
+	// Serverヘッダ (e.g. nginx/1.24.0)
+	Server string `json:"server"`
+	// HTTPプロトコルバージョン (e.g. HTTP/2.0)
+	Proto string `json:"proto"`
+
+	// NOTE: TLS接続の場合のみ
+	TLSVersion            string `json:"tls_version,omitempty"`
+	TLSCipherSuite        string `json:"tls_cipher_suite,omitempty"`
+	TLSNegotiatedProtocol string `json:"tls_negotiated_protocol,omitempty"`
+}
+
+func newServerMetadata(resp *http.Response) ServerMetadata {
+	metadata := ServerMetadata{
+		Server: resp.Header.Get("Server"),
+		Proto:  resp.Proto,
+	}
+	if resp.TLS != nil {
+		metadata.TLSVersion = tls.VersionName(resp.TLS.Version)
+		metadata.TLSCipherSuite = tls.CipherSuiteName(resp.TLS.CipherSuite)
+		metadata.TLSNegotiatedProtocol = resp.TLS.NegotiatedProtocol
+	}
+	return metadata
 }
 
 func (c *Client) Initialize(ctx context.Context) (*InitializeResponse, error) {
 	lgr := zap.S()
 
-	req, err := c.agent.NewRequest(http.MethodPost, "/api/initialize", nil)
+	var body io.Reader
+	if config.HasInitializeParams() {
+		payload, err := json.Marshal(&InitializeRequest{
+			Scale:    config.InitializeScale,
+			Features: config.InitializeFeatureList(),
+		})
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(payload)
+	}
+
+	req, err := c.agent.NewRequest(http.MethodPost, "/api/initialize", body)
 	if err != nil {
 		lgr.Warnf("initializeのリクエスト初期化失敗: %s\n", err.Error())
 		return nil, err
 	}
 	req.Header.Add("Content-Type", "application/json;charset=utf-8")
 
+	startAt := time.Now()
 	resp, err := c.agent.Do(ctx, req)
 	if err != nil {
 		c.contestantLogger.Warn("POST /api/initialize のリクエストが失敗しました", zap.Error(err))
 		return nil, fmt.Errorf("initializeのリクエストに失敗しました %v", err)
 	}
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("initialize へのリクエストに対して、期待されたHTTPステータスコードが確認できませんでした (expected:%d, actual:%d)", http.StatusOK, resp.StatusCode)
-	}
 	defer func() {
 		io.Copy(io.Discard, resp.Body)
 		resp.Body.Close()
 	}()
-
-	var initializeResp *InitializeResponse
-	if json.NewDecoder(resp.Body).Decode(&initializeResp); err != nil {
-		return nil, fmt.Errorf("initializeのJSONのdecodeに失敗しました %v", err)
+	if wantStatusCode := ExpectedStatusCode(ActionInitialize); resp.StatusCode != wantStatusCode {
+		// NOTE: 選手がinitializeハンドラのエラー内容を確認できるよう、レスポンスボディを添える
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, config.InitializeErrorBodyLimit))
+		c.contestantLogger.Warn("POST /api/initialize がエラーを返しました", zap.Int("status_code", resp.StatusCode), zap.String("body", string(body)))
+		return nil, fmt.Errorf("initialize へのリクエストに対して、期待されたHTTPステータスコードが確認できませんでした (expected:%d, actual:%d, body:%q)", wantStatusCode, resp.StatusCode, body)
 	}
-	if err := ValidateResponse(req, initializeResp); err != nil {
+
+	initializeResp, err := DecodeAndValidate[*InitializeResponse](ctx, req, resp.Body)
+	if err != nil {
 		c.contestantLogger.Warn(err.Error())
 		return nil, err
 	}
+	initializeResp.Metadata = newServerMetadata(resp)
+	initializeResp.Duration = time.Since(startAt)
 
 	return initializeResp, nil
 }
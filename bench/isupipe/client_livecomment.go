@@ -20,7 +20,7 @@ type Livecomment struct {
 	Livestream Livestream `json:"livestream" validate:"required"`
 	Comment    string     `json:"comment" validate:"required"`
 	// NOTE: Tipがない場合が許容される(tip=0)
-	Tip       int `json:"tip"`
+	Tip       int `json:"tip" validate:"gte=0"`
 	CreatedAt int `json:"created_at" validate:"required"`
 }
 
@@ -41,7 +41,7 @@ type (
 		User       User       `json:"user" validate:"required"`
 		Livestream Livestream `json:"livestream" validate:"required"`
 		Comment    string     `json:"comment" validate:"required"`
-		Tip        int64      `json:"tip"`
+		Tip        int64      `json:"tip" validate:"gte=0"`
 		CreatedAt  int64      `json:"created_at" validate:"required"`
 	}
 )
@@ -66,17 +66,17 @@ type NGWord struct {
 
 func (c *Client) GetLivecomments(ctx context.Context, livestreamID int64, streamerName string, opts ...ClientOption) ([]*Livecomment, error) {
 	var (
-		defaultStatusCode = http.StatusOK
+		defaultStatusCode = ExpectedStatusCode(ActionGetLivecomments)
 		o                 = newClientOptions(defaultStatusCode, opts...)
 	)
 
 	if err := c.setStreamerURL(streamerName); err != nil {
-		return nil, bencherror.NewInternalError(err)
+		return nil, bencherror.NewInternalError(ctx, err)
 	}
 	urlPath := fmt.Sprintf("/api/livestream/%d/livecomment", livestreamID)
 	req, err := c.themeAgent.NewRequest(http.MethodGet, urlPath, nil)
 	if err != nil {
-		return nil, bencherror.NewInternalError(err)
+		return nil, bencherror.NewInternalError(ctx, err)
 	}
 
 	if o.limitParam != nil {
@@ -85,7 +85,7 @@ func (c *Client) GetLivecomments(ctx context.Context, livestreamID int64, stream
 		req.URL.RawQuery = query.Encode()
 	}
 
-	resp, err := sendRequest(ctx, c.themeAgent, req)
+	resp, err := c.sendRequest(ctx, c.themeAgent, req)
 	if err != nil {
 		return nil, err
 	}
@@ -95,16 +95,13 @@ func (c *Client) GetLivecomments(ctx context.Context, livestreamID int64, stream
 	}()
 
 	if resp.StatusCode != o.wantStatusCode {
-		return nil, bencherror.NewHttpStatusError(req, o.wantStatusCode, resp.StatusCode)
+		return nil, bencherror.NewHttpStatusError(ctx, req, o.wantStatusCode, resp.StatusCode)
 	}
 
 	livecomments := []*Livecomment{}
 	if resp.StatusCode == defaultStatusCode {
-		if err := json.NewDecoder(resp.Body).Decode(&livecomments); err != nil {
-			return livecomments, bencherror.NewHttpResponseError(err, req)
-		}
-
-		if err := ValidateSlice(req, livecomments); err != nil {
+		livecomments, err = DecodeAndValidate[[]*Livecomment](ctx, req, resp.Body)
+		if err != nil {
 			return nil, err
 		}
 	}
@@ -114,21 +111,21 @@ func (c *Client) GetLivecomments(ctx context.Context, livestreamID int64, stream
 
 func (c *Client) GetLivecommentReports(ctx context.Context, livestreamID int64, streamerName string, opts ...ClientOption) ([]LivecommentReport, error) {
 	var (
-		defaultStatusCode = http.StatusOK
+		defaultStatusCode = ExpectedStatusCode(ActionGetLivecommentReports)
 		o                 = newClientOptions(defaultStatusCode, opts...)
 	)
 
 	if err := c.setStreamerURL(streamerName); err != nil {
-		return nil, bencherror.NewInternalError(err)
+		return nil, bencherror.NewInternalError(ctx, err)
 	}
 
 	urlPath := fmt.Sprintf("/api/livestream/%d/report", livestreamID)
 	req, err := c.themeAgent.NewRequest(http.MethodGet, urlPath, nil)
 	if err != nil {
-		return nil, bencherror.NewInternalError(err)
+		return nil, bencherror.NewInternalError(ctx, err)
 	}
 
-	resp, err := sendRequest(ctx, c.themeAgent, req)
+	resp, err := c.sendRequest(ctx, c.themeAgent, req)
 	if err != nil {
 		return nil, err
 	}
@@ -138,16 +135,13 @@ func (c *Client) GetLivecommentReports(ctx context.Context, livestreamID int64,
 	}()
 
 	if resp.StatusCode != o.wantStatusCode {
-		return nil, bencherror.NewHttpStatusError(req, o.wantStatusCode, resp.StatusCode)
+		return nil, bencherror.NewHttpStatusError(ctx, req, o.wantStatusCode, resp.StatusCode)
 	}
 
 	reports := []LivecommentReport{}
 	if resp.StatusCode == defaultStatusCode {
-		if err := json.NewDecoder(resp.Body).Decode(&reports); err != nil {
-			return reports, bencherror.NewHttpResponseError(err, req)
-		}
-
-		if err := ValidateSlice(req, reports); err != nil {
+		reports, err = DecodeAndValidate[[]LivecommentReport](ctx, req, resp.Body)
+		if err != nil {
 			return nil, err
 		}
 	}
@@ -157,21 +151,21 @@ func (c *Client) GetLivecommentReports(ctx context.Context, livestreamID int64,
 
 func (c *Client) GetNgwords(ctx context.Context, livestreamID int64, streamerName string, opts ...ClientOption) ([]*NGWord, error) {
 	var (
-		defaultStatusCode = http.StatusOK
+		defaultStatusCode = ExpectedStatusCode(ActionGetNgwords)
 		o                 = newClientOptions(defaultStatusCode, opts...)
 	)
 
 	if err := c.setStreamerURL(streamerName); err != nil {
-		return nil, bencherror.NewInternalError(err)
+		return nil, bencherror.NewInternalError(ctx, err)
 	}
 
 	urlPath := fmt.Sprintf("/api/livestream/%d/ngwords", livestreamID)
 	req, err := c.themeAgent.NewRequest(http.MethodGet, urlPath, nil)
 	if err != nil {
-		return nil, bencherror.NewInternalError(err)
+		return nil, bencherror.NewInternalError(ctx, err)
 	}
 
-	resp, err := sendRequest(ctx, c.themeAgent, req)
+	resp, err := c.sendRequest(ctx, c.themeAgent, req)
 	if err != nil {
 		return nil, err
 	}
@@ -181,16 +175,13 @@ func (c *Client) GetNgwords(ctx context.Context, livestreamID int64, streamerNam
 	}()
 
 	if resp.StatusCode != o.wantStatusCode {
-		return nil, bencherror.NewHttpStatusError(req, o.wantStatusCode, resp.StatusCode)
+		return nil, bencherror.NewHttpStatusError(ctx, req, o.wantStatusCode, resp.StatusCode)
 	}
 
 	var ngwords []*NGWord
 	if resp.StatusCode == defaultStatusCode {
-		if err := json.NewDecoder(resp.Body).Decode(&ngwords); err != nil {
-			return nil, bencherror.NewHttpResponseError(err, req)
-		}
-
-		if err := ValidateSlice(req, ngwords); err != nil {
+		ngwords, err = DecodeAndValidate[[]*NGWord](ctx, req, resp.Body)
+		if err != nil {
 			return nil, err
 		}
 	}
@@ -200,7 +191,7 @@ func (c *Client) GetNgwords(ctx context.Context, livestreamID int64, streamerNam
 
 func (c *Client) PostLivecomment(ctx context.Context, livestreamID int64, streamerName string, comment string, tip *scheduler.Tip, opts ...ClientOption) (*PostLivecommentResponse, int, error) {
 	var (
-		defaultStatusCode = http.StatusCreated
+		defaultStatusCode = ExpectedStatusCode(ActionPostLivecomment)
 		o                 = newClientOptions(defaultStatusCode, opts...)
 		r                 = &PostLivecommentRequest{
 			Comment: comment,
@@ -210,20 +201,29 @@ func (c *Client) PostLivecomment(ctx context.Context, livestreamID int64, stream
 
 	payload, err := json.Marshal(r)
 	if err != nil {
-		return nil, 0, bencherror.NewInternalError(err)
+		return nil, 0, bencherror.NewInternalError(ctx, err)
 	}
 
 	if err := c.setStreamerURL(streamerName); err != nil {
-		return nil, 0, bencherror.NewInternalError(err)
+		return nil, 0, bencherror.NewInternalError(ctx, err)
 	}
 	urlPath := fmt.Sprintf("/api/livestream/%d/livecomment", livestreamID)
 	req, err := c.themeAgent.NewRequest(http.MethodPost, urlPath, bytes.NewReader(payload))
 	if err != nil {
-		return nil, 0, bencherror.NewInternalError(err)
+		return nil, 0, bencherror.NewInternalError(ctx, err)
 	}
 	req.Header.Add("Content-Type", "application/json;charset=utf-8")
 
-	resp, err := sendRequest(ctx, c.themeAgent, req)
+	if tip.Tip > 0 {
+		scheduler.RankingLedger.RecordScoreEvent(livestreamID)
+	}
+	benchscore.RecordTipAttempt(ctx, livestreamID, streamerName, uint64(tip.Tip))
+	activity := benchscore.ActivityLivecomment
+	if scheduler.LivecommentScheduler.IsNgLivecomment(comment) {
+		activity = benchscore.ActivitySpamLivecomment
+	}
+	benchscore.RecordActivityAttempt(ctx, activity, livestreamID, streamerName)
+	resp, err := c.sendRequest(ctx, c.themeAgent, req)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -233,42 +233,109 @@ func (c *Client) PostLivecomment(ctx context.Context, livestreamID int64, stream
 	}()
 
 	if resp.StatusCode != o.wantStatusCode {
-		return nil, 0, bencherror.NewHttpStatusError(req, o.wantStatusCode, resp.StatusCode)
+		return nil, 0, bencherror.NewHttpStatusError(ctx, req, o.wantStatusCode, resp.StatusCode)
 	}
 
 	var livecommentResponse *PostLivecommentResponse
 	if resp.StatusCode == defaultStatusCode {
-		if err := json.NewDecoder(resp.Body).Decode(&livecommentResponse); err != nil {
-			return nil, 0, bencherror.NewHttpResponseError(err, req)
-		}
-
-		if err := ValidateResponse(req, livecommentResponse); err != nil {
+		livecommentResponse, err = DecodeAndValidate[*PostLivecommentResponse](ctx, req, resp.Body)
+		if err != nil {
 			return nil, 0, err
 		}
 
-		benchscore.AddTip(uint64(tip.Tip))
+		benchscore.AddTip(ctx, livestreamID, streamerName, uint64(tip.Tip))
+		benchscore.RecordTippedLivecomment(ctx, livestreamID, comment, uint64(tip.Tip))
+		benchscore.ConfirmActivity(ctx, activity, livestreamID, streamerName)
 	}
 
 	return livecommentResponse, tip.Tip, nil
 }
 
+// PostLivecommentWithRawTip は、tipにJSONの値をそのまま与えてライブコメントを投稿し、レスポンスのステータスコードを返します
+// 範囲外や整数でないチップに対するwebappの入力検証を確かめるのに用います
+// NOTE: 受理されたチップは台帳に記録しますが、売上には加算しません
+func (c *Client) PostLivecommentWithRawTip(ctx context.Context, livestreamID int64, streamerName string, comment string, rawTip json.RawMessage, opts ...ClientOption) (*PostLivecommentResponse, int, error) {
+	var (
+		defaultStatusCode = ExpectedStatusCode(ActionPostLivecomment)
+		o                 = newClientOptions(defaultStatusCode, opts...)
+	)
+
+	payload, err := json.Marshal(map[string]any{
+		"comment": comment,
+		"tip":     rawTip,
+	})
+	if err != nil {
+		return nil, 0, bencherror.NewInternalError(ctx, err)
+	}
+
+	if err := c.setStreamerURL(streamerName); err != nil {
+		return nil, 0, bencherror.NewInternalError(ctx, err)
+	}
+	urlPath := fmt.Sprintf("/api/livestream/%d/livecomment", livestreamID)
+	req, err := c.themeAgent.NewRequest(http.MethodPost, urlPath, bytes.NewReader(payload))
+	if err != nil {
+		return nil, 0, bencherror.NewInternalError(ctx, err)
+	}
+	req.Header.Add("Content-Type", "application/json;charset=utf-8")
+
+	// NOTE: 整数として解釈できない値も、webappによっては丸めてスコアに反映しうる
+	tip, parseErr := strconv.ParseInt(string(rawTip), 10, 64)
+	if parseErr != nil || tip != 0 {
+		scheduler.RankingLedger.RecordScoreEvent(livestreamID)
+	}
+	if parseErr == nil && tip > 0 {
+		benchscore.RecordTipAttempt(ctx, livestreamID, streamerName, uint64(tip))
+	}
+	resp, err := c.sendRequest(ctx, c.themeAgent, req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer func() {
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}()
+
+	if !o.isExpectedStatusCode(resp.StatusCode) {
+		return nil, resp.StatusCode, bencherror.NewHttpStatusError(ctx, req, o.wantStatusCode, resp.StatusCode)
+	}
+
+	var livecommentResponse *PostLivecommentResponse
+	if resp.StatusCode == defaultStatusCode {
+		// NOTE: 範囲外のチップをそのまま返すことは呼び出し側で検証するため、ここではスキーマの検証を行わない
+		if err := decodeJSON(ctx, req, resp.Body, &livecommentResponse); err != nil {
+			// NOTE: 整数でないチップを受理したwebappは、tipを整数でない値のまま返しうる
+			if parseErr != nil {
+				return nil, resp.StatusCode, nil
+			}
+			return nil, resp.StatusCode, err
+		}
+		if parseErr == nil && tip > 0 {
+			benchscore.ConfirmTip(ctx, livestreamID, streamerName, uint64(tip))
+			benchscore.RecordTippedLivecomment(ctx, livestreamID, comment, uint64(tip))
+		}
+	}
+
+	return livecommentResponse, resp.StatusCode, nil
+}
+
 func (c *Client) ReportLivecomment(ctx context.Context, livestreamID int64, streamerName string, livecommentID int64, opts ...ClientOption) error {
 	var (
-		defaultStatusCode = http.StatusCreated
+		defaultStatusCode = ExpectedStatusCode(ActionReportLivecomment)
 		o                 = newClientOptions(defaultStatusCode, opts...)
 	)
 
 	if err := c.setStreamerURL(streamerName); err != nil {
-		return bencherror.NewInternalError(err)
+		return bencherror.NewInternalError(ctx, err)
 	}
 	urlPath := fmt.Sprintf("/api/livestream/%d/livecomment/%d/report", livestreamID, livecommentID)
 	req, err := c.themeAgent.NewRequest(http.MethodPost, urlPath, nil)
 	if err != nil {
-		return bencherror.NewInternalError(err)
+		return bencherror.NewInternalError(ctx, err)
 	}
 	req.Header.Add("Content-Type", "application/json;charset=utf-8")
 
-	resp, err := sendRequest(ctx, c.themeAgent, req)
+	benchscore.RecordActivityAttempt(ctx, benchscore.ActivityReport, livestreamID, streamerName)
+	resp, err := c.sendRequest(ctx, c.themeAgent, req)
 	if err != nil {
 		return err
 	}
@@ -278,33 +345,39 @@ func (c *Client) ReportLivecomment(ctx context.Context, livestreamID int64, stre
 	}()
 
 	if resp.StatusCode != o.wantStatusCode {
-		return bencherror.NewHttpStatusError(req, o.wantStatusCode, resp.StatusCode)
+		return bencherror.NewHttpStatusError(ctx, req, o.wantStatusCode, resp.StatusCode)
 	}
 
 	var livecommentReport *LivecommentReport
 	if resp.StatusCode == defaultStatusCode {
-		if err := json.NewDecoder(resp.Body).Decode(&livecommentReport); err != nil {
-			return bencherror.NewHttpResponseError(err, req)
-		}
-
 		if o.validateReportLivecomment {
-			if err := ValidateResponse(req, livecommentReport); err != nil {
+			if _, err := DecodeAndValidate[*LivecommentReport](ctx, req, resp.Body); err != nil {
 				return err
 			}
+		} else if err := decodeJSON(ctx, req, resp.Body, &livecommentReport); err != nil {
+			return err
 		}
+
+		benchscore.ConfirmActivity(ctx, benchscore.ActivityReport, livestreamID, streamerName)
 	}
 
 	return nil
 }
 
 func (c *Client) Moderate(ctx context.Context, livestreamID int64, streamerName string, ngWord string, opts ...ClientOption) error {
+	_, err := c.RegisterNgWord(ctx, livestreamID, streamerName, ngWord, opts...)
+	return err
+}
+
+// RegisterNgWord は、配信にNGワードを登録し、登録されたNGワードのIDを含むレスポンスを返します
+func (c *Client) RegisterNgWord(ctx context.Context, livestreamID int64, streamerName string, ngWord string, opts ...ClientOption) (*ModerateResponse, error) {
 	var (
-		defaultStatusCode = http.StatusCreated
+		defaultStatusCode = ExpectedStatusCode(ActionModerate)
 		o                 = newClientOptions(defaultStatusCode, opts...)
 	)
 
 	if err := c.setStreamerURL(streamerName); err != nil {
-		return bencherror.NewInternalError(err)
+		return nil, bencherror.NewInternalError(ctx, err)
 	}
 
 	urlPath := fmt.Sprintf("/api/livestream/%d/moderate", livestreamID)
@@ -312,18 +385,18 @@ func (c *Client) Moderate(ctx context.Context, livestreamID int64, streamerName
 		NGWord: ngWord,
 	})
 	if err != nil {
-		return bencherror.NewInternalError(err)
+		return nil, bencherror.NewInternalError(ctx, err)
 	}
 
 	req, err := c.themeAgent.NewRequest(http.MethodPost, urlPath, bytes.NewBuffer(payload))
 	if err != nil {
-		return bencherror.NewInternalError(err)
+		return nil, bencherror.NewInternalError(ctx, err)
 	}
 	req.Header.Add("Content-Type", "application/json;charset=utf-8")
 
-	resp, err := sendRequest(ctx, c.themeAgent, req)
+	resp, err := c.sendRequest(ctx, c.themeAgent, req)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer func() {
 		io.Copy(io.Discard, resp.Body)
@@ -331,19 +404,18 @@ func (c *Client) Moderate(ctx context.Context, livestreamID int64, streamerName
 	}()
 
 	if resp.StatusCode != o.wantStatusCode {
-		return bencherror.NewHttpStatusError(req, o.wantStatusCode, resp.StatusCode)
+		return nil, bencherror.NewHttpStatusError(ctx, req, o.wantStatusCode, resp.StatusCode)
 	}
 
 	var moderateResp *ModerateResponse
 	if resp.StatusCode == defaultStatusCode {
-		if err := json.NewDecoder(resp.Body).Decode(&moderateResp); err != nil {
-			return bencherror.NewHttpResponseError(err, req)
-		}
-
-		if err := ValidateResponse(req, moderateResp); err != nil {
-			return err
+		// NOTE: NGワードを含む過去のライブコメントは、チップごと削除される
+		benchscore.ModerateTips(ctx, livestreamID, streamerName, ngWord)
+		moderateResp, err = DecodeAndValidate[*ModerateResponse](ctx, req, resp.Body)
+		if err != nil {
+			return nil, err
 		}
 	}
 
-	return nil
+	return moderateResp, nil
 }
@@ -21,6 +21,7 @@ func TestModerate(t *testing.T) {
 	assert.NoError(t, err)
 
 	client, err := NewClient(
+		ctx,
 		testLogger,
 		agent.WithBaseURL(config.TargetBaseURL),
 		agent.WithTimeout(10*time.Minute),
@@ -62,6 +63,10 @@ func TestModerate(t *testing.T) {
 
 	err = client.Moderate(ctx, livestream.ID, livestream.Owner.Name, "test")
 	assert.NoError(t, err)
+
+	moderateResp, err := client.RegisterNgWord(ctx, livestream.ID, livestream.Owner.Name, "test2")
+	assert.NoError(t, err)
+	assert.NotZero(t, moderateResp.WordID)
 }
 
 // ref. https://github.com/isucon/isucon13/pull/141/files#r1380262831
@@ -72,6 +77,7 @@ func TestGetNgWordsBug(t *testing.T) {
 	assert.NoError(t, err)
 
 	client, err := NewClient(
+		ctx,
 		testLogger,
 		agent.WithBaseURL(config.TargetBaseURL),
 		// FIXME: moderateが遅い
@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/isucon/isucon13/bench/internal/bencherror"
+	"github.com/isucon/isucon13/bench/internal/benchscore"
 )
 
 type Livestream struct {
@@ -49,20 +50,20 @@ func (c *Client) GetLivestream(
 	opts ...ClientOption,
 ) (*Livestream, error) {
 	var (
-		defaultStatusCode = http.StatusOK
+		defaultStatusCode = ExpectedStatusCode(ActionGetLivestream)
 		o                 = newClientOptions(defaultStatusCode, opts...)
 	)
 
 	if err := c.setStreamerURL(streamerName); err != nil {
-		return nil, bencherror.NewInternalError(err)
+		return nil, bencherror.NewInternalError(ctx, err)
 	}
 	urlPath := fmt.Sprintf("/api/livestream/%d", livestreamID)
 	req, err := c.themeAgent.NewRequest(http.MethodGet, urlPath, nil)
 	if err != nil {
-		return nil, bencherror.NewInternalError(err)
+		return nil, bencherror.NewInternalError(ctx, err)
 	}
 
-	resp, err := sendRequest(ctx, c.themeAgent, req)
+	resp, err := c.sendRequest(ctx, c.themeAgent, req)
 	if err != nil {
 		return nil, err
 	}
@@ -71,17 +72,14 @@ func (c *Client) GetLivestream(
 		resp.Body.Close()
 	}()
 
-	if resp.StatusCode != o.wantStatusCode {
-		return nil, bencherror.NewHttpStatusError(req, o.wantStatusCode, resp.StatusCode)
+	if !o.isExpectedStatusCode(resp.StatusCode) {
+		return nil, bencherror.NewHttpStatusError(ctx, req, o.wantStatusCode, resp.StatusCode)
 	}
 
 	var livestream *Livestream
 	if resp.StatusCode == defaultStatusCode {
-		if err := json.NewDecoder(resp.Body).Decode(&livestream); err != nil {
-			return nil, bencherror.NewHttpResponseError(err, req)
-		}
-
-		if err := ValidateResponse(req, livestream); err != nil {
+		livestream, err = DecodeAndValidate[*Livestream](ctx, req, resp.Body)
+		if err != nil {
 			return nil, err
 		}
 	}
@@ -94,13 +92,13 @@ func (c *Client) SearchLivestreams(
 	opts ...ClientOption,
 ) ([]*Livestream, error) {
 	var (
-		defaultStatusCode = http.StatusOK
+		defaultStatusCode = ExpectedStatusCode(ActionSearchLivestreams)
 		o                 = newClientOptions(defaultStatusCode, opts...)
 	)
 
 	req, err := c.agent.NewRequest(http.MethodGet, "/api/livestream/search", nil)
 	if err != nil {
-		return nil, bencherror.NewInternalError(err)
+		return nil, bencherror.NewInternalError(ctx, err)
 	}
 	if o.searchTag != nil {
 		query := req.URL.Query()
@@ -114,7 +112,7 @@ func (c *Client) SearchLivestreams(
 		req.URL.RawQuery = query.Encode()
 	}
 
-	resp, err := sendRequest(ctx, c.agent, req)
+	resp, err := c.sendRequest(ctx, c.agent, req)
 	if err != nil {
 		return nil, err
 	}
@@ -124,16 +122,13 @@ func (c *Client) SearchLivestreams(
 	}()
 
 	if resp.StatusCode != o.wantStatusCode {
-		return nil, bencherror.NewHttpStatusError(req, o.wantStatusCode, resp.StatusCode)
+		return nil, bencherror.NewHttpStatusError(ctx, req, o.wantStatusCode, resp.StatusCode)
 	}
 
 	var livestreams []*Livestream
 	if resp.StatusCode == defaultStatusCode {
-		if err := json.NewDecoder(resp.Body).Decode(&livestreams); err != nil {
-			return nil, err
-		}
-
-		if err := ValidateSlice(req, livestreams); err != nil {
+		livestreams, err = DecodeAndValidate[[]*Livestream](ctx, req, resp.Body)
+		if err != nil {
 			return nil, err
 		}
 	}
@@ -144,16 +139,16 @@ func (c *Client) SearchLivestreams(
 // 自分のライブ配信一覧取得
 func (c *Client) GetMyLivestreams(ctx context.Context, opts ...ClientOption) ([]*Livestream, error) {
 	var (
-		defaultStatusCode = http.StatusOK
+		defaultStatusCode = ExpectedStatusCode(ActionGetMyLivestreams)
 		o                 = newClientOptions(defaultStatusCode, opts...)
 	)
 
 	req, err := c.agent.NewRequest(http.MethodGet, "/api/livestream", nil)
 	if err != nil {
-		return nil, bencherror.NewInternalError(err)
+		return nil, bencherror.NewInternalError(ctx, err)
 	}
 
-	resp, err := sendRequest(ctx, c.agent, req)
+	resp, err := c.sendRequest(ctx, c.agent, req)
 	if err != nil {
 		return nil, err
 	}
@@ -163,16 +158,13 @@ func (c *Client) GetMyLivestreams(ctx context.Context, opts ...ClientOption) ([]
 	}()
 
 	if resp.StatusCode != o.wantStatusCode {
-		return nil, bencherror.NewHttpStatusError(req, o.wantStatusCode, resp.StatusCode)
+		return nil, bencherror.NewHttpStatusError(ctx, req, o.wantStatusCode, resp.StatusCode)
 	}
 
 	var livestreams []*Livestream
 	if resp.StatusCode == defaultStatusCode {
-		if err := json.NewDecoder(resp.Body).Decode(&livestreams); err != nil {
-			return nil, bencherror.NewHttpResponseError(err, req)
-		}
-
-		if err := ValidateSlice(req, livestreams); err != nil {
+		livestreams, err = DecodeAndValidate[[]*Livestream](ctx, req, resp.Body)
+		if err != nil {
 			return nil, err
 		}
 	}
@@ -183,16 +175,16 @@ func (c *Client) GetMyLivestreams(ctx context.Context, opts ...ClientOption) ([]
 // 特定ユーザのライブ配信取得
 func (c *Client) GetUserLivestreams(ctx context.Context, username string, opts ...ClientOption) ([]*Livestream, error) {
 	var (
-		defaultStatusCode = http.StatusOK
+		defaultStatusCode = ExpectedStatusCode(ActionGetUserLivestreams)
 		o                 = newClientOptions(defaultStatusCode, opts...)
 	)
 
 	req, err := c.agent.NewRequest(http.MethodGet, fmt.Sprintf("/api/user/%s/livestream", username), nil)
 	if err != nil {
-		return nil, bencherror.NewInternalError(err)
+		return nil, bencherror.NewInternalError(ctx, err)
 	}
 
-	resp, err := sendRequest(ctx, c.agent, req)
+	resp, err := c.sendRequest(ctx, c.agent, req)
 	if err != nil {
 		return nil, err
 	}
@@ -202,16 +194,13 @@ func (c *Client) GetUserLivestreams(ctx context.Context, username string, opts .
 	}()
 
 	if resp.StatusCode != o.wantStatusCode {
-		return nil, bencherror.NewHttpStatusError(req, o.wantStatusCode, resp.StatusCode)
+		return nil, bencherror.NewHttpStatusError(ctx, req, o.wantStatusCode, resp.StatusCode)
 	}
 
 	var livestreams []*Livestream
 	if resp.StatusCode == defaultStatusCode {
-		if err := json.NewDecoder(resp.Body).Decode(&livestreams); err != nil {
-			return nil, bencherror.NewHttpResponseError(err, req)
-		}
-
-		if err := ValidateSlice(req, livestreams); err != nil {
+		livestreams, err = DecodeAndValidate[[]*Livestream](ctx, req, resp.Body)
+		if err != nil {
 			return nil, err
 		}
 	}
@@ -221,25 +210,25 @@ func (c *Client) GetUserLivestreams(ctx context.Context, username string, opts .
 
 func (c *Client) ReserveLivestream(ctx context.Context, streamerName string, r *ReserveLivestreamRequest, opts ...ClientOption) (*Livestream, error) {
 	var (
-		defaultStatusCode = http.StatusCreated
+		defaultStatusCode = ExpectedStatusCode(ActionReserveLivestream)
 		o                 = newClientOptions(defaultStatusCode, opts...)
 	)
 
 	payload, err := json.Marshal(r)
 	if err != nil {
-		return nil, bencherror.NewInternalError(err)
+		return nil, bencherror.NewInternalError(ctx, err)
 	}
 
 	if err := c.setStreamerURL(streamerName); err != nil {
-		return nil, bencherror.NewInternalError(err)
+		return nil, bencherror.NewInternalError(ctx, err)
 	}
 	req, err := c.themeAgent.NewRequest(http.MethodPost, "/api/livestream/reservation", bytes.NewReader(payload))
 	if err != nil {
-		return nil, bencherror.NewInternalError(err)
+		return nil, bencherror.NewInternalError(ctx, err)
 	}
 	req.Header.Add("Content-Type", "application/json;charset=utf-8")
 
-	resp, err := sendRequest(ctx, c.themeAgent, req)
+	resp, err := c.sendRequest(ctx, c.themeAgent, req)
 	if err != nil {
 		return nil, err
 	}
@@ -248,17 +237,14 @@ func (c *Client) ReserveLivestream(ctx context.Context, streamerName string, r *
 		resp.Body.Close()
 	}()
 
-	if resp.StatusCode != o.wantStatusCode {
-		return nil, bencherror.NewHttpStatusError(req, o.wantStatusCode, resp.StatusCode)
+	if !o.isExpectedStatusCode(resp.StatusCode) {
+		return nil, bencherror.NewHttpStatusError(ctx, req, o.wantStatusCode, resp.StatusCode)
 	}
 
 	var livestream *Livestream
 	if resp.StatusCode == defaultStatusCode {
-		if err := json.NewDecoder(resp.Body).Decode(&livestream); err != nil {
-			return nil, bencherror.NewHttpResponseError(err, req)
-		}
-
-		if err := ValidateResponse(req, livestream); err != nil {
+		livestream, err = DecodeAndValidate[*Livestream](ctx, req, resp.Body)
+		if err != nil {
 			return nil, err
 		}
 	}
@@ -268,21 +254,23 @@ func (c *Client) ReserveLivestream(ctx context.Context, streamerName string, r *
 
 func (c *Client) EnterLivestream(ctx context.Context, livestreamID int64, streamerName string, opts ...ClientOption) error {
 	var (
-		defaultStatusCode = http.StatusOK
+		defaultStatusCode = ExpectedStatusCode(ActionEnterLivestream)
 		o                 = newClientOptions(defaultStatusCode, opts...)
 	)
 
 	if err := c.setStreamerURL(streamerName); err != nil {
-		return bencherror.NewInternalError(err)
+		return bencherror.NewInternalError(ctx, err)
 	}
 	urlPath := fmt.Sprintf("/api/livestream/%d/enter", livestreamID)
 	req, err := c.themeAgent.NewRequest(http.MethodPost, urlPath, nil)
 	if err != nil {
-		return bencherror.NewInternalError(err)
+		return bencherror.NewInternalError(ctx, err)
 	}
 	req.Header.Add("Content-Type", "application/json;charset=utf-8")
 
-	resp, err := sendRequest(ctx, c.themeAgent, req)
+	benchscore.RecordPresenceAttempt(ctx, benchscore.ActivityEnter, livestreamID, streamerName, c.username)
+
+	resp, err := c.sendRequest(ctx, c.themeAgent, req)
 	if err != nil {
 		return err
 	}
@@ -292,28 +280,31 @@ func (c *Client) EnterLivestream(ctx context.Context, livestreamID int64, stream
 	}()
 
 	if resp.StatusCode != o.wantStatusCode {
-		return bencherror.NewHttpStatusError(req, o.wantStatusCode, resp.StatusCode)
+		return bencherror.NewHttpStatusError(ctx, req, o.wantStatusCode, resp.StatusCode)
 	}
+	benchscore.ConfirmPresence(ctx, benchscore.ActivityEnter, livestreamID, streamerName, c.username)
 
 	return nil
 }
 
 func (c *Client) ExitLivestream(ctx context.Context, livestreamID int64, streamerName string, opts ...ClientOption) error {
 	var (
-		defaultStatusCode = http.StatusOK
+		defaultStatusCode = ExpectedStatusCode(ActionExitLivestream)
 		o                 = newClientOptions(defaultStatusCode, opts...)
 	)
 
 	if err := c.setStreamerURL(streamerName); err != nil {
-		return bencherror.NewInternalError(err)
+		return bencherror.NewInternalError(ctx, err)
 	}
 	urlPath := fmt.Sprintf("/api/livestream/%d/exit", livestreamID)
 	req, err := c.themeAgent.NewRequest(http.MethodDelete, urlPath, nil)
 	if err != nil {
-		return bencherror.NewInternalError(err)
+		return bencherror.NewInternalError(ctx, err)
 	}
 
-	resp, err := sendRequest(ctx, c.themeAgent, req)
+	benchscore.RecordPresenceAttempt(ctx, benchscore.ActivityExit, livestreamID, streamerName, c.username)
+
+	resp, err := c.sendRequest(ctx, c.themeAgent, req)
 	if err != nil {
 		return err
 	}
@@ -323,8 +314,9 @@ func (c *Client) ExitLivestream(ctx context.Context, livestreamID int64, streame
 	}()
 
 	if resp.StatusCode != o.wantStatusCode {
-		return bencherror.NewHttpStatusError(req, o.wantStatusCode, resp.StatusCode)
+		return bencherror.NewHttpStatusError(ctx, req, o.wantStatusCode, resp.StatusCode)
 	}
+	benchscore.ConfirmPresence(ctx, benchscore.ActivityExit, livestreamID, streamerName, c.username)
 
 	return nil
 }
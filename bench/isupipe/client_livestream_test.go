@@ -22,6 +22,7 @@ func TestLivestream(t *testing.T) {
 	testLogger, err := logger.InitTestLogger()
 
 	client, err := NewClient(
+		ctx,
 		testLogger,
 		agent.WithBaseURL(config.TargetBaseURL),
 		agent.WithTimeout(1*time.Minute),
@@ -80,6 +81,7 @@ func TestLivestream(t *testing.T) {
 	)
 	for i := 1; i <= config.NumSlots*2; i++ {
 		loopClient, err := NewClient(
+			ctx,
 			testLogger,
 			agent.WithBaseURL(config.TargetBaseURL),
 			agent.WithTimeout(3*time.Second),
@@ -12,9 +12,11 @@ type SearchTagParam struct {
 
 type ClientOptions struct {
 	wantStatusCode int
-	limitParam     *LimitParam
-	searchTag      *SearchTagParam
-	eTag           string
+	// NOTE: 競合時のエラーなど、wantStatusCode以外にも正常とみなすステータスコード
+	allowedStatusCodes []int
+	limitParam         *LimitParam
+	searchTag          *SearchTagParam
+	eTag               string
 	// NOTE: スパム報告は、ベンチ走行中は粛清されたライブコメントを期待する場合が有り、エラーになることがある
 	// Pretestでのみスパム報告のバリデーションを行うための対応
 	validateReportLivecomment bool
@@ -39,6 +41,26 @@ func WithStatusCode(statusCode int) ClientOption {
 	}
 }
 
+// WithAllowedStatusCodes は、期待するステータスコード以外に許容するステータスコードを指定します
+// 許容されたステータスコードが返された場合、レスポンスボディは検証されません
+func WithAllowedStatusCodes(statusCodes ...int) ClientOption {
+	return func(o *ClientOptions) {
+		o.allowedStatusCodes = append(o.allowedStatusCodes, statusCodes...)
+	}
+}
+
+func (o *ClientOptions) isExpectedStatusCode(statusCode int) bool {
+	if statusCode == o.wantStatusCode {
+		return true
+	}
+	for _, allowed := range o.allowedStatusCodes {
+		if statusCode == allowed {
+			return true
+		}
+	}
+	return false
+}
+
 func WithLimitQueryParam(limit int) ClientOption {
 	return func(o *ClientOptions) {
 		o.limitParam = &LimitParam{
@@ -2,14 +2,15 @@ package isupipe
 
 import (
 	"context"
-	"encoding/json"
 	"io"
 	"net/http"
+
+	"github.com/isucon/isucon13/bench/internal/bencherror"
 )
 
 type PaymentResult struct {
 	// NOTE: 売上0を許容
-	TotalTip int64 `json:"total_tip"`
+	TotalTip int64 `json:"total_tip" validate:"gte=0"`
 }
 
 func (c *Client) GetPaymentResult(ctx context.Context) (*PaymentResult, error) {
@@ -27,12 +28,13 @@ func (c *Client) GetPaymentResult(ctx context.Context) (*PaymentResult, error) {
 		resp.Body.Close()
 	}()
 
-	var paymentResp *PaymentResult
-	if json.NewDecoder(resp.Body).Decode(&paymentResp); err != nil {
-		return nil, err
+	if wantStatusCode := ExpectedStatusCode(ActionGetPayment); resp.StatusCode != wantStatusCode {
+		return nil, bencherror.NewHttpStatusError(ctx, req, wantStatusCode, resp.StatusCode)
 	}
 
-	if err := ValidateResponse(req, paymentResp); err != nil {
+	var paymentResp *PaymentResult
+	paymentResp, err = DecodeAndValidate[*PaymentResult](ctx, req, resp.Body)
+	if err != nil {
 		return nil, err
 	}
 
@@ -19,6 +19,7 @@ func TestPayment(t *testing.T) {
 	assert.NoError(t, err)
 
 	client, err := NewClient(
+		ctx,
 		testLogger,
 		agent.WithBaseURL(config.TargetBaseURL),
 		agent.WithTimeout(3*time.Second),
@@ -0,0 +1,63 @@
+package isupipe
+
+import (
+	"context"
+	"io"
+	"net/http"
+
+	"github.com/isucon/isucon13/bench/internal/bencherror"
+)
+
+// HEADを実装していないとみなすステータスコード
+var headUnsupportedStatusCodes = map[int]struct{}{
+	http.StatusNotFound:         {},
+	http.StatusMethodNotAllowed: {},
+	http.StatusNotImplemented:   {},
+}
+
+// VerifyHead は、HEADリクエストへのレスポンスがGETと一致するか検証します
+// HEADを実装していない場合は、検証せずにfalseを返します
+// NOTE: ボディの長さが変わりうるため、内容が変化しないエンドポイントに用いること
+func (c *Client) VerifyHead(ctx context.Context, urlPath string) (bool, error) {
+	headReq, err := c.agent.NewRequest(http.MethodHead, urlPath, nil)
+	if err != nil {
+		return false, bencherror.NewInternalError(ctx, err)
+	}
+	headResp, err := c.sendRequest(ctx, c.agent, headReq)
+	if err != nil {
+		return false, err
+	}
+	io.Copy(io.Discard, headResp.Body)
+	headResp.Body.Close()
+	if _, ok := headUnsupportedStatusCodes[headResp.StatusCode]; ok {
+		return false, nil
+	}
+
+	getReq, err := c.agent.NewRequest(http.MethodGet, urlPath, nil)
+	if err != nil {
+		return false, bencherror.NewInternalError(ctx, err)
+	}
+	getResp, err := c.sendRequest(ctx, c.agent, getReq)
+	if err != nil {
+		return false, err
+	}
+	defer getResp.Body.Close()
+
+	// NOTE: Content-Lengthとの不一致は読み込み時に記録される
+	body, err := io.ReadAll(getResp.Body)
+	if err != nil {
+		return false, err
+	}
+
+	if headResp.StatusCode != getResp.StatusCode {
+		return true, bencherror.NewHeadMismatchError(ctx, headReq, "ステータスコードが異なります (HEAD: %d, GET: %d)", headResp.StatusCode, getResp.StatusCode)
+	}
+	if headResp.ContentLength >= 0 && headResp.ContentLength != int64(len(body)) {
+		return true, bencherror.NewHeadMismatchError(ctx, headReq, "Content-Length(%d)がGETのボディの長さ(%d)と異なります", headResp.ContentLength, len(body))
+	}
+	if headType, getType := headResp.Header.Get("Content-Type"), getResp.Header.Get("Content-Type"); headType != getType {
+		return true, bencherror.NewHeadMismatchError(ctx, headReq, "Content-Typeが異なります (HEAD: %s, GET: %s)", headType, getType)
+	}
+
+	return true, nil
+}
@@ -10,6 +10,8 @@ import (
 	"strconv"
 
 	"github.com/isucon/isucon13/bench/internal/bencherror"
+	"github.com/isucon/isucon13/bench/internal/benchscore"
+	"github.com/isucon/isucon13/bench/internal/scheduler"
 )
 
 type PostReactionRequest struct {
@@ -26,17 +28,17 @@ type Reaction struct {
 
 func (c *Client) GetReactions(ctx context.Context, livestreamID int64, streamerName string, opts ...ClientOption) ([]Reaction, error) {
 	var (
-		defaultStatusCode = http.StatusOK
+		defaultStatusCode = ExpectedStatusCode(ActionGetReactions)
 		o                 = newClientOptions(defaultStatusCode, opts...)
 	)
 
 	if err := c.setStreamerURL(streamerName); err != nil {
-		return nil, bencherror.NewInternalError(err)
+		return nil, bencherror.NewInternalError(ctx, err)
 	}
 	urlPath := fmt.Sprintf("/api/livestream/%d/reaction", livestreamID)
 	req, err := c.themeAgent.NewRequest(http.MethodGet, urlPath, nil)
 	if err != nil {
-		return nil, bencherror.NewInternalError(err)
+		return nil, bencherror.NewInternalError(ctx, err)
 	}
 
 	if o.limitParam != nil {
@@ -45,7 +47,7 @@ func (c *Client) GetReactions(ctx context.Context, livestreamID int64, streamerN
 		req.URL.RawQuery = query.Encode()
 	}
 
-	resp, err := sendRequest(ctx, c.themeAgent, req)
+	resp, err := c.sendRequest(ctx, c.themeAgent, req)
 	if err != nil {
 		return nil, err
 	}
@@ -55,16 +57,13 @@ func (c *Client) GetReactions(ctx context.Context, livestreamID int64, streamerN
 	}()
 
 	if resp.StatusCode != o.wantStatusCode {
-		return nil, bencherror.NewHttpStatusError(req, o.wantStatusCode, resp.StatusCode)
+		return nil, bencherror.NewHttpStatusError(ctx, req, o.wantStatusCode, resp.StatusCode)
 	}
 
 	reactions := []Reaction{}
 	if resp.StatusCode == defaultStatusCode {
-		if err := json.NewDecoder(resp.Body).Decode(&reactions); err != nil {
-			return nil, bencherror.NewHttpResponseError(err, req)
-		}
-
-		if err := ValidateSlice(req, reactions); err != nil {
+		reactions, err = DecodeAndValidate[[]Reaction](ctx, req, resp.Body)
+		if err != nil {
 			return nil, err
 		}
 	}
@@ -74,26 +73,28 @@ func (c *Client) GetReactions(ctx context.Context, livestreamID int64, streamerN
 
 func (c *Client) PostReaction(ctx context.Context, livestreamID int64, streamerName string, r *PostReactionRequest, opts ...ClientOption) (*Reaction, error) {
 	var (
-		defaultStatusCode = http.StatusCreated
+		defaultStatusCode = ExpectedStatusCode(ActionPostReaction)
 		o                 = newClientOptions(defaultStatusCode, opts...)
 	)
 
 	payload, err := json.Marshal(r)
 	if err != nil {
-		return nil, bencherror.NewInternalError(err)
+		return nil, bencherror.NewInternalError(ctx, err)
 	}
 
 	if err := c.setStreamerURL(streamerName); err != nil {
-		return nil, bencherror.NewInternalError(err)
+		return nil, bencherror.NewInternalError(ctx, err)
 	}
 	urlPath := fmt.Sprintf("/api/livestream/%d/reaction", livestreamID)
 	req, err := c.themeAgent.NewRequest(http.MethodPost, urlPath, bytes.NewReader(payload))
 	if err != nil {
-		return nil, bencherror.NewInternalError(err)
+		return nil, bencherror.NewInternalError(ctx, err)
 	}
 	req.Header.Add("Content-Type", "application/json;charset=utf-8")
 
-	resp, err := sendRequest(ctx, c.themeAgent, req)
+	scheduler.RankingLedger.RecordScoreEvent(livestreamID)
+	benchscore.RecordActivityAttempt(ctx, benchscore.ActivityReaction, livestreamID, streamerName)
+	resp, err := c.sendRequest(ctx, c.themeAgent, req)
 	if err != nil {
 		return nil, err
 	}
@@ -103,18 +104,17 @@ func (c *Client) PostReaction(ctx context.Context, livestreamID int64, streamerN
 	}()
 
 	if resp.StatusCode != o.wantStatusCode {
-		return nil, bencherror.NewHttpStatusError(req, o.wantStatusCode, resp.StatusCode)
+		return nil, bencherror.NewHttpStatusError(ctx, req, o.wantStatusCode, resp.StatusCode)
 	}
 
 	reaction := &Reaction{}
 	if resp.StatusCode == defaultStatusCode {
-		if err := json.NewDecoder(resp.Body).Decode(&reaction); err != nil {
-			return nil, bencherror.NewHttpResponseError(err, req)
-		}
-
-		if err := ValidateResponse(req, reaction); err != nil {
+		reaction, err = DecodeAndValidate[*Reaction](ctx, req, resp.Body)
+		if err != nil {
 			return nil, err
 		}
+
+		benchscore.ConfirmActivity(ctx, benchscore.ActivityReaction, livestreamID, streamerName)
 	}
 
 	return reaction, nil
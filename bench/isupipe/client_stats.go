@@ -2,7 +2,6 @@ package isupipe
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
@@ -12,35 +11,35 @@ import (
 
 type LivestreamStatistics struct {
 	Rank           int64 `json:"rank" validate:"required"`
-	ViewersCount   int64 `json:"viewers_count"`
-	TotalReactions int64 `json:"total_reactions"`
-	TotalReports   int64 `json:"total_reports"`
-	MaxTip         int64 `json:"max_tip"`
+	ViewersCount   int64 `json:"viewers_count" validate:"gte=0"`
+	TotalReactions int64 `json:"total_reactions" validate:"gte=0"`
+	TotalReports   int64 `json:"total_reports" validate:"gte=0"`
+	MaxTip         int64 `json:"max_tip" validate:"gte=0"`
 }
 
 type UserStatistics struct {
 	Rank              int64 `json:"rank" validate:"required"`
-	ViewersCount      int64 `json:"viewers_count"`
-	TotalReactions    int64 `json:"total_reactions"`
-	TotalLivecomments int64 `json:"total_livecomments"`
-	TotalTip          int64 `json:"total_tip"`
+	ViewersCount      int64 `json:"viewers_count" validate:"gte=0"`
+	TotalReactions    int64 `json:"total_reactions" validate:"gte=0"`
+	TotalLivecomments int64 `json:"total_livecomments" validate:"gte=0"`
+	TotalTip          int64 `json:"total_tip" validate:"gte=0"`
 	// NOTE: リアクション投稿がない場合、空文字になるのでvalidate対象外
 	FavoriteEmoji string `json:"favorite_emoji"`
 }
 
 func (c *Client) GetUserStatistics(ctx context.Context, username string, opts ...ClientOption) (*UserStatistics, error) {
 	var (
-		defaultStatusCode = http.StatusOK
+		defaultStatusCode = ExpectedStatusCode(ActionGetUserStatistics)
 		o                 = newClientOptions(defaultStatusCode, opts...)
 	)
 
 	urlPath := fmt.Sprintf("/api/user/%s/statistics", username)
 	req, err := c.agent.NewRequest(http.MethodGet, urlPath, nil)
 	if err != nil {
-		return nil, bencherror.NewInternalError(err)
+		return nil, bencherror.NewInternalError(ctx, err)
 	}
 
-	resp, err := sendRequest(ctx, c.agent, req)
+	resp, err := c.sendRequest(ctx, c.agent, req)
 	if err != nil {
 		return nil, err
 	}
@@ -50,16 +49,13 @@ func (c *Client) GetUserStatistics(ctx context.Context, username string, opts ..
 	}()
 
 	if resp.StatusCode != o.wantStatusCode {
-		return nil, bencherror.NewHttpStatusError(req, o.wantStatusCode, resp.StatusCode)
+		return nil, bencherror.NewHttpStatusError(ctx, req, o.wantStatusCode, resp.StatusCode)
 	}
 
 	var stats *UserStatistics
 	if resp.StatusCode == defaultStatusCode {
-		if json.NewDecoder(resp.Body).Decode(&stats); err != nil {
-			return nil, err
-		}
-
-		if err := ValidateResponse(req, stats); err != nil {
+		stats, err = DecodeAndValidate[*UserStatistics](ctx, req, resp.Body)
+		if err != nil {
 			return nil, err
 		}
 	}
@@ -69,20 +65,20 @@ func (c *Client) GetUserStatistics(ctx context.Context, username string, opts ..
 
 func (c *Client) GetLivestreamStatistics(ctx context.Context, livestreamID int64, streamerName string, opts ...ClientOption) (*LivestreamStatistics, error) {
 	var (
-		defaultStatusCode = http.StatusOK
+		defaultStatusCode = ExpectedStatusCode(ActionGetLivestreamStatistics)
 		o                 = newClientOptions(defaultStatusCode, opts...)
 	)
 
 	if err := c.setStreamerURL(streamerName); err != nil {
-		return nil, bencherror.NewInternalError(err)
+		return nil, bencherror.NewInternalError(ctx, err)
 	}
 	urlPath := fmt.Sprintf("/api/livestream/%d/statistics", livestreamID)
 	req, err := c.themeAgent.NewRequest(http.MethodGet, urlPath, nil)
 	if err != nil {
-		return nil, bencherror.NewInternalError(err)
+		return nil, bencherror.NewInternalError(ctx, err)
 	}
 
-	resp, err := sendRequest(ctx, c.agent, req)
+	resp, err := c.sendRequest(ctx, c.agent, req)
 	if err != nil {
 		return nil, err
 	}
@@ -92,16 +88,13 @@ func (c *Client) GetLivestreamStatistics(ctx context.Context, livestreamID int64
 	}()
 
 	if resp.StatusCode != o.wantStatusCode {
-		return nil, bencherror.NewHttpStatusError(req, o.wantStatusCode, resp.StatusCode)
+		return nil, bencherror.NewHttpStatusError(ctx, req, o.wantStatusCode, resp.StatusCode)
 	}
 
 	var stats *LivestreamStatistics
 	if resp.StatusCode == defaultStatusCode {
-		if json.NewDecoder(resp.Body).Decode(&stats); err != nil {
-			return nil, err
-		}
-
-		if err := ValidateResponse(req, stats); err != nil {
+		stats, err = DecodeAndValidate[*LivestreamStatistics](ctx, req, resp.Body)
+		if err != nil {
 			return nil, err
 		}
 	}
@@ -17,7 +17,7 @@ func TestGetUserStats(t *testing.T) {
 	testLogger, err := logger.InitTestLogger()
 	assert.NoError(t, err)
 
-	client, err := NewClient(testLogger, agent.WithTimeout(30*time.Second))
+	client, err := NewClient(ctx, testLogger, agent.WithTimeout(30*time.Second))
 	assert.NoError(t, err)
 
 	user, err := client.Register(ctx, &RegisterRequest{
@@ -38,7 +38,7 @@ func TestGetUserStats(t *testing.T) {
 	assert.NoError(t, err)
 
 	// (２つくらい配信作る)
-	streamer1Client, err := NewClient(nil)
+	streamer1Client, err := NewClient(ctx, nil)
 	assert.NoError(t, err)
 	streamer1, err := streamer1Client.Register(ctx, &RegisterRequest{
 		Name:        "get-user-stats-streamer1",
@@ -120,7 +120,7 @@ func TestGetLivestreamStats(t *testing.T) {
 	testLogger, err := logger.InitTestLogger()
 	assert.NoError(t, err)
 
-	client, err := NewClient(testLogger, agent.WithTimeout(20*time.Second))
+	client, err := NewClient(ctx, testLogger, agent.WithTimeout(20*time.Second))
 	assert.NoError(t, err)
 
 	user, err := client.Register(ctx, &RegisterRequest{
@@ -158,7 +158,7 @@ func TestGetLivestreamStats(t *testing.T) {
 	assert.Equal(t, int64(1), stats3.TotalReactions-stats2.TotalReactions)
 
 	// コメント (チップ)
-	commenterClient, err := NewClient(nil)
+	commenterClient, err := NewClient(ctx, nil)
 	assert.NoError(t, err)
 	commenter, _ := commenterClient.Register(ctx, &RegisterRequest{
 		Name:        "get-livestream-stats-commenter",
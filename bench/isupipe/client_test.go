@@ -11,6 +11,7 @@ import (
 
 	"github.com/isucon/isucandar/agent"
 	"github.com/isucon/isucon13/bench/internal/bencherror"
+	"github.com/isucon/isucon13/bench/internal/config"
 	"github.com/isucon/isucon13/bench/internal/logger"
 	"github.com/stretchr/testify/assert"
 )
@@ -28,7 +29,7 @@ func TestClient_Timeout(t *testing.T) {
 	ts := httptest.NewServer(h)
 	defer ts.Close()
 
-	client, err := NewClient(testLogger, agent.WithBaseURL(ts.URL), agent.WithTimeout(1*time.Microsecond))
+	client, err := NewClient(ctx, testLogger, agent.WithBaseURL(ts.URL), agent.WithTimeout(1*time.Microsecond))
 	assert.NoError(t, err)
 
 	// NOTE: 呼び出すエンドポイントは何でも良い
@@ -36,3 +37,75 @@ func TestClient_Timeout(t *testing.T) {
 	_, err = client.GetTags(ctx)
 	assert.True(t, errors.Is(err, bencherror.ErrTimeout))
 }
+
+func TestClient_TimeoutTier(t *testing.T) {
+	ctx := context.Background()
+
+	testLogger, err := logger.InitTestLogger()
+	assert.NoError(t, err)
+
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(500 * time.Millisecond)
+		fmt.Fprintln(w, `{"tags": []}`)
+	})
+	ts := httptest.NewServer(h)
+	defer ts.Close()
+
+	// NOTE: HTTPクライアント全体のタイムアウトより、参照系のタイムアウトが先に切れる
+	config.TimeoutTiersSpec = "read=100ms"
+	assert.NoError(t, config.ApplyTimeoutTiers())
+	defer func() {
+		config.TimeoutTiersSpec = ""
+		config.ApplyTimeoutTiers()
+	}()
+
+	client, err := NewClient(ctx, testLogger, agent.WithBaseURL(ts.URL))
+	assert.NoError(t, err)
+
+	_, err = client.GetTags(ctx)
+	assert.True(t, errors.Is(err, bencherror.ErrTimeout))
+
+	// 明示的にタイムアウトを指定したクライアントには、分類ごとのタイムアウトを適用しない
+	explicitClient, err := NewClient(ctx, testLogger, agent.WithBaseURL(ts.URL), agent.WithTimeout(1*time.Minute))
+	assert.NoError(t, err)
+
+	_, err = explicitClient.GetTags(ctx)
+	assert.NoError(t, err)
+}
+
+func TestClient_UserAgentAndBotMarker(t *testing.T) {
+	ctx := context.Background()
+
+	testLogger, err := logger.InitTestLogger()
+	assert.NoError(t, err)
+
+	var (
+		userAgent string
+		marker    string
+	)
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userAgent = r.Header.Get("User-Agent")
+		marker = r.Header.Get(config.BotMarkerHeader)
+		fmt.Fprintln(w, `{"tags": []}`)
+	})
+	ts := httptest.NewServer(h)
+	defer ts.Close()
+
+	client, err := NewClient(ctx, testLogger, agent.WithBaseURL(ts.URL))
+	assert.NoError(t, err)
+	// NOTE: レスポンスの検証結果によらず、リクエストのヘッダを確認する
+	client.GetTags(ctx)
+	assert.Contains(t, config.UserAgents, userAgent)
+	assert.Equal(t, config.BotMarkerValue, marker)
+
+	// NOTE: ステルス走行では、ベンチマーカーからのリクエストであることを示すヘッダを付与しない
+	config.BotMarkerEnabled = false
+	defer func() { config.BotMarkerEnabled = config.DefaultBotMarkerEnabled }()
+
+	client, err = NewClient(ctx, testLogger, agent.WithBaseURL(ts.URL))
+	assert.NoError(t, err)
+	// NOTE: レスポンスの検証結果によらず、リクエストのヘッダを確認する
+	client.GetTags(ctx)
+	assert.Contains(t, config.UserAgents, userAgent)
+	assert.Empty(t, marker)
+}
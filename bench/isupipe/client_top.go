@@ -2,13 +2,12 @@ package isupipe
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"io"
-	"math/rand"
 	"net/http"
 
 	"github.com/isucon/isucon13/bench/internal/bencherror"
+	"github.com/isucon/isucon13/bench/internal/benchrand"
 )
 
 type Tag struct {
@@ -22,20 +21,20 @@ type TagsResponse struct {
 
 func (c *Client) GetTagsWithUser(ctx context.Context, streamerName string, opts ...ClientOption) (*TagsResponse, error) {
 	var (
-		defaultStatusCode = http.StatusOK
+		defaultStatusCode = ExpectedStatusCode(ActionGetTags)
 		o                 = newClientOptions(defaultStatusCode, opts...)
 	)
 
 	if err := c.setStreamerURL(streamerName); err != nil {
-		return nil, bencherror.NewInternalError(err)
+		return nil, bencherror.NewInternalError(ctx, err)
 	}
 
 	req, err := c.agent.NewRequest(http.MethodGet, "/api/tag", nil)
 	if err != nil {
-		return nil, bencherror.NewInternalError(err)
+		return nil, bencherror.NewInternalError(ctx, err)
 	}
 
-	resp, err := sendRequest(ctx, c.agent, req)
+	resp, err := c.sendRequest(ctx, c.agent, req)
 	if err != nil {
 		return nil, err
 	}
@@ -45,16 +44,13 @@ func (c *Client) GetTagsWithUser(ctx context.Context, streamerName string, opts
 	}()
 
 	if resp.StatusCode != o.wantStatusCode {
-		return nil, bencherror.NewHttpStatusError(req, o.wantStatusCode, resp.StatusCode)
+		return nil, bencherror.NewHttpStatusError(ctx, req, o.wantStatusCode, resp.StatusCode)
 	}
 
 	var tags *TagsResponse
 	if resp.StatusCode == defaultStatusCode {
-		if err := json.NewDecoder(resp.Body).Decode(&tags); err != nil {
-			return nil, err
-		}
-
-		if err := ValidateResponse(req, tags); err != nil {
+		tags, err = DecodeAndValidate[*TagsResponse](ctx, req, resp.Body)
+		if err != nil {
 			return nil, err
 		}
 	}
@@ -64,16 +60,16 @@ func (c *Client) GetTagsWithUser(ctx context.Context, streamerName string, opts
 
 func (c *Client) GetTags(ctx context.Context, opts ...ClientOption) (*TagsResponse, error) {
 	var (
-		defaultStatusCode = http.StatusOK
+		defaultStatusCode = ExpectedStatusCode(ActionGetTags)
 		o                 = newClientOptions(defaultStatusCode, opts...)
 	)
 
 	req, err := c.agent.NewRequest(http.MethodGet, "/api/tag", nil)
 	if err != nil {
-		return nil, bencherror.NewInternalError(err)
+		return nil, bencherror.NewInternalError(ctx, err)
 	}
 
-	resp, err := sendRequest(ctx, c.agent, req)
+	resp, err := c.sendRequest(ctx, c.agent, req)
 	if err != nil {
 		return nil, err
 	}
@@ -83,16 +79,13 @@ func (c *Client) GetTags(ctx context.Context, opts ...ClientOption) (*TagsRespon
 	}()
 
 	if resp.StatusCode != o.wantStatusCode {
-		return nil, bencherror.NewHttpStatusError(req, o.wantStatusCode, resp.StatusCode)
+		return nil, bencherror.NewHttpStatusError(ctx, req, o.wantStatusCode, resp.StatusCode)
 	}
 
 	var tags *TagsResponse
 	if resp.StatusCode == defaultStatusCode {
-		if err := json.NewDecoder(resp.Body).Decode(&tags); err != nil {
-			return nil, err
-		}
-
-		if err := ValidateResponse(req, tags); err != nil {
+		tags, err = DecodeAndValidate[*TagsResponse](ctx, req, resp.Body)
+		if err != nil {
 			return nil, err
 		}
 	}
@@ -105,7 +98,7 @@ func (c *Client) getRandomTags(ctx context.Context, n int) ([]*Tag, error) {
 	if err != nil {
 		return nil, err
 	}
-	rand.Shuffle(len(resp.Tags), func(i, j int) {
+	benchrand.Shuffle(len(resp.Tags), func(i, j int) {
 		resp.Tags[i], resp.Tags[j] = resp.Tags[j], resp.Tags[i]
 	})
 	if len(resp.Tags) < n {
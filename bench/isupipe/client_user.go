@@ -7,9 +7,11 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"time"
 
 	"github.com/isucon/isucandar/agent"
 	"github.com/isucon/isucon13/bench/internal/bencherror"
+	"github.com/isucon/isucon13/bench/internal/redact"
 )
 
 type User struct {
@@ -52,16 +54,16 @@ type PostIconResponse struct {
 
 func (c *Client) GetStreamerTheme(ctx context.Context, streamer *User, opts ...ClientOption) (*Theme, error) {
 	var (
-		defaultStatusCode = http.StatusOK
+		defaultStatusCode = ExpectedStatusCode(ActionGetStreamerTheme)
 		o                 = newClientOptions(defaultStatusCode, opts...)
 	)
 
 	endpoint := fmt.Sprintf("/api/user/%s/theme", streamer.Name)
 	req, err := c.agent.NewRequest(http.MethodGet, endpoint, nil)
 	if err != nil {
-		return nil, bencherror.NewInternalError(err)
+		return nil, bencherror.NewInternalError(ctx, err)
 	}
-	resp, err := sendRequest(ctx, c.agent, req)
+	resp, err := c.sendRequest(ctx, c.agent, req)
 	if err != nil {
 		return nil, err
 	}
@@ -71,12 +73,13 @@ func (c *Client) GetStreamerTheme(ctx context.Context, streamer *User, opts ...C
 	}()
 
 	if resp.StatusCode != o.wantStatusCode {
-		return nil, bencherror.NewHttpStatusError(req, o.wantStatusCode, resp.StatusCode)
+		return nil, bencherror.NewHttpStatusError(ctx, req, o.wantStatusCode, resp.StatusCode)
 	}
 
 	var theme *Theme
 	if resp.StatusCode == defaultStatusCode {
-		if err := json.NewDecoder(resp.Body).Decode(&theme); err != nil {
+		theme, err = DecodeAndValidate[*Theme](ctx, req, resp.Body)
+		if err != nil {
 			return nil, err
 		}
 	}
@@ -84,22 +87,38 @@ func (c *Client) GetStreamerTheme(ctx context.Context, streamer *User, opts ...C
 	return theme, nil
 }
 
+// IconContent は、アイコン取得のレスポンスです
+type IconContent struct {
+	// 304の場合は空
+	Image       []byte
+	ContentType string
+}
+
 func (c *Client) GetIcon(ctx context.Context, username string, opts ...ClientOption) ([]byte, error) {
+	icon, err := c.GetIconContent(ctx, username, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return icon.Image, nil
+}
+
+// GetIconContent は、アイコンの画像とContent-Typeを取得します
+func (c *Client) GetIconContent(ctx context.Context, username string, opts ...ClientOption) (*IconContent, error) {
 	var (
-		defaultStatusCode = http.StatusOK
+		defaultStatusCode = ExpectedStatusCode(ActionGetIcon)
 		o                 = newClientOptions(defaultStatusCode, opts...)
 	)
 
 	endpoint := fmt.Sprintf("/api/user/%s/icon", username)
 	req, err := c.assetAgent.NewRequest(http.MethodGet, endpoint, nil)
 	if err != nil {
-		return nil, bencherror.NewInternalError(err)
+		return nil, bencherror.NewInternalError(ctx, err)
 	}
 	if o.eTag != "" {
 		req.Header.Set("If-None-Match", `"`+o.eTag+`"`)
 	}
 
-	resp, err := sendRequest(ctx, c.assetAgent, req)
+	resp, err := c.sendRequest(ctx, c.assetAgent, req)
 	if err != nil {
 		return nil, err
 	}
@@ -108,51 +127,53 @@ func (c *Client) GetIcon(ctx context.Context, username string, opts ...ClientOpt
 		resp.Body.Close()
 	}()
 
-	if resp.StatusCode != http.StatusNotModified && resp.StatusCode != o.wantStatusCode {
-		return nil, bencherror.NewHttpStatusError(req, o.wantStatusCode, resp.StatusCode)
+	if resp.StatusCode != ExpectedStatusCode(ActionGetIconNotModified) && resp.StatusCode != o.wantStatusCode {
+		return nil, bencherror.NewHttpStatusError(ctx, req, o.wantStatusCode, resp.StatusCode)
 	}
 
-	var imageBytes []byte
+	icon := &IconContent{
+		ContentType: resp.Header.Get("Content-Type"),
+	}
 	switch resp.StatusCode {
-	case http.StatusNotModified:
+	case ExpectedStatusCode(ActionGetIconNotModified):
 		if o.eTag == "" {
-			return nil, bencherror.NewInternalError(fmt.Errorf("If-None-Matchを指定していないのに304が返却されました"))
+			return nil, bencherror.NewInternalError(ctx, fmt.Errorf("If-None-Matchを指定していないのに304が返却されました"))
 		}
 	case defaultStatusCode:
-		imageBytes, err = io.ReadAll(resp.Body)
+		icon.Image, err = io.ReadAll(resp.Body)
 		if err != nil {
-			return nil, bencherror.NewHttpResponseError(err, req)
+			return nil, bencherror.NewHttpResponseError(ctx, err, req)
 		}
 	}
 
-	return imageBytes, nil
+	return icon, nil
 }
 
 func (c *Client) GetMyIcon(ctx context.Context, opts ...ClientOption) ([]byte, error) {
 	if c.username == "" {
-		return nil, bencherror.NewInternalError(fmt.Errorf("未ログインクライアントで画像取得を試みました"))
+		return nil, bencherror.NewInternalError(ctx, fmt.Errorf("未ログインクライアントで画像取得を試みました"))
 	}
 	return c.GetIcon(ctx, c.username)
 }
 
 func (c *Client) PostIcon(ctx context.Context, r *PostIconRequest, opts ...ClientOption) (*PostIconResponse, error) {
 	var (
-		defaultStatusCode = http.StatusCreated
+		defaultStatusCode = ExpectedStatusCode(ActionPostIcon)
 		o                 = newClientOptions(defaultStatusCode, opts...)
 	)
 
 	payload, err := json.Marshal(r)
 	if err != nil {
-		return nil, bencherror.NewInternalError(err)
+		return nil, bencherror.NewInternalError(ctx, err)
 	}
 
 	endpoint := "/api/icon"
 	req, err := c.agent.NewRequest(http.MethodPost, endpoint, bytes.NewReader(payload))
 	if err != nil {
-		return nil, bencherror.NewInternalError(err)
+		return nil, bencherror.NewInternalError(ctx, err)
 	}
 	req.Header.Add("Content-Type", "application/json;charset=utf-8")
-	resp, err := sendRequest(ctx, c.agent, req)
+	resp, err := c.sendRequest(ctx, c.agent, req)
 	if err != nil {
 		return nil, err
 	}
@@ -162,16 +183,13 @@ func (c *Client) PostIcon(ctx context.Context, r *PostIconRequest, opts ...Clien
 	}()
 
 	if resp.StatusCode != o.wantStatusCode {
-		return nil, bencherror.NewHttpStatusError(req, o.wantStatusCode, resp.StatusCode)
+		return nil, bencherror.NewHttpStatusError(ctx, req, o.wantStatusCode, resp.StatusCode)
 	}
 
 	var iconResp *PostIconResponse
 	if resp.StatusCode == defaultStatusCode {
-		if err := json.NewDecoder(resp.Body).Decode(&iconResp); err != nil {
-			return nil, bencherror.NewHttpResponseError(err, req)
-		}
-
-		if err := ValidateResponse(req, iconResp); err != nil {
+		iconResp, err = DecodeAndValidate[*PostIconResponse](ctx, req, resp.Body)
+		if err != nil {
 			return nil, err
 		}
 	}
@@ -181,17 +199,17 @@ func (c *Client) PostIcon(ctx context.Context, r *PostIconRequest, opts ...Clien
 
 func (c *Client) GetUser(ctx context.Context, username string, opts ...ClientOption) (*User, error) {
 	var (
-		defaultStatusCode = http.StatusOK
+		defaultStatusCode = ExpectedStatusCode(ActionGetUser)
 		o                 = newClientOptions(defaultStatusCode, opts...)
 	)
 
 	urlPath := fmt.Sprintf("/api/user/%s", username)
 	req, err := c.agent.NewRequest(http.MethodGet, urlPath, nil)
 	if err != nil {
-		return nil, bencherror.NewInternalError(err)
+		return nil, bencherror.NewInternalError(ctx, err)
 	}
 
-	resp, err := sendRequest(ctx, c.agent, req)
+	resp, err := c.sendRequest(ctx, c.agent, req)
 	if err != nil {
 		return nil, err
 	}
@@ -201,16 +219,13 @@ func (c *Client) GetUser(ctx context.Context, username string, opts ...ClientOpt
 	}()
 
 	if resp.StatusCode != o.wantStatusCode {
-		return nil, bencherror.NewHttpStatusError(req, o.wantStatusCode, resp.StatusCode)
+		return nil, bencherror.NewHttpStatusError(ctx, req, o.wantStatusCode, resp.StatusCode)
 	}
 
 	var user *User
 	if resp.StatusCode == defaultStatusCode {
-		if err := json.NewDecoder(resp.Body).Decode(&user); err != nil {
-			return nil, bencherror.NewHttpResponseError(err, req)
-		}
-
-		if err := ValidateResponse(req, user); err != nil {
+		user, err = DecodeAndValidate[*User](ctx, req, resp.Body)
+		if err != nil {
 			return nil, err
 		}
 	}
@@ -220,16 +235,16 @@ func (c *Client) GetUser(ctx context.Context, username string, opts ...ClientOpt
 
 func (c *Client) GetMe(ctx context.Context, opts ...ClientOption) (*User, error) {
 	var (
-		defaultStatusCode = http.StatusOK
+		defaultStatusCode = ExpectedStatusCode(ActionGetMe)
 		o                 = newClientOptions(defaultStatusCode, opts...)
 	)
 
 	req, err := c.agent.NewRequest(http.MethodGet, "/api/user/me", nil)
 	if err != nil {
-		return nil, bencherror.NewInternalError(err)
+		return nil, bencherror.NewInternalError(ctx, err)
 	}
 
-	resp, err := sendRequest(ctx, c.agent, req)
+	resp, err := c.sendRequest(ctx, c.agent, req)
 	if err != nil {
 		return nil, err
 	}
@@ -239,16 +254,13 @@ func (c *Client) GetMe(ctx context.Context, opts ...ClientOption) (*User, error)
 	}()
 
 	if resp.StatusCode != o.wantStatusCode {
-		return nil, bencherror.NewHttpStatusError(req, o.wantStatusCode, resp.StatusCode)
+		return nil, bencherror.NewHttpStatusError(ctx, req, o.wantStatusCode, resp.StatusCode)
 	}
 
 	var user *User
 	if resp.StatusCode == defaultStatusCode {
-		if err := json.NewDecoder(resp.Body).Decode(&user); err != nil {
-			return nil, bencherror.NewHttpResponseError(err, req)
-		}
-
-		if err := ValidateResponse(req, user); err != nil {
+		user, err = DecodeAndValidate[*User](ctx, req, resp.Body)
+		if err != nil {
 			return nil, err
 		}
 	}
@@ -258,22 +270,24 @@ func (c *Client) GetMe(ctx context.Context, opts ...ClientOption) (*User, error)
 
 func (c *Client) Register(ctx context.Context, r *RegisterRequest, opts ...ClientOption) (*User, error) {
 	var (
-		defaultStatusCode = http.StatusCreated
+		defaultStatusCode = ExpectedStatusCode(ActionRegister)
 		o                 = newClientOptions(defaultStatusCode, opts...)
 	)
 
+	redact.Register(r.Password)
+
 	payload, err := json.Marshal(r)
 	if err != nil {
-		return nil, bencherror.NewInternalError(err)
+		return nil, bencherror.NewInternalError(ctx, err)
 	}
 
 	req, err := c.agent.NewRequest(http.MethodPost, "/api/register", bytes.NewReader(payload))
 	if err != nil {
-		return nil, bencherror.NewInternalError(err)
+		return nil, bencherror.NewInternalError(ctx, err)
 	}
 	req.Header.Add("Content-Type", "application/json;charset=utf-8")
 
-	resp, err := sendRequest(ctx, c.agent, req)
+	resp, err := c.sendRequest(ctx, c.agent, req)
 	if err != nil {
 		// sendRequestはWrapErrorを行っているのでそのままreturn
 		return nil, err
@@ -284,16 +298,13 @@ func (c *Client) Register(ctx context.Context, r *RegisterRequest, opts ...Clien
 	}()
 
 	if resp.StatusCode != o.wantStatusCode {
-		return nil, bencherror.NewHttpStatusError(req, o.wantStatusCode, resp.StatusCode)
+		return nil, bencherror.NewHttpStatusError(ctx, req, o.wantStatusCode, resp.StatusCode)
 	}
 
 	var user *User
 	if resp.StatusCode == defaultStatusCode {
-		if err := json.NewDecoder(resp.Body).Decode(&user); err != nil {
-			return nil, bencherror.NewHttpResponseError(err, req)
-		}
-
-		if err := ValidateResponse(req, user); err != nil {
+		user, err = DecodeAndValidate[*User](ctx, req, resp.Body)
+		if err != nil {
 			return nil, err
 		}
 	}
@@ -305,26 +316,28 @@ func (c *Client) Register(ctx context.Context, r *RegisterRequest, opts ...Clien
 // NOTE: ログイン後はログインユーザとして振る舞うので、各種agentやユーザ名、人気ユーザであるかの判定フラグなどの情報もここで確定する
 func (c *Client) Login(ctx context.Context, r *LoginRequest, opts ...ClientOption) error {
 	var (
-		defaultStatusCode = http.StatusOK
+		defaultStatusCode = ExpectedStatusCode(ActionLogin)
 		o                 = newClientOptions(defaultStatusCode, opts...)
 	)
 
 	if len(c.username) != 0 {
-		return bencherror.NewInternalError(fmt.Errorf("同一クライアントに対して複数回ログインが試行されました"))
+		return bencherror.NewInternalError(ctx, fmt.Errorf("同一クライアントに対して複数回ログインが試行されました"))
 	}
 
+	redact.Register(r.Password)
+
 	payload, err := json.Marshal(r)
 	if err != nil {
-		return bencherror.NewInternalError(err)
+		return bencherror.NewInternalError(ctx, err)
 	}
 
 	req, err := c.agent.NewRequest(http.MethodPost, "/api/login", bytes.NewReader(payload))
 	if err != nil {
-		return bencherror.NewInternalError(err)
+		return bencherror.NewInternalError(ctx, err)
 	}
 	req.Header.Add("Content-Type", "application/json;charset=utf-8")
 
-	resp, err := sendRequest(ctx, c.agent, req)
+	resp, err := c.sendRequest(ctx, c.agent, req)
 	if err != nil {
 		return err
 	}
@@ -334,7 +347,14 @@ func (c *Client) Login(ctx context.Context, r *LoginRequest, opts ...ClientOptio
 	}()
 
 	if resp.StatusCode != o.wantStatusCode {
-		return bencherror.NewHttpStatusError(req, o.wantStatusCode, resp.StatusCode)
+		return bencherror.NewHttpStatusError(ctx, req, o.wantStatusCode, resp.StatusCode)
+	}
+
+	// NOTE: セッションを乗っ取れる値だが、ログインのたびに登録すると伏せる値が際限なく増えるため、redact の書式による検出で伏せる
+	c.loginCookies = resp.Cookies()
+	c.loginIssuedAt = time.Now()
+	if date, err := http.ParseTime(resp.Header.Get("Date")); err == nil {
+		c.loginIssuedAt = date
 	}
 
 	c.username = r.Username
@@ -343,12 +363,12 @@ func (c *Client) Login(ctx context.Context, r *LoginRequest, opts ...ClientOptio
 	c.themeOptions = append(c.themeOptions)
 	c.themeAgent, err = agent.NewAgent(c.themeOptions...)
 	if err != nil {
-		return bencherror.NewInternalError(err)
+		return bencherror.NewInternalError(ctx, err)
 	}
 
 	c.assetAgent, err = agent.NewAgent(c.assetOptions...)
 	if err != nil {
-		return bencherror.NewInternalError(err)
+		return bencherror.NewInternalError(ctx, err)
 	}
 
 	return nil
@@ -22,6 +22,7 @@ func TestClientUser_Login(t *testing.T) {
 	testLogger, err := logger.InitTestLogger()
 
 	client, err := NewClient(
+		ctx,
 		testLogger,
 		agent.WithBaseURL(config.TargetBaseURL),
 		agent.WithTimeout(1*time.Minute),
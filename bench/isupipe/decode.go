@@ -0,0 +1,375 @@
+package isupipe
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/isucon/isucon13/bench/internal/bencherror"
+)
+
+// requiredFields は、レスポンスの型ごとの、API仕様で必須のフィールド(JSONのキー)です
+// NOTE: 参照実装によって省略されうるフィールド(ライブコメントのtipなど)は含めない. 省略された場合はゼロ値として扱う
+// 含まれない型のフィールドは、すべて省略を許容する
+var requiredFields = map[reflect.Type][]string{
+	reflect.TypeOf(InitializeResponse{}):      {"language"},
+	reflect.TypeOf(InitializeDataset{}):       {"scale", "features", "users", "livestreams"},
+	reflect.TypeOf(User{}):                    {"id", "name", "display_name", "description", "theme", "icon_hash"},
+	reflect.TypeOf(Theme{}):                   {"dark_mode"},
+	reflect.TypeOf(PostIconResponse{}):        {"id"},
+	reflect.TypeOf(Tag{}):                     {"id", "name"},
+	reflect.TypeOf(TagsResponse{}):            {"tags"},
+	reflect.TypeOf(Livestream{}):              {"id", "owner", "tags", "title", "description", "playlist_url", "thumbnail_url", "start_at", "end_at"},
+	reflect.TypeOf(Livecomment{}):             {"id", "user", "livestream", "comment", "created_at"},
+	reflect.TypeOf(PostLivecommentResponse{}): {"id", "user", "livestream", "comment", "created_at"},
+	reflect.TypeOf(LivecommentReport{}):       {"id", "reporter", "livecomment", "created_at"},
+	reflect.TypeOf(ModerateResponse{}):        {"word_id"},
+	reflect.TypeOf(NGWord{}):                  {"id", "user_id", "livestream_id", "word", "created_at"},
+	reflect.TypeOf(Reaction{}):                {"id", "emoji_name", "user", "livestream", "created_at"},
+	reflect.TypeOf(LivestreamStatistics{}):    {"rank", "viewers_count", "total_reactions", "total_reports", "max_tip"},
+	reflect.TypeOf(UserStatistics{}):          {"rank", "viewers_count", "total_reactions", "total_livecomments", "total_tip", "favorite_emoji"},
+	reflect.TypeOf(PaymentResult{}):           {"total_tip"},
+}
+
+// DecodeAndValidate は、レスポンスボディをTとしてデコードし、API仕様に沿っているか検証します
+// 必須フィールドの欠落、JSONの型不一致、validateタグによる値域をまとめて検証し、
+// "livecomment.tip は0以上の整数でなければなりません" のように問題箇所を特定したエラーを返します
+// NOTE: 必須フィールドは requiredFields に従う
+func DecodeAndValidate[T any](ctx context.Context, req *http.Request, body io.Reader) (T, error) {
+	var v T
+
+	buf, err := readBody(ctx, req, body)
+	if err != nil {
+		return v, err
+	}
+	// NOTE: デコードした値はバッファを参照しないため、デコードを終えたらプールに戻してよい
+	defer releaseBuffer(buf)
+
+	typ := reflect.TypeOf(&v).Elem()
+	root := responseRootName(typ)
+
+	// NOTE: キーの有無を検証するため汎用の値として1度だけデコードし、検証しながらTに詰め替える
+	var raw interface{}
+	if err := json.Unmarshal(buf.Bytes(), &raw); err != nil {
+		return v, bencherror.NewHttpResponseError(ctx, err, req)
+	}
+
+	var messages []string
+	if raw == nil {
+		if indirectType(typ).Kind() == reflect.Struct {
+			messages = append(messages, fmt.Sprintf("%s はnullであってはなりません", root))
+		}
+	} else {
+		messages = assignValue(reflect.ValueOf(&v).Elem(), raw, root, messages)
+	}
+	if len(messages) > 0 {
+		return v, bencherror.NewInvalidHttpResponseError(ctx, messages, req)
+	}
+
+	messages, err = validateFieldValues(v, root)
+	if err != nil {
+		return v, bencherror.NewInternalError(ctx, err)
+	}
+	if len(messages) > 0 {
+		return v, bencherror.NewInvalidHttpResponseError(ctx, messages, req)
+	}
+
+	return v, nil
+}
+
+// assignValue は、デコードしたJSONの値を rv に詰め替えながら、必須フィールドの欠落・nullと型の不一致を再帰的に検証します
+func assignValue(rv reflect.Value, raw interface{}, path string, messages []string) []string {
+	switch rv.Kind() {
+	case reflect.Pointer:
+		if rv.IsNil() {
+			rv.Set(reflect.New(rv.Type().Elem()))
+		}
+		return assignValue(rv.Elem(), raw, path, messages)
+	case reflect.Interface:
+		rv.Set(reflect.ValueOf(raw))
+		return messages
+	case reflect.Struct:
+		obj, ok := raw.(map[string]interface{})
+		if !ok {
+			return append(messages, typeMismatchMessage(path, rv.Type(), raw))
+		}
+		return assignStruct(rv, obj, path, messages)
+	case reflect.Slice:
+		// NOTE: []byteはbase64文字列としてエンコードされる
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			str, ok := raw.(string)
+			if !ok {
+				return append(messages, typeMismatchMessage(path, rv.Type(), raw))
+			}
+			b, err := base64.StdEncoding.DecodeString(str)
+			if err != nil {
+				return append(messages, typeMismatchMessage(path, rv.Type(), raw))
+			}
+			rv.SetBytes(b)
+			return messages
+		}
+		arr, ok := raw.([]interface{})
+		if !ok {
+			return append(messages, typeMismatchMessage(path, rv.Type(), raw))
+		}
+		rv.Set(reflect.MakeSlice(rv.Type(), len(arr), len(arr)))
+		for i, elem := range arr {
+			elemPath := fmt.Sprintf("%s[%d]", path, i)
+			if elem == nil {
+				if !isNullable(rv.Type().Elem()) {
+					messages = append(messages, fmt.Sprintf("%s はnullであってはなりません", elemPath))
+				}
+				continue
+			}
+			messages = assignValue(rv.Index(i), elem, elemPath, messages)
+		}
+		return messages
+	case reflect.String:
+		str, ok := raw.(string)
+		if !ok {
+			return append(messages, typeMismatchMessage(path, rv.Type(), raw))
+		}
+		rv.SetString(str)
+		return messages
+	case reflect.Bool:
+		b, ok := raw.(bool)
+		if !ok {
+			return append(messages, typeMismatchMessage(path, rv.Type(), raw))
+		}
+		rv.SetBool(b)
+		return messages
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		// NOTE: 数値はfloat64としてデコードされる. IDなどの値域(2^53未満)では整数を正確に表せる
+		f, ok := raw.(float64)
+		if !ok || f != math.Trunc(f) || rv.OverflowInt(int64(f)) {
+			return append(messages, typeMismatchMessage(path, rv.Type(), raw))
+		}
+		rv.SetInt(int64(f))
+		return messages
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		f, ok := raw.(float64)
+		if !ok || f != math.Trunc(f) || f < 0 || rv.OverflowUint(uint64(f)) {
+			return append(messages, typeMismatchMessage(path, rv.Type(), raw))
+		}
+		rv.SetUint(uint64(f))
+		return messages
+	case reflect.Float32, reflect.Float64:
+		f, ok := raw.(float64)
+		if !ok || rv.OverflowFloat(f) {
+			return append(messages, typeMismatchMessage(path, rv.Type(), raw))
+		}
+		rv.SetFloat(f)
+		return messages
+	}
+	return messages
+}
+
+// assignStruct は、JSONオブジェクトをstructに詰め替えます. requiredFields に含まれるフィールドの欠落とnullを検証します
+func assignStruct(rv reflect.Value, obj map[string]interface{}, path string, messages []string) []string {
+	typ := rv.Type()
+	required := requiredFields[typ]
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name := jsonFieldName(field)
+		if name == "-" {
+			continue
+		}
+		if field.Anonymous && name == "" && indirectType(field.Type).Kind() == reflect.Struct {
+			// 埋め込みstructのフィールドは親のオブジェクトに展開される
+			messages = assignValue(rv.Field(i), obj, path, messages)
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		fieldPath := path + "." + name
+		value, ok := obj[name]
+		if !ok {
+			if slices.Contains(required, name) {
+				messages = append(messages, fmt.Sprintf("%s は必須です", fieldPath))
+			}
+			continue
+		}
+		if value == nil {
+			if slices.Contains(required, name) && !isNullable(field.Type) {
+				messages = append(messages, fmt.Sprintf("%s はnullであってはなりません", fieldPath))
+			}
+			continue
+		}
+		messages = assignValue(rv.Field(i), value, fieldPath, messages)
+	}
+	return messages
+}
+
+// validateFieldValues は、validateタグに基づいてフィールドの値域を検証します
+func validateFieldValues(v interface{}, root string) ([]string, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return nil, nil
+		}
+		rv = rv.Elem()
+	}
+
+	var err error
+	switch rv.Kind() {
+	case reflect.Struct:
+		err = validate.Struct(rv.Interface())
+	case reflect.Slice:
+		err = validate.Var(rv.Interface(), "dive,required")
+	default:
+		return nil, nil
+	}
+	if err == nil {
+		return nil, nil
+	}
+
+	var validationErrs validator.ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		return nil, err
+	}
+
+	var messages []string
+	for _, fieldErr := range validationErrs {
+		messages = append(messages, validationMessage(root, rv.Kind(), fieldErr))
+	}
+	return messages, nil
+}
+
+func validationMessage(root string, kind reflect.Kind, fieldErr validator.FieldError) string {
+	// NOTE: Namespaceの先頭は型名(structの場合)か空(sliceの場合)なので、ルート名に置き換える
+	namespace := fieldErr.Namespace()
+	if kind == reflect.Struct {
+		if idx := strings.Index(namespace, "."); idx >= 0 {
+			namespace = namespace[idx:]
+		} else {
+			namespace = ""
+		}
+	} else if idx := strings.Index(namespace, "["); idx >= 0 {
+		namespace = namespace[idx:]
+	}
+	path := root + namespace
+
+	switch fieldErr.Tag() {
+	case "required":
+		return fmt.Sprintf("%s は必須です", path)
+	case "gte":
+		if isIntegerKind(fieldErr.Kind()) {
+			if fieldErr.Param() == "0" {
+				return fmt.Sprintf("%s は0以上の整数でなければなりません", path)
+			}
+			return fmt.Sprintf("%s は%s以上の整数でなければなりません", path, fieldErr.Param())
+		}
+		return fmt.Sprintf("%s は%s以上でなければなりません", path, fieldErr.Param())
+	default:
+		return fmt.Sprintf("%s が制約(%s)を満たしていません", path, fieldErr.Tag())
+	}
+}
+
+func typeMismatchMessage(path string, typ reflect.Type, raw interface{}) string {
+	return fmt.Sprintf("%s は%sでなければなりません (actual:%s)", path, jsonTypeName(typ), rawTypeName(raw))
+}
+
+// rawTypeName は、デコードしたJSONの値の型名を返します
+func rawTypeName(raw interface{}) string {
+	switch raw.(type) {
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "bool"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	default:
+		return "null"
+	}
+}
+
+func jsonTypeName(typ reflect.Type) string {
+	typ = indirectType(typ)
+	switch {
+	case isIntegerKind(typ.Kind()):
+		switch typ.Kind() {
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			return "非負の整数"
+		}
+		return "整数"
+	case typ.Kind() == reflect.Float32, typ.Kind() == reflect.Float64:
+		return "数値"
+	case typ.Kind() == reflect.String:
+		return "文字列"
+	case typ.Kind() == reflect.Bool:
+		return "真偽値"
+	case typ.Kind() == reflect.Slice, typ.Kind() == reflect.Array:
+		return "配列"
+	default:
+		return "オブジェクト"
+	}
+}
+
+func isIntegerKind(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
+
+func isNullable(typ reflect.Type) bool {
+	switch typ.Kind() {
+	case reflect.Pointer, reflect.Slice, reflect.Map, reflect.Interface:
+		return true
+	}
+	return false
+}
+
+func indirectType(typ reflect.Type) reflect.Type {
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	return typ
+}
+
+// jsonFieldName は、jsonタグからフィールド名を返します
+func jsonFieldName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	return name
+}
+
+// responseRootName は、エラーメッセージで使うレスポンスの名前を型名から決めます
+// (e.g. Livecomment -> livecomment, []*NGWord -> ngWords)
+func responseRootName(typ reflect.Type) string {
+	typ = indirectType(typ)
+	if typ.Kind() == reflect.Slice || typ.Kind() == reflect.Array {
+		return responseRootName(typ.Elem()) + "s"
+	}
+
+	runes := []rune(typ.Name())
+	for i := 0; i < len(runes) && unicode.IsUpper(runes[i]); i++ {
+		// 先頭の頭字語は、後続が小文字の場合その直前の文字まで小文字にする (NGWord -> ngWord)
+		if i > 0 && i+1 < len(runes) && unicode.IsLower(runes[i+1]) {
+			break
+		}
+		runes[i] = unicode.ToLower(runes[i])
+	}
+	return string(runes)
+}
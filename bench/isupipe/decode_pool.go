@@ -0,0 +1,64 @@
+package isupipe
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/isucon/isucon13/bench/internal/bencherror"
+)
+
+// 再利用するバッファの容量の上限[byte]
+// NOTE: 大きなレスポンスを読んだバッファをプールに戻すと、以降ずっとメモリを専有し続けるため破棄する
+const maxPooledBufferSize = 64 * 1024
+
+// decodeBufferPool は、レスポンスボディを読み込むバッファのプールです
+// NOTE: 高負荷時にレスポンスごとにバッファを確保すると、ベンチマーカー側のGCが律速になるため再利用する
+var decodeBufferPool = sync.Pool{
+	New: func() any {
+		return bytes.NewBuffer(make([]byte, 0, 4096))
+	},
+}
+
+// readBody は、ボディをプールから取得したバッファに読み込みます
+// 読み込んだ内容を使い終えたら releaseBuffer でプールに戻します
+func readBody(ctx context.Context, req *http.Request, body io.Reader) (*bytes.Buffer, error) {
+	buf := decodeBufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	if _, err := buf.ReadFrom(body); err != nil {
+		releaseBuffer(buf)
+		if errors.Is(err, bencherror.ErrContentLengthMismatch) {
+			// NOTE: 読み込み時に記録済み
+			return nil, err
+		}
+		return nil, bencherror.NewHttpResponseError(ctx, err, req)
+	}
+	return buf, nil
+}
+
+// releaseBuffer は、readBody で取得したバッファをプールに戻します
+// NOTE: 戻した後はバッファの内容を参照してはならない
+func releaseBuffer(buf *bytes.Buffer) {
+	if buf.Cap() > maxPooledBufferSize {
+		return
+	}
+	decodeBufferPool.Put(buf)
+}
+
+// decodeJSON は、スキーマを検証せずにボディをvにデコードします
+func decodeJSON(ctx context.Context, req *http.Request, body io.Reader, v any) error {
+	buf, err := readBody(ctx, req, body)
+	if err != nil {
+		return err
+	}
+	defer releaseBuffer(buf)
+
+	if err := json.Unmarshal(buf.Bytes(), v); err != nil {
+		return bencherror.NewHttpResponseError(ctx, err, req)
+	}
+	return nil
+}
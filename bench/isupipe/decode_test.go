@@ -0,0 +1,115 @@
+package isupipe
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecodeAndValidate(t *testing.T) {
+	ctx := context.Background()
+	req, err := http.NewRequest(http.MethodGet, "http://pipe.u.isucon.dev/api/livestream/1/statistics", nil)
+	assert.NoError(t, err)
+
+	stats, err := DecodeAndValidate[*LivestreamStatistics](ctx, req, strings.NewReader(`{"rank":1,"viewers_count":2,"total_reactions":3,"total_reports":0,"max_tip":0}`))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), stats.ViewersCount)
+
+	// フィールドの欠落
+	_, err = DecodeAndValidate[*LivestreamStatistics](ctx, req, strings.NewReader(`{"rank":1,"viewers_count":2,"total_reactions":3,"max_tip":0}`))
+	assert.ErrorContains(t, err, "livestreamStatistics.total_reports は必須です")
+
+	// 型の不一致
+	_, err = DecodeAndValidate[*LivestreamStatistics](ctx, req, strings.NewReader(`{"rank":1,"viewers_count":"2","total_reactions":3,"total_reports":0,"max_tip":0}`))
+	assert.ErrorContains(t, err, "livestreamStatistics.viewers_count は整数でなければなりません")
+
+	// 値域
+	_, err = DecodeAndValidate[*LivestreamStatistics](ctx, req, strings.NewReader(`{"rank":1,"viewers_count":2,"total_reactions":3,"total_reports":0,"max_tip":-1}`))
+	assert.ErrorContains(t, err, "livestreamStatistics.max_tip は0以上の整数でなければなりません")
+
+	// null
+	_, err = DecodeAndValidate[*LivestreamStatistics](ctx, req, strings.NewReader(`null`))
+	assert.ErrorContains(t, err, "livestreamStatistics はnullであってはなりません")
+}
+
+func TestDecodeAndValidate_Slice(t *testing.T) {
+	ctx := context.Background()
+	req, err := http.NewRequest(http.MethodGet, "http://pipe.u.isucon.dev/api/livestream/1/ngwords", nil)
+	assert.NoError(t, err)
+
+	ngwords, err := DecodeAndValidate[[]*NGWord](ctx, req, strings.NewReader(`[{"id":1,"user_id":2,"livestream_id":3,"word":"isu","created_at":4}]`))
+	assert.NoError(t, err)
+	assert.Len(t, ngwords, 1)
+
+	_, err = DecodeAndValidate[[]*NGWord](ctx, req, strings.NewReader(`[{"id":1,"user_id":2,"livestream_id":3,"created_at":4}]`))
+	assert.ErrorContains(t, err, "ngWords[0].word は必須です")
+
+	_, err = DecodeAndValidate[[]*NGWord](ctx, req, strings.NewReader(`[{"id":1,"user_id":2,"livestream_id":3,"word":"","created_at":4}]`))
+	assert.ErrorContains(t, err, "ngWords[0].word は必須です")
+}
+
+func TestDecodeAndValidate_RequiredFields(t *testing.T) {
+	ctx := context.Background()
+	req, err := http.NewRequest(http.MethodGet, "http://pipe.u.isucon.dev/api/livestream/1/livecomment", nil)
+	assert.NoError(t, err)
+
+	const user = `{"id":1,"name":"isu","display_name":"isu","description":"isu","theme":{"dark_mode":true},"icon_hash":"abc"}`
+	const livestream = `{"id":1,"owner":` + user + `,"tags":[],"title":"t","description":"d","playlist_url":"p","thumbnail_url":"t","start_at":1,"end_at":2}`
+
+	// tipは省略されうる
+	livecomments, err := DecodeAndValidate[[]*Livecomment](ctx, req, strings.NewReader(`[{"id":1,"user":`+user+`,"livestream":`+livestream+`,"comment":"isu","created_at":1}]`))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, livecomments[0].Tip)
+	assert.True(t, livecomments[0].User.Theme.DarkMode)
+
+	_, err = DecodeAndValidate[[]*Livecomment](ctx, req, strings.NewReader(`[{"id":1,"user":`+user+`,"livestream":`+livestream+`,"tip":10}]`))
+	assert.ErrorContains(t, err, "livecomments[0].comment は必須です")
+	assert.ErrorContains(t, err, "livecomments[0].created_at は必須です")
+
+	_, err = DecodeAndValidate[[]*Livecomment](ctx, req, strings.NewReader(`[{"id":1.5,"user":null,"livestream":`+livestream+`,"comment":"isu","created_at":1}]`))
+	assert.ErrorContains(t, err, "livecomments[0].id は整数でなければなりません")
+	assert.ErrorContains(t, err, "livecomments[0].user はnullであってはなりません")
+}
+
+// 1レスポンスのデコードと検証で許容するアロケーション回数
+// NOTE: 高負荷時にベンチマーカー側のGCが律速にならないよう、回数が増えた場合に検知する
+const decodeAllocsBudget = 30
+
+const statisticsBody = `{"rank":1,"viewers_count":2,"total_reactions":3,"total_reports":0,"max_tip":0}`
+
+func TestDecodeAndValidate_Allocs(t *testing.T) {
+	ctx := context.Background()
+	req, err := http.NewRequest(http.MethodGet, "http://pipe.u.isucon.dev/api/livestream/1/statistics", nil)
+	assert.NoError(t, err)
+
+	r := strings.NewReader(statisticsBody)
+	allocs := testing.AllocsPerRun(100, func() {
+		r.Reset(statisticsBody)
+		if _, err := DecodeAndValidate[*LivestreamStatistics](ctx, req, r); err != nil {
+			t.Fatal(err)
+		}
+	})
+	assert.LessOrEqual(t, allocs, float64(decodeAllocsBudget))
+}
+
+func BenchmarkDecodeAndValidate(b *testing.B) {
+	ctx := context.Background()
+	req, err := http.NewRequest(http.MethodGet, "http://pipe.u.isucon.dev/api/livestream/1/statistics", nil)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		r := strings.NewReader(statisticsBody)
+		for pb.Next() {
+			r.Reset(statisticsBody)
+			if _, err := DecodeAndValidate[*LivestreamStatistics](ctx, req, r); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
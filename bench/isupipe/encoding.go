@@ -0,0 +1,198 @@
+package isupipe
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+
+	"github.com/isucon/isucandar/agent"
+	"github.com/isucon/isucon13/bench/internal/bandwidth"
+	"github.com/isucon/isucon13/bench/internal/bencherror"
+	"github.com/isucon/isucon13/bench/internal/compression"
+	"github.com/isucon/isucon13/bench/internal/config"
+	"github.com/isucon/isucon13/bench/internal/metrics"
+)
+
+// wireCounter は、通信路上で受信したレスポンスボディのバイト数です
+// NOTE: isucandarのagentは受信後に展開したボディを返すため、展開前のバイト数はTransportで数える
+type wireCounter struct {
+	n atomic.Int64
+	// Transportで数えたか (数えていないagentでは記録しない)
+	counted atomic.Bool
+}
+
+type wireCounterKey struct{}
+
+func withWireCounter(ctx context.Context, counter *wireCounter) context.Context {
+	return context.WithValue(ctx, wireCounterKey{}, counter)
+}
+
+// wireCountingTransport は、展開前のレスポンスボディのバイト数を数えるhttp.RoundTripperです
+// contextに転送量の集計が紐づいていれば、webappと送受信したバイト数をエンドポイントの分類ごとに記録します
+// NOTE: ヘッダはHTTP/1.1の表記で数える. HTTP/2ではヘッダが圧縮されるため、実際の転送量より多めに見積もる
+type wireCountingTransport struct {
+	http.RoundTripper
+}
+
+func (t *wireCountingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	meter := bandwidth.FromContext(req.Context())
+	class := string(config.ClassifyEndpoint(req.Method, req.URL.Path))
+	sent := requestBytes(req)
+
+	resp, err := t.RoundTripper.RoundTrip(req)
+	if err != nil {
+		meter.Observe(class, sent, 0)
+		return resp, err
+	}
+	received := responseHeaderBytes(resp)
+	if resp.Body == nil || resp.Body == http.NoBody {
+		meter.Observe(class, sent, received)
+		return resp, nil
+	}
+	counter, _ := req.Context().Value(wireCounterKey{}).(*wireCounter)
+	if counter != nil {
+		counter.counted.Store(true)
+	}
+	resp.Body = &countingBody{
+		ReadCloser: resp.Body,
+		onRead: func(n int) {
+			if counter != nil {
+				counter.n.Add(int64(n))
+			}
+		},
+		onClose: func(n int64) { meter.Observe(class, sent, received+n) },
+	}
+	return resp, nil
+}
+
+// withWireCountingTransport は、送受信したバイト数を数えるようにします
+// NOTE: agent.WithCloneTransport でTransportが差し替えられるため、カスタムオプションの後に指定する
+// NOTE: 付与したヘッダも数えるよう、ヘッダを付与するTransportより内側 (ラップするオプションの先頭) に指定する
+func withWireCountingTransport() agent.AgentOption {
+	return func(a *agent.Agent) error {
+		if _, ok := a.HttpClient.Transport.(*wireCountingTransport); !ok {
+			a.HttpClient.Transport = &wireCountingTransport{RoundTripper: a.HttpClient.Transport}
+		}
+		return nil
+	}
+}
+
+// countingBody は、読み込んだレスポンスボディのバイト数を数え、閉じた際に合計を通知します
+type countingBody struct {
+	io.ReadCloser
+	onRead  func(n int)
+	onClose func(n int64)
+	n       int64
+	once    sync.Once
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.n += int64(n)
+	b.onRead(n)
+	return n, err
+}
+
+func (b *countingBody) Close() error {
+	b.once.Do(func() { b.onClose(b.n) })
+	return b.ReadCloser.Close()
+}
+
+type byteCounter int64
+
+func (c *byteCounter) Write(p []byte) (int, error) {
+	*c += byteCounter(len(p))
+	return len(p), nil
+}
+
+func requestBytes(req *http.Request) int64 {
+	var c byteCounter
+	host := req.Host
+	if len(host) == 0 {
+		host = req.URL.Host
+	}
+	// リクエストライン, Hostヘッダ
+	c.Write([]byte(req.Method + " " + req.URL.RequestURI() + " HTTP/1.1\r\n"))
+	c.Write([]byte("Host: " + host + "\r\n"))
+	req.Header.Write(&c)
+	c.Write([]byte("\r\n"))
+	if req.ContentLength > 0 {
+		c += byteCounter(req.ContentLength)
+	}
+	return int64(c)
+}
+
+func responseHeaderBytes(resp *http.Response) int64 {
+	var c byteCounter
+	c.Write([]byte(resp.Proto + " " + resp.Status + "\r\n"))
+	resp.Header.Write(&c)
+	c.Write([]byte("\r\n"))
+	return int64(c)
+}
+
+// decodedBody は、展開後のレスポンスボディを読み終えた際に、圧縮の効果を記録します
+// 展開に失敗した場合は、webappが不正な圧縮データを返したものとして扱います
+type decodedBody struct {
+	io.ReadCloser
+	ctx      context.Context
+	req      *http.Request
+	encoding string
+	wire     *wireCounter
+	decoded  int64
+	observed bool
+	err      error
+}
+
+func withEncodingObservation(ctx context.Context, req *http.Request, resp *http.Response, wire *wireCounter) {
+	if req.Method == http.MethodHead || resp.Body == nil || resp.Body == http.NoBody {
+		return
+	}
+	resp.Body = &decodedBody{
+		ReadCloser: resp.Body,
+		ctx:        ctx,
+		req:        req,
+		encoding:   compression.Encoding(resp.Header.Get("Content-Encoding")),
+		wire:       wire,
+	}
+}
+
+func (b *decodedBody) Read(p []byte) (int, error) {
+	if b.err != nil {
+		return 0, b.err
+	}
+	n, err := b.ReadCloser.Read(p)
+	b.decoded += int64(n)
+	if err == nil {
+		return n, nil
+	}
+	if err == io.EOF {
+		b.observe()
+		return n, err
+	}
+
+	// NOTE: Content-Lengthの食い違いなど、圧縮されていないレスポンスの読み込みエラーはそのまま返す
+	if b.encoding == compression.Identity || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return n, err
+	}
+	b.err = bencherror.NewApplicationError(b.ctx, err, "%s %s のレスポンスを Content-Encoding: %s として展開できません", b.req.Method, b.req.URL.EscapedPath(), b.encoding)
+	return n, b.err
+}
+
+func (b *decodedBody) observe() {
+	if b.observed {
+		return
+	}
+	b.observed = true
+	if !b.wire.counted.Load() {
+		return
+	}
+	compression.Observe(b.req.Method+" "+metrics.Route(b.req.URL.Path), b.encoding, b.wire.n.Load(), b.decoded)
+}
+
+// isUnknownContentEncoding は、Accept-Encoding で受け付けていない方式で圧縮されたレスポンスであったかを返します
+func isUnknownContentEncoding(err error) bool {
+	return errors.Is(err, agent.ErrUnknownContentEncoding)
+}
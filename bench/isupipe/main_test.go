@@ -7,8 +7,6 @@ import (
 	"time"
 
 	"github.com/isucon/isucandar/agent"
-	"github.com/isucon/isucon13/bench/internal/bencherror"
-	"github.com/isucon/isucon13/bench/internal/benchscore"
 	"github.com/isucon/isucon13/bench/internal/config"
 	"github.com/isucon/isucon13/bench/internal/logger"
 )
@@ -19,7 +17,9 @@ func TestMain(m *testing.M) {
 		log.Fatalln(err)
 	}
 
+	ctx := context.Background()
 	client, err := NewClient(
+		ctx,
 		testLogger,
 		agent.WithTimeout(1*time.Minute),
 	)
@@ -27,13 +27,9 @@ func TestMain(m *testing.M) {
 		log.Fatalln(err)
 	}
 	config.TargetWebapps = []string{"127.0.0.1"}
-	if _, err := client.Initialize(context.Background()); err != nil {
+	if _, err := client.Initialize(ctx); err != nil {
 		log.Fatalln(err)
 	}
 
-	ctx := context.Background()
-	benchscore.InitCounter(ctx)
-	bencherror.InitErrors(ctx)
-
 	m.Run()
 }
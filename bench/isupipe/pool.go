@@ -3,7 +3,9 @@ package isupipe
 import (
 	"context"
 	"fmt"
+	"strconv"
 
+	"github.com/isucon/isucon13/bench/internal/journal"
 	"github.com/isucon/isucon13/bench/internal/pubsub"
 )
 
@@ -30,6 +32,7 @@ func (p *ClientPool) Get(ctx context.Context) (*Client, error) {
 	if !ok {
 		return nil, fmt.Errorf("got invalid client from pool")
 	}
+	journal.Note(ctx, journal.NoteUser, client.username)
 
 	return client, nil
 }
@@ -61,6 +64,7 @@ func (p *LivestreamPool) Get(ctx context.Context) (*Livestream, error) {
 	if !ok {
 		return nil, fmt.Errorf("got invalid livestream from pool")
 	}
+	journal.Note(ctx, journal.NoteLivestream, strconv.FormatInt(livestream.ID, 10))
 
 	return livestream, nil
 }
@@ -91,6 +95,7 @@ func (p *LivecommentPool) Get(ctx context.Context) (*Livecomment, error) {
 	if !ok {
 		return nil, fmt.Errorf("got invalid livestream from pool")
 	}
+	journal.Note(ctx, journal.NoteLivecomment, strconv.FormatInt(livecomment.ID, 10))
 
 	return livecomment, nil
 }
@@ -0,0 +1,39 @@
+package isupipe
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/url"
+
+	"github.com/isucon/isucon13/bench/internal/config"
+	"github.com/isucon/isucon13/bench/internal/resolver"
+)
+
+// proxyFunc は、--proxy が指定されていればプロキシを経由させるための http.Transport.Proxy を返します
+func proxyFunc() func(*http.Request) (*url.URL, error) {
+	u, err := config.ParseProxyURL()
+	if err != nil || u == nil {
+		// NOTE: URLは起動時に検証済み
+		return nil
+	}
+	return http.ProxyURL(u)
+}
+
+// dialContext は、webappへの接続に用いる http.Transport.DialContext を返します
+// NOTE: プロキシのホスト名は選手のDNSサーバでは名前解決できないため、プロキシへの接続のみOSのリゾルバを用いる
+func dialContext(dnsResolver *resolver.DNSResolver) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	u, err := config.ParseProxyURL()
+	if err != nil || u == nil {
+		return dnsResolver.DialContext
+	}
+
+	proxyAddr := config.ProxyAddr(u)
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if addr == proxyAddr {
+			dialer := &net.Dialer{Timeout: dnsResolver.Timeout, LocalAddr: config.LocalAddr(network, addr)}
+			return dialer.DialContext(ctx, network, addr)
+		}
+		return dnsResolver.DialContext(ctx, network, addr)
+	}
+}
@@ -0,0 +1,45 @@
+package isupipe
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/isucon/isucon13/bench/internal/config"
+)
+
+// LoginSetCookies は、ログインのレスポンスで発行されたCookieと、その発行時刻を返します
+// NOTE: 発行時刻はレスポンスの Date ヘッダによる. Expires をwebappの時計で評価するため
+func (c *Client) LoginSetCookies() ([]*http.Cookie, time.Time) {
+	return c.loginCookies, c.loginIssuedAt
+}
+
+// CheckSessionCookie は、セッションCookieの属性が仕様を満たすか検証します
+//   - SameSite=None の場合は Secure 属性が必須
+//   - Max-Age または Expires を指定する場合、有効期間が config.SessionCookieMinLifetime 以上 config.SessionCookieMaxLifetime 以下
+//
+// NOTE: HttpOnly や Secure 属性は参照実装によって付与しないものがあるため、必須としない
+func CheckSessionCookie(cookie *http.Cookie, issuedAt time.Time) error {
+	if cookie.SameSite == http.SameSiteNoneMode && !cookie.Secure {
+		return fmt.Errorf("SameSite=None ですが Secure 属性がありません")
+	}
+
+	var lifetime time.Duration
+	switch {
+	case cookie.MaxAge < 0:
+		return fmt.Errorf("Max-Age が0以下のため即座に失効します")
+	case cookie.MaxAge > 0:
+		// NOTE: Max-Age と Expires の両方がある場合は Max-Age が優先される
+		lifetime = time.Duration(cookie.MaxAge) * time.Second
+	case !cookie.Expires.IsZero():
+		lifetime = cookie.Expires.Sub(issuedAt)
+	default:
+		// NOTE: 有効期間の指定がないセッションCookieは、ブラウザを閉じるまで有効とする
+		return nil
+	}
+	if lifetime < config.SessionCookieMinLifetime || lifetime > config.SessionCookieMaxLifetime {
+		return fmt.Errorf("有効期間は %s 以上 %s 以下でなければなりません (actual: %s)", config.SessionCookieMinLifetime, config.SessionCookieMaxLifetime, lifetime.Round(time.Second))
+	}
+
+	return nil
+}
@@ -0,0 +1,27 @@
+package isupipe
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckSessionCookie(t *testing.T) {
+	issuedAt := time.Date(2023, 11, 25, 10, 0, 0, 0, time.UTC)
+
+	// NOTE: 参照実装(go, php)と同じ Max-Age=60000 のCookie
+	assert.NoError(t, CheckSessionCookie(&http.Cookie{Name: "SESSIONID", MaxAge: 60000}, issuedAt))
+	assert.NoError(t, CheckSessionCookie(&http.Cookie{Name: "SESSIONID", HttpOnly: true, Secure: true, MaxAge: 60000}, issuedAt))
+	// 有効期間の指定がないセッションCookie (python)
+	assert.NoError(t, CheckSessionCookie(&http.Cookie{Name: "session", HttpOnly: true}, issuedAt))
+	// Expires で1時間後に失効するCookie (perl)
+	assert.NoError(t, CheckSessionCookie(&http.Cookie{Name: "isupipe_perl", Expires: issuedAt.Add(time.Hour)}, issuedAt))
+
+	assert.Error(t, CheckSessionCookie(&http.Cookie{Name: "SESSIONID", HttpOnly: true, SameSite: http.SameSiteNoneMode}, issuedAt))
+	assert.Error(t, CheckSessionCookie(&http.Cookie{Name: "SESSIONID", HttpOnly: true, MaxAge: -1}, issuedAt))
+	assert.Error(t, CheckSessionCookie(&http.Cookie{Name: "SESSIONID", HttpOnly: true, MaxAge: 60}, issuedAt))
+	assert.Error(t, CheckSessionCookie(&http.Cookie{Name: "SESSIONID", HttpOnly: true, MaxAge: 365 * 24 * 60 * 60}, issuedAt))
+	assert.Error(t, CheckSessionCookie(&http.Cookie{Name: "SESSIONID", HttpOnly: true, Expires: issuedAt.Add(time.Minute)}, issuedAt))
+}
@@ -0,0 +1,146 @@
+package isupipe
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// Action は、エンドポイントに対する操作の種別です
+// 操作ごとに期待するHTTPステータスコードはstatusSpecTableで一元管理します
+// NOTE: 仕様変更時はstatusSpecTableのみを修正してください
+type Action string
+
+const (
+	// 初期化
+	ActionInitialize Action = "initialize"
+
+	// ユーザ
+	ActionRegister           Action = "register"
+	ActionRegisterPipeUser   Action = "register-pipe-user"
+	ActionRegisterDuplicated Action = "register-duplicated"
+	ActionLogin              Action = "login"
+	ActionLoginUnknownUser   Action = "login-unknown-user"
+	ActionLoginWrongPassword Action = "login-wrong-password"
+	ActionGetUser            Action = "get-user"
+	ActionGetMe              Action = "get-me"
+	ActionGetStreamerTheme   Action = "get-streamer-theme"
+	ActionGetIcon            Action = "get-icon"
+	ActionGetIconNotModified Action = "get-icon-not-modified"
+	ActionPostIcon           Action = "post-icon"
+
+	// 認証・認可
+	ActionUnauthenticated Action = "unauthenticated"
+	// NOTE: 参照実装は、セッションCookieが無い場合にセッションの有効期限を取り出せず403を返す
+	ActionUnauthenticatedWithoutSession Action = "unauthenticated-without-session"
+
+	// ライブ配信
+	ActionGetLivestream             Action = "get-livestream"
+	ActionSearchLivestreams         Action = "search-livestreams"
+	ActionGetMyLivestreams          Action = "get-my-livestreams"
+	ActionGetUserLivestreams        Action = "get-user-livestreams"
+	ActionReserveLivestream         Action = "reserve-livestream"
+	ActionReserveLivestreamConflict Action = "reserve-livestream-conflict"
+	ActionReserveLivestreamOutTerm  Action = "reserve-livestream-out-of-term"
+	ActionEnterLivestream           Action = "enter-livestream"
+	ActionExitLivestream            Action = "exit-livestream"
+
+	// ライブコメント
+	ActionGetLivecomments          Action = "get-livecomments"
+	ActionGetLivecommentReports    Action = "get-livecomment-reports"
+	ActionGetNgwords               Action = "get-ngwords"
+	ActionPostLivecomment          Action = "post-livecomment"
+	ActionPostLivecommentModerated Action = "post-livecomment-moderated"
+	ActionPostLivecommentBadTip    Action = "post-livecomment-bad-tip"
+	ActionReportLivecomment        Action = "report-livecomment"
+	ActionModerate                 Action = "moderate"
+	ActionModerateOthersLivestream Action = "moderate-others-livestream"
+
+	// リアクション
+	ActionGetReactions Action = "get-reactions"
+	ActionPostReaction Action = "post-reaction"
+
+	// 統計情報
+	ActionGetUserStatistics       Action = "get-user-statistics"
+	ActionGetLivestreamStatistics Action = "get-livestream-statistics"
+
+	// タグ
+	ActionGetTags Action = "get-tags"
+
+	// 売上
+	ActionGetPayment Action = "get-payment"
+)
+
+// statusSpecTable は、操作ごとに期待するHTTPステータスコードの仕様表です
+var statusSpecTable = map[Action]int{
+	ActionInitialize: http.StatusOK,
+
+	ActionRegister:           http.StatusCreated,
+	ActionRegisterPipeUser:   http.StatusBadRequest,
+	ActionRegisterDuplicated: http.StatusInternalServerError,
+	ActionLogin:              http.StatusOK,
+	ActionLoginUnknownUser:   http.StatusUnauthorized,
+	ActionLoginWrongPassword: http.StatusUnauthorized,
+	ActionGetUser:            http.StatusOK,
+	ActionGetMe:              http.StatusOK,
+	ActionGetStreamerTheme:   http.StatusOK,
+	ActionGetIcon:            http.StatusOK,
+	ActionGetIconNotModified: http.StatusNotModified,
+	ActionPostIcon:           http.StatusCreated,
+
+	ActionUnauthenticated:               http.StatusUnauthorized,
+	ActionUnauthenticatedWithoutSession: http.StatusForbidden,
+
+	ActionGetLivestream:             http.StatusOK,
+	ActionSearchLivestreams:         http.StatusOK,
+	ActionGetMyLivestreams:          http.StatusOK,
+	ActionGetUserLivestreams:        http.StatusOK,
+	ActionReserveLivestream:         http.StatusCreated,
+	ActionReserveLivestreamConflict: http.StatusBadRequest,
+	ActionReserveLivestreamOutTerm:  http.StatusBadRequest,
+	ActionEnterLivestream:           http.StatusOK,
+	ActionExitLivestream:            http.StatusOK,
+
+	ActionGetLivecomments:          http.StatusOK,
+	ActionGetLivecommentReports:    http.StatusOK,
+	ActionGetNgwords:               http.StatusOK,
+	ActionPostLivecomment:          http.StatusCreated,
+	ActionPostLivecommentModerated: http.StatusBadRequest,
+	ActionPostLivecommentBadTip:    http.StatusBadRequest,
+	ActionReportLivecomment:        http.StatusCreated,
+	ActionModerate:                 http.StatusCreated,
+	ActionModerateOthersLivestream: http.StatusBadRequest,
+
+	ActionGetReactions: http.StatusOK,
+	ActionPostReaction: http.StatusCreated,
+
+	ActionGetUserStatistics:       http.StatusOK,
+	ActionGetLivestreamStatistics: http.StatusOK,
+
+	ActionGetTags: http.StatusOK,
+
+	ActionGetPayment: http.StatusOK,
+}
+
+// ExpectedStatusCode は、操作に対して期待するHTTPステータスコードを返します
+// 仕様表に存在しない操作はベンチマーカーの実装ミスなのでpanicします
+func ExpectedStatusCode(action Action) int {
+	statusCode, ok := statusSpecTable[action]
+	if !ok {
+		panic(fmt.Sprintf("ステータスコードの仕様が定義されていない操作です: %s", action))
+	}
+	return statusCode
+}
+
+// WithAction は、仕様表に基づいて操作に対応するステータスコードを期待するようにします
+func WithAction(action Action) ClientOption {
+	return WithStatusCode(ExpectedStatusCode(action))
+}
+
+// WithAllowedActions は、仕様表に基づいて操作に対応するステータスコードを追加で許容します
+func WithAllowedActions(actions ...Action) ClientOption {
+	statusCodes := make([]int, len(actions))
+	for i, action := range actions {
+		statusCodes[i] = ExpectedStatusCode(action)
+	}
+	return WithAllowedStatusCodes(statusCodes...)
+}
@@ -0,0 +1,31 @@
+package isupipe
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatusSpec(t *testing.T) {
+	assert.Equal(t, http.StatusOK, ExpectedStatusCode(ActionInitialize))
+	assert.Equal(t, http.StatusCreated, ExpectedStatusCode(ActionReserveLivestream))
+	assert.Equal(t, http.StatusBadRequest, ExpectedStatusCode(ActionReserveLivestreamConflict))
+	assert.Equal(t, http.StatusUnauthorized, ExpectedStatusCode(ActionLoginWrongPassword))
+	assert.Equal(t, http.StatusNotModified, ExpectedStatusCode(ActionGetIconNotModified))
+	assert.Equal(t, http.StatusUnauthorized, ExpectedStatusCode(ActionUnauthenticated))
+	assert.Equal(t, http.StatusBadRequest, ExpectedStatusCode(ActionModerateOthersLivestream))
+	assert.Equal(t, http.StatusBadRequest, ExpectedStatusCode(ActionPostLivecommentBadTip))
+
+	assert.Panics(t, func() { ExpectedStatusCode(Action("unknown")) })
+}
+
+func TestStatusSpec_Options(t *testing.T) {
+	o := newClientOptions(ExpectedStatusCode(ActionRegister), WithAction(ActionRegisterPipeUser))
+	assert.Equal(t, http.StatusBadRequest, o.wantStatusCode)
+
+	o = newClientOptions(ExpectedStatusCode(ActionReserveLivestream), WithAllowedActions(ActionReserveLivestreamConflict))
+	assert.True(t, o.isExpectedStatusCode(http.StatusCreated))
+	assert.True(t, o.isExpectedStatusCode(http.StatusBadRequest))
+	assert.False(t, o.isExpectedStatusCode(http.StatusInternalServerError))
+}
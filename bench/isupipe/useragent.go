@@ -0,0 +1,42 @@
+package isupipe
+
+import (
+	"net/http"
+
+	"github.com/isucon/isucandar/agent"
+	"github.com/isucon/isucon13/bench/internal/benchrand"
+	"github.com/isucon/isucon13/bench/internal/config"
+)
+
+// pickUserAgent は、実在するブラウザのUser-Agentを乱数源に従って1つ選びます
+func pickUserAgent() string {
+	if len(config.UserAgents) == 0 {
+		return agent.DefaultName
+	}
+	return config.UserAgents[benchrand.Intn(len(config.UserAgents))]
+}
+
+// withBotMarkerTransport は、ベンチマーカーからのリクエストであることを示すヘッダを付与します
+// NOTE: --bot-marker=false の場合は何もしない
+func withBotMarkerTransport() agent.AgentOption {
+	return func(a *agent.Agent) error {
+		if !config.BotMarkerEnabled {
+			return nil
+		}
+		if _, ok := a.HttpClient.Transport.(*botMarkerTransport); !ok {
+			a.HttpClient.Transport = &botMarkerTransport{RoundTripper: a.HttpClient.Transport}
+		}
+		return nil
+	}
+}
+
+type botMarkerTransport struct {
+	http.RoundTripper
+}
+
+func (t *botMarkerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// NOTE: RoundTripperはリクエストを変更してはならないため、複製してからヘッダを付与する
+	req = req.Clone(req.Context())
+	req.Header.Set(config.BotMarkerHeader, config.BotMarkerValue)
+	return t.RoundTripper.RoundTrip(req)
+}
@@ -1,7 +1,10 @@
 package isupipe
 
 import (
+	"context"
 	"net/http"
+	"reflect"
+	"strings"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/isucon/isucon13/bench/internal/bencherror"
@@ -12,14 +15,22 @@ var validate *validator.Validate
 
 func init() {
 	validate = validator.New(validator.WithRequiredStructEnabled())
+	// NOTE: エラーメッセージのフィールド名をAPI仕様(JSON)の名前に揃える
+	validate.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
 }
 
-func ValidateResponse(req *http.Request, response interface{}) error {
+func ValidateResponse(ctx context.Context, req *http.Request, response interface{}) error {
 	lgr := zap.S()
 	if err := validate.Struct(response); err != nil {
 		if _, ok := err.(*validator.InvalidValidationError); ok {
 			lgr.Warnf("ValidateResponse: invalid validation error発生: %s\n", err.Error())
-			return bencherror.NewInternalError(err)
+			return bencherror.NewInternalError(ctx, err)
 		}
 
 		var errorFields []string
@@ -27,18 +38,18 @@ func ValidateResponse(req *http.Request, response interface{}) error {
 			errorFields = append(errorFields, err.Namespace())
 		}
 
-		return bencherror.NewEmptyHttpResponseError(errorFields, req)
+		return bencherror.NewEmptyHttpResponseError(ctx, errorFields, req)
 	}
 
 	return nil
 }
 
-func ValidateSlice(req *http.Request, slice interface{}) error {
+func ValidateSlice(ctx context.Context, req *http.Request, slice interface{}) error {
 	lgr := zap.S()
 	if err := validate.Var(slice, "dive,required"); err != nil {
 		if _, ok := err.(*validator.InvalidValidationError); ok {
 			lgr.Warnf("ValidateSlice: invalid validation error発生: %s\n", err.Error())
-			return bencherror.NewInternalError(err)
+			return bencherror.NewInternalError(ctx, err)
 		}
 
 		var errorFields []string
@@ -46,7 +57,7 @@ func ValidateSlice(req *http.Request, slice interface{}) error {
 			errorFields = append(errorFields, err.Namespace())
 		}
 
-		return bencherror.NewEmptyHttpResponseError(errorFields, req)
+		return bencherror.NewEmptyHttpResponseError(ctx, errorFields, req)
 	}
 
 	return nil
@@ -0,0 +1,183 @@
+package bench
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/isucon/isucandar/score"
+	"github.com/isucon/isucon13/bench/internal/benchrand"
+	"github.com/isucon/isucon13/bench/internal/config"
+	"github.com/isucon/isucon13/bench/internal/journal"
+	"github.com/isucon/isucon13/bench/scenario"
+	"go.uber.org/zap"
+)
+
+// ReplayOptions は、Replay の走行条件のうち、config パッケージで表せないものです
+type ReplayOptions struct {
+	// 選手向けのログの出力先 (nilの場合は出力しない)
+	ContestantLogger *zap.Logger
+	// 再生するジャーナルのレコード
+	Records []*journal.Record
+	// このイテレーションまで再生する (0の場合はすべて再生する)
+	UntilIteration uint64
+}
+
+// ReplayStep は、1イテレーションの再生結果です
+type ReplayStep struct {
+	Iteration uint64 `json:"iteration"`
+	Scenario  string `json:"scenario"`
+	// 記録した走行の開始からの経過時間
+	Offset time.Duration `json:"offset"`
+	// 記録した結果と、再生した結果
+	Recorded string `json:"recorded"`
+	Replayed string `json:"replayed,omitempty"`
+	// 記録と再生で成否が一致したか
+	Reproduced bool `json:"reproduced"`
+	// 再生できないシナリオのため、再生しなかったか
+	Skipped bool `json:"skipped,omitempty"`
+}
+
+// ReplayReport は、ジャーナルの再生結果です
+type ReplayReport struct {
+	Seed  int64         `json:"seed"`
+	Steps []*ReplayStep `json:"steps"`
+}
+
+// replayScenarios は、再生できるシナリオです
+// NOTE: 走行中ずっと続くシナリオや、攻撃のシナリオは再生しない
+func (b *benchmarker) replayScenarios() map[score.ScoreTag]func(ctx context.Context) error {
+	return map[score.ScoreTag]func(ctx context.Context) error{
+		BasicStreamerColdReserve: func(ctx context.Context) error {
+			return scenario.BasicStreamerColdReserveScenario(ctx, b.contestantLogger, b.streamerClientPool, b.livestreamPool)
+		},
+		BasicStreamerModerateScenario: func(ctx context.Context) error {
+			return scenario.BasicStreamerModerateScenario(ctx, b.contestantLogger, b.streamerClientPool)
+		},
+		BasicViewerScenario: func(ctx context.Context) error {
+			return scenario.BasicViewerScenario(ctx, b.contestantLogger, b.viewerClientPool, b.livestreamPool)
+		},
+		BasicViewerReportScenario: func(ctx context.Context) error {
+			return scenario.BasicViewerReportScenario(ctx, b.contestantLogger, b.viewerClientPool, b.spamPool)
+		},
+		ViewerSpamScenario: func(ctx context.Context) error {
+			return scenario.ViewerSpamScenario(ctx, b.contestantLogger, b.viewerClientPool, b.livestreamPool, b.spamPool)
+		},
+		AggressiveStreamerModerateScenario: func(ctx context.Context) error {
+			return scenario.AggressiveStreamerModerateScenario(ctx, b.contestantLogger, b.streamerClientPool)
+		},
+		ReservationCollisionScenario: func(ctx context.Context) error {
+			return scenario.ReservationCollisionScenario(ctx, b.contestantLogger, b.streamerClientPool, b.livestreamPool)
+		},
+		RaidScenario: func(ctx context.Context) error {
+			return scenario.RaidScenario(ctx, b.contestantLogger, b.viewerClientPool, b.livestreamPool)
+		},
+		RankingStabilityScenario: func(ctx context.Context) error {
+			return scenario.RankingStabilityScenario(ctx, b.contestantLogger, b.viewerClientPool, b.livestreamPool)
+		},
+		ModerationEffectivenessScenario: func(ctx context.Context) error {
+			return scenario.ModerationEffectivenessScenario(ctx, b.contestantLogger, b.streamerClientPool, b.viewerClientPool)
+		},
+		ReactionBurstScenario: func(ctx context.Context) error {
+			return scenario.ReactionBurstScenario(ctx, b.contestantLogger, b.viewerClientPool, b.livestreamPool)
+		},
+		TipRecordingScenario: func(ctx context.Context) error {
+			return scenario.TipRecordingScenario(ctx, b.contestantLogger, b.streamerClientPool, b.viewerClientPool)
+		},
+		UserRegistrationScenario: func(ctx context.Context) error {
+			return scenario.UserRegistrationScenario(ctx, b.contestantLogger)
+		},
+		RankingConsistencyScenario: func(ctx context.Context) error {
+			return scenario.RankingConsistencyScenario(ctx, b.contestantLogger, b.viewerClientPool, b.livestreamPool)
+		},
+		NgWordIdempotencyScenario: func(ctx context.Context) error {
+			return scenario.NgWordIdempotencyScenario(ctx, b.contestantLogger, b.streamerClientPool)
+		},
+	}
+}
+
+// Replay は、--journal で記録したシナリオのイテレーションを、記録した順序と間隔で再実行します
+// 記録した走行と同じシードで初期化し、イテレーションごとに記録したシードで乱数源を初期化してから実行します
+// NOTE: 決定的に再生できるよう、イテレーションは並行させずに1つずつ実行する. 前のイテレーションが長引いた場合、以降の開始は遅れる
+// NOTE: 記録した走行では並行するイテレーションが乱数源を共有するため、再生するリクエストの内容は記録した走行と一致するとは限らない
+func Replay(ctx context.Context, opts ReplayOptions) (*ReplayReport, error) {
+	lgr := zap.S()
+	contestantLogger := opts.ContestantLogger
+	if contestantLogger == nil {
+		contestantLogger = zap.NewNop()
+	}
+
+	runNotes := journal.RunNotes(opts.Records)
+	seed, err := strconv.ParseInt(runNotes[journal.RunSeed], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("ジャーナルに走行のシードが記録されていません: %w", err)
+	}
+	config.Seed = seed
+
+	runner, cleanup, err := prepareRunner(ctx, contestantLogger, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+	if err := runner.Initialize(ctx); err != nil {
+		return nil, err
+	}
+
+	b := newBenchmarker(ctx, contestantLogger, runner.profile, runner.scenarioCounter)
+	b.runClientProviders(ctx)
+	scenarios := b.replayScenarios()
+
+	report := &ReplayReport{Seed: seed}
+	summaries := journal.Summarize(opts.Records)
+	var origin time.Time
+	for _, s := range summaries {
+		if !s.Begin.IsZero() {
+			origin = s.Begin
+			break
+		}
+	}
+
+	startAt := time.Now()
+	for _, s := range summaries {
+		if opts.UntilIteration > 0 && s.ID > opts.UntilIteration {
+			break
+		}
+		step := &ReplayStep{
+			Iteration: s.ID,
+			Scenario:  s.Scenario,
+			Offset:    s.Begin.Sub(origin),
+			Recorded:  s.Result,
+		}
+		report.Steps = append(report.Steps, step)
+
+		tag := score.ScoreTag(s.Scenario)
+		fn, ok := scenarios[tag]
+		if !ok || s.Begin.IsZero() {
+			step.Skipped = true
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return report, ctx.Err()
+		case <-time.After(time.Until(startAt.Add(step.Offset))):
+		}
+
+		if v, ok := s.Note(journal.NoteSeed); ok {
+			if iterationSeed, err := strconv.ParseInt(v, 10, 64); err == nil {
+				benchrand.Init(iterationSeed)
+			}
+		}
+		err := b.runScenario(ctx, tag, fn)
+		step.Replayed = journal.ResultOK
+		if err != nil {
+			step.Replayed = err.Error()
+		}
+		// NOTE: 終了していないイテレーションは、成否を比較できない
+		step.Reproduced = !s.InFlight() && (s.Result == journal.ResultOK) == (err == nil)
+		lgr.Infof("再生: #%d %s 記録=%s 再生=%s", step.Iteration, step.Scenario, step.Recorded, step.Replayed)
+	}
+
+	return report, nil
+}
@@ -0,0 +1,508 @@
+package bench
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/isucon/isucon13/bench/internal/adaptive"
+	"github.com/isucon/isucon13/bench/internal/bandwidth"
+	"github.com/isucon/isucon13/bench/internal/bencherror"
+	"github.com/isucon/isucon13/bench/internal/benchrand"
+	"github.com/isucon/isucon13/bench/internal/benchscore"
+	"github.com/isucon/isucon13/bench/internal/benchtrace"
+	"github.com/isucon/isucon13/bench/internal/calibrate"
+	"github.com/isucon/isucon13/bench/internal/chaos"
+	"github.com/isucon/isucon13/bench/internal/churn"
+	"github.com/isucon/isucon13/bench/internal/compression"
+	"github.com/isucon/isucon13/bench/internal/config"
+	"github.com/isucon/isucon13/bench/internal/coverage"
+	"github.com/isucon/isucon13/bench/internal/disqualify"
+	"github.com/isucon/isucon13/bench/internal/failfast"
+	"github.com/isucon/isucon13/bench/internal/idleprobe"
+	"github.com/isucon/isucon13/bench/internal/logger"
+	"github.com/isucon/isucon13/bench/internal/scheduler"
+	"github.com/isucon/isucon13/bench/internal/slowloris"
+	"github.com/isucon/isucon13/bench/internal/topology"
+	"github.com/isucon/isucon13/bench/internal/version"
+	"github.com/isucon/isucon13/bench/isupipe"
+	"go.uber.org/zap"
+)
+
+// Result は、走行結果です (CLIは --result-path に書き出します)
+type Result struct {
+	Pass          bool     `json:"pass"`
+	Score         int64    `json:"score"`
+	Messages      []string `json:"messages"`
+	Language      string   `json:"language"`
+	ResolvedCount int64    `json:"resolved_count"`
+	// カテゴリ別のエラー件数
+	ErrorCounts map[bencherror.Category]int64 `json:"error_counts"`
+	// 段階・シナリオごとの成功・失敗回数 (段階の順、シナリオのタグ名の順)
+	Scenarios []benchscore.ScenarioCount `json:"scenarios,omitempty"`
+	// webappのミドルウェアや通信方式 (初期化に成功した場合のみ)
+	Server *isupipe.ServerMetadata `json:"server,omitempty"`
+	// ベンチマーカーのホストの性能計測結果 (--calibrate 指定時のみ)
+	Calibration *calibrate.Result `json:"calibration,omitempty"`
+	// 許可・除外リストによって一部のエンドポイントを除外した走行か (--endpoint-filter 指定時のみ)
+	Partial bool `json:"partial,omitempty"`
+	// 実施しなかったpretestの項目・シナリオと、リクエストを送らなかったエンドポイント
+	SkippedCoverage []string `json:"skipped_coverage,omitempty"`
+	// 走行に用いた負荷プロファイル
+	Profile string `json:"profile"`
+	// 走行に用いた乱数のシード
+	Seed int64 `json:"seed"`
+	// ベンチマーカーからのリクエストであることを示すヘッダを付与した走行か (--bot-marker=false で付与しない)
+	BotMarker bool `json:"bot_marker"`
+	// 走行したベンチマーカーのビルド情報
+	Build version.Info `json:"build"`
+	// 宛先ごとの成功率とレイテンシ (--targets 指定時のみ)
+	Targets []topology.TargetReport `json:"targets,omitempty"`
+	// 基本シナリオのワーカー数の調整結果 (--adaptive-concurrency 指定時のみ)
+	Concurrency *adaptive.Report `json:"concurrency,omitempty"`
+	// 観測したwebappのアイドルタイムアウトと接続の扱い (--idle-probe 指定時のみ)
+	IdleTimeout *idleprobe.Report `json:"idle_timeout,omitempty"`
+	// リクエストを送り終えないクライアントの接続の扱いと、正規のリクエストへの影響 (--slow-loris 指定時のみ)
+	SlowLoris *slowloris.Report `json:"slow_loris,omitempty"`
+	// 負荷走行中に注入した遅延と接続の切断 (--chaos 指定時のみ)
+	Chaos *chaos.Report `json:"chaos,omitempty"`
+	// エラーの予算を超えて負荷走行を打ち切った条件 (--fail-fast-errors, --fail-fast-5xx-rate 指定時のみ)
+	FailFast *failfast.Exceeded `json:"fail_fast,omitempty"`
+	// エンドポイントごとのレスポンスの圧縮方式と転送量 (負荷走行のみ)
+	Compression *compression.Report `json:"compression,omitempty"`
+	// エンドポイントの分類ごとの送受信のバイト数と、合計の転送量[MB] (初期化から最終チェックまで)
+	Bandwidth *bandwidth.Report `json:"bandwidth,omitempty"`
+	// 負荷走行中に投稿したスパムコメントの種類数と、用いた定義 (負荷走行のみ)
+	SpamCorpus *scheduler.SpamCorpusCoverage `json:"spam_corpus,omitempty"`
+	// 接続の再利用 (keep-alive) とハンドシェイクの所要時間 (負荷走行のみ)
+	KeepAlive *benchtrace.KeepAliveReport `json:"keep_alive,omitempty"`
+	// 書き込めなかったため出力先から外したログファイル
+	LogFallbacks []logger.Fallback `json:"log_fallbacks,omitempty"`
+	// POST /api/initialize の所要時間[ms] (初期化に成功した場合のみ)
+	InitializeDurationMillis int64 `json:"initialize_duration_ms,omitempty"`
+	// 失格とした場合に、該当した判定規則のID
+	DisqualifiedBy []disqualify.RuleID `json:"disqualified_by,omitempty"`
+	// 練習モードの走行か (--practice 指定時のみ). 公式の結果として扱わない
+	Practice bool `json:"practice,omitempty"`
+	// 練習モードで、失格とせずに走行を続けた判定規則とその詳細
+	Waived []disqualify.Triggered `json:"waived,omitempty"`
+	// 成功率が下限を下回ったシナリオ (--score-config で下限を指定した場合のみ)
+	SLOViolations []benchscore.SLOViolation `json:"slo_violations,omitempty"`
+	// 凍結した時点までのスコア (--score-freeze-after 指定時のみ). Score には凍結後に得たスコアも含む
+	ScoreFreeze *ScoreFreeze `json:"score_freeze,omitempty"`
+	// スコアタグごとのスコアへの寄与と減点 (--explain-score 指定時のみ)
+	ScoreExplanation *ScoreExplanation `json:"score_explanation,omitempty"`
+	// 署名を除いた結果に対するHMAC-SHA256 (--result-signing-key 指定時のみ)
+	Signature string `json:"signature,omitempty"`
+}
+
+// ScoreFreeze は、選手に見せる、凍結した時点までの売上とスコアです
+type ScoreFreeze struct {
+	// 負荷走行の開始からスコアを凍結するまでの時間
+	After  time.Duration `json:"after"`
+	Profit uint64        `json:"profit"`
+	Score  int64         `json:"score"`
+}
+
+// UniqueMsgs は重複除去したメッセージ配列を返します
+func uniqueMsgs(msgs []string) (uniqMsgs []string) {
+	dedup := map[string]struct{}{}
+	for _, msg := range msgs {
+		if _, ok := dedup[msg]; ok {
+			continue
+		}
+		dedup[msg] = struct{}{}
+		uniqMsgs = append(uniqMsgs, msg)
+	}
+	return
+}
+
+// collectErrorMessages は、重複除去したベンチエラーのメッセージをカテゴリ順に並べて返します
+func collectErrorMessages(errs *bencherror.ErrorSet) []string {
+	var msgs []string
+	categorized := errs.FinalErrorMessages()
+	for _, category := range bencherror.Categories {
+		msgs = append(msgs, categorized[category]...)
+	}
+	return msgs
+}
+
+// terseMessages は、封印モードで選手に返すメッセージを先頭の要約のみに絞ります
+func terseMessages(msgs []string) []string {
+	if !config.Sealed || len(msgs) <= 1 {
+		return msgs
+	}
+	return msgs[:1]
+}
+
+// sloViolations は、成功率が下限を下回ったシナリオを返します
+// NOTE: 除外したエンドポイントを利用するため実行しなかったシナリオは対象外とする
+func (r *Runner) sloViolations() []benchscore.SLOViolation {
+	var violations []benchscore.SLOViolation
+	for _, v := range benchscore.CheckSLOs(r.scenarioCounter, benchscore.SLOs()) {
+		if r.benchmarker.skipped(v.Scenario) {
+			continue
+		}
+		violations = append(violations, v)
+	}
+	return violations
+}
+
+// keepAlivePenalty は、webappが接続を閉じたレスポンスの割合に応じて、スコアから差し引く点数を返します
+func keepAlivePenalty(score int64, report *benchtrace.KeepAliveReport) int64 {
+	if config.KeepAlivePenalty <= 0 || report == nil || score <= 0 {
+		return 0
+	}
+	return int64(float64(score) * config.KeepAlivePenalty * report.ForcedCloseRatio())
+}
+
+// practiceMessage は、練習モードの走行であることを示す選手向けのメッセージです
+const practiceMessage = "練習モードの走行です。公式の結果としては扱われません"
+
+// logBandwidth は、走行全体の転送量をログに出力し、集計を返します
+// NOTE: 大会当日のネットワークの帯域を見積もれるよう、失格とした走行でも出力する
+func (r *Runner) logBandwidth() *bandwidth.Report {
+	lgr := zap.S()
+
+	report := r.bandwidth.Report()
+	if report == nil {
+		return nil
+	}
+	lgr.Infof("転送量: 合計 %.1f MB (送信 %.1f MB, 受信 %.1f MB, リクエスト %d 件)", report.TotalMB, bandwidth.MB(report.SentBytes), bandwidth.MB(report.ReceivedBytes), report.Requests)
+	for _, stat := range report.Classes {
+		lgr.Infof("[転送量 %s] 送信 %.1f MB, 受信 %.1f MB, リクエスト %d 件", stat.Class, bandwidth.MB(stat.SentBytes), bandwidth.MB(stat.ReceivedBytes), stat.Requests)
+	}
+	return report
+}
+
+// FailedResult は、失格とした走行の結果を返します
+func (r *Runner) FailedResult(msgs []string) *Result {
+	messages := []string{}
+	if config.Practice {
+		messages = append(messages, practiceMessage)
+	}
+	if config.Sealed {
+		messages = append(messages, terseMessages(msgs)...)
+	} else {
+		messages = append(messages, msgs...)
+		messages = append(messages, collectErrorMessages(r.accountedErrors())...)
+	}
+	messages = uniqueMsgs(messages)
+
+	return &Result{
+		Pass:            false,
+		Score:           0,
+		Messages:        messages,
+		Language:        config.Language,
+		Server:          r.serverMetadata,
+		ErrorCounts:     r.accountedErrors().CategoryCounts(),
+		Scenarios:       r.scenarioCounter.Rows(),
+		Profile:         config.LoadProfileName,
+		Calibration:     r.Calibration,
+		Partial:         coverage.Partial(),
+		SkippedCoverage: coverage.Skipped(),
+		Seed:            benchrand.Seed(),
+		BotMarker:       config.BotMarkerEnabled,
+		Build:           version.Get(),
+		Targets:         topology.Report(),
+		Concurrency:     adaptive.CurrentReport(),
+		IdleTimeout:     r.idleTimeoutReport,
+		SlowLoris:       r.slowLorisReport,
+		Chaos:           chaos.CurrentReport(),
+		FailFast:        r.failFast,
+		Compression:     compression.CurrentReport(),
+		Bandwidth:       r.logBandwidth(),
+		KeepAlive:       benchtrace.CurrentKeepAliveReport(),
+		LogFallbacks:    logger.Fallbacks(),
+		DisqualifiedBy:  r.DisqualifiedBy(),
+		Practice:        config.Practice,
+		Waived:          r.disqualify.Waived(),
+
+		InitializeDurationMillis: r.initializeDuration.Milliseconds(),
+	}
+}
+
+// Report は、最終チェックまで成功した走行の集計をログに出力し、結果を返します
+func (r *Runner) Report() *Result {
+	lgr := zap.S()
+
+	r.contestantLogger.Info("重複排除したログを以下に出力します")
+
+	// ベンチマーク処理のエラー収集
+	lgr.Info("ベンチエラーを収集します")
+	scores, errs := r.accountedScores(), r.accountedErrors()
+	benchErrors := collectErrorMessages(errs)
+	errorCounts := errs.CategoryCounts()
+	for _, category := range bencherror.Categories {
+		lgr.Infof("エラー件数 (%s): %d", category, errorCounts[category])
+	}
+	if numEvicted := errs.NumEvictedMessages(); numEvicted > 0 {
+		lgr.Warnf("エラーメッセージの種類が上限(%d)を超えたため、%d 種類のメッセージを破棄しました", config.MaxUniqueErrorMessages, numEvicted)
+	}
+
+	// ベンチマーカー内部エラー
+	lgr.Info("内部エラーを収集します")
+	var systemErrorFound bool
+	for _, msgs := range errs.FinalSystemErrors() {
+		for _, msg := range msgs {
+			if len(msg) == 0 {
+				continue
+			}
+			lgr.Warnf("内部エラー: %s\n", msg)
+			systemErrorFound = true
+		}
+	}
+	if systemErrorFound {
+		r.contestantLogger.Warn("システム内部エラーが発生しました。運営にジョブIDとともに連絡お願いいたします")
+	}
+
+	var msgs []string
+	lgr.Info("シナリオカウンタを出力します")
+	if count := r.scenarioCounter.Count(benchscore.ScenarioPhaseLoad, BasicViewerScenario, benchscore.ScenarioSuccess); count > 0 {
+		r.contestantLogger.Info("配信を最後まで視聴できた視聴者数", zap.Int64("viewers", count))
+	}
+	for _, row := range r.scenarioCounter.Rows() {
+		lgr.Infof("[%s シナリオ %s] %d 回成功, %d 回失敗", row.Phase, row.Scenario, row.Success, row.Fail)
+	}
+
+	lgr.Info("シナリオのタイムアウトによるワーカー枯渇回数を出力します")
+	var timeoutLogs []string
+	for name, count := range r.benchmarker.TimeoutCounter() {
+		timeoutLogs = append(timeoutLogs, fmt.Sprintf("[ワーカー枯渇 %s] %d 回タイムアウト (上限 %s)", name, count, scenarioTimeouts[name]))
+	}
+	slices.Sort(timeoutLogs)
+	for _, l := range timeoutLogs {
+		lgr.Info(l)
+	}
+
+	priorityStats := r.benchmarker.PriorityStats()
+	lgr.Infof("検証のシナリオを優先するため、スループットのシナリオの起動を %d 回, 合計 %s 譲りました", priorityStats.Yields, priorityStats.YieldDuration)
+
+	lgr.Info("HTTPリクエストの段階ごとの所要時間を出力します")
+	for _, stat := range benchtrace.Stats() {
+		lgr.Infof("[HTTPフェーズ %s] %d 回, 平均 %s, 最大 %s", stat.Phase, stat.Count, stat.Mean(), stat.Max)
+	}
+	h2Stats := benchtrace.H2Stats()
+	for _, event := range benchtrace.H2Events() {
+		lgr.Infof("[HTTP/2 %s] %d 回", event, h2Stats[event])
+	}
+	if n := h2Stats[benchtrace.H2GoAway] + h2Stats[benchtrace.H2StreamReset] + h2Stats[benchtrace.H2ConnectionLost] + h2Stats[benchtrace.H2Retried]; n > 0 {
+		r.contestantLogger.Info("走行中にHTTP/2の接続の切断を検出しました",
+			zap.Int64("goaway", h2Stats[benchtrace.H2GoAway]),
+			zap.Int64("stream_reset", h2Stats[benchtrace.H2StreamReset]),
+			zap.Int64("connection_lost", h2Stats[benchtrace.H2ConnectionLost]),
+			zap.Int64("retried", h2Stats[benchtrace.H2Retried]),
+		)
+	}
+	keepAlive := benchtrace.CurrentKeepAliveReport()
+	if keepAlive != nil {
+		msgs = append(msgs, fmt.Sprintf("接続の再利用率 %.1f%% (新規接続 %d/%d 件, Connection: close %d 件)", keepAlive.ReuseRatio()*100, keepAlive.NewConns, keepAlive.Requests, keepAlive.ForcedCloses))
+		lgr.Infof("接続の再利用: %d/%d 件, 新規接続: %d 件, Connection: close: %d 件, ハンドシェイクの所要時間: 合計 %s (リクエストあたり %s)",
+			keepAlive.ReusedConns, keepAlive.Requests, keepAlive.NewConns, keepAlive.ForcedCloses, keepAlive.HandshakeOverhead, keepAlive.HandshakeOverheadPerRequest())
+		if keepAlive.ForcedCloseRatio() > config.KeepAliveWarnRatio {
+			r.contestantLogger.Warn("webappが Connection: close によって接続を閉じるため、リクエストのたびに接続を確立しています. keep-aliveの設定を見直してください",
+				zap.Int64("forced_closes", keepAlive.ForcedCloses),
+				zap.Int64("requests", keepAlive.Requests),
+			)
+		}
+	}
+	for _, report := range topology.Report() {
+		lgr.Infof("[宛先 %s] リクエスト %d 件, 成功率 %.2f%%, p50 %dms, p99 %dms, 期待しない名前解決 %d 回",
+			report.Name, report.Requests, report.SuccessRate*100, report.LatencyP50Millis, report.LatencyP99Millis, report.DNSMismatches)
+	}
+
+	numResolves := scores.GetByTag(benchscore.DNSResolve)
+	numDNSFailed := scores.GetByTag(benchscore.DNSFailed)
+	numDNSMalformed := scores.GetByTag(benchscore.DNSMalformed)
+	msgs = append(msgs, fmt.Sprintf("名前解決成功数 %d", numResolves))
+	lgr.Infof("DNSAttacker並列数: %d", r.benchmarker.attackParallelis)
+	if report := adaptive.CurrentReport(); report != nil {
+		lgr.Infof("持続できた最大のワーカー数: %d (終了時: %d, 増加: %d回, 減少: %d回)", report.PeakSustained, report.Final, report.Increases, report.Decreases)
+	}
+	lgr.Infof("名前解決成功数: %d", numResolves)
+	lgr.Infof("名前解決失敗数: %d", numDNSFailed)
+	lgr.Infof("不正な形式のDNSレスポンス数: %d", numDNSMalformed)
+	if numDNSMalformed > 0 {
+		msgs = append(msgs, fmt.Sprintf("不正な形式のDNSレスポンス数 %d", numDNSMalformed))
+	}
+	numDNSOversized := scores.GetByTag(benchscore.DNSOversized)
+	lgr.Infof("広告したサイズを超えるUDPのDNSレスポンス数: %d", numDNSOversized)
+	if numDNSOversized > 0 {
+		msgs = append(msgs, fmt.Sprintf("広告したサイズを超えるUDPのDNSレスポンス数 %d", numDNSOversized))
+	}
+	numDNSDualStackMismatch := scores.GetByTag(benchscore.DNSDualStackMismatch)
+	if numDNSDualStackMismatch > 0 {
+		lgr.Infof("AレコードとAAAAレコードが一致しない名前解決数: %d", numDNSDualStackMismatch)
+		msgs = append(msgs, fmt.Sprintf("AレコードとAAAAレコードが一致しない名前解決数 %d", numDNSDualStackMismatch))
+	}
+	numDNSTimeout := scores.GetByTag(benchscore.DNSTimeout)
+	lgr.Infof("名前解決のタイムアウト数: %d, SERVFAIL数: %d, NXDOMAIN数: %d", numDNSTimeout, scores.GetByTag(benchscore.DNSServFail), scores.GetByTag(benchscore.DNSNXDomain))
+	if numDNSTimeout > 0 {
+		msgs = append(msgs, fmt.Sprintf("名前解決のタイムアウト数 %d", numDNSTimeout))
+	}
+	numDNSCacheHit, numDNSCacheMiss := scores.GetByTag(benchscore.DNSCacheHit), scores.GetByTag(benchscore.DNSCacheMiss)
+	if lookups := numDNSCacheHit + numDNSCacheMiss; lookups > 0 {
+		lgr.Infof("名前解決のキャッシュ: ヒット %d, ミス %d (ヒット率 %.1f%%)", numDNSCacheHit, numDNSCacheMiss, float64(numDNSCacheHit)/float64(lookups)*100)
+	}
+	numDNSInsensibleTTL := scores.GetByTag(benchscore.DNSInsensibleTTL)
+	lgr.Infof("TTLが妥当な応答数: %d, 妥当でない応答数: %d", scores.GetByTag(benchscore.DNSSensibleTTL), numDNSInsensibleTTL)
+	if numDNSInsensibleTTL > 0 {
+		msgs = append(msgs, fmt.Sprintf("TTLが妥当な範囲(%s〜%s)にないDNS応答数 %d", config.DNSSensibleTTLMin, config.DNSSensibleTTLMax, numDNSInsensibleTTL))
+	}
+
+	lgr.Infof("レイド中のライブコメント投稿数: %d", scores.GetByTag(benchscore.RaidLivecomment))
+	lgr.Infof("レイド中のリアクション投稿数: %d", scores.GetByTag(benchscore.RaidReaction))
+	numModerationVerified := scores.GetByTag(benchscore.ModerationVerified)
+	msgs = append(msgs, fmt.Sprintf("猶予時間内に反映されたモデレーション数 %d", numModerationVerified))
+	lgr.Infof("猶予時間内に反映されたモデレーション数: %d", numModerationVerified)
+	numReactionBurstAccepted := scores.GetByTag(benchscore.ReactionBurstAccepted)
+	msgs = append(msgs, fmt.Sprintf("猶予時間内に一覧に反映された連投リアクション数 %d", numReactionBurstAccepted))
+	lgr.Infof("猶予時間内に一覧に反映された連投リアクション数: %d", numReactionBurstAccepted)
+
+	if report := chaos.CurrentReport(); report != nil {
+		msgs = append(msgs, fmt.Sprintf("chaosモードで走行しました (遅延 %d 件, 切断 %d 件)", report.Delayed, report.Dropped))
+		lgr.Warnf("chaosモード: リクエスト %d 件のうち、遅延 %d 件 (合計 %dms), 切断 %d 件", report.Requests, report.Delayed, report.TotalDelayMillis, report.Dropped)
+	}
+
+	if report := compression.CurrentReport(); report != nil {
+		msgs = append(msgs, fmt.Sprintf("レスポンスの圧縮による転送量の削減 %.1f%% (圧縮されたレスポンス %d/%d 件)", report.SavedRatio()*100, report.CompressedResponses, report.Responses))
+		lgr.Infof("レスポンスの転送量: %d bytes (展開後: %d bytes, 削減: %.1f%%)", report.WireBytes, report.DecodedBytes, report.SavedRatio()*100)
+		for _, stat := range report.Endpoints {
+			lgr.Debugf("圧縮: %s (%s) %d 件 %d bytes -> %d bytes", stat.Endpoint, stat.Encoding, stat.Responses, stat.WireBytes, stat.DecodedBytes)
+		}
+	}
+
+	spamCorpus := scheduler.LivecommentScheduler.SpamCorpusCoverage()
+	lgr.Infof("投稿したスパムコメントの種類: %d/%d, NGワード: %d/%d", spamCorpus.Comments, spamCorpus.TotalComments, spamCorpus.NgWords, spamCorpus.TotalNgWords)
+
+	numDNSProvisioned := scores.GetByTag(benchscore.DNSProvisioned)
+	msgs = append(msgs, fmt.Sprintf("猶予時間内に名前解決できた新規ユーザ数 %d", numDNSProvisioned))
+	lgr.Infof("猶予時間内に名前解決できた新規ユーザ数: %d, 否定応答がキャッシュされていた数: %d", numDNSProvisioned, scores.GetByTag(benchscore.DNSProvisioningStale))
+
+	numSessions, meanQuality, numZeroQualitySessions := scores.QualityStats()
+	msgs = append(msgs, fmt.Sprintf("視聴者の体感品質 平均 %.2f (%d 人)", meanQuality, numSessions))
+	lgr.Infof("視聴を終えた視聴者数: %d, 体感品質の平均: %.3f, 体感品質0の視聴者数: %d", numSessions, meanQuality, numZeroQualitySessions)
+	departures := churn.ViewerChurn.Departures()
+	lgr.Infof("途中で離脱した視聴者数: 遅延 %d, スパム %d", departures[churn.DepartureTooSlow], departures[churn.DepartureTooManySpam])
+
+	if coverage.Partial() {
+		skipped := coverage.Skipped()
+		msgs = append(msgs, fmt.Sprintf("一部のエンドポイントを除外した部分的な走行です (除外した範囲 %d 件)", len(skipped)))
+		for _, s := range skipped {
+			lgr.Infof("除外した範囲: %s", s)
+		}
+	}
+
+	// NOTE: 売上が正であっても、成功率の下限を下回ったシナリオがある場合は失格とする
+	violations := r.sloViolations()
+	if len(violations) > 0 {
+		var sloMsgs []string
+		for _, v := range violations {
+			msg := fmt.Sprintf("シナリオ %s の成功率 %.1f%% が下限 %.1f%% を下回りました (%d 回成功, %d 回失敗)", v.Scenario, v.Ratio*100, v.MinRatio*100, v.Success, v.Fail)
+			lgr.Warn(msg)
+			sloMsgs = append(sloMsgs, msg)
+		}
+		detail := errors.New(strings.Join(sloMsgs, ", "))
+		if !r.waive(disqualify.SLOViolation, detail, sloMsgs...) {
+			r.disqualify.Trigger(disqualify.SLOViolation, detail)
+			result := r.FailedResult(sloMsgs)
+			result.SLOViolations = violations
+			return result
+		}
+	}
+
+	profit := scores.TotalProfit()
+	finalScore := scores.Score()
+	penalty := keepAlivePenalty(finalScore, keepAlive)
+	finalScore -= penalty
+	var freeze *ScoreFreeze
+	if config.ScoreFreezeAfter > 0 {
+		// NOTE: 凍結後に得た売上とスコアは選手向けのメッセージに含めず、結果のスコアにのみ含める
+		frozen := scores.SnapshotBefore(config.ScoreFreezeAfter)
+		freeze = &ScoreFreeze{
+			After:  config.ScoreFreezeAfter,
+			Profit: frozen.Profit,
+			Score:  frozen.Score - keepAlivePenalty(frozen.Score, keepAlive),
+		}
+		msgs = append(msgs, fmt.Sprintf("売上: %d (走行開始から %s 以降の売上は表示していません)", freeze.Profit, freeze.After))
+		lgr.Infof("凍結した時点までの売上: %d, スコア: %d", freeze.Profit, freeze.Score)
+	} else {
+		msgs = append(msgs, fmt.Sprintf("売上: %d", profit))
+		if penalty > 0 {
+			msgs = append(msgs, fmt.Sprintf("Connection: close による減点 %d", penalty))
+		}
+	}
+	if penalty > 0 {
+		lgr.Infof("Connection: close による減点: %d", penalty)
+	}
+	lgr.Infof("売上: %d", profit)
+	lgr.Infof("スコア: %d", finalScore)
+
+	var explanation *ScoreExplanation
+	if config.ExplainScore {
+		explanation = &ScoreExplanation{
+			Contributions: scores.Contributions(),
+			Score:         finalScore,
+		}
+		if penalty > 0 {
+			explanation.Deductions = append(explanation.Deductions, ScoreDeduction{Reason: "Connection: close による減点", Points: penalty})
+		}
+		// NOTE: 凍結後に得たスコアを選手に見せないよう、スコアを凍結する場合は結果にのみ含める
+		if freeze != nil {
+			r.contestantLogger.Info("スコアを凍結しているため、スコアの内訳は表示しません")
+		} else {
+			explainScore(r.contestantLogger, explanation)
+		}
+	}
+
+	// NOTE: 練習モードで失格とせずに走行を続けた場合、スコアは参考値として返し、合格とはしない
+	waived := r.disqualify.Waived()
+	if config.Practice {
+		msgs = append([]string{practiceMessage}, msgs...)
+		for _, w := range waived {
+			msgs = append(msgs, fmt.Sprintf("練習モードのため失格としませんでした: %s (%s)", w.Rule.Message, w.Detail))
+		}
+	}
+	messages := append(benchErrors, msgs...)
+	if config.Sealed {
+		// NOTE: 封印モードではエラーの詳細を返さず、件数と要約のみとする
+		messages = msgs
+	}
+	result := &Result{
+		Pass:             len(waived) == 0,
+		Score:            finalScore,
+		Messages:         messages,
+		Language:         config.Language,
+		Server:           r.serverMetadata,
+		ResolvedCount:    numResolves,
+		ErrorCounts:      errorCounts,
+		Scenarios:        r.scenarioCounter.Rows(),
+		Profile:          config.LoadProfileName,
+		Calibration:      r.Calibration,
+		Partial:          coverage.Partial(),
+		SkippedCoverage:  coverage.Skipped(),
+		Seed:             benchrand.Seed(),
+		BotMarker:        config.BotMarkerEnabled,
+		Build:            version.Get(),
+		Targets:          topology.Report(),
+		Concurrency:      adaptive.CurrentReport(),
+		IdleTimeout:      r.idleTimeoutReport,
+		SlowLoris:        r.slowLorisReport,
+		Chaos:            chaos.CurrentReport(),
+		Compression:      compression.CurrentReport(),
+		Bandwidth:        r.logBandwidth(),
+		SpamCorpus:       spamCorpus,
+		KeepAlive:        keepAlive,
+		LogFallbacks:     logger.Fallbacks(),
+		ScoreFreeze:      freeze,
+		ScoreExplanation: explanation,
+		SLOViolations:    violations,
+		Practice:         config.Practice,
+		Waived:           waived,
+
+		InitializeDurationMillis: r.initializeDuration.Milliseconds(),
+	}
+	return result
+}
@@ -0,0 +1,260 @@
+package bench
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/isucon/isucon13/bench/internal/benchrand"
+	"github.com/isucon/isucon13/bench/internal/benchscore"
+	"github.com/isucon/isucon13/bench/internal/benchtrace"
+	"github.com/isucon/isucon13/bench/internal/calibrate"
+	"github.com/isucon/isucon13/bench/internal/churn"
+	"github.com/isucon/isucon13/bench/internal/config"
+	"github.com/isucon/isucon13/bench/internal/coverage"
+	"github.com/isucon/isucon13/bench/internal/journal"
+	"github.com/isucon/isucon13/bench/internal/mediacheck"
+	"github.com/isucon/isucon13/bench/internal/redact"
+	"github.com/isucon/isucon13/bench/internal/topology"
+	"github.com/isucon/isucon13/bench/scenario"
+	"go.uber.org/zap"
+)
+
+// Options は、Run の走行条件です
+// NOTE: ここにない走行条件は、CLIのオプションに対応する config パッケージの値を用いる. Run の前に設定しておく
+type Options struct {
+	// webappのURL (空の場合は config.TargetBaseURL)
+	Target string
+	// 負荷プロファイル名 (空の場合は config.LoadProfileName)
+	Profile string
+	// 負荷走行の時間 (0の場合は負荷プロファイルの時間)
+	Timeout time.Duration
+	// 選手向けのログの出力先 (nilの場合は出力しない)
+	ContestantLogger *zap.Logger
+	// 走行の段階の通知と負荷走行の締切の管理 (nilの場合は負荷走行の時間で打ち切る)
+	Control Control
+	// pretestのみ実施し、負荷走行を行わない
+	PretestOnly bool
+	// pretestの結果を受け取る (nilの場合は受け取らない). pretestに失敗した場合も、それまでの結果を渡す
+	OnPretestReport func(report *scenario.PretestReport)
+}
+
+// Run は、webappの初期化から最終チェックまでの1回の走行を行い、結果を返します
+// 失格とした走行も結果を返します. エラーは、走行条件の不備やベンチマーカー側の問題など、CLIが非0で終了すべき場合のみ返します
+// PretestOnly を指定した場合、pretestに成功すれば結果はnilです
+// NOTE: 結果の署名、ファイルへの書き出し、ポータルへの送信は行わない (呼び出し側で行う)
+func Run(ctx context.Context, opts Options) (*Result, error) {
+	contestantLogger := opts.ContestantLogger
+	if contestantLogger == nil {
+		contestantLogger = zap.NewNop()
+	}
+
+	// NOTE: 走行条件の検証・読み込みの前に、config パッケージの値を上書きする
+	if len(opts.Target) > 0 {
+		config.TargetBaseURL = opts.Target
+	}
+	if len(opts.Profile) > 0 {
+		config.LoadProfileName = opts.Profile
+	}
+	if opts.Timeout < 0 {
+		return nil, fmt.Errorf("負荷走行の時間が不正です: %s", opts.Timeout)
+	}
+
+	runner, cleanup, err := prepareRunner(ctx, contestantLogger, opts.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+	runner.Control = opts.Control
+
+	if err := runner.Initialize(ctx); err != nil {
+		return failedResult(runner, err)
+	}
+
+	report, err := runner.Pretest(ctx)
+	if opts.OnPretestReport != nil && report != nil {
+		opts.OnPretestReport(report)
+	}
+	if err != nil {
+		return failedResult(runner, err)
+	}
+
+	if opts.PretestOnly {
+		zap.S().Info("pretestのみ実施するため、ベンチマーク走行をスキップします")
+		return nil, nil
+	}
+
+	if err := runner.Load(ctx); err != nil {
+		return failedResult(runner, err)
+	}
+	if err := runner.Finalcheck(ctx); err != nil {
+		return failedResult(runner, err)
+	}
+
+	return runner.Report(), nil
+}
+
+// failedResult は、失敗した段階の結果を返します. ベンチマーカー側の問題や最終チェックの失敗ではエラーも返します
+func failedResult(runner *Runner, err error) (*Result, error) {
+	var phaseErr *PhaseError
+	if !errors.As(err, &phaseErr) {
+		return runner.FailedResult([]string{err.Error()}), err
+	}
+	result := runner.FailedResult(phaseErr.Messages)
+	if phaseErr.Fatal {
+		if phaseErr.Err != nil {
+			return result, phaseErr.Err
+		}
+		return result, phaseErr
+	}
+	return result, nil
+}
+
+// prepareRunner は、config パッケージの走行条件を検証・読み込みし、走行前の準備を行った Runner を返します
+// timeout が0でなければ、負荷プロファイルの負荷走行の時間に代えて用います. cleanup は走行後に呼び出します
+func prepareRunner(ctx context.Context, contestantLogger *zap.Logger, timeout time.Duration) (*Runner, func(), error) {
+	lgr := zap.S()
+	cleanup := func() {}
+
+	profile, err := config.CurrentLoadProfile()
+	if err != nil {
+		return nil, cleanup, err
+	}
+	if len(config.LoadConfigPath) > 0 {
+		if err := config.ApplyLoadConfig(config.LoadConfigPath, &profile); err != nil {
+			return nil, cleanup, err
+		}
+		lgr.Infof("負荷設定ファイルを読み込みました: %s", config.LoadConfigPath)
+	}
+	if timeout > 0 {
+		profile.Duration = timeout
+	}
+	if err := ValidatePacing(profile); err != nil {
+		return nil, cleanup, err
+	}
+	if err := ValidateSLOs(benchscore.SLOs()); err != nil {
+		return nil, cleanup, err
+	}
+	lgr.Infof("負荷プロファイル: %+v", profile)
+	if profile.Name != config.DefaultLoadProfile {
+		contestantLogger.Info("負荷プロファイルを変更して走行します. スコアは本番の競技環境と比較できません", zap.String("profile", profile.Name))
+	}
+
+	if config.Practice {
+		contestantLogger.Warn("練習モードで走行します. 失格となる検証の失敗も警告として出力して走行を続けます. 結果は公式のものとして扱われません")
+	}
+
+	if len(config.EndpointFilterPath) > 0 {
+		if err := coverage.LoadFilter(config.EndpointFilterPath); err != nil {
+			return nil, cleanup, err
+		}
+		lgr.Infof("エンドポイントの許可・除外リストを読み込みました: %s", config.EndpointFilterPath)
+		contestantLogger.Info("一部のエンドポイントを除外して走行します. 結果は部分的な走行として扱われます")
+	}
+
+	if config.Chaos {
+		lgr.Warnf("chaosモード: 負荷走行中のリクエストの %.0f%% に最大 %s の遅延を、%.0f%% に接続の切断を注入します", config.ChaosDelayRate*100, config.ChaosMaxDelay, config.ChaosDropRate*100)
+	}
+
+	var calibration *calibrate.Result
+	if config.Calibrate {
+		// NOTE: 負荷走行と干渉しないよう、webappへのアクセスを始める前に計測する
+		result, err := calibrate.Run(ctx, config.CalibrationDuration)
+		if err != nil {
+			return nil, cleanup, err
+		}
+		calibration = result
+		lgr.Infof("ホストの性能計測: %s", calibration)
+	}
+
+	seed := benchrand.Init(config.Seed)
+	lgr.Infof("乱数のシード: %d (--seed %d で同等の負荷を再現できます)", seed, seed)
+
+	if _, err := mediacheck.Get(config.IconComparator); err != nil {
+		return nil, cleanup, err
+	}
+	lgr.Infof("アイコン画像の比較方式: %s", config.IconComparator)
+
+	if err := churn.Init(); err != nil {
+		return nil, cleanup, err
+	}
+	lgr.Infof("視聴者の到着間隔[秒]: %s, 視聴時間の割合: %s, 遅延離脱を判断するリクエスト数: %d", churn.ViewerChurn.ArrivalDistribution(), churn.ViewerChurn.SessionDistribution(), config.ViewerAbandonWindow)
+
+	for tag, weight := range benchscore.Weights() {
+		lgr.Infof("配点 %s: %d", tag, weight)
+	}
+
+	if proxyURL, err := config.ParseProxyURL(); err != nil {
+		return nil, cleanup, err
+	} else if proxyURL != nil {
+		if password, ok := proxyURL.User.Password(); ok {
+			redact.Register(password)
+		}
+		lgr.Warnf("プロキシ %s を経由してwebappへリクエストします (名前解決はプロキシ側で行われます)", proxyURL.Redacted())
+	}
+	if err := config.ValidateInitializeParams(); err != nil {
+		return nil, cleanup, err
+	}
+	if err := config.ValidateFailFast(); err != nil {
+		return nil, cleanup, err
+	}
+	if err := config.ValidateKeepAlivePenalty(); err != nil {
+		return nil, cleanup, err
+	}
+	if err := config.ApplyTimeoutTiers(); err != nil {
+		return nil, cleanup, err
+	}
+	for _, name := range config.EndpointClassNames() {
+		lgr.Infof("リクエストのタイムアウト (%s): %s", name, config.CurrentTimeoutTier(config.EndpointClass(name)))
+	}
+	if config.ScoreFreezeAfter < 0 {
+		return nil, cleanup, fmt.Errorf("スコアを凍結するまでの時間(--score-freeze-after)が不正です: %s", config.ScoreFreezeAfter)
+	} else if config.ScoreFreezeAfter > 0 {
+		lgr.Infof("負荷走行の開始から %s 以降のスコアを選手向けの出力に含めません", config.ScoreFreezeAfter)
+	}
+	if bindIPs, err := config.ResolveBindAddress(); err != nil {
+		return nil, cleanup, err
+	} else if len(bindIPs) > 0 {
+		lgr.Infof("送信元のアドレス: %v", bindIPs)
+	}
+	idleProbePeriods, err := config.ParseIdleProbePeriods()
+	if err != nil {
+		return nil, cleanup, err
+	}
+	if config.HasInitializeParams() {
+		lgr.Infof("initializeで初期データの規模=%v, 機能フラグ=%v を要求します", config.InitializeScale, config.InitializeFeatureList())
+	}
+	if len(config.TargetsPath) > 0 {
+		if err := topology.Load(config.TargetsPath); err != nil {
+			return nil, cleanup, err
+		}
+		for _, report := range topology.Report() {
+			lgr.Infof("宛先: %s", report.Name)
+		}
+	}
+	lgr.Infof("統計情報検証の猶予期間: %s, リトライ回数: %d", config.StatsGracePeriod, config.StatsVerifyRetries)
+
+	benchtrace.InitTrace(config.MaxConcurrentTLSHandshakes)
+	if config.MaxConcurrentTLSHandshakes > 0 {
+		lgr.Infof("TLSハンドシェイク同時実行数の上限: %d", config.MaxConcurrentTLSHandshakes)
+	} else {
+		lgr.Info("TLSハンドシェイク同時実行数の上限: なし")
+	}
+
+	// NOTE: ジャーナルは走行の前後にまたがって記録するため、検証がすべて済んでから開く
+	if len(config.JournalPath) > 0 {
+		if err := journal.Open(config.JournalPath); err != nil {
+			return nil, cleanup, err
+		}
+		cleanup = func() { journal.Close() }
+		journal.RecordRun(journal.RunSeed, strconv.FormatInt(seed, 10))
+		lgr.Infof("シナリオのジャーナルを記録します: %s", config.JournalPath)
+	}
+
+	runner := NewRunner(contestantLogger, profile)
+	runner.IdleProbePeriods = idleProbePeriods
+	runner.Calibration = calibration
+	return runner, cleanup, nil
+}
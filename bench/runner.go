@@ -0,0 +1,428 @@
+// bench は、isupipeのベンチマーク走行を段階ごとに実行します
+//
+// CLI(cmd/bench)のほか、ポータルのワーカーや運営の検証ツールからライブラリとして組み込めるよう、
+// 初期化・整合性チェック・負荷走行・最終チェック・結果の集計を Runner のメソッドとして提供します
+package bench
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/isucon/isucandar/agent"
+	"github.com/isucon/isucandar/score"
+	"github.com/isucon/isucon13/bench/internal/adaptive"
+	"github.com/isucon/isucon13/bench/internal/bandwidth"
+	"github.com/isucon/isucon13/bench/internal/bencherror"
+	"github.com/isucon/isucon13/bench/internal/benchrand"
+	"github.com/isucon/isucon13/bench/internal/benchscore"
+	"github.com/isucon/isucon13/bench/internal/calibrate"
+	"github.com/isucon/isucon13/bench/internal/chaos"
+	"github.com/isucon/isucon13/bench/internal/compression"
+	"github.com/isucon/isucon13/bench/internal/config"
+	"github.com/isucon/isucon13/bench/internal/coverage"
+	"github.com/isucon/isucon13/bench/internal/disqualify"
+	"github.com/isucon/isucon13/bench/internal/failfast"
+	"github.com/isucon/isucon13/bench/internal/idleprobe"
+	"github.com/isucon/isucon13/bench/internal/resolver"
+	"github.com/isucon/isucon13/bench/internal/scheduler"
+	"github.com/isucon/isucon13/bench/internal/slowloris"
+	"github.com/isucon/isucon13/bench/isupipe"
+	"github.com/isucon/isucon13/bench/scenario"
+	"go.uber.org/zap"
+)
+
+// Phase は、走行の段階です
+type Phase string
+
+const (
+	PhaseInitialize Phase = "initialize"
+	PhasePretest    Phase = "pretest"
+	PhaseLoad       Phase = "load"
+	PhaseFinalcheck Phase = "finalcheck"
+	PhaseDone       Phase = "done"
+)
+
+// Phases は、走行の段階を実行順に並べたものです
+var Phases = []Phase{PhaseInitialize, PhasePretest, PhaseLoad, PhaseFinalcheck, PhaseDone}
+
+// Control は、走行の段階の通知と負荷走行の締切の管理を行います
+// NOTE: CLIでは管理APIから締切を延長・早期終了できるよう、独自の実装を用います
+type Control interface {
+	SetPhase(phase Phase)
+	// StartLoad は、負荷走行のcontextを返します. 締切は timeout 後です
+	StartLoad(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc)
+}
+
+type defaultControl struct{}
+
+func (defaultControl) SetPhase(Phase) {}
+
+func (defaultControl) StartLoad(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, timeout)
+}
+
+// PhaseError は、走行の段階が失敗したことを表します
+// Messages は選手向けの結果に記録するメッセージです
+type PhaseError struct {
+	Phase    Phase
+	Messages []string
+	Err      error
+	// ベンチマーカー側の問題や最終チェックの失敗など、CLIが非0で終了すべきもの
+	Fatal bool
+}
+
+func (e *PhaseError) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("%s: %v", e.Phase, e.Messages)
+	}
+	return fmt.Sprintf("%s: %s", e.Phase, e.Err.Error())
+}
+
+func (e *PhaseError) Unwrap() error {
+	return e.Err
+}
+
+// Runner は、1回のベンチマーク走行です
+// NOTE: 走行条件はCLIと同じく config パッケージの値を用います. スコアとエラーの集計は Runner ごとに持ち、contextで各段階に引き渡す
+type Runner struct {
+	contestantLogger *zap.Logger
+	profile          config.LoadProfile
+
+	// 走行の段階の通知と負荷走行の締切の管理 (nilの場合は負荷走行の時間で打ち切る)
+	Control Control
+	// 負荷走行と並行してアイドルタイムアウトを確かめる放置時間 (空の場合は確かめない)
+	IdleProbePeriods []time.Duration
+	// ホストの性能計測結果 (結果に記録する)
+	Calibration *calibrate.Result
+
+	// 初期化時に収集し、結果に記録する
+	serverMetadata     *isupipe.ServerMetadata
+	initializeDuration time.Duration
+	// 負荷走行後に記録する
+	benchmarker       *benchmarker
+	idleTimeoutReport *idleprobe.Report
+	slowLorisReport   *slowloris.Report
+	// エラーの予算を超えて負荷走行を打ち切った場合に記録する
+	failFast *failfast.Exceeded
+	// 該当した失格の判定規則
+	disqualify *disqualify.Engine
+	// 段階ごとのシナリオの成功・失敗回数
+	scenarioCounter *benchscore.ScenarioCounter
+	// 走行の段階ごとに作り直す、スコアとエラーの集計
+	scores *benchscore.ScoreSet
+	errs   *bencherror.ErrorSet
+	// pretestのスコアの集計 (pretestで送ったチップを最終チェックで突き合わせる)
+	pretestScores *benchscore.ScoreSet
+	// 初期化から最終チェックまでの転送量の集計 (初期化時に作る)
+	bandwidth *bandwidth.Meter
+}
+
+// NewRunner は、負荷プロファイルに従って走行する Runner を返します
+func NewRunner(contestantLogger *zap.Logger, profile config.LoadProfile) *Runner {
+	return &Runner{
+		contestantLogger: contestantLogger,
+		profile:          profile,
+		disqualify:       disqualify.NewEngine(),
+		scenarioCounter:  benchscore.NewScenarioCounter(),
+	}
+}
+
+// resetAccounting は、スコアとエラーの集計を作り直し、それらを紐づけたcontextを返します
+// NOTE: 前の段階の集計は終了せずに残るため、段階ごとの結果を読み出せる
+func (r *Runner) resetAccounting(ctx context.Context) context.Context {
+	r.scores = benchscore.NewScoreSet(ctx)
+	r.errs = bencherror.NewErrorSet()
+	ctx = bencherror.WithErrorSet(ctx, r.errs)
+	return benchscore.WithScoreSet(ctx, r.scores)
+}
+
+// Scores は、現在の段階のスコアの集計を返します
+func (r *Runner) Scores() *benchscore.ScoreSet {
+	return r.scores
+}
+
+// Errors は、現在の段階のエラーの記録を返します
+func (r *Runner) Errors() *bencherror.ErrorSet {
+	return r.errs
+}
+
+// accountedScores, accountedErrors は、結果に用いる集計を返します
+// NOTE: 初期化の前に失敗した場合は、空の集計を用いる
+func (r *Runner) accountedScores() *benchscore.ScoreSet {
+	if r.scores == nil {
+		// NOTE: 集計しないため、カウンタの集計を終えたcontextで作る
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		r.scores = benchscore.NewScoreSet(ctx)
+	}
+	return r.scores
+}
+
+func (r *Runner) accountedErrors() *bencherror.ErrorSet {
+	if r.errs == nil {
+		r.errs = bencherror.NewErrorSet()
+	}
+	return r.errs
+}
+
+func (r *Runner) control() Control {
+	if r.Control == nil {
+		return defaultControl{}
+	}
+	return r.Control
+}
+
+// Initialize は、webappの初期化(POST /api/initialize)を行います
+func (r *Runner) Initialize(ctx context.Context) error {
+	lgr := zap.S()
+
+	r.control().SetPhase(PhaseInitialize)
+	r.bandwidth = bandwidth.NewMeter()
+	ctx = r.resetAccounting(ctx)
+
+	// FIXME: アセット読み込み
+	r.contestantLogger.Info("静的ファイルチェックを行います")
+	r.contestantLogger.Info("静的ファイルチェックが完了しました")
+
+	r.contestantLogger.Info("webappの初期化を行います")
+	initClient, err := isupipe.NewClient(ctx, r.contestantLogger,
+		agent.WithBaseURL(config.TargetBaseURL),
+		agent.WithTimeout(config.CurrentTimeoutTier(config.EndpointClassInitialize).Timeout),
+	)
+	if err != nil {
+		return &PhaseError{Phase: PhaseInitialize, Messages: []string{"webapp初期化クライアント生成が失敗しました"}, Err: err, Fatal: true}
+	}
+
+	initializeResp, err := initClient.Initialize(ctx)
+	if err != nil {
+		return &PhaseError{Phase: PhaseInitialize, Messages: []string{"初期化が失敗しました", err.Error()}, Err: r.disqualify.Trigger(disqualify.InitializeFailed, err)}
+	}
+	config.Language = initializeResp.Language
+	scenario.InitializeDataset = initializeResp.Dataset
+	r.initializeDuration = initializeResp.Duration
+	lgr.Infof("initializeの所要時間: %s", r.initializeDuration)
+	r.contestantLogger.Info("webappの初期化が完了しました", zap.Duration("duration", r.initializeDuration))
+	if r.initializeDuration > config.InitializeTimeLimit {
+		detail := fmt.Errorf("制限時間: %s, 所要時間: %s", config.InitializeTimeLimit, r.initializeDuration)
+		msg := fmt.Sprintf("初期化処理が制限時間(%s)を超過しました (所要時間: %s)", config.InitializeTimeLimit, r.initializeDuration)
+		if !r.waive(disqualify.InitializeTimeout, detail, msg) {
+			return &PhaseError{Phase: PhaseInitialize, Messages: []string{msg}, Err: r.disqualify.Trigger(disqualify.InitializeTimeout, detail)}
+		}
+	}
+	r.serverMetadata = &initializeResp.Metadata
+	lgr.Infof("webapp: language=%s, server=%s, proto=%s, tls=%s %s %s",
+		config.Language, r.serverMetadata.Server, r.serverMetadata.Proto,
+		r.serverMetadata.TLSVersion, r.serverMetadata.TLSCipherSuite, r.serverMetadata.TLSNegotiatedProtocol)
+
+	return nil
+}
+
+// Pretest は、負荷走行前のデータ整合性チェックを行います. 失敗した場合も、それまでの結果を返します
+func (r *Runner) Pretest(ctx context.Context) (*scenario.PretestReport, error) {
+	r.contestantLogger.Info("ベンチマーク走行前のデータ整合性チェックを行います")
+
+	pretestDNSResolver := resolver.NewDNSResolver()
+	pretestDNSResolver.ResolveAttempts = 10
+
+	// NOTE: pretestにはこれら初期化が必要
+	ctx = r.resetAccounting(ctx)
+	r.pretestScores = r.scores
+	r.control().SetPhase(PhasePretest)
+	report, err := scenario.Pretest(ctx, r.contestantLogger, pretestDNSResolver)
+	r.countPretestChecks(report)
+	if err != nil {
+		if !r.waive(disqualify.PretestFailed, err, failedPretestChecks(report)...) {
+			r.errs.Done()
+			return report, &PhaseError{Phase: PhasePretest, Messages: []string{"整合性チェックに失敗しました", err.Error()}, Err: r.disqualify.Trigger(disqualify.PretestFailed, err)}
+		}
+	} else {
+		r.contestantLogger.Info("整合性チェックが成功しました")
+	}
+	probeConformance(ctx, pretestDNSResolver)
+	return report, nil
+}
+
+// failedPretestChecks は、失敗したpretestのチェックとその詳細を返します
+func failedPretestChecks(report *scenario.PretestReport) []string {
+	if report == nil {
+		return nil
+	}
+	var msgs []string
+	for _, check := range report.Checks {
+		if check.Status == scenario.PretestCheckFail {
+			msgs = append(msgs, fmt.Sprintf("整合性チェック %s (%s) に失敗しました: %s", check.Name, check.Description, check.Detail))
+		}
+	}
+	return msgs
+}
+
+// countPretestChecks は、pretestのチェックごとの成否をシナリオの実行回数として数えます
+// NOTE: 先行するチェックの失敗により実施されなかったチェックは数えない
+func (r *Runner) countPretestChecks(report *scenario.PretestReport) {
+	if report == nil {
+		return
+	}
+	counter := r.scenarioCounter.Phase(benchscore.ScenarioPhasePretest)
+	for _, check := range report.Checks {
+		switch check.Status {
+		case scenario.PretestCheckPass:
+			counter.Succeed(score.ScoreTag(check.Name))
+		case scenario.PretestCheckFail:
+			counter.Fail(score.ScoreTag(check.Name))
+		}
+	}
+}
+
+// Load は、負荷走行を行います. 負荷プロファイルの走行時間が経過するか、Control によって打ち切られるまで実行します
+func (r *Runner) Load(ctx context.Context) error {
+	lgr := zap.S()
+
+	r.contestantLogger.Info("ベンチマーク走行を開始します")
+	benchStartAt := time.Now()
+
+	// NOTE: benchmarkにはこれら初期化が必要
+	ctx = r.resetAccounting(ctx)
+	failfast.Reset()
+	compression.Reset()
+	scheduler.LivecommentScheduler.ResetSpamCorpusCoverage()
+
+	benchCtx, cancelBench := r.control().StartLoad(ctx, r.profile.Duration)
+	defer cancelBench()
+
+	r.benchmarker = newBenchmarker(benchCtx, r.contestantLogger, r.profile, r.scenarioCounter)
+	r.benchmarker.waiveViolation = func(err error) bool {
+		return r.waive(disqualify.LoadAborted, err)
+	}
+	if r.benchmarker.concurrency != nil {
+		adaptive.Set(r.benchmarker.concurrency)
+	}
+	var prober *idleProber
+	if len(r.IdleProbePeriods) > 0 {
+		prober = startIdleProbe(benchCtx, r.IdleProbePeriods, resolver.NewDNSResolver())
+	}
+	var loris *slowLoris
+	if config.SlowLoris {
+		loris = startSlowLoris(benchCtx, resolver.NewDNSResolver())
+	}
+	if config.Chaos {
+		chaos.Start(chaos.Config{
+			DelayRate: config.ChaosDelayRate,
+			MaxDelay:  config.ChaosMaxDelay,
+			DropRate:  config.ChaosDropRate,
+		}, benchrand.Seed())
+		defer chaos.Stop()
+	}
+	if err := r.benchmarker.run(benchCtx); err != nil {
+		lgr.Warnf("ベンチマーク中断: %s", err.Error())
+		r.errs.Done()
+		rule := disqualify.LoadAborted
+		var exceeded *failfast.Exceeded
+		if errors.As(err, &exceeded) {
+			r.failFast = exceeded
+			rule = failFastRule(exceeded)
+		}
+		return &PhaseError{Phase: PhaseLoad, Messages: []string{"ベンチマーク走行が中断されました", err.Error()}, Err: r.disqualify.Trigger(rule, err)}
+	}
+	if prober != nil {
+		r.idleTimeoutReport = prober.report(r.contestantLogger)
+	}
+	if loris != nil {
+		r.slowLorisReport = loris.report(r.contestantLogger)
+	}
+
+	benchElapsed := time.Since(benchStartAt)
+	lgr.Infof("ベンチマーク走行時間: %s", benchElapsed.String())
+
+	r.scores.Done()
+	r.errs.Done()
+	r.contestantLogger.Info("ベンチマーク走行終了")
+	return nil
+}
+
+// Finalcheck は、負荷走行後の最終チェックを行います
+func (r *Runner) Finalcheck(ctx context.Context) error {
+	lgr := zap.S()
+
+	r.contestantLogger.Info("最終チェックを実施します")
+	r.control().SetPhase(PhaseFinalcheck)
+	finalcheckDNSResolver := resolver.NewDNSResolver()
+	finalcheckDNSResolver.ResolveAttempts = 10
+	counter := r.scenarioCounter.Phase(benchscore.ScenarioPhaseFinal)
+	// NOTE: 負荷走行の集計と突き合わせる
+	ctx = benchscore.WithScoreSet(ctx, r.accountedScores())
+	ctx = bencherror.WithErrorSet(ctx, r.accountedErrors())
+	ctx = bandwidth.WithMeter(ctx, r.bandwidth)
+	if err := scenario.FinalcheckScenario(ctx, r.contestantLogger, finalcheckDNSResolver, r.accumulatedTips()); coverage.IsSkippedError(err) {
+		endpoint, _ := coverage.SkippedEndpoint(err)
+		coverage.MarkSkipped("最終チェック", endpoint)
+		r.contestantLogger.Info("除外されたエンドポイントを利用するため、最終チェックの残りを実施しません")
+	} else if err != nil {
+		var driftMsgs []string
+		var finalcheckErr *scenario.FinalcheckError
+		if errors.As(err, &finalcheckErr) {
+			driftMsgs = finalcheckErr.Messages(config.FinalcheckDriftMessages)
+			lgr.Infof("最終チェックのデータの食い違い(全%d件)を %s に書き出しました", len(finalcheckErr.Drifts), config.FinalcheckDriftPath)
+		}
+		counter.Fail(FinalcheckScenario)
+		if !r.waive(disqualify.FinalcheckFailed, err, driftMsgs...) {
+			msgs := append([]string{"最終チェックに失敗しました", err.Error()}, driftMsgs...)
+			return &PhaseError{Phase: PhaseFinalcheck, Messages: msgs, Err: r.disqualify.Trigger(disqualify.FinalcheckFailed, err), Fatal: true}
+		}
+		r.control().SetPhase(PhaseDone)
+		return nil
+	}
+	counter.Succeed(FinalcheckScenario)
+	r.contestantLogger.Info("最終チェックが成功しました")
+	r.control().SetPhase(PhaseDone)
+	return nil
+}
+
+// accumulatedTips は、pretestと負荷走行を通じて送ったチップの台帳の合計を返します
+// NOTE: webappの売上合計には、pretestで送ったチップも含まれるため
+func (r *Runner) accumulatedTips() benchscore.TipLedgerEntry {
+	tips := r.accountedScores().TotalTips()
+	if r.pretestScores != nil && r.pretestScores != r.scores {
+		tips = tips.Merge(r.pretestScores.TotalTips())
+	}
+	return tips
+}
+
+// failFastRule は、負荷走行を打ち切った条件に対応する失格の判定規則を返します
+func failFastRule(exceeded *failfast.Exceeded) disqualify.Rule {
+	switch exceeded.Condition {
+	case failfast.ConditionErrors:
+		return disqualify.ErrorBudget
+	case failfast.ConditionServerErrorRate:
+		return disqualify.ServerErrorRate
+	default:
+		return disqualify.LoadAborted
+	}
+}
+
+// waive は、練習モードであれば、失格の判定規則に該当したことを詳細とともに警告として出力し、走行を続けるかを返します
+// NOTE: 練習モードでない場合や、走行を続けられない判定規則の場合はfalseを返し、呼び出し側で失格とする
+func (r *Runner) waive(rule disqualify.Rule, err error, details ...string) bool {
+	if !config.Practice {
+		return false
+	}
+	waived, ok := r.disqualify.Waive(rule, err)
+	if !ok {
+		return false
+	}
+	r.contestantLogger.Warn("練習モードのため、失格とせずに走行を続けます",
+		zap.String("rule", string(waived.Rule.ID)),
+		zap.String("message", waived.Rule.Message),
+		zap.String("detail", waived.Detail),
+	)
+	for _, detail := range details {
+		r.contestantLogger.Warn(detail)
+	}
+	return true
+}
+
+// DisqualifiedBy は、該当した失格の判定規則のIDを返します
+func (r *Runner) DisqualifiedBy() []disqualify.RuleID {
+	return r.disqualify.RuleIDs()
+}
@@ -2,18 +2,32 @@ package scenario
 
 import (
 	"context"
+	"encoding/json"
 	"os"
 
 	"github.com/isucon/isucandar/agent"
+	"github.com/isucon/isucon13/bench/internal/benchscore"
 	"github.com/isucon/isucon13/bench/internal/config"
 	"github.com/isucon/isucon13/bench/internal/resolver"
+	"github.com/isucon/isucon13/bench/internal/scheduler"
 	"github.com/isucon/isucon13/bench/isupipe"
 	"go.uber.org/zap"
 )
 
-func FinalcheckScenario(ctx context.Context, contestantLogger *zap.Logger, dnsResolver *resolver.DNSResolver) error {
+// FinalcheckResult は、最終チェックの結果です (config.FinalcheckPath に書き出します)
+type FinalcheckResult struct {
+	Payment *PaymentReconciliation `json:"payment,omitempty"`
+	Tips    *TipReconciliation     `json:"tips,omitempty"`
+	Stats   *StatsReconciliation   `json:"stats,omitempty"`
+}
+
+// FinalcheckScenario は、負荷走行後のwebappの売上・統計情報を、ベンチマーカーの記録と突き合わせます
+// paymentLedger は、pretestと負荷走行を通じて送ったチップの台帳の合計です. webappの売上合計と突き合わせます
+func FinalcheckScenario(ctx context.Context, contestantLogger *zap.Logger, dnsResolver *resolver.DNSResolver, paymentLedger benchscore.TipLedgerEntry) error {
+	lgr := zap.S()
 
 	client, err := isupipe.NewCustomResolverClient(
+		ctx,
 		contestantLogger,
 		dnsResolver,
 		agent.WithTimeout(config.FinalcheckTimeout),
@@ -22,12 +36,68 @@ func FinalcheckScenario(ctx context.Context, contestantLogger *zap.Logger, dnsRe
 		return err
 	}
 
+	// NOTE: 統計情報の取得にはログインが必要
+	user, err := scheduler.UserScheduler.GetInitialUserForPretest(1)
+	if err != nil {
+		return err
+	}
+	if err := client.Login(ctx, &isupipe.LoginRequest{
+		Username: user.Name,
+		Password: user.RawPassword,
+	}); err != nil {
+		return err
+	}
+
 	// FIXME: ライブコメント存在チェック
-	_ = client
 
-	if err := os.WriteFile(config.FinalcheckPath, []byte("{}"), os.ModePerm); err != nil {
+	var result FinalcheckResult
+	payment, err := reconcilePayment(ctx, client, paymentLedger)
+	if err != nil {
 		return err
 	}
+	result.Payment = payment
+	lgr.Infof("finalcheck: %s: ledger=%+v, profit=%d, in_flight=%d, actual=%d, ok=%v", payment.Target, payment.Ledger, payment.Profit, payment.InFlight, payment.Actual, payment.OK)
+
+	tips, err := reconcileTips(ctx, client)
+	if err != nil {
+		return err
+	}
+	result.Tips = tips
+	for _, c := range tips.Streamers {
+		lgr.Infof("finalcheck: %s: ledger=%+v, actual=%d, ok=%v", c.Target, c.Ledger, c.Actual, c.OK)
+	}
+
+	stats, err := reconcileStats(ctx, client)
+	if err != nil {
+		return err
+	}
+	result.Stats = stats
+	for _, c := range append(stats.Livestreams, stats.Streamers...) {
+		lgr.Infof("finalcheck: %s: %s: expected=%d〜%d, actual=%d, ok=%v", c.Target, c.Field, c.Lower, c.Upper, c.Actual, c.OK)
+	}
+
+	b, err := json.Marshal(&result)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(config.FinalcheckPath, b, os.ModePerm); err != nil {
+		return err
+	}
+
+	var drifts []Drift
+	drifts = append(drifts, payment.Drifts...)
+	drifts = append(drifts, tips.Drifts...)
+	drifts = append(drifts, stats.Drifts...)
+	if len(drifts) > 0 {
+		b, err := json.Marshal(drifts)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(config.FinalcheckDriftPath, b, os.ModePerm); err != nil {
+			lgr.Warnf("最終チェックの食い違いの書き出しに失敗しました: %s", err.Error())
+		}
+		return &FinalcheckError{Drifts: drifts}
+	}
 
 	return nil
 }
@@ -0,0 +1,74 @@
+package scenario
+
+import (
+	"fmt"
+)
+
+// 食い違いの対象
+const (
+	DriftEntityPayment    = "payment"
+	DriftEntityUser       = "user"
+	DriftEntityLivestream = "livestream"
+)
+
+// Drift は、ベンチマーカーの記録から期待される値とwebappが返した値の食い違いです
+type Drift struct {
+	Entity   string `json:"entity"`
+	ID       string `json:"id"`
+	Field    string `json:"field"`
+	Expected string `json:"expected"`
+	Actual   string `json:"actual"`
+}
+
+func driftLabel(entity, id string) string {
+	switch entity {
+	case DriftEntityPayment:
+		return "売上"
+	case DriftEntityUser:
+		return fmt.Sprintf("配信者 %s", id)
+	case DriftEntityLivestream:
+		return fmt.Sprintf("配信 %s", id)
+	default:
+		return fmt.Sprintf("%s %s", entity, id)
+	}
+}
+
+func (d Drift) String() string {
+	return fmt.Sprintf("%s の %s が一致しません (expected: %s, actual: %s)", driftLabel(d.Entity, d.ID), d.Field, d.Expected, d.Actual)
+}
+
+// newRangeDrift は、期待される値が範囲で表される食い違いを生成します
+func newRangeDrift(entity, id, field string, lower, upper, actual int64) Drift {
+	expected := fmt.Sprintf("%d〜%d", lower, upper)
+	if lower == upper {
+		expected = fmt.Sprint(lower)
+	}
+	return Drift{
+		Entity:   entity,
+		ID:       id,
+		Field:    field,
+		Expected: expected,
+		Actual:   fmt.Sprint(actual),
+	}
+}
+
+// FinalcheckError は、最終チェックで見つかったデータの食い違いです
+type FinalcheckError struct {
+	Drifts []Drift
+}
+
+func (e *FinalcheckError) Error() string {
+	return fmt.Sprintf("最終チェックで %d 件のデータの食い違いが見つかりました", len(e.Drifts))
+}
+
+// Messages は、選手向けに先頭 n 件の食い違いを返します
+func (e *FinalcheckError) Messages(n int) []string {
+	msgs := make([]string, 0, min(len(e.Drifts), n)+1)
+	for _, d := range e.Drifts[:min(len(e.Drifts), n)] {
+		msgs = append(msgs, d.String())
+	}
+	if rest := len(e.Drifts) - n; rest > 0 {
+		msgs = append(msgs, fmt.Sprintf("ほか %d 件の食い違いがあります", rest))
+	}
+	return msgs
+}
@@ -0,0 +1,43 @@
+package scenario
+
+import (
+	"context"
+
+	"github.com/isucon/isucon13/bench/internal/benchscore"
+	"github.com/isucon/isucon13/bench/isupipe"
+)
+
+// PaymentReconciliation は、webappの売上合計とベンチマーカーのチップの台帳の突き合わせ結果です
+type PaymentReconciliation struct {
+	TipCheck
+	// 負荷走行で売上としてスコアに加算したチップの合計
+	Profit uint64 `json:"profit"`
+	// 送信したが成功を確認できなかったチップの合計 (許容範囲に含める)
+	InFlight uint64  `json:"in_flight"`
+	Drifts   []Drift `json:"drifts"`
+}
+
+// reconcilePayment は、webappの売上合計(GET /api/payment)が、ベンチマーカーが送ったチップの台帳から想定される範囲に収まっているか確かめます
+// ledger は、pretestと負荷走行を通じて送ったチップの台帳の合計です
+// NOTE: 売上合計が台帳を下回る場合はスコアに加算した売上をwebappが失っており、上回る場合は送っていないチップを計上している
+func reconcilePayment(ctx context.Context, client *isupipe.Client, ledger benchscore.TipLedgerEntry) (*PaymentReconciliation, error) {
+	payment, err := client.GetPaymentResult(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &PaymentReconciliation{
+		TipCheck: TipCheck{
+			Target: driftLabel(DriftEntityPayment, ""),
+			Ledger: ledger,
+			Actual: payment.TotalTip,
+			OK:     withinTipTolerance(payment.TotalTip, ledger),
+		},
+		Profit:   benchscore.GetTotalProfit(ctx),
+		InFlight: ledger.Unconfirmed(),
+	}
+	if !r.OK {
+		r.Drifts = append(r.Drifts, newRangeDrift(DriftEntityPayment, "", "total_tip", int64(ledger.Retained()), int64(ledger.Attempted), payment.TotalTip))
+	}
+	return r, nil
+}
@@ -0,0 +1,101 @@
+package scenario
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/isucon/isucon13/bench/internal/benchscore"
+	"github.com/isucon/isucon13/bench/internal/config"
+	"github.com/isucon/isucon13/bench/internal/scheduler"
+	"github.com/isucon/isucon13/bench/isupipe"
+)
+
+// StatsCheck は、操作の記録から再計算した値とwebappの統計情報を1項目突き合わせた結果です
+type StatsCheck struct {
+	Target string `json:"target"`
+	Field  string `json:"field"`
+	Lower  int64  `json:"lower"`
+	Upper  int64  `json:"upper"`
+	Actual int64  `json:"actual"`
+	OK     bool   `json:"ok"`
+}
+
+// StatsReconciliation は、操作の記録とwebappの統計情報の突き合わせ結果です
+type StatsReconciliation struct {
+	Livestreams []StatsCheck `json:"livestreams"`
+	Streamers   []StatsCheck `json:"streamers"`
+	Drifts      []Drift      `json:"drifts"`
+}
+
+func (r *StatsReconciliation) check(entity, id, field string, lower, upper, actual int64) StatsCheck {
+	slack := int64(math.Ceil(float64(lower) * config.StatsReconciliationTolerance))
+	c := StatsCheck{
+		Target: driftLabel(entity, id),
+		Field:  field,
+		Lower:  lower,
+		Upper:  upper,
+		Actual: actual,
+		OK:     lower-slack <= actual && actual <= upper+slack,
+	}
+	if !c.OK {
+		r.Drifts = append(r.Drifts, newRangeDrift(entity, id, field, lower, upper, actual))
+	}
+	return c
+}
+
+// reconcileStats は、ベンチマーカーが行ったリアクション・ライブコメント・スパム報告・入退室の記録から統計情報を再計算し、webappの統計情報と突き合わせます
+// NOTE: 初期データを含む配信・配信者は、ベンチマーカーが操作する前の値を正確に知り得ないため対象外とする. pretestと最終チェックで用いる
+func reconcileStats(ctx context.Context, client *isupipe.Client) (*StatsReconciliation, error) {
+	r := &StatsReconciliation{}
+
+	var livestreams []benchscore.LivestreamActivityEntry
+	for _, e := range benchscore.LivestreamActivities(ctx) {
+		if len(livestreams) >= config.StatsReconciliationSamples {
+			break
+		}
+		if _, err := scheduler.StatsSched.GetLivestreamStats(e.LivestreamID); err == nil {
+			continue
+		}
+		livestreams = append(livestreams, e)
+	}
+	for _, e := range livestreams {
+		stats, err := client.GetLivestreamStatistics(ctx, e.LivestreamID, e.Streamer)
+		if err != nil {
+			return nil, err
+		}
+		id := fmt.Sprint(e.LivestreamID)
+		viewersLower, viewersUpper := e.ViewersRange()
+		r.Livestreams = append(r.Livestreams,
+			r.check(DriftEntityLivestream, id, "total_reactions", e.Reactions.Confirmed, e.Reactions.Attempted, stats.TotalReactions),
+			r.check(DriftEntityLivestream, id, "total_reports", e.Reports.Confirmed, e.Reports.Attempted, stats.TotalReports),
+			r.check(DriftEntityLivestream, id, "viewers_count", viewersLower, viewersUpper, stats.ViewersCount),
+		)
+	}
+
+	var streamers []benchscore.StreamerActivityEntry
+	for _, e := range benchscore.StreamerActivities(ctx) {
+		if len(streamers) >= config.StatsReconciliationSamples {
+			break
+		}
+		if _, err := scheduler.StatsSched.GetUserStats(e.Streamer); err == nil {
+			continue
+		}
+		streamers = append(streamers, e)
+	}
+	for _, e := range streamers {
+		stats, err := client.GetUserStatistics(ctx, e.Streamer)
+		if err != nil {
+			return nil, err
+		}
+		// NOTE: スパムはモデレーションで削除されうるため、反映されていてもいなくても許容する
+		viewersLower, viewersUpper := e.ViewersRange()
+		r.Streamers = append(r.Streamers,
+			r.check(DriftEntityUser, e.Streamer, "total_reactions", e.Reactions.Confirmed, e.Reactions.Attempted, stats.TotalReactions),
+			r.check(DriftEntityUser, e.Streamer, "total_livecomments", e.Livecomments.Confirmed, e.Livecomments.Attempted+e.SpamLivecomments.Attempted, stats.TotalLivecomments),
+			r.check(DriftEntityUser, e.Streamer, "viewers_count", viewersLower, viewersUpper, stats.ViewersCount),
+		)
+	}
+
+	return r, nil
+}
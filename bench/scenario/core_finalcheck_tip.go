@@ -0,0 +1,123 @@
+package scenario
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/isucon/isucon13/bench/internal/benchscore"
+	"github.com/isucon/isucon13/bench/internal/config"
+	"github.com/isucon/isucon13/bench/isupipe"
+)
+
+// TipCheck は、売上の台帳とwebappの統計情報を1項目突き合わせた結果です
+type TipCheck struct {
+	Target string                    `json:"target"`
+	Ledger benchscore.TipLedgerEntry `json:"ledger"`
+	Actual int64                     `json:"actual"`
+	OK     bool                      `json:"ok"`
+}
+
+// TipReconciliation は、売上の台帳とwebappの統計情報の突き合わせ結果です
+type TipReconciliation struct {
+	Streamers   []TipCheck `json:"streamers"`
+	Livestreams []TipCheck `json:"livestreams"`
+	// 統計情報の順位とスコア(リアクション数+チップ合計)の食い違い
+	Ranking []string `json:"ranking"`
+	Drifts  []Drift  `json:"drifts"`
+}
+
+// withinTipTolerance は、webappが集計したチップ合計が台帳から想定される範囲に収まっているかを返します
+// NOTE: モデレーションで削除されたチップは、下限から除く
+func withinTipTolerance(actual int64, e benchscore.TipLedgerEntry) bool {
+	slack := uint64(math.Ceil(float64(e.Confirmed) * config.TipReconciliationTolerance))
+	lower := int64(e.Retained()) - int64(slack)
+	upper := int64(e.Attempted + slack)
+	return lower <= actual && actual <= upper
+}
+
+func (r *TipReconciliation) check(entity, id string, e benchscore.TipLedgerEntry, actual int64) TipCheck {
+	c := TipCheck{
+		Target: driftLabel(entity, id),
+		Ledger: e,
+		Actual: actual,
+		OK:     withinTipTolerance(actual, e),
+	}
+	if !c.OK {
+		r.Drifts = append(r.Drifts, newRangeDrift(entity, id, "total_tip", int64(e.Retained()), int64(e.Attempted), actual))
+	}
+	return c
+}
+
+// reconcileTips は、ベンチマーカーが送ったチップの台帳と、webappの統計情報を突き合わせます
+// NOTE: 全件の突き合わせはリクエスト数が多くなるため、配信者・配信はチップの多いものに絞る
+func reconcileTips(ctx context.Context, client *isupipe.Client) (*TipReconciliation, error) {
+	r := &TipReconciliation{}
+
+	type rankedStreamer struct {
+		name  string
+		rank  int64
+		score int64
+	}
+	var ranked []rankedStreamer
+	streamers := benchscore.StreamerTips(ctx)
+	for _, e := range streamers[:min(len(streamers), config.TipReconciliationSamples)] {
+		stats, err := client.GetUserStatistics(ctx, e.Streamer)
+		if err != nil {
+			return nil, err
+		}
+		r.Streamers = append(r.Streamers, r.check(DriftEntityUser, e.Streamer, e.TipLedgerEntry, stats.TotalTip))
+		ranked = append(ranked, rankedStreamer{
+			name:  e.Streamer,
+			rank:  stats.Rank,
+			score: stats.TotalReactions + stats.TotalTip,
+		})
+	}
+
+	// 順位が上の配信者のスコアは、順位が下の配信者のスコア以上でなければならない
+	sort.Slice(ranked, func(i, j int) bool {
+		return ranked[i].rank < ranked[j].rank
+	})
+	for i := 1; i < len(ranked); i++ {
+		upper, lower := ranked[i-1], ranked[i]
+		if upper.rank == lower.rank || upper.score < lower.score {
+			msg := fmt.Sprintf("配信者 %s (%d位, スコア %d) と %s (%d位, スコア %d) の順位が統計情報と矛盾しています", upper.name, upper.rank, upper.score, lower.name, lower.rank, lower.score)
+			r.Ranking = append(r.Ranking, msg)
+			r.Drifts = append(r.Drifts, Drift{
+				Entity:   DriftEntityUser,
+				ID:       lower.name,
+				Field:    "rank",
+				Expected: fmt.Sprintf("%d位 (%s) より下位かつスコア %d 以下", upper.rank, upper.name, upper.score),
+				Actual:   fmt.Sprintf("%d位, スコア %d", lower.rank, lower.score),
+			})
+		}
+	}
+
+	livestreams := benchscore.LivestreamTips(ctx)
+	for _, e := range livestreams[:min(len(livestreams), config.TipReconciliationSamples)] {
+		stats, err := client.GetLivestreamStatistics(ctx, e.LivestreamID, e.Streamer)
+		if err != nil {
+			return nil, err
+		}
+		// NOTE: 配信の統計情報にはチップ合計がないため、最大チップを突き合わせる
+		// 最大チップのライブコメントが削除されたかは分からないため、モデレーションがあった配信は下限を設けない
+		id := fmt.Sprint(e.LivestreamID)
+		lower := int64(e.MaxConfirmed)
+		if e.Moderated > 0 {
+			lower = 0
+		}
+		c := TipCheck{
+			Target: driftLabel(DriftEntityLivestream, id),
+			Ledger: e.TipLedgerEntry,
+			Actual: stats.MaxTip,
+			OK:     lower <= stats.MaxTip && stats.MaxTip <= int64(e.MaxAttempted),
+		}
+		if !c.OK {
+			r.Drifts = append(r.Drifts, newRangeDrift(DriftEntityLivestream, id, "max_tip", lower, int64(e.MaxAttempted), stats.MaxTip))
+		}
+		r.Livestreams = append(r.Livestreams, c)
+	}
+
+	return r, nil
+}
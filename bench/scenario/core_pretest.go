@@ -2,10 +2,13 @@ package scenario
 
 import (
 	"context"
-	"math/rand"
+	"errors"
+	"fmt"
 
 	"github.com/isucon/isucandar/agent"
+	"github.com/isucon/isucon13/bench/internal/benchrand"
 	"github.com/isucon/isucon13/bench/internal/config"
+	"github.com/isucon/isucon13/bench/internal/coverage"
 	"github.com/isucon/isucon13/bench/internal/resolver"
 	"github.com/isucon/isucon13/bench/isupipe"
 	"github.com/najeira/randstr"
@@ -26,8 +29,8 @@ func init() {
 
 func randDisplayName() string {
 	s := ""
-	for i := 0; i < rand.Intn(3)+6; i++ {
-		s += hiragana[rand.Intn(len(hiragana))]
+	for i := 0; i < benchrand.Intn(3)+6; i++ {
+		s += hiragana[benchrand.Intn(len(hiragana))]
 	}
 	return s
 }
@@ -41,6 +44,7 @@ func defaultPasswordOrPretest(name string) string {
 
 func setupTestUser(ctx context.Context, contestantLogger *zap.Logger, dnsResolver *resolver.DNSResolver) (*isupipe.User, error) {
 	client, err := isupipe.NewCustomResolverClient(
+		ctx,
 		contestantLogger,
 		dnsResolver,
 		agent.WithTimeout(config.PretestTimeout),
@@ -62,69 +66,202 @@ func setupTestUser(ctx context.Context, contestantLogger *zap.Logger, dnsResolve
 	return user, nil
 }
 
-// 初期データチェック -> 基本的なエンドポイントの機能テスト -> 前後比較テスト
-func Pretest(ctx context.Context, contestantLogger *zap.Logger, dnsResolver *resolver.DNSResolver) error {
-	// dns 初期レコード
-	if err := dnsRecordPretest(ctx, dnsResolver); err != nil {
-		return err
-	}
+// errTestUserUnavailable は、テストユーザの作成に失敗したため、テストユーザを利用するチェックを実施しなかったことを表します
+var errTestUserUnavailable = errors.New("テストユーザの作成に失敗したため実施しませんでした")
 
-	// 初期データチェック
-	// FIXME: reactions, livecommentsは統計情報をもとにチェックする
-	// FIXME: ngwordsはライブ配信のIDをいくつか問い合わせ、存在することをチェックする
-	if err := normalInitialPaymentPretest(ctx, contestantLogger, dnsResolver); err != nil {
-		return err
-	}
+// PretestCheckStatus は、pretestの各チェックの結果です
+type PretestCheckStatus string
 
-	// 統計情報
-	if err := normalStatsCalcPretest(ctx, contestantLogger, dnsResolver); err != nil {
-		return err
-	}
+const (
+	PretestCheckPass PretestCheckStatus = "pass"
+	PretestCheckFail PretestCheckStatus = "fail"
+	// 先行するチェックが失敗したため実施されなかった
+	PretestCheckSkip PretestCheckStatus = "skip"
+)
 
-	testUser, err := setupTestUser(ctx, contestantLogger, dnsResolver)
-	if err != nil {
-		return err
-	}
-	if err := NormalLivestreamPretest(ctx, contestantLogger, testUser, dnsResolver); err != nil {
-		return err
-	}
+// PretestCheck は、pretestのチェック1件分の結果です
+type PretestCheck struct {
+	Name        string             `json:"name"`
+	Description string             `json:"description"`
+	Status      PretestCheckStatus `json:"status"`
+	// 失敗したリクエスト/レスポンスの詳細
+	Detail string `json:"detail,omitempty"`
+}
 
-	// 正常系
-	if err := NormalUserPretest(ctx, contestantLogger, dnsResolver); err != nil {
-		return err
-	}
-	if err := NormalIconPretest(ctx, contestantLogger, dnsResolver); err != nil {
-		return err
-	}
-	if err := NormalReactionPretest(ctx, contestantLogger, testUser, dnsResolver); err != nil {
-		return err
-	}
-	if err := NormalPostLivecommentPretest(ctx, contestantLogger, testUser, dnsResolver); err != nil {
-		return err
-	}
-	if err := NormalModerateLivecommentPretest(ctx, contestantLogger, testUser, dnsResolver); err != nil {
-		return err
-	}
+// PretestReport は、選手向けのチェックリスト表示に用いるpretestの結果です
+type PretestReport struct {
+	Pass   bool            `json:"pass"`
+	Checks []*PretestCheck `json:"checks"`
+}
 
-	// 異常系
-	if err := assertBadLogin(ctx, contestantLogger, dnsResolver); err != nil {
-		return err
-	}
-	if err := assertPipeUserRegistration(ctx, contestantLogger, dnsResolver); err != nil {
-		return err
-	}
-	if err := assertUserUniqueConstraint(ctx, contestantLogger, dnsResolver); err != nil {
-		return err
-	}
-	if err := assertReserveOverflowPretest(ctx, contestantLogger, dnsResolver); err != nil {
-		return err
+type pretestStep struct {
+	name        string
+	description string
+	fn          func(ctx context.Context) error
+}
+
+// 初期データチェック -> 基本的なエンドポイントの機能テスト -> 前後比較テスト
+// NOTE: いずれかのチェックが失敗した時点で以降のチェックは実施せず、skipとして報告する
+// 練習モードでは、失敗したチェックがあっても以降のチェックを実施し、最初の失敗を返す
+func Pretest(ctx context.Context, contestantLogger *zap.Logger, dnsResolver *resolver.DNSResolver) (*PretestReport, error) {
+	var (
+		testUser *isupipe.User
+		// テストユーザの作成を除外した場合、テストユーザを利用する項目も実施しない
+		testUserSkipErr error
+	)
+	requireTestUser := func(fn func(ctx context.Context) error) func(ctx context.Context) error {
+		return func(ctx context.Context) error {
+			if testUserSkipErr != nil {
+				return testUserSkipErr
+			}
+			if testUser == nil {
+				// NOTE: 練習モードでは、テストユーザの作成に失敗しても以降のチェックを実施する
+				return errTestUserUnavailable
+			}
+			return fn(ctx)
+		}
 	}
-	if err := assertReserveOutOfTerm(ctx, contestantLogger, testUser, dnsResolver); err != nil {
-		return err
+	steps := []pretestStep{
+		// dns 初期レコード
+		{"dns_record", "DNSの初期レコード", func(ctx context.Context) error {
+			return dnsRecordPretest(ctx, dnsResolver)
+		}},
+		{"dns_zone", "DNSゾーンのSOA・NSレコード", func(ctx context.Context) error {
+			return dnsZonePretest(ctx, contestantLogger, dnsResolver)
+		}},
+		// SSL接続が有効な場合のみ
+		{"tls_certificate", "TLS証明書とHTTPSへの誘導", func(ctx context.Context) error {
+			return tlsPretest(ctx, dnsResolver)
+		}},
+		// 初期データチェック
+		{"initialize_dataset", "初期データの規模と機能フラグ", func(ctx context.Context) error {
+			return initializeDatasetPretest(ctx, contestantLogger, dnsResolver)
+		}},
+		// FIXME: reactions, livecommentsは統計情報をもとにチェックする
+		// FIXME: ngwordsはライブ配信のIDをいくつか問い合わせ、存在することをチェックする
+		{"initial_payment", "初期データの売上", func(ctx context.Context) error {
+			return normalInitialPaymentPretest(ctx, contestantLogger, dnsResolver)
+		}},
+		// 統計情報
+		{"stats_calc", "統計情報の計算", func(ctx context.Context) error {
+			return normalStatsCalcPretest(ctx, contestantLogger, dnsResolver)
+		}},
+		{"setup_test_user", "テストユーザの作成", func(ctx context.Context) error {
+			user, err := setupTestUser(ctx, contestantLogger, dnsResolver)
+			if _, ok := coverage.SkippedEndpoint(err); ok {
+				testUserSkipErr = err
+			}
+			if err != nil {
+				return err
+			}
+			testUser = user
+			return nil
+		}},
+		{"livestream", "ライブ配信の予約・取得", requireTestUser(func(ctx context.Context) error {
+			return NormalLivestreamPretest(ctx, contestantLogger, testUser, dnsResolver)
+		})},
+		// 正常系
+		{"user", "ユーザ情報の取得", func(ctx context.Context) error {
+			return NormalUserPretest(ctx, contestantLogger, dnsResolver)
+		}},
+		{"tag", "タグ一覧の取得", func(ctx context.Context) error {
+			return normalTagPretest(ctx, contestantLogger, dnsResolver)
+		}},
+		{"icon", "アイコンの登録・取得", func(ctx context.Context) error {
+			return NormalIconPretest(ctx, contestantLogger, dnsResolver)
+		}},
+		{"caching", "アイコンと静的ファイルのキャッシュ", func(ctx context.Context) error {
+			return cachingPretest(ctx, contestantLogger, dnsResolver)
+		}},
+		{"reaction", "リアクションの投稿・取得", requireTestUser(func(ctx context.Context) error {
+			return NormalReactionPretest(ctx, contestantLogger, testUser, dnsResolver)
+		})},
+		{"post_livecomment", "ライブコメントの投稿・取得", requireTestUser(func(ctx context.Context) error {
+			return NormalPostLivecommentPretest(ctx, contestantLogger, testUser, dnsResolver)
+		})},
+		{"pagination", "一覧取得の件数指定と並び順", requireTestUser(func(ctx context.Context) error {
+			return paginationPretest(ctx, contestantLogger, testUser, dnsResolver)
+		})},
+		{"moderate_livecomment", "ライブコメントのモデレーション", requireTestUser(func(ctx context.Context) error {
+			return NormalModerateLivecommentPretest(ctx, contestantLogger, testUser, dnsResolver)
+		})},
+		// 異常系
+		{"bad_login", "不正なログインの拒否", func(ctx context.Context) error {
+			return assertBadLogin(ctx, contestantLogger, dnsResolver)
+		}},
+		{"auth_required", "未ログインのリクエストの拒否", func(ctx context.Context) error {
+			return assertAuthRequired(ctx, contestantLogger, dnsResolver)
+		}},
+		{"tampered_session", "改ざんされたセッションの拒否", requireTestUser(func(ctx context.Context) error {
+			return assertTamperedSession(ctx, contestantLogger, testUser, dnsResolver)
+		})},
+		{"session_cookie", "セッションCookieの属性", requireTestUser(func(ctx context.Context) error {
+			return assertSessionCookieAttributes(ctx, contestantLogger, testUser, dnsResolver)
+		})},
+		{"moderate_others_livestream", "他の配信者のライブ配信のモデレーションの拒否", requireTestUser(func(ctx context.Context) error {
+			return assertModerateOthersLivestream(ctx, contestantLogger, testUser, dnsResolver)
+		})},
+		{"pipe_user_registration", "'pipe'ユーザ登録の拒否", func(ctx context.Context) error {
+			return assertPipeUserRegistration(ctx, contestantLogger, dnsResolver)
+		}},
+		{"user_unique_constraint", "ユーザ名の重複登録の拒否", func(ctx context.Context) error {
+			return assertUserUniqueConstraint(ctx, contestantLogger, dnsResolver)
+		}},
+		{"reserve_overflow", "枠数を超えた予約の拒否", func(ctx context.Context) error {
+			return assertReserveOverflowPretest(ctx, contestantLogger, dnsResolver)
+		}},
+		{"reserve_out_of_term", "期間外の予約の拒否", requireTestUser(func(ctx context.Context) error {
+			return assertReserveOutOfTerm(ctx, contestantLogger, testUser, dnsResolver)
+		})},
+		{"multiple_enter_livestream", "ライブ配信への重複入場", func(ctx context.Context) error {
+			return assertMultipleEnterLivestream(ctx, dnsResolver)
+		}},
+		{"head", "HEADとGETのレスポンスの一致", func(ctx context.Context) error {
+			return headPretest(ctx, contestantLogger, dnsResolver)
+		}},
+		// NOTE: これまでのチェックで行った操作の記録と突き合わせるため、最後に行う
+		{"stats_reconcile", "統計情報と操作の記録の突き合わせ", func(ctx context.Context) error {
+			return statsReconcilePretest(ctx, contestantLogger, dnsResolver)
+		}},
 	}
-	if err := assertMultipleEnterLivestream(ctx, dnsResolver); err != nil {
-		return err
+
+	report := &PretestReport{Pass: true}
+	var pretestErr error
+	for _, step := range steps {
+		check := &PretestCheck{
+			Name:        step.name,
+			Description: step.description,
+		}
+		report.Checks = append(report.Checks, check)
+
+		if pretestErr != nil && !config.Practice {
+			check.Status = PretestCheckSkip
+			continue
+		}
+		err := step.fn(ctx)
+		if endpoint, ok := coverage.SkippedEndpoint(err); ok {
+			// NOTE: 除外されたエンドポイントを利用する項目は、失敗とせずに以降の項目を続ける
+			check.Status = PretestCheckSkip
+			check.Detail = err.Error()
+			coverage.MarkSkipped(fmt.Sprintf("整合性チェック %s", step.name), endpoint)
+			continue
+		}
+		if errors.Is(err, errTestUserUnavailable) {
+			check.Status = PretestCheckSkip
+			check.Detail = err.Error()
+			continue
+		}
+		if err != nil {
+			check.Status = PretestCheckFail
+			check.Detail = err.Error()
+			report.Pass = false
+			if pretestErr == nil {
+				pretestErr = err
+			}
+			continue
+		}
+		check.Status = PretestCheckPass
 	}
 
-	return nil
+	return report, pretestErr
 }
@@ -3,15 +3,14 @@ package scenario
 import (
 	"context"
 	"fmt"
-	"net/http"
 	"time"
 
 	"github.com/isucon/isucandar/agent"
 	"github.com/isucon/isucon13/bench/internal/bencherror"
+	"github.com/isucon/isucon13/bench/internal/benchrand"
 	"github.com/isucon/isucon13/bench/internal/config"
 	"github.com/isucon/isucon13/bench/internal/resolver"
 	"github.com/isucon/isucon13/bench/isupipe"
-	"github.com/najeira/randstr"
 	"go.uber.org/zap"
 )
 
@@ -19,6 +18,7 @@ import (
 
 func assertPipeUserRegistration(ctx context.Context, contestantLogger *zap.Logger, dnsResolver *resolver.DNSResolver) error {
 	client, err := isupipe.NewCustomResolverClient(
+		ctx,
 		contestantLogger,
 		dnsResolver,
 		agent.WithTimeout(config.PretestTimeout),
@@ -36,7 +36,7 @@ func assertPipeUserRegistration(ctx context.Context, contestantLogger *zap.Logge
 		Theme: isupipe.Theme{
 			DarkMode: true,
 		},
-	}, isupipe.WithStatusCode(http.StatusBadRequest)); err != nil {
+	}, isupipe.WithAction(isupipe.ActionRegisterPipeUser)); err != nil {
 		return fmt.Errorf("'pipe'ユーザの作成は拒否されなければなりません: %w", err)
 	}
 
@@ -46,37 +46,39 @@ func assertPipeUserRegistration(ctx context.Context, contestantLogger *zap.Logge
 func assertBadLogin(ctx context.Context, contestantLogger *zap.Logger, dnsResolver *resolver.DNSResolver) error {
 	// 存在しないユーザでログインされた場合はエラー
 	client1, err := isupipe.NewCustomResolverClient(
+		ctx,
 		contestantLogger,
 		dnsResolver,
 		agent.WithTimeout(config.PretestTimeout),
 	)
 	if err != nil {
-		return bencherror.NewInternalError(err)
+		return bencherror.NewInternalError(ctx, err)
 	}
 	unknownUserReq := isupipe.LoginRequest{
 		Username: "unknownUser4328904823",
 		Password: "unknownUser",
 	}
 
-	if err := client1.Login(ctx, &unknownUserReq, isupipe.WithStatusCode(http.StatusUnauthorized)); err != nil {
-		return bencherror.NewViolationError(err, "データベースに存在しないユーザからのログインは無効です")
+	if err := client1.Login(ctx, &unknownUserReq, isupipe.WithAction(isupipe.ActionLoginUnknownUser)); err != nil {
+		return bencherror.NewViolationError(ctx, err, "データベースに存在しないユーザからのログインは無効です")
 	}
 
 	// パスワードが間違っている場合はエラー
 	client2, err := isupipe.NewCustomResolverClient(
+		ctx,
 		contestantLogger,
 		dnsResolver,
 		agent.WithTimeout(config.PretestTimeout),
 	)
 	if err != nil {
-		return bencherror.NewInternalError(err)
+		return bencherror.NewInternalError(ctx, err)
 	}
 	wrongPasswordReq := isupipe.LoginRequest{
 		Username: "test001",
 		Password: "wrongPassword",
 	}
-	if err := client2.Login(ctx, &wrongPasswordReq, isupipe.WithStatusCode(http.StatusUnauthorized)); err != nil {
-		return bencherror.NewViolationError(err, "パスワードが間違っているログインは無効です")
+	if err := client2.Login(ctx, &wrongPasswordReq, isupipe.WithAction(isupipe.ActionLoginWrongPassword)); err != nil {
+		return bencherror.NewViolationError(ctx, err, "パスワードが間違っているログインは無効です")
 	}
 
 	return nil
@@ -84,6 +86,7 @@ func assertBadLogin(ctx context.Context, contestantLogger *zap.Logger, dnsResolv
 
 func assertUserUniqueConstraint(ctx context.Context, contestantLogger *zap.Logger, dnsResolver *resolver.DNSResolver) error {
 	client, err := isupipe.NewCustomResolverClient(
+		ctx,
 		contestantLogger,
 		dnsResolver,
 		agent.WithTimeout(config.PretestTimeout),
@@ -105,7 +108,7 @@ func assertUserUniqueConstraint(ctx context.Context, contestantLogger *zap.Logge
 		return err
 	}
 
-	if _, err := client.Register(ctx, &testDupReq, isupipe.WithStatusCode(http.StatusInternalServerError)); err != nil {
+	if _, err := client.Register(ctx, &testDupReq, isupipe.WithAction(isupipe.ActionRegisterDuplicated)); err != nil {
 		return fmt.Errorf("重複したユーザ名を含むリクエストはエラーを返さなければなりません: %w", err)
 	}
 
@@ -117,6 +120,7 @@ func assertReserveOverflowPretest(ctx context.Context, contestantLogger *zap.Log
 	var overflow bool
 	for idx := 0; idx < config.NumSlots; idx++ {
 		overflowClient, err := isupipe.NewCustomResolverClient(
+			ctx,
 			contestantLogger,
 			dnsResolver,
 			agent.WithTimeout(config.PretestTimeout),
@@ -125,8 +129,8 @@ func assertReserveOverflowPretest(ctx context.Context, contestantLogger *zap.Log
 			return err
 		}
 
-		name := fmt.Sprintf("%s%d", randstr.String(10), idx)
-		passwd := randstr.String(10)
+		name := fmt.Sprintf("%s%d", benchrand.String(10), idx)
+		passwd := benchrand.String(10)
 		overflowUser, err := overflowClient.Register(ctx, &isupipe.RegisterRequest{
 			Name:        name,
 			DisplayName: randDisplayName(),
@@ -181,6 +185,7 @@ func assertReserveOverflowPretest(ctx context.Context, contestantLogger *zap.Log
 func assertReserveOutOfTerm(ctx context.Context, contestantLogger *zap.Logger, testUser *isupipe.User, dnsResolver *resolver.DNSResolver) error {
 	// 期間外の予約をするとエラーになる
 	client, err := isupipe.NewCustomResolverClient(
+		ctx,
 		contestantLogger,
 		dnsResolver,
 		agent.WithTimeout(config.PretestTimeout),
@@ -208,7 +213,7 @@ func assertReserveOutOfTerm(ctx context.Context, contestantLogger *zap.Logger, t
 		StartAt:      startAt.Unix(),
 		EndAt:        endAt.Unix(),
 		Tags:         []int64{},
-	}, isupipe.WithStatusCode(http.StatusBadRequest)); err != nil {
+	}, isupipe.WithAction(isupipe.ActionReserveLivestreamOutTerm)); err != nil {
 		return fmt.Errorf("期間外予約が不正にできてしまいます")
 	}
 
@@ -224,7 +229,7 @@ func assertReserveOutOfTerm(ctx context.Context, contestantLogger *zap.Logger, t
 		StartAt:      startAt2.Unix(),
 		EndAt:        endAt2.Unix(),
 		Tags:         []int64{},
-	}, isupipe.WithStatusCode(http.StatusBadRequest)); err != nil {
+	}, isupipe.WithAction(isupipe.ActionReserveLivestreamOutTerm)); err != nil {
 		return fmt.Errorf("期間外予約が不正にできてしまいます")
 	}
 
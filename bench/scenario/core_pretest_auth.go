@@ -0,0 +1,206 @@
+package scenario
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/isucon/isucandar/agent"
+	"github.com/isucon/isucon13/bench/internal/bencherror"
+	"github.com/isucon/isucon13/bench/internal/config"
+	"github.com/isucon/isucon13/bench/internal/resolver"
+	"github.com/isucon/isucon13/bench/isupipe"
+	"go.uber.org/zap"
+)
+
+// 認証・認可の異常系
+// NOTE: 高速化のために認証を省いたwebappは、負荷走行に進ませずに失格とする
+
+// authRequiredEndpoint は、ログインが必要なエンドポイントです
+type authRequiredEndpoint struct {
+	method string
+	path   string
+}
+
+// NOTE: ライブ配信ID 1 と test001 は初期データに存在する
+var authRequiredEndpoints = []authRequiredEndpoint{
+	{http.MethodGet, "/api/user/me"},
+	{http.MethodGet, "/api/user/test001"},
+	{http.MethodGet, "/api/user/test001/statistics"},
+	{http.MethodGet, "/api/user/test001/livestream"},
+	{http.MethodPost, "/api/icon"},
+	{http.MethodGet, "/api/livestream"},
+	{http.MethodPost, "/api/livestream/reservation"},
+	{http.MethodGet, "/api/livestream/1"},
+	{http.MethodGet, "/api/livestream/1/statistics"},
+	{http.MethodGet, "/api/livestream/1/livecomment"},
+	{http.MethodPost, "/api/livestream/1/livecomment"},
+	{http.MethodGet, "/api/livestream/1/reaction"},
+	{http.MethodPost, "/api/livestream/1/reaction"},
+	{http.MethodGet, "/api/livestream/1/report"},
+	{http.MethodGet, "/api/livestream/1/ngwords"},
+	{http.MethodPost, "/api/livestream/1/moderate"},
+	{http.MethodPost, "/api/livestream/1/enter"},
+	{http.MethodDelete, "/api/livestream/1/exit"},
+}
+
+// assertAuthRequired は、セッションを持たないリクエストが拒否されることを確認します
+func assertAuthRequired(ctx context.Context, contestantLogger *zap.Logger, dnsResolver *resolver.DNSResolver) error {
+	client, err := isupipe.NewCustomResolverClient(
+		ctx,
+		contestantLogger,
+		dnsResolver,
+		agent.WithTimeout(config.PretestTimeout),
+	)
+	if err != nil {
+		return bencherror.NewInternalError(ctx, err)
+	}
+
+	for _, endpoint := range authRequiredEndpoints {
+		if err := client.VerifyAuthRequired(ctx, endpoint.method, endpoint.path, nil,
+			isupipe.WithAllowedActions(isupipe.ActionUnauthenticatedWithoutSession),
+		); err != nil {
+			return bencherror.NewViolationError(ctx, err, "ログインしていないユーザからの %s %s は拒否されなければなりません", endpoint.method, endpoint.path)
+		}
+	}
+
+	return nil
+}
+
+// assertTamperedSession は、改ざんされたセッションCookieが拒否されることを確認します
+// NOTE: webappにログアウトのエンドポイントは存在しないため、破棄されたセッションの代わりに改ざんされたセッションを用いる
+func assertTamperedSession(ctx context.Context, contestantLogger *zap.Logger, testUser *isupipe.User, dnsResolver *resolver.DNSResolver) error {
+	client, err := isupipe.NewCustomResolverClient(
+		ctx,
+		contestantLogger,
+		dnsResolver,
+		agent.WithTimeout(config.PretestTimeout),
+	)
+	if err != nil {
+		return bencherror.NewInternalError(ctx, err)
+	}
+	if err := client.Login(ctx, &isupipe.LoginRequest{
+		Username: testUser.Name,
+		Password: defaultPasswordOrPretest(testUser.Name),
+	}); err != nil {
+		return err
+	}
+
+	cookies := client.SessionCookies()
+	if len(cookies) == 0 {
+		return bencherror.NewViolationError(ctx, fmt.Errorf("Cookieが発行されていません"), "ログイン時にはセッションCookieを発行しなければなりません")
+	}
+	tampered := make([]*http.Cookie, len(cookies))
+	for i, cookie := range cookies {
+		tampered[i] = &http.Cookie{
+			Name:  cookie.Name,
+			Value: tamperCookieValue(cookie.Value),
+		}
+	}
+
+	noSessionClient, err := isupipe.NewCustomResolverClient(
+		ctx,
+		contestantLogger,
+		dnsResolver,
+		agent.WithTimeout(config.PretestTimeout),
+	)
+	if err != nil {
+		return bencherror.NewInternalError(ctx, err)
+	}
+	if err := noSessionClient.VerifyAuthRequired(ctx, http.MethodGet, "/api/user/me", tampered,
+		isupipe.WithAllowedActions(isupipe.ActionUnauthenticatedWithoutSession),
+	); err != nil {
+		return bencherror.NewViolationError(ctx, err, "改ざんされたセッションCookieは拒否されなければなりません")
+	}
+
+	return nil
+}
+
+// assertSessionCookieAttributes は、ログイン時に発行されるセッションCookieの属性が仕様を満たすことを確認します
+// NOTE: 転送量を削るためにCookieの有効期間を縮めるなどして、セッションの扱いを変えたwebappを検出するためのもの
+func assertSessionCookieAttributes(ctx context.Context, contestantLogger *zap.Logger, testUser *isupipe.User, dnsResolver *resolver.DNSResolver) error {
+	client, err := isupipe.NewCustomResolverClient(
+		ctx,
+		contestantLogger,
+		dnsResolver,
+		agent.WithTimeout(config.PretestTimeout),
+	)
+	if err != nil {
+		return bencherror.NewInternalError(ctx, err)
+	}
+	if err := client.Login(ctx, &isupipe.LoginRequest{
+		Username: testUser.Name,
+		Password: defaultPasswordOrPretest(testUser.Name),
+	}); err != nil {
+		return err
+	}
+
+	cookies, issuedAt := client.LoginSetCookies()
+	if len(cookies) == 0 {
+		return bencherror.NewViolationError(ctx, fmt.Errorf("Set-Cookieヘッダがありません"), "ログイン時にはセッションCookieを発行しなければなりません")
+	}
+	for _, cookie := range cookies {
+		if err := isupipe.CheckSessionCookie(cookie, issuedAt); err != nil {
+			return bencherror.NewViolationError(ctx, err, "ログイン時に発行するセッションCookie %s の属性が不正です", cookie.Name)
+		}
+	}
+
+	return nil
+}
+
+// tamperCookieValue は、Cookieの値の中ほどの1文字を書き換えます
+func tamperCookieValue(value string) string {
+	if len(value) == 0 {
+		return "tampered"
+	}
+	b := []byte(value)
+	i := len(b) / 2
+	if b[i] == 'A' {
+		b[i] = 'B'
+	} else {
+		b[i] = 'A'
+	}
+	return string(b)
+}
+
+// assertModerateOthersLivestream は、他の配信者のライブ配信をモデレーションできないことを確認します
+func assertModerateOthersLivestream(ctx context.Context, contestantLogger *zap.Logger, testUser *isupipe.User, dnsResolver *resolver.DNSResolver) error {
+	client, err := isupipe.NewCustomResolverClient(
+		ctx,
+		contestantLogger,
+		dnsResolver,
+		agent.WithTimeout(config.PretestTimeout),
+	)
+	if err != nil {
+		return bencherror.NewInternalError(ctx, err)
+	}
+	if err := client.Login(ctx, &isupipe.LoginRequest{
+		Username: testUser.Name,
+		Password: defaultPasswordOrPretest(testUser.Name),
+	}); err != nil {
+		return err
+	}
+
+	livestreams, err := client.SearchLivestreams(ctx, isupipe.WithLimitQueryParam(config.NumSearchLivestreams))
+	if err != nil {
+		return err
+	}
+	var others *isupipe.Livestream
+	for _, livestream := range livestreams {
+		if livestream.Owner.ID != testUser.ID {
+			others = livestream
+			break
+		}
+	}
+	if others == nil {
+		return fmt.Errorf("他の配信者のライブ配信が見つかりません")
+	}
+
+	if err := client.Moderate(ctx, others.ID, others.Owner.Name, "isupipe-pretest-moderate-others",
+		isupipe.WithAction(isupipe.ActionModerateOthersLivestream),
+	); err != nil {
+		return bencherror.NewViolationError(ctx, err, "他の配信者のライブ配信のライブコメントをモデレーションできてはいけません")
+	}
+
+	return nil
+}
@@ -0,0 +1,308 @@
+package scenario
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/isucon/isucandar/agent"
+	"github.com/isucon/isucon13/bench/assets"
+	"github.com/isucon/isucon13/bench/internal/bencherror"
+	"github.com/isucon/isucon13/bench/internal/benchrand"
+	"github.com/isucon/isucon13/bench/internal/config"
+	"github.com/isucon/isucon13/bench/internal/mediacheck"
+	"github.com/isucon/isucon13/bench/internal/resolver"
+	"github.com/isucon/isucon13/bench/internal/scheduler"
+	"github.com/isucon/isucon13/bench/isupipe"
+	"go.uber.org/zap"
+)
+
+// cachingPretest は、アイコンと静的ファイルのキャッシュに関するヘッダと条件付きリクエストの扱いを検証します
+// NOTE: 参照実装はキャッシュに関するヘッダを返さないため、ヘッダを返さないことは許容し、返した場合にその意味どおりに振る舞うことを検証する
+//   - ETagを返した場合、そのETagを指定した If-None-Match には 304 を返さなければならない
+//   - アイコンの更新後に、更新前の検証子に対して 304 を返して古いアイコンを使わせてはならない
+//   - アイコンの更新が反映されるまでの猶予を超えて、再検証なしにキャッシュさせてはならない
+func cachingPretest(ctx context.Context, contestantLogger *zap.Logger, dnsResolver *resolver.DNSResolver) error {
+	client, err := isupipe.NewCustomResolverClient(
+		ctx,
+		contestantLogger,
+		dnsResolver,
+		agent.WithTimeout(config.PretestTimeout),
+	)
+	if err != nil {
+		return err
+	}
+
+	// NOTE: 他のpretestが参照するユーザのアイコンを変えないよう、新たなユーザで検証する
+	username := "cache" + strings.ToLower(benchrand.String(10))
+	password := benchrand.String(13)
+	if _, err := client.Register(ctx, &isupipe.RegisterRequest{
+		Name:        username,
+		Password:    password,
+		DisplayName: randDisplayName(),
+		Description: "キャッシュの検証用ユーザです",
+	}); err != nil {
+		return err
+	}
+	if err := client.Login(ctx, &isupipe.LoginRequest{
+		Username: username,
+		Password: password,
+	}); err != nil {
+		return err
+	}
+
+	iconComparator, err := mediacheck.Get(config.IconComparator)
+	if err != nil {
+		return bencherror.NewInternalError(ctx, err)
+	}
+
+	before := scheduler.IconSched.GetRandomIcon()
+	after, ok := scheduler.IconSched.GetRandomIconExcept(before)
+	if !ok {
+		return bencherror.NewInternalError(ctx, errors.New("アイコンの更新を検証するための、異なるアイコン画像がありません"))
+	}
+	iconPath := fmt.Sprintf("/api/user/%s/icon", username)
+
+	// 更新前のアイコンについて、キャッシュの有効期限と条件付きリクエストを検証する
+	if _, err := client.PostIcon(ctx, &isupipe.PostIconRequest{Image: before.Image}); err != nil {
+		return err
+	}
+	time.Sleep(IconHashAppliedDelay)
+	first, err := getIconForCaching(ctx, client, iconComparator, iconPath, before.Image)
+	if err != nil {
+		return err
+	}
+	if lifetime, ok := freshnessLifetime(first.CacheControl); ok && lifetime > IconHashAppliedDelay {
+		return bencherror.NewCachingError(ctx, first.Request, "Cache-Control: %s によって、アイコンの更新後も古いアイコンが再検証なしに使われ続けます (再検証なしにキャッシュできる期間は %s まで)", first.CacheControl, IconHashAppliedDelay)
+	}
+	staleConditions := iconConditions(first, fmt.Sprintf("%x", before.Hash))
+	for _, conditions := range staleConditions {
+		if err := verifyIconRevalidation(ctx, client, dnsResolver, iconComparator, iconPath, conditions, first.ETag, before.Image); err != nil {
+			return err
+		}
+	}
+
+	// アイコンを更新した後、更新前の検証子で古いアイコンを使わせないこと
+	// NOTE: Last-Modifiedは秒単位のため、更新前のアイコンの取得から1秒以上空ける (IconHashAppliedDelay を待っている)
+	if _, err := client.PostIcon(ctx, &isupipe.PostIconRequest{Image: after.Image}); err != nil {
+		return err
+	}
+	time.Sleep(IconHashAppliedDelay)
+	for _, conditions := range staleConditions {
+		resp, err := client.GetCacheable(ctx, iconPath, conditions)
+		if err != nil {
+			return err
+		}
+		if resp.NotModified() {
+			return bencherror.NewCachingError(ctx, resp.Request, "アイコンの更新後に、更新前の検証子 (%s) に対して 304 が返され、古いアイコンが使われ続けます", describeConditions(conditions))
+		}
+		if resp.StatusCode != http.StatusOK {
+			return bencherror.NewHttpStatusError(ctx, resp.Request, http.StatusOK, resp.StatusCode)
+		}
+		if err := iconComparator.Compare(after.Image, resp.Body); err != nil {
+			return bencherror.NewCachingError(ctx, resp.Request, "アイコンの更新後に、更新前の検証子 (%s) に対して新しいアイコンが返されません: %s", describeConditions(conditions), err.Error())
+		}
+	}
+
+	// 更新後のアイコンについても、条件付きリクエストを検証する
+	second, err := getIconForCaching(ctx, client, iconComparator, iconPath, after.Image)
+	if err != nil {
+		return err
+	}
+	if first.ETag != "" && first.ETag == second.ETag {
+		return bencherror.NewCachingError(ctx, second.Request, "アイコンの更新前後でETag (%s) が変わっていません", second.ETag)
+	}
+	for _, conditions := range iconConditions(second, fmt.Sprintf("%x", after.Hash)) {
+		if err := verifyIconRevalidation(ctx, client, dnsResolver, iconComparator, iconPath, conditions, second.ETag, after.Image); err != nil {
+			return err
+		}
+	}
+
+	return verifyStaticAssetCaching(ctx, client, dnsResolver)
+}
+
+// getIconForCaching は、条件なしでアイコンを取得し、期待するアイコンであることを検証します
+func getIconForCaching(ctx context.Context, client *isupipe.Client, iconComparator mediacheck.Comparator, iconPath string, want []byte) (*isupipe.CacheableResponse, error) {
+	resp, err := client.GetCacheable(ctx, iconPath, isupipe.CacheConditions{})
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, bencherror.NewHttpStatusError(ctx, resp.Request, http.StatusOK, resp.StatusCode)
+	}
+	if err := iconComparator.Compare(want, resp.Body); err != nil {
+		return nil, fmt.Errorf("設定したアイコンが反映されていません: %w", err)
+	}
+	return resp, nil
+}
+
+// iconConditions は、アイコンの条件付きリクエストに用いる検証子を返します
+// NOTE: icon_hashをETagとして用いる実装が多いため、レスポンスのETagに加えてicon_hashも検証子とする
+func iconConditions(resp *isupipe.CacheableResponse, iconHash string) []isupipe.CacheConditions {
+	conditions := []isupipe.CacheConditions{
+		{IfNoneMatch: strconv.Quote(iconHash)},
+	}
+	if resp.ETag != "" && resp.ETag != strconv.Quote(iconHash) {
+		conditions = append(conditions, isupipe.CacheConditions{IfNoneMatch: resp.ETag})
+	}
+	if resp.LastModified != "" {
+		conditions = append(conditions, isupipe.CacheConditions{IfModifiedSince: resp.LastModified})
+	}
+	return conditions
+}
+
+// verifyIconRevalidation は、現在のアイコンに対する条件付きリクエストに、304 または現在のアイコンが返されることを検証します
+// NOTE: webappが返したETagを指定した場合は、304 を返さなければならない
+func verifyIconRevalidation(ctx context.Context, client *isupipe.Client, dnsResolver *resolver.DNSResolver, iconComparator mediacheck.Comparator, iconPath string, conditions isupipe.CacheConditions, eTag string, want []byte) error {
+	resp, err := client.GetCacheable(ctx, iconPath, conditions)
+	if err != nil {
+		return err
+	}
+	if resp.NotModified() {
+		return verifyNotModifiedBody(ctx, dnsResolver, resp.Request)
+	}
+	if eTag != "" && conditions.IfNoneMatch == eTag {
+		return bencherror.NewCachingError(ctx, resp.Request, "レスポンスのETag (%s) を指定した If-None-Match に対して 304 が返されません (status: %d)", eTag, resp.StatusCode)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return bencherror.NewHttpStatusError(ctx, resp.Request, http.StatusOK, resp.StatusCode)
+	}
+	if err := iconComparator.Compare(want, resp.Body); err != nil {
+		return fmt.Errorf("条件付きリクエスト (%s) に対して、設定したアイコンが返されません: %w", describeConditions(conditions), err)
+	}
+	return nil
+}
+
+// verifyStaticAssetCaching は、静的ファイルがETagを返す場合に、If-None-Match に 304 を返すことを検証します
+// NOTE: 静的ファイルの配信はwebappの構成に依存するため、取得できないものは検証しない
+func verifyStaticAssetCaching(ctx context.Context, client *isupipe.Client, dnsResolver *resolver.DNSResolver) error {
+	lgr := zap.S()
+
+	paths := []string{"/"}
+	if list, err := assets.Load(); err == nil {
+		for i := 0; i < len(list) && i < config.CachingPretestAssets; i++ {
+			paths = append(paths, list[i].Path)
+		}
+	}
+
+	for _, path := range paths {
+		resp, err := client.GetCacheable(ctx, path, isupipe.CacheConditions{})
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode != http.StatusOK {
+			lgr.Infof("pretest: static asset %s is not served (status: %d)", path, resp.StatusCode)
+			continue
+		}
+		if resp.ETag == "" {
+			continue
+		}
+
+		revalidated, err := client.GetCacheable(ctx, path, isupipe.CacheConditions{IfNoneMatch: resp.ETag})
+		if err != nil {
+			return err
+		}
+		if !revalidated.NotModified() {
+			return bencherror.NewCachingError(ctx, revalidated.Request, "レスポンスのETag (%s) を指定した If-None-Match に対して 304 が返されません (status: %d)", resp.ETag, revalidated.StatusCode)
+		}
+		if err := verifyNotModifiedBody(ctx, dnsResolver, revalidated.Request); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// verifyNotModifiedBody は、304 を返した条件付きリクエストを改めて素のHTTP/1.1で送り、304 のレスポンスにボディが続かないことを検証します
+// NOTE: net/http は 304 のボディを読まずに捨てるため、GetCacheable ではボディを検出できない
+// NOTE: Connection: close を指定し、ヘッダの後に接続が閉じられるまでに受信したバイトをボディとみなす
+func verifyNotModifiedBody(ctx context.Context, dnsResolver *resolver.DNSResolver, req *http.Request) error {
+	ctx, cancel := context.WithTimeout(ctx, config.PretestTimeout)
+	defer cancel()
+
+	conn, err := dnsResolver.DialContext(ctx, "tcp", req.URL.Host)
+	if err != nil {
+		return bencherror.NewHttpError(ctx, err, req, "接続できません")
+	}
+	defer conn.Close()
+	if req.URL.Scheme == "https" {
+		tlsConn := tls.Client(conn, &tls.Config{
+			ServerName:         req.URL.Hostname(),
+			InsecureSkipVerify: config.InsecureSkipVerify,
+			// NOTE: 304 のボディをバイト列として確かめるため、HTTP/2をネゴシエートしない
+			NextProtos: []string{"http/1.1"},
+		})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			return bencherror.NewHttpError(ctx, err, req, "TLSハンドシェイクに失敗しました")
+		}
+		conn = tlsConn
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	raw := req.Clone(ctx)
+	raw.Header = req.Header.Clone()
+	raw.Header.Del("Accept-Encoding")
+	raw.Close = true
+	if err := raw.Write(conn); err != nil {
+		return bencherror.NewHttpError(ctx, err, req, "リクエストを送信できません")
+	}
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, raw)
+	if err != nil {
+		return bencherror.NewHttpError(ctx, err, req, "レスポンスを読み込めません")
+	}
+	if resp.StatusCode != http.StatusNotModified {
+		// NOTE: 改めて送ったリクエストに 304 が返されなければ、ボディの有無は確かめない
+		return nil
+	}
+	conn.SetReadDeadline(time.Now().Add(config.NotModifiedBodyWait))
+	n, _ := io.Copy(io.Discard, br)
+	if n > 0 {
+		return bencherror.NewCachingError(ctx, req, "304 のレスポンスにボディ(%d bytes)が含まれています", n)
+	}
+	return nil
+}
+
+// freshnessLifetime は、Cache-Controlから再検証なしにキャッシュを使える期間を返します
+// 再検証を求める場合 (no-cache, no-store) や、期間の指定がない場合はfalseを返します
+func freshnessLifetime(cacheControl string) (time.Duration, bool) {
+	var (
+		lifetime time.Duration
+		found    bool
+	)
+	for _, directive := range strings.Split(cacheControl, ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		switch strings.ToLower(name) {
+		case "no-cache", "no-store":
+			return 0, false
+		case "immutable":
+			// NOTE: immutableは期間によらず再検証しないことを表す
+			lifetime, found = time.Duration(1<<63-1), true
+		case "max-age", "s-maxage":
+			seconds, err := strconv.ParseInt(strings.Trim(value, `"`), 10, 64)
+			if err != nil {
+				continue
+			}
+			if d := time.Duration(seconds) * time.Second; !found || d > lifetime {
+				lifetime, found = d, true
+			}
+		}
+	}
+	return lifetime, found
+}
+
+func describeConditions(conditions isupipe.CacheConditions) string {
+	if conditions.IfNoneMatch != "" {
+		return "If-None-Match: " + conditions.IfNoneMatch
+	}
+	return "If-Modified-Since: " + conditions.IfModifiedSince
+}
@@ -7,6 +7,7 @@ import (
 	"math/rand"
 
 	"github.com/isucon/isucandar/agent"
+	"github.com/isucon/isucon13/bench/internal/benchrand"
 	"github.com/isucon/isucon13/bench/internal/config"
 	"github.com/isucon/isucon13/bench/internal/resolver"
 	"github.com/isucon/isucon13/bench/internal/scheduler"
@@ -26,6 +27,7 @@ func normalStatsCalcPretest(ctx context.Context, contestantLogger *zap.Logger, d
 		return err
 	}
 	streamerClient, err := isupipe.NewCustomResolverClient(
+		ctx,
 		contestantLogger,
 		dnsResolver,
 		agent.WithTimeout(config.PretestTimeout),
@@ -122,6 +124,7 @@ func normalStatsCalcPretest(ctx context.Context, contestantLogger *zap.Logger, d
 
 	// 操作
 	viewerClient, err := isupipe.NewCustomResolverClient(
+		ctx,
 		contestantLogger,
 		dnsResolver,
 		agent.WithTimeout(config.PretestTimeout),
@@ -163,7 +166,7 @@ func normalStatsCalcPretest(ctx context.Context, contestantLogger *zap.Logger, d
 	var livecomments []*isupipe.PostLivecommentResponse
 	for l := 0; l < livecommentCount; l++ {
 		livecomment := scheduler.LivecommentScheduler.GetLongPositiveComment()
-		tip := &scheduler.Tip{Tip: rand.Intn(10)}
+		tip := &scheduler.Tip{Tip: benchrand.Intn(10)}
 		resp, _, err := viewerClient.PostLivecomment(ctx, livestream.ID, livestream.Owner.Name, livecomment.Comment, tip)
 		if err != nil {
 			return err
@@ -0,0 +1,147 @@
+package scenario
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/isucon/isucandar/agent"
+	"github.com/isucon/isucon13/bench/internal/config"
+	"github.com/isucon/isucon13/bench/internal/jsondiff"
+	"github.com/isucon/isucon13/bench/internal/resolver"
+	"github.com/isucon/isucon13/bench/internal/scheduler"
+	"github.com/isucon/isucon13/bench/isupipe"
+	"go.uber.org/zap"
+)
+
+// 2つのwebappに対するpretestの差分比較
+// NOTE: 参照実装と選手の実装(または移植した言語実装)のレスポンスを比較するためのもので、
+//
+//	初期データを変更しない読み取り系のエンドポイントのみを対象とする
+
+// 差分比較で用いる初期ユーザ
+const pretestDiffUserID = 22
+
+// PretestDiffResult は、エンドポイント1つ分の差分比較の結果です
+type PretestDiffResult struct {
+	Name        string                `json:"name"`
+	Differences []jsondiff.Difference `json:"differences,omitempty"`
+	// リクエスト自体が失敗した場合のエラー
+	ErrorA string `json:"error_a,omitempty"`
+	ErrorB string `json:"error_b,omitempty"`
+}
+
+// Same は、両者のレスポンスが一致したかを返します
+func (r *PretestDiffResult) Same() bool {
+	return len(r.Differences) == 0 && r.ErrorA == "" && r.ErrorB == ""
+}
+
+type pretestDiffStep struct {
+	name  string
+	fetch func(ctx context.Context, client *isupipe.Client) (any, error)
+}
+
+// PretestDiff は、初期化済みの2つのwebappに対して読み取り系のpretestを行い、レスポンスをフィールド単位で比較します
+func PretestDiff(ctx context.Context, contestantLogger *zap.Logger, dnsResolverA, dnsResolverB *resolver.DNSResolver) ([]*PretestDiffResult, error) {
+	user, err := scheduler.UserScheduler.GetInitialUserForPretest(pretestDiffUserID)
+	if err != nil {
+		return nil, err
+	}
+
+	clientA, err := newPretestDiffClient(ctx, contestantLogger, dnsResolverA, user)
+	if err != nil {
+		return nil, fmt.Errorf("target-a: %w", err)
+	}
+	clientB, err := newPretestDiffClient(ctx, contestantLogger, dnsResolverB, user)
+	if err != nil {
+		return nil, fmt.Errorf("target-b: %w", err)
+	}
+
+	// NOTE: 初期データのIDは両者で一致するため、片方から得た配信で比較する
+	livestreams, err := clientA.GetUserLivestreams(ctx, user.Name)
+	if err != nil {
+		return nil, fmt.Errorf("target-a: %w", err)
+	}
+
+	steps := []pretestDiffStep{
+		{"tags", func(ctx context.Context, client *isupipe.Client) (any, error) {
+			return client.GetTags(ctx)
+		}},
+		{"search_livestreams", func(ctx context.Context, client *isupipe.Client) (any, error) {
+			return client.SearchLivestreams(ctx, isupipe.WithLimitQueryParam(config.NumSearchLivestreams))
+		}},
+		{"user", func(ctx context.Context, client *isupipe.Client) (any, error) {
+			return client.GetUser(ctx, user.Name)
+		}},
+		{"user_livestreams", func(ctx context.Context, client *isupipe.Client) (any, error) {
+			return client.GetUserLivestreams(ctx, user.Name)
+		}},
+		{"user_statistics", func(ctx context.Context, client *isupipe.Client) (any, error) {
+			return client.GetUserStatistics(ctx, user.Name)
+		}},
+		{"payment", func(ctx context.Context, client *isupipe.Client) (any, error) {
+			return client.GetPaymentResult(ctx)
+		}},
+	}
+	for _, livestream := range livestreams {
+		livestream := livestream
+		steps = append(steps, []pretestDiffStep{
+			{fmt.Sprintf("livestream/%d", livestream.ID), func(ctx context.Context, client *isupipe.Client) (any, error) {
+				return client.GetLivestream(ctx, livestream.ID, livestream.Owner.Name)
+			}},
+			{fmt.Sprintf("livestream/%d/livecomment", livestream.ID), func(ctx context.Context, client *isupipe.Client) (any, error) {
+				return client.GetLivecomments(ctx, livestream.ID, livestream.Owner.Name)
+			}},
+			{fmt.Sprintf("livestream/%d/reaction", livestream.ID), func(ctx context.Context, client *isupipe.Client) (any, error) {
+				return client.GetReactions(ctx, livestream.ID, livestream.Owner.Name)
+			}},
+			{fmt.Sprintf("livestream/%d/statistics", livestream.ID), func(ctx context.Context, client *isupipe.Client) (any, error) {
+				return client.GetLivestreamStatistics(ctx, livestream.ID, livestream.Owner.Name)
+			}},
+		}...)
+	}
+
+	var results []*PretestDiffResult
+	for _, step := range steps {
+		result := &PretestDiffResult{Name: step.name}
+		results = append(results, result)
+
+		respA, errA := step.fetch(ctx, clientA)
+		if errA != nil {
+			result.ErrorA = errA.Error()
+		}
+		respB, errB := step.fetch(ctx, clientB)
+		if errB != nil {
+			result.ErrorB = errB.Error()
+		}
+		if errA != nil || errB != nil {
+			continue
+		}
+
+		diffs, err := jsondiff.Diff(respA, respB)
+		if err != nil {
+			return nil, err
+		}
+		result.Differences = diffs
+	}
+
+	return results, nil
+}
+
+func newPretestDiffClient(ctx context.Context, contestantLogger *zap.Logger, dnsResolver *resolver.DNSResolver, user *scheduler.User) (*isupipe.Client, error) {
+	client, err := isupipe.NewCustomResolverClient(
+		ctx,
+		contestantLogger,
+		dnsResolver,
+		agent.WithTimeout(config.PretestTimeout),
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := client.Login(ctx, &isupipe.LoginRequest{
+		Username: user.Name,
+		Password: user.RawPassword,
+	}); err != nil {
+		return nil, err
+	}
+	return client, nil
+}
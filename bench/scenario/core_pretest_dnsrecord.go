@@ -3,12 +3,13 @@ package scenario
 import (
 	"context"
 	"fmt"
-	"math/rand"
 	"strings"
 
+	"github.com/isucon/isucon13/bench/internal/bencherror"
+	"github.com/isucon/isucon13/bench/internal/benchrand"
 	"github.com/isucon/isucon13/bench/internal/config"
 	"github.com/isucon/isucon13/bench/internal/resolver"
-	"github.com/najeira/randstr"
+	"github.com/miekg/dns"
 )
 
 func dnsRecordPretest(ctx context.Context, dnsResolver *resolver.DNSResolver) error {
@@ -17,15 +18,28 @@ func dnsRecordPretest(ctx context.Context, dnsResolver *resolver.DNSResolver) er
 		return fmt.Errorf("名前解決エラー: %v", err)
 	}
 	for i := 0; i < 10; i++ {
-		r := config.DefaultDNSRecord[rand.Intn(len(config.DefaultDNSRecord))]
+		r := config.DefaultDNSRecord[benchrand.Intn(len(config.DefaultDNSRecord))]
 		_, err := dnsResolver.Lookup(ctx, "udp", fmt.Sprintf("%s.%s", r, config.BaseDomain))
 		if err != nil {
 			return fmt.Errorf("名前解決エラー: %v", err)
 		}
 	}
+	// EDNS0で広告したサイズを守るか
+	for _, size := range config.EDNSPayloadSizes {
+		for _, qtype := range []uint16{dns.TypeA, dns.TypeANY} {
+			name := fmt.Sprintf("%s.%s", "pipe", config.BaseDomain)
+			if qtype == dns.TypeANY {
+				// NOTE: ゾーンの頂点には複数のレコードがあり、応答が大きくなる
+				name = config.BaseDomain
+			}
+			if err := dnsResolver.CheckEDNS(ctx, name, qtype, size); err != nil {
+				return bencherror.NewDNSEDNSError(ctx, err, "EDNS0のクエリへの応答が不正です")
+			}
+		}
+	}
 	// 存在しない名前で
 	for i := 0; i < 3; i++ {
-		r := strings.ToLower(randstr.String(16))
+		r := strings.ToLower(benchrand.String(16))
 		_, err = dnsResolver.Lookup(ctx, "udp", fmt.Sprintf("%s.%s", r, config.BaseDomain))
 		if err != nil && strings.Contains(err.Error(), "サーバーリストに含まれていません") {
 			// is not in the server listの時だけerr。それ以外は無視できる
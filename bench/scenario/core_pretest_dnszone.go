@@ -0,0 +1,63 @@
+package scenario
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/isucon/isucandar/agent"
+	"github.com/isucon/isucon13/bench/internal/bencherror"
+	"github.com/isucon/isucon13/bench/internal/benchrand"
+	"github.com/isucon/isucon13/bench/internal/config"
+	"github.com/isucon/isucon13/bench/internal/resolver"
+	"github.com/isucon/isucon13/bench/isupipe"
+	"go.uber.org/zap"
+)
+
+// dnsZonePretest は、ゾーンのSOA・NSレコードによる委譲が要件を満たすか、ユーザ登録の前後でSOAレコードのシリアルが減らないかを検証します
+// NOTE: 参照実装は pdnsutil add-record でレコードを追加し、シリアルを更新しないため、シリアルが増えないことは選手への警告にとどめる
+func dnsZonePretest(ctx context.Context, contestantLogger *zap.Logger, dnsResolver *resolver.DNSResolver) error {
+	before, err := dnsResolver.QueryZone(ctx, config.BaseDomain)
+	if err != nil {
+		return bencherror.NewDNSZoneError(ctx, err, "ゾーン「%s」のSOA・NSレコードを取得できません", config.BaseDomain)
+	}
+	if problems := before.Misconfigurations(config.ZoneNameservers); len(problems) > 0 {
+		return bencherror.NewDNSZoneError(ctx, errors.New(strings.Join(problems, ", ")), "ゾーン「%s」の委譲が要件を満たしていません", config.BaseDomain)
+	}
+
+	client, err := isupipe.NewCustomResolverClient(
+		ctx,
+		contestantLogger,
+		dnsResolver,
+		agent.WithTimeout(config.PretestTimeout),
+	)
+	if err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%szone", strings.ToLower(benchrand.String(10)))
+	if _, err := client.Register(ctx, &isupipe.RegisterRequest{
+		Name:        name,
+		DisplayName: randDisplayName(),
+		Description: "ゾーンの確認用ユーザです",
+		Password:    benchrand.String(12),
+	}); err != nil {
+		return err
+	}
+
+	after, err := dnsResolver.QueryZone(ctx, config.BaseDomain)
+	if err != nil {
+		return bencherror.NewDNSZoneError(ctx, err, "ユーザ登録後に、ゾーン「%s」のSOA・NSレコードを取得できません", config.BaseDomain)
+	}
+	if problems := after.Misconfigurations(config.ZoneNameservers); len(problems) > 0 {
+		return bencherror.NewDNSZoneError(ctx, errors.New(strings.Join(problems, ", ")), "ユーザ登録後に、ゾーン「%s」の委譲が要件を満たさなくなりました", config.BaseDomain)
+	}
+	switch oldSerial, newSerial := before.SOA.Serial, after.SOA.Serial; {
+	case resolver.SerialDecreased(oldSerial, newSerial):
+		return bencherror.NewDNSZoneError(ctx, fmt.Errorf("serial: %d -> %d", oldSerial, newSerial), "ユーザ登録後に、ゾーン「%s」のSOAレコードのシリアルが減少しました", config.BaseDomain)
+	case !resolver.SerialIncreased(oldSerial, newSerial):
+		contestantLogger.Warn("ユーザ登録後も、ゾーンのSOAレコードのシリアルが増えていません。セカンダリのネームサーバやキャッシュに変更が伝わらない可能性があります",
+			zap.String("zone", config.BaseDomain), zap.Uint32("serial", newSerial))
+	}
+	return nil
+}
@@ -16,6 +16,7 @@ import (
 func normalInitialPaymentPretest(ctx context.Context, contestantLogger *zap.Logger, dnsResolver *resolver.DNSResolver) error {
 	// 初期状態で0円であるか
 	client, err := isupipe.NewCustomResolverClient(
+		ctx,
 		contestantLogger,
 		dnsResolver,
 		agent.WithTimeout(config.PretestTimeout),
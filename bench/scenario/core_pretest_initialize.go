@@ -0,0 +1,86 @@
+package scenario
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+
+	"github.com/isucon/isucandar/agent"
+	"github.com/isucon/isucon13/bench/internal/config"
+	"github.com/isucon/isucon13/bench/internal/resolver"
+	"github.com/isucon/isucon13/bench/internal/scheduler"
+	"github.com/isucon/isucon13/bench/isupipe"
+	"go.uber.org/zap"
+)
+
+// InitializeDataset は、initializeのレスポンスで報告された初期データの情報です
+// NOTE: initialize後に設定され、pretestで要求した規模や機能フラグが反映されているかの検証に用います
+var InitializeDataset *isupipe.InitializeDataset
+
+// scaledInitialCount は、要求した規模で投入されるべき初期データの件数を返します
+func scaledInitialCount(n int) int64 {
+	return int64(math.Round(float64(n) * config.InitializeScale))
+}
+
+// initializeDatasetPretest は、initializeで要求した規模・機能フラグでwebappが初期化されたか検証します
+// NOTE: パラメータを指定していない場合は検証しない
+func initializeDatasetPretest(ctx context.Context, contestantLogger *zap.Logger, dnsResolver *resolver.DNSResolver) error {
+	if !config.HasInitializeParams() {
+		return nil
+	}
+
+	dataset := InitializeDataset
+	if dataset == nil {
+		return fmt.Errorf("initializeのレスポンスに初期データの情報(dataset)が含まれていません")
+	}
+	for _, feature := range config.InitializeFeatureList() {
+		if !slices.Contains(dataset.Features, feature) {
+			return fmt.Errorf("initializeで要求した機能フラグ %s が有効になっていません (actual: %v)", feature, dataset.Features)
+		}
+	}
+	if config.InitializeScale == 0 {
+		return nil
+	}
+	if dataset.Scale != config.InitializeScale {
+		return fmt.Errorf("initializeで要求した初期データの規模が反映されていません (expected: %v, actual: %v)", config.InitializeScale, dataset.Scale)
+	}
+	if want := scaledInitialCount(scheduler.InitialUserCount()); dataset.Users != want {
+		return fmt.Errorf("初期データのユーザ数が要求した規模と一致しません (expected: %d, actual: %d)", want, dataset.Users)
+	}
+	wantLivestreams := scaledInitialCount(scheduler.InitialLivestreamCount())
+	if dataset.Livestreams != wantLivestreams {
+		return fmt.Errorf("初期データのライブ配信数が要求した規模と一致しません (expected: %d, actual: %d)", wantLivestreams, dataset.Livestreams)
+	}
+
+	// 報告された件数だけでなく、実際に投入されたライブ配信の範囲を確認する
+	// NOTE: pretestで予約を行う前に実施する必要がある
+	client, err := isupipe.NewCustomResolverClient(
+		ctx,
+		contestantLogger,
+		dnsResolver,
+		agent.WithTimeout(config.PretestTimeout),
+	)
+	if err != nil {
+		return err
+	}
+	user, err := scheduler.UserScheduler.GetInitialUserForPretest(1)
+	if err != nil {
+		return err
+	}
+	if err := client.Login(ctx, &isupipe.LoginRequest{
+		Username: user.Name,
+		Password: user.RawPassword,
+	}); err != nil {
+		return err
+	}
+	if _, err := client.GetLivestream(ctx, wantLivestreams, user.Name); err != nil {
+		return err
+	}
+	if _, err := client.GetLivestream(ctx, wantLivestreams+1, user.Name, isupipe.WithStatusCode(http.StatusNotFound)); err != nil {
+		return err
+	}
+
+	return nil
+}
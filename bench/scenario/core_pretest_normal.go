@@ -1,23 +1,23 @@
 package scenario
 
 import (
-	"bytes"
 	"context"
 	"crypto/sha256"
 	_ "embed"
 	"fmt"
-	"math/rand"
 	"reflect"
 	"slices"
 	"strings"
 	"time"
 
 	"github.com/isucon/isucandar/agent"
+	"github.com/isucon/isucon13/bench/internal/bencherror"
+	"github.com/isucon/isucon13/bench/internal/benchrand"
 	"github.com/isucon/isucon13/bench/internal/config"
+	"github.com/isucon/isucon13/bench/internal/mediacheck"
 	"github.com/isucon/isucon13/bench/internal/resolver"
 	"github.com/isucon/isucon13/bench/internal/scheduler"
 	"github.com/isucon/isucon13/bench/isupipe"
-	"github.com/najeira/randstr"
 	"go.uber.org/zap"
 )
 
@@ -31,6 +31,7 @@ const IconHashAppliedDelay = 2 * time.Second
 
 func NormalUserPretest(ctx context.Context, contestantLogger *zap.Logger, dnsResolver *resolver.DNSResolver) error {
 	client, err := isupipe.NewCustomResolverClient(
+		ctx,
 		contestantLogger,
 		dnsResolver,
 		agent.WithTimeout(config.PretestTimeout),
@@ -140,6 +141,7 @@ func NormalLivestreamPretest(ctx context.Context, contestantLogger *zap.Logger,
 	// enter/exitできるか (other)
 
 	client, err := isupipe.NewCustomResolverClient(
+		ctx,
 		contestantLogger,
 		dnsResolver,
 		agent.WithTimeout(config.PretestTimeout),
@@ -149,6 +151,7 @@ func NormalLivestreamPretest(ctx context.Context, contestantLogger *zap.Logger,
 	}
 
 	clientNoSession, err := isupipe.NewCustomResolverClient(
+		ctx,
 		contestantLogger,
 		dnsResolver,
 		agent.WithTimeout(config.PretestTimeout),
@@ -217,7 +220,7 @@ func NormalLivestreamPretest(ctx context.Context, contestantLogger *zap.Logger,
 
 	tags := []int64{1, 103}
 	for len(tags) <= 10 {
-		t := rand.Intn(len(tagResponse.Tags))
+		t := benchrand.Intn(len(tagResponse.Tags))
 		tags = append(tags, tagResponse.Tags[t].ID)
 		slices.Sort(tags)
 		tags = slices.Compact(tags)
@@ -232,8 +235,8 @@ func NormalLivestreamPretest(ctx context.Context, contestantLogger *zap.Logger,
 		startAt = time.Date(2024, 4, 1, 0, 0, 0, 0, time.Local)
 		endAt   = time.Date(2024, 4, 1, 1, 0, 0, 0, time.Local)
 	)
-	title := randstr.String(19)
-	description := randstr.String(29)
+	title := benchrand.String(19)
+	description := benchrand.String(29)
 	livestream, err := client.ReserveLivestream(ctx, testUser.Name, &isupipe.ReserveLivestreamRequest{
 		Tags:         tags,
 		Title:        title,
@@ -267,13 +270,20 @@ func NormalLivestreamPretest(ctx context.Context, contestantLogger *zap.Logger,
 		return err
 	}
 
+	// NOTE: 予約したばかりの配信なので、視聴者はこのクライアントのみ
 	if err := client.EnterLivestream(ctx, livestream.ID, livestream.Owner.Name); err != nil {
 		return err
 	}
+	if err := assertPretestViewersCount(ctx, client, livestream, "入室", 1); err != nil {
+		return err
+	}
 
 	if err := client.ExitLivestream(ctx, livestream.ID, livestream.Owner.Name); err != nil {
 		return err
 	}
+	if err := assertPretestViewersCount(ctx, client, livestream, "退室", 0); err != nil {
+		return err
+	}
 
 	{
 		searchedStream, err := client.SearchLivestreams(ctx, isupipe.WithSearchTagQueryParam("椅子"))
@@ -298,7 +308,7 @@ func NormalLivestreamPretest(ctx context.Context, contestantLogger *zap.Logger,
 		// ランダムn個目
 		for i := 0; i < 5; i++ {
 			tagPool := scheduler.GetStreamIDsByTagID(103)
-			randNumber := rand.Intn(50) + pretestTags[103]
+			randNumber := benchrand.Intn(50) + pretestTags[103]
 			livestreamID := tagPool[len(tagPool)-randNumber-1]
 			if searchedStream[randNumber+pretestTags[103]].ID != livestreamID {
 				return fmt.Errorf("「椅子」検索結果の%d番目のlivestream.idが一致しません (expected:%d actual:%d)", randNumber, livestreamID, searchedStream[randNumber+pretestTags[103]].ID)
@@ -319,8 +329,8 @@ func NormalLivestreamPretest(ctx context.Context, contestantLogger *zap.Logger,
 		startAt2nd = time.Date(2024, 4, 1, 1, 0, 0, 0, time.Local)
 		endAt2nd   = time.Date(2024, 4, 1, 2, 0, 0, 0, time.Local)
 	)
-	title2nd := randstr.String(12)
-	description2nd := randstr.String(36)
+	title2nd := benchrand.String(12)
+	description2nd := benchrand.String(36)
 	tags2nd := []int64{1, 2, 103}
 	pretestTags[1]++
 	pretestTags[2]++
@@ -366,9 +376,9 @@ func NormalLivestreamPretest(ctx context.Context, contestantLogger *zap.Logger,
 		for i := 0; i < 19; i++ {
 			startAtExt := time.Date(2024, 4, 1, i+2, 0, 0, 0, time.Local)
 			endAtExt := time.Date(2024, 4, 1, i+3, 0, 0, 0, time.Local)
-			titleExt := randstr.String(17 + rand.Intn(19))
-			descriptionExt := randstr.String(51 + rand.Intn(19))
-			tagId := int64(rand.Intn(99)) + 1
+			titleExt := benchrand.String(17 + benchrand.Intn(19))
+			descriptionExt := benchrand.String(51 + benchrand.Intn(19))
+			tagId := int64(benchrand.Intn(99)) + 1
 			tagsExt := []int64{tagId, tagId + 1}
 			pretestTags[tagId]++
 			pretestTags[tagId+1]++
@@ -392,7 +402,7 @@ func NormalLivestreamPretest(ctx context.Context, contestantLogger *zap.Logger,
 	}
 
 	for i := 0; i < 7; i++ {
-		tagID := int64(rand.Intn(len(tagResponse.Tags))) + 1
+		tagID := int64(benchrand.Intn(len(tagResponse.Tags))) + 1
 		searchedStream, err := client.SearchLivestreams(ctx, isupipe.WithSearchTagQueryParam(tagNames[tagID]))
 		if err != nil {
 			return err
@@ -402,7 +412,7 @@ func NormalLivestreamPretest(ctx context.Context, contestantLogger *zap.Logger,
 		}
 
 		tagPool := scheduler.GetStreamIDsByTagID(tagID)
-		randNumber := rand.Intn(50) + pretestTags[tagID]
+		randNumber := benchrand.Intn(50) + pretestTags[tagID]
 		livestreamID := tagPool[len(tagPool)-randNumber-1]
 		if searchedStream[randNumber+pretestTags[tagID]].ID != livestreamID {
 			return fmt.Errorf("「%s」検索結果の%d番目のlivestream.idが一致しません (expected:%d actual:%d)", tagNames[tagID], randNumber+1, livestreamID, searchedStream[randNumber+pretestTags[tagID]].ID)
@@ -428,7 +438,7 @@ func NormalLivestreamPretest(ctx context.Context, contestantLogger *zap.Logger,
 			return fmt.Errorf("タグ指定なし検索結果の数が想定外です (expected:%d actual:%d)", config.NumSearchLivestreams, len(searchedStream))
 		}
 		for i := 0; i < 5; i++ {
-			randNumber := rand.Intn(20)
+			randNumber := benchrand.Intn(20)
 			if searchedStream[randNumber].ID != reserveStreams[randNumber] {
 				return fmt.Errorf("タグ指定なし検索結果の%d番目のlivestream.idが一致しません (expected:%d actual:%d)", randNumber+1, reserveStreams[randNumber], searchedStream[randNumber].ID)
 			}
@@ -444,13 +454,13 @@ func NormalLivestreamPretest(ctx context.Context, contestantLogger *zap.Logger,
 			return fmt.Errorf("タグ指定なし検索結果の数が想定外です (expected:%d actual:%d)", config.NumSearchLivestreams, len(searchedStream))
 		}
 		for i := 0; i < 5; i++ {
-			randNumber := rand.Intn(20)
+			randNumber := benchrand.Intn(20)
 			if searchedStream[randNumber].ID != reserveStreams[randNumber] {
 				return fmt.Errorf("タグ指定なし検索結果の%d番目のlivestream.idが一致しません (expected:%d actual:%d)", randNumber+1, reserveStreams[randNumber], searchedStream[randNumber].ID)
 			}
 		}
 		for i := 0; i < 5; i++ {
-			randNumber := rand.Intn(20) + 25
+			randNumber := benchrand.Intn(20) + 25
 			livestreamID := int64(scheduler.GetLivestreamLength()+len(reserveStreams)-randNumber) + 1
 			if searchedStream[randNumber].ID != livestreamID {
 				return fmt.Errorf("タグ指定なし検索結果の%d番目のlivestream.idが一致しません (expected:%d actual:%d)", randNumber+1, livestreamID, searchedStream[randNumber].ID)
@@ -471,6 +481,7 @@ func NormalLivestreamPretest(ctx context.Context, contestantLogger *zap.Logger,
 
 func NormalIconPretest(ctx context.Context, contestantLogger *zap.Logger, dnsResolver *resolver.DNSResolver) error {
 	client, err := isupipe.NewCustomResolverClient(
+		ctx,
 		contestantLogger,
 		dnsResolver,
 		agent.WithTimeout(config.PretestTimeout),
@@ -486,13 +497,14 @@ func NormalIconPretest(ctx context.Context, contestantLogger *zap.Logger, dnsRes
 		return err
 	}
 
-	// アイコンを投稿する前、No Imageの画像が返されているか
-	icon1, err := client.GetIcon(ctx, "test001")
+	iconComparator, err := mediacheck.Get(config.IconComparator)
 	if err != nil {
-		return err
+		return bencherror.NewInternalError(ctx, err)
 	}
-	if !bytes.Equal(icon1[:], fallbackImage[:]) {
-		return fmt.Errorf("アイコン未設定の場合は、NoImage.jpgを返さなければなりません")
+
+	// アイコンを投稿する前、No Imageの画像がそのまま返されているか
+	if err := verifyFallbackIcon(ctx, client, "test001"); err != nil {
+		return err
 	}
 
 	// アイコンを投稿する前、No Imageの画像のハッシュが返されているか
@@ -537,9 +549,8 @@ func NormalIconPretest(ctx context.Context, contestantLogger *zap.Logger, dnsRes
 	if err != nil {
 		return err
 	}
-	icon2Hash := sha256.Sum256(icon2)
-	if !bytes.Equal(icon2Hash[:], randomIcon.Hash[:]) {
-		return fmt.Errorf("新たに設定したアイコンが反映されていません")
+	if err := iconComparator.Compare(randomIcon.Image, icon2); err != nil {
+		return fmt.Errorf("新たに設定したアイコンが反映されていません: %w", err)
 	}
 
 	// マッチするetag付きでリクエストする(レスポンスは200でも304でもどっちでもOK)
@@ -553,9 +564,8 @@ func NormalIconPretest(ctx context.Context, contestantLogger *zap.Logger, dnsRes
 	if err != nil {
 		return err
 	}
-	icon3Hash := sha256.Sum256(icon3)
-	if !bytes.Equal(icon3Hash[:], randomIcon.Hash[:]) {
-		return fmt.Errorf("設定したアイコンが反映されていません")
+	if err := iconComparator.Compare(randomIcon.Image, icon3); err != nil {
+		return fmt.Errorf("設定したアイコンが反映されていません: %w", err)
 	}
 
 	// アイコンを投稿後、期待するアイコンが設定されているか
@@ -574,6 +584,7 @@ func NormalIconPretest(ctx context.Context, contestantLogger *zap.Logger, dnsRes
 
 func NormalPostLivecommentPretest(ctx context.Context, contestantLogger *zap.Logger, testUser *isupipe.User, dnsResolver *resolver.DNSResolver) error {
 	client, err := isupipe.NewCustomResolverClient(
+		ctx,
 		contestantLogger,
 		dnsResolver,
 		agent.WithTimeout(config.PretestTimeout),
@@ -598,7 +609,7 @@ func NormalPostLivecommentPretest(ctx context.Context, contestantLogger *zap.Log
 		return fmt.Errorf("自分のライブ配信が存在しません")
 	}
 
-	livestream := livestreams[rand.Intn(len(livestreams))] // ランダムに選ぶ
+	livestream := livestreams[benchrand.Intn(len(livestreams))] // ランダムに選ぶ
 	if livestream.Owner.ID != testUser.ID {
 		return fmt.Errorf("自分がownerではないlivestreamが返されました expected:%s actual:%s", testUser.Name, livestream.Owner.Name)
 	}
@@ -665,6 +676,7 @@ func NormalReactionPretest(ctx context.Context, contestantLogger *zap.Logger, te
 	// limitをつけられるか
 	// 初期データが期待する件数あるか
 	client, err := isupipe.NewCustomResolverClient(
+		ctx,
 		contestantLogger,
 		dnsResolver,
 		agent.WithTimeout(config.PretestTimeout),
@@ -731,6 +743,7 @@ func NormalReportLivecommentPretest(ctx context.Context, contestantLogger *zap.L
 	// 初期で報告が0件
 
 	client, err := isupipe.NewCustomResolverClient(
+		ctx,
 		contestantLogger,
 		dnsResolver,
 		agent.WithTimeout(config.PretestTimeout),
@@ -769,12 +782,13 @@ func NormalReportLivecommentPretest(ctx context.Context, contestantLogger *zap.L
 		return fmt.Errorf("limitを使用してライブコメント取得しましたが、指定件数が返ってきませんでした")
 	}
 
-	rand.Shuffle(len(livecomments), func(i, j int) {
+	benchrand.Shuffle(len(livecomments), func(i, j int) {
 		livecomments[i], livecomments[j] = livecomments[j], livecomments[i]
 	})
 	livecomment := livecomments[0]
 
 	reporterClient, err := isupipe.NewCustomResolverClient(
+		ctx,
 		contestantLogger,
 		dnsResolver,
 		agent.WithTimeout(config.PretestTimeout),
@@ -783,8 +797,8 @@ func NormalReportLivecommentPretest(ctx context.Context, contestantLogger *zap.L
 		return err
 	}
 
-	name := fmt.Sprintf("%srpt", randstr.String(11))
-	passwd := randstr.String(13)
+	name := fmt.Sprintf("%srpt", benchrand.String(11))
+	passwd := benchrand.String(13)
 	reporter, err := reporterClient.Register(ctx, &isupipe.RegisterRequest{
 		Name:        name,
 		DisplayName: randDisplayName(),
@@ -824,6 +838,7 @@ func NormalModerateLivecommentPretest(ctx context.Context, contestantLogger *zap
 	// 投稿済みのスパムライブコメントが、moderateによって粛清されているか
 	// ライブコメントを投稿してきちんとエラーを返せているか
 	client, err := isupipe.NewCustomResolverClient(
+		ctx,
 		contestantLogger,
 		dnsResolver,
 		agent.WithTimeout(config.PretestTimeout),
@@ -851,7 +866,7 @@ func NormalModerateLivecommentPretest(ctx context.Context, contestantLogger *zap
 			return fmt.Errorf("自分がownerではないlivestreamが返されました expected:%s actual:%s", testUser.Name, livestream.Owner.Name)
 		}
 	}
-	livestream := livestreams[rand.Intn(len(livestreams))] // ランダムに選ぶ
+	livestream := livestreams[benchrand.Intn(len(livestreams))] // ランダムに選ぶ
 
 	ngwords, err := client.GetNgwords(ctx, livestream.ID, livestream.Owner.Name)
 	if err != nil {
@@ -868,6 +883,7 @@ func NormalModerateLivecommentPretest(ctx context.Context, contestantLogger *zap
 
 	// スパム投稿
 	spammerClient, err := isupipe.NewCustomResolverClient(
+		ctx,
 		contestantLogger,
 		dnsResolver,
 		agent.WithTimeout(config.PretestTimeout),
@@ -876,8 +892,8 @@ func NormalModerateLivecommentPretest(ctx context.Context, contestantLogger *zap
 		return err
 	}
 
-	name := fmt.Sprintf("%sspm", randstr.String(11))
-	passwd := randstr.String(18)
+	name := fmt.Sprintf("%sspm", benchrand.String(11))
+	passwd := benchrand.String(18)
 	_, err = spammerClient.Register(ctx, &isupipe.RegisterRequest{
 		Name:        name,
 		DisplayName: randDisplayName(),
@@ -967,3 +983,15 @@ func NormalModerateLivecommentPretest(ctx context.Context, contestantLogger *zap
 
 	return nil
 }
+
+// assertPretestViewersCount は、入退室した直後の配信の視聴者数を検証します
+func assertPretestViewersCount(ctx context.Context, client *isupipe.Client, livestream *isupipe.Livestream, action string, expected int64) error {
+	stats, err := client.GetLivestreamStatistics(ctx, livestream.ID, livestream.Owner.Name)
+	if err != nil {
+		return err
+	}
+	if stats.ViewersCount != expected {
+		return fmt.Errorf("%s後の配信の視聴者数が不正です (livestream_id:%d expected:%d actual:%d)", action, livestream.ID, expected, stats.ViewersCount)
+	}
+	return nil
+}
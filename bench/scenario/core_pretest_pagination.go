@@ -0,0 +1,134 @@
+package scenario
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/isucon/isucandar/agent"
+	"github.com/isucon/isucon13/bench/internal/bencherror"
+	"github.com/isucon/isucon13/bench/internal/benchrand"
+	"github.com/isucon/isucon13/bench/internal/config"
+	"github.com/isucon/isucon13/bench/internal/resolver"
+	"github.com/isucon/isucon13/bench/internal/scheduler"
+	"github.com/isucon/isucon13/bench/isupipe"
+	"go.uber.org/zap"
+)
+
+// ページングの検証のために投稿するライブコメント数
+const paginationLivecomments = 3
+
+// ページングの検証で指定する件数
+const paginationLimit = 2
+
+// paginationPretest は、一覧取得のエンドポイントが limit クエリパラメータに従い、新しい順に返すことを確かめます
+// 全件数を超える limit では全件を返すことも確かめます
+// NOTE: limit を無視して全件返す、あるいは並び順を省いて先頭から返すといった手抜きを検出するためのもの
+// NOTE: API仕様に offset クエリパラメータは存在しないため、件数の上限と並び順のみ検証する
+// NOTE: limit=0 の扱いは参照実装によって異なる (perlは未指定と同様に全件を返す) ため、検証しない
+func paginationPretest(ctx context.Context, contestantLogger *zap.Logger, testUser *isupipe.User, dnsResolver *resolver.DNSResolver) error {
+	client, err := isupipe.NewCustomResolverClient(
+		ctx,
+		contestantLogger,
+		dnsResolver,
+		agent.WithTimeout(config.PretestTimeout),
+	)
+	if err != nil {
+		return bencherror.NewInternalError(ctx, err)
+	}
+
+	if err := client.Login(ctx, &isupipe.LoginRequest{
+		Username: testUser.Name,
+		Password: defaultPasswordOrPretest(testUser.Name),
+	}); err != nil {
+		return err
+	}
+
+	if err := livecommentPaginationPretest(ctx, client, testUser); err != nil {
+		return err
+	}
+	return livestreamPaginationPretest(ctx, client)
+}
+
+func livecommentPaginationPretest(ctx context.Context, client *isupipe.Client, testUser *isupipe.User) error {
+	livestreams, err := client.GetMyLivestreams(ctx)
+	if err != nil {
+		return err
+	}
+	if len(livestreams) == 0 {
+		return fmt.Errorf("自分のライブ配信が存在しません")
+	}
+	livestream := livestreams[benchrand.Intn(len(livestreams))]
+
+	notip := &scheduler.Tip{}
+	for i := 0; i < paginationLivecomments; i++ {
+		if _, _, err := client.PostLivecomment(ctx, livestream.ID, testUser.Name, fmt.Sprintf("pagination %d", i), notip); err != nil {
+			return err
+		}
+	}
+
+	const name = "GET /api/livestream/:livestream_id/livecomment"
+	all, err := client.GetLivecomments(ctx, livestream.ID, testUser.Name)
+	if err != nil {
+		return err
+	}
+	if len(all) < paginationLivecomments {
+		return bencherror.NewViolationError(ctx, fmt.Errorf("expected:%d以上 actual:%d", paginationLivecomments, len(all)), "%s が投稿したライブコメントを返しません", name)
+	}
+	for i := 1; i < len(all); i++ {
+		if all[i-1].CreatedAt < all[i].CreatedAt {
+			return bencherror.NewViolationError(ctx, fmt.Errorf("id=%d (created_at=%d) が id=%d (created_at=%d) より前に返されました", all[i-1].ID, all[i-1].CreatedAt, all[i].ID, all[i].CreatedAt), "%s は新しい順にライブコメントを返さなければなりません", name)
+		}
+	}
+
+	limited, err := client.GetLivecomments(ctx, livestream.ID, testUser.Name, isupipe.WithLimitQueryParam(paginationLimit))
+	if err != nil {
+		return err
+	}
+	if len(limited) != paginationLimit {
+		return bencherror.NewViolationError(ctx, fmt.Errorf("expected:%d actual:%d", paginationLimit, len(limited)), "%s?limit=%d の件数が正しくありません", name, paginationLimit)
+	}
+	// NOTE: 作成日時が同じライブコメントの順序は定まらないため、返されたものが最新のものであることのみ確かめる
+	limitedIDs := make(map[int64]struct{}, len(limited))
+	oldest := limited[0].CreatedAt
+	for _, livecomment := range limited {
+		limitedIDs[livecomment.ID] = struct{}{}
+		oldest = min(oldest, livecomment.CreatedAt)
+	}
+	for _, livecomment := range all {
+		if _, ok := limitedIDs[livecomment.ID]; ok {
+			continue
+		}
+		if livecomment.CreatedAt > oldest {
+			return bencherror.NewViolationError(ctx, fmt.Errorf("より新しい id=%d (created_at=%d) が含まれていません", livecomment.ID, livecomment.CreatedAt), "%s?limit=%d は最新のライブコメントを返さなければなりません", name, paginationLimit)
+		}
+	}
+
+	beyond, err := client.GetLivecomments(ctx, livestream.ID, testUser.Name, isupipe.WithLimitQueryParam(len(all)+paginationLimit))
+	if err != nil {
+		return err
+	}
+	if len(beyond) != len(all) {
+		return bencherror.NewViolationError(ctx, fmt.Errorf("expected:%d actual:%d", len(all), len(beyond)), "%s?limit=%d は全件を返さなければなりません", name, len(all)+paginationLimit)
+	}
+
+	return nil
+}
+
+func livestreamPaginationPretest(ctx context.Context, client *isupipe.Client) error {
+	const name = "GET /api/livestream/search"
+
+	// NOTE: 初期データに十分な数のライブ配信があるため、全件の取得は行わない
+	limited, err := client.SearchLivestreams(ctx, isupipe.WithLimitQueryParam(paginationLimit))
+	if err != nil {
+		return err
+	}
+	if len(limited) != paginationLimit {
+		return bencherror.NewViolationError(ctx, fmt.Errorf("expected:%d actual:%d", paginationLimit, len(limited)), "%s?limit=%d の件数が正しくありません", name, paginationLimit)
+	}
+	for i := 1; i < len(limited); i++ {
+		if limited[i-1].ID <= limited[i].ID {
+			return bencherror.NewViolationError(ctx, fmt.Errorf("id=%d が id=%d より前に返されました", limited[i-1].ID, limited[i].ID), "%s は新しい順にライブ配信を返さなければなりません", name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package scenario
+
+import (
+	"context"
+
+	"github.com/isucon/isucandar/agent"
+	"github.com/isucon/isucon13/bench/internal/config"
+	"github.com/isucon/isucon13/bench/internal/resolver"
+	"github.com/isucon/isucon13/bench/isupipe"
+	"go.uber.org/zap"
+)
+
+// HEADとGETの一致を検証するエンドポイント (走行中に内容が変化しないもの)
+var headPretestPaths = []string{
+	"/api/tag",
+}
+
+// headPretest は、HEADを実装している場合に、レスポンスがGETと一致するか検証します
+// NOTE: Content-Lengthとボディの長さの一致は、全てのリクエストで検証している
+func headPretest(ctx context.Context, contestantLogger *zap.Logger, dnsResolver *resolver.DNSResolver) error {
+	lgr := zap.S()
+
+	client, err := isupipe.NewCustomResolverClient(
+		ctx,
+		contestantLogger,
+		dnsResolver,
+		agent.WithTimeout(config.PretestTimeout),
+	)
+	if err != nil {
+		return err
+	}
+
+	for _, path := range headPretestPaths {
+		supported, err := client.VerifyHead(ctx, path)
+		if err != nil {
+			return err
+		}
+		if !supported {
+			lgr.Infof("pretest: HEAD %s is not supported", path)
+		}
+	}
+
+	return nil
+}
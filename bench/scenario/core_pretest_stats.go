@@ -0,0 +1,52 @@
+package scenario
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/isucon/isucandar/agent"
+	"github.com/isucon/isucon13/bench/internal/config"
+	"github.com/isucon/isucon13/bench/internal/resolver"
+	"github.com/isucon/isucon13/bench/internal/scheduler"
+	"github.com/isucon/isucon13/bench/isupipe"
+	"go.uber.org/zap"
+)
+
+// statsReconcilePretest は、pretestで行ったリアクション・ライブコメント・スパム報告・入退室の記録から統計情報を再計算し、webappの統計情報と突き合わせます
+// NOTE: 最終チェックと同じく、初期データを含む配信・配信者は対象外とする. pretestで予約した配信とテストユーザが対象となる
+func statsReconcilePretest(ctx context.Context, contestantLogger *zap.Logger, dnsResolver *resolver.DNSResolver) error {
+	client, err := isupipe.NewCustomResolverClient(
+		ctx,
+		contestantLogger,
+		dnsResolver,
+		agent.WithTimeout(config.PretestTimeout),
+	)
+	if err != nil {
+		return err
+	}
+
+	// NOTE: 統計情報の取得にはログインが必要
+	user, err := scheduler.UserScheduler.GetInitialUserForPretest(1)
+	if err != nil {
+		return err
+	}
+	if err := client.Login(ctx, &isupipe.LoginRequest{
+		Username: user.Name,
+		Password: user.RawPassword,
+	}); err != nil {
+		return err
+	}
+
+	stats, err := reconcileStats(ctx, client)
+	if err != nil {
+		return err
+	}
+	if len(stats.Drifts) > 0 {
+		msg := stats.Drifts[0].String()
+		if rest := len(stats.Drifts) - 1; rest > 0 {
+			msg = fmt.Sprintf("%s (ほか %d 件の食い違いがあります)", msg, rest)
+		}
+		return fmt.Errorf("統計情報がpretestでの操作と一致しません: %s", msg)
+	}
+	return nil
+}
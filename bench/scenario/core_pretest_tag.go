@@ -0,0 +1,79 @@
+package scenario
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/isucon/isucandar/agent"
+	"github.com/isucon/isucon13/bench/internal/bencherror"
+	"github.com/isucon/isucon13/bench/internal/config"
+	"github.com/isucon/isucon13/bench/internal/resolver"
+	"github.com/isucon/isucon13/bench/internal/scheduler"
+	"github.com/isucon/isucon13/bench/isupipe"
+	"go.uber.org/zap"
+)
+
+// pretestTagStreamerName は、配信者のドメインでタグ一覧を取得する際に用いる初期データのユーザです
+const pretestTagStreamerName = "test001"
+
+// タグ一覧の食い違いを選手向けに表示する最大件数
+const tagListProblemMessages = 5
+
+// normalTagPretest は、タグ一覧がマスタデータと完全に一致し、取得元や取得回数によって変化しないことを確かめます
+// NOTE: タグ一覧のキャッシュの誤りで、重複や欠落が生じることがある
+func normalTagPretest(ctx context.Context, contestantLogger *zap.Logger, dnsResolver *resolver.DNSResolver) error {
+	client, err := isupipe.NewCustomResolverClient(
+		ctx,
+		contestantLogger,
+		dnsResolver,
+		agent.WithTimeout(config.PretestTimeout),
+	)
+	if err != nil {
+		return bencherror.NewInternalError(ctx, err)
+	}
+
+	first, err := client.GetTags(ctx)
+	if err != nil {
+		return err
+	}
+	if err := verifyTagList(ctx, "GET /api/tag", first); err != nil {
+		return err
+	}
+
+	second, err := client.GetTags(ctx)
+	if err != nil {
+		return err
+	}
+	if !reflect.DeepEqual(first, second) {
+		return bencherror.NewViolationError(ctx, fmt.Errorf("1回目と2回目のレスポンスが異なります"), "GET /api/tag は繰り返し取得しても同じタグ一覧を返さなければなりません")
+	}
+
+	withUser, err := client.GetTagsWithUser(ctx, pretestTagStreamerName)
+	if err != nil {
+		return err
+	}
+	if err := verifyTagList(ctx, fmt.Sprintf("配信者(%s)のドメインでの GET /api/tag", pretestTagStreamerName), withUser); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func verifyTagList(ctx context.Context, name string, resp *isupipe.TagsResponse) error {
+	ids := make([]int64, len(resp.Tags))
+	names := make([]string, len(resp.Tags))
+	for i, tag := range resp.Tags {
+		ids[i] = tag.ID
+		names[i] = tag.Name
+	}
+	problems := scheduler.CheckTagList(ids, names)
+	if len(problems) == 0 {
+		return nil
+	}
+	if len(problems) > tagListProblemMessages {
+		problems = append(problems[:tagListProblemMessages], fmt.Sprintf("ほか %d 件", len(problems)-tagListProblemMessages))
+	}
+	return bencherror.NewViolationError(ctx, fmt.Errorf("%s", strings.Join(problems, ", ")), "%s のタグ一覧が初期データと一致しません", name)
+}
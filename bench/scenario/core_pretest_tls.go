@@ -0,0 +1,150 @@
+package scenario
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/isucon/isucon13/bench/internal/bencherror"
+	"github.com/isucon/isucon13/bench/internal/benchrand"
+	"github.com/isucon/isucon13/bench/internal/config"
+	"github.com/isucon/isucon13/bench/internal/resolver"
+)
+
+// tlsPretest は、SSL接続が有効な場合に、webappが提示する証明書とHTTPSへの誘導を検証します
+// NOTE: 通常のクライアントも証明書を検証するが、失敗理由が選手に伝わりにくいため個別に検証する
+func tlsPretest(ctx context.Context, dnsResolver *resolver.DNSResolver) error {
+	if config.HTTPScheme != "https" {
+		return nil
+	}
+
+	pipeHost := fmt.Sprintf("pipe.%s", config.BaseDomain)
+	certs, err := fetchPeerCertificates(ctx, dnsResolver, pipeHost)
+	if err != nil {
+		return err
+	}
+	if err := verifyServerCertificate(ctx, certs, time.Now()); err != nil {
+		return err
+	}
+
+	// 配信者のサブドメインでも同じ証明書が使えること
+	streamerHost := fmt.Sprintf("%s.%s", strings.ToLower(benchrand.String(16)), config.BaseDomain)
+	if err := certs[0].VerifyHostname(streamerHost); err != nil {
+		return bencherror.NewTLSCertificateError(ctx, err, "証明書が %s に対して有効ではありません", config.TLSCertificateDomain)
+	}
+
+	return plainHTTPPretest(ctx, dnsResolver, pipeHost)
+}
+
+// fetchPeerCertificates は、TLSハンドシェイクを行い、webappが提示した証明書チェーンを返します
+func fetchPeerCertificates(ctx context.Context, dnsResolver *resolver.DNSResolver, host string) ([]*x509.Certificate, error) {
+	ctx, cancel := context.WithTimeout(ctx, config.PretestTimeout)
+	defer cancel()
+
+	rawConn, err := dnsResolver.DialContext(ctx, "tcp", net.JoinHostPort(host, strconv.Itoa(config.TargetPort)))
+	if err != nil {
+		return nil, bencherror.NewTLSCertificateError(ctx, err, "%s:%d に接続できません", host, config.TargetPort)
+	}
+	defer rawConn.Close()
+
+	// NOTE: 検証は後段で個別に行うため、ここでは検証せずに証明書を取得する
+	conn := tls.Client(rawConn, &tls.Config{
+		ServerName:         host,
+		InsecureSkipVerify: true,
+	})
+	if err := conn.HandshakeContext(ctx); err != nil {
+		return nil, bencherror.NewTLSCertificateError(ctx, err, "%s とのTLSハンドシェイクに失敗しました", host)
+	}
+
+	certs := conn.ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return nil, bencherror.NewTLSCertificateError(ctx, errors.New("no peer certificates"), "%s が証明書を提示しませんでした", host)
+	}
+	return certs, nil
+}
+
+// verifyServerCertificate は、証明書が対象ドメインに対して有効期間内かつ信頼できるものであることを検証します
+func verifyServerCertificate(ctx context.Context, certs []*x509.Certificate, now time.Time) error {
+	leaf := certs[0]
+
+	if now.Before(leaf.NotBefore) {
+		return bencherror.NewTLSCertificateError(ctx, errors.New("certificate is not yet valid"), "証明書の有効期間が開始していません (NotBefore: %s)", leaf.NotBefore.Format(time.RFC3339))
+	}
+	if now.After(leaf.NotAfter) {
+		return bencherror.NewTLSCertificateError(ctx, errors.New("certificate has expired"), "証明書の有効期限が切れています (NotAfter: %s)", leaf.NotAfter.Format(time.RFC3339))
+	}
+
+	if err := leaf.VerifyHostname(config.TLSCertificateDomain); err != nil {
+		return bencherror.NewTLSCertificateError(ctx, err, "証明書が %s を対象としていません (DNSNames: %v)", config.TLSCertificateDomain, leaf.DNSNames)
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+	if _, err := leaf.Verify(x509.VerifyOptions{
+		Intermediates: intermediates,
+		CurrentTime:   now,
+	}); err != nil {
+		return bencherror.NewTLSCertificateError(ctx, err, "証明書チェーンを検証できません")
+	}
+
+	return nil
+}
+
+// plainHTTPPretest は、平文HTTPのポートへのアクセスがHTTPSへリダイレクトされるか、拒否されることを検証します
+func plainHTTPPretest(ctx context.Context, dnsResolver *resolver.DNSResolver, host string) error {
+	ctx, cancel := context.WithTimeout(ctx, config.PretestTimeout)
+	defer cancel()
+
+	httpClient := &http.Client{
+		Transport: &http.Transport{
+			DialContext:       dnsResolver.DialContext,
+			DisableKeepAlives: true,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	endpoint := fmt.Sprintf("http://%s/api/tag", net.JoinHostPort(host, strconv.Itoa(config.PlainHTTPPort)))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return bencherror.NewInternalError(ctx, err)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		// 接続拒否は許容する
+		if errors.Is(err, syscall.ECONNREFUSED) {
+			return nil
+		}
+		return bencherror.NewTLSCertificateError(ctx, err, "平文HTTP(%d番ポート)へのアクセスが、リダイレクトも拒否もされませんでした", config.PlainHTTPPort)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 300 || resp.StatusCode >= 400 {
+		return bencherror.NewTLSCertificateError(
+			ctx,
+			fmt.Errorf("status code: %d", resp.StatusCode),
+			"平文HTTP(%d番ポート)へのアクセスは、HTTPSへリダイレクトするか拒否しなければなりません", config.PlainHTTPPort)
+	}
+	location, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil || location.Scheme != "https" {
+		return bencherror.NewTLSCertificateError(
+			ctx,
+			fmt.Errorf("location: %q", resp.Header.Get("Location")),
+			"平文HTTP(%d番ポート)へのアクセスが、HTTPSへリダイレクトされませんでした", config.PlainHTTPPort)
+	}
+
+	return nil
+}
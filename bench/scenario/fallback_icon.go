@@ -0,0 +1,32 @@
+package scenario
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"mime"
+
+	"github.com/isucon/isucon13/bench/internal/bencherror"
+	"github.com/isucon/isucon13/bench/internal/config"
+	"github.com/isucon/isucon13/bench/isupipe"
+)
+
+// verifyFallbackIcon は、アイコン未設定のユーザのアイコンとして、NoImage.jpgがそのまま返されるか検証します
+// NOTE: 304で済まされないよう、ETagを付けずに取得する
+func verifyFallbackIcon(ctx context.Context, client *isupipe.Client, username string) error {
+	icon, err := client.GetIconContent(ctx, username)
+	if err != nil {
+		return err
+	}
+
+	mediaType, _, err := mime.ParseMediaType(icon.ContentType)
+	if err != nil || mediaType != config.FallbackIconContentType {
+		return bencherror.NewFallbackIconError(ctx, fmt.Errorf("Content-Type: %q", icon.ContentType), "アイコン未設定のユーザ「%s」のアイコンのContent-Typeは%sでなければなりません", username, config.FallbackIconContentType)
+	}
+
+	want, got := sha256.Sum256(fallbackImage), sha256.Sum256(icon.Image)
+	if want != got {
+		return bencherror.NewFallbackIconError(ctx, fmt.Errorf("sha256: expected=%x, actual=%x (%d bytes)", want, got, len(icon.Image)), "アイコン未設定のユーザ「%s」のアイコンは、NoImage.jpgと一致しなければなりません", username)
+	}
+	return nil
+}
@@ -0,0 +1,182 @@
+package scenario
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/isucon/isucandar/agent"
+	"github.com/isucon/isucon13/bench/internal/benchrand"
+	"github.com/isucon/isucon13/bench/internal/config"
+	"github.com/isucon/isucon13/bench/internal/fuzz"
+	"github.com/isucon/isucon13/bench/internal/resolver"
+	"github.com/isucon/isucon13/bench/internal/scheduler"
+	"github.com/isucon/isucon13/bench/isupipe"
+	"go.uber.org/zap"
+)
+
+// 運営向けのfuzzモード
+// NOTE: 初期化済みのwebappに対して、全エンドポイントのパラメータを範囲内外に変化させたリクエストを送る
+//
+//	書き込み系のエンドポイントも対象とするため、最後に初期化を行うまでのデータは初期データと一致しない
+
+// fuzzモードで用いる初期ユーザ (自身の配信を持つ)
+const fuzzUserID = 22
+
+// FuzzScenario は、全エンドポイントに対してfuzzモードのリクエストを送り、エンドポイントごとの結果を返します
+func FuzzScenario(ctx context.Context, contestantLogger *zap.Logger, dnsResolver *resolver.DNSResolver) (*fuzz.Report, error) {
+	lgr := zap.S()
+
+	user, err := scheduler.UserScheduler.GetInitialUserForPretest(fuzzUserID)
+	if err != nil {
+		return nil, err
+	}
+	client, err := isupipe.NewCustomResolverClient(
+		ctx,
+		contestantLogger,
+		dnsResolver,
+		agent.WithTimeout(config.FuzzTimeout),
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := client.Login(ctx, &isupipe.LoginRequest{
+		Username: user.Name,
+		Password: user.RawPassword,
+	}); err != nil {
+		return nil, err
+	}
+
+	livestreams, err := client.GetUserLivestreams(ctx, user.Name)
+	if err != nil {
+		return nil, err
+	}
+	if len(livestreams) == 0 {
+		return nil, fmt.Errorf("fuzzモードに用いる配信がありません (user=%s)", user.Name)
+	}
+	livestream := livestreams[0]
+	livecommentID := int64(1)
+	if livecomments, err := client.GetLivecomments(ctx, livestream.ID, user.Name); err == nil && len(livecomments) > 0 {
+		livecommentID = livecomments[0].ID
+	}
+
+	// NOTE: 初期化は時間がかかるため、初期化のタイムアウトを用いる
+	initClient, err := isupipe.NewCustomResolverClient(
+		ctx,
+		contestantLogger,
+		dnsResolver,
+		agent.WithTimeout(config.InitializeAgentTimeout),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	report := fuzz.NewReport()
+	send := func(client *isupipe.Client, endpoint *fuzz.Endpoint) error {
+		for _, c := range fuzz.Cases(endpoint) {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			status, body, err := client.SendRaw(ctx, endpoint.Method, c.Path, c.Body)
+			report.Record(c, status, body, err)
+		}
+		lgr.Infof("fuzz: %s を確認しました", endpoint.Name())
+		return nil
+	}
+	for _, endpoint := range fuzzEndpoints(user, livestream.ID, livecommentID) {
+		if err := send(client, endpoint); err != nil {
+			return report, err
+		}
+	}
+	// NOTE: 初期化は他のエンドポイントで書き込んだデータを消すため、最後に行う
+	if err := send(initClient, fuzzInitializeEndpoint()); err != nil {
+		return report, err
+	}
+	return report, nil
+}
+
+// fuzzInitializeEndpoint は、初期化のエンドポイントのモデルを返します
+// NOTE: 初期データの規模を範囲外に変化させると、webappが巨大な初期データを投入しうるため、パラメータは変化させない
+func fuzzInitializeEndpoint() *fuzz.Endpoint {
+	return &fuzz.Endpoint{Method: http.MethodPost, Path: "/api/initialize"}
+}
+
+// fuzzEndpoints は、初期化を除くエンドポイントのモデルを返します
+// NOTE: パラメータを持たないエンドポイントには、有効なリクエストのみを送る
+func fuzzEndpoints(user *scheduler.User, livestreamID, livecommentID int64) []*fuzz.Endpoint {
+	var (
+		username   = fuzz.Param{Name: "username", In: fuzz.InPath, Kind: fuzz.KindName, Valid: user.Name}
+		livestream = fuzz.Param{
+			Name:  "livestream_id",
+			In:    fuzz.InPath,
+			Kind:  fuzz.KindID,
+			Valid: strconv.FormatInt(livestreamID, 10),
+			Min:   1,
+			Max:   int64(scheduler.InitialLivestreamCount()),
+		}
+		limit = fuzz.Param{Name: "limit", In: fuzz.InQuery, Kind: fuzz.KindLimit, Valid: "10", Min: 1, Max: config.FuzzMaxLimit}
+	)
+
+	// 予約は初期データで予約済みの枠を避け、期間の末尾に1時間で行う
+	startAt := int64(config.BaseAt + (config.NumHours-1)*3600)
+	endAt := startAt + 3600
+
+	// 登録するユーザ名は、他のケースで登録したユーザと重複しないようにする
+	registerName := "fuzz" + strings.ToLower(benchrand.String(10))
+
+	return []*fuzz.Endpoint{
+		{Method: http.MethodGet, Path: "/api/tag"},
+		{Method: http.MethodGet, Path: "/api/user/me"},
+		{Method: http.MethodGet, Path: "/api/livestream"},
+		{Method: http.MethodGet, Path: "/api/payment"},
+		{Method: http.MethodPost, Path: "/api/icon", Body: map[string]any{"image": scheduler.IconSched.GetRandomIcon().Image}},
+		{Method: http.MethodPost, Path: "/api/login", Params: []fuzz.Param{
+			{Name: "username", In: fuzz.InBody, Kind: fuzz.KindName, Valid: user.Name},
+		}, Body: map[string]any{"password": user.RawPassword}},
+		{Method: http.MethodGet, Path: "/api/user/{username}", Params: []fuzz.Param{username}},
+		{Method: http.MethodGet, Path: "/api/user/{username}/theme", Params: []fuzz.Param{username}},
+		{Method: http.MethodGet, Path: "/api/user/{username}/statistics", Params: []fuzz.Param{username}},
+		{Method: http.MethodGet, Path: "/api/user/{username}/icon", Params: []fuzz.Param{username}},
+		{Method: http.MethodGet, Path: "/api/user/{username}/livestream", Params: []fuzz.Param{username}},
+		{Method: http.MethodGet, Path: "/api/livestream/search", Params: []fuzz.Param{limit}},
+		{Method: http.MethodPost, Path: "/api/livestream/reservation", Params: []fuzz.Param{
+			{Name: "start_at", In: fuzz.InBody, Kind: fuzz.KindTimestamp, Valid: strconv.FormatInt(startAt, 10), Min: config.BaseAt, Max: endAt - 3600},
+			{Name: "end_at", In: fuzz.InBody, Kind: fuzz.KindTimestamp, Valid: strconv.FormatInt(endAt, 10), Min: startAt + 3600, Max: endAt},
+		}, Body: map[string]any{
+			"tags":          []int64{},
+			"title":         "fuzz",
+			"description":   "fuzz",
+			"playlist_url":  "https://media.xiii.isucon.dev/api/4/playlist.m3u8",
+			"thumbnail_url": "https://media.xiii.isucon.dev/isucon12_final.webp",
+		}},
+		{Method: http.MethodGet, Path: "/api/livestream/{livestream_id}", Params: []fuzz.Param{livestream}},
+		{Method: http.MethodGet, Path: "/api/livestream/{livestream_id}/livecomment", Params: []fuzz.Param{livestream, limit}},
+		{Method: http.MethodPost, Path: "/api/livestream/{livestream_id}/livecomment", Params: []fuzz.Param{
+			livestream,
+			{Name: "tip", In: fuzz.InBody, Kind: fuzz.KindAmount, Valid: "0", Min: 0, Max: config.FuzzMaxTip},
+		}, Body: map[string]any{"comment": "fuzz"}},
+		{Method: http.MethodGet, Path: "/api/livestream/{livestream_id}/reaction", Params: []fuzz.Param{livestream, limit}},
+		{Method: http.MethodPost, Path: "/api/livestream/{livestream_id}/reaction", Params: []fuzz.Param{livestream}, Body: map[string]any{"emoji_name": "innocent"}},
+		{Method: http.MethodGet, Path: "/api/livestream/{livestream_id}/report", Params: []fuzz.Param{livestream}},
+		{Method: http.MethodGet, Path: "/api/livestream/{livestream_id}/ngwords", Params: []fuzz.Param{livestream}},
+		{Method: http.MethodPost, Path: "/api/livestream/{livestream_id}/livecomment/{livecomment_id}/report", Params: []fuzz.Param{
+			livestream,
+			{Name: "livecomment_id", In: fuzz.InPath, Kind: fuzz.KindID, Valid: strconv.FormatInt(livecommentID, 10), Min: 1, Max: livecommentID},
+		}},
+		{Method: http.MethodPost, Path: "/api/livestream/{livestream_id}/moderate", Params: []fuzz.Param{livestream}, Body: map[string]any{"ng_word": "isupipe-fuzz"}},
+		{Method: http.MethodPost, Path: "/api/livestream/{livestream_id}/enter", Params: []fuzz.Param{livestream}},
+		{Method: http.MethodDelete, Path: "/api/livestream/{livestream_id}/exit", Params: []fuzz.Param{livestream}},
+		{Method: http.MethodGet, Path: "/api/livestream/{livestream_id}/statistics", Params: []fuzz.Param{livestream}},
+		// NOTE: 範囲外のユーザ名の確認で未登録のユーザ名を登録しないよう、他のエンドポイントの後に行う
+		{Method: http.MethodPost, Path: "/api/register", Params: []fuzz.Param{
+			{Name: "name", In: fuzz.InBody, Kind: fuzz.KindName, Valid: registerName},
+		}, Body: map[string]any{
+			"display_name": "fuzz",
+			"description":  "fuzz",
+			"password":     "fuzz",
+			"theme":        map[string]any{"dark_mode": false},
+		}},
+	}
+}
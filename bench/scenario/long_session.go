@@ -0,0 +1,89 @@
+package scenario
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/isucon/isucon13/bench/internal/bencherror"
+	"github.com/isucon/isucon13/bench/internal/config"
+	"github.com/isucon/isucon13/bench/isupipe"
+	"go.uber.org/zap"
+)
+
+// LongSessionStreamerScenario は、走行中ずっとログインしたままの配信者として、自身の統計情報を定期的に確認します
+// 短いシナリオでは露見しない、セッションストアの不具合(期限切れ、退避による消失など)を検出するためのものです
+// 配信者のチップ合計と総リアクション数は走行中に減らないため、減少した場合は整合性エラーとします
+// NOTE: 視聴者数と順位は、他の視聴者の退室や他の配信者の書き込みで上下するため検証しない
+func LongSessionStreamerScenario(
+	ctx context.Context,
+	contestantLogger *zap.Logger,
+	streamerPool *isupipe.ClientPool,
+) error {
+	lgr := zap.S()
+
+	streamer, err := streamerPool.Get(ctx)
+	if err != nil {
+		lgr.Warnf("long_session: failed to get streamer from pool: %s\n", err.Error())
+		return err
+	}
+	// NOTE: 統計情報の確認は読み取りのみのため、ログインしたままのクライアントをプールに戻して他のシナリオと共有する.
+	// 走行中ずっと配信者を占有すると、配信者を必要とするシナリオが待たされる
+	streamerPool.Put(ctx, streamer)
+
+	username, err := streamer.Username(ctx)
+	if err != nil {
+		return err
+	}
+
+	var prev *isupipe.UserStatistics
+	for i := 0; ; i++ {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(config.LongSessionPollInterval):
+			}
+		}
+
+		// NOTE: ログインからの経過時間によらずセッションが有効であることを確認する
+		if _, err := streamer.GetMe(ctx); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			lgr.Warnf("long_session: session lost: %s\n", err.Error())
+			return err
+		}
+
+		stats, err := streamer.GetUserStatistics(ctx, username)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			lgr.Warnf("long_session: failed to get user stats: %s\n", err.Error())
+			return err
+		}
+
+		if prev != nil {
+			if err := verifyLongSessionTransition(ctx, username, prev, stats); err != nil {
+				lgr.Warnf("long_session: inconsistent user stats: %s\n", err.Error())
+				return err
+			}
+		}
+		prev = stats
+	}
+}
+
+func verifyLongSessionTransition(ctx context.Context, username string, prev, cur *isupipe.UserStatistics) error {
+	evidence := fmt.Sprintf("user=%s, total_tip: %d -> %d, total_reactions: %d -> %d",
+		username, prev.TotalTip, cur.TotalTip, prev.TotalReactions, cur.TotalReactions)
+
+	if cur.TotalTip < prev.TotalTip {
+		return bencherror.NewAssertionError(ctx, fmt.Errorf("%s", evidence), "配信者のチップ合計が減少しました")
+	}
+	if cur.TotalReactions < prev.TotalReactions {
+		return bencherror.NewAssertionError(ctx, fmt.Errorf("%s", evidence), "配信者の総リアクション数が減少しました")
+	}
+
+	return nil
+}
@@ -0,0 +1,144 @@
+package scenario
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/isucon/isucon13/bench/internal/bencherror"
+	"github.com/isucon/isucon13/bench/internal/benchrand"
+	"github.com/isucon/isucon13/bench/internal/benchscore"
+	"github.com/isucon/isucon13/bench/internal/config"
+	"github.com/isucon/isucon13/bench/internal/scheduler"
+	"github.com/isucon/isucon13/bench/isupipe"
+	"go.uber.org/zap"
+)
+
+// ModerationEffectivenessScenario は、NGワードの登録がライブコメントに反映されることを端から端まで検証します
+// 視聴者がNGワードを含むスパムを投稿した後に配信者がNGワードを登録し、
+// 猶予時間内にスパムが一覧から消え、以降の同じ内容の投稿が拒否されることを確認します
+// NOTE: 猶予時間内にモデレーションが反映された場合、スコアを加算する
+func ModerationEffectivenessScenario(
+	ctx context.Context,
+	contestantLogger *zap.Logger,
+	streamerPool *isupipe.ClientPool,
+	viewerPool *isupipe.ClientPool,
+) error {
+	lgr := zap.S()
+
+	streamer, err := streamerPool.Get(ctx)
+	if err != nil {
+		lgr.Warnf("moderation: failed to get streamer from pool: %s\n", err.Error())
+		return err
+	}
+	defer streamerPool.Put(ctx, streamer)
+
+	livestreams, err := streamer.GetMyLivestreams(ctx)
+	if err != nil {
+		lgr.Warnf("moderation: failed to get my livestreams: %s\n", err.Error())
+		return err
+	}
+	if len(livestreams) == 0 {
+		return nil
+	}
+	livestream := livestreams[benchrand.Intn(len(livestreams))]
+
+	viewer, err := viewerPool.Get(ctx)
+	if err != nil {
+		lgr.Warnf("moderation: failed to get viewer from pool: %s\n", err.Error())
+		return err
+	}
+	defer viewerPool.Put(ctx, viewer)
+
+	if err := viewer.EnterLivestream(ctx, livestream.ID, livestream.Owner.Name); err != nil {
+		lgr.Warnf("moderation: failed to enter livestream: %s\n", err.Error())
+		return err
+	}
+	defer viewer.ExitLivestream(ctx, livestream.ID, livestream.Owner.Name)
+
+	// NOTE: 他のシナリオのNGワードと衝突しないよう、走行ごとに一意な語を用いる
+	ngWord := strings.ToLower(benchrand.String(12))
+	spam := fmt.Sprintf("この配信%sすぎる", ngWord)
+	if _, _, err := viewer.PostLivecomment(ctx, livestream.ID, livestream.Owner.Name, spam, &scheduler.Tip{}); err != nil {
+		lgr.Warnf("moderation: failed to post spam: %s\n", err.Error())
+		return err
+	}
+
+	moderateResp, err := streamer.RegisterNgWord(ctx, livestream.ID, livestream.Owner.Name, ngWord)
+	if err != nil {
+		lgr.Warnf("moderation: failed to register ngword: %s\n", err.Error())
+		return err
+	}
+	moderatedAt := time.Now()
+
+	ngWords, err := streamer.GetNgwords(ctx, livestream.ID, livestream.Owner.Name)
+	if err != nil {
+		lgr.Warnf("moderation: failed to get ngwords: %s\n", err.Error())
+		return err
+	}
+	if !containsNgWord(ngWords, moderateResp.WordID, ngWord) {
+		err := fmt.Errorf("配信 %d のNGワード一覧に word_id=%d (%s) が含まれていません", livestream.ID, moderateResp.WordID, ngWord)
+		return bencherror.NewAssertionError(ctx, err, "登録したNGワードがNGワード一覧に反映されていません")
+	}
+
+	elapsed, err := waitSpamRemoved(ctx, viewer, livestream, ngWord, moderatedAt)
+	if err != nil {
+		if !errors.Is(err, bencherror.ErrTimeout) {
+			lgr.Warnf("moderation: spam remains after moderation: %s\n", err.Error())
+		}
+		return err
+	}
+	lgr.Infof("moderation: livestream %d removed spam in %s", livestream.ID, elapsed)
+
+	// 登録済みのNGワードを含む投稿は拒否されること
+	if _, _, err := viewer.PostLivecomment(ctx, livestream.ID, livestream.Owner.Name, spam, &scheduler.Tip{}, isupipe.WithAction(isupipe.ActionPostLivecommentModerated)); err != nil {
+		lgr.Warnf("moderation: spam is not rejected after moderation: %s\n", err.Error())
+		return err
+	}
+
+	benchscore.IncModerationVerified(ctx)
+	return nil
+}
+
+// waitSpamRemoved は、NGワードを含むライブコメントが一覧から消えるまで待ち、モデレーションからの経過時間を返します
+func waitSpamRemoved(ctx context.Context, viewer *isupipe.Client, livestream *isupipe.Livestream, ngWord string, moderatedAt time.Time) (time.Duration, error) {
+	deadline := moderatedAt.Add(config.ModerationMaxDelay)
+	for {
+		livecomments, err := viewer.GetLivecomments(ctx, livestream.ID, livestream.Owner.Name)
+		if err != nil {
+			return 0, err
+		}
+
+		var remaining *isupipe.Livecomment
+		for _, livecomment := range livecomments {
+			if strings.Contains(livecomment.Comment, ngWord) {
+				remaining = livecomment
+				break
+			}
+		}
+		if remaining == nil {
+			return time.Since(moderatedAt), nil
+		}
+		if time.Now().After(deadline) {
+			err := fmt.Errorf("配信 %d のライブコメント %d がNGワード '%s' を含んでいます", livestream.ID, remaining.ID, ngWord)
+			return 0, bencherror.NewAssertionError(ctx, err, "NGワード登録から%s経過後も、NGワードを含むライブコメントが一覧に残っています", config.ModerationMaxDelay)
+		}
+
+		select {
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		case <-time.After(config.ModerationPollInterval):
+		}
+	}
+}
+
+func containsNgWord(ngWords []*isupipe.NGWord, wordID int64, word string) bool {
+	for _, ngWord := range ngWords {
+		if ngWord.ID == wordID && ngWord.Word == word {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,129 @@
+package scenario
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/isucon/isucon13/bench/internal/bencherror"
+	"github.com/isucon/isucon13/bench/internal/benchrand"
+	"github.com/isucon/isucon13/bench/internal/config"
+	"github.com/isucon/isucon13/bench/isupipe"
+	"go.uber.org/zap"
+)
+
+var ngWordDuplicateWarnOnce sync.Once
+
+// NgWordIdempotencyScenario は、同じNGワードを同時・連続に登録し、NGワードの登録が冪等に扱われることを検証します
+// すべての登録が成功し、レスポンスの word_id がいずれもNGワード一覧に含まれ、一覧に同じ word_id が重複して含まれないことを確認します
+// NOTE: 初期実装は登録のたびに行を追加するため、ng_wordsテーブルが嵩んでmoderateが重くなる. これに気づいてもらうため意図的に重複登録する
+// NOTE: 異なる word_id で同じNGワードが並ぶこと自体は初期実装の振る舞いのため、仕様違反とせず選手向けに一度だけ警告する
+func NgWordIdempotencyScenario(
+	ctx context.Context,
+	contestantLogger *zap.Logger,
+	streamerPool *isupipe.ClientPool,
+) error {
+	lgr := zap.S()
+
+	streamer, err := streamerPool.Get(ctx)
+	if err != nil {
+		lgr.Warnf("ngword_idempotency: failed to get streamer from pool: %s\n", err.Error())
+		return err
+	}
+	defer streamerPool.Put(ctx, streamer)
+
+	livestreams, err := streamer.GetMyLivestreams(ctx)
+	if err != nil {
+		lgr.Warnf("ngword_idempotency: failed to get my livestreams: %s\n", err.Error())
+		return err
+	}
+	if len(livestreams) == 0 {
+		return nil
+	}
+	livestream := livestreams[benchrand.Intn(len(livestreams))]
+
+	// NOTE: 他のシナリオのNGワードと衝突しないよう、走行ごとに一意な語を用いる
+	ngWord := strings.ToLower(benchrand.String(12))
+	register := func() (int64, error) {
+		resp, err := streamer.RegisterNgWord(ctx, livestream.ID, livestream.Owner.Name, ngWord)
+		if err != nil {
+			return 0, err
+		}
+		return resp.WordID, nil
+	}
+
+	// 同じNGワードを一斉に登録する
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		wordIDs  []int64
+		firstErr error
+	)
+	for i := 0; i < config.NumNgWordConcurrentRegistrations; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			wordID, err := register()
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+				}
+				return
+			}
+			wordIDs = append(wordIDs, wordID)
+		}()
+	}
+	wg.Wait()
+	if firstErr != nil {
+		lgr.Warnf("ngword_idempotency: failed to register ngword concurrently: %s\n", firstErr.Error())
+		return firstErr
+	}
+
+	// 登録済みのNGワードを続けて登録する
+	for i := 0; i < config.NumNgWordSequentialRegistrations; i++ {
+		wordID, err := register()
+		if err != nil {
+			lgr.Warnf("ngword_idempotency: failed to register duplicate ngword: %s\n", err.Error())
+			return err
+		}
+		wordIDs = append(wordIDs, wordID)
+	}
+
+	ngWords, err := streamer.GetNgwords(ctx, livestream.ID, livestream.Owner.Name)
+	if err != nil {
+		lgr.Warnf("ngword_idempotency: failed to get ngwords: %s\n", err.Error())
+		return err
+	}
+
+	seen := make(map[int64]struct{}, len(ngWords))
+	var rows int
+	for _, w := range ngWords {
+		if _, ok := seen[w.ID]; ok {
+			err := fmt.Errorf("配信 %d のNGワード一覧に word_id=%d が重複して含まれています", livestream.ID, w.ID)
+			return bencherror.NewAssertionError(ctx, err, "NGワード一覧に同じNGワードが重複して含まれています")
+		}
+		seen[w.ID] = struct{}{}
+		if w.Word == ngWord {
+			rows++
+		}
+	}
+	for _, wordID := range wordIDs {
+		if !containsNgWord(ngWords, wordID, ngWord) {
+			err := fmt.Errorf("配信 %d のNGワード一覧に word_id=%d (%s) が含まれていません", livestream.ID, wordID, ngWord)
+			return bencherror.NewAssertionError(ctx, err, "重複して登録したNGワードがNGワード一覧に反映されていません")
+		}
+	}
+
+	if rows > 1 {
+		lgr.Infof("ngword_idempotency: livestream %d has %d rows for the same ngword (%d registrations)", livestream.ID, rows, len(wordIDs))
+		ngWordDuplicateWarnOnce.Do(func() {
+			contestantLogger.Warn("同じNGワードの登録が重複して保存されています. NGワードが増えるほどモデレーションが重くなります",
+				zap.Int64("livestream_id", livestream.ID), zap.Int("rows", rows), zap.Int("registrations", len(wordIDs)))
+		})
+	}
+
+	return nil
+}
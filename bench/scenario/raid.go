@@ -0,0 +1,139 @@
+package scenario
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/isucon/isucon13/bench/internal/bencherror"
+	"github.com/isucon/isucon13/bench/internal/benchscore"
+	"github.com/isucon/isucon13/bench/internal/config"
+	"github.com/isucon/isucon13/bench/internal/scheduler"
+	"github.com/isucon/isucon13/bench/isupipe"
+	"go.uber.org/zap"
+)
+
+// RaidScenario は、人気配信に大量の視聴者が数秒のうちに押し寄せる状況(レイド)を再現します
+// 視聴者は一斉に入室し、ライブコメントとリアクションを投稿してから退室します
+// 殺到が収まったあと、統計情報に投稿したリアクションが反映されることを検証します
+func RaidScenario(
+	ctx context.Context,
+	contestantLogger *zap.Logger,
+	viewerPool *isupipe.ClientPool,
+	livestreamPool *isupipe.LivestreamPool,
+) error {
+	lgr := zap.S()
+
+	livestream, err := livestreamPool.Get(ctx)
+	if err != nil {
+		lgr.Warnf("raid: failed to get livestream from pool: %s\n", err.Error())
+		return err
+	}
+	defer livestreamPool.Put(ctx, livestream)
+
+	// NOTE: 視聴者が揃うまで待ってから一斉に押し寄せる
+	viewers := make([]*isupipe.Client, 0, config.NumRaidViewers)
+	putViewers := func(viewers []*isupipe.Client) {
+		for _, viewer := range viewers {
+			viewerPool.Put(ctx, viewer)
+		}
+	}
+	gatherCtx, cancelGather := context.WithTimeout(ctx, config.RaidGatherTimeout)
+	defer cancelGather()
+	for len(viewers) < config.NumRaidViewers {
+		viewer, err := viewerPool.Get(gatherCtx)
+		if err != nil {
+			// NOTE: 視聴者が揃わない間、他のシナリオの視聴者を占有し続けないよう、集めた視聴者を戻してレイドを見送る
+			putViewers(viewers)
+			if ctx.Err() != nil {
+				lgr.Warnf("raid: failed to get viewer from pool: %s\n", err.Error())
+				return err
+			}
+			lgr.Infof("raid: only %d viewers gathered in %s, skip raid", len(viewers), config.RaidGatherTimeout)
+			return nil
+		}
+		viewers = append(viewers, viewer)
+	}
+
+	lgr.Infof("raid: %d viewers rush into livestream %d", len(viewers), livestream.ID)
+	var (
+		postedLivecomments int64
+		postedReactions    int64
+
+		errMu    sync.Mutex
+		raidErr  error
+		raidWg   sync.WaitGroup
+		raidFrom = time.Now()
+	)
+	setErr := func(err error) {
+		errMu.Lock()
+		defer errMu.Unlock()
+		if raidErr == nil {
+			raidErr = err
+		}
+	}
+	for _, viewer := range viewers {
+		raidWg.Add(1)
+		go func(viewer *isupipe.Client) {
+			defer raidWg.Done()
+
+			if err := viewer.EnterLivestream(ctx, livestream.ID, livestream.Owner.Name); err != nil {
+				if !errors.Is(err, bencherror.ErrTimeout) {
+					setErr(err)
+				}
+				return
+			}
+			defer viewer.ExitLivestream(ctx, livestream.ID, livestream.Owner.Name)
+
+			livecomment := scheduler.LivecommentScheduler.GetShortPositiveComment()
+			tip, err := scheduler.LivecommentScheduler.GetTipsForStream(ctx, livestream.Hours(), 1)
+			if err != nil {
+				setErr(err)
+				return
+			}
+			if _, _, err := viewer.PostLivecomment(ctx, livestream.ID, livestream.Owner.Name, livecomment.Comment, tip); err != nil {
+				if !errors.Is(err, bencherror.ErrTimeout) {
+					setErr(err)
+				}
+			} else {
+				atomic.AddInt64(&postedLivecomments, 1)
+				benchscore.IncRaidLivecomment(ctx)
+			}
+
+			if _, err := viewer.PostReaction(ctx, livestream.ID, livestream.Owner.Name, &isupipe.PostReactionRequest{
+				EmojiName: scheduler.GetReaction(),
+			}); err != nil {
+				if !errors.Is(err, bencherror.ErrTimeout) {
+					setErr(err)
+				}
+			} else {
+				atomic.AddInt64(&postedReactions, 1)
+				benchscore.IncRaidReaction(ctx)
+			}
+		}(viewer)
+	}
+	raidWg.Wait()
+
+	// NOTE: 殺到が収まったら、統計情報の検証に用いる1人を除いて視聴者を戻す
+	verifier := viewers[0]
+	putViewers(viewers[1:])
+	defer viewerPool.Put(ctx, verifier)
+
+	elapsed := time.Since(raidFrom)
+	lgr.Infof("raid: livestream %d received %d livecomments and %d reactions in %s (%.1f posts/s)",
+		livestream.ID, postedLivecomments, postedReactions, elapsed, float64(postedLivecomments+postedReactions)/elapsed.Seconds())
+	if raidErr != nil {
+		lgr.Warnf("raid: failed to rush into livestream: %s\n", raidErr.Error())
+		return raidErr
+	}
+
+	// 殺到が収まった後、統計情報が投稿内容に追いつくこと
+	if err := verifyLivestreamReactions(ctx, verifier, livestream, postedReactions); err != nil && !errors.Is(err, bencherror.ErrTimeout) {
+		lgr.Warnf("raid: failed to verify livestream stats: %s\n", err.Error())
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,159 @@
+package scenario
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/isucon/isucon13/bench/internal/bencherror"
+	"github.com/isucon/isucon13/bench/internal/config"
+	"github.com/isucon/isucon13/bench/internal/scheduler"
+	"github.com/isucon/isucon13/bench/isupipe"
+	"go.uber.org/zap"
+)
+
+// rankingConsistencyRound は、複数の配信者の統計情報を同時に取得した結果です
+type rankingConsistencyRound struct {
+	stats map[string]*isupipe.UserStatistics
+	// 取得前後の全配信の書き込み数
+	eventsBefore int64
+	eventsAfter  int64
+}
+
+func userRankingScore(stats *isupipe.UserStatistics) int64 {
+	return stats.TotalReactions + stats.TotalTip
+}
+
+// RankingConsistencyScenario は、他のシナリオがチップやリアクションを書き込む中で、複数の配信者の統計情報を同時に取得し、
+// 配信者の順位がスコア(総リアクション数+チップ合計)の大小と整合するか検証します
+// ランキングのキャッシュの不整合は、読み込みと書き込みが並行する状況でのみ露見するためです
+// NOTE: 取得の間にも書き込みは反映されうるため、期間中の全配信への書き込み数までの順位の食い違いは許容する
+func RankingConsistencyScenario(
+	ctx context.Context,
+	contestantLogger *zap.Logger,
+	viewerPool *isupipe.ClientPool,
+	livestreamPool *isupipe.LivestreamPool,
+) error {
+	lgr := zap.S()
+
+	viewer, err := viewerPool.Get(ctx)
+	if err != nil {
+		lgr.Warnf("ranking_consistency: failed to get viewer from pool: %s\n", err.Error())
+		return err
+	}
+	defer viewerPool.Put(ctx, viewer)
+
+	// NOTE: 視聴者が書き込めるよう、配信はプールにすぐ戻す
+	var usernames []string
+	seen := make(map[string]struct{}, config.NumRankingConsistencyUsers)
+	for i := 0; i < config.NumRankingConsistencyUsers*2 && len(usernames) < config.NumRankingConsistencyUsers; i++ {
+		livestream, err := livestreamPool.Get(ctx)
+		if err != nil {
+			lgr.Warnf("ranking_consistency: failed to get livestream from pool: %s\n", err.Error())
+			return err
+		}
+		livestreamPool.Put(ctx, livestream)
+		if _, ok := seen[livestream.Owner.Name]; ok {
+			continue
+		}
+		seen[livestream.Owner.Name] = struct{}{}
+		usernames = append(usernames, livestream.Owner.Name)
+	}
+	if len(usernames) < 2 {
+		return nil
+	}
+
+	var prev *rankingConsistencyRound
+	for i := 0; i < config.NumRankingConsistencyRounds; i++ {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(config.RankingConsistencyRoundPeriod):
+			}
+		}
+
+		round, err := fetchRankingConsistencyRound(ctx, viewer, usernames)
+		if err != nil {
+			lgr.Warnf("ranking_consistency: failed to get user stats: %s\n", err.Error())
+			return err
+		}
+		if err := verifyRankingConsistency(ctx, usernames, round); err != nil {
+			lgr.Warnf("ranking_consistency: inconsistent ranking: %s\n", err.Error())
+			return err
+		}
+		if prev != nil {
+			for _, username := range usernames {
+				prevScore, curScore := userRankingScore(prev.stats[username]), userRankingScore(round.stats[username])
+				if curScore < prevScore {
+					err := fmt.Errorf("user=%s, score(total_reactions+total_tip): %d -> %d", username, prevScore, curScore)
+					lgr.Warnf("ranking_consistency: score decreased: %s\n", err.Error())
+					return bencherror.NewAssertionError(ctx, err, "配信者のスコアが減少しました")
+				}
+			}
+		}
+		prev = round
+	}
+
+	return nil
+}
+
+// fetchRankingConsistencyRound は、配信者の統計情報を並行して取得します
+func fetchRankingConsistencyRound(ctx context.Context, viewer *isupipe.Client, usernames []string) (*rankingConsistencyRound, error) {
+	round := &rankingConsistencyRound{
+		stats:        make(map[string]*isupipe.UserStatistics, len(usernames)),
+		eventsBefore: scheduler.RankingLedger.NumTotalScoreEvents(),
+	}
+
+	var (
+		mu       sync.Mutex
+		fetchErr error
+		wg       sync.WaitGroup
+	)
+	for _, username := range usernames {
+		username := username
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			stats, err := viewer.GetUserStatistics(ctx, username)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if fetchErr == nil {
+					fetchErr = err
+				}
+				return
+			}
+			round.stats[username] = stats
+		}()
+	}
+	wg.Wait()
+	if fetchErr != nil {
+		return nil, fetchErr
+	}
+
+	round.eventsAfter = scheduler.RankingLedger.NumTotalScoreEvents()
+	return round, nil
+}
+
+// verifyRankingConsistency は、スコアの大きい配信者が、期間中の書き込みで説明できないほど下位にいないか検証します
+func verifyRankingConsistency(ctx context.Context, usernames []string, round *rankingConsistencyRound) error {
+	events := round.eventsAfter - round.eventsBefore
+	for _, higher := range usernames {
+		for _, lower := range usernames {
+			h, l := round.stats[higher], round.stats[lower]
+			// NOTE: スコアが同じ場合の順位はユーザ名で決まるため、検証しない
+			if userRankingScore(h) <= userRankingScore(l) {
+				continue
+			}
+			// スコアの大きい配信者が、小さい配信者と同じかそれより下位にいる
+			if gap := h.Rank - l.Rank + 1; gap > 0 && gap > events {
+				err := fmt.Errorf("%s(rank=%d, score=%d), %s(rank=%d, score=%d), 期間中の書き込み数=%d",
+					higher, h.Rank, userRankingScore(h), lower, l.Rank, userRankingScore(l), events)
+				return bencherror.NewAssertionError(ctx, err, "スコアの大きい配信者が、スコアの小さい配信者より下位に表示されました")
+			}
+		}
+	}
+	return nil
+}
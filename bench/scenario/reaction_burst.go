@@ -0,0 +1,215 @@
+package scenario
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/isucon/isucon13/bench/internal/bencherror"
+	"github.com/isucon/isucon13/bench/internal/benchscore"
+	"github.com/isucon/isucon13/bench/internal/config"
+	"github.com/isucon/isucon13/bench/internal/scheduler"
+	"github.com/isucon/isucon13/bench/isupipe"
+	"go.uber.org/zap"
+)
+
+// reactionBurstDistribution は、連投するリアクションの絵文字の偏りです
+// NOTE: 実際の配信と同様に、一部の絵文字に投稿が集中するようにする (3:2:1)
+var reactionBurstDistribution = []int{0, 0, 0, 1, 1, 2}
+
+// ReactionBurstScenario は、視聴者がライブ配信にリアクションを短時間で連投する状況を再現します
+// 連投したリアクションが猶予時間内にリアクション一覧へ絵文字の内訳どおりに反映され、
+// 統計情報の総リアクション数にも反映されることを検証します
+// NOTE: 猶予時間内に一覧への反映を確認できたリアクションについて、スコアを加算する
+func ReactionBurstScenario(
+	ctx context.Context,
+	contestantLogger *zap.Logger,
+	viewerPool *isupipe.ClientPool,
+	livestreamPool *isupipe.LivestreamPool,
+) error {
+	lgr := zap.S()
+
+	livestream, err := livestreamPool.Get(ctx)
+	if err != nil {
+		lgr.Warnf("reaction-burst: failed to get livestream from pool: %s\n", err.Error())
+		return err
+	}
+	defer livestreamPool.Put(ctx, livestream)
+
+	viewers := make([]*isupipe.Client, 0, config.NumReactionBurstViewers)
+	defer func() {
+		for _, viewer := range viewers {
+			viewerPool.Put(ctx, viewer)
+		}
+	}()
+	for len(viewers) < config.NumReactionBurstViewers {
+		viewer, err := viewerPool.Get(ctx)
+		if err != nil {
+			lgr.Warnf("reaction-burst: failed to get viewer from pool: %s\n", err.Error())
+			return err
+		}
+		viewers = append(viewers, viewer)
+	}
+
+	statsBefore, err := viewers[0].GetLivestreamStatistics(ctx, livestream.ID, livestream.Owner.Name)
+	if err != nil {
+		lgr.Warnf("reaction-burst: failed to get livestream stats: %s\n", err.Error())
+		return err
+	}
+
+	emojis := make([]string, 0, len(reactionBurstDistribution))
+	for len(emojis) < reactionBurstDistribution[len(reactionBurstDistribution)-1]+1 {
+		emojis = append(emojis, scheduler.GetReaction())
+	}
+
+	var (
+		// 受理されたリアクションのIDと絵文字
+		accepted = map[int64]string{}
+
+		mu       sync.Mutex
+		burstErr error
+		burstWg  sync.WaitGroup
+	)
+	setErr := func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if burstErr == nil {
+			burstErr = err
+		}
+	}
+	for _, viewer := range viewers {
+		burstWg.Add(1)
+		go func(viewer *isupipe.Client) {
+			defer burstWg.Done()
+
+			if err := viewer.EnterLivestream(ctx, livestream.ID, livestream.Owner.Name); err != nil {
+				if !errors.Is(err, bencherror.ErrTimeout) {
+					setErr(err)
+				}
+				return
+			}
+			defer viewer.ExitLivestream(ctx, livestream.ID, livestream.Owner.Name)
+
+			for i := 0; i < config.ReactionBurstSize; i++ {
+				emojiName := emojis[reactionBurstDistribution[i%len(reactionBurstDistribution)]]
+				reaction, err := viewer.PostReaction(ctx, livestream.ID, livestream.Owner.Name, &isupipe.PostReactionRequest{
+					EmojiName: emojiName,
+				})
+				if err != nil {
+					if !errors.Is(err, bencherror.ErrTimeout) {
+						setErr(err)
+					}
+					return
+				}
+				if reaction.EmojiName != emojiName {
+					err := fmt.Errorf("リアクション %d の絵文字が正しくありません: expected=%s, actual=%s", reaction.ID, emojiName, reaction.EmojiName)
+					setErr(bencherror.NewAssertionError(ctx, err, "POST /api/livestream/:livestream_id/reaction のレスポンスが投稿内容と一致しません"))
+					return
+				}
+
+				mu.Lock()
+				accepted[reaction.ID] = emojiName
+				mu.Unlock()
+			}
+		}(viewer)
+	}
+	burstWg.Wait()
+	postedAt := time.Now()
+
+	if burstErr != nil {
+		lgr.Warnf("reaction-burst: failed to post reactions: %s\n", burstErr.Error())
+		return burstErr
+	}
+	if len(accepted) == 0 {
+		return nil
+	}
+	lgr.Infof("reaction-burst: livestream %d accepted %d reactions", livestream.ID, len(accepted))
+
+	listed, err := waitReactionsListed(ctx, viewers[0], livestream, accepted, postedAt)
+	if err != nil {
+		if !errors.Is(err, bencherror.ErrTimeout) {
+			lgr.Warnf("reaction-burst: reactions are not listed: %s\n", err.Error())
+		}
+		return err
+	}
+	for i := 0; i < listed; i++ {
+		benchscore.IncReactionBurstAccepted(ctx)
+	}
+
+	// 統計情報の総リアクション数にも、連投したリアクションが反映されること
+	if err := verifyLivestreamReactions(ctx, viewers[0], livestream, statsBefore.TotalReactions+int64(len(accepted))); err != nil && !errors.Is(err, bencherror.ErrTimeout) {
+		lgr.Warnf("reaction-burst: failed to verify livestream stats: %s\n", err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// waitReactionsListed は、受理されたリアクションがリアクション一覧に絵文字の内訳どおりに現れるまで待ち、一覧で確認できた件数を返します
+// NOTE: 他のシナリオのリアクションによって一覧から押し出されたものは、確認できなかったものとして扱いエラーにはしない
+func waitReactionsListed(ctx context.Context, viewer *isupipe.Client, livestream *isupipe.Livestream, accepted map[int64]string, postedAt time.Time) (int, error) {
+	var (
+		deadline = postedAt.Add(config.ReactionBurstFreshness)
+		limit    = len(accepted) + config.ReactionBurstListMargin
+	)
+	for {
+		reactions, err := viewer.GetReactions(ctx, livestream.ID, livestream.Owner.Name, isupipe.WithLimitQueryParam(limit))
+		if err != nil {
+			return 0, err
+		}
+
+		listed := map[string]int{}
+		numListed := 0
+		for _, reaction := range reactions {
+			emojiName, ok := accepted[reaction.ID]
+			if !ok {
+				continue
+			}
+			if reaction.EmojiName != emojiName {
+				err := fmt.Errorf("配信 %d のリアクション %d の絵文字が正しくありません: expected=%s, actual=%s", livestream.ID, reaction.ID, emojiName, reaction.EmojiName)
+				return 0, bencherror.NewAssertionError(ctx, err, "リアクション一覧の絵文字が投稿内容と一致しません")
+			}
+			listed[emojiName]++
+			numListed++
+		}
+		if numListed == len(accepted) {
+			return numListed, nil
+		}
+		if time.Now().After(deadline) {
+			if len(reactions) >= limit {
+				return numListed, nil
+			}
+			err := fmt.Errorf("配信 %d のリアクション一覧に %d 件中 %d 件しか含まれていません (%s)", livestream.ID, len(accepted), numListed, reactionBreakdown(accepted, listed))
+			return 0, bencherror.NewAssertionError(ctx, err, "リアクションの投稿から%s経過後も、リアクション一覧に反映されていません", config.ReactionBurstFreshness)
+		}
+
+		select {
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		case <-time.After(config.ReactionBurstPollInterval):
+		}
+	}
+}
+
+// reactionBreakdown は、絵文字ごとの一覧に含まれていた件数と投稿した件数を表示用に整形します
+func reactionBreakdown(accepted map[int64]string, listed map[string]int) string {
+	posted := map[string]int{}
+	for _, emojiName := range accepted {
+		posted[emojiName]++
+	}
+	emojiNames := make([]string, 0, len(posted))
+	for emojiName := range posted {
+		emojiNames = append(emojiNames, emojiName)
+	}
+	sort.Strings(emojiNames)
+
+	breakdown := make([]string, len(emojiNames))
+	for i, emojiName := range emojiNames {
+		breakdown[i] = fmt.Sprintf("%s: %d/%d", emojiName, listed[emojiName], posted[emojiName])
+	}
+	return strings.Join(breakdown, ", ")
+}
@@ -0,0 +1,108 @@
+package scenario
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/isucon/isucon13/bench/internal/bencherror"
+	"github.com/isucon/isucon13/bench/internal/config"
+	"github.com/isucon/isucon13/bench/isupipe"
+	"go.uber.org/zap"
+)
+
+// 負荷走行中の統計情報の検証
+
+// verifyLivestreamReactions は、配信の総リアクション数が、視聴者自身が投稿したリアクション数以上であることを検証します
+// 統計情報を非同期に集計する設計を許容するため、不一致の場合は猶予期間をおいて再取得してから判定します
+// NOTE: 他の視聴者もリアクションを投稿するため、下限のみを検証する
+//
+//	ライブコメントはモデレーションで削除されうるので、最大チップは検証しない
+func verifyLivestreamReactions(ctx context.Context, client *isupipe.Client, livestream *isupipe.Livestream, postedReactions int64) error {
+	lgr := zap.S()
+
+	for attempt := 0; ; attempt++ {
+		stats, err := client.GetLivestreamStatistics(ctx, livestream.ID, livestream.Owner.Name)
+		if err != nil {
+			return err
+		}
+		if stats.TotalReactions >= postedReactions {
+			return nil
+		}
+		if attempt >= config.StatsVerifyRetries {
+			err := fmt.Errorf("配信 %d の総リアクション数が少なすぎます: expected>=%d, actual=%d", livestream.ID, postedReactions, stats.TotalReactions)
+			return bencherror.NewAssertionError(ctx, err, "%s経過後も統計情報に投稿済みのリアクションが反映されていません", config.StatsGracePeriod)
+		}
+
+		lgr.Infof("stats: total reactions mismatch, retry after grace period (livestream_id=%d, expected>=%d, actual=%d)", livestream.ID, postedReactions, stats.TotalReactions)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(config.StatsGracePeriod):
+		}
+	}
+}
+
+// RankingStabilityScenario は、同じ配信の統計情報を繰り返し取得し、配信自身のスコアが単調に増加するか検証します
+// 配信の総リアクション数は走行中に減ることはないため、減った場合は整合性エラーとします
+// NOTE: 順位は他の配信のスコアや同点の並びで上下しうるため、検証しない
+func RankingStabilityScenario(
+	ctx context.Context,
+	contestantLogger *zap.Logger,
+	viewerPool *isupipe.ClientPool,
+	livestreamPool *isupipe.LivestreamPool,
+) error {
+	lgr := zap.S()
+
+	viewer, err := viewerPool.Get(ctx)
+	if err != nil {
+		lgr.Warnf("ranking: failed to get viewer from pool: %s\n", err.Error())
+		return err
+	}
+	defer viewerPool.Put(ctx, viewer)
+
+	livestream, err := livestreamPool.Get(ctx)
+	if err != nil {
+		lgr.Warnf("ranking: failed to get livestream from pool: %s\n", err.Error())
+		return err
+	}
+	livestreamPool.Put(ctx, livestream) // 視聴者が書き込めるようにプールにすぐ戻す
+
+	var prev *isupipe.LivestreamStatistics
+	for i := 0; i < config.NumRankingSamples; i++ {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(config.RankingSampleInterval):
+			}
+		}
+
+		stats, err := viewer.GetLivestreamStatistics(ctx, livestream.ID, livestream.Owner.Name)
+		if err != nil {
+			lgr.Warnf("ranking: failed to get livestream stats: %s\n", err.Error())
+			return err
+		}
+
+		if prev != nil {
+			if err := verifyRankingTransition(ctx, livestream, prev, stats); err != nil {
+				lgr.Warnf("ranking: inexplicable ranking transition: %s\n", err.Error())
+				return err
+			}
+		}
+		prev = stats
+	}
+
+	return nil
+}
+
+func verifyRankingTransition(ctx context.Context, livestream *isupipe.Livestream, prev, cur *isupipe.LivestreamStatistics) error {
+	evidence := fmt.Sprintf("livestream_id=%d, rank: %d -> %d, total_reactions: %d -> %d",
+		livestream.ID, prev.Rank, cur.Rank, prev.TotalReactions, cur.TotalReactions)
+
+	if cur.TotalReactions < prev.TotalReactions {
+		return bencherror.NewAssertionError(ctx, fmt.Errorf("%s", evidence), "配信の総リアクション数が減少しました")
+	}
+
+	return nil
+}
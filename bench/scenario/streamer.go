@@ -3,9 +3,12 @@ package scenario
 import (
 	"context"
 	"errors"
+	"fmt"
 	"math/rand"
+	"sync"
 
 	"github.com/isucon/isucon13/bench/internal/bencherror"
+	"github.com/isucon/isucon13/bench/internal/config"
 	"github.com/isucon/isucon13/bench/internal/scheduler"
 	"github.com/isucon/isucon13/bench/isupipe"
 	"go.uber.org/zap"
@@ -23,6 +26,135 @@ func BasicLongStreamerScenario(
 	return nil
 }
 
+// ErrDoubleBooking は、予約枠を超えて予約が成立したことを表します
+var ErrDoubleBooking = errors.New("予約枠を超えて予約が成立しました")
+
+// ReservationCollisionScenario は、残り枠数1の区間に対して複数の配信者が一斉に予約を行い、
+// ちょうど1件だけ予約が成立することを検証します
+// 枠数を超えて予約が成立した場合(ダブルブッキング)と、枠が残っているのに1件も成立しなかった場合は仕様違反とします
+func ReservationCollisionScenario(
+	ctx context.Context,
+	contestantLogger *zap.Logger,
+	streamerPool *isupipe.ClientPool,
+	livestreamPool *isupipe.LivestreamPool,
+) error {
+	lgr := zap.S()
+
+	reservation, err := scheduler.ReservationSched.GetCollisionReservation()
+	if err != nil {
+		lgr.Warnf("reservation_collision: failed to get collision reservation: %s\n", err.Error())
+		return err
+	}
+
+	clients := make([]*isupipe.Client, config.NumReservationCollisionStreamers)
+	for i := range clients {
+		client, err := streamerPool.Get(ctx)
+		if err != nil {
+			return err
+		}
+		defer streamerPool.Put(ctx, client)
+		clients[i] = client
+	}
+
+	reserve := func(client *isupipe.Client) (*isupipe.Livestream, error) {
+		username, err := client.Username(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return client.ReserveLivestream(ctx, username, &isupipe.ReserveLivestreamRequest{
+			Tags:         []int64{},
+			Title:        reservation.Title,
+			Description:  reservation.Description,
+			PlaylistUrl:  reservation.PlaylistUrl,
+			ThumbnailUrl: reservation.ThumbnailUrl,
+			StartAt:      reservation.StartAt,
+			EndAt:        reservation.EndAt,
+		}, isupipe.WithAllowedActions(isupipe.ActionReserveLivestreamConflict))
+	}
+	// NOTE: 同じ区間に何件も予約を成立させるが、スケジューラには払い出した予約として1度だけ記録する
+	var committed bool
+	commit := func(livestream *isupipe.Livestream) {
+		if !committed {
+			scheduler.ReservationSched.CommitReservation(reservation)
+			committed = true
+		}
+		livestreamPool.Put(ctx, livestream)
+	}
+
+	// 残り枠数が1になるまで順に予約する
+	// NOTE: ベンチ側で把握していない予約(pretestなど)によって既に枠が減っている場合、この時点で予約が拒否されうる
+	//       その場合は検証を行わずにシナリオを終了する
+	for i := 0; i < config.NumSlots-1; i++ {
+		livestream, err := reserve(clients[i%len(clients)])
+		if err != nil {
+			lgr.Warnf("reservation_collision: failed to fill slots: %s\n", err.Error())
+			return err
+		}
+		if livestream == nil {
+			lgr.Infof("reservation_collision: slots are already filled (%d ~ %d)\n", reservation.StartAt, reservation.EndAt)
+			return nil
+		}
+		commit(livestream)
+	}
+
+	// 残り枠数1の区間に、一斉に予約を行う
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		winners  []*isupipe.Livestream
+		firstErr error
+	)
+	for _, client := range clients {
+		wg.Add(1)
+		go func(client *isupipe.Client) {
+			defer wg.Done()
+			livestream, err := reserve(client)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+				}
+				return
+			}
+			if livestream != nil {
+				winners = append(winners, livestream)
+			}
+		}(client)
+	}
+	wg.Wait()
+
+	for _, livestream := range winners {
+		commit(livestream)
+	}
+	switch {
+	case len(winners) > 1:
+		err := fmt.Errorf("%d ~ %d の区間で%d件の予約が同時に成立しました: %w", reservation.StartAt, reservation.EndAt, len(winners), ErrDoubleBooking)
+		return bencherror.NewViolationError(ctx, err, "予約枠数(%d)を超えて予約が成立してはいけません", config.NumSlots)
+	case len(winners) == 0 && firstErr != nil:
+		// NOTE: タイムアウトなどで成否が分からない予約が成立している可能性があるため、検証しない
+		lgr.Warnf("reservation_collision: failed to reserve last slot: %s\n", firstErr.Error())
+		return firstErr
+	case len(winners) == 0:
+		err := fmt.Errorf("%d ~ %d の区間で残り枠数1に対する予約が%d件とも拒否されました", reservation.StartAt, reservation.EndAt, len(clients))
+		return bencherror.NewViolationError(ctx, err, "予約枠が残っている区間の予約は成立しなければなりません")
+	}
+
+	// 枠が埋まった区間に対する予約は拒否されなければならない
+	livestream, err := reserve(clients[0])
+	if err != nil {
+		lgr.Warnf("reservation_collision: failed to reserve filled slot: %s\n", err.Error())
+		return err
+	}
+	if livestream != nil {
+		commit(livestream)
+		err := fmt.Errorf("%d ~ %d の区間で枠数を超えて予約が成立しました: %w", reservation.StartAt, reservation.EndAt, ErrDoubleBooking)
+		return bencherror.NewViolationError(ctx, err, "予約枠数(%d)を超えて予約が成立してはいけません", config.NumSlots)
+	}
+
+	return nil
+}
+
 func BasicStreamerColdReserveScenario(
 	ctx context.Context,
 	contestantLogger *zap.Logger,
@@ -38,7 +170,7 @@ func BasicStreamerColdReserveScenario(
 	}
 	streamerPool.Put(ctx, client) // 他のviewerが参入できるようにプールにすぐもどす
 
-	username, err := client.Username()
+	username, err := client.Username(ctx)
 	if err != nil {
 		lgr.Warnf("reserve: failed to get username: %s\n", err.Error())
 		return err
@@ -151,7 +283,7 @@ func BasicStreamerModerateScenario(
 			// icon取得のエラーは無視
 
 			livestreamID := report.Livecomment.Livestream.ID
-			ngword, err := scheduler.LivecommentScheduler.GetNgWord(report.Livecomment.Comment)
+			ngword, err := scheduler.LivecommentScheduler.GetNgWord(ctx, report.Livecomment.Comment)
 			if err != nil {
 				lgr.Warnf("streamer_moderate: failed to get ngwords: %s\n", err.Error())
 				return err
@@ -0,0 +1,139 @@
+package scenario
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/isucon/isucon13/bench/internal/bencherror"
+	"github.com/isucon/isucon13/bench/internal/benchrand"
+	"github.com/isucon/isucon13/bench/internal/config"
+	"github.com/isucon/isucon13/bench/isupipe"
+	"go.uber.org/zap"
+)
+
+// ErrTipRecording は、受理したチップが送った額のまま記録されず、売上の台帳が壊れうることを表します
+var ErrTipRecording = errors.New("受理したチップの記録に誤りがあります")
+
+// tipRecordingCase は、ライブコメントのtipに与える境界値や不正な値です
+type tipRecordingCase struct {
+	label string
+	raw   string
+	// 受理された場合に、NGワードの登録によって一覧から取り除くか
+	// NOTE: 負のチップなどはライブコメント一覧のスキーマに反するため、他の視聴者が取得する前に取り除く
+	purge bool
+}
+
+// NOTE: 参照実装はチップの上限・下限を検証せず、整数でない値の扱いも異なる (goは拒否し、ruby, python, nodeは受理する) ため、
+// どの値も受理・拒否のいずれも許容し、受理した整数は送った額のまま記録されていることのみを検証する
+var tipRecordingCases = []tipRecordingCase{
+	{label: "0", raw: "0"},
+	{label: "max", raw: strconv.Itoa(config.MaxTip)},
+	{label: "max+1", raw: strconv.Itoa(config.MaxTip + 1)},
+	{label: "negative", raw: "-1", purge: true},
+	{label: "fraction", raw: "1.5", purge: true},
+	{label: "string", raw: `"100"`, purge: true},
+	{label: "int64-overflow", raw: "9223372036854775808", purge: true},
+}
+
+// TipRecordingScenario は、ライブコメントのチップに境界値や不正な値を与え、webappが受理したチップを送った額のまま記録していることを検証します
+// チップの記録の誤りは売上の台帳を直接壊すため、違反は仕様違反として走行を中断します
+func TipRecordingScenario(
+	ctx context.Context,
+	contestantLogger *zap.Logger,
+	streamerPool *isupipe.ClientPool,
+	viewerPool *isupipe.ClientPool,
+) error {
+	lgr := zap.S()
+
+	streamer, err := streamerPool.Get(ctx)
+	if err != nil {
+		lgr.Warnf("tip_recording: failed to get streamer from pool: %s\n", err.Error())
+		return err
+	}
+	defer streamerPool.Put(ctx, streamer)
+
+	livestreams, err := streamer.GetMyLivestreams(ctx)
+	if err != nil {
+		lgr.Warnf("tip_recording: failed to get my livestreams: %s\n", err.Error())
+		return err
+	}
+	if len(livestreams) == 0 {
+		return nil
+	}
+	livestream := livestreams[benchrand.Intn(len(livestreams))]
+
+	viewer, err := viewerPool.Get(ctx)
+	if err != nil {
+		lgr.Warnf("tip_recording: failed to get viewer from pool: %s\n", err.Error())
+		return err
+	}
+	defer viewerPool.Put(ctx, viewer)
+
+	if err := viewer.EnterLivestream(ctx, livestream.ID, livestream.Owner.Name); err != nil {
+		lgr.Warnf("tip_recording: failed to enter livestream: %s\n", err.Error())
+		return err
+	}
+	defer viewer.ExitLivestream(ctx, livestream.ID, livestream.Owner.Name)
+
+	// NOTE: 取り除くライブコメントには、他のシナリオと衝突しない一意な語を含める
+	marker := strings.ToLower(benchrand.String(12))
+	var (
+		needPurge bool
+		firstErr  error
+	)
+	for _, c := range tipRecordingCases {
+		comment := fmt.Sprintf("チップの境界値 %s", c.label)
+		if c.purge {
+			comment = fmt.Sprintf("%s %s", comment, marker)
+		}
+		accepted, err := postTipRecording(ctx, viewer, livestream, comment, c)
+		if accepted && c.purge {
+			needPurge = true
+		}
+		if err != nil {
+			firstErr = err
+			break
+		}
+	}
+
+	if needPurge {
+		if _, err := streamer.RegisterNgWord(ctx, livestream.ID, livestream.Owner.Name, marker); err != nil {
+			lgr.Warnf("tip_recording: failed to purge accepted livecomments: %s\n", err.Error())
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+
+	if firstErr != nil && !errors.Is(firstErr, bencherror.ErrTimeout) {
+		lgr.Warnf("tip_recording: %s\n", firstErr.Error())
+	}
+	return firstErr
+}
+
+// postTipRecording は、境界値や不正な値のチップを含むライブコメントを投稿し、受理されたかを返します
+func postTipRecording(ctx context.Context, viewer *isupipe.Client, livestream *isupipe.Livestream, comment string, c tipRecordingCase) (bool, error) {
+	resp, statusCode, err := viewer.PostLivecommentWithRawTip(ctx, livestream.ID, livestream.Owner.Name, comment, json.RawMessage(c.raw), isupipe.WithAllowedActions(isupipe.ActionPostLivecommentBadTip))
+	if err != nil {
+		return false, err
+	}
+	if statusCode != isupipe.ExpectedStatusCode(isupipe.ActionPostLivecomment) {
+		return false, nil
+	}
+
+	// 受理したチップは、送った額のまま記録されること
+	// NOTE: 整数でない値をどう記録するかは定めないため、検証しない
+	want, err := strconv.ParseInt(c.raw, 10, 64)
+	if err != nil || resp == nil {
+		return true, nil
+	}
+	if resp.Tip != want {
+		err := fmt.Errorf("配信 %d で tip=%s (%s) が %d として記録されました: %w", livestream.ID, c.raw, c.label, resp.Tip, ErrTipRecording)
+		return true, bencherror.NewViolationError(ctx, err, "受理したライブコメントのチップが送信した額と一致しません")
+	}
+	return true, nil
+}
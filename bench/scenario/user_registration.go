@@ -0,0 +1,98 @@
+package scenario
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/isucon/isucon13/bench/internal/bencherror"
+	"github.com/isucon/isucon13/bench/internal/benchrand"
+	"github.com/isucon/isucon13/bench/internal/benchscore"
+	"github.com/isucon/isucon13/bench/internal/config"
+	"github.com/isucon/isucon13/bench/internal/resolver"
+	"github.com/isucon/isucon13/bench/isupipe"
+	"go.uber.org/zap"
+)
+
+var registrationSeq atomic.Int64
+
+// UserRegistrationScenario は、走行中に新規ユーザを登録し、配信者用のサブドメインが猶予時間内に名前解決できるか検証します
+// 登録前に一度名前解決して否定応答を受け取っておき、それがキャッシュされ続けていないかも確認します
+// 一定の割合で、登録直後のユーザのアイコンがNoImage.jpgであるかも検証します
+// NOTE: 猶予時間内に名前解決できた場合、スコアを加算する
+func UserRegistrationScenario(
+	ctx context.Context,
+	contestantLogger *zap.Logger,
+) error {
+	lgr := zap.S()
+
+	// NOTE: 登録直後の状態を見るため、ベンチマーカー側ではキャッシュしない
+	dnsResolver := resolver.NewDNSResolver()
+	dnsResolver.UseCache = false
+
+	client, err := isupipe.NewClient(ctx, contestantLogger)
+	if err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("%sreg%d", strings.ToLower(benchrand.String(10)), registrationSeq.Add(1))
+	domain := fmt.Sprintf("%s.%s", name, config.BaseDomain)
+
+	// 登録前は存在しない名前として否定応答が返るはず
+	if _, err := dnsResolver.Lookup(ctx, "udp", domain); !errors.Is(err, resolver.ErrNXDomain) {
+		lgr.Warnf("registration: unexpected result before register: domain=%s, err=%v\n", domain, err)
+		return nil
+	}
+
+	if _, err := client.Register(ctx, &isupipe.RegisterRequest{
+		Name:        name,
+		DisplayName: randDisplayName(),
+		Description: "ライブ配信を始めてみました。よろしくおねがいします！",
+		Password:    benchrand.String(12),
+		Theme: isupipe.Theme{
+			DarkMode: benchrand.Intn(2) == 0,
+		},
+	}); err != nil {
+		lgr.Warnf("registration: failed to register: %s\n", err.Error())
+		return err
+	}
+	registeredAt := time.Now()
+
+	// NOTE: 登録直後のユーザはアイコン未設定のため、一定の割合でNoImage.jpgが返されるか抜き打ちで検証する
+	if benchrand.Float64() < config.FallbackIconCheckRate {
+		if err := verifyFallbackIcon(ctx, client, name); err != nil {
+			lgr.Warnf("registration: failed to verify fallback icon: %s\n", err.Error())
+			return err
+		}
+	}
+
+	provisionCtx, cancel := context.WithTimeout(ctx, config.DNSProvisioningDeadline)
+	defer cancel()
+
+	ticker := time.NewTicker(config.DNSProvisioningPollInterval)
+	defer ticker.Stop()
+	for {
+		_, err := dnsResolver.Lookup(provisionCtx, "udp", domain)
+		if err == nil {
+			lgr.Debugf("registration: provisioned %s in %s\n", domain, time.Since(registeredAt))
+			benchscore.IncDNSProvisioned(ctx)
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			// NOTE: 走行終了による打ち切りは減点しない
+			return nil
+		case <-provisionCtx.Done():
+			if errors.Is(err, resolver.ErrNXDomain) {
+				benchscore.IncDNSProvisioningStale(ctx)
+				return bencherror.NewDNSProvisioningError(ctx, err, "ユーザ登録から %s 経過しても「%s」が存在しないという応答が返されました", config.DNSProvisioningDeadline, domain)
+			}
+			return bencherror.NewDNSProvisioningError(ctx, err, "ユーザ登録から %s 以内に「%s」を名前解決できませんでした", config.DNSProvisioningDeadline, domain)
+		case <-ticker.C:
+		}
+	}
+}
@@ -4,10 +4,12 @@ import (
 	"context"
 	"errors"
 	"math/rand"
-	"net/http"
 	"sync"
 
 	"github.com/isucon/isucon13/bench/internal/bencherror"
+	"github.com/isucon/isucon13/bench/internal/benchscore"
+	"github.com/isucon/isucon13/bench/internal/churn"
+	"github.com/isucon/isucon13/bench/internal/config"
 	"github.com/isucon/isucon13/bench/internal/scheduler"
 	"github.com/isucon/isucon13/bench/isupipe"
 	"go.uber.org/zap"
@@ -37,7 +39,7 @@ func BasicViewerScenario(
 	}
 	defer viewerPool.Put(ctx, client)
 
-	username, err := client.Username()
+	username, err := client.Username(ctx)
 	if err != nil {
 		lgr.Warnf("view: failed to get client username: %s\n", err.Error())
 	}
@@ -69,12 +71,18 @@ func BasicViewerScenario(
 	}
 	defer livestreamPool.Put(ctx, livestream)
 
+	// NOTE: 視聴を終えた(離脱した)時点の体感品質をスコアに反映する
+	session := benchscore.NewSession()
+	ctx = benchscore.WithSession(ctx, session)
+	defer benchscore.RecordSession(ctx, session)
+
 	// NOTE: 配信者のプロフィールが気になる人が一定数いる
 	if n%10 == 0 {
 		// ログ削減
 		// contestantLogger.Info("視聴者が配信者のプロフィールに関心を持ち、訪問しようとしています", zap.String("viewer", username), zap.String("streamer", livestream.Owner.Name))
 		lgr.Info("visit user profile")
 		if err := VisitUserProfile(ctx, contestantLogger, client, &livestream.Owner); err != nil && !errors.Is(err, bencherror.ErrTimeout) {
+			session.ObserveError(err)
 			lgr.Warnf("view: failed to visit user profile: %s\n", err.Error())
 			return err
 		}
@@ -82,6 +90,7 @@ func BasicViewerScenario(
 
 	lgr.Info("visit livestream")
 	if err := VisitLivestream(ctx, contestantLogger, client, livestream); err != nil && !errors.Is(err, bencherror.ErrTimeout) {
+		session.ObserveError(err)
 		lgr.Warnf("view: failed to visit livestream: %s\n", err.Error())
 		return err
 	}
@@ -89,18 +98,30 @@ func BasicViewerScenario(
 	if n%10 == 1 { // NOTE: 本来のクライアントは全員statsを呼ぶが、重すぎるので調整
 		lgr.Info("get livestream stats")
 		if _, err := client.GetLivestreamStatistics(ctx, livestream.ID, livestream.Owner.Name); err != nil && !errors.Is(err, bencherror.ErrTimeout) {
+			session.ObserveError(err)
 			lgr.Warnf("view: failed to get livestream stats: %s\n", err.Error())
 			return err
 		}
 	}
 
+	var postedReactions int64
 	// ログ削減
 	// contestantLogger.Info("視聴を開始しました", zap.String("username", username), zap.Int("duration_hours", livestream.Hours()))
-	for hour := 1; hour <= livestream.Hours(); hour++ {
+	sessionHours := churn.ViewerChurn.SessionHours(livestream.Hours())
+	for hour := 1; hour <= sessionHours; hour++ {
 		if comments, err := client.GetLivecomments(ctx, livestream.ID, livestream.Owner.Name); err != nil && !errors.Is(err, bencherror.ErrTimeout) {
+			session.ObserveError(err)
 			lgr.Warnf("view: failed to get livecomments: %s\n", err.Error())
 			continue
 		} else {
+			var spams int
+			for _, comment := range comments {
+				if scheduler.LivecommentScheduler.IsNgLivecomment(comment.Comment) {
+					spams++
+				}
+			}
+			session.ObserveLivecomments(len(comments), spams)
+
 			for i, comment := range comments {
 				client.GetIcon(ctx, comment.User.Name, isupipe.WithETag(comment.User.IconHash))
 				// icon取得はエラーになっても気にしない
@@ -111,18 +132,20 @@ func BasicViewerScenario(
 		}
 
 		livecomment := scheduler.LivecommentScheduler.GetLongPositiveComment()
-		tip, err := scheduler.LivecommentScheduler.GetTipsForStream(livestream.Hours(), hour)
+		tip, err := scheduler.LivecommentScheduler.GetTipsForStream(ctx, livestream.Hours(), hour)
 		if err != nil {
 			lgr.Warnf("view: failed to get tips for stream: %s\n", err.Error())
 			return err
 		}
 		if _, _, err := client.PostLivecomment(ctx, livestream.ID, livestream.Owner.Name, livecomment.Comment, tip); err != nil && !errors.Is(err, bencherror.ErrTimeout) {
+			session.ObserveError(err)
 			contestantLogger.Warn("ライブコメントを配信に投稿できないため、視聴者が離脱します", zap.String("viewer", username), zap.Int64("livestream_id", livestream.ID), zap.Error(err))
 			lgr.Warnf("view: failed to post livecomment: %s\n", err.Error())
 			return err
 		}
 
 		if _, err := client.GetReactions(ctx, livestream.ID, livestream.Owner.Name); err != nil && !errors.Is(err, bencherror.ErrTimeout) {
+			session.ObserveError(err)
 			lgr.Warnf("view: failed to get reactions: %s\n", err.Error())
 			continue
 		}
@@ -131,14 +154,37 @@ func BasicViewerScenario(
 		if _, err := client.PostReaction(ctx, livestream.ID, livestream.Owner.Name, &isupipe.PostReactionRequest{
 			EmojiName: emojiName,
 		}); err != nil {
+			session.ObserveError(err)
 			lgr.Warnf("view: failed to post reactions: %s\n", err.Error())
 			continue
 		}
+		postedReactions++
+
+		// NOTE: 遅延やスパムに耐えかねた視聴者は、配信の途中で離脱する
+		if reason, ok := churn.ViewerChurn.ShouldDepart(session.RecentSlowness(config.TooSlowPercentile), session.SpamPercentage()); ok {
+			lgr.Infof("view: viewer departs from livestream %d (%s)", livestream.ID, reason)
+			switch reason {
+			case churn.DepartureTooSlow:
+				benchscore.IncTooSlow(ctx)
+			case churn.DepartureTooManySpam:
+				benchscore.IncTooManySpam(ctx)
+			}
+			break
+		}
+	}
+
+	if n%10 == 1 {
+		lgr.Info("verify livestream stats")
+		if err := verifyLivestreamReactions(ctx, client, livestream, postedReactions); err != nil && !errors.Is(err, bencherror.ErrTimeout) {
+			lgr.Warnf("view: failed to verify livestream stats: %s\n", err.Error())
+			return err
+		}
 	}
 	// ログ削減
 	// contestantLogger.Info("視聴者が配信を最後まで視聴できました", zap.String("username", username), zap.Int("duration_hours", livestream.Hours()))
 
 	if err := LeaveFromLivestream(ctx, contestantLogger, client, livestream); err != nil && !errors.Is(err, bencherror.ErrTimeout) {
+		session.ObserveError(err)
 		lgr.Warnf("view: failed to leave from livestream: %s\n", err.Error())
 		return err
 	}
@@ -173,7 +219,7 @@ func ViewerSpamScenario(
 
 	comment, isModerated := scheduler.LivecommentScheduler.GetNegativeComment()
 	if isModerated {
-		_, _, err := viewer.PostLivecomment(ctx, livestream.ID, livestream.Owner.Name, comment.Comment, &scheduler.Tip{}, isupipe.WithStatusCode(http.StatusBadRequest))
+		_, _, err := viewer.PostLivecomment(ctx, livestream.ID, livestream.Owner.Name, comment.Comment, &scheduler.Tip{}, isupipe.WithAction(isupipe.ActionPostLivecommentModerated))
 		if err != nil {
 			lgr.Warnf("viewer_spam: failed to post livecomment (moderated spam): %s\n", err.Error())
 			return err
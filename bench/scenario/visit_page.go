@@ -2,6 +2,7 @@ package scenario
 
 import (
 	"context"
+	"sync"
 
 	"github.com/isucon/isucon13/bench/internal/config"
 	"github.com/isucon/isucon13/bench/isupipe"
@@ -10,6 +11,30 @@ import (
 
 // 訪問時に行うGET操作をまとめた関数郡
 
+// fetchIcons は、ブラウザが画像を読み込むように、アイコンをまとめて取得します
+// NOTE: クライアントの並列数(isupipe.Client.ParallelFetches)だけ並行に取得し、残りは空くまで待たされる
+// アイコンの取得失敗は無視する
+func fetchIcons(ctx context.Context, client *isupipe.Client, users ...isupipe.User) {
+	var (
+		wg   sync.WaitGroup
+		next = make(chan isupipe.User)
+	)
+	for i := 0; i < min(client.ParallelFetches(), len(users)); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for user := range next {
+				client.GetIcon(ctx, user.Name, isupipe.WithETag(user.IconHash))
+			}
+		}()
+	}
+	for _, user := range users {
+		next <- user
+	}
+	close(next)
+	wg.Wait()
+}
+
 func VisitTop(ctx context.Context, contestantLogger *zap.Logger, client *isupipe.Client) error {
 	if _, err := client.GetMyIcon(ctx); err != nil {
 		return err
@@ -19,10 +44,11 @@ func VisitTop(ctx context.Context, contestantLogger *zap.Logger, client *isupipe
 	if err != nil {
 		return err
 	}
-	for _, livestream := range livestreams {
-		client.GetIcon(ctx, livestream.Owner.Name, isupipe.WithETag(livestream.Owner.IconHash))
-		// iconの取得失敗は無視
+	owners := make([]isupipe.User, len(livestreams))
+	for i, livestream := range livestreams {
+		owners[i] = livestream.Owner
 	}
+	fetchIcons(ctx, client, owners...)
 
 	tags, err := client.GetRandomSearchTags(ctx, 1)
 	if err != nil {
@@ -32,10 +58,11 @@ func VisitTop(ctx context.Context, contestantLogger *zap.Logger, client *isupipe
 	if livestreams, err := client.SearchLivestreams(ctx, isupipe.WithSearchTagQueryParam(tags[0])); err != nil {
 		return err
 	} else {
-		for _, livestream := range livestreams {
-			client.GetIcon(ctx, livestream.Owner.Name, isupipe.WithETag(livestream.Owner.IconHash))
-			// iconの取得失敗は無視
+		owners := make([]isupipe.User, len(livestreams))
+		for i, livestream := range livestreams {
+			owners[i] = livestream.Owner
 		}
+		fetchIcons(ctx, client, owners...)
 	}
 
 	return nil
@@ -60,10 +87,11 @@ func VisitLivestream(ctx context.Context, contestantLogger *zap.Logger, client *
 	if err != nil {
 		return err
 	}
-	for _, livecomment := range livecomments {
-		client.GetIcon(ctx, livecomment.User.Name, isupipe.WithETag(livecomment.User.IconHash))
-		// iconの取得失敗は無視
+	users := make([]isupipe.User, len(livecomments))
+	for i, livecomment := range livecomments {
+		users[i] = livecomment.User
 	}
+	fetchIcons(ctx, client, users...)
 
 	_, err = client.GetReactions(ctx, livestream.ID, livestream.Owner.Name, isupipe.WithLimitQueryParam(10))
 	if err != nil {
@@ -0,0 +1,124 @@
+package bench
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/isucon/isucon13/bench/internal/config"
+	"github.com/isucon/isucon13/bench/internal/resolver"
+	"github.com/isucon/isucon13/bench/internal/slowloris"
+	"go.uber.org/zap"
+)
+
+// slowLoris は、負荷走行と並行して行儀の悪いクライアントの接続を張り、正規のリクエストへの影響を確かめます
+type slowLoris struct {
+	mu          sync.Mutex
+	results     []slowloris.Result
+	baseline    []time.Duration
+	underAttack []time.Duration
+	failed      int
+}
+
+// startSlowLoris は、正規のリクエストのレイテンシを計測してから、行儀の悪いクライアントの接続を張ります
+// NOTE: 負荷走行の終了を待たずに結果をまとめるため、終わっていない接続は結果に含めない
+func startSlowLoris(ctx context.Context, dnsResolver *resolver.DNSResolver) *slowLoris {
+	s := &slowLoris{}
+	host := fmt.Sprintf("pipe.%s", config.BaseDomain)
+	target := slowloris.Target{
+		Addr:     net.JoinHostPort(host, strconv.Itoa(config.TargetPort)),
+		Host:     host,
+		Path:     "/api/tag",
+		BodyPath: "/api/register",
+	}
+	if config.HTTPScheme == "https" {
+		target.TLS = &tls.Config{InsecureSkipVerify: config.InsecureSkipVerify}
+	}
+	opts := slowloris.Options{
+		TrickleInterval:    config.SlowLorisTrickleInterval,
+		HoldLimit:          config.SlowLorisHoldLimit,
+		DeclaredBodyLength: config.SlowLorisDeclaredBodyLength,
+	}
+
+	go func() {
+		baseline, _ := measureLatencies(ctx, dnsResolver, target)
+		s.mu.Lock()
+		s.baseline = baseline
+		s.mu.Unlock()
+		for _, mode := range slowloris.Modes {
+			for i := 0; i < config.SlowLorisConnsPerMode; i++ {
+				go func(mode slowloris.Mode) {
+					result := slowloris.Attack(ctx, dnsResolver.DialContext, target, mode, opts)
+					s.mu.Lock()
+					defer s.mu.Unlock()
+					s.results = append(s.results, result)
+				}(mode)
+			}
+		}
+		// NOTE: 接続が張られ、webappが打ち切る前の状態で計測する
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(2 * config.SlowLorisTrickleInterval):
+		}
+		underAttack, failed := measureLatencies(ctx, dnsResolver, target)
+		s.mu.Lock()
+		s.underAttack = underAttack
+		s.failed = failed
+		s.mu.Unlock()
+	}()
+	return s
+}
+
+// measureLatencies は、正規のリクエストのレイテンシを計測し、失敗したリクエスト数とともに返します
+func measureLatencies(ctx context.Context, dnsResolver *resolver.DNSResolver, target slowloris.Target) ([]time.Duration, int) {
+	var (
+		latencies []time.Duration
+		failed    int
+	)
+	for i := 0; i < config.SlowLorisLatencySamples; i++ {
+		latency, err := slowloris.Measure(ctx, dnsResolver.DialContext, target, config.SlowLorisRequestTimeout)
+		if ctx.Err() != nil {
+			break
+		}
+		if err != nil {
+			zap.S().Infof("行儀の悪いクライアントの確認: 正規のリクエストに失敗しました: %s", err.Error())
+			failed++
+			continue
+		}
+		latencies = append(latencies, latency)
+	}
+	return latencies, failed
+}
+
+// report は、終わった接続と計測の結果をまとめ、選手向けに記録します
+func (s *slowLoris) report(contestantLogger *zap.Logger) *slowloris.Report {
+	s.mu.Lock()
+	results := append([]slowloris.Result(nil), s.results...)
+	baseline := append([]time.Duration(nil), s.baseline...)
+	underAttack := append([]time.Duration(nil), s.underAttack...)
+	failed := s.failed
+	s.mu.Unlock()
+
+	report := slowloris.Summarize(results, baseline, underAttack, failed, config.SlowLorisDegradationRatio)
+	for _, r := range report.Results {
+		zap.S().Infof("行儀の悪いクライアントの確認: %+v", r)
+	}
+	zap.S().Infof("行儀の悪いクライアントの確認: 正規のリクエストのレイテンシ %s -> %s", report.BaselineLatency, report.UnderAttackLatency)
+	if report.Survived > 0 {
+		contestantLogger.Warn("リクエストを送り終えないクライアントの接続を打ち切っていません",
+			zap.Int("survived", report.Survived),
+			zap.Duration("hold_limit", config.SlowLorisHoldLimit))
+	}
+	if report.Degraded {
+		contestantLogger.Warn("リクエストを送り終えないクライアントがいる間、正規のリクエストのレイテンシが悪化しました",
+			zap.Duration("baseline", report.BaselineLatency),
+			zap.Duration("under_attack", report.UnderAttackLatency),
+			zap.Int("failed", report.FailedRequests))
+	}
+	return report
+}